@@ -0,0 +1 @@
+ALTER TABLE `months` ADD `notes` text;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE `expenses` ADD `currency` text;--> statement-breakpoint
+ALTER TABLE `incomes` ADD `currency` text;
\ No newline at end of file
@@ -1,6 +1,9 @@
 import { Hono } from 'hono';
+import { etag } from 'hono/etag';
 import { logger } from 'hono/logger';
 import { corsMiddleware } from './middleware/cors';
+import { changeEvents } from './middleware/change-events';
+import { idempotency } from './middleware/idempotency';
 import health from './routes/health';
 import auth from './routes/auth';
 import categoriesRoute from './routes/categories';
@@ -12,6 +15,10 @@ import monthsRoute from './routes/months';
 import summaryRoute from './routes/summary';
 import backupRoute from './routes/backup';
 import backupsRoute from './routes/backups';
+import eventsRoute from './routes/events';
+import shareRoute from './routes/share';
+import exportRoute from './routes/export';
+import openapiRoute from './routes/openapi';
 import frontendRoute from './routes/frontend';
 
 const app = new Hono();
@@ -19,6 +26,17 @@ const app = new Hono();
 // Global middleware
 app.use('*', logger());
 app.use('*', corsMiddleware);
+// Lets clients revalidate cached GETs with If-None-Match. The event stream
+// never ends, so it can't be hashed.
+const etagMiddleware = etag();
+app.use('/api/v1/*', (c, next) =>
+  c.req.path === '/api/v1/events' ? next() : etagMiddleware(c, next),
+);
+// Replays the response to a POST sent again with the same Idempotency-Key;
+// before the change events, so a replay isn't announced as a new write
+app.use('/api/v1/*', idempotency);
+// Tells clients on the event stream about writes
+app.use('/api/v1/*', changeEvents);
 
 // API routes
 app.route('/', health);
@@ -32,6 +50,10 @@ app.route('/', monthsRoute);
 app.route('/', summaryRoute);
 app.route('/', backupRoute);
 app.route('/', backupsRoute);
+app.route('/', eventsRoute);
+app.route('/', shareRoute);
+app.route('/', exportRoute);
+app.route('/', openapiRoute);
 
 // Frontend static files — must be last (catch-all)
 app.route('/', frontendRoute);
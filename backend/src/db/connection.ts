@@ -31,6 +31,16 @@ const initialMigrationTables = [
   'users',
 ];
 
+/**
+ * The column each later migration adds, to tell whether a database without a
+ * journal already has it (a backup taken after the migration whose journal
+ * was lost, say).
+ */
+const laterMigrationColumns: Record<string, { table: string; column: string }> = {
+  '0001_month_notes': { table: 'months', column: 'notes' },
+  '0002_entry_currency': { table: 'expenses', column: 'currency' },
+};
+
 // Run migrations at startup
 const migrationsFolder = path.resolve(import.meta.dir, '../../drizzle');
 seedExistingDb(sqlite);
@@ -39,8 +49,9 @@ console.log('[db] Migrations applied successfully');
 
 /**
  * For databases created before Drizzle migrations were added:
- * Create the journal table and mark all existing migrations as applied
+ * Create the journal table and mark the initial migration as applied
  * so migrate() doesn't try to re-create tables that already exist.
+ * Later migrations are marked too when their columns are already there.
  */
 function seedExistingDb(sqliteDb: Database): void {
   const hasJournal = sqliteDb
@@ -67,7 +78,16 @@ function seedExistingDb(sqliteDb: Database): void {
   const journalPath = path.resolve(migrationsFolder, 'meta/_journal.json');
   const journal = JSON.parse(fs.readFileSync(journalPath, 'utf-8'));
 
-  for (const entry of journal.entries) {
+  // Later migrations still run, unless the database already has their changes
+  const [initial, ...later] = journal.entries;
+  const applied = [initial];
+  for (const entry of later) {
+    const added = laterMigrationColumns[entry.tag];
+    if (!added || !hasColumn(sqliteDb, added.table, added.column)) break;
+    applied.push(entry);
+  }
+
+  for (const entry of applied) {
     const sqlContent = fs.readFileSync(
       path.resolve(migrationsFolder, `${entry.tag}.sql`),
       'utf-8',
@@ -81,6 +101,13 @@ function seedExistingDb(sqliteDb: Database): void {
   console.log('[db] Existing database detected — marked migrations as applied');
 }
 
+function hasColumn(sqliteDb: Database, table: string, column: string): boolean {
+  return sqliteDb
+    .query<{ name: string }, []>(`PRAGMA table_info(${table})`)
+    .all()
+    .some((row) => row.name === column);
+}
+
 /**
  * Re-open the database connection after a restore.
  * Called by the backup service after overwriting the DB file.
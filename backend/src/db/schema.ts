@@ -75,6 +75,7 @@ export const months = sqliteTable('months', {
   is_closed: integer('is_closed', { mode: 'boolean' }).default(false),
   closed_at: text('closed_at'),
   closed_by: text('closed_by'),
+  notes: text('notes'),
   created_at: text('created_at'),
   updated_at: text('updated_at'),
   created_by: text('created_by'),
@@ -97,6 +98,7 @@ export const expenses = sqliteTable('expenses', {
   order: integer('order').default(0),
   purchases: text('purchases'), // JSON stored as text string
   expense_date: text('expense_date'),
+  currency: text('currency'), // ISO 4217 code; null means the budget's own
   created_at: text('created_at'),
   updated_at: text('updated_at'),
   created_by: text('created_by'),
@@ -116,6 +118,7 @@ export const incomes = sqliteTable('incomes', {
   month_id: integer('month_id')
     .notNull()
     .references(() => months.id),
+  currency: text('currency'), // ISO 4217 code; null means the budget's own
   created_at: text('created_at'),
   updated_at: text('updated_at'),
   created_by: text('created_by'),
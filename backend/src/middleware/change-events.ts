@@ -0,0 +1,60 @@
+/**
+ * Publishes a change event after every successful write under /api/v1.
+ */
+
+import type { Context, Next } from 'hono';
+import { publish, subscriberCount, type ChangeAction } from '../services/events';
+
+const ACTIONS: Record<string, ChangeAction> = {
+  POST: 'created',
+  PUT: 'updated',
+  PATCH: 'updated',
+  DELETE: 'deleted',
+};
+
+/** Resources clients keep on screen; auth and backup writes aren't announced. */
+const WATCHED = new Set(['expenses', 'incomes', 'months', 'categories', 'periods', 'income-types']);
+
+export async function changeEvents(c: Context, next: Next) {
+  await next();
+
+  const method = c.req.method;
+  let action = ACTIONS[method];
+  if (!action || c.res.status >= 300 || subscriberCount() === 0) {
+    return;
+  }
+
+  // /api/v1/<resource>[/<id>[/<verb>]]
+  const [resource, idPart, verb] = c.req.path.split('/').slice(3);
+  // Creating a share link changes nothing
+  if (!WATCHED.has(resource) || verb === 'share') {
+    return;
+  }
+  // Pay, close, clone and reorder are updates of the record they name;
+  // bulk writes keep the action of their method
+  if (verb || (method === 'POST' && idPart && idPart !== 'bulk' && !/^\d+$/.test(idPart))) {
+    action = 'updated';
+  }
+
+  let id = idPart && /^\d+$/.test(idPart) ? parseInt(idPart, 10) : null;
+  let monthId = resource === 'months' ? id : null;
+  if (resource === 'expenses' || resource === 'incomes') {
+    try {
+      const body = await c.res.clone().json();
+      if (body && typeof body === 'object' && !Array.isArray(body)) {
+        id = typeof body.id === 'number' ? body.id : id;
+        monthId = typeof body.month_id === 'number' ? body.month_id : null;
+      }
+    } catch {
+      // Not JSON; the event goes out without a month
+    }
+  }
+
+  publish({
+    resource,
+    action,
+    id,
+    month_id: monthId,
+    source: c.req.header('X-Client-Id') ?? null,
+  });
+}
@@ -9,6 +9,6 @@ import { isDev } from '../config';
 export const corsMiddleware = cors({
   origin: isDev ? ['http://localhost:3000', 'http://localhost:5173'] : '*',
   allowMethods: ['GET', 'POST', 'PUT', 'PATCH', 'DELETE', 'OPTIONS'],
-  allowHeaders: ['Content-Type', 'Authorization', 'X-API-Key', 'X-User-Name', 'X-Client-Info'],
+  allowHeaders: ['Content-Type', 'Authorization', 'X-API-Key', 'X-User-Name', 'X-Client-Info', 'X-Client-Id', 'Idempotency-Key', 'X-Allow-Closed-Month'],
   credentials: true,
 });
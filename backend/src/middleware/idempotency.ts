@@ -0,0 +1,76 @@
+/**
+ * Answers a repeated POST carrying the same Idempotency-Key with the response
+ * to the first one, instead of applying it again.
+ *
+ * Clients send a key with each write that creates something and reuse it when
+ * they send the write again after a timeout or dropped connection, so a
+ * request that actually went through isn't applied twice. Only successful
+ * responses are kept, for a day, in memory; a failed request can be retried
+ * with the same key.
+ */
+
+import type { Context, Next } from 'hono';
+
+const TTL_MS = 24 * 60 * 60 * 1000;
+const MAX_KEY_LENGTH = 255;
+
+interface Saved {
+  status: number;
+  contentType: string | null;
+  body: string;
+  expires: number;
+}
+
+const saved = new Map<string, Saved>();
+const inFlight = new Set<string>();
+
+function prune(now: number) {
+  for (const [key, entry] of saved) {
+    if (entry.expires <= now) {
+      saved.delete(key);
+    }
+  }
+}
+
+export async function idempotency(c: Context, next: Next) {
+  const key = c.req.header('Idempotency-Key');
+  if (c.req.method !== 'POST' || !key) {
+    return next();
+  }
+  if (key.length > MAX_KEY_LENGTH) {
+    return c.json({ detail: 'Idempotency-Key is too long' }, 400);
+  }
+
+  // Keys are only unique per caller and endpoint
+  const caller = c.req.header('Authorization') ?? c.req.header('X-API-Key') ?? '';
+  const scoped = `${caller}\n${c.req.path}\n${key}`;
+
+  const now = Date.now();
+  prune(now);
+  const previous = saved.get(scoped);
+  if (previous) {
+    const headers: Record<string, string> = { 'Idempotent-Replayed': 'true' };
+    if (previous.contentType) {
+      headers['Content-Type'] = previous.contentType;
+    }
+    return c.body(previous.body, previous.status as 200, headers);
+  }
+  if (inFlight.has(scoped)) {
+    return c.json({ detail: 'A request with this Idempotency-Key is still in progress' }, 409);
+  }
+
+  inFlight.add(scoped);
+  try {
+    await next();
+    if (c.res.status >= 200 && c.res.status < 300) {
+      saved.set(scoped, {
+        status: c.res.status,
+        contentType: c.res.headers.get('Content-Type'),
+        body: await c.res.clone().text(),
+        expires: now + TTL_MS,
+      });
+    }
+  } finally {
+    inFlight.delete(scoped);
+  }
+}
@@ -163,6 +163,7 @@ backupRoute.post('/api/v1/backup/restore', apiKeyAuth, optionalAuth, async (c) =
           is_closed: (m.is_closed as boolean) ?? false,
           closed_at: (m.closed_at as string) ?? null,
           closed_by: (m.closed_by as string) ?? null,
+          notes: (m.notes as string) ?? null,
           created_at: (m.created_at as string) ?? timestamp,
           updated_at: (m.updated_at as string) ?? timestamp,
           created_by: (m.created_by as string) ?? userName,
@@ -204,6 +205,7 @@ backupRoute.post('/api/v1/backup/restore', apiKeyAuth, optionalAuth, async (c) =
           order: (exp.order as number) ?? 0,
           purchases: (exp.purchases as string) ?? null,
           expense_date: (exp.expense_date as string) ?? null,
+          currency: (exp.currency as string) ?? null,
           created_at: (exp.created_at as string) ?? timestamp,
           updated_at: (exp.updated_at as string) ?? timestamp,
           created_by: (exp.created_by as string) ?? userName,
@@ -228,6 +230,7 @@ backupRoute.post('/api/v1/backup/restore', apiKeyAuth, optionalAuth, async (c) =
           budget: (inc.budget as number) ?? 0,
           amount: (inc.amount as number) ?? 0,
           month_id: newMonthId,
+          currency: (inc.currency as string) ?? null,
           created_at: (inc.created_at as string) ?? timestamp,
           updated_at: (inc.updated_at as string) ?? timestamp,
           created_by: (inc.created_by as string) ?? userName,
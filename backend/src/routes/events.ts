@@ -0,0 +1,56 @@
+/**
+ * Server-sent stream of data changes, so open clients can refresh without
+ * being asked to.
+ */
+
+import { Hono } from 'hono';
+import { streamSSE } from 'hono/streaming';
+
+import { apiKeyAuth } from '../middleware/api-key';
+import { optionalAuth } from '../middleware/jwt';
+import { subscribe, type ChangeEvent } from '../services/events';
+
+/** Comment sent while idle so proxies keep the connection open. */
+const KEEPALIVE_MS = 15_000;
+
+const eventsRoute = new Hono();
+
+// ─── GET /api/v1/events ─────────────────────────────────────────────────────
+
+eventsRoute.get('/api/v1/events', apiKeyAuth, optionalAuth, (c) =>
+  streamSSE(c, async (stream) => {
+    const pending: ChangeEvent[] = [];
+    let wake: (() => void) | null = null;
+    const unsubscribe = subscribe((event) => {
+      pending.push(event);
+      wake?.();
+    });
+    stream.onAbort(() => {
+      unsubscribe();
+      wake?.();
+    });
+
+    let sequence = 0;
+    while (!stream.aborted) {
+      const event = pending.shift();
+      if (event) {
+        sequence += 1;
+        await stream.writeSSE({ event: 'change', id: String(sequence), data: JSON.stringify(event) });
+        continue;
+      }
+      let timer: ReturnType<typeof setTimeout> | undefined;
+      await new Promise<void>((resolve) => {
+        wake = resolve;
+        timer = setTimeout(resolve, KEEPALIVE_MS);
+      });
+      clearTimeout(timer);
+      wake = null;
+      if (pending.length === 0 && !stream.aborted) {
+        await stream.write(': keepalive\n\n');
+      }
+    }
+    unsubscribe();
+  }),
+);
+
+export default eventsRoute;
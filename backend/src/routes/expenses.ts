@@ -7,6 +7,7 @@
 import { Hono } from 'hono';
 import { eq, and, asc, sql } from 'drizzle-orm';
 import { zValidator } from '@hono/zod-validator';
+import type { z } from 'zod';
 
 import { db } from '../db/connection';
 import { expenses, months } from '../db/schema';
@@ -15,9 +16,13 @@ import { optionalAuth } from '../middleware/jwt';
 import {
   expenseCreateSchema,
   expenseUpdateSchema,
+  expenseBulkCreateSchema,
+  expenseBulkUpdateSchema,
   expenseReorderSchema,
   payExpenseSchema,
 } from '../types/schemas';
+import { parsePageParams } from '../utils/pagination';
+import { ALLOW_CLOSED_MONTH_HEADER, allowsClosedMonth } from '../utils/closed-month';
 
 type Variables = {
   userId: number;
@@ -76,15 +81,16 @@ expensesRoute.get('/api/v1/expenses', apiKeyAuth, optionalAuth, async (c) => {
     conditions.push(eq(expenses.category, category));
   }
 
+  // id breaks ties so pages don't overlap
   const query = db
     .select()
     .from(expenses)
-    .orderBy(asc(expenses.order), asc(expenses.expense_name));
+    .where(conditions.length > 0 ? and(...conditions) : undefined)
+    .orderBy(asc(expenses.order), asc(expenses.expense_name), asc(expenses.id))
+    .$dynamic();
 
-  const rows =
-    conditions.length > 0
-      ? await query.where(and(...conditions))
-      : await query;
+  const page = parsePageParams(c.req.query('limit'), c.req.query('offset'));
+  const rows = page ? await query.limit(page.limit).offset(page.offset) : await query;
 
   return c.json(rows.map(serializeExpense));
 });
@@ -107,6 +113,123 @@ expensesRoute.get('/api/v1/expenses/:id', apiKeyAuth, optionalAuth, async (c) =>
   return c.json(serializeExpense(expense));
 });
 
+// ─── Shared write helpers ───────────────────────────────────────────────────
+
+type ExpenseCreate = z.infer<typeof expenseCreateSchema>;
+type ExpenseUpdate = z.infer<typeof expenseUpdateSchema>;
+
+/**
+ * Check that a month exists and is open, or that the client asked to change
+ * it anyway.
+ * Returns the error detail when expenses can't be written to it.
+ */
+async function closedMonthDetail(
+  monthId: number,
+  action: string,
+  allowClosed: boolean,
+): Promise<string | null> {
+  const [month] = await db
+    .select()
+    .from(months)
+    .where(eq(months.id, monthId))
+    .limit(1);
+
+  if (!month) {
+    return `Month with ID ${monthId} not found`;
+  }
+  if (month.is_closed && !allowClosed) {
+    return `Cannot ${action} expense: Month '${month.name}' is closed`;
+  }
+  return null;
+}
+
+/**
+ * The order after the last expense of a month.
+ */
+async function nextOrder(monthId: number): Promise<number> {
+  const [maxRow] = await db
+    .select({ maxOrder: sql<number>`coalesce(max(${expenses.order}), -1)` })
+    .from(expenses)
+    .where(eq(expenses.month_id, monthId));
+  return (maxRow?.maxOrder ?? -1) + 1;
+}
+
+/**
+ * Column values for a new expense.
+ */
+function newExpenseValues(
+  body: ExpenseCreate,
+  order: number,
+  userName: string | undefined,
+  timestamp: string,
+) {
+  // Calculate cost from purchases if they exist
+  let cost = body.cost ?? 0;
+  let purchasesJson: string | null = null;
+
+  if (body.purchases && body.purchases.length > 0) {
+    cost = body.purchases.reduce((sum, p) => sum + (p.amount ?? 0), 0);
+    purchasesJson = JSON.stringify(body.purchases);
+  }
+
+  return {
+    expense_name: body.expense_name,
+    period: body.period,
+    category: body.category,
+    budget: body.budget ?? 0,
+    cost,
+    notes: body.notes ?? null,
+    month_id: body.month_id,
+    order,
+    purchases: purchasesJson,
+    // Set expense_date to today if not provided
+    expense_date: body.expense_date || today(),
+    currency: body.currency ?? null,
+    created_at: timestamp,
+    updated_at: timestamp,
+    created_by: userName ?? null,
+    updated_by: userName ?? null,
+  };
+}
+
+/**
+ * Column values for an update (only fields that were actually sent).
+ */
+function expenseChanges(body: ExpenseUpdate, userName: string | undefined, timestamp: string) {
+  const updateData: Record<string, unknown> = {
+    updated_at: timestamp,
+    updated_by: userName ?? null,
+  };
+
+  if (body.expense_name !== undefined) updateData.expense_name = body.expense_name;
+  if (body.period !== undefined) updateData.period = body.period;
+  if (body.category !== undefined) updateData.category = body.category;
+  if (body.budget !== undefined) updateData.budget = body.budget;
+  if (body.notes !== undefined) updateData.notes = body.notes;
+  if (body.month_id !== undefined) updateData.month_id = body.month_id;
+  if (body.order !== undefined) updateData.order = body.order;
+  if (body.expense_date !== undefined) updateData.expense_date = body.expense_date;
+  if (body.currency !== undefined) updateData.currency = body.currency;
+
+  // Handle purchases and cost recalculation
+  if (body.purchases !== undefined) {
+    if (body.purchases && body.purchases.length > 0) {
+      updateData.purchases = JSON.stringify(body.purchases);
+      updateData.cost = body.purchases.reduce((sum, p) => sum + (p.amount ?? 0), 0);
+    } else {
+      updateData.purchases = null;
+      // Keep existing cost unless cost was also explicitly provided
+      if (body.cost !== undefined) {
+        updateData.cost = body.cost;
+      }
+    }
+  } else if (body.cost !== undefined) {
+    updateData.cost = body.cost;
+  }
+
+  return updateData;
+}
+
 // ─── POST /api/v1/expenses ──────────────────────────────────────────────────
 
 expensesRoute.post(
@@ -117,71 +240,135 @@ expensesRoute.post(
   async (c) => {
     const body = c.req.valid('json');
     const userName = c.get('userName') as string | undefined;
+    const allowClosed = allowsClosedMonth(c.req.header(ALLOW_CLOSED_MONTH_HEADER));
 
     // Validate month exists and is not closed
-    const [month] = await db
-      .select()
-      .from(months)
-      .where(eq(months.id, body.month_id))
-      .limit(1);
-
-    if (!month) {
-      return c.json({ detail: `Month with ID ${body.month_id} not found` }, 400);
-    }
-    if (month.is_closed) {
-      return c.json({ detail: `Cannot add expense: Month '${month.name}' is closed` }, 400);
-    }
-
-    // Set expense_date to today if not provided
-    const expenseDate = body.expense_date || today();
-
-    // Calculate cost from purchases if they exist
-    let cost = body.cost ?? 0;
-    let purchasesJson: string | null = null;
-
-    if (body.purchases && body.purchases.length > 0) {
-      cost = body.purchases.reduce((sum, p) => sum + (p.amount ?? 0), 0);
-      purchasesJson = JSON.stringify(body.purchases);
+    const detail = await closedMonthDetail(body.month_id, 'add', allowClosed);
+    if (detail) {
+      return c.json({ detail }, 400);
     }
 
     // Set order if not provided — use max(order)+1 for this month
     let order = body.order;
     if (order === undefined || order === null || order === 0) {
-      const [maxRow] = await db
-        .select({ maxOrder: sql<number>`coalesce(max(${expenses.order}), -1)` })
-        .from(expenses)
-        .where(eq(expenses.month_id, body.month_id));
-      order = (maxRow?.maxOrder ?? -1) + 1;
+      order = await nextOrder(body.month_id);
     }
 
-    const timestamp = now();
     const [created] = await db
       .insert(expenses)
-      .values({
-        expense_name: body.expense_name,
-        period: body.period,
-        category: body.category,
-        budget: body.budget ?? 0,
-        cost,
-        notes: body.notes ?? null,
-        month_id: body.month_id,
-        order,
-        purchases: purchasesJson,
-        expense_date: expenseDate,
-        created_at: timestamp,
-        updated_at: timestamp,
-        created_by: userName ?? null,
-        updated_by: userName ?? null,
-      })
+      .values(newExpenseValues(body, order, userName, now()))
       .returning();
 
     return c.json(serializeExpense(created), 201);
   },
 );
 
-// ─── PUT /api/v1/expenses/:id ───────────────────────────────────────────────
+// ─── POST /api/v1/expenses/bulk ─────────────────────────────────────────────
+
+expensesRoute.post(
+  '/api/v1/expenses/bulk',
+  apiKeyAuth,
+  optionalAuth,
+  zValidator('json', expenseBulkCreateSchema),
+  async (c) => {
+    const { expenses: items } = c.req.valid('json');
+    const userName = c.get('userName') as string | undefined;
+    const allowClosed = allowsClosedMonth(c.req.header(ALLOW_CLOSED_MONTH_HEADER));
+
+    // Validate every month before writing anything
+    const monthIds = [...new Set(items.map((item) => item.month_id))];
+    for (const monthId of monthIds) {
+      const detail = await closedMonthDetail(monthId, 'add', allowClosed);
+      if (detail) {
+        return c.json({ detail }, 400);
+      }
+    }
+
+    // Expenses without an order go after the month's last one, in request order
+    const orders = new Map<number, number>();
+    for (const monthId of monthIds) {
+      orders.set(monthId, await nextOrder(monthId));
+    }
+
+    // All or nothing: a failed insert rolls back the ones before it
+    const timestamp = now();
+    const result = db.transaction((tx) =>
+      items.map((item) => {
+        let order = item.order;
+        if (order === undefined || order === null || order === 0) {
+          order = orders.get(item.month_id)!;
+          orders.set(item.month_id, order + 1);
+        }
+
+        const created = tx
+          .insert(expenses)
+          .values(newExpenseValues(item, order, userName, timestamp))
+          .returning()
+          .get();
+        return serializeExpense(created);
+      }),
+    );
+
+    return c.json(result, 201);
+  },
+);
+
+// ─── PUT /api/v1/expenses/bulk ──────────────────────────────────────────────
 
 expensesRoute.put(
+  '/api/v1/expenses/bulk',
+  apiKeyAuth,
+  optionalAuth,
+  zValidator('json', expenseBulkUpdateSchema),
+  async (c) => {
+    const { updates } = c.req.valid('json');
+    const userName = c.get('userName') as string | undefined;
+    const allowClosed = allowsClosedMonth(c.req.header(ALLOW_CLOSED_MONTH_HEADER));
+
+    // Validate every expense and month before writing anything
+    for (const update of updates) {
+      const [expense] = await db
+        .select({ month_id: expenses.month_id })
+        .from(expenses)
+        .where(eq(expenses.id, update.id))
+        .limit(1);
+      if (!expense) {
+        return c.json({ detail: `Expense with ID ${update.id} not found` }, 404);
+      }
+
+      const detail = await closedMonthDetail(
+        update.month_id ?? expense.month_id,
+        'update',
+        allowClosed,
+      );
+      if (detail) {
+        return c.json({ detail }, 400);
+      }
+    }
+
+    // All or nothing: a failed update rolls back the ones before it
+    const timestamp = now();
+    const result = db.transaction((tx) =>
+      updates.map(({ id, ...update }) => {
+        const updated = tx
+          .update(expenses)
+          .set(expenseChanges(update, userName, timestamp))
+          .where(eq(expenses.id, id))
+          .returning()
+          .get();
+        return serializeExpense(updated!);
+      }),
+    );
+
+    return c.json(result);
+  },
+);
+
+// ─── PUT/PATCH /api/v1/expenses/:id ─────────────────────────────────────────
+// Both change only the fields in the body
+
+expensesRoute.on(
+  ['PUT', 'PATCH'],
   '/api/v1/expenses/:id',
   apiKeyAuth,
   optionalAuth,
@@ -190,6 +377,7 @@ expensesRoute.put(
     const id = parseInt(c.req.param('id'), 10);
     const body = c.req.valid('json');
     const userName = c.get('userName') as string | undefined;
+    const allowClosed = allowsClosedMonth(c.req.header(ALLOW_CLOSED_MONTH_HEADER));
 
     const [expense] = await db
       .select()
@@ -202,54 +390,18 @@ expensesRoute.put(
     }
 
     // Validate month is not closed (check target month_id or current)
-    const monthIdToCheck = body.month_id ?? expense.month_id;
-    const [month] = await db
-      .select()
-      .from(months)
-      .where(eq(months.id, monthIdToCheck))
-      .limit(1);
-
-    if (!month) {
-      return c.json({ detail: `Month with ID ${monthIdToCheck} not found` }, 400);
-    }
-    if (month.is_closed) {
-      return c.json({ detail: `Cannot update expense: Month '${month.name}' is closed` }, 400);
-    }
-
-    // Build the update payload (only fields that were actually sent)
-    const updateData: Record<string, unknown> = {
-      updated_at: now(),
-      updated_by: userName ?? null,
-    };
-
-    if (body.expense_name !== undefined) updateData.expense_name = body.expense_name;
-    if (body.period !== undefined) updateData.period = body.period;
-    if (body.category !== undefined) updateData.category = body.category;
-    if (body.budget !== undefined) updateData.budget = body.budget;
-    if (body.notes !== undefined) updateData.notes = body.notes;
-    if (body.month_id !== undefined) updateData.month_id = body.month_id;
-    if (body.order !== undefined) updateData.order = body.order;
-    if (body.expense_date !== undefined) updateData.expense_date = body.expense_date;
-
-    // Handle purchases and cost recalculation
-    if (body.purchases !== undefined) {
-      if (body.purchases && body.purchases.length > 0) {
-        updateData.purchases = JSON.stringify(body.purchases);
-        updateData.cost = body.purchases.reduce((sum, p) => sum + (p.amount ?? 0), 0);
-      } else {
-        updateData.purchases = null;
-        // Keep existing cost unless cost was also explicitly provided
-        if (body.cost !== undefined) {
-          updateData.cost = body.cost;
-        }
-      }
-    } else if (body.cost !== undefined) {
-      updateData.cost = body.cost;
+    const detail = await closedMonthDetail(
+      body.month_id ?? expense.month_id,
+      'update',
+      allowClosed,
+    );
+    if (detail) {
+      return c.json({ detail }, 400);
     }
 
     const [updated] = await db
       .update(expenses)
-      .set(updateData)
+      .set(expenseChanges(body, userName, now()))
       .where(eq(expenses.id, id))
       .returning();
 
@@ -279,7 +431,8 @@ expensesRoute.delete('/api/v1/expenses/:id', apiKeyAuth, optionalAuth, async (c)
     .where(eq(months.id, expense.month_id))
     .limit(1);
 
-  if (month && month.is_closed) {
+  const allowClosed = allowsClosedMonth(c.req.header(ALLOW_CLOSED_MONTH_HEADER));
+  if (month && month.is_closed && !allowClosed) {
     return c.json({ detail: `Cannot delete expense: Month '${month.name}' is closed` }, 400);
   }
 
@@ -374,7 +527,8 @@ expensesRoute.post(
       .where(eq(months.id, expense.month_id))
       .limit(1);
 
-    if (month && month.is_closed) {
+    const allowClosed = allowsClosedMonth(c.req.header(ALLOW_CLOSED_MONTH_HEADER));
+    if (month && month.is_closed && !allowClosed) {
       return c.json({ detail: `Cannot pay expense: Month '${month.name}' is closed` }, 400);
     }
 
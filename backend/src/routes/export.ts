@@ -0,0 +1,87 @@
+/**
+ * CSV export of a month's expenses and incomes.
+ *
+ * GET /api/v1/months/:id/export — download as `budget-YYYY-MM.csv`
+ *
+ * Columns match what the TUI's `--import` reads, so an export can be imported
+ * into another server.
+ */
+
+import { Hono } from 'hono';
+import { asc, eq } from 'drizzle-orm';
+
+import { db } from '../db/connection';
+import { expenses, incomes, incomeTypes, months } from '../db/schema';
+import { apiKeyAuth } from '../middleware/api-key';
+import { optionalAuth } from '../middleware/jwt';
+
+const HEADER = ['month', 'type', 'name', 'category', 'period', 'projected', 'actual'];
+
+const exportRoute = new Hono();
+
+/** Quote a field if it holds a comma, quote or line break */
+function csvField(value: string | number | null): string {
+  const text = value === null ? '' : String(value);
+  return /[",\r\n]/.test(text) ? `"${text.replace(/"/g, '""')}"` : text;
+}
+
+function csvRow(fields: (string | number | null)[]): string {
+  return fields.map(csvField).join(',');
+}
+
+// ─── GET /api/v1/months/:id/export ──────────────────────────────────────────
+
+exportRoute.get('/api/v1/months/:id/export', apiKeyAuth, optionalAuth, async (c) => {
+  const id = parseInt(c.req.param('id'), 10);
+
+  const [month] = await db.select().from(months).where(eq(months.id, id)).limit(1);
+  if (!month) {
+    return c.json({ detail: `Month with ID ${id} not found` }, 404);
+  }
+  const key = `${month.year}-${String(month.month).padStart(2, '0')}`;
+
+  const monthExpenses = await db
+    .select()
+    .from(expenses)
+    .where(eq(expenses.month_id, id))
+    .orderBy(asc(expenses.order));
+  const monthIncomes = await db
+    .select({
+      name: incomeTypes.name,
+      period: incomes.period,
+      budget: incomes.budget,
+      amount: incomes.amount,
+    })
+    .from(incomes)
+    .innerJoin(incomeTypes, eq(incomes.income_type_id, incomeTypes.id))
+    .where(eq(incomes.month_id, id));
+
+  const lines = [HEADER.join(',')];
+  for (const expense of monthExpenses) {
+    lines.push(
+      csvRow([
+        key,
+        'expense',
+        expense.expense_name,
+        expense.category,
+        expense.period,
+        expense.budget ?? 0,
+        expense.cost ?? 0,
+      ]),
+    );
+  }
+  for (const income of monthIncomes) {
+    lines.push(
+      csvRow([key, 'income', income.name, null, income.period, income.budget ?? 0, income.amount ?? 0]),
+    );
+  }
+
+  return new Response(lines.join('\r\n') + '\r\n', {
+    headers: {
+      'Content-Type': 'text/csv; charset=utf-8',
+      'Content-Disposition': `attachment; filename="budget-${key}.csv"`,
+    },
+  });
+});
+
+export default exportRoute;
@@ -13,6 +13,8 @@ import { incomes, months, incomeTypes } from '../db/schema';
 import { apiKeyAuth } from '../middleware/api-key';
 import { optionalAuth } from '../middleware/jwt';
 import { incomeCreateSchema, incomeUpdateSchema } from '../types/schemas';
+import { parsePageParams } from '../utils/pagination';
+import { ALLOW_CLOSED_MONTH_HEADER, allowsClosedMonth } from '../utils/closed-month';
 
 type Variables = {
   userId: number;
@@ -43,15 +45,16 @@ incomesRoute.get('/api/v1/incomes', apiKeyAuth, optionalAuth, async (c) => {
     conditions.push(eq(incomes.income_type_id, parseInt(incomeTypeIdParam, 10)));
   }
 
+  // id breaks ties so pages don't overlap
   const query = db
     .select()
     .from(incomes)
-    .orderBy(incomes.income_type_id);
+    .where(conditions.length > 0 ? and(...conditions) : undefined)
+    .orderBy(incomes.income_type_id, incomes.id)
+    .$dynamic();
 
-  const rows =
-    conditions.length > 0
-      ? await query.where(and(...conditions))
-      : await query;
+  const page = parsePageParams(c.req.query('limit'), c.req.query('offset'));
+  const rows = page ? await query.limit(page.limit).offset(page.offset) : await query;
 
   return c.json(rows);
 });
@@ -84,6 +87,7 @@ incomesRoute.post(
   async (c) => {
     const body = c.req.valid('json');
     const userName = c.get('userName') as string | undefined;
+    const allowClosed = allowsClosedMonth(c.req.header(ALLOW_CLOSED_MONTH_HEADER));
 
     // Validate month exists and is not closed
     const [month] = await db
@@ -95,7 +99,7 @@ incomesRoute.post(
     if (!month) {
       return c.json({ detail: `Month with ID ${body.month_id} not found` }, 400);
     }
-    if (month.is_closed) {
+    if (month.is_closed && !allowClosed) {
       return c.json({ detail: `Cannot add income: Month '${month.name}' is closed` }, 400);
     }
 
@@ -119,6 +123,7 @@ incomesRoute.post(
         budget: body.budget ?? 0,
         amount: body.amount ?? 0,
         month_id: body.month_id,
+        currency: body.currency ?? null,
         created_at: timestamp,
         updated_at: timestamp,
         created_by: userName ?? null,
@@ -130,9 +135,11 @@ incomesRoute.post(
   },
 );
 
-// ─── PUT /api/v1/incomes/:id ────────────────────────────────────────────────
+// ─── PUT/PATCH /api/v1/incomes/:id ──────────────────────────────────────────
+// Both change only the fields in the body
 
-incomesRoute.put(
+incomesRoute.on(
+  ['PUT', 'PATCH'],
   '/api/v1/incomes/:id',
   apiKeyAuth,
   optionalAuth,
@@ -141,6 +148,7 @@ incomesRoute.put(
     const id = parseInt(c.req.param('id'), 10);
     const body = c.req.valid('json');
     const userName = c.get('userName') as string | undefined;
+    const allowClosed = allowsClosedMonth(c.req.header(ALLOW_CLOSED_MONTH_HEADER));
 
     const [income] = await db
       .select()
@@ -163,7 +171,7 @@ incomesRoute.put(
     if (!month) {
       return c.json({ detail: `Month with ID ${monthIdToCheck} not found` }, 400);
     }
-    if (month.is_closed) {
+    if (month.is_closed && !allowClosed) {
       return c.json({ detail: `Cannot update income: Month '${month.name}' is closed` }, 400);
     }
 
@@ -191,6 +199,7 @@ incomesRoute.put(
     if (body.budget !== undefined) updateData.budget = body.budget;
     if (body.amount !== undefined) updateData.amount = body.amount;
     if (body.month_id !== undefined) updateData.month_id = body.month_id;
+    if (body.currency !== undefined) updateData.currency = body.currency;
 
     const [updated] = await db
       .update(incomes)
@@ -224,7 +233,8 @@ incomesRoute.delete('/api/v1/incomes/:id', apiKeyAuth, optionalAuth, async (c) =
     .where(eq(months.id, income.month_id))
     .limit(1);
 
-  if (month && month.is_closed) {
+  const allowClosed = allowsClosedMonth(c.req.header(ALLOW_CLOSED_MONTH_HEADER));
+  if (month && month.is_closed && !allowClosed) {
     return c.json({ detail: `Cannot delete income: Month '${month.name}' is closed` }, 400);
   }
 
@@ -234,6 +234,9 @@ monthsRoute.put(
     if (body.end_date !== undefined && updateData.end_date === undefined)
       updateData.end_date = body.end_date;
 
+    // Blank notes clear them
+    if (body.notes !== undefined) updateData.notes = body.notes?.trim() ? body.notes : null;
+
     const [updated] = await db
       .update(months)
       .set(updateData)
@@ -418,6 +421,7 @@ monthsRoute.post('/api/v1/months/:id/clone', apiKeyAuth, optionalAuth, async (c)
       purchases: null,
       order: expense.order,
       expense_date: null,
+      currency: expense.currency,
       created_at: timestamp,
       updated_at: timestamp,
       created_by: userName ?? null,
@@ -440,6 +444,7 @@ monthsRoute.post('/api/v1/months/:id/clone', apiKeyAuth, optionalAuth, async (c)
       budget: income.budget,
       amount: 0,
       month_id: nextMonth.id,
+      currency: income.currency,
       created_at: timestamp,
       updated_at: timestamp,
       created_by: userName ?? null,
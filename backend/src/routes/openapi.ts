@@ -0,0 +1,14 @@
+/**
+ * OpenAPI spec route.
+ * The spec is kept by hand next to the routes; the TUI SDK generates its
+ * models from it.
+ */
+
+import { Hono } from 'hono';
+import spec from '../openapi.json';
+
+const openapiRoute = new Hono();
+
+openapiRoute.get('/openapi.json', (c) => c.json(spec));
+
+export default openapiRoute;
@@ -0,0 +1,176 @@
+/**
+ * Read-only share links for a month's summary.
+ *
+ * POST /api/v1/months/:id/share — create an expiring link
+ * GET  /share/:token            — the shared summary as a page, no API key needed
+ *
+ * Links are signed tokens rather than stored records: they stop working when
+ * they expire or when JWT_SECRET_KEY changes, and can't be revoked one by one.
+ */
+
+import { Hono } from 'hono';
+import { eq } from 'drizzle-orm';
+import { zValidator } from '@hono/zod-validator';
+
+import { db } from '../db/connection';
+import { expenses, incomes, incomeTypes, months } from '../db/schema';
+import { apiKeyAuth } from '../middleware/api-key';
+import { optionalAuth } from '../middleware/jwt';
+import { monthShareSchema } from '../types/schemas';
+import { createAccessToken, decodeAccessToken } from '../utils/auth';
+
+const SHARE_SCOPE = 'share';
+const DEFAULT_EXPIRES_IN_HOURS = 7 * 24;
+
+type Variables = {
+  userId: number;
+  userName: string;
+};
+
+const shareRoute = new Hono<{ Variables: Variables }>();
+
+function escapeHtml(value: string): string {
+  return value
+    .replace(/&/g, '&amp;')
+    .replace(/</g, '&lt;')
+    .replace(/>/g, '&gt;')
+    .replace(/"/g, '&quot;');
+}
+
+function money(value: number | null): string {
+  return (value ?? 0).toLocaleString('en-US', { minimumFractionDigits: 2, maximumFractionDigits: 2 });
+}
+
+// ─── POST /api/v1/months/:id/share ──────────────────────────────────────────
+
+shareRoute.post(
+  '/api/v1/months/:id/share',
+  apiKeyAuth,
+  optionalAuth,
+  zValidator('json', monthShareSchema),
+  async (c) => {
+    const id = parseInt(c.req.param('id'), 10);
+    const body = c.req.valid('json');
+
+    const [month] = await db.select().from(months).where(eq(months.id, id)).limit(1);
+    if (!month) {
+      return c.json({ detail: `Month with ID ${id} not found` }, 404);
+    }
+
+    const hours = body.expires_in_hours ?? DEFAULT_EXPIRES_IN_HOURS;
+    const token = await createAccessToken(
+      { scope: SHARE_SCOPE, month_id: id, shared_by: c.get('userName') ?? null },
+      hours * 60,
+    );
+    const path = `/share/${token}`;
+
+    return c.json({
+      token,
+      path,
+      url: new URL(path, c.req.url).toString(),
+      month_id: id,
+      expires_at: new Date(Date.now() + hours * 3600 * 1000).toISOString(),
+    });
+  },
+);
+
+// ─── GET /share/:token ──────────────────────────────────────────────────────
+
+shareRoute.get('/share/:token', async (c) => {
+  c.header('Cache-Control', 'no-store');
+  c.header('X-Robots-Tag', 'noindex');
+
+  const payload = await decodeAccessToken(c.req.param('token'));
+  // Login tokens are signed with the same key; only share tokens open a page
+  if (!payload || payload.scope !== SHARE_SCOPE || typeof payload.month_id !== 'number') {
+    return c.html('<p>This link is invalid or has expired.</p>', 404);
+  }
+
+  const monthId = payload.month_id;
+  const [month] = await db.select().from(months).where(eq(months.id, monthId)).limit(1);
+  if (!month) {
+    return c.html('<p>This month no longer exists.</p>', 404);
+  }
+
+  const monthExpenses = await db.select().from(expenses).where(eq(expenses.month_id, monthId));
+  const monthIncomes = await db.select().from(incomes).where(eq(incomes.month_id, monthId));
+  const types = await db.select().from(incomeTypes);
+
+  const sum = (values: Array<number | null>) => values.reduce<number>((a, v) => a + (v ?? 0), 0);
+  const incomeTotal = sum(monthIncomes.map((i) => i.amount));
+  const expenseTotal = sum(monthExpenses.map((e) => e.cost));
+
+  const byCategory = new Map<string, { budget: number; cost: number }>();
+  for (const expense of monthExpenses) {
+    const entry = byCategory.get(expense.category) ?? { budget: 0, cost: 0 };
+    entry.budget += expense.budget ?? 0;
+    entry.cost += expense.cost ?? 0;
+    byCategory.set(expense.category, entry);
+  }
+
+  const row = (cells: string[]) => `<tr>${cells.map((cell) => `<td>${cell}</td>`).join('')}</tr>`;
+  const incomeRows = monthIncomes.map((income) =>
+    row([
+      escapeHtml(types.find((t) => t.id === income.income_type_id)?.name ?? 'Unknown'),
+      money(income.budget),
+      money(income.amount),
+    ]),
+  );
+  const categoryRows = [...byCategory.entries()]
+    .sort(([a], [b]) => a.localeCompare(b))
+    .map(([category, totals]) => row([escapeHtml(category), money(totals.budget), money(totals.cost)]));
+  const expenseRows = [...monthExpenses]
+    .sort((a, b) => (a.order ?? 0) - (b.order ?? 0))
+    .map((expense) =>
+      row([
+        escapeHtml(expense.expense_name),
+        escapeHtml(expense.category),
+        money(expense.budget),
+        money(expense.cost),
+      ]),
+    );
+  const title = escapeHtml(month.name);
+
+  return c.html(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>${title}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #1f2937; }
+table { width: 100%; border-collapse: collapse; margin-bottom: 2rem; }
+th, td { text-align: left; padding: 0.35rem 0.5rem; border-bottom: 1px solid #e5e7eb; }
+td:nth-last-child(-n+2), th:nth-last-child(-n+2) { text-align: right; }
+.note { color: #6b7280; font-size: 0.875rem; }
+</style>
+</head>
+<body>
+<h1>${title}</h1>
+<p class="note">Read-only summary${month.is_closed ? ' of a closed month' : ''}.</p>
+<table>
+<tr><th>Income</th><td>${money(incomeTotal)}</td></tr>
+<tr><th>Expenses</th><td>${money(expenseTotal)}</td></tr>
+<tr><th>Balance</th><td>${money(incomeTotal - expenseTotal)}</td></tr>
+</table>
+<h2>Income</h2>
+<table>
+<tr><th>Type</th><th>Projected</th><th>Actual</th></tr>
+${incomeRows.join('\n')}
+</table>
+<h2>Categories</h2>
+<table>
+<tr><th>Category</th><th>Projected</th><th>Actual</th></tr>
+${categoryRows.join('\n')}
+</table>
+<h2>Expenses</h2>
+<table>
+<tr><th>Expense</th><th>Category</th><th>Projected</th><th>Actual</th></tr>
+${expenseRows.join('\n')}
+</table>
+</body>
+</html>`);
+});
+
+export default shareRoute;
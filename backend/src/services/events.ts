@@ -0,0 +1,42 @@
+/**
+ * In-process feed of data changes, streamed to clients by the events route.
+ */
+
+export type ChangeAction = 'created' | 'updated' | 'deleted';
+
+export interface ChangeEvent {
+  /** Collection that changed, e.g. `expenses` or `months`. */
+  resource: string;
+  action: ChangeAction;
+  /** Changed record, when the request was about a single one. */
+  id: number | null;
+  /** Month the change belongs to, when known. */
+  month_id: number | null;
+  /** `X-Client-Id` of the client that made the change, so it can skip its own. */
+  source: string | null;
+}
+
+type Listener = (event: ChangeEvent) => void;
+
+const listeners = new Set<Listener>();
+
+/**
+ * Call `listener` for every change until the returned function is called.
+ */
+export function subscribe(listener: Listener): () => void {
+  listeners.add(listener);
+  return () => {
+    listeners.delete(listener);
+  };
+}
+
+export function publish(event: ChangeEvent): void {
+  for (const listener of listeners) {
+    listener(event);
+  }
+}
+
+/** Number of connected clients. */
+export function subscriberCount(): number {
+  return listeners.size;
+}
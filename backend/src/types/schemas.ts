@@ -63,6 +63,13 @@ export const purchaseSchema = z.object({
   date: z.string().optional(),
 });
 
+/** ISO 4217 code such as "EUR"; null puts an entry back in the budget's own currency */
+const currencySchema = z
+  .string()
+  .regex(/^[A-Z]{3}$/, 'Currency must be a three-letter ISO 4217 code')
+  .nullable()
+  .optional();
+
 // ─── Expense Schemas ─────────────────────────────────────────────────────────
 
 export const expenseCreateSchema = z.object({
@@ -76,6 +83,7 @@ export const expenseCreateSchema = z.object({
   purchases: z.array(purchaseSchema).optional(),
   order: z.number().int().default(0),
   expense_date: z.string().optional(),
+  currency: currencySchema,
 });
 
 export const expenseUpdateSchema = z.object({
@@ -89,6 +97,21 @@ export const expenseUpdateSchema = z.object({
   purchases: z.array(purchaseSchema).optional(),
   order: z.number().int().optional(),
   expense_date: z.string().optional(),
+  currency: currencySchema,
+});
+
+/** Most expenses a single bulk request may create or update */
+const MAX_BULK_EXPENSES = 500;
+
+export const expenseBulkCreateSchema = z.object({
+  expenses: z.array(expenseCreateSchema).min(1).max(MAX_BULK_EXPENSES),
+});
+
+export const expenseBulkUpdateSchema = z.object({
+  updates: z
+    .array(expenseUpdateSchema.extend({ id: z.number().int().positive() }))
+    .min(1)
+    .max(MAX_BULK_EXPENSES),
 });
 
 export const expenseReorderSchema = z.object({
@@ -108,6 +131,7 @@ export const incomeCreateSchema = z.object({
   budget: z.number().default(0),
   amount: z.number().default(0),
   month_id: z.number().int().positive(),
+  currency: currencySchema,
 });
 
 export const incomeUpdateSchema = z.object({
@@ -116,6 +140,7 @@ export const incomeUpdateSchema = z.object({
   budget: z.number().optional(),
   amount: z.number().optional(),
   month_id: z.number().int().positive().optional(),
+  currency: currencySchema,
 });
 
 // ─── Category Schemas ────────────────────────────────────────────────────────
@@ -167,6 +192,11 @@ export const monthUpdateSchema = z.object({
   name: z.string().optional(),
   start_date: z.string().optional(),
   end_date: z.string().optional(),
+  notes: z.string().max(5000).nullable().optional(),
+});
+
+export const monthShareSchema = z.object({
+  expires_in_hours: z.number().int().min(1).max(720).optional(),
 });
 
 // ─── Summary Insights Schemas ───────────────────────────────────────────────
@@ -0,0 +1,15 @@
+/**
+ * Opting in to changes in a closed month.
+ */
+
+/** Header a client sends to change entries in a closed month anyway. */
+export const ALLOW_CLOSED_MONTH_HEADER = 'X-Allow-Closed-Month';
+
+/**
+ * Check if the request asks to change a closed month anyway.
+ * Closing a month guards against slips, not against its own users, so a
+ * client that confirmed the change can send the header.
+ */
+export function allowsClosedMonth(headerValue: string | undefined): boolean {
+  return headerValue?.trim().toLowerCase() === 'true';
+}
@@ -0,0 +1,30 @@
+/**
+ * Optional limit/offset paging for list endpoints.
+ */
+
+/** Largest page a client can ask for. */
+export const MAX_PAGE_SIZE = 1000;
+
+export interface PageParams {
+  limit: number;
+  offset: number;
+}
+
+/**
+ * Read `limit` and `offset` from the query string.
+ * Returns null when no valid limit is given, so the whole list is returned.
+ */
+export function parsePageParams(
+  limitParam: string | undefined,
+  offsetParam: string | undefined,
+): PageParams | null {
+  const limit = limitParam ? parseInt(limitParam, 10) : NaN;
+  if (!Number.isFinite(limit) || limit <= 0) {
+    return null;
+  }
+  const offset = offsetParam ? parseInt(offsetParam, 10) : 0;
+  return {
+    limit: Math.min(limit, MAX_PAGE_SIZE),
+    offset: Number.isFinite(offset) && offset > 0 ? offset : 0,
+  };
+}
@@ -284,6 +284,69 @@ describe('SQLite Backups (Hono client)', () => {
     }
   });
 
+  test('POST /api/v1/backups/upload-restore upgrades a legacy database through later migrations', async () => {
+    const dlRes = await app.request(`/api/v1/backups/${createdFilename}/download`, {
+      headers: apiHeaders(adminToken),
+    });
+    expect(dlRes.status).toBe(200);
+
+    // A database from before month notes: no journal and none of the later columns
+    const tempPath = `${process.env.DATABASE_PATH}.pre-notes-upload.db`;
+    writeFileSync(tempPath, Buffer.from(await dlRes.arrayBuffer()));
+    const legacyDb = new Database(tempPath);
+    try {
+      legacyDb.exec('DROP TABLE IF EXISTS __drizzle_migrations');
+      legacyDb.exec('ALTER TABLE months DROP COLUMN notes');
+      legacyDb.exec('ALTER TABLE expenses DROP COLUMN currency');
+      legacyDb.exec('ALTER TABLE incomes DROP COLUMN currency');
+    } finally {
+      legacyDb.close();
+    }
+
+    const formData = new FormData();
+    formData.append('file', new File([readFileSync(tempPath)], 'pre-notes-backup.db'));
+    try {
+      const headers = apiHeaders(adminToken);
+      delete headers['Content-Type'];
+
+      const res = await app.request('/api/v1/backups/upload-restore', {
+        method: 'POST',
+        headers,
+        body: formData,
+      });
+      expect(res.status).toBe(200);
+
+      const restoredDb = new Database(process.env.DATABASE_PATH!);
+      try {
+        const columns = restoredDb
+          .query<{ name: string }, []>('PRAGMA table_info(months)')
+          .all()
+          .map((row) => row.name);
+        expect(columns).toContain('notes');
+      } finally {
+        restoredDb.close();
+      }
+
+      // The upgraded month takes notes again
+      const months = (await (await app.request('/api/v1/months', { headers: apiHeaders() })).json()) as Array<{
+        id: number;
+      }>;
+      const noted = await app.request(`/api/v1/months/${months[0].id}`, {
+        method: 'PUT',
+        headers: apiHeaders(),
+        body: JSON.stringify({ notes: 'Restored' }),
+      });
+      expect(noted.status).toBe(200);
+      expect(((await noted.json()) as { notes: string }).notes).toBe('Restored');
+    } finally {
+      try {
+        unlinkSync(tempPath);
+      } catch {
+        // ignore cleanup failure
+      }
+    }
+  });
+
   test('POST /api/v1/backups/upload-restore rejects non-.db files', async () => {
     const formData = new FormData();
     formData.append('file', new File(['not a db'], 'backup.txt'));
@@ -0,0 +1,251 @@
+import { describe, test, expect, beforeAll } from 'bun:test';
+import { getApp } from './setup';
+import { apiHeaders, seedMonth, seedPeriod, seedCategory, seedExpense, seedIncomeType, seedIncome } from './helpers';
+import type { Hono } from 'hono';
+import { Database } from 'bun:sqlite';
+import { readFileSync } from 'node:fs';
+import { resolve } from 'node:path';
+
+let app: Hono;
+let closedMonthId: number;
+let expenseId: number;
+let incomeId: number;
+let periodName: string;
+let categoryName: string;
+let incomeTypeId: number;
+
+/** Headers for changing a closed month anyway */
+function overrideHeaders(value = 'true') {
+  return { ...apiHeaders(), 'X-Allow-Closed-Month': value };
+}
+
+beforeAll(async () => {
+  app = await getApp();
+  const period = await seedPeriod(app, 'Closed-Period');
+  const category = await seedCategory(app, 'Closed-Category');
+  const incomeType = await seedIncomeType(app, 'Closed-Salary');
+  periodName = period.name;
+  categoryName = category.name;
+  incomeTypeId = incomeType.id;
+
+  const month = await seedMonth(app, 2018, 4);
+  closedMonthId = month.id;
+  const expense = await seedExpense(app, closedMonthId, {
+    expense_name: 'Before closing',
+    period: periodName,
+    category: categoryName,
+    budget: 20,
+  });
+  expenseId = expense.id;
+  const income = await seedIncome(app, closedMonthId, { income_type_id: incomeTypeId, period: periodName });
+  incomeId = income.id;
+  await app.request(`/api/v1/months/${closedMonthId}/close`, { method: 'POST', headers: apiHeaders() });
+});
+
+describe('Closed months', () => {
+  test('adding an expense or income is rejected', async () => {
+    const expenseRes = await app.request('/api/v1/expenses', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        expense_name: 'Too late',
+        period: periodName,
+        category: categoryName,
+        month_id: closedMonthId,
+      }),
+    });
+    expect(expenseRes.status).toBe(400);
+    expect(((await expenseRes.json()) as { detail: string }).detail).toBe(
+      "Cannot add expense: Month 'April 2018' is closed",
+    );
+
+    const incomeRes = await app.request('/api/v1/incomes', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({ income_type_id: incomeTypeId, period: periodName, month_id: closedMonthId }),
+    });
+    expect(incomeRes.status).toBe(400);
+  });
+
+  test('changing, paying or deleting is rejected', async () => {
+    const patch = await app.request(`/api/v1/expenses/${expenseId}`, {
+      method: 'PATCH',
+      headers: apiHeaders(),
+      body: JSON.stringify({ cost: 5 }),
+    });
+    expect(patch.status).toBe(400);
+
+    const pay = await app.request(`/api/v1/expenses/${expenseId}/pay`, {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({}),
+    });
+    expect(pay.status).toBe(400);
+
+    const incomePatch = await app.request(`/api/v1/incomes/${incomeId}`, {
+      method: 'PATCH',
+      headers: apiHeaders(),
+      body: JSON.stringify({ amount: 5 }),
+    });
+    expect(incomePatch.status).toBe(400);
+
+    const del = await app.request(`/api/v1/expenses/${expenseId}`, {
+      method: 'DELETE',
+      headers: apiHeaders(),
+    });
+    expect(del.status).toBe(400);
+
+    const unchanged = await app.request(`/api/v1/expenses/${expenseId}`, { headers: apiHeaders() });
+    expect(((await unchanged.json()) as { cost: number }).cost).toBe(0);
+  });
+
+  test('only a true override header lets changes through', async () => {
+    for (const value of ['false', 'yes', '']) {
+      const res = await app.request(`/api/v1/expenses/${expenseId}`, {
+        method: 'PATCH',
+        headers: overrideHeaders(value),
+        body: JSON.stringify({ cost: 5 }),
+      });
+      expect(res.status).toBe(400);
+    }
+  });
+
+  test('the override header changes a closed month anyway', async () => {
+    const created = await app.request('/api/v1/expenses', {
+      method: 'POST',
+      headers: overrideHeaders(),
+      body: JSON.stringify({
+        expense_name: 'Late receipt',
+        period: periodName,
+        category: categoryName,
+        month_id: closedMonthId,
+      }),
+    });
+    expect(created.status).toBe(201);
+    const { id } = (await created.json()) as { id: number };
+
+    const patch = await app.request(`/api/v1/expenses/${expenseId}`, {
+      method: 'PATCH',
+      headers: overrideHeaders('TRUE'),
+      body: JSON.stringify({ cost: 7 }),
+    });
+    expect(patch.status).toBe(200);
+    expect(((await patch.json()) as { cost: number }).cost).toBe(7);
+
+    const pay = await app.request(`/api/v1/expenses/${expenseId}/pay`, {
+      method: 'POST',
+      headers: overrideHeaders(),
+      body: JSON.stringify({ amount: 3 }),
+    });
+    expect(pay.status).toBe(200);
+
+    const incomePatch = await app.request(`/api/v1/incomes/${incomeId}`, {
+      method: 'PATCH',
+      headers: overrideHeaders(),
+      body: JSON.stringify({ amount: 900 }),
+    });
+    expect(incomePatch.status).toBe(200);
+
+    const del = await app.request(`/api/v1/expenses/${id}`, {
+      method: 'DELETE',
+      headers: overrideHeaders(),
+    });
+    expect(del.status).toBe(200);
+
+    // The month stays closed
+    const month = await app.request(`/api/v1/months/${closedMonthId}`, { headers: apiHeaders() });
+    expect(((await month.json()) as { is_closed: boolean }).is_closed).toBe(true);
+  });
+});
+
+describe('Month notes', () => {
+  let monthId: number;
+
+  async function putNotes(notes: string | null) {
+    return app.request(`/api/v1/months/${monthId}`, {
+      method: 'PUT',
+      headers: apiHeaders(),
+      body: JSON.stringify({ notes }),
+    });
+  }
+
+  beforeAll(async () => {
+    const month = await seedMonth(app, 2018, 5);
+    monthId = month.id;
+  });
+
+  test('a new month has no notes', async () => {
+    const res = await app.request(`/api/v1/months/${monthId}`, { headers: apiHeaders() });
+    expect(((await res.json()) as { notes: string | null }).notes).toBeNull();
+  });
+
+  test('notes are saved and returned with the month', async () => {
+    const res = await putNotes('Car insurance renews in June.\nAsk for the loyalty discount.');
+    expect(res.status).toBe(200);
+    const data = (await res.json()) as { notes: string; name: string };
+    expect(data.notes).toBe('Car insurance renews in June.\nAsk for the loyalty discount.');
+    expect(data.name).toBe('May 2018');
+
+    const fetched = await app.request(`/api/v1/months/${monthId}`, { headers: apiHeaders() });
+    expect(((await fetched.json()) as { notes: string }).notes).toContain('loyalty discount');
+  });
+
+  test('changing other fields keeps the notes', async () => {
+    await putNotes('Keep me');
+    const res = await app.request(`/api/v1/months/${monthId}`, {
+      method: 'PUT',
+      headers: apiHeaders(),
+      body: JSON.stringify({ name: 'May 2018' }),
+    });
+    expect(((await res.json()) as { notes: string }).notes).toBe('Keep me');
+  });
+
+  test('blank or null notes clear them', async () => {
+    await putNotes('Something');
+    expect(((await (await putNotes('   ')).json()) as { notes: string | null }).notes).toBeNull();
+
+    await putNotes('Something');
+    expect(((await (await putNotes(null)).json()) as { notes: string | null }).notes).toBeNull();
+  });
+
+  test('notes over 5000 characters are rejected', async () => {
+    expect((await putNotes('x'.repeat(5000))).status).toBe(200);
+    expect((await putNotes('x'.repeat(5001))).status).toBe(400);
+  });
+
+  test('a closed month keeps taking notes', async () => {
+    const res = await app.request(`/api/v1/months/${closedMonthId}`, {
+      method: 'PUT',
+      headers: apiHeaders(),
+      body: JSON.stringify({ notes: 'Closed, but the refund is still due' }),
+    });
+    expect(res.status).toBe(200);
+  });
+});
+
+describe('Migrations', () => {
+  test('the pre-migration test database was upgraded at startup', () => {
+    const journal = JSON.parse(
+      readFileSync(resolve(import.meta.dir, '../backend/drizzle/meta/_journal.json'), 'utf-8'),
+    ) as { entries: unknown[] };
+
+    const sqlite = new Database(process.env.DATABASE_PATH!, { readonly: true });
+    try {
+      const columns = (table: string) =>
+        sqlite
+          .query<{ name: string }, []>(`PRAGMA table_info(${table})`)
+          .all()
+          .map((row) => row.name);
+      expect(columns('months')).toContain('notes');
+      expect(columns('expenses')).toContain('currency');
+      expect(columns('incomes')).toContain('currency');
+
+      const applied = sqlite
+        .query<{ count: number }, []>('SELECT count(*) AS count FROM __drizzle_migrations')
+        .get();
+      expect(applied?.count).toBe(journal.entries.length);
+    } finally {
+      sqlite.close();
+    }
+  });
+});
@@ -0,0 +1,167 @@
+import { describe, test, expect, beforeAll, afterAll } from 'bun:test';
+import { getApp, startServer, stopServer } from './setup';
+import { apiHeaders, seedMonth, seedPeriod, seedCategory } from './helpers';
+import type { Hono } from 'hono';
+
+let app: Hono;
+let baseUrl: string;
+let monthId: number;
+let periodName: string;
+let categoryName: string;
+
+type ChangeEvent = {
+  resource: string;
+  action: string;
+  id: number | null;
+  month_id: number | null;
+  source: string | null;
+};
+
+/** Connect to the event stream; `next` waits for the next change event. */
+async function openEvents() {
+  const controller = new AbortController();
+  const response = fetch(`${baseUrl}/api/v1/events`, {
+    headers: apiHeaders(),
+    signal: controller.signal,
+  });
+  // Give the stream time to subscribe before anything is written
+  await Bun.sleep(100);
+
+  let reader: ReadableStreamDefaultReader<Uint8Array> | null = null;
+  const decoder = new TextDecoder();
+  let buffer = '';
+
+  async function next(): Promise<ChangeEvent> {
+    reader ??= (await response).body!.getReader();
+    while (true) {
+      const end = buffer.indexOf('\n\n');
+      if (end >= 0) {
+        const frame = buffer.slice(0, end);
+        buffer = buffer.slice(end + 2);
+        const data = frame.split('\n').find((line) => line.startsWith('data: '));
+        // Keepalive comments carry no event
+        if (frame.includes('event: change') && data) {
+          return JSON.parse(data.slice('data: '.length)) as ChangeEvent;
+        }
+        continue;
+      }
+      const { value, done } = await reader.read();
+      if (done) throw new Error('The event stream ended');
+      buffer += decoder.decode(value, { stream: true });
+    }
+  }
+
+  return { response, next, close: () => controller.abort() };
+}
+
+beforeAll(async () => {
+  app = await getApp();
+  baseUrl = await startServer();
+  const period = await seedPeriod(app, 'Events-Period');
+  const category = await seedCategory(app, 'Events-Category');
+  const month = await seedMonth(app, 2018, 3);
+  monthId = month.id;
+  periodName = period.name;
+  categoryName = category.name;
+});
+
+afterAll(async () => {
+  await stopServer();
+});
+
+describe('Change events (HTTP)', () => {
+  test('the stream needs the API key', async () => {
+    const res = await fetch(`${baseUrl}/api/v1/events`);
+    expect(res.status).toBe(403);
+  });
+
+  test('the stream is server-sent events', async () => {
+    const events = await openEvents();
+    const res = await events.response;
+    expect(res.status).toBe(200);
+    expect(res.headers.get('content-type')).toContain('text/event-stream');
+    events.close();
+  });
+
+  test('a write is delivered to the stream', async () => {
+    const events = await openEvents();
+
+    const res = await fetch(`${baseUrl}/api/v1/expenses`, {
+      method: 'POST',
+      headers: { ...apiHeaders(), 'X-Client-Id': 'events-test' },
+      body: JSON.stringify({
+        expense_name: 'Announced',
+        period: periodName,
+        category: categoryName,
+        month_id: monthId,
+      }),
+    });
+    expect(res.status).toBe(201);
+    const created = (await res.json()) as { id: number };
+
+    expect(await events.next()).toEqual({
+      resource: 'expenses',
+      action: 'created',
+      id: created.id,
+      month_id: monthId,
+      source: 'events-test',
+    });
+    events.close();
+  });
+
+  test('updates and deletes are announced; failed writes are not', async () => {
+    const created = (await (
+      await fetch(`${baseUrl}/api/v1/expenses`, {
+        method: 'POST',
+        headers: apiHeaders(),
+        body: JSON.stringify({
+          expense_name: 'Changed',
+          period: periodName,
+          category: categoryName,
+          month_id: monthId,
+        }),
+      })
+    ).json()) as { id: number };
+    const events = await openEvents();
+
+    const missing = await fetch(`${baseUrl}/api/v1/expenses/99999`, {
+      method: 'PATCH',
+      headers: apiHeaders(),
+      body: JSON.stringify({ cost: 1 }),
+    });
+    expect(missing.status).toBe(404);
+
+    await fetch(`${baseUrl}/api/v1/expenses/${created.id}`, {
+      method: 'PATCH',
+      headers: apiHeaders(),
+      body: JSON.stringify({ cost: 12 }),
+    });
+    await fetch(`${baseUrl}/api/v1/expenses/${created.id}`, {
+      method: 'DELETE',
+      headers: apiHeaders(),
+    });
+
+    expect(await events.next()).toMatchObject({ resource: 'expenses', action: 'updated', id: created.id });
+    expect(await events.next()).toMatchObject({ resource: 'expenses', action: 'deleted', id: created.id });
+    events.close();
+  });
+
+  test('closing a month is announced as an update of it', async () => {
+    const month = await seedMonth(app, 2018, 9);
+    const events = await openEvents();
+
+    const res = await fetch(`${baseUrl}/api/v1/months/${month.id}/close`, {
+      method: 'POST',
+      headers: apiHeaders(),
+    });
+    expect(res.status).toBe(200);
+
+    expect(await events.next()).toMatchObject({
+      resource: 'months',
+      action: 'updated',
+      id: month.id,
+      month_id: month.id,
+    });
+    events.close();
+  });
+});
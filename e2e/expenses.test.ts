@@ -46,6 +46,47 @@ describe('Expenses (Hono client)', () => {
     expect(data.cost).toBe(150);
   });
 
+  test('an expense keeps its currency until it is cleared', async () => {
+    const res = await app.request('/api/v1/expenses', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        expense_name: 'Hotel',
+        period: periodName,
+        category: categoryName,
+        cost: 120,
+        month_id: monthId,
+        currency: 'EUR',
+      }),
+    });
+    expect(res.status).toBe(201);
+    const created = (await res.json()) as { id: number; currency: string | null };
+    expect(created.currency).toBe('EUR');
+
+    const cleared = await app.request(`/api/v1/expenses/${created.id}`, {
+      method: 'PATCH',
+      headers: apiHeaders(),
+      body: JSON.stringify({ currency: null }),
+    });
+    expect(cleared.status).toBe(200);
+    expect(((await cleared.json()) as { currency: string | null }).currency).toBeNull();
+  });
+
+  test('create expense with an invalid currency fails', async () => {
+    const res = await app.request('/api/v1/expenses', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        expense_name: 'Souvenirs',
+        period: periodName,
+        category: categoryName,
+        month_id: monthId,
+        currency: 'euro',
+      }),
+    });
+    expect(res.status).toBe(400);
+  });
+
   test('create expense with purchases calculates cost', async () => {
     const res = await app.request('/api/v1/expenses', {
       method: 'POST',
@@ -133,6 +174,83 @@ describe('Expenses (Hono client)', () => {
     expect(getRes.status).toBe(404);
   });
 
+  test('PATCH changes only the fields sent', async () => {
+    const res = await app.request('/api/v1/expenses', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        expense_name: 'Phone',
+        period: periodName,
+        category: categoryName,
+        budget: 40,
+        notes: 'Family plan',
+        purchases: [{ name: 'Bill', amount: 35 }],
+        month_id: monthId,
+      }),
+    });
+    const created = (await res.json()) as { id: number };
+
+    const patch = await app.request(`/api/v1/expenses/${created.id}`, {
+      method: 'PATCH',
+      headers: apiHeaders(),
+      body: JSON.stringify({ budget: 45 }),
+    });
+    expect(patch.status).toBe(200);
+    const data = (await patch.json()) as Record<string, unknown>;
+    expect(data).toMatchObject({
+      expense_name: 'Phone',
+      budget: 45,
+      cost: 35,
+      notes: 'Family plan',
+      purchases: [{ name: 'Bill', amount: 35 }],
+    });
+  });
+
+  test('PUT replaces every field it is sent', async () => {
+    const res = await app.request('/api/v1/expenses', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        expense_name: 'Gym',
+        period: periodName,
+        category: categoryName,
+        budget: 30,
+        notes: 'Annual',
+        purchases: [{ name: 'Fee', amount: 30 }],
+        month_id: monthId,
+      }),
+    });
+    const created = (await res.json()) as { id: number };
+
+    const put = await app.request(`/api/v1/expenses/${created.id}`, {
+      method: 'PUT',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        expense_name: 'Pool',
+        period: periodName,
+        category: categoryName,
+        budget: 20,
+        cost: 12,
+        notes: '',
+        purchases: [],
+        month_id: monthId,
+        order: 3,
+        expense_date: '2025-05-02',
+      }),
+    });
+    expect(put.status).toBe(200);
+    const data = (await put.json()) as Record<string, unknown>;
+    expect(data).toMatchObject({
+      expense_name: 'Pool',
+      budget: 20,
+      cost: 12,
+      notes: '',
+      purchases: null,
+      order: 3,
+      expense_date: '2025-05-02',
+    });
+  });
+
   test('cannot add expense to closed month', async () => {
     const closedMonth = await seedMonth(app, 2020, 1);
     await app.request(`/api/v1/months/${closedMonth.id}/close`, {
@@ -198,6 +316,113 @@ describe('Expenses (Hono client)', () => {
   });
 });
 
+describe('Bulk expenses', () => {
+  let bulkMonthId: number;
+
+  const bulkItem = (name: string, month = bulkMonthId) => ({
+    expense_name: name,
+    period: periodName,
+    category: categoryName,
+    budget: 10,
+    month_id: month,
+  });
+
+  const namesInMonth = async (month: number) => {
+    const res = await app.request(`/api/v1/expenses?month_id=${month}`, { headers: apiHeaders() });
+    const data = (await res.json()) as Array<{ expense_name: string }>;
+    return data.map((e) => e.expense_name);
+  };
+
+  beforeAll(async () => {
+    const month = await seedMonth(app, 2019, 1);
+    bulkMonthId = month.id;
+  });
+
+  test('bulk create returns the expenses in request order', async () => {
+    const res = await app.request('/api/v1/expenses/bulk', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({ expenses: [bulkItem('Bulk A'), bulkItem('Bulk B')] }),
+    });
+    expect(res.status).toBe(201);
+    const data = (await res.json()) as Array<{ id: number; expense_name: string; order: number }>;
+    expect(data.map((e) => e.expense_name)).toEqual(['Bulk A', 'Bulk B']);
+    expect(data[1].order).toBe(data[0].order + 1);
+    expect(await namesInMonth(bulkMonthId)).toEqual(['Bulk A', 'Bulk B']);
+  });
+
+  test('bulk update changes only the fields sent', async () => {
+    const created = (await (
+      await app.request('/api/v1/expenses/bulk', {
+        method: 'POST',
+        headers: apiHeaders(),
+        body: JSON.stringify({ expenses: [bulkItem('Bulk C'), bulkItem('Bulk D')] }),
+      })
+    ).json()) as Array<{ id: number }>;
+
+    const res = await app.request('/api/v1/expenses/bulk', {
+      method: 'PUT',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        updates: [
+          { id: created[0].id, cost: 4 },
+          { id: created[1].id, expense_name: 'Bulk D2' },
+        ],
+      }),
+    });
+    expect(res.status).toBe(200);
+    const data = (await res.json()) as Array<{ expense_name: string; cost: number; budget: number }>;
+    expect(data[0]).toMatchObject({ expense_name: 'Bulk C', cost: 4, budget: 10 });
+    expect(data[1]).toMatchObject({ expense_name: 'Bulk D2', cost: 0, budget: 10 });
+  });
+
+  test('bulk requests take at most 500 expenses', async () => {
+    const items = Array.from({ length: 501 }, (_, i) => bulkItem(`Too many ${i}`));
+    const res = await app.request('/api/v1/expenses/bulk', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({ expenses: items }),
+    });
+    expect(res.status).toBe(400);
+    expect((await namesInMonth(bulkMonthId)).some((name) => name.startsWith('Too many'))).toBe(false);
+  });
+
+  test('a rejected batch writes nothing', async () => {
+    const closedMonth = await seedMonth(app, 2019, 2);
+    await app.request(`/api/v1/months/${closedMonth.id}/close`, {
+      method: 'POST',
+      headers: apiHeaders(),
+    });
+    const before = await namesInMonth(bulkMonthId);
+
+    const res = await app.request('/api/v1/expenses/bulk', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        expenses: [bulkItem('Never written'), bulkItem('Closed', closedMonth.id)],
+      }),
+    });
+    expect(res.status).toBe(400);
+    expect(await namesInMonth(bulkMonthId)).toEqual(before);
+
+    const [existing] = (await (
+      await app.request(`/api/v1/expenses?month_id=${bulkMonthId}`, { headers: apiHeaders() })
+    ).json()) as Array<{ id: number; expense_name: string }>;
+    const updateRes = await app.request('/api/v1/expenses/bulk', {
+      method: 'PUT',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        updates: [
+          { id: existing.id, expense_name: 'Never renamed' },
+          { id: 999999, cost: 1 },
+        ],
+      }),
+    });
+    expect(updateRes.status).toBe(404);
+    expect(await namesInMonth(bulkMonthId)).toEqual(before);
+  });
+});
+
 describe('Expenses (HTTP)', () => {
   let baseUrl: string;
 
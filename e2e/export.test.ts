@@ -0,0 +1,76 @@
+import { describe, test, expect, beforeAll } from 'bun:test';
+import { getApp } from './setup';
+import { apiHeaders, seedMonth, seedPeriod, seedCategory, seedExpense, seedIncomeType, seedIncome } from './helpers';
+import type { Hono } from 'hono';
+
+let app: Hono;
+let monthId: number;
+
+beforeAll(async () => {
+  app = await getApp();
+  const period = await seedPeriod(app, 'Export-Period');
+  const category = await seedCategory(app, 'Export-Category');
+  const incomeType = await seedIncomeType(app, 'Export-Salary');
+  const month = await seedMonth(app, 2018, 7);
+  monthId = month.id;
+
+  await seedExpense(app, monthId, {
+    expense_name: 'Rent',
+    period: period.name,
+    category: category.name,
+    budget: 1200,
+    cost: 1200,
+  });
+  await seedExpense(app, monthId, {
+    expense_name: 'Dinner, "the good one"',
+    period: period.name,
+    category: category.name,
+    budget: 80,
+    cost: 92.5,
+  });
+  await seedIncome(app, monthId, {
+    income_type_id: incomeType.id,
+    period: period.name,
+    budget: 3000,
+    amount: 3100,
+  });
+});
+
+describe('Month export', () => {
+  test('exports the month as a CSV download', async () => {
+    const res = await app.request(`/api/v1/months/${monthId}/export`, { headers: apiHeaders() });
+    expect(res.status).toBe(200);
+    expect(res.headers.get('content-type')).toBe('text/csv; charset=utf-8');
+    expect(res.headers.get('content-disposition')).toBe('attachment; filename="budget-2018-07.csv"');
+  });
+
+  test('has a header row, then expenses and incomes', async () => {
+    const res = await app.request(`/api/v1/months/${monthId}/export`, { headers: apiHeaders() });
+    const text = await res.text();
+
+    expect(text.endsWith('\r\n')).toBe(true);
+    expect(text.trimEnd().split('\r\n')).toEqual([
+      'month,type,name,category,period,projected,actual',
+      '2018-07,expense,Rent,Export-Category,Export-Period,1200,1200',
+      '2018-07,expense,"Dinner, ""the good one""",Export-Category,Export-Period,80,92.5',
+      '2018-07,income,Export-Salary,,Export-Period,3000,3100',
+    ]);
+  });
+
+  test('an empty month exports just the header', async () => {
+    const empty = await seedMonth(app, 2018, 10);
+    const res = await app.request(`/api/v1/months/${empty.id}/export`, { headers: apiHeaders() });
+    expect(res.status).toBe(200);
+    expect(await res.text()).toBe('month,type,name,category,period,projected,actual\r\n');
+  });
+
+  test('exporting a missing month fails', async () => {
+    const res = await app.request('/api/v1/months/99999/export', { headers: apiHeaders() });
+    expect(res.status).toBe(404);
+  });
+
+  test('export needs the API key', async () => {
+    const res = await app.request(`/api/v1/months/${monthId}/export`);
+    expect(res.status).toBe(403);
+  });
+});
@@ -0,0 +1,122 @@
+import { describe, test, expect, beforeAll } from 'bun:test';
+import { getApp } from './setup';
+import { apiHeaders, registerAndGetToken, seedMonth, seedPeriod, seedCategory } from './helpers';
+import type { Hono } from 'hono';
+
+let app: Hono;
+let monthId: number;
+let periodName: string;
+let categoryName: string;
+
+function expense(name: string) {
+  return { expense_name: name, period: periodName, category: categoryName, cost: 5, month_id: monthId };
+}
+
+function postExpense(body: unknown, key: string, headers = apiHeaders()) {
+  return app.request('/api/v1/expenses', {
+    method: 'POST',
+    headers: { ...headers, 'Idempotency-Key': key },
+    body: JSON.stringify(body),
+  });
+}
+
+async function countNamed(name: string) {
+  const res = await app.request(`/api/v1/expenses?month_id=${monthId}`, { headers: apiHeaders() });
+  const data = (await res.json()) as Array<{ expense_name: string }>;
+  return data.filter((e) => e.expense_name === name).length;
+}
+
+beforeAll(async () => {
+  app = await getApp();
+  const period = await seedPeriod(app, 'Idem-Period');
+  const category = await seedCategory(app, 'Idem-Category');
+  const month = await seedMonth(app, 2018, 2);
+  monthId = month.id;
+  periodName = period.name;
+  categoryName = category.name;
+});
+
+describe('Idempotency-Key', () => {
+  test('a repeated POST gets the stored response without writing again', async () => {
+    const first = await postExpense(expense('Replayed'), 'replay-1');
+    expect(first.status).toBe(201);
+    expect(first.headers.get('Idempotent-Replayed')).toBeNull();
+    const created = (await first.json()) as { id: number };
+
+    const again = await postExpense(expense('Replayed'), 'replay-1');
+    expect(again.status).toBe(201);
+    expect(again.headers.get('Idempotent-Replayed')).toBe('true');
+    expect(again.headers.get('content-type')).toContain('application/json');
+    expect(((await again.json()) as { id: number }).id).toBe(created.id);
+
+    expect(await countNamed('Replayed')).toBe(1);
+  });
+
+  test('a failed request is not stored, so the key can be retried', async () => {
+    const failed = await postExpense({ ...expense('Retried'), month_id: 99999 }, 'retry-1');
+    expect(failed.status).toBe(400);
+
+    const retried = await postExpense(expense('Retried'), 'retry-1');
+    expect(retried.status).toBe(201);
+    expect(retried.headers.get('Idempotent-Replayed')).toBeNull();
+    expect(await countNamed('Retried')).toBe(1);
+  });
+
+  test('a repeat while the first is still running gets 409', async () => {
+    // Hold the first request open by not finishing its body yet
+    let finish!: () => void;
+    const body = new ReadableStream<Uint8Array>({
+      start(controller) {
+        controller.enqueue(new TextEncoder().encode(JSON.stringify(expense('In flight'))));
+        finish = () => controller.close();
+      },
+    });
+    const first = app.request('/api/v1/expenses', {
+      method: 'POST',
+      headers: { ...apiHeaders(), 'Idempotency-Key': 'in-flight-1' },
+      body,
+      duplex: 'half',
+    } as RequestInit);
+    await Bun.sleep(20);
+
+    const repeat = await postExpense(expense('In flight'), 'in-flight-1');
+    expect(repeat.status).toBe(409);
+    expect(((await repeat.json()) as { detail: string }).detail).toContain('still in progress');
+
+    finish();
+    expect((await first).status).toBe(201);
+    expect(await countNamed('In flight')).toBe(1);
+  });
+
+  test('keys are scoped to the caller', async () => {
+    const token = await registerAndGetToken(app, 'idempotency@example.com', 'idempass123', 'Idem User');
+
+    const asKey = await postExpense(expense('Per caller'), 'shared-key');
+    const asUser = await postExpense(expense('Per caller'), 'shared-key', apiHeaders(token));
+    expect(asKey.status).toBe(201);
+    expect(asUser.status).toBe(201);
+    expect(asUser.headers.get('Idempotent-Replayed')).toBeNull();
+
+    expect(await countNamed('Per caller')).toBe(2);
+  });
+
+  test('keys are scoped to the endpoint', async () => {
+    const expenseRes = await postExpense(expense('Per endpoint'), 'endpoint-key');
+    expect(expenseRes.status).toBe(201);
+
+    const categoryRes = await app.request('/api/v1/categories', {
+      method: 'POST',
+      headers: { ...apiHeaders(), 'Idempotency-Key': 'endpoint-key' },
+      body: JSON.stringify({ name: 'Idem-Other', color: '#8b5cf6' }),
+    });
+    expect(categoryRes.status).toBe(201);
+    expect(categoryRes.headers.get('Idempotent-Replayed')).toBeNull();
+    expect(((await categoryRes.json()) as { name: string }).name).toBe('Idem-Other');
+  });
+
+  test('an overlong key is rejected', async () => {
+    const res = await postExpense(expense('Long key'), 'k'.repeat(256));
+    expect(res.status).toBe(400);
+    expect(await countNamed('Long key')).toBe(0);
+  });
+});
@@ -44,6 +44,26 @@ describe('Incomes (Hono client)', () => {
     expect(data.amount).toBe(5200);
   });
 
+  test('an income keeps its currency', async () => {
+    const res = await app.request('/api/v1/incomes', {
+      method: 'POST',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        income_type_id: incomeTypeId,
+        period: periodName,
+        amount: 800,
+        month_id: monthId,
+        currency: 'GBP',
+      }),
+    });
+    expect(res.status).toBe(201);
+    const created = (await res.json()) as { id: number; currency: string | null };
+    expect(created.currency).toBe('GBP');
+
+    const fetched = await app.request(`/api/v1/incomes/${created.id}`, { headers: apiHeaders() });
+    expect(((await fetched.json()) as { currency: string | null }).currency).toBe('GBP');
+  });
+
   test('create income for non-existent month fails', async () => {
     const res = await app.request('/api/v1/incomes', {
       method: 'POST',
@@ -103,6 +123,47 @@ describe('Incomes (Hono client)', () => {
     expect(data.amount).toBe(2500);
   });
 
+  test('PATCH changes only the fields sent', async () => {
+    const income = await seedIncome(app, monthId, {
+      income_type_id: incomeTypeId,
+      period: periodName,
+      budget: 3000,
+      amount: 2900,
+    });
+    const res = await app.request(`/api/v1/incomes/${income.id}`, {
+      method: 'PATCH',
+      headers: apiHeaders(),
+      body: JSON.stringify({ amount: 3100 }),
+    });
+    expect(res.status).toBe(200);
+    const data = (await res.json()) as { amount: number; budget: number; period: string; month_id: number };
+    expect(data).toMatchObject({ amount: 3100, budget: 3000, period: periodName, month_id: monthId });
+  });
+
+  test('PUT replaces every field it is sent', async () => {
+    const income = await seedIncome(app, monthId, {
+      income_type_id: incomeTypeId,
+      period: periodName,
+      budget: 3000,
+      amount: 2900,
+    });
+    const otherType = await seedIncomeType(app, 'Inc-Bonus');
+    const res = await app.request(`/api/v1/incomes/${income.id}`, {
+      method: 'PUT',
+      headers: apiHeaders(),
+      body: JSON.stringify({
+        income_type_id: otherType.id,
+        period: periodName,
+        budget: 0,
+        amount: 0,
+        month_id: monthId,
+      }),
+    });
+    expect(res.status).toBe(200);
+    const data = (await res.json()) as { income_type_id: number; amount: number; budget: number };
+    expect(data).toMatchObject({ income_type_id: otherType.id, amount: 0, budget: 0 });
+  });
+
   test('delete an income', async () => {
     const income = await seedIncome(app, monthId, {
       income_type_id: incomeTypeId,
@@ -0,0 +1,105 @@
+import { describe, test, expect, beforeAll } from 'bun:test';
+import { getApp } from './setup';
+import { apiHeaders, seedMonth, seedPeriod, seedCategory, seedIncomeType, seedIncome } from './helpers';
+import type { Hono } from 'hono';
+
+let app: Hono;
+let monthId: number;
+let periodName: string;
+let categoryName: string;
+
+async function createExpenses(month: number, names: string[]) {
+  const res = await app.request('/api/v1/expenses/bulk', {
+    method: 'POST',
+    headers: apiHeaders(),
+    body: JSON.stringify({
+      expenses: names.map((expense_name) => ({
+        expense_name,
+        period: periodName,
+        category: categoryName,
+        month_id: month,
+      })),
+    }),
+  });
+  expect(res.status).toBe(201);
+}
+
+async function expenseNames(query: string) {
+  const res = await app.request(`/api/v1/expenses?month_id=${monthId}&${query}`, { headers: apiHeaders() });
+  expect(res.status).toBe(200);
+  return ((await res.json()) as Array<{ expense_name: string }>).map((e) => e.expense_name);
+}
+
+beforeAll(async () => {
+  app = await getApp();
+  const period = await seedPeriod(app, 'Page-Period');
+  const category = await seedCategory(app, 'Page-Category');
+  periodName = period.name;
+  categoryName = category.name;
+  const month = await seedMonth(app, 2018, 8);
+  monthId = month.id;
+  await createExpenses(monthId, ['P1', 'P2', 'P3', 'P4', 'P5']);
+});
+
+describe('Pagination', () => {
+  test('without a limit the whole list comes back', async () => {
+    expect(await expenseNames('')).toEqual(['P1', 'P2', 'P3', 'P4', 'P5']);
+  });
+
+  test('limit and offset pick one page', async () => {
+    expect(await expenseNames('limit=2')).toEqual(['P1', 'P2']);
+    expect(await expenseNames('limit=2&offset=2')).toEqual(['P3', 'P4']);
+    expect(await expenseNames('limit=2&offset=4')).toEqual(['P5']);
+  });
+
+  test('an offset past the end gives an empty page', async () => {
+    expect(await expenseNames('limit=2&offset=5')).toEqual([]);
+    expect(await expenseNames('limit=2&offset=1000')).toEqual([]);
+  });
+
+  test('a limit that is not a positive number returns the whole list', async () => {
+    for (const limit of ['0', '-3', 'abc', '']) {
+      expect(await expenseNames(`limit=${limit}&offset=2`)).toEqual(['P1', 'P2', 'P3', 'P4', 'P5']);
+    }
+  });
+
+  test('an offset that is not a positive number starts at the beginning', async () => {
+    for (const offset of ['-1', 'abc', '0']) {
+      expect(await expenseNames(`limit=2&offset=${offset}`)).toEqual(['P1', 'P2']);
+    }
+  });
+
+  test('limits above 1000 are capped at 1000', async () => {
+    const big = await seedMonth(app, 2018, 11);
+    for (let start = 0; start < 1001; start += 500) {
+      const count = Math.min(500, 1001 - start);
+      await createExpenses(
+        big.id,
+        Array.from({ length: count }, (_, i) => `Row ${String(start + i).padStart(4, '0')}`),
+      );
+    }
+
+    const res = await app.request(`/api/v1/expenses?month_id=${big.id}&limit=5000`, { headers: apiHeaders() });
+    expect(((await res.json()) as unknown[]).length).toBe(1000);
+
+    const all = await app.request(`/api/v1/expenses?month_id=${big.id}`, { headers: apiHeaders() });
+    expect(((await all.json()) as unknown[]).length).toBe(1001);
+  });
+
+  test('incomes page the same way', async () => {
+    const incomeType = await seedIncomeType(app, 'Page-Salary');
+    for (const amount of [1, 2, 3]) {
+      await seedIncome(app, monthId, { income_type_id: incomeType.id, period: periodName, amount });
+    }
+    const page = async (query: string) => {
+      const res = await app.request(`/api/v1/incomes?month_id=${monthId}&${query}`, { headers: apiHeaders() });
+      return ((await res.json()) as Array<{ amount: number }>).map((i) => i.amount);
+    };
+
+    expect(await page('')).toHaveLength(3);
+    expect(await page('limit=2')).toHaveLength(2);
+    expect(await page('limit=2&offset=2')).toHaveLength(1);
+    expect(await page('limit=2&offset=3')).toEqual([]);
+    expect(await page('limit=nope')).toHaveLength(3);
+  });
+});
@@ -0,0 +1,108 @@
+import { describe, test, expect, beforeAll, afterEach, setSystemTime } from 'bun:test';
+import { getApp } from './setup';
+import { apiHeaders, registerAndGetToken, seedMonth, seedPeriod, seedCategory, seedExpense } from './helpers';
+import type { Hono } from 'hono';
+
+let app: Hono;
+let monthId: number;
+
+type ShareLink = { token: string; path: string; url: string; month_id: number; expires_at: string };
+
+async function share(id: number, body: Record<string, unknown> = {}) {
+  return app.request(`/api/v1/months/${id}/share`, {
+    method: 'POST',
+    headers: apiHeaders(),
+    body: JSON.stringify(body),
+  });
+}
+
+beforeAll(async () => {
+  app = await getApp();
+  const period = await seedPeriod(app, 'Share-Period');
+  const category = await seedCategory(app, 'R&D <Team>');
+  const month = await seedMonth(app, 2018, 1);
+  monthId = month.id;
+  await seedExpense(app, monthId, {
+    expense_name: '<script>alert("x")</script>',
+    period: period.name,
+    category: category.name,
+    budget: 50,
+    cost: 42,
+  });
+});
+
+afterEach(() => {
+  setSystemTime();
+});
+
+describe('Share links', () => {
+  test('create a link to a month', async () => {
+    const res = await share(monthId);
+    expect(res.status).toBe(200);
+    const data = (await res.json()) as ShareLink;
+    expect(data.month_id).toBe(monthId);
+    expect(data.path).toBe(`/share/${data.token}`);
+    expect(data.url.endsWith(data.path)).toBe(true);
+
+    // A week by default
+    const hours = (new Date(data.expires_at).getTime() - Date.now()) / 3_600_000;
+    expect(hours).toBeGreaterThan(7 * 24 - 1);
+    expect(hours).toBeLessThanOrEqual(7 * 24);
+  });
+
+  test('the shared page opens without an API key', async () => {
+    const { path } = (await (await share(monthId)).json()) as ShareLink;
+
+    const res = await app.request(path);
+    expect(res.status).toBe(200);
+    expect(res.headers.get('content-type')).toContain('text/html');
+    expect(res.headers.get('cache-control')).toBe('no-store');
+    const html = await res.text();
+    expect(html).toContain('<h1>January 2018</h1>');
+    expect(html).toContain('42.00');
+  });
+
+  test('names are escaped on the shared page', async () => {
+    const { path } = (await (await share(monthId)).json()) as ShareLink;
+
+    const html = await (await app.request(path)).text();
+    expect(html).toContain('&lt;script&gt;alert(&quot;x&quot;)&lt;/script&gt;');
+    expect(html).toContain('R&amp;D &lt;Team&gt;');
+    expect(html).not.toContain('<script>');
+    expect(html).not.toContain('<Team>');
+  });
+
+  test('a link stops working when it expires', async () => {
+    const { path, expires_at } = (await (await share(monthId, { expires_in_hours: 1 })).json()) as ShareLink;
+    expect(new Date(expires_at).getTime() - Date.now()).toBeLessThanOrEqual(3_600_000);
+    expect((await app.request(path)).status).toBe(200);
+
+    setSystemTime(new Date(Date.now() + 2 * 3_600_000));
+    const res = await app.request(path);
+    expect(res.status).toBe(404);
+    expect(await res.text()).toContain('invalid or has expired');
+  });
+
+  test('a login token does not open a shared page', async () => {
+    const token = await registerAndGetToken(app, 'share@example.com', 'sharepass123', 'Share User');
+
+    const res = await app.request(`/share/${token}`);
+    expect(res.status).toBe(404);
+    expect(await res.text()).not.toContain('January 2018');
+  });
+
+  test('a made-up token does not open a shared page', async () => {
+    const res = await app.request('/share/not-a-token');
+    expect(res.status).toBe(404);
+  });
+
+  test('expiry outside 1 to 720 hours is rejected', async () => {
+    expect((await share(monthId, { expires_in_hours: 0 })).status).toBe(400);
+    expect((await share(monthId, { expires_in_hours: 721 })).status).toBe(400);
+  });
+
+  test('sharing a missing month fails', async () => {
+    const res = await share(99999);
+    expect(res.status).toBe(404);
+  });
+});
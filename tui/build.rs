@@ -0,0 +1,20 @@
+//! Embeds the app version, so the binary knows it wherever it is installed
+//!
+//! `BUDGET_VERSION` set for the build wins, for packaging from a release tag;
+//! otherwise it's the repository's VERSION file, and the crate's own version
+//! when built outside the repository.
+
+use std::fs;
+
+fn main() {
+    println!("cargo:rerun-if-env-changed=BUDGET_VERSION");
+    println!("cargo:rerun-if-changed=../VERSION");
+
+    let version = std::env::var("BUDGET_VERSION")
+        .ok()
+        .or_else(|| fs::read_to_string("../VERSION").ok())
+        .map(|version| version.trim().trim_start_matches('v').to_string())
+        .filter(|version| !version.is_empty())
+        .unwrap_or_else(|| std::env::var("CARGO_PKG_VERSION").unwrap_or_default());
+    println!("cargo:rustc-env=BUDGET_VERSION={}", version);
+}
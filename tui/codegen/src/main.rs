@@ -0,0 +1,55 @@
+//! `budget-codegen [SPEC] [--check]`
+//!
+//! Writes the SDK's generated models and operations from SPEC (the backend's
+//! `openapi.json` by default). With `--check`, only reports whether the files
+//! are up to date.
+
+use std::path::PathBuf;
+use std::process::ExitCode;
+
+fn main() -> ExitCode {
+    let args: Vec<String> = std::env::args().skip(1).collect();
+    let check = args.iter().any(|arg| arg == "--check");
+    let spec_path = args
+        .iter()
+        .find(|arg| !arg.starts_with("--"))
+        .map(PathBuf::from)
+        .unwrap_or_else(budget_codegen::spec_path);
+
+    let generated = match std::fs::read_to_string(&spec_path)
+        .map_err(|e| format!("Failed to read {}: {}", spec_path.display(), e))
+        .and_then(|spec| budget_codegen::generate(&spec))
+    {
+        Ok(generated) => generated,
+        Err(e) => {
+            eprintln!("{}", e);
+            return ExitCode::FAILURE;
+        }
+    };
+
+    let (models_path, operations_path) = budget_codegen::output_paths();
+    let mut stale = false;
+    for (path, code) in [
+        (models_path, &generated.models),
+        (operations_path, &generated.operations),
+    ] {
+        if std::fs::read_to_string(&path).ok().as_ref() == Some(code) {
+            continue;
+        }
+        stale = true;
+        if check {
+            eprintln!("{} is out of date", path.display());
+        } else if let Err(e) = std::fs::write(&path, code) {
+            eprintln!("Failed to write {}: {}", path.display(), e);
+            return ExitCode::FAILURE;
+        } else {
+            println!("Wrote {}", path.display());
+        }
+    }
+
+    if check && stale {
+        eprintln!("Run `make tui-generate` to update them");
+        return ExitCode::FAILURE;
+    }
+    ExitCode::SUCCESS
+}
@@ -0,0 +1,89 @@
+use std::collections::BTreeSet;
+
+use crate::json::Json;
+use crate::types::{doc_comment, field_name, rust_type};
+
+/// Structs for the schemas in `used`, in the order the spec lists them
+pub fn generate(spec: &Json, used: &BTreeSet<String>) -> Result<String, String> {
+    let schemas = spec
+        .get("components")
+        .and_then(|c| c.get("schemas"))
+        .map(Json::entries)
+        .unwrap_or_default();
+
+    let mut code = String::from("use serde::{Deserialize, Serialize};\n");
+    for (name, schema) in schemas {
+        if used.contains(name) {
+            code.push('\n');
+            code.push_str(&generate_struct(name, schema).map_err(|e| format!("{}: {}", name, e))?);
+        }
+    }
+    Ok(code)
+}
+
+fn generate_struct(name: &str, schema: &Json) -> Result<String, String> {
+    if schema.get("type").and_then(Json::as_str) != Some("object") {
+        return Err("only object schemas are supported".to_string());
+    }
+    let required: Vec<&str> = schema
+        .get("required")
+        .map(Json::items)
+        .unwrap_or_default()
+        .iter()
+        .filter_map(Json::as_str)
+        .collect();
+
+    let mut fields = String::new();
+    let mut all_optional = true;
+    for (property, property_schema) in schema
+        .get("properties")
+        .map(Json::entries)
+        .unwrap_or_default()
+    {
+        let field = field_name(property)?;
+        let (rust, nullable) =
+            rust_type(property_schema).map_err(|e| format!("{}: {}", property, e))?;
+        if let Some(description) = property_schema.get("description").and_then(Json::as_str) {
+            fields.push_str(&doc_comment(description, "    "));
+        }
+
+        if required.contains(&property.as_str()) {
+            all_optional = false;
+            if nullable {
+                fields.push_str(&format!("    pub {}: Option<{}>,\n", field, rust));
+            } else {
+                fields.push_str(&format!("    pub {}: {},\n", field, rust));
+            }
+        } else if let Some(default) = property_schema.get("default") {
+            // Only the type's own default can be expressed as `#[serde(default)]`
+            let zero = match default {
+                Json::Bool(value) => !value,
+                Json::Number(value) => *value == 0.0,
+                Json::String(value) => value.is_empty(),
+                Json::Array(items) => items.is_empty(),
+                _ => false,
+            };
+            if !zero || nullable {
+                return Err(format!("{}: unsupported default {:?}", property, default));
+            }
+            fields.push_str("    #[serde(default)]\n");
+            fields.push_str(&format!("    pub {}: {},\n", field, rust));
+        } else {
+            fields.push_str("    #[serde(skip_serializing_if = \"Option::is_none\")]\n");
+            fields.push_str(&format!("    pub {}: Option<{}>,\n", field, rust));
+        }
+    }
+
+    let mut code = String::new();
+    if let Some(description) = schema.get("description").and_then(Json::as_str) {
+        code.push_str(&doc_comment(description, ""));
+    }
+    // Types with nothing required can be built with `..Default::default()`
+    if all_optional {
+        code.push_str("#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]\n");
+    } else {
+        code.push_str("#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]\n");
+    }
+    code.push_str(&format!("pub struct {} {{\n{}}}\n", name, fields));
+    Ok(code)
+}
@@ -0,0 +1,256 @@
+use std::collections::BTreeSet;
+
+use crate::json::Json;
+use crate::types::{doc_comment, field_name, rust_type, schema_name, snake_case};
+
+const METHODS: &[&str] = &["get", "post", "put", "patch", "delete"];
+
+/// One `Operations` method per operation in the spec
+pub struct Operations {
+    pub code: String,
+    /// Schemas the methods take or return, and the schemas those refer to
+    pub schemas: BTreeSet<String>,
+}
+
+pub fn generate(spec: &Json) -> Result<Operations, String> {
+    let mut methods = String::new();
+    let mut names = BTreeSet::new();
+    let mut direct = BTreeSet::new();
+
+    for (path, item) in spec.get("paths").map(Json::entries).unwrap_or_default() {
+        let shared = item.get("parameters").map(Json::items).unwrap_or_default();
+        for (method, operation) in item.entries() {
+            if !METHODS.contains(&method.as_str()) {
+                continue;
+            }
+            let id = operation
+                .get("operationId")
+                .and_then(Json::as_str)
+                .ok_or_else(|| format!("{} {} has no operationId", method, path))?;
+            let name = snake_case(id);
+            if !names.insert(name.clone()) {
+                return Err(format!("operationId '{}' is used twice", id));
+            }
+            let parameters: Vec<&Json> = shared
+                .iter()
+                .chain(
+                    operation
+                        .get("parameters")
+                        .map(Json::items)
+                        .unwrap_or_default(),
+                )
+                .collect();
+            methods.push_str(
+                &generate_method(&name, method, path, operation, &parameters, &mut direct)
+                    .map_err(|e| format!("{}: {}", id, e))?,
+            );
+        }
+    }
+
+    let schemas = with_referenced(spec, &direct)?;
+    let mut code = String::from("use crate::api::client::{ApiClient, ApiError};\n");
+    if !direct.is_empty() {
+        let imports: Vec<&str> = direct.iter().map(String::as_str).collect();
+        code.push_str(&format!("use crate::models::{{{}}};\n", imports.join(", ")));
+    }
+    code.push_str(
+        "
+/// Every operation in the server's OpenAPI spec, named by its `operationId`
+pub struct Operations<'a> {
+    client: &'a ApiClient,
+}
+
+impl<'a> Operations<'a> {
+    pub fn new(client: &'a ApiClient) -> Self {
+        Self { client }
+    }
+",
+    );
+    code.push_str(&methods);
+    code.push_str("}\n");
+    Ok(Operations { code, schemas })
+}
+
+fn generate_method(
+    name: &str,
+    method: &str,
+    path: &str,
+    operation: &Json,
+    parameters: &[&Json],
+    schemas: &mut BTreeSet<String>,
+) -> Result<String, String> {
+    let mut arguments = Vec::new();
+    let mut path_args = Vec::new();
+    let mut query = Vec::new();
+    for parameter in parameters {
+        let parameter_name = parameter
+            .get("name")
+            .and_then(Json::as_str)
+            .ok_or("parameter has no name")?;
+        let argument = field_name(parameter_name)?;
+        let schema = parameter.get("schema").ok_or("parameter has no schema")?;
+        let (rust, _) = rust_type(schema)?;
+        match parameter.get("in").and_then(Json::as_str) {
+            Some("path") => {
+                arguments.push(format!("{}: {}", argument, rust));
+                path_args.push((parameter_name.to_string(), argument));
+            }
+            Some("query") if method == "get" => {
+                if parameter.get("required").and_then(Json::as_bool) == Some(true) {
+                    arguments.push(format!("{}: {}", argument, rust));
+                    query.push((parameter_name.to_string(), argument, true));
+                } else {
+                    arguments.push(format!("{}: Option<{}>", argument, rust));
+                    query.push((parameter_name.to_string(), argument, false));
+                }
+            }
+            Some(location) => return Err(format!("unsupported parameter in '{}'", location)),
+            None => return Err(format!("parameter '{}' has no location", parameter_name)),
+        }
+    }
+
+    // `/months/{id}/close` as `format!("/months/{}/close", id)`
+    let mut template = path.to_string();
+    let mut format_args = Vec::new();
+    for (parameter_name, argument) in &path_args {
+        let placeholder = format!("{{{}}}", parameter_name);
+        if !template.contains(&placeholder) {
+            return Err(format!("path has no {}", placeholder));
+        }
+        template = template.replace(&placeholder, "{}");
+        format_args.push(argument.as_str());
+    }
+    let endpoint = if format_args.is_empty() {
+        format!("\"{}\"", template)
+    } else {
+        format!("&format!(\"{}\", {})", template, format_args.join(", "))
+    };
+
+    let body = match operation.get("requestBody") {
+        Some(request) => {
+            let schema = json_schema(request).ok_or("request body isn't JSON")?;
+            let (rust, _) = rust_type(schema)?;
+            collect_refs(schema, schemas)?;
+            arguments.push(format!("body: &{}", rust));
+            "body"
+        }
+        None => "&()",
+    };
+
+    let response = success_schema(operation);
+    let returns = match (response, method) {
+        (Some(schema), _) => {
+            collect_refs(schema, schemas)?;
+            rust_type(schema)?.0
+        }
+        (None, "delete") => "()".to_string(),
+        (None, _) => return Err("no JSON success response".to_string()),
+    };
+
+    let call = match method {
+        "get" if !query.is_empty() => {
+            let mut lines = String::from("        let mut params = Vec::new();\n");
+            for (parameter_name, argument, required) in &query {
+                if *required {
+                    lines.push_str(&format!(
+                        "        params.push((\"{}\", {}.to_string()));\n",
+                        parameter_name, argument
+                    ));
+                } else {
+                    lines.push_str(&format!(
+                        "        if let Some({}) = {} {{\n            params.push((\"{}\", {}.to_string()));\n        }}\n",
+                        argument, argument, parameter_name, argument
+                    ));
+                }
+            }
+            format!(
+                "{}        self.client.get_with_params({}, &params).await\n",
+                lines, endpoint
+            )
+        }
+        "get" => format!("        self.client.get({}).await\n", endpoint),
+        "delete" => format!("        self.client.delete({}).await\n", endpoint),
+        "post" | "put" | "patch" => {
+            format!(
+                "        self.client.{}({}, {}).await\n",
+                method, endpoint, body
+            )
+        }
+        _ => unreachable!(),
+    };
+
+    let mut code = String::from("\n");
+    if let Some(summary) = operation.get("summary").and_then(Json::as_str) {
+        code.push_str(&doc_comment(summary, "    "));
+    }
+    if let Some(description) = operation.get("description").and_then(Json::as_str) {
+        code.push_str("    ///\n");
+        code.push_str(&doc_comment(description, "    "));
+    }
+    let mut signature = vec!["&self".to_string()];
+    signature.extend(arguments);
+    code.push_str(&format!(
+        "    pub async fn {}({}) -> Result<{}, ApiError> {{\n{}    }}\n",
+        name,
+        signature.join(", "),
+        returns,
+        call
+    ));
+    Ok(code)
+}
+
+/// Schema of a request or response's `application/json` content
+fn json_schema(content_holder: &Json) -> Option<&Json> {
+    content_holder
+        .get("content")?
+        .get("application/json")?
+        .get("schema")
+}
+
+/// Schema of the first 2xx response with a JSON body
+fn success_schema(operation: &Json) -> Option<&Json> {
+    operation
+        .get("responses")?
+        .entries()
+        .iter()
+        .filter(|(status, _)| status.starts_with('2'))
+        .find_map(|(_, response)| json_schema(response))
+}
+
+/// Add the schemas `schema` refers to, directly or through arrays
+fn collect_refs(schema: &Json, schemas: &mut BTreeSet<String>) -> Result<(), String> {
+    if let Some(reference) = schema.get("$ref").and_then(Json::as_str) {
+        schemas.insert(schema_name(reference)?.to_string());
+    }
+    if let Some(items) = schema.get("items") {
+        collect_refs(items, schemas)?;
+    }
+    Ok(())
+}
+
+/// `direct` plus every schema their properties refer to
+fn with_referenced(spec: &Json, direct: &BTreeSet<String>) -> Result<BTreeSet<String>, String> {
+    let mut all = direct.clone();
+    let mut pending: Vec<String> = direct.iter().cloned().collect();
+    while let Some(name) = pending.pop() {
+        let schema = spec
+            .get("components")
+            .and_then(|c| c.get("schemas"))
+            .and_then(|s| s.get(&name))
+            .ok_or_else(|| format!("Schema '{}' is referenced but not defined", name))?;
+        let mut found = BTreeSet::new();
+        for (_, property) in schema
+            .get("properties")
+            .map(Json::entries)
+            .unwrap_or_default()
+        {
+            collect_refs(property, &mut found)?;
+        }
+        for name in found {
+            if all.insert(name.clone()) {
+                pending.push(name);
+            }
+        }
+    }
+    Ok(all)
+}
@@ -0,0 +1,107 @@
+use crate::json::Json;
+
+/// Rust keywords a property or parameter could be named after
+const KEYWORDS: &[&str] = &[
+    "as", "async", "await", "break", "const", "continue", "crate", "dyn", "else", "enum", "extern",
+    "false", "fn", "for", "if", "impl", "in", "let", "loop", "match", "mod", "move", "mut", "pub",
+    "ref", "return", "self", "static", "struct", "super", "trait", "true", "type", "unsafe", "use",
+    "where", "while",
+];
+
+/// A schema's Rust type, and whether the schema also allows `null`
+pub fn rust_type(schema: &Json) -> Result<(String, bool), String> {
+    if let Some(reference) = schema.get("$ref").and_then(Json::as_str) {
+        return Ok((schema_name(reference)?.to_string(), false));
+    }
+
+    // `"type": ["string", "null"]` is a nullable string
+    let (kind, nullable) = match schema.get("type") {
+        Some(Json::String(kind)) => (kind.as_str(), false),
+        Some(Json::Array(kinds)) => {
+            let kinds: Vec<&str> = kinds.iter().filter_map(Json::as_str).collect();
+            match kinds.as_slice() {
+                [kind, "null"] | ["null", kind] => (*kind, true),
+                _ => return Err(format!("Unsupported type list {:?}", kinds)),
+            }
+        }
+        _ => return Err("Schema has no type".to_string()),
+    };
+
+    let format = schema.get("format").and_then(Json::as_str);
+    let rust = match (kind, format) {
+        ("string", _) => "String".to_string(),
+        ("boolean", _) => "bool".to_string(),
+        ("number", _) => "f64".to_string(),
+        ("integer", None | Some("int32")) => "i32".to_string(),
+        ("integer", Some("int64")) => "i64".to_string(),
+        ("integer", Some("uint32")) => "u32".to_string(),
+        ("integer", Some(format)) => {
+            return Err(format!("Unsupported integer format '{}'", format))
+        }
+        ("array", _) => {
+            let items = schema.get("items").ok_or("Array schema has no items")?;
+            let (item, item_nullable) = rust_type(items)?;
+            if item_nullable {
+                format!("Vec<Option<{}>>", item)
+            } else {
+                format!("Vec<{}>", item)
+            }
+        }
+        (kind, _) => return Err(format!("Unsupported type '{}'", kind)),
+    };
+    Ok((rust, nullable))
+}
+
+/// Name of the schema a `#/components/schemas/...` reference points at
+pub fn schema_name(reference: &str) -> Result<&str, String> {
+    reference
+        .strip_prefix("#/components/schemas/")
+        .ok_or_else(|| format!("Unsupported reference '{}'", reference))
+}
+
+/// A property or parameter name as a Rust identifier, e.g. `type` as `r#type`
+pub fn field_name(name: &str) -> Result<String, String> {
+    let valid = name
+        .chars()
+        .next()
+        .is_some_and(|c| c.is_ascii_lowercase() || c == '_')
+        && name
+            .chars()
+            .all(|c| c.is_ascii_lowercase() || c.is_ascii_digit() || c == '_');
+    if !valid {
+        return Err(format!("'{}' is not a snake_case name", name));
+    }
+    if KEYWORDS.contains(&name) {
+        return Ok(format!("r#{}", name));
+    }
+    Ok(name.to_string())
+}
+
+/// `getCurrentMonth` as `get_current_month`
+pub fn snake_case(name: &str) -> String {
+    let mut out = String::new();
+    for (index, c) in name.chars().enumerate() {
+        if c.is_ascii_uppercase() {
+            if index > 0 {
+                out.push('_');
+            }
+            out.push(c.to_ascii_lowercase());
+        } else if c == '-' {
+            out.push('_');
+        } else {
+            out.push(c);
+        }
+    }
+    out
+}
+
+/// A description as `///` lines at `indent`
+pub fn doc_comment(text: &str, indent: &str) -> String {
+    text.trim()
+        .lines()
+        .map(|line| match line.trim_end() {
+            "" => format!("{}///\n", indent),
+            line => format!("{}/// {}\n", indent, line),
+        })
+        .collect()
+}
@@ -0,0 +1,143 @@
+//! Code generation tests for budget-codegen
+
+use budget_codegen::{generate, output_paths, rust_type, snake_case, spec_path, Json};
+
+fn spec(paths: &str, schemas: &str) -> String {
+    format!(
+        r#"{{"openapi": "3.1.0", "paths": {{{}}}, "components": {{"schemas": {{{}}}}}}}"#,
+        paths, schemas
+    )
+}
+
+#[test]
+fn test_generated_files_match_spec() {
+    let spec = std::fs::read_to_string(spec_path()).unwrap();
+    let generated = generate(&spec).unwrap();
+
+    let (models_path, operations_path) = output_paths();
+    let models = std::fs::read_to_string(models_path).unwrap();
+    let operations = std::fs::read_to_string(operations_path).unwrap();
+    assert!(
+        models == generated.models && operations == generated.operations,
+        "The SDK's generated code doesn't match backend/src/openapi.json - run `make tui-generate`"
+    );
+}
+
+#[test]
+fn test_snake_case() {
+    assert_eq!(snake_case("getCurrentMonth"), "get_current_month");
+    assert_eq!(snake_case("list-income-types"), "list_income_types");
+    assert_eq!(snake_case("close"), "close");
+}
+
+#[test]
+fn test_rust_types() {
+    let rust = |schema: &str| rust_type(&Json::parse(schema).unwrap()).unwrap();
+
+    assert_eq!(rust(r#"{"type": "integer"}"#), ("i32".to_string(), false));
+    assert_eq!(
+        rust(r#"{"type": "integer", "format": "int64"}"#),
+        ("i64".to_string(), false)
+    );
+    assert_eq!(
+        rust(r#"{"type": ["string", "null"]}"#),
+        ("String".to_string(), true)
+    );
+    assert_eq!(
+        rust(r##"{"type": "array", "items": {"$ref": "#/components/schemas/Month"}}"##),
+        ("Vec<Month>".to_string(), false)
+    );
+    assert!(rust_type(&Json::parse(r#"{"type": "object"}"#).unwrap()).is_err());
+}
+
+#[test]
+fn test_generate_model_fields() {
+    let spec = spec(
+        r##""/notes": {"post": {"operationId": "createNote",
+            "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Note"}}}},
+            "responses": {"201": {"description": "", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Note"}}}}}}}"##,
+        r##""Unused": {"type": "object", "properties": {}},
+            "Note": {"type": "object", "description": "A note", "required": ["type", "text", "pinned_at"],
+              "properties": {
+                "type": {"type": "string"},
+                "text": {"type": "string", "description": "What it says"},
+                "pinned_at": {"type": ["string", "null"]},
+                "done": {"type": "boolean", "default": false},
+                "tags": {"type": "array", "items": {"$ref": "#/components/schemas/Tag"}}}},
+            "Tag": {"type": "object", "properties": {"name": {"type": "string"}}}"##,
+    );
+    let models = generate(&spec).unwrap().models;
+
+    assert!(models.starts_with("// @generated"));
+    // Only schemas the operations use, with what they refer to, in spec order
+    assert!(!models.contains("Unused"));
+    assert!(models.find("pub struct Note").unwrap() < models.find("pub struct Tag").unwrap());
+    assert!(
+        models.contains("/// A note\n#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]")
+    );
+    assert!(models.contains("    pub r#type: String,\n"));
+    assert!(models.contains("    /// What it says\n    pub text: String,\n"));
+    assert!(models.contains("    pub pinned_at: Option<String>,\n"));
+    assert!(models.contains("    #[serde(default)]\n    pub done: bool,\n"));
+    assert!(models.contains(
+        "    #[serde(skip_serializing_if = \"Option::is_none\")]\n    pub tags: Option<Vec<Tag>>,\n"
+    ));
+    // Nothing required, so it can be defaulted
+    assert!(models.contains(
+        "#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]\npub struct Tag"
+    ));
+}
+
+#[test]
+fn test_generate_operation_parameters() {
+    let spec = spec(
+        r##""/months/{id}/notes": {"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}}],
+            "get": {"operationId": "listNotes", "summary": "Get a month's notes",
+              "parameters": [{"name": "tag", "in": "query", "schema": {"type": "string"}}],
+              "responses": {"200": {"description": "", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Note"}}}}}}},
+            "delete": {"operationId": "deleteNotes", "responses": {"200": {"description": "Deleted"}}}}"##,
+        r#""Note": {"type": "object", "properties": {"text": {"type": "string"}}}"#,
+    );
+    let operations = generate(&spec).unwrap().operations;
+
+    assert!(operations.contains("use crate::models::Note;"));
+    assert!(operations.contains("    /// Get a month's notes\n"));
+    assert!(operations.contains(
+        "pub async fn list_notes(&self, id: i32, tag: Option<String>) -> Result<Vec<Note>, ApiError>"
+    ));
+    assert!(operations.contains(
+        "if let Some(tag) = tag {\n            params.push((\"tag\", tag.to_string()));"
+    ));
+    assert!(operations.contains(".get_with_params(&format!(\"/months/{}/notes\", id), &params)"));
+    assert!(
+        operations.contains("pub async fn delete_notes(&self, id: i32) -> Result<(), ApiError>")
+    );
+}
+
+#[test]
+fn test_generate_errors() {
+    let missing_id = spec(r#""/notes": {"get": {"responses": {}}}"#, "");
+    assert_eq!(
+        generate(&missing_id).unwrap_err(),
+        "get /notes has no operationId"
+    );
+
+    let no_body = spec(
+        r#""/notes": {"get": {"operationId": "listNotes", "responses": {"200": {"description": ""}}}}"#,
+        "",
+    );
+    assert_eq!(
+        generate(&no_body).unwrap_err(),
+        "listNotes: no JSON success response"
+    );
+
+    let undefined = spec(
+        r##""/notes": {"get": {"operationId": "listNotes", "responses": {"200": {"description": "",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Note"}}}}}}}"##,
+        "",
+    );
+    assert_eq!(
+        generate(&undefined).unwrap_err(),
+        "Schema 'Note' is referenced but not defined"
+    );
+}
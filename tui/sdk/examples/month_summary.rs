@@ -0,0 +1,37 @@
+//! Print the current month's totals and its five largest expenses
+//!
+//! ```bash
+//! BUDGET_API_URL=http://localhost:8000 BUDGET_API_KEY=secret \
+//!     cargo run -p budget-sdk --example month_summary
+//! ```
+
+use budget_sdk::api::ApiClient;
+use budget_sdk::models::ExpenseFilters;
+
+#[tokio::main]
+async fn main() -> anyhow::Result<()> {
+    let url = std::env::var("BUDGET_API_URL").unwrap_or("http://localhost:8000".to_string());
+    let key = std::env::var("BUDGET_API_KEY")?;
+    let api = ApiClient::new(url, key)?;
+
+    let month = api.months().get_current().await?;
+    let totals = api.summary().get_totals(None, Some(month.id)).await?;
+    println!(
+        "{}: income {:.2}, expenses {:.2}, balance {:.2}",
+        month.display_name(),
+        totals.total_current_income,
+        totals.total_current_expenses,
+        totals.total_current
+    );
+
+    let filters = ExpenseFilters {
+        month_id: Some(month.id),
+        ..Default::default()
+    };
+    let mut expenses = api.expenses().get_all(&filters).await?;
+    expenses.sort_by(|a, b| b.cost.total_cmp(&a.cost));
+    for expense in expenses.iter().take(5) {
+        println!("  {:<30} {:>10.2}", expense.expense_name, expense.cost);
+    }
+    Ok(())
+}
@@ -0,0 +1,32 @@
+use crate::api::client::{ApiClient, ApiError};
+use crate::models::{Account, AccountCreate, AccountUpdate};
+
+pub struct AccountsApi<'a> {
+    client: &'a ApiClient,
+}
+
+impl<'a> AccountsApi<'a> {
+    pub fn new(client: &'a ApiClient) -> Self {
+        Self { client }
+    }
+
+    /// Get all accounts
+    pub async fn get_all(&self) -> Result<Vec<Account>, ApiError> {
+        self.client.get("/accounts").await
+    }
+
+    /// Create a new account
+    pub async fn create(&self, account: &AccountCreate) -> Result<Account, ApiError> {
+        self.client.post("/accounts", account).await
+    }
+
+    /// Update an account
+    pub async fn update(&self, id: i32, account: &AccountUpdate) -> Result<Account, ApiError> {
+        self.client.put(&format!("/accounts/{}", id), account).await
+    }
+
+    /// Delete an account; its expenses and incomes are kept without one
+    pub async fn delete(&self, id: i32) -> Result<(), ApiError> {
+        self.client.delete(&format!("/accounts/{}", id)).await
+    }
+}
@@ -0,0 +1,172 @@
+use crate::api::client::{ApiClient, ApiError};
+use crate::models::{
+    AdminSetPassword, ChangePasswordRequest, ChangePasswordResponse, DeviceCode, DevicePoll,
+    DeviceTokenRequest, KeyScopes, LoginResponse, MessageResponse, TokenResponse, TotpVerify, User,
+    UserCreate, UserLogin, UserUpdate,
+};
+
+pub struct AuthApi<'a> {
+    client: &'a ApiClient,
+}
+
+impl<'a> AuthApi<'a> {
+    pub fn new(client: &'a ApiClient) -> Self {
+        Self { client }
+    }
+
+    /// Login with email and password
+    ///
+    /// Accounts with two-factor login get a challenge instead of a token;
+    /// finish with `verify_totp`.
+    pub async fn login(&self, email: &str, password: &str) -> Result<LoginResponse, ApiError> {
+        let body = UserLogin {
+            email: email.to_string(),
+            password: password.to_string(),
+        };
+        self.client.post("/auth/login", &body).await
+    }
+
+    /// Second login step: the current code from the authenticator app
+    ///
+    /// A wrong code fails with `Unauthorized` and can be retried; once the
+    /// challenge has expired it fails with `Expired` and the login has to
+    /// start over.
+    pub async fn verify_totp(
+        &self,
+        challenge: &str,
+        code: &str,
+    ) -> Result<TokenResponse, ApiError> {
+        let body = TotpVerify {
+            challenge: challenge.to_string(),
+            code: code.trim().to_string(),
+        };
+        self.client.post("/auth/login/totp", &body).await
+    }
+
+    /// Start a device login, for servers behind single sign-on
+    ///
+    /// Show the user `verification_uri` and `user_code`, then call
+    /// `poll_device_login` every `interval` seconds until it has an answer.
+    pub async fn start_device_login(&self) -> Result<DeviceCode, ApiError> {
+        self.client
+            .post("/auth/device/code", &serde_json::json!({}))
+            .await
+    }
+
+    /// Ask whether the device login has been approved yet
+    pub async fn poll_device_login(&self, device_code: &str) -> Result<DevicePoll, ApiError> {
+        let body = DeviceTokenRequest {
+            device_code: device_code.to_string(),
+        };
+        match self.client.post("/auth/device/token", &body).await {
+            Ok(token) => Ok(DevicePoll::Token(token)),
+            Err(ApiError::Expired) => Ok(DevicePoll::Expired),
+            Err(ApiError::BadRequest(message)) => match device_error(&message).as_str() {
+                "authorization_pending" => Ok(DevicePoll::Pending),
+                "slow_down" => Ok(DevicePoll::SlowDown),
+                "access_denied" => Ok(DevicePoll::Denied),
+                "expired_token" => Ok(DevicePoll::Expired),
+                _ => Err(ApiError::BadRequest(message)),
+            },
+            Err(e) => Err(e),
+        }
+    }
+
+    /// Get the current user
+    pub async fn me(&self) -> Result<User, ApiError> {
+        self.client.get("/auth/me").await
+    }
+
+    /// What the API key may change
+    ///
+    /// Servers without scoped keys answer `NotFound`; their keys may change
+    /// anything.
+    pub async fn key_scopes(&self) -> Result<KeyScopes, ApiError> {
+        self.client.get("/auth/key").await
+    }
+
+    /// Change password
+    pub async fn change_password(
+        &self,
+        current_password: &str,
+        new_password: &str,
+    ) -> Result<ChangePasswordResponse, ApiError> {
+        let body = ChangePasswordRequest {
+            current_password: current_password.to_string(),
+            new_password: new_password.to_string(),
+        };
+        self.client.post("/auth/change-password", &body).await
+    }
+
+    // Admin only: the server answers `Forbidden` for other accounts
+
+    /// List all accounts
+    pub async fn list_users(&self) -> Result<Vec<User>, ApiError> {
+        self.client.get("/auth/users").await
+    }
+
+    /// Get one account
+    pub async fn get_user(&self, id: i32) -> Result<User, ApiError> {
+        self.client.get(&format!("/auth/users/{}", id)).await
+    }
+
+    /// Create an account
+    pub async fn create_user(&self, user: &UserCreate) -> Result<User, ApiError> {
+        self.client.post("/auth/users", user).await
+    }
+
+    /// Change an account's email, name or flags
+    pub async fn update_user(&self, id: i32, update: &UserUpdate) -> Result<User, ApiError> {
+        self.client
+            .put(&format!("/auth/users/{}", id), update)
+            .await
+    }
+
+    /// Activate or deactivate an account; deactivated accounts can't log in
+    pub async fn set_user_active(&self, id: i32, active: bool) -> Result<User, ApiError> {
+        let update = UserUpdate {
+            is_active: Some(active),
+            ..Default::default()
+        };
+        self.update_user(id, &update).await
+    }
+
+    /// Grant or take away admin rights
+    pub async fn set_user_admin(&self, id: i32, admin: bool) -> Result<User, ApiError> {
+        let update = UserUpdate {
+            is_admin: Some(admin),
+            ..Default::default()
+        };
+        self.update_user(id, &update).await
+    }
+
+    /// Set a new password for an account without knowing the current one
+    pub async fn reset_user_password(
+        &self,
+        id: i32,
+        new_password: &str,
+    ) -> Result<MessageResponse, ApiError> {
+        let body = AdminSetPassword {
+            new_password: new_password.to_string(),
+        };
+        self.client
+            .post(&format!("/auth/users/{}/set-password", id), &body)
+            .await
+    }
+
+    /// Delete an account; the server refuses to delete your own
+    pub async fn delete_user(&self, id: i32) -> Result<(), ApiError> {
+        self.client.delete(&format!("/auth/users/{}", id)).await
+    }
+}
+
+/// Error code of a device login poll
+///
+/// The server sends it as `detail` like other errors, which the client has
+/// already unwrapped; an OAuth-style `{"error": ...}` body arrives whole.
+fn device_error(message: &str) -> String {
+    serde_json::from_str::<serde_json::Value>(message)
+        .ok()
+        .and_then(|body| Some(body.get("error")?.as_str()?.to_string()))
+        .unwrap_or_else(|| message.to_string())
+}
@@ -0,0 +1,328 @@
+use crate::api::client::{ApiClient, ApiError};
+use crate::api::range::{in_range, load_months, MonthData, MONTH_RANGE_PARALLELISM};
+use crate::models::{
+    Account, Category, CategorySummary, Debt, Expense, ExpenseBulkUpdate, ExpenseCreate,
+    ExpenseFilters, ExpenseUpdate, Income, IncomeCreate, IncomeFilters, IncomeType,
+    IncomeTypeSummary, IncomeUpdate, KeyScopes, Month, MonthCreate, Page, PageRequest,
+    PayExpenseRequest, Period, PeriodSummaryResponse, SummaryInsights, SummaryTotals, Transfer,
+};
+
+/// Budget data the dashboard views load and edit
+///
+/// `ApiClient` implements this against the server; `MockApi` keeps everything
+/// in memory so view logic can be tested without one. Only used with concrete
+/// types, so the futures don't need to be `Send`.
+#[allow(async_fn_in_trait)]
+pub trait BudgetApi {
+    /// Get all months
+    async fn get_months(&self) -> Result<Vec<Month>, ApiError>;
+
+    /// Get the month containing today
+    async fn get_current_month(&self) -> Result<Month, ApiError>;
+
+    /// Create a month
+    async fn create_month(&self, month: &MonthCreate) -> Result<Month, ApiError>;
+
+    /// Get all categories
+    async fn get_categories(&self) -> Result<Vec<Category>, ApiError>;
+
+    /// Get all periods
+    async fn get_periods(&self) -> Result<Vec<Period>, ApiError>;
+
+    /// Get all income types
+    async fn get_income_types(&self) -> Result<Vec<IncomeType>, ApiError>;
+
+    /// Get all accounts; `NotFound` on servers without accounts
+    async fn get_accounts(&self) -> Result<Vec<Account>, ApiError>;
+
+    /// Get the transfers between accounts of a month; `NotFound` on servers
+    /// without transfers
+    async fn get_transfers(&self, month_id: Option<i32>) -> Result<Vec<Transfer>, ApiError>;
+
+    /// Get all debts; `NotFound` on servers without debts
+    async fn get_debts(&self) -> Result<Vec<Debt>, ApiError>;
+
+    /// Get expenses matching the filters
+    async fn get_expenses(&self, filters: &ExpenseFilters) -> Result<Vec<Expense>, ApiError>;
+
+    /// Get incomes matching the filters
+    async fn get_incomes(&self, filters: &IncomeFilters) -> Result<Vec<Income>, ApiError>;
+
+    /// Get the current version of one expense
+    async fn get_expense(&self, id: i32) -> Result<Expense, ApiError>;
+
+    /// Get the current version of one income
+    async fn get_income(&self, id: i32) -> Result<Income, ApiError>;
+
+    /// Get one page of the expenses matching the filters
+    async fn get_expenses_page(
+        &self,
+        filters: &ExpenseFilters,
+        page: PageRequest,
+    ) -> Result<Page<Expense>, ApiError> {
+        let filters = ExpenseFilters {
+            page: Some(page),
+            ..filters.clone()
+        };
+        Ok(Page::from_items(self.get_expenses(&filters).await?, page))
+    }
+
+    /// Get one page of the incomes matching the filters
+    async fn get_incomes_page(
+        &self,
+        filters: &IncomeFilters,
+        page: PageRequest,
+    ) -> Result<Page<Income>, ApiError> {
+        let filters = IncomeFilters {
+            page: Some(page),
+            ..filters.clone()
+        };
+        Ok(Page::from_items(self.get_incomes(&filters).await?, page))
+    }
+
+    /// Get income and expense totals for a month
+    async fn get_summary_totals(&self, month_id: Option<i32>) -> Result<SummaryTotals, ApiError>;
+
+    /// Get expense totals per category for a month
+    async fn get_category_summary(
+        &self,
+        month_id: Option<i32>,
+    ) -> Result<Vec<CategorySummary>, ApiError>;
+
+    /// Get income totals per income type for a month
+    async fn get_income_type_summary(
+        &self,
+        month_id: Option<i32>,
+    ) -> Result<Vec<IncomeTypeSummary>, ApiError>;
+
+    /// Get income and expense totals per period for a month
+    async fn get_period_summary(
+        &self,
+        month_id: Option<i32>,
+    ) -> Result<PeriodSummaryResponse, ApiError>;
+
+    /// Get budget health insights for a month
+    async fn get_insights(&self, month_id: Option<i32>) -> Result<SummaryInsights, ApiError>;
+
+    /// Get what the API key may change; `NotFound` on servers without
+    /// scoped keys
+    async fn get_key_scopes(&self) -> Result<KeyScopes, ApiError>;
+
+    /// Get the expenses, incomes and summaries of every month from `from` to
+    /// `to` (inclusive, as `(year, month)`), oldest first
+    ///
+    /// `MONTH_RANGE_PARALLELISM` months are fetched at a time. Months the
+    /// server doesn't have are left out.
+    async fn get_month_range_data(
+        &self,
+        from: (i32, i32),
+        to: (i32, i32),
+    ) -> Result<Vec<MonthData>, ApiError> {
+        let mut months: Vec<Month> = self
+            .get_months()
+            .await?
+            .into_iter()
+            .filter(|month| in_range(month, from, to))
+            .collect();
+        months.sort_by_key(|month| (month.year, month.month));
+        load_months(self, months, MONTH_RANGE_PARALLELISM).await
+    }
+
+    /// Create an expense
+    async fn create_expense(&self, expense: &ExpenseCreate) -> Result<Expense, ApiError>;
+
+    /// Update an expense
+    async fn update_expense(&self, id: i32, expense: &ExpenseUpdate) -> Result<Expense, ApiError>;
+
+    /// Create many expenses, returned in the same order
+    async fn create_expenses_bulk(
+        &self,
+        expenses: &[ExpenseCreate],
+    ) -> Result<Vec<Expense>, ApiError> {
+        let mut created = Vec::with_capacity(expenses.len());
+        for expense in expenses {
+            created.push(self.create_expense(expense).await?);
+        }
+        Ok(created)
+    }
+
+    /// Update many expenses, returned in the same order
+    async fn update_expenses_bulk(
+        &self,
+        updates: &[ExpenseBulkUpdate],
+    ) -> Result<Vec<Expense>, ApiError> {
+        let mut updated = Vec::with_capacity(updates.len());
+        for update in updates {
+            updated.push(self.update_expense(update.id, &update.changes).await?);
+        }
+        Ok(updated)
+    }
+
+    /// Delete an expense
+    async fn delete_expense(&self, id: i32) -> Result<(), ApiError>;
+
+    /// Pay an expense (the projected amount unless an amount is given)
+    async fn pay_expense(
+        &self,
+        id: i32,
+        request: Option<&PayExpenseRequest>,
+    ) -> Result<Expense, ApiError>;
+
+    /// Create an income
+    async fn create_income(&self, income: &IncomeCreate) -> Result<Income, ApiError>;
+
+    /// Update an income
+    async fn update_income(&self, id: i32, income: &IncomeUpdate) -> Result<Income, ApiError>;
+
+    /// Delete an income
+    async fn delete_income(&self, id: i32) -> Result<(), ApiError>;
+}
+
+impl BudgetApi for ApiClient {
+    async fn get_months(&self) -> Result<Vec<Month>, ApiError> {
+        self.months().get_all().await
+    }
+
+    async fn get_current_month(&self) -> Result<Month, ApiError> {
+        self.months().get_current().await
+    }
+
+    async fn create_month(&self, month: &MonthCreate) -> Result<Month, ApiError> {
+        self.months().create(month).await
+    }
+
+    async fn get_categories(&self) -> Result<Vec<Category>, ApiError> {
+        self.categories().get_all().await
+    }
+
+    async fn get_periods(&self) -> Result<Vec<Period>, ApiError> {
+        self.periods().get_all().await
+    }
+
+    async fn get_income_types(&self) -> Result<Vec<IncomeType>, ApiError> {
+        self.income_types().get_all().await
+    }
+
+    async fn get_accounts(&self) -> Result<Vec<Account>, ApiError> {
+        self.accounts().get_all().await
+    }
+
+    async fn get_transfers(&self, month_id: Option<i32>) -> Result<Vec<Transfer>, ApiError> {
+        self.transfers().get_all(month_id).await
+    }
+
+    async fn get_debts(&self) -> Result<Vec<Debt>, ApiError> {
+        self.debts().get_all().await
+    }
+
+    async fn get_expenses(&self, filters: &ExpenseFilters) -> Result<Vec<Expense>, ApiError> {
+        self.expenses().get_all(filters).await
+    }
+
+    async fn get_incomes(&self, filters: &IncomeFilters) -> Result<Vec<Income>, ApiError> {
+        self.incomes().get_all(filters).await
+    }
+
+    async fn get_expenses_page(
+        &self,
+        filters: &ExpenseFilters,
+        page: PageRequest,
+    ) -> Result<Page<Expense>, ApiError> {
+        self.expenses().get_page(filters, page).await
+    }
+
+    async fn get_incomes_page(
+        &self,
+        filters: &IncomeFilters,
+        page: PageRequest,
+    ) -> Result<Page<Income>, ApiError> {
+        self.incomes().get_page(filters, page).await
+    }
+
+    async fn get_expense(&self, id: i32) -> Result<Expense, ApiError> {
+        self.expenses().get_by_id(id).await
+    }
+
+    async fn get_income(&self, id: i32) -> Result<Income, ApiError> {
+        self.incomes().get_by_id(id).await
+    }
+
+    async fn get_summary_totals(&self, month_id: Option<i32>) -> Result<SummaryTotals, ApiError> {
+        self.summary().get_totals(None, month_id).await
+    }
+
+    async fn get_category_summary(
+        &self,
+        month_id: Option<i32>,
+    ) -> Result<Vec<CategorySummary>, ApiError> {
+        self.categories().get_summary(month_id).await
+    }
+
+    async fn get_income_type_summary(
+        &self,
+        month_id: Option<i32>,
+    ) -> Result<Vec<IncomeTypeSummary>, ApiError> {
+        self.income_types().get_summary(None, month_id).await
+    }
+
+    async fn get_period_summary(
+        &self,
+        month_id: Option<i32>,
+    ) -> Result<PeriodSummaryResponse, ApiError> {
+        self.summary().get_by_period(month_id).await
+    }
+
+    async fn get_insights(&self, month_id: Option<i32>) -> Result<SummaryInsights, ApiError> {
+        self.summary().get_insights(month_id).await
+    }
+
+    async fn get_key_scopes(&self) -> Result<KeyScopes, ApiError> {
+        self.auth().key_scopes().await
+    }
+
+    async fn create_expense(&self, expense: &ExpenseCreate) -> Result<Expense, ApiError> {
+        self.expenses().create(expense).await
+    }
+
+    async fn update_expense(&self, id: i32, expense: &ExpenseUpdate) -> Result<Expense, ApiError> {
+        self.expenses().update(id, expense).await
+    }
+
+    async fn create_expenses_bulk(
+        &self,
+        expenses: &[ExpenseCreate],
+    ) -> Result<Vec<Expense>, ApiError> {
+        self.expenses().create_bulk(expenses).await
+    }
+
+    async fn update_expenses_bulk(
+        &self,
+        updates: &[ExpenseBulkUpdate],
+    ) -> Result<Vec<Expense>, ApiError> {
+        self.expenses().update_bulk(updates).await
+    }
+
+    async fn delete_expense(&self, id: i32) -> Result<(), ApiError> {
+        self.expenses().delete(id).await
+    }
+
+    async fn pay_expense(
+        &self,
+        id: i32,
+        request: Option<&PayExpenseRequest>,
+    ) -> Result<Expense, ApiError> {
+        self.expenses().pay(id, request).await
+    }
+
+    async fn create_income(&self, income: &IncomeCreate) -> Result<Income, ApiError> {
+        self.incomes().create(income).await
+    }
+
+    async fn update_income(&self, id: i32, income: &IncomeUpdate) -> Result<Income, ApiError> {
+        self.incomes().update(id, income).await
+    }
+
+    async fn delete_income(&self, id: i32) -> Result<(), ApiError> {
+        self.incomes().delete(id).await
+    }
+}
@@ -0,0 +1,26 @@
+use std::path::Path;
+
+use crate::api::client::{ApiClient, ApiError};
+
+pub struct BackupApi<'a> {
+    client: &'a ApiClient,
+}
+
+impl<'a> BackupApi<'a> {
+    pub fn new(client: &'a ApiClient) -> Self {
+        Self { client }
+    }
+
+    /// Download a JSON backup of all data to `dest`
+    ///
+    /// `progress` gets the bytes received so far and the total, if known.
+    pub async fn download(
+        &self,
+        dest: &Path,
+        progress: impl FnMut(u64, Option<u64>),
+    ) -> Result<u64, ApiError> {
+        self.client
+            .download("/backup/download", dest, progress)
+            .await
+    }
+}
@@ -0,0 +1,72 @@
+use std::collections::HashMap;
+
+/// Endpoints whose GET responses are cached and revalidated with ETags
+///
+/// These hold reference data that rarely changes but is fetched on every
+/// month switch.
+const CACHED_RESOURCES: &[&str] = &["/months", "/categories", "/periods", "/income-types"];
+
+/// A cached GET response body and the ETag the server sent with it
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct CachedResponse {
+    pub etag: String,
+    pub body: String,
+}
+
+/// GET response cache keyed by endpoint path (including the query string)
+///
+/// Entries are always revalidated with `If-None-Match`, so they are never
+/// served stale; a `304 Not Modified` just saves downloading the body again.
+#[derive(Debug, Default)]
+pub struct ResponseCache {
+    entries: HashMap<String, CachedResponse>,
+}
+
+impl ResponseCache {
+    /// Create an empty cache
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Check if responses for an endpoint are cached at all
+    pub fn is_cacheable(endpoint: &str) -> bool {
+        CACHED_RESOURCES.contains(&resource(endpoint))
+    }
+
+    /// Get the cached response for an endpoint
+    pub fn get(&self, endpoint: &str) -> Option<&CachedResponse> {
+        self.entries.get(endpoint)
+    }
+
+    /// Store a response for an endpoint, if it is cacheable
+    pub fn insert(&mut self, endpoint: &str, etag: String, body: String) {
+        if Self::is_cacheable(endpoint) {
+            self.entries
+                .insert(endpoint.to_string(), CachedResponse { etag, body });
+        }
+    }
+
+    /// Drop all cached responses
+    pub fn clear(&mut self) {
+        self.entries.clear();
+    }
+
+    /// Number of cached responses
+    pub fn len(&self) -> usize {
+        self.entries.len()
+    }
+
+    /// Check if nothing is cached
+    pub fn is_empty(&self) -> bool {
+        self.entries.is_empty()
+    }
+}
+
+/// First path segment of an endpoint, e.g. `/months` for `/months/4/close?x=1`
+fn resource(endpoint: &str) -> &str {
+    let path = endpoint.split('?').next().unwrap_or(endpoint);
+    match path[1.min(path.len())..].find('/') {
+        Some(end) => &path[..end + 1],
+        None => path,
+    }
+}
@@ -0,0 +1,1115 @@
+use std::future::Future;
+use std::io::Write;
+use std::path::{Path, PathBuf};
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::sync::{Arc, Mutex, RwLock};
+use std::time::{Duration, Instant};
+
+use anyhow::{Context, Result};
+use reqwest::{
+    header, Certificate, Client, Method, NoProxy, Proxy, RequestBuilder, Response, StatusCode,
+};
+use serde::{de::DeserializeOwned, Serialize};
+use serde_json::Value;
+use thiserror::Error;
+
+use super::{
+    parse_retry_after, AccountsApi, AuthApi, BackupApi, CategoriesApi, DebtsApi, DebugLog,
+    EndpointMetrics, ExpensesApi, IdempotencyKeys, IncomeTypesApi, IncomesApi, MonthsApi,
+    Operations, Outcome, PeriodsApi, RequestContext, RequestHook, RequestInfo, RequestMetrics,
+    ResponseCache, ResponseInfo, RetryPolicy, Subscription, SummaryApi, TransfersApi,
+    IDEMPOTENCY_KEY_HEADER, MAX_RETRY_AFTER,
+};
+use crate::journal::{JournalEntry, WriteJournal};
+use crate::models::PageInfo;
+
+const SDK_VERSION: &str = env!("CARGO_PKG_VERSION");
+
+/// Header with the number of items in all pages of a list
+const TOTAL_COUNT_HEADER: &str = "x-total-count";
+
+/// Header asking the server to change entries in a closed month anyway
+const ALLOW_CLOSED_MONTH_HEADER: &str = "X-Allow-Closed-Month";
+
+/// Resources whose writes are queued instead of failing while the server is unreachable
+const QUEUED_RESOURCES: &[&str] = &["/expenses", "/incomes"];
+
+#[derive(Error, Debug)]
+pub enum ApiError {
+    #[error("Unauthorized - please login again")]
+    Unauthorized,
+    /// 403: a wrong API key, or an admin-only endpoint for another account
+    #[error("{0}")]
+    Forbidden(String),
+    #[error("Not found")]
+    NotFound,
+    /// 501 or 405, from servers that don't have an endpoint or method yet
+    #[error("Not supported by this server")]
+    NotImplemented,
+    #[error("{0}")]
+    BadRequest(String),
+    #[error("{0}")]
+    Conflict(String),
+    /// 422, or a 400 listing the fields the server rejected
+    #[error("{}", validation_message(.0))]
+    Validation(Vec<FieldError>),
+    /// 410, for something that was only valid for a while, e.g. a login
+    /// challenge
+    #[error("Expired - please start over")]
+    Expired,
+    #[error("Server error: {0}")]
+    Server(String),
+    /// 429 that outlasted the retries, with the server's `Retry-After` if given
+    #[error("{}", rate_limited_message(*.0))]
+    RateLimited(Option<Duration>),
+    #[error("Network error: {0}")]
+    Network(#[from] reqwest::Error),
+    #[error("Invalid response: {0}")]
+    InvalidResponse(String),
+    #[error("Request cancelled")]
+    Cancelled,
+    #[error("Server unreachable - saved offline, will sync when it's back")]
+    Queued,
+    #[error("Failed to save download: {0}")]
+    Io(#[from] std::io::Error),
+}
+
+impl ApiError {
+    /// Whether the server lacks the endpoint, as older servers answer for
+    /// features added after them
+    pub fn is_unsupported(&self) -> bool {
+        matches!(self, ApiError::NotFound | ApiError::NotImplemented)
+    }
+
+    /// Fields the server rejected, empty for other errors
+    pub fn field_errors(&self) -> &[FieldError] {
+        match self {
+            ApiError::Validation(errors) => errors,
+            _ => &[],
+        }
+    }
+}
+
+/// A value the server rejected, by the name of its field in the request body
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct FieldError {
+    /// `None` when the error isn't about one field
+    pub field: Option<String>,
+    pub message: String,
+}
+
+fn validation_message(errors: &[FieldError]) -> String {
+    let messages: Vec<String> = errors
+        .iter()
+        .map(|error| match &error.field {
+            Some(field) => format!("{}: {}", field, error.message),
+            None => error.message.clone(),
+        })
+        .collect();
+    match messages.is_empty() {
+        true => "Invalid input".to_string(),
+        false => format!("Invalid input - {}", messages.join("; ")),
+    }
+}
+
+/// Field errors of a response body, in either of the shapes servers send
+///
+/// FastAPI lists `{"loc": ["body", "field"], "msg": ...}` under `detail`; zod
+/// lists `{"path": ["field"], "message": ...}` under `error.issues`. A plain
+/// `detail` string is an error without a field.
+pub fn parse_field_errors(body: &Value) -> Option<Vec<FieldError>> {
+    let (issues, location, message) = match (body.get("detail"), body.pointer("/error/issues")) {
+        (Some(Value::Array(issues)), _) => (issues, "loc", "msg"),
+        (_, Some(Value::Array(issues))) => (issues, "path", "message"),
+        (Some(Value::String(detail)), _) => {
+            return Some(vec![FieldError {
+                field: None,
+                message: detail.clone(),
+            }])
+        }
+        _ => return None,
+    };
+    let errors = issues
+        .iter()
+        .map(|issue| FieldError {
+            // FastAPI starts with where the value was ("body", "query")
+            field: issue
+                .get(location)
+                .and_then(Value::as_array)
+                .and_then(|path| path.iter().rev().find_map(Value::as_str))
+                .filter(|field| !matches!(*field, "body" | "query" | "path"))
+                .map(str::to_string),
+            message: issue
+                .get(message)
+                .and_then(Value::as_str)
+                .unwrap_or("Invalid value")
+                .to_string(),
+        })
+        .collect();
+    Some(errors)
+}
+
+fn rate_limited_message(retry_after: Option<Duration>) -> String {
+    match retry_after {
+        Some(wait) => format!(
+            "Rate limited by the server - try again in {}s",
+            wait.as_secs().max(1)
+        ),
+        None => "Rate limited by the server - try again shortly".to_string(),
+    }
+}
+
+/// The `Retry-After` of a response, if it has a valid one
+fn retry_after(response: &Response) -> Option<Duration> {
+    let value = response.headers().get(header::RETRY_AFTER)?.to_str().ok()?;
+    parse_retry_after(value, chrono::Utc::now())
+}
+
+/// The paging headers of a list response
+fn page_info(response: &Response) -> PageInfo {
+    let value = |name: &str| response.headers().get(name)?.to_str().ok();
+    PageInfo::parse(value(TOTAL_COUNT_HEADER), value(header::LINK.as_str()))
+}
+
+/// `endpoint` with `params` as its query string
+fn with_query(endpoint: &str, params: &[(&str, String)]) -> String {
+    if params.is_empty() {
+        return endpoint.to_string();
+    }
+    let query: Vec<String> = params
+        .iter()
+        .map(|(k, v)| format!("{}={}", k, urlencoding::encode(v)))
+        .collect();
+    format!("{}?{}", endpoint, query.join("&"))
+}
+
+/// `path` without the `/api/v1` prefix
+fn api_path(path: &str) -> &str {
+    path.strip_prefix("/api/v1").unwrap_or(path)
+}
+
+/// Outcome of replaying the offline write queue
+#[derive(Debug, Default)]
+pub struct SyncReport {
+    /// Writes the server accepted
+    pub synced: usize,
+    /// Writes the server rejected; these are dropped from the queue
+    pub failed: Vec<String>,
+    /// Writes still waiting, e.g. because the server is unreachable again
+    pub remaining: usize,
+}
+
+/// Connection settings for the HTTP client
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct ClientOptions {
+    /// Limit for a whole request, from connecting to reading the body
+    pub timeout: Duration,
+    /// PEM file with extra CA certificates to trust (e.g. an internal CA)
+    pub ca_bundle: Option<PathBuf>,
+    /// Accept any certificate; only for testing against self-signed servers
+    pub insecure_skip_verify: bool,
+    /// Proxy for all requests (`http://`, `https://` or `socks5h://`), or
+    /// `"none"` to ignore the environment; unset uses `HTTPS_PROXY`/`HTTP_PROXY`
+    pub proxy: Option<String>,
+    /// Hosts that bypass `proxy`, comma separated; unset uses `NO_PROXY`
+    pub no_proxy: Option<String>,
+    /// Sent as `X-Client-Info`, e.g. `TUI/1.2.0`
+    pub client_info: String,
+}
+
+impl Default for ClientOptions {
+    fn default() -> Self {
+        Self {
+            timeout: Duration::from_secs(30),
+            ca_bundle: None,
+            insecure_skip_verify: false,
+            proxy: None,
+            no_proxy: None,
+            client_info: format!("SDK/{}", SDK_VERSION),
+        }
+    }
+}
+
+impl ClientOptions {
+    /// Build a reqwest client with these settings
+    pub fn build_client(&self) -> Result<Client> {
+        let mut builder = Client::builder()
+            .timeout(self.timeout)
+            .danger_accept_invalid_certs(self.insecure_skip_verify);
+
+        match self.proxy.as_deref() {
+            // reqwest picks up HTTP_PROXY/HTTPS_PROXY/ALL_PROXY by itself
+            None => {}
+            Some("none") => builder = builder.no_proxy(),
+            Some(url) => {
+                // The URL may hold credentials; keep it out of the error
+                let proxy = Proxy::all(url).context("Invalid proxy URL")?;
+                let no_proxy = match self.no_proxy.as_deref() {
+                    Some(hosts) => NoProxy::from_string(hosts),
+                    None => NoProxy::from_env(),
+                };
+                builder = builder.proxy(proxy.no_proxy(no_proxy));
+            }
+        }
+
+        if let Some(path) = &self.ca_bundle {
+            let pem = std::fs::read(path)
+                .with_context(|| format!("Failed to read CA bundle {}", path.display()))?;
+            let certificates = Certificate::from_pem_bundle(&pem)
+                .with_context(|| format!("Invalid CA bundle {}", path.display()))?;
+            if certificates.is_empty() {
+                anyhow::bail!("No certificates found in CA bundle {}", path.display());
+            }
+            for certificate in certificates {
+                builder = builder.add_root_certificate(certificate);
+            }
+        }
+
+        builder.build().context("Failed to create HTTP client")
+    }
+}
+
+/// HTTP API client for the budget backend
+pub struct ApiClient {
+    client: Client,
+    base_url: String,
+    api_key: String,
+    /// Random ID sent with every request, so the change feed can tell this
+    /// client's own writes apart
+    client_id: String,
+    client_info: String,
+    token: RwLock<Option<String>>,
+    context: RwLock<RequestContext>,
+    retry: RwLock<RetryPolicy>,
+    /// Time spent waiting out 429s since the app last asked
+    rate_limit_wait: Mutex<Option<Duration>>,
+    /// Set when the server answered 401 to a request sent with a token
+    token_rejected: AtomicBool,
+    /// Set while the user chose to edit a closed month
+    allow_closed_month: AtomicBool,
+    cache: RwLock<ResponseCache>,
+    journal: Mutex<WriteJournal>,
+    journal_dir: RwLock<Option<PathBuf>>,
+    debug_log: RwLock<Option<DebugLog>>,
+    metrics: Mutex<RequestMetrics>,
+    hooks: RwLock<Vec<Arc<dyn RequestHook>>>,
+    idempotency_keys: Mutex<IdempotencyKeys>,
+}
+
+impl ApiClient {
+    /// Create a new API client with the default connection settings
+    pub fn new(base_url: String, api_key: String) -> Result<Self> {
+        Self::with_options(base_url, api_key, &ClientOptions::default())
+    }
+
+    /// Create a new API client with custom timeout and TLS settings
+    pub fn with_options(
+        base_url: String,
+        api_key: String,
+        options: &ClientOptions,
+    ) -> Result<Self> {
+        let client = options.build_client()?;
+
+        Ok(Self {
+            client,
+            base_url,
+            api_key,
+            client_id: format!("{:016x}", rand::random::<u64>()),
+            client_info: options.client_info.clone(),
+            token: RwLock::new(None),
+            context: RwLock::new(RequestContext::new()),
+            retry: RwLock::new(RetryPolicy::default()),
+            rate_limit_wait: Mutex::new(None),
+            token_rejected: AtomicBool::new(false),
+            allow_closed_month: AtomicBool::new(false),
+            cache: RwLock::new(ResponseCache::new()),
+            journal: Mutex::new(WriteJournal::default()),
+            journal_dir: RwLock::new(None),
+            debug_log: RwLock::new(None),
+            metrics: Mutex::new(RequestMetrics::new()),
+            hooks: RwLock::new(Vec::new()),
+            idempotency_keys: Mutex::new(IdempotencyKeys::default()),
+        })
+    }
+
+    /// Set the authentication token
+    pub fn set_token(&self, token: String) {
+        *self.token.write().unwrap() = Some(token);
+    }
+
+    /// Clear the authentication token
+    pub fn clear_token(&self) {
+        *self.token.write().unwrap() = None;
+        self.clear_cache();
+    }
+
+    /// Check if client has a token
+    pub fn has_token(&self) -> bool {
+        self.token.read().unwrap().is_some()
+    }
+
+    /// Get the context new requests run under
+    pub fn context(&self) -> RequestContext {
+        self.context.read().unwrap().clone()
+    }
+
+    /// Start a new request context, cancelling anything still running under the previous one
+    pub fn new_context(&self) -> RequestContext {
+        let context = RequestContext::new();
+        let previous = std::mem::replace(&mut *self.context.write().unwrap(), context.clone());
+        previous.cancel();
+        context
+    }
+
+    /// Get the retry policy for transient failures
+    pub fn retry_policy(&self) -> RetryPolicy {
+        *self.retry.read().unwrap()
+    }
+
+    /// Set the retry policy for transient failures
+    pub fn set_retry_policy(&self, policy: RetryPolicy) {
+        *self.retry.write().unwrap() = policy;
+    }
+
+    /// Time spent waiting on rate limits since the last call, if any
+    ///
+    /// Rate-limited requests are retried after the server's `Retry-After`;
+    /// this lets the UI say why they were slow.
+    pub fn take_rate_limit_wait(&self) -> Option<Duration> {
+        self.rate_limit_wait.lock().unwrap().take()
+    }
+
+    /// Whether the server refused the token since the last call
+    ///
+    /// Any request may be the one to find out that the session expired or was
+    /// revoked; this lets the app send the user back to the login screen.
+    pub fn take_token_rejected(&self) -> bool {
+        self.token_rejected.swap(false, Ordering::Relaxed)
+    }
+
+    /// Ask the server to accept changes to entries in closed months
+    ///
+    /// Servers refuse them otherwise; the app sets this only once the user
+    /// chose to edit a closed month anyway.
+    pub fn set_allow_closed_month(&self, allow: bool) {
+        self.allow_closed_month.store(allow, Ordering::Relaxed);
+    }
+
+    /// Latency and failures per endpoint since the client was created or reset
+    pub fn request_metrics(&self) -> Vec<EndpointMetrics> {
+        self.metrics.lock().unwrap().snapshot()
+    }
+
+    /// Count, error rate and latency of all requests together
+    pub fn request_totals(&self) -> EndpointMetrics {
+        self.metrics.lock().unwrap().totals()
+    }
+
+    /// Start the request metrics over
+    pub fn reset_request_metrics(&self) {
+        self.metrics.lock().unwrap().clear();
+    }
+
+    /// Run `hook` around every request from now on, after the hooks added
+    /// before it
+    pub fn add_hook(&self, hook: impl RequestHook + 'static) {
+        self.hooks.write().unwrap().push(Arc::new(hook));
+    }
+
+    /// Stop running the hooks added so far
+    pub fn clear_hooks(&self) {
+        self.hooks.write().unwrap().clear();
+    }
+
+    /// Drop all cached GET responses
+    pub fn clear_cache(&self) {
+        self.cache.write().unwrap().clear();
+    }
+
+    /// Queue expense and income writes in `data_dir` while the server is unreachable
+    pub fn enable_write_queue(&self, data_dir: PathBuf) -> Result<()> {
+        *self.journal.lock().unwrap() = WriteJournal::load(&data_dir)?;
+        *self.journal_dir.write().unwrap() = Some(data_dir);
+        Ok(())
+    }
+
+    /// Log every request and response to a file, for troubleshooting
+    pub fn enable_debug_log(&self, path: &Path) -> Result<()> {
+        *self.debug_log.write().unwrap() = Some(DebugLog::open(path)?);
+        Ok(())
+    }
+
+    /// File requests are logged to, if debug logging is on
+    pub fn debug_log_path(&self) -> Option<PathBuf> {
+        self.debug_log
+            .read()
+            .unwrap()
+            .as_ref()
+            .map(|log| log.path().to_path_buf())
+    }
+
+    /// Writes waiting to be sent to the server, oldest first
+    pub fn queued_writes(&self) -> Vec<JournalEntry> {
+        self.journal.lock().unwrap().entries().to_vec()
+    }
+
+    /// Check if any writes are waiting to be sent to the server
+    pub fn has_queued_writes(&self) -> bool {
+        !self.journal.lock().unwrap().is_empty()
+    }
+
+    /// Replay queued writes in order, stopping at the first one the server can't take yet
+    pub async fn sync_queued_writes(&self) -> SyncReport {
+        let mut report = SyncReport::default();
+
+        for entry in self.queued_writes() {
+            match self.replay(&entry).await {
+                Ok(()) => report.synced += 1,
+                // Still offline (or logged out); keep this and everything after it
+                Err(ApiError::Network(e)) if e.is_connect() => break,
+                // Throttled; try the rest on the next sync
+                Err(ApiError::Cancelled | ApiError::Unauthorized | ApiError::RateLimited(_)) => {
+                    break
+                }
+                Err(e) => report
+                    .failed
+                    .push(format!("{} {}: {}", entry.method, entry.endpoint, e)),
+            }
+            self.journal.lock().unwrap().remove(entry.id);
+        }
+
+        let _ = self.save_journal();
+        report.remaining = self.journal.lock().unwrap().len();
+        report
+    }
+
+    /// Make a GET request
+    pub async fn get<T: DeserializeOwned>(&self, endpoint: &str) -> Result<T, ApiError> {
+        self.request::<(), T>(Method::GET, endpoint, None).await
+    }
+
+    /// Make a GET request with query parameters
+    pub async fn get_with_params<T: DeserializeOwned>(
+        &self,
+        endpoint: &str,
+        params: &[(&str, String)],
+    ) -> Result<T, ApiError> {
+        self.request::<(), T>(Method::GET, &with_query(endpoint, params), None)
+            .await
+    }
+
+    /// Make a GET request for one page of a list, with its paging headers
+    ///
+    /// Not cached: pages shift as items are added, so an ETag for one says
+    /// little about the next.
+    pub async fn get_page<T: DeserializeOwned>(
+        &self,
+        endpoint: &str,
+        params: &[(&str, String)],
+    ) -> Result<(T, PageInfo), ApiError> {
+        let req = self.build_request(Method::GET, &with_query(endpoint, params));
+        self.with_context(async {
+            let response = self.send_with_retry(req, true).await?;
+            if !response.status().is_success() {
+                return Err(self.error_from_response(response).await);
+            }
+            let info = page_info(&response);
+            let text = response.text().await?;
+            self.log_response_body(&text);
+            let data = serde_json::from_str(&text)
+                .map_err(|e| ApiError::InvalidResponse(e.to_string()))?;
+            Ok((data, info))
+        })
+        .await
+    }
+
+    /// Make a POST request
+    pub async fn post<B: Serialize, T: DeserializeOwned>(
+        &self,
+        endpoint: &str,
+        body: &B,
+    ) -> Result<T, ApiError> {
+        self.request(Method::POST, endpoint, Some(body)).await
+    }
+
+    /// Make a PUT request
+    pub async fn put<B: Serialize, T: DeserializeOwned>(
+        &self,
+        endpoint: &str,
+        body: &B,
+    ) -> Result<T, ApiError> {
+        self.request(Method::PUT, endpoint, Some(body)).await
+    }
+
+    /// Make a PATCH request, changing only the fields in `body`
+    pub async fn patch<B: Serialize, T: DeserializeOwned>(
+        &self,
+        endpoint: &str,
+        body: &B,
+    ) -> Result<T, ApiError> {
+        self.request(Method::PATCH, endpoint, Some(body)).await
+    }
+
+    /// Make a DELETE request
+    pub async fn delete(&self, endpoint: &str) -> Result<(), ApiError> {
+        let req = self.build_request(Method::DELETE, endpoint);
+
+        self.with_context(async {
+            let response = self.send_with_retry(req, true).await?;
+
+            if response.status().is_success() {
+                Ok(())
+            } else {
+                Err(self.error_from_response(response).await)
+            }
+        })
+        .await
+        .map_err(|e| self.queue_if_offline(&Method::DELETE, endpoint, None, e))
+    }
+
+    /// Listen to the server's change feed for writes made by other clients
+    ///
+    /// Uses the current token; subscribe again after logging in as someone else.
+    pub fn subscribe(&self) -> Subscription {
+        let request = self
+            .build_request(Method::GET, "/events")
+            .header(header::ACCEPT, "text/event-stream");
+        Subscription::start(request, self.client_id.clone())
+    }
+
+    /// Make an HTTP request
+    async fn request<B: Serialize, T: DeserializeOwned>(
+        &self,
+        method: Method,
+        endpoint: &str,
+        body: Option<&B>,
+    ) -> Result<T, ApiError> {
+        let idempotent = method != Method::POST;
+        let cacheable = method == Method::GET && ResponseCache::is_cacheable(endpoint);
+        let cached = if cacheable {
+            self.cache.read().unwrap().get(endpoint).cloned()
+        } else {
+            None
+        };
+
+        // Keep a copy of the body in case the write has to be queued
+        let queued_body = match body {
+            Some(body) if method != Method::GET => serde_json::to_value(body).ok(),
+            _ => None,
+        };
+
+        let mut req = self.build_request(method.clone(), endpoint);
+
+        if let Some(body) = body {
+            req = req.json(body);
+        }
+
+        if let Some(cached) = &cached {
+            req = req.header(header::IF_NONE_MATCH, &cached.etag);
+        }
+
+        // Sending the same POST again after an unknown outcome reuses its key
+        let idempotency_key = (method == Method::POST).then(|| {
+            self.idempotency_keys
+                .lock()
+                .unwrap()
+                .key_for(endpoint, queued_body.as_ref())
+        });
+        if let Some(key) = &idempotency_key {
+            req = req.header(IDEMPOTENCY_KEY_HEADER, key);
+        }
+
+        let result = self
+            .with_context(async {
+                let response = self.send_with_retry(req, idempotent).await?;
+
+                if let (StatusCode::NOT_MODIFIED, Some(cached)) = (response.status(), &cached) {
+                    return serde_json::from_str(&cached.body)
+                        .map_err(|e| ApiError::InvalidResponse(e.to_string()));
+                }
+
+                if response.status().is_success() {
+                    let etag = response
+                        .headers()
+                        .get(header::ETAG)
+                        .and_then(|value| value.to_str().ok())
+                        .map(str::to_string);
+                    let text = response.text().await?;
+                    self.log_response_body(&text);
+                    let data = serde_json::from_str(&text)
+                        .map_err(|e| ApiError::InvalidResponse(e.to_string()))?;
+                    if let Some(etag) = etag.filter(|_| cacheable) {
+                        self.cache.write().unwrap().insert(endpoint, etag, text);
+                    }
+                    Ok(data)
+                } else {
+                    Err(self.error_from_response(response).await)
+                }
+            })
+            .await;
+
+        if let Some(key) = idempotency_key {
+            // The server may have applied it without us hearing back
+            let unsettled = match &result {
+                Err(ApiError::Network(e)) => !e.is_connect(),
+                Err(ApiError::Server(_)) => true,
+                _ => false,
+            };
+            self.idempotency_keys.lock().unwrap().record(
+                endpoint,
+                queued_body.as_ref(),
+                key,
+                unsettled,
+            );
+        }
+
+        result.map_err(|e| self.queue_if_offline(&method, endpoint, queued_body, e))
+    }
+
+    /// POST `body` as it is rather than as JSON, e.g. a file, with `query`
+    /// in the URL
+    ///
+    /// Uploads aren't queued while offline; they need the file at hand.
+    pub async fn upload<T: DeserializeOwned>(
+        &self,
+        endpoint: &str,
+        query: &[(&str, &str)],
+        content_type: &str,
+        body: Vec<u8>,
+    ) -> Result<T, ApiError> {
+        let req = self
+            .build_request_as(Method::POST, endpoint, content_type)
+            .query(query)
+            .body(body);
+
+        self.with_context(async {
+            let response = self.send_with_retry(req, false).await?;
+
+            if response.status().is_success() {
+                let text = response.text().await?;
+                self.log_response_body(&text);
+                serde_json::from_str(&text).map_err(|e| ApiError::InvalidResponse(e.to_string()))
+            } else {
+                Err(self.error_from_response(response).await)
+            }
+        })
+        .await
+    }
+
+    /// Save the body of a GET to a file as it arrives, e.g. an export
+    ///
+    /// `progress` is called after each chunk with the bytes received so far
+    /// and the total, when the server sends a length. The body is written next
+    /// to `dest` with a `.part` suffix and moved into place once complete, so
+    /// a failed download leaves no half-written file. Returns the size.
+    pub async fn download(
+        &self,
+        endpoint: &str,
+        dest: &Path,
+        mut progress: impl FnMut(u64, Option<u64>),
+    ) -> Result<u64, ApiError> {
+        let req = self.build_request(Method::GET, endpoint);
+        let mut partial = dest.as_os_str().to_owned();
+        partial.push(".part");
+        let partial = PathBuf::from(partial);
+
+        let result = self
+            .with_context(async {
+                let mut response = self.send_with_retry(req, true).await?;
+                if !response.status().is_success() {
+                    return Err(self.error_from_response(response).await);
+                }
+                let total = response.content_length();
+                let mut file = std::fs::File::create(&partial)?;
+                let mut received = 0;
+                while let Some(chunk) = response.chunk().await? {
+                    file.write_all(&chunk)?;
+                    received += chunk.len() as u64;
+                    progress(received, total);
+                }
+                file.sync_all()?;
+                Ok(received)
+            })
+            .await;
+
+        match result {
+            Ok(received) => {
+                std::fs::rename(&partial, dest)?;
+                Ok(received)
+            }
+            Err(e) => {
+                let _ = std::fs::remove_file(&partial);
+                Err(e)
+            }
+        }
+    }
+
+    /// Build a request with the API key, client info and auth headers
+    fn build_request(&self, method: Method, endpoint: &str) -> RequestBuilder {
+        self.build_request_as(method, endpoint, "application/json")
+    }
+
+    /// Build a request like `build_request`, for a body of `content_type`
+    fn build_request_as(
+        &self,
+        method: Method,
+        endpoint: &str,
+        content_type: &str,
+    ) -> RequestBuilder {
+        let url = format!("{}/api/v1{}", self.base_url, endpoint);
+
+        let mut req = self
+            .client
+            .request(method, &url)
+            .header("X-API-Key", &self.api_key)
+            .header("X-Client-Info", &self.client_info)
+            .header("X-Client-Id", &self.client_id)
+            .header(header::CONTENT_TYPE, content_type);
+
+        if let Some(token) = self.token.read().unwrap().as_ref() {
+            req = req.header(header::AUTHORIZATION, format!("Bearer {}", token));
+        }
+        if self.allow_closed_month.load(Ordering::Relaxed) {
+            req = req.header(ALLOW_CLOSED_MONTH_HEADER, "true");
+        }
+
+        req
+    }
+
+    /// Append a failed write to the journal if the server couldn't be reached
+    ///
+    /// Returns `ApiError::Queued` when the write was queued, or the original error.
+    fn queue_if_offline(
+        &self,
+        method: &Method,
+        endpoint: &str,
+        body: Option<Value>,
+        error: ApiError,
+    ) -> ApiError {
+        let unreachable = matches!(&error, ApiError::Network(e) if e.is_connect());
+        let queueable = *method != Method::GET
+            && QUEUED_RESOURCES.iter().any(|resource| {
+                endpoint == *resource || endpoint.starts_with(&format!("{}/", resource))
+            });
+        if !unreachable || !queueable || self.journal_dir.read().unwrap().is_none() {
+            return error;
+        }
+
+        let id = self
+            .journal
+            .lock()
+            .unwrap()
+            .push(method.as_str(), endpoint, body);
+        if self.save_journal().is_err() {
+            // Don't pretend the write is safe if it can't be persisted
+            self.journal.lock().unwrap().remove(id);
+            return error;
+        }
+        ApiError::Queued
+    }
+
+    /// Persist the journal, if queueing is enabled
+    fn save_journal(&self) -> Result<()> {
+        if let Some(dir) = self.journal_dir.read().unwrap().as_ref() {
+            self.journal.lock().unwrap().save(dir)?;
+        }
+        Ok(())
+    }
+
+    /// Send a queued write to the server
+    async fn replay(&self, entry: &JournalEntry) -> Result<(), ApiError> {
+        let method = Method::from_bytes(entry.method.as_bytes())
+            .map_err(|e| ApiError::InvalidResponse(e.to_string()))?;
+        let idempotent = method != Method::POST;
+        let is_delete = method == Method::DELETE;
+
+        let mut req = self.build_request(method, &entry.endpoint);
+        if let Some(body) = &entry.body {
+            req = req.json(body);
+        }
+        if let Some(key) = &entry.idempotency_key {
+            req = req.header(IDEMPOTENCY_KEY_HEADER, key);
+        }
+
+        self.with_context(async {
+            let response = self.send_with_retry(req, idempotent).await?;
+
+            // Something deleted offline may already be gone
+            if response.status().is_success()
+                || (is_delete && response.status() == StatusCode::NOT_FOUND)
+            {
+                Ok(())
+            } else {
+                Err(self.error_from_response(response).await)
+            }
+        })
+        .await
+    }
+
+    /// Send a request, retrying transient failures with jittered exponential backoff
+    ///
+    /// Non-idempotent requests (POST) are only retried when the connection could not be
+    /// established, since the server never saw them; a 5xx or dropped connection may
+    /// mean the change was already applied.
+    async fn send_with_retry(
+        &self,
+        req: RequestBuilder,
+        idempotent: bool,
+    ) -> Result<Response, ApiError> {
+        let policy = self.retry_policy();
+        let hooks = self.hooks.read().unwrap().clone();
+        let target = match hooks.is_empty() {
+            true => None,
+            false => req
+                .try_clone()
+                .and_then(|req| req.build().ok())
+                .map(|request| RequestInfo {
+                    method: request.method().to_string(),
+                    path: api_path(request.url().path()).to_string(),
+                    attempt: 0,
+                }),
+        };
+        let mut attempt = 0;
+
+        loop {
+            // Streaming bodies can't be cloned; send those once
+            let mut attempt_req = match req.try_clone() {
+                Some(attempt_req) => attempt_req,
+                None => return Ok(req.send().await?),
+            };
+
+            let info = target.as_ref().map(|target| RequestInfo {
+                attempt,
+                ..target.clone()
+            });
+            if let Some(info) = &info {
+                let mut headers = header::HeaderMap::new();
+                for hook in &hooks {
+                    hook.before_request(info, &mut headers);
+                }
+                attempt_req = attempt_req.headers(headers);
+            }
+
+            let started = Instant::now();
+            let result = attempt_req.send().await;
+            self.log_attempt(&req, &result, started.elapsed());
+            self.record_attempt(&req, &result, started.elapsed());
+            if let Some(info) = &info {
+                let response = ResponseInfo {
+                    status: result.as_ref().ok().map(|r| r.status().as_u16()),
+                    elapsed: started.elapsed(),
+                };
+                for hook in &hooks {
+                    hook.after_response(info, &response);
+                }
+            }
+            // A 429 wasn't processed, so even POSTs can be sent again
+            let rate_limited = match &result {
+                Ok(response) if response.status() == StatusCode::TOO_MANY_REQUESTS => Some(
+                    retry_after(response)
+                        .unwrap_or_else(|| policy.delay(attempt, rand::random::<f64>())),
+                ),
+                _ => None,
+            };
+            let retryable = match (&result, rate_limited) {
+                (Ok(_), Some(wait)) => wait <= MAX_RETRY_AFTER,
+                // A 501 won't change on a retry
+                (Ok(response), None) => {
+                    idempotent
+                        && response.status().is_server_error()
+                        && response.status() != StatusCode::NOT_IMPLEMENTED
+                }
+                (Err(e), _) => e.is_connect() || (idempotent && (e.is_timeout() || e.is_request())),
+            };
+            if !retryable || attempt >= policy.max_retries {
+                return Ok(result?);
+            }
+
+            let delay = match rate_limited {
+                Some(wait) => {
+                    let mut total = self.rate_limit_wait.lock().unwrap();
+                    *total = Some(total.unwrap_or_default() + wait);
+                    wait
+                }
+                None => policy.delay(attempt, rand::random::<f64>()),
+            };
+            tokio::time::sleep(delay).await;
+            attempt += 1;
+        }
+    }
+
+    /// Log a request attempt, if debug logging is on
+    fn log_attempt(
+        &self,
+        req: &RequestBuilder,
+        result: &Result<Response, reqwest::Error>,
+        elapsed: Duration,
+    ) {
+        let debug_log = self.debug_log.read().unwrap();
+        let (log, request) = match (
+            debug_log.as_ref(),
+            req.try_clone().and_then(|req| req.build().ok()),
+        ) {
+            (Some(log), Some(request)) => (log, request),
+            _ => return,
+        };
+        let outcome = match result {
+            Ok(response) => response.status().to_string(),
+            Err(e) => format!("error: {}", e),
+        };
+        log.request(
+            request.method().as_str(),
+            request.url().as_str(),
+            &outcome,
+            elapsed,
+            request.body().and_then(|body| body.as_bytes()),
+        );
+    }
+
+    /// Add an attempt to the request metrics
+    fn record_attempt(
+        &self,
+        req: &RequestBuilder,
+        result: &Result<Response, reqwest::Error>,
+        elapsed: Duration,
+    ) {
+        let request = match req.try_clone().and_then(|req| req.build().ok()) {
+            Some(request) => request,
+            None => return,
+        };
+        let path = request.url().path();
+        let outcome = match result {
+            Ok(response) if response.status().is_server_error() => Outcome::ServerError,
+            Ok(_) => Outcome::Response,
+            Err(_) => Outcome::NetworkError,
+        };
+        self.metrics.lock().unwrap().record(
+            request.method().as_str(),
+            api_path(path),
+            elapsed,
+            outcome,
+        );
+    }
+
+    /// Log a response body, if debug logging is on
+    fn log_response_body(&self, body: &str) {
+        if let Some(log) = self.debug_log.read().unwrap().as_ref() {
+            log.response_body(body);
+        }
+    }
+
+    /// Map an unsuccessful response to an error, using the server's `detail` message if any
+    async fn error_from_response(&self, response: Response) -> ApiError {
+        let status = response.status();
+        let wait = retry_after(&response);
+        let text = response.text().await.unwrap_or_default();
+        self.log_response_body(&text);
+        let body = serde_json::from_str::<Value>(&text).ok();
+        let detail = body
+            .as_ref()
+            .and_then(|body| body.get("detail")?.as_str().map(str::to_string));
+        let zod_issues = body
+            .as_ref()
+            .is_some_and(|body| body.pointer("/error/issues").is_some());
+
+        match status {
+            StatusCode::UNAUTHORIZED => {
+                if self.has_token() {
+                    self.token_rejected.store(true, Ordering::Relaxed);
+                }
+                ApiError::Unauthorized
+            }
+            StatusCode::FORBIDDEN => ApiError::Forbidden(detail.unwrap_or(text)),
+            StatusCode::NOT_FOUND => ApiError::NotFound,
+            StatusCode::NOT_IMPLEMENTED | StatusCode::METHOD_NOT_ALLOWED => {
+                ApiError::NotImplemented
+            }
+            StatusCode::UNPROCESSABLE_ENTITY => ApiError::Validation(
+                body.as_ref()
+                    .and_then(parse_field_errors)
+                    .unwrap_or_default(),
+            ),
+            StatusCode::BAD_REQUEST if zod_issues => ApiError::Validation(
+                body.as_ref()
+                    .and_then(parse_field_errors)
+                    .unwrap_or_default(),
+            ),
+            StatusCode::BAD_REQUEST => ApiError::BadRequest(detail.unwrap_or(text)),
+            StatusCode::CONFLICT => ApiError::Conflict(detail.unwrap_or(text)),
+            StatusCode::GONE => ApiError::Expired,
+            StatusCode::TOO_MANY_REQUESTS => ApiError::RateLimited(wait),
+            status => ApiError::Server(format!("{}: {}", status, detail.unwrap_or(text))),
+        }
+    }
+
+    /// Run a request under the current context, aborting it if the context is cancelled
+    async fn with_context<T>(
+        &self,
+        request: impl Future<Output = Result<T, ApiError>>,
+    ) -> Result<T, ApiError> {
+        let context = self.context();
+        if context.is_cancelled() {
+            return Err(ApiError::Cancelled);
+        }
+
+        tokio::select! {
+            result = request => result,
+            _ = context.cancelled() => Err(ApiError::Cancelled),
+        }
+    }
+
+    // Domain-specific API accessors
+
+    pub fn auth(&self) -> AuthApi<'_> {
+        AuthApi::new(self)
+    }
+
+    pub fn expenses(&self) -> ExpensesApi<'_> {
+        ExpensesApi::new(self)
+    }
+
+    pub fn incomes(&self) -> IncomesApi<'_> {
+        IncomesApi::new(self)
+    }
+
+    pub fn categories(&self) -> CategoriesApi<'_> {
+        CategoriesApi::new(self)
+    }
+
+    pub fn periods(&self) -> PeriodsApi<'_> {
+        PeriodsApi::new(self)
+    }
+
+    pub fn income_types(&self) -> IncomeTypesApi<'_> {
+        IncomeTypesApi::new(self)
+    }
+
+    pub fn accounts(&self) -> AccountsApi<'_> {
+        AccountsApi::new(self)
+    }
+
+    pub fn debts(&self) -> DebtsApi<'_> {
+        DebtsApi::new(self)
+    }
+
+    pub fn transfers(&self) -> TransfersApi<'_> {
+        TransfersApi::new(self)
+    }
+
+    pub fn months(&self) -> MonthsApi<'_> {
+        MonthsApi::new(self)
+    }
+
+    pub fn summary(&self) -> SummaryApi<'_> {
+        SummaryApi::new(self)
+    }
+
+    pub fn backup(&self) -> BackupApi<'_> {
+        BackupApi::new(self)
+    }
+
+    /// Every operation in the OpenAPI spec, generated from it
+    pub fn operations(&self) -> Operations<'_> {
+        Operations::new(self)
+    }
+}
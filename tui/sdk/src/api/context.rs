@@ -0,0 +1,46 @@
+use std::sync::Arc;
+
+use tokio::sync::watch;
+
+/// Cancellation scope for API requests
+///
+/// Every request made through the `ApiClient` runs under its current context.
+/// Cancelling a context aborts requests in flight and makes any later request
+/// under the same context fail immediately with `ApiError::Cancelled`.
+#[derive(Debug, Clone)]
+pub struct RequestContext {
+    cancelled: Arc<watch::Sender<bool>>,
+}
+
+impl RequestContext {
+    /// Create a new, active context
+    pub fn new() -> Self {
+        let (sender, _) = watch::channel(false);
+        Self {
+            cancelled: Arc::new(sender),
+        }
+    }
+
+    /// Cancel this context and every request running under it
+    pub fn cancel(&self) {
+        self.cancelled.send_replace(true);
+    }
+
+    /// Check if this context has been cancelled
+    pub fn is_cancelled(&self) -> bool {
+        *self.cancelled.borrow()
+    }
+
+    /// Wait until this context is cancelled
+    pub async fn cancelled(&self) {
+        let mut receiver = self.cancelled.subscribe();
+        // The sender lives as long as self, so this only returns on cancel
+        let _ = receiver.wait_for(|cancelled| *cancelled).await;
+    }
+}
+
+impl Default for RequestContext {
+    fn default() -> Self {
+        Self::new()
+    }
+}
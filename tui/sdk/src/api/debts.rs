@@ -0,0 +1,32 @@
+use crate::api::client::{ApiClient, ApiError};
+use crate::models::{Debt, DebtCreate, DebtUpdate};
+
+pub struct DebtsApi<'a> {
+    client: &'a ApiClient,
+}
+
+impl<'a> DebtsApi<'a> {
+    pub fn new(client: &'a ApiClient) -> Self {
+        Self { client }
+    }
+
+    /// Get all debts
+    pub async fn get_all(&self) -> Result<Vec<Debt>, ApiError> {
+        self.client.get("/debts").await
+    }
+
+    /// Create a new debt
+    pub async fn create(&self, debt: &DebtCreate) -> Result<Debt, ApiError> {
+        self.client.post("/debts", debt).await
+    }
+
+    /// Update a debt
+    pub async fn update(&self, id: i32, debt: &DebtUpdate) -> Result<Debt, ApiError> {
+        self.client.put(&format!("/debts/{}", id), debt).await
+    }
+
+    /// Delete a debt; the expenses its payments were recorded as are kept
+    pub async fn delete(&self, id: i32) -> Result<(), ApiError> {
+        self.client.delete(&format!("/debts/{}", id)).await
+    }
+}
@@ -0,0 +1,132 @@
+use std::fs::{self, File, OpenOptions};
+use std::io::Write;
+use std::path::{Path, PathBuf};
+use std::sync::Mutex;
+use std::time::Duration;
+
+use anyhow::{Context, Result};
+use serde_json::Value;
+
+/// Bodies longer than this are cut off in the log
+pub const MAX_BODY_CHARS: usize = 2000;
+
+/// JSON fields whose values never go into the log
+const SECRET_FIELDS: &[&str] = &[
+    "password",
+    "token",
+    "api_key",
+    "secret",
+    "challenge",
+    "device_code",
+];
+
+/// Fields masked only by this exact name, as it is too short to look for
+/// inside others: the two-factor `code`
+const SECRET_NAMES: &[&str] = &["code"];
+
+/// Append-only log of HTTP traffic, for troubleshooting API errors
+///
+/// Headers aren't logged, so the API key and session token stay out of the
+/// file; passwords and tokens in JSON bodies are masked.
+#[derive(Debug)]
+pub struct DebugLog {
+    path: PathBuf,
+    file: Mutex<File>,
+}
+
+impl DebugLog {
+    /// Open (or create) the log file, appending to what is already there
+    pub fn open(path: &Path) -> Result<Self> {
+        if let Some(parent) = path.parent() {
+            fs::create_dir_all(parent)
+                .with_context(|| format!("Failed to create {}", parent.display()))?;
+        }
+        let file = OpenOptions::new()
+            .create(true)
+            .append(true)
+            .open(path)
+            .with_context(|| format!("Failed to open {}", path.display()))?;
+        Ok(Self {
+            path: path.to_path_buf(),
+            file: Mutex::new(file),
+        })
+    }
+
+    /// Path of the log file
+    pub fn path(&self) -> &Path {
+        &self.path
+    }
+
+    /// Log one attempt of a request: its outcome, latency and request body
+    ///
+    /// `outcome` is the status code, or the error if no response came back.
+    pub fn request(
+        &self,
+        method: &str,
+        url: &str,
+        outcome: &str,
+        elapsed: Duration,
+        body: Option<&[u8]>,
+    ) {
+        let mut entry = format!(
+            "{} {} {} -> {} ({} ms)\n",
+            chrono::Local::now().format("%Y-%m-%d %H:%M:%S%.3f"),
+            method,
+            url,
+            outcome,
+            elapsed.as_millis()
+        );
+        if let Some(body) = body.filter(|body| !body.is_empty()) {
+            entry.push_str(&format!("  > {}\n", format_body(body)));
+        }
+        self.write(&entry);
+    }
+
+    /// Log the body of the response to the last request
+    pub fn response_body(&self, body: &str) {
+        self.write(&format!("  < {}\n", format_body(body.as_bytes())));
+    }
+
+    /// Logging must never break a request, so write errors are ignored
+    fn write(&self, entry: &str) {
+        let _ = self.file.lock().unwrap().write_all(entry.as_bytes());
+    }
+}
+
+/// Prepare a body for the log: secrets masked, on one line, and truncated
+pub fn format_body(body: &[u8]) -> String {
+    let text = match serde_json::from_slice::<Value>(body) {
+        Ok(mut value) => {
+            redact(&mut value);
+            value.to_string()
+        }
+        Err(_) => String::from_utf8_lossy(body).replace('\n', "\\n"),
+    };
+
+    let total = text.chars().count();
+    if total <= MAX_BODY_CHARS {
+        return text;
+    }
+    let kept: String = text.chars().take(MAX_BODY_CHARS).collect();
+    format!("{}... ({} more chars)", kept, total - MAX_BODY_CHARS)
+}
+
+/// Mask every field that looks like a secret, at any depth
+fn redact(value: &mut Value) {
+    match value {
+        Value::Object(fields) => {
+            for (name, field) in fields.iter_mut() {
+                let name = name.to_lowercase();
+                if SECRET_FIELDS.iter().any(|secret| name.contains(secret))
+                    || SECRET_NAMES.contains(&name.as_str())
+                {
+                    *field = Value::String("****".to_string());
+                } else {
+                    redact(field);
+                }
+            }
+        }
+        Value::Array(items) => items.iter_mut().for_each(redact),
+        _ => {}
+    }
+}
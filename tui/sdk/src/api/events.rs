@@ -0,0 +1,230 @@
+use std::sync::mpsc::{self, Receiver, Sender};
+use std::time::Duration;
+
+use reqwest::{RequestBuilder, StatusCode};
+use serde::Deserialize;
+use tokio::task::JoinHandle;
+
+use super::RetryPolicy;
+
+/// Longest a single stream is kept open before it is reopened
+const STREAM_TIMEOUT: Duration = Duration::from_secs(3600);
+/// No bytes for this long (the server sends a keepalive every 15s) means the
+/// connection is dead
+const IDLE_TIMEOUT: Duration = Duration::from_secs(45);
+
+/// A write announced by the server's change feed
+#[derive(Debug, Clone, PartialEq, Deserialize)]
+pub struct ChangeEvent {
+    /// Collection that changed, e.g. `expenses` or `months`
+    pub resource: String,
+    /// `created`, `updated` or `deleted`
+    pub action: String,
+    #[serde(default)]
+    pub id: Option<i32>,
+    #[serde(default)]
+    pub month_id: Option<i32>,
+    /// Client ID of whoever made the change
+    #[serde(default)]
+    pub source: Option<String>,
+}
+
+impl ChangeEvent {
+    /// Check if the change could alter the expenses, incomes or summaries of a month
+    ///
+    /// Expense and income changes without a month (e.g. reordering) count for
+    /// every month.
+    pub fn affects_month(&self, month_id: i32) -> bool {
+        match self.resource.as_str() {
+            "expenses" | "incomes" => self.month_id.is_none_or(|id| id == month_id),
+            "months" => self.id == Some(month_id),
+            // Renamed categories and the like show up in every month
+            _ => true,
+        }
+    }
+
+    /// Check if the change is to categories, periods or income types
+    pub fn affects_settings(&self) -> bool {
+        matches!(
+            self.resource.as_str(),
+            "categories" | "periods" | "income-types"
+        )
+    }
+}
+
+/// What a subscription reports to the app
+#[derive(Debug, Clone, PartialEq)]
+pub enum LiveEvent {
+    /// Another client changed something
+    Change(ChangeEvent),
+    /// The stream was reopened after dropping; changes in between were missed
+    Reconnected,
+}
+
+/// One message of a `text/event-stream` body
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct SseMessage {
+    /// Event name, `message` when the server gave none
+    pub event: String,
+    pub data: String,
+}
+
+/// Incremental parser for a `text/event-stream` body
+///
+/// Chunks may end anywhere, even inside a UTF-8 character; incomplete lines
+/// are kept until the next chunk.
+#[derive(Debug, Default)]
+pub struct SseParser {
+    buffer: Vec<u8>,
+    event: String,
+    data: Vec<String>,
+}
+
+impl SseParser {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Add a chunk of the body and return the messages it completes
+    pub fn feed(&mut self, chunk: &[u8]) -> Vec<SseMessage> {
+        self.buffer.extend_from_slice(chunk);
+        let mut messages = Vec::new();
+
+        while let Some(end) = self.buffer.iter().position(|&b| b == b'\n') {
+            let line: Vec<u8> = self.buffer.drain(..=end).collect();
+            let line = String::from_utf8_lossy(&line);
+            let line = line.trim_end_matches(['\n', '\r']);
+
+            if line.is_empty() {
+                // A blank line ends the message; one without data is ignored
+                let event = std::mem::take(&mut self.event);
+                if !self.data.is_empty() {
+                    messages.push(SseMessage {
+                        event: if event.is_empty() {
+                            "message".to_string()
+                        } else {
+                            event
+                        },
+                        data: std::mem::take(&mut self.data).join("\n"),
+                    });
+                }
+                continue;
+            }
+            if line.starts_with(':') {
+                // Comment, e.g. a keepalive
+                continue;
+            }
+
+            let (field, value) = match line.split_once(':') {
+                Some((field, value)) => (field, value.strip_prefix(' ').unwrap_or(value)),
+                None => (line, ""),
+            };
+            match field {
+                "event" => self.event = value.to_string(),
+                "data" => self.data.push(value.to_string()),
+                // id and retry aren't needed: missed events are never replayed
+                _ => {}
+            }
+        }
+
+        messages
+    }
+}
+
+/// A running subscription to the server's change feed
+///
+/// Events are collected on a background task; dropping the subscription
+/// stops it.
+pub struct Subscription {
+    receiver: Receiver<LiveEvent>,
+    task: JoinHandle<()>,
+}
+
+impl Subscription {
+    /// Open the stream with `request` and keep it open until dropped
+    ///
+    /// Changes made by `client_id` itself are skipped. Dropped connections are
+    /// reopened with growing delays; a server without a change feed (404) or
+    /// one rejecting the credentials ends the subscription quietly.
+    pub(crate) fn start(request: RequestBuilder, client_id: String) -> Self {
+        let (sender, receiver) = mpsc::channel();
+        let task = tokio::spawn(listen(request, client_id, sender));
+        Self { receiver, task }
+    }
+
+    /// Take the events received since the last call
+    pub fn drain(&self) -> Vec<LiveEvent> {
+        self.receiver.try_iter().collect()
+    }
+
+    /// Check if the subscription gave up, e.g. because the server has no feed
+    pub fn is_finished(&self) -> bool {
+        self.task.is_finished()
+    }
+}
+
+impl Drop for Subscription {
+    fn drop(&mut self) {
+        self.task.abort();
+    }
+}
+
+/// Keep the change feed open, sending its events until the receiver is gone
+async fn listen(request: RequestBuilder, client_id: String, sender: Sender<LiveEvent>) {
+    let backoff = RetryPolicy {
+        max_retries: u32::MAX,
+        base_delay: Duration::from_secs(1),
+        max_delay: Duration::from_secs(60),
+    };
+    let mut attempt = 0;
+    let mut connected_before = false;
+
+    loop {
+        let response = match request.try_clone() {
+            Some(request) => request.timeout(STREAM_TIMEOUT).send().await,
+            None => return,
+        };
+        match response {
+            Ok(mut response) if response.status().is_success() => {
+                attempt = 0;
+                if connected_before && sender.send(LiveEvent::Reconnected).is_err() {
+                    return;
+                }
+                connected_before = true;
+
+                let mut parser = SseParser::new();
+                while let Ok(Ok(Some(chunk))) =
+                    tokio::time::timeout(IDLE_TIMEOUT, response.chunk()).await
+                {
+                    for message in parser.feed(&chunk) {
+                        if message.event != "change" {
+                            continue;
+                        }
+                        let change: ChangeEvent = match serde_json::from_str(&message.data) {
+                            Ok(change) => change,
+                            Err(_) => continue,
+                        };
+                        if change.source.as_deref() == Some(client_id.as_str()) {
+                            continue;
+                        }
+                        if sender.send(LiveEvent::Change(change)).is_err() {
+                            return;
+                        }
+                    }
+                }
+            }
+            Ok(response)
+                if matches!(
+                    response.status(),
+                    StatusCode::NOT_FOUND | StatusCode::UNAUTHORIZED | StatusCode::FORBIDDEN
+                ) =>
+            {
+                return;
+            }
+            _ => {}
+        }
+
+        tokio::time::sleep(backoff.delay(attempt, rand::random())).await;
+        attempt = attempt.saturating_add(1);
+    }
+}
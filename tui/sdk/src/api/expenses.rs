@@ -0,0 +1,194 @@
+use std::path::Path;
+
+use crate::api::client::{ApiClient, ApiError};
+use crate::models::{
+    Attachment, CloneResponse, Expense, ExpenseBulkCreateRequest, ExpenseBulkUpdate,
+    ExpenseBulkUpdateRequest, ExpenseCreate, ExpenseFilters, ExpenseReorderRequest, ExpenseUpdate,
+    Page, PageRequest, PayExpenseRequest,
+};
+
+/// Most expenses the server takes in one bulk request
+pub const MAX_BULK_EXPENSES: usize = 500;
+
+pub struct ExpensesApi<'a> {
+    client: &'a ApiClient,
+}
+
+impl<'a> ExpensesApi<'a> {
+    pub fn new(client: &'a ApiClient) -> Self {
+        Self { client }
+    }
+
+    /// Get all expenses with optional filters
+    pub async fn get_all(&self, filters: &ExpenseFilters) -> Result<Vec<Expense>, ApiError> {
+        let params = filters.to_query_params();
+        self.client.get_with_params("/expenses", &params).await
+    }
+
+    /// Get one page of expenses, with the server's paging headers
+    pub async fn get_page(
+        &self,
+        filters: &ExpenseFilters,
+        page: PageRequest,
+    ) -> Result<Page<Expense>, ApiError> {
+        let filters = ExpenseFilters {
+            page: Some(page),
+            ..filters.clone()
+        };
+        let params = filters.to_query_params();
+        let (items, info) = self.client.get_page("/expenses", &params).await?;
+        Ok(Page::with_info(items, page, info))
+    }
+
+    /// Get a single expense by ID
+    pub async fn get_by_id(&self, id: i32) -> Result<Expense, ApiError> {
+        self.client.get(&format!("/expenses/{}", id)).await
+    }
+
+    /// Create a new expense
+    pub async fn create(&self, expense: &ExpenseCreate) -> Result<Expense, ApiError> {
+        self.client.post("/expenses", expense).await
+    }
+
+    /// Update the fields set in `expense`, leaving the others as they are
+    ///
+    /// Sent as a PATCH; servers without it get the same partial body as a PUT.
+    pub async fn update(&self, id: i32, expense: &ExpenseUpdate) -> Result<Expense, ApiError> {
+        let endpoint = format!("/expenses/{}", id);
+        match self.client.patch(&endpoint, expense).await {
+            Err(e) if e.is_unsupported() => self.client.put(&endpoint, expense).await,
+            result => result,
+        }
+    }
+
+    /// Create many expenses, returned in the same order
+    ///
+    /// Sent in batches of `MAX_BULK_EXPENSES`; the server writes a batch only
+    /// if every expense in it is valid. Servers without the bulk endpoint get
+    /// one request per expense, stopping at the first failure.
+    pub async fn create_bulk(&self, expenses: &[ExpenseCreate]) -> Result<Vec<Expense>, ApiError> {
+        let mut created = Vec::with_capacity(expenses.len());
+        for batch in expenses.chunks(MAX_BULK_EXPENSES) {
+            let body = ExpenseBulkCreateRequest {
+                expenses: batch.to_vec(),
+            };
+            let result: Result<Vec<Expense>, ApiError> =
+                self.client.post("/expenses/bulk", &body).await;
+            match result {
+                Ok(expenses) => created.extend(expenses),
+                Err(e) if e.is_unsupported() => {
+                    for expense in batch {
+                        created.push(self.create(expense).await?);
+                    }
+                }
+                Err(e) => return Err(e),
+            }
+        }
+        Ok(created)
+    }
+
+    /// Update many expenses, returned in the same order
+    ///
+    /// Batched like `create_bulk`, with the same fallback for older servers.
+    pub async fn update_bulk(
+        &self,
+        updates: &[ExpenseBulkUpdate],
+    ) -> Result<Vec<Expense>, ApiError> {
+        let mut updated = Vec::with_capacity(updates.len());
+        for batch in updates.chunks(MAX_BULK_EXPENSES) {
+            let body = ExpenseBulkUpdateRequest {
+                updates: batch.to_vec(),
+            };
+            let result: Result<Vec<Expense>, ApiError> =
+                self.client.put("/expenses/bulk", &body).await;
+            match result {
+                Ok(expenses) => updated.extend(expenses),
+                // Also what a missing expense gets; updating one by one reports it
+                Err(e) if e.is_unsupported() => {
+                    for update in batch {
+                        updated.push(self.update(update.id, &update.changes).await?);
+                    }
+                }
+                Err(e) => return Err(e),
+            }
+        }
+        Ok(updated)
+    }
+
+    /// Attach a file to an expense, e.g. a photo of the receipt
+    ///
+    /// The file is sent as it is, with its name in the URL; the content type
+    /// comes from the name's extension.
+    pub async fn upload_attachment(
+        &self,
+        id: i32,
+        file_name: &str,
+        contents: Vec<u8>,
+    ) -> Result<Attachment, ApiError> {
+        self.client
+            .upload(
+                &format!("/expenses/{}/attachments", id),
+                &[("file_name", file_name)],
+                Attachment::content_type_for(file_name),
+                contents,
+            )
+            .await
+    }
+
+    /// Download an expense's attachment to `dest`; `progress` as for
+    /// `ApiClient::download`
+    pub async fn download_attachment(
+        &self,
+        id: i32,
+        attachment_id: i32,
+        dest: &Path,
+        progress: impl FnMut(u64, Option<u64>),
+    ) -> Result<u64, ApiError> {
+        self.client
+            .download(
+                &format!("/expenses/{}/attachments/{}", id, attachment_id),
+                dest,
+                progress,
+            )
+            .await
+    }
+
+    /// Remove an attachment from an expense
+    pub async fn delete_attachment(&self, id: i32, attachment_id: i32) -> Result<(), ApiError> {
+        self.client
+            .delete(&format!("/expenses/{}/attachments/{}", id, attachment_id))
+            .await
+    }
+
+    /// Delete an expense
+    pub async fn delete(&self, id: i32) -> Result<(), ApiError> {
+        self.client.delete(&format!("/expenses/{}", id)).await
+    }
+
+    /// Reorder expenses
+    pub async fn reorder(&self, expense_ids: &[i32]) -> Result<Vec<Expense>, ApiError> {
+        let body = ExpenseReorderRequest {
+            expense_ids: expense_ids.to_vec(),
+        };
+        self.client.post("/expenses/reorder", &body).await
+    }
+
+    /// Clone expenses to the next month
+    pub async fn clone_to_next_month(&self, month_id: i32) -> Result<CloneResponse, ApiError> {
+        self.client
+            .post(&format!("/expenses/clone-to-next-month/{}", month_id), &())
+            .await
+    }
+
+    /// Pay an expense (adds a payment entry with the projected amount)
+    pub async fn pay(
+        &self,
+        id: i32,
+        request: Option<&PayExpenseRequest>,
+    ) -> Result<Expense, ApiError> {
+        let body = request.cloned().unwrap_or_default();
+        self.client
+            .post(&format!("/expenses/{}/pay", id), &body)
+            .await
+    }
+}
@@ -0,0 +1,77 @@
+use std::time::Duration;
+
+use anyhow::{Context, Result};
+use reqwest::header::{HeaderMap, HeaderName, HeaderValue};
+
+/// A request the client is about to send, or just sent
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct RequestInfo {
+    pub method: String,
+    /// Path without the `/api/v1` prefix, e.g. `/expenses/12`
+    pub path: String,
+    /// Retries of the same request count up from 0
+    pub attempt: u32,
+}
+
+/// How an attempt ended
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct ResponseInfo {
+    /// `None` when no response arrived: refused, dropped or timed out
+    pub status: Option<u16>,
+    pub elapsed: Duration,
+}
+
+/// Code run around every request the client sends, e.g. to add headers, pass
+/// on tracing IDs or count requests
+///
+/// Hooks run for each attempt, so a retried request shows up once per try,
+/// in the order they were added. The change feed isn't included.
+pub trait RequestHook: Send + Sync {
+    /// Before an attempt is sent; `headers` are added to the request,
+    /// replacing ones of the same name
+    fn before_request(&self, _request: &RequestInfo, _headers: &mut HeaderMap) {}
+
+    /// After an attempt, with or without a response
+    fn after_response(&self, _request: &RequestInfo, _response: &ResponseInfo) {}
+}
+
+/// Closures only add headers
+impl<F> RequestHook for F
+where
+    F: Fn(&RequestInfo, &mut HeaderMap) + Send + Sync,
+{
+    fn before_request(&self, request: &RequestInfo, headers: &mut HeaderMap) {
+        self(request, headers)
+    }
+}
+
+/// The same headers on every request, e.g. for a gateway in front of the server
+#[derive(Debug, Clone, Default)]
+pub struct StaticHeaders {
+    headers: HeaderMap,
+}
+
+impl StaticHeaders {
+    /// Headers from name/value pairs; fails on the first invalid one
+    pub fn new<'a>(headers: impl IntoIterator<Item = (&'a str, &'a str)>) -> Result<Self> {
+        let mut map = HeaderMap::new();
+        for (name, value) in headers {
+            let name = HeaderName::from_bytes(name.trim().as_bytes())
+                .with_context(|| format!("Invalid header name '{}'", name))?;
+            let value = HeaderValue::from_str(value)
+                .with_context(|| format!("Invalid value for header '{}'", name))?;
+            map.insert(name, value);
+        }
+        Ok(Self { headers: map })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.headers.is_empty()
+    }
+}
+
+impl RequestHook for StaticHeaders {
+    fn before_request(&self, _request: &RequestInfo, headers: &mut HeaderMap) {
+        headers.extend(self.headers.clone());
+    }
+}
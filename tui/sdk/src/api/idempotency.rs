@@ -0,0 +1,66 @@
+use std::collections::HashMap;
+
+use serde_json::Value;
+
+/// Header carrying the key of a write that creates something
+pub const IDEMPOTENCY_KEY_HEADER: &str = "Idempotency-Key";
+
+/// A random UUID (v4) naming one intended write
+pub fn new_idempotency_key() -> String {
+    let bits = rand::random::<u128>();
+    // Version 4, RFC 4122 variant
+    let bits = (bits & !(0xf << 76) & !(0x3 << 62)) | (0x4 << 76) | (0x2 << 62);
+    let hex = format!("{:032x}", bits);
+    format!(
+        "{}-{}-{}-{}-{}",
+        &hex[..8],
+        &hex[8..12],
+        &hex[12..16],
+        &hex[16..20],
+        &hex[20..]
+    )
+}
+
+/// Keys of POSTs that may or may not have gone through
+///
+/// A POST that timed out, lost its connection or got a 5xx may have been
+/// applied anyway. Its key is kept here, by endpoint and body, so sending
+/// the same write again (say, submitting the form once more) reuses it and a
+/// server that saw the first one answers without creating a duplicate. Once
+/// the outcome is known the key is dropped, so a later identical write is a
+/// new one.
+#[derive(Debug, Default)]
+pub struct IdempotencyKeys {
+    unsettled: HashMap<String, String>,
+}
+
+impl IdempotencyKeys {
+    /// Key for a POST of `body` to `endpoint`: the unsettled one, or a new one
+    pub fn key_for(&self, endpoint: &str, body: Option<&Value>) -> String {
+        self.unsettled
+            .get(&Self::write_id(endpoint, body))
+            .cloned()
+            .unwrap_or_else(new_idempotency_key)
+    }
+
+    /// Remember `key` until the write's outcome is known, or forget it
+    pub fn record(&mut self, endpoint: &str, body: Option<&Value>, key: String, unsettled: bool) {
+        let id = Self::write_id(endpoint, body);
+        if unsettled {
+            self.unsettled.insert(id, key);
+        } else {
+            self.unsettled.remove(&id);
+        }
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.unsettled.is_empty()
+    }
+
+    fn write_id(endpoint: &str, body: Option<&Value>) -> String {
+        match body {
+            Some(body) => format!("{} {}", endpoint, body),
+            None => endpoint.to_string(),
+        }
+    }
+}
@@ -1,5 +1,5 @@
 use crate::api::client::{ApiClient, ApiError};
-use crate::models::{Income, IncomeCreate, IncomeFilters, IncomeUpdate};
+use crate::models::{Income, IncomeCreate, IncomeFilters, IncomeUpdate, Page, PageRequest};
 
 pub struct IncomesApi<'a> {
     client: &'a ApiClient,
@@ -16,6 +16,21 @@ impl<'a> IncomesApi<'a> {
         self.client.get_with_params("/incomes", &params).await
     }
 
+    /// Get one page of incomes, with the server's paging headers
+    pub async fn get_page(
+        &self,
+        filters: &IncomeFilters,
+        page: PageRequest,
+    ) -> Result<Page<Income>, ApiError> {
+        let filters = IncomeFilters {
+            page: Some(page),
+            ..filters.clone()
+        };
+        let params = filters.to_query_params();
+        let (items, info) = self.client.get_page("/incomes", &params).await?;
+        Ok(Page::with_info(items, page, info))
+    }
+
     /// Get a single income by ID
     pub async fn get_by_id(&self, id: i32) -> Result<Income, ApiError> {
         self.client.get(&format!("/incomes/{}", id)).await
@@ -26,9 +41,13 @@ impl<'a> IncomesApi<'a> {
         self.client.post("/incomes", income).await
     }
 
-    /// Update an income
+    /// Update the fields set in `income`, like `ExpensesApi::update`
     pub async fn update(&self, id: i32, income: &IncomeUpdate) -> Result<Income, ApiError> {
-        self.client.put(&format!("/incomes/{}", id), income).await
+        let endpoint = format!("/incomes/{}", id);
+        match self.client.patch(&endpoint, income).await {
+            Err(e) if e.is_unsupported() => self.client.put(&endpoint, income).await,
+            result => result,
+        }
     }
 
     /// Delete an income
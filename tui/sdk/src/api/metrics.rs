@@ -0,0 +1,154 @@
+use std::collections::{BTreeMap, VecDeque};
+use std::time::Duration;
+
+/// Latest latencies kept per endpoint for the 95th percentile
+const MAX_SAMPLES: usize = 200;
+
+/// How a single attempt ended
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Outcome {
+    /// Any response below 500, including 4xx answers
+    Response,
+    /// A 5xx response
+    ServerError,
+    /// No response: refused, dropped or timed out
+    NetworkError,
+}
+
+/// Latency and failure counts of one endpoint
+#[derive(Debug, Clone, PartialEq)]
+pub struct EndpointMetrics {
+    /// Method and path with IDs replaced, e.g. `GET /expenses/{id}`
+    pub endpoint: String,
+    pub requests: u64,
+    pub server_errors: u64,
+    pub network_errors: u64,
+    pub average: Duration,
+    /// Over the latest requests only
+    pub p95: Duration,
+}
+
+impl EndpointMetrics {
+    pub fn failures(&self) -> u64 {
+        self.server_errors + self.network_errors
+    }
+
+    /// Share of requests that failed, from 0 to 1
+    pub fn error_rate(&self) -> f64 {
+        if self.requests == 0 {
+            return 0.0;
+        }
+        self.failures() as f64 / self.requests as f64
+    }
+}
+
+#[derive(Debug, Default)]
+struct EndpointStats {
+    requests: u64,
+    server_errors: u64,
+    network_errors: u64,
+    total: Duration,
+    recent: VecDeque<Duration>,
+}
+
+impl EndpointStats {
+    fn record(&mut self, latency: Duration, outcome: Outcome) {
+        self.requests += 1;
+        match outcome {
+            Outcome::Response => {}
+            Outcome::ServerError => self.server_errors += 1,
+            Outcome::NetworkError => self.network_errors += 1,
+        }
+        self.total += latency;
+        if self.recent.len() == MAX_SAMPLES {
+            self.recent.pop_front();
+        }
+        self.recent.push_back(latency);
+    }
+
+    fn metrics(&self, endpoint: &str) -> EndpointMetrics {
+        EndpointMetrics {
+            endpoint: endpoint.to_string(),
+            requests: self.requests,
+            server_errors: self.server_errors,
+            network_errors: self.network_errors,
+            average: self.total / self.requests.max(1) as u32,
+            p95: percentile(&self.recent, 0.95),
+        }
+    }
+}
+
+/// Per-endpoint latency of every request the client sent
+///
+/// Each attempt counts, so a retried request shows up once per try. Kept in
+/// memory only.
+#[derive(Debug, Default)]
+pub struct RequestMetrics {
+    endpoints: BTreeMap<String, EndpointStats>,
+    /// Every endpoint together
+    all: EndpointStats,
+}
+
+impl RequestMetrics {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Count one attempt at `path` (without the `/api/v1` prefix or query)
+    pub fn record(&mut self, method: &str, path: &str, latency: Duration, outcome: Outcome) {
+        self.endpoints
+            .entry(endpoint_key(method, path))
+            .or_default()
+            .record(latency, outcome);
+        self.all.record(latency, outcome);
+    }
+
+    /// Every endpoint requested so far, by path
+    pub fn snapshot(&self) -> Vec<EndpointMetrics> {
+        self.endpoints
+            .iter()
+            .map(|(endpoint, stats)| stats.metrics(endpoint))
+            .collect()
+    }
+
+    /// All requests together, whatever the endpoint
+    pub fn totals(&self) -> EndpointMetrics {
+        self.all.metrics("All requests")
+    }
+
+    pub fn clear(&mut self) {
+        self.endpoints.clear();
+        self.all = EndpointStats::default();
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.endpoints.is_empty()
+    }
+}
+
+/// Group requests that differ only by ID, e.g. `GET /expenses/{id}`
+pub fn endpoint_key(method: &str, path: &str) -> String {
+    let path = path.split('?').next().unwrap_or_default();
+    let segments: Vec<&str> = path
+        .split('/')
+        .map(|segment| {
+            if !segment.is_empty() && segment.bytes().all(|b| b.is_ascii_digit()) {
+                "{id}"
+            } else {
+                segment
+            }
+        })
+        .collect();
+    format!("{} {}", method, segments.join("/"))
+}
+
+/// Nearest-rank percentile (`fraction` in 0..=1) of the samples
+fn percentile(samples: &VecDeque<Duration>, fraction: f64) -> Duration {
+    if samples.is_empty() {
+        return Duration::ZERO;
+    }
+    let mut sorted: Vec<Duration> = samples.iter().copied().collect();
+    sorted.sort();
+    let rank = (fraction * sorted.len() as f64).ceil() as usize;
+    sorted[rank.clamp(1, sorted.len()) - 1]
+}
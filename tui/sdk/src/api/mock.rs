@@ -0,0 +1,421 @@
+use std::collections::VecDeque;
+use std::sync::{Mutex, MutexGuard};
+
+use serde::{de::DeserializeOwned, Serialize};
+
+use crate::api::backend::BudgetApi;
+use crate::api::client::ApiError;
+use crate::models::{
+    Account, Category, CategorySummary, Debt, Expense, ExpenseCreate, ExpenseFilters,
+    ExpenseUpdate, Income, IncomeCreate, IncomeFilters, IncomeType, IncomeTypeSummary,
+    IncomeUpdate, KeyScopes, Month, MonthCreate, Page, PageInfo, PageRequest, PayExpenseRequest,
+    Period, PeriodSummaryResponse, Purchase, SummaryInsights, SummaryTotals, Transfer,
+};
+
+/// Everything a `MockApi` serves
+///
+/// Summaries are returned as stored rather than computed from the expenses and
+/// incomes; leave one unset to have its request fail with `NotFound`.
+#[derive(Debug, Clone, Default)]
+pub struct MockData {
+    pub months: Vec<Month>,
+    pub current_month_id: Option<i32>,
+    pub categories: Vec<Category>,
+    pub periods: Vec<Period>,
+    pub income_types: Vec<IncomeType>,
+    pub accounts: Vec<Account>,
+    pub transfers: Vec<Transfer>,
+    pub debts: Vec<Debt>,
+    pub expenses: Vec<Expense>,
+    pub incomes: Vec<Income>,
+    pub summary_totals: Option<SummaryTotals>,
+    pub category_summary: Vec<CategorySummary>,
+    pub income_type_summary: Vec<IncomeTypeSummary>,
+    pub period_summary: Option<PeriodSummaryResponse>,
+    pub insights: Option<SummaryInsights>,
+    pub key_scopes: Option<KeyScopes>,
+}
+
+/// In-memory `BudgetApi` for tests
+///
+/// Writes change the stored data the way the server would, and failures can be
+/// scripted with `fail_next`.
+#[derive(Debug, Default)]
+pub struct MockApi {
+    data: Mutex<MockData>,
+    failures: Mutex<VecDeque<ApiError>>,
+}
+
+impl MockApi {
+    /// Create a mock serving the given data
+    pub fn new(data: MockData) -> Self {
+        Self {
+            data: Mutex::new(data),
+            failures: Mutex::new(VecDeque::new()),
+        }
+    }
+
+    /// The stored data, to set up or check
+    pub fn data(&self) -> MutexGuard<'_, MockData> {
+        self.data.lock().unwrap()
+    }
+
+    /// Make the next request fail with an error (queued, one per request)
+    pub fn fail_next(&self, error: ApiError) {
+        self.failures.lock().unwrap().push_back(error);
+    }
+
+    /// Start a request: take a scripted failure, or lock the data
+    fn begin(&self) -> Result<MutexGuard<'_, MockData>, ApiError> {
+        match self.failures.lock().unwrap().pop_front() {
+            Some(error) => Err(error),
+            None => Ok(self.data()),
+        }
+    }
+}
+
+impl BudgetApi for MockApi {
+    async fn get_months(&self) -> Result<Vec<Month>, ApiError> {
+        Ok(self.begin()?.months.clone())
+    }
+
+    async fn get_current_month(&self) -> Result<Month, ApiError> {
+        let data = self.begin()?;
+        data.months
+            .iter()
+            .find(|month| Some(month.id) == data.current_month_id)
+            .cloned()
+            .ok_or(ApiError::NotFound)
+    }
+
+    async fn create_month(&self, month: &MonthCreate) -> Result<Month, ApiError> {
+        let mut data = self.begin()?;
+        if data
+            .months
+            .iter()
+            .any(|m| m.year == month.year && m.month == month.month)
+        {
+            return Err(ApiError::Conflict("Month already exists".to_string()));
+        }
+        let start = chrono::NaiveDate::from_ymd_opt(month.year, month.month as u32, 1)
+            .ok_or_else(|| ApiError::BadRequest("Invalid month".to_string()))?;
+        let end = start
+            .checked_add_months(chrono::Months::new(1))
+            .and_then(|next| next.pred_opt())
+            .unwrap_or(start);
+        let mut created = Month {
+            id: data.months.iter().map(|m| m.id).max().unwrap_or(0) + 1,
+            year: month.year,
+            month: month.month,
+            name: String::new(),
+            start_date: start.to_string(),
+            end_date: end.to_string(),
+            is_closed: false,
+            closed_at: None,
+            closed_by: None,
+            notes: None,
+        };
+        created.name = created.display_name();
+        data.months.push(created.clone());
+        Ok(created)
+    }
+
+    async fn get_categories(&self) -> Result<Vec<Category>, ApiError> {
+        Ok(self.begin()?.categories.clone())
+    }
+
+    async fn get_periods(&self) -> Result<Vec<Period>, ApiError> {
+        Ok(self.begin()?.periods.clone())
+    }
+
+    async fn get_income_types(&self) -> Result<Vec<IncomeType>, ApiError> {
+        Ok(self.begin()?.income_types.clone())
+    }
+
+    async fn get_accounts(&self) -> Result<Vec<Account>, ApiError> {
+        Ok(self.begin()?.accounts.clone())
+    }
+
+    async fn get_transfers(&self, month_id: Option<i32>) -> Result<Vec<Transfer>, ApiError> {
+        Ok(self
+            .begin()?
+            .transfers
+            .iter()
+            .filter(|t| month_id.is_none_or(|id| t.month_id == id))
+            .cloned()
+            .collect())
+    }
+
+    async fn get_debts(&self) -> Result<Vec<Debt>, ApiError> {
+        Ok(self.begin()?.debts.clone())
+    }
+
+    async fn get_expenses(&self, filters: &ExpenseFilters) -> Result<Vec<Expense>, ApiError> {
+        let mut expenses: Vec<Expense> = self
+            .begin()?
+            .expenses
+            .iter()
+            .filter(|e| filters.month_id.is_none_or(|id| e.month_id == id))
+            .filter(|e| filters.period.as_ref().is_none_or(|p| e.period == *p))
+            .filter(|e| filters.category.as_ref().is_none_or(|c| e.category == *c))
+            .cloned()
+            .collect();
+        expenses.sort_by_key(|e| e.order);
+        Ok(paginate(expenses, filters.page))
+    }
+
+    async fn get_incomes(&self, filters: &IncomeFilters) -> Result<Vec<Income>, ApiError> {
+        let incomes = self
+            .begin()?
+            .incomes
+            .iter()
+            .filter(|i| filters.month_id.is_none_or(|id| i.month_id == id))
+            .filter(|i| filters.period.as_ref().is_none_or(|p| i.period == *p))
+            .filter(|i| {
+                filters
+                    .income_type_id
+                    .is_none_or(|id| i.income_type_id == id)
+            })
+            .cloned()
+            .collect();
+        Ok(paginate(incomes, filters.page))
+    }
+
+    /// Sends the total count, like a server with paging headers
+    async fn get_expenses_page(
+        &self,
+        filters: &ExpenseFilters,
+        page: PageRequest,
+    ) -> Result<Page<Expense>, ApiError> {
+        let filters = ExpenseFilters {
+            page: None,
+            ..filters.clone()
+        };
+        let all = self.get_expenses(&filters).await?;
+        Ok(with_total(all, page))
+    }
+
+    /// Sends the total count, like a server with paging headers
+    async fn get_incomes_page(
+        &self,
+        filters: &IncomeFilters,
+        page: PageRequest,
+    ) -> Result<Page<Income>, ApiError> {
+        let filters = IncomeFilters {
+            page: None,
+            ..filters.clone()
+        };
+        let all = self.get_incomes(&filters).await?;
+        Ok(with_total(all, page))
+    }
+
+    async fn get_expense(&self, id: i32) -> Result<Expense, ApiError> {
+        self.begin()?
+            .expenses
+            .iter()
+            .find(|e| e.id == id)
+            .cloned()
+            .ok_or(ApiError::NotFound)
+    }
+
+    async fn get_income(&self, id: i32) -> Result<Income, ApiError> {
+        self.begin()?
+            .incomes
+            .iter()
+            .find(|i| i.id == id)
+            .cloned()
+            .ok_or(ApiError::NotFound)
+    }
+
+    async fn get_summary_totals(&self, _month_id: Option<i32>) -> Result<SummaryTotals, ApiError> {
+        self.begin()?
+            .summary_totals
+            .clone()
+            .ok_or(ApiError::NotFound)
+    }
+
+    async fn get_category_summary(
+        &self,
+        _month_id: Option<i32>,
+    ) -> Result<Vec<CategorySummary>, ApiError> {
+        Ok(self.begin()?.category_summary.clone())
+    }
+
+    async fn get_income_type_summary(
+        &self,
+        _month_id: Option<i32>,
+    ) -> Result<Vec<IncomeTypeSummary>, ApiError> {
+        Ok(self.begin()?.income_type_summary.clone())
+    }
+
+    async fn get_period_summary(
+        &self,
+        _month_id: Option<i32>,
+    ) -> Result<PeriodSummaryResponse, ApiError> {
+        self.begin()?
+            .period_summary
+            .clone()
+            .ok_or(ApiError::NotFound)
+    }
+
+    async fn get_insights(&self, _month_id: Option<i32>) -> Result<SummaryInsights, ApiError> {
+        self.begin()?.insights.clone().ok_or(ApiError::NotFound)
+    }
+
+    async fn get_key_scopes(&self) -> Result<KeyScopes, ApiError> {
+        self.begin()?.key_scopes.clone().ok_or(ApiError::NotFound)
+    }
+
+    async fn create_expense(&self, expense: &ExpenseCreate) -> Result<Expense, ApiError> {
+        let mut data = self.begin()?;
+        let created = Expense {
+            id: data.expenses.iter().map(|e| e.id).max().unwrap_or(0) + 1,
+            expense_name: expense.expense_name.clone(),
+            period: expense.period.clone(),
+            category: expense.category.clone(),
+            projected: expense.projected,
+            cost: expense.cost,
+            notes: expense.notes.clone(),
+            month_id: expense.month_id,
+            purchases: expense.purchases.clone(),
+            order: data.expenses.iter().map(|e| e.order).max().unwrap_or(0) + 1,
+            expense_date: expense.expense_date.clone(),
+            created_at: None,
+            updated_at: None,
+            created_by: None,
+            updated_by: None,
+            attachments: None,
+            account_id: expense.account_id,
+            currency: expense.currency.clone(),
+            custom_fields: expense.custom_fields.clone(),
+        };
+        data.expenses.push(created.clone());
+        Ok(created)
+    }
+
+    async fn update_expense(&self, id: i32, expense: &ExpenseUpdate) -> Result<Expense, ApiError> {
+        let mut data = self.begin()?;
+        let item = data
+            .expenses
+            .iter_mut()
+            .find(|e| e.id == id)
+            .ok_or(ApiError::NotFound)?;
+        *item = patched(item, expense)?;
+        Ok(item.clone())
+    }
+
+    async fn delete_expense(&self, id: i32) -> Result<(), ApiError> {
+        let mut data = self.begin()?;
+        let count = data.expenses.len();
+        data.expenses.retain(|e| e.id != id);
+        if data.expenses.len() == count {
+            return Err(ApiError::NotFound);
+        }
+        Ok(())
+    }
+
+    async fn pay_expense(
+        &self,
+        id: i32,
+        request: Option<&PayExpenseRequest>,
+    ) -> Result<Expense, ApiError> {
+        let mut data = self.begin()?;
+        let item = data
+            .expenses
+            .iter_mut()
+            .find(|e| e.id == id)
+            .ok_or(ApiError::NotFound)?;
+
+        // Same as the server: add a payment entry and total up the cost
+        let amount = request
+            .and_then(|request| request.amount)
+            .unwrap_or(item.projected);
+        let purchases = item.purchases.get_or_insert_with(Vec::new);
+        purchases.push(Purchase {
+            name: "Payment".to_string(),
+            amount,
+            date: Some(chrono::Local::now().format("%Y-%m-%d").to_string()),
+        });
+        item.cost = purchases.iter().map(|p| p.amount).sum();
+        Ok(item.clone())
+    }
+
+    async fn create_income(&self, income: &IncomeCreate) -> Result<Income, ApiError> {
+        let mut data = self.begin()?;
+        let now = chrono::Local::now().to_rfc3339();
+        let created = Income {
+            id: data.incomes.iter().map(|i| i.id).max().unwrap_or(0) + 1,
+            income_type_id: income.income_type_id,
+            period: income.period.clone(),
+            projected: income.projected,
+            amount: income.amount,
+            month_id: income.month_id,
+            created_at: now.clone(),
+            updated_at: now,
+            created_by: None,
+            updated_by: None,
+            account_id: income.account_id,
+            currency: income.currency.clone(),
+        };
+        data.incomes.push(created.clone());
+        Ok(created)
+    }
+
+    async fn update_income(&self, id: i32, income: &IncomeUpdate) -> Result<Income, ApiError> {
+        let mut data = self.begin()?;
+        let item = data
+            .incomes
+            .iter_mut()
+            .find(|i| i.id == id)
+            .ok_or(ApiError::NotFound)?;
+        *item = patched(item, income)?;
+        Ok(item.clone())
+    }
+
+    async fn delete_income(&self, id: i32) -> Result<(), ApiError> {
+        let mut data = self.begin()?;
+        let count = data.incomes.len();
+        data.incomes.retain(|i| i.id != id);
+        if data.incomes.len() == count {
+            return Err(ApiError::NotFound);
+        }
+        Ok(())
+    }
+}
+
+/// Apply the fields set in an update request to an item
+fn patched<T: Serialize + DeserializeOwned, U: Serialize>(
+    item: &T,
+    update: &U,
+) -> Result<T, ApiError> {
+    let invalid = |e: serde_json::Error| ApiError::InvalidResponse(e.to_string());
+    let mut value = serde_json::to_value(item).map_err(invalid)?;
+    if let (Some(target), serde_json::Value::Object(fields)) = (
+        value.as_object_mut(),
+        serde_json::to_value(update).map_err(invalid)?,
+    ) {
+        target.extend(fields);
+    }
+    serde_json::from_value(value).map_err(invalid)
+}
+
+/// The requested slice of `items`, with their count as the total
+fn with_total<T>(items: Vec<T>, page: PageRequest) -> Page<T> {
+    let info = PageInfo {
+        total: Some(items.len()),
+        ..Default::default()
+    };
+    Page::with_info(paginate(items, Some(page)), page, info)
+}
+
+/// Keep only the requested slice, the way the server applies limit and offset
+fn paginate<T>(items: Vec<T>, page: Option<PageRequest>) -> Vec<T> {
+    match page {
+        Some(page) => items
+            .into_iter()
+            .skip(page.offset)
+            .take(page.limit)
+            .collect(),
+        None => items,
+    }
+}
@@ -0,0 +1,53 @@
+mod accounts;
+mod auth;
+mod backend;
+mod backup;
+mod cache;
+mod categories;
+mod client;
+mod context;
+mod debts;
+mod debug_log;
+mod events;
+mod expenses;
+mod generated;
+mod hooks;
+mod idempotency;
+mod income_types;
+mod incomes;
+mod metrics;
+mod mock;
+mod months;
+mod pages;
+mod periods;
+mod range;
+mod retry;
+mod summary;
+mod transfers;
+
+pub use accounts::AccountsApi;
+pub use auth::AuthApi;
+pub use backend::BudgetApi;
+pub use backup::BackupApi;
+pub use cache::{CachedResponse, ResponseCache};
+pub use categories::CategoriesApi;
+pub use client::{parse_field_errors, ApiClient, ApiError, ClientOptions, FieldError, SyncReport};
+pub use context::RequestContext;
+pub use debts::DebtsApi;
+pub use debug_log::{format_body, DebugLog, MAX_BODY_CHARS};
+pub use events::{ChangeEvent, LiveEvent, SseMessage, SseParser, Subscription};
+pub use expenses::{ExpensesApi, MAX_BULK_EXPENSES};
+pub use generated::Operations;
+pub use hooks::{RequestHook, RequestInfo, ResponseInfo, StaticHeaders};
+pub use idempotency::{new_idempotency_key, IdempotencyKeys, IDEMPOTENCY_KEY_HEADER};
+pub use income_types::IncomeTypesApi;
+pub use incomes::IncomesApi;
+pub use metrics::{endpoint_key, EndpointMetrics, Outcome, RequestMetrics};
+pub use mock::{MockApi, MockData};
+pub use months::MonthsApi;
+pub use pages::{expense_pages, income_pages, PagedList, Pages};
+pub use periods::PeriodsApi;
+pub use range::{in_range, load_months, MonthData, MONTH_RANGE_PARALLELISM};
+pub use retry::{parse_retry_after, RetryPolicy, MAX_RETRY_AFTER};
+pub use summary::SummaryApi;
+pub use transfers::TransfersApi;
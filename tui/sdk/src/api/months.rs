@@ -1,5 +1,9 @@
+use std::path::Path;
+
 use crate::api::client::{ApiClient, ApiError};
-use crate::models::{Month, MonthCloseResponse, MonthCreate};
+use crate::models::{
+    Month, MonthCloseResponse, MonthCreate, MonthShareRequest, MonthUpdate, ShareLink,
+};
 
 pub struct MonthsApi<'a> {
     client: &'a ApiClient,
@@ -30,6 +34,11 @@ impl<'a> MonthsApi<'a> {
         self.client.post("/months", month).await
     }
 
+    /// Change a month's fields, such as its shared notes
+    pub async fn update(&self, id: i32, month: &MonthUpdate) -> Result<Month, ApiError> {
+        self.client.put(&format!("/months/{}", id), month).await
+    }
+
     /// Delete a month
     pub async fn delete(&self, id: i32) -> Result<(), ApiError> {
         self.client.delete(&format!("/months/{}", id)).await
@@ -46,4 +55,30 @@ impl<'a> MonthsApi<'a> {
     pub async fn open(&self, id: i32) -> Result<MonthCloseResponse, ApiError> {
         self.client.post(&format!("/months/{}/open", id), &()).await
     }
+
+    /// Create an expiring read-only link to a month's summary
+    ///
+    /// Servers without share links answer with an error that
+    /// `ApiError::is_unsupported`.
+    pub async fn share(&self, id: i32, request: &MonthShareRequest) -> Result<ShareLink, ApiError> {
+        self.client
+            .post(&format!("/months/{}/share", id), request)
+            .await
+    }
+
+    /// Download a month's expenses and incomes as CSV to `dest`
+    ///
+    /// One row per expense or income, with the columns `month`, `type`, `name`,
+    /// `category`, `period`, `projected` and `actual`. `progress` gets the
+    /// bytes received so far and the total, if known.
+    pub async fn export_csv(
+        &self,
+        id: i32,
+        dest: &Path,
+        progress: impl FnMut(u64, Option<u64>),
+    ) -> Result<u64, ApiError> {
+        self.client
+            .download(&format!("/months/{}/export", id), dest, progress)
+            .await
+    }
 }
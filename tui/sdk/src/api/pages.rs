@@ -0,0 +1,107 @@
+use crate::api::backend::BudgetApi;
+use crate::api::client::ApiError;
+use crate::models::{Expense, ExpenseFilters, Income, IncomeFilters, Page, PageRequest};
+
+/// Filters of a list that can be fetched in pages
+#[allow(async_fn_in_trait)]
+pub trait PagedList {
+    type Item;
+
+    /// Fetch one page of the list; a limit of 0 fetches all of it
+    async fn fetch<A: BudgetApi + ?Sized>(
+        &self,
+        api: &A,
+        page: PageRequest,
+    ) -> Result<Page<Self::Item>, ApiError>;
+}
+
+impl PagedList for ExpenseFilters {
+    type Item = Expense;
+
+    async fn fetch<A: BudgetApi + ?Sized>(
+        &self,
+        api: &A,
+        page: PageRequest,
+    ) -> Result<Page<Expense>, ApiError> {
+        match page.limit {
+            0 => Ok(Page::from_items(api.get_expenses(self).await?, page)),
+            _ => api.get_expenses_page(self, page).await,
+        }
+    }
+}
+
+impl PagedList for IncomeFilters {
+    type Item = Income;
+
+    async fn fetch<A: BudgetApi + ?Sized>(
+        &self,
+        api: &A,
+        page: PageRequest,
+    ) -> Result<Page<Income>, ApiError> {
+        match page.limit {
+            0 => Ok(Page::from_items(api.get_incomes(self).await?, page)),
+            _ => api.get_incomes_page(self, page).await,
+        }
+    }
+}
+
+/// A list read one page at a time
+///
+/// Each page is requested only when asked for, so a long list can be written
+/// out or copied without holding all of it. Iteration ends after the last
+/// page or the first error.
+pub struct Pages<'a, A: ?Sized, F> {
+    api: &'a A,
+    filters: F,
+    next: Option<PageRequest>,
+    total: Option<usize>,
+}
+
+impl<'a, A: BudgetApi + ?Sized, F: PagedList> Pages<'a, A, F> {
+    /// Pages of `page_size` items of the list `filters` select; 0 fetches
+    /// everything as one page
+    pub fn new(api: &'a A, filters: F, page_size: usize) -> Self {
+        Self {
+            api,
+            filters,
+            next: Some(PageRequest::first(page_size)),
+            total: None,
+        }
+    }
+
+    /// Items of the next page, or `None` once there are no more
+    pub async fn next_page(&mut self) -> Option<Result<Vec<F::Item>, ApiError>> {
+        let request = self.next.take()?;
+        match self.filters.fetch(self.api, request).await {
+            Ok(page) => {
+                self.next = page.next();
+                self.total = page.total.or(self.total);
+                Some(Ok(page.items))
+            }
+            Err(e) => Some(Err(e)),
+        }
+    }
+
+    /// Items in all pages, once the server has told
+    pub fn total(&self) -> Option<usize> {
+        self.total
+    }
+}
+
+/// Expenses matching `filters`, `page_size` at a time
+pub fn expense_pages<A: BudgetApi + ?Sized>(
+    api: &A,
+    filters: ExpenseFilters,
+    page_size: usize,
+) -> Pages<'_, A, ExpenseFilters> {
+    Pages::new(api, filters, page_size)
+}
+
+/// Incomes matching `filters`, `page_size` at a time
+pub fn income_pages<A: BudgetApi + ?Sized>(
+    api: &A,
+    filters: IncomeFilters,
+    page_size: usize,
+) -> Pages<'_, A, IncomeFilters> {
+    Pages::new(api, filters, page_size)
+}
@@ -0,0 +1,78 @@
+use futures_util::stream::{self, StreamExt, TryStreamExt};
+
+use crate::api::backend::BudgetApi;
+use crate::api::client::ApiError;
+use crate::models::{
+    CategorySummary, Expense, ExpenseFilters, Income, IncomeFilters, Month, SummaryTotals,
+};
+
+/// Months of a range fetched at the same time
+pub const MONTH_RANGE_PARALLELISM: usize = 4;
+
+/// Everything loaded for one month of a range
+#[derive(Debug, Clone)]
+pub struct MonthData {
+    pub month: Month,
+    pub expenses: Vec<Expense>,
+    pub incomes: Vec<Income>,
+    /// `None` from servers without the summary endpoints
+    pub totals: Option<SummaryTotals>,
+    pub category_summary: Option<Vec<CategorySummary>>,
+}
+
+/// Whether `month` is within `from..=to`, both `(year, month)`
+pub fn in_range(month: &Month, from: (i32, i32), to: (i32, i32)) -> bool {
+    let key = (month.year, month.month);
+    from <= key && key <= to
+}
+
+/// Load the given months, up to `parallelism` at a time, in the same order
+///
+/// The requests of one month are sent together too. Fails with the first
+/// error, except for summaries the server doesn't have.
+pub async fn load_months<A: BudgetApi + ?Sized>(
+    api: &A,
+    months: Vec<Month>,
+    parallelism: usize,
+) -> Result<Vec<MonthData>, ApiError> {
+    stream::iter(months)
+        .map(|month| load_month(api, month))
+        .buffered(parallelism.max(1))
+        .try_collect()
+        .await
+}
+
+async fn load_month<A: BudgetApi + ?Sized>(api: &A, month: Month) -> Result<MonthData, ApiError> {
+    let expense_filters = ExpenseFilters {
+        month_id: Some(month.id),
+        ..Default::default()
+    };
+    let income_filters = IncomeFilters {
+        month_id: Some(month.id),
+        ..Default::default()
+    };
+    let (expenses, incomes, totals, category_summary) = tokio::try_join!(
+        api.get_expenses(&expense_filters),
+        api.get_incomes(&income_filters),
+        optional(api.get_summary_totals(Some(month.id))),
+        optional(api.get_category_summary(Some(month.id))),
+    )?;
+    Ok(MonthData {
+        month,
+        expenses,
+        incomes,
+        totals,
+        category_summary,
+    })
+}
+
+/// `None` instead of an error when the server lacks the endpoint
+async fn optional<T>(
+    request: impl std::future::Future<Output = Result<T, ApiError>>,
+) -> Result<Option<T>, ApiError> {
+    match request.await {
+        Ok(value) => Ok(Some(value)),
+        Err(e) if e.is_unsupported() => Ok(None),
+        Err(e) => Err(e),
+    }
+}
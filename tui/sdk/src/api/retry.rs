@@ -0,0 +1,72 @@
+use std::time::Duration;
+
+use chrono::{DateTime, Utc};
+
+/// Longest `Retry-After` the client waits out before giving up on a request
+pub const MAX_RETRY_AFTER: Duration = Duration::from_secs(60);
+
+/// How the client retries transient failures (5xx responses, dropped connections)
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct RetryPolicy {
+    /// Retries after the first attempt (0 disables retrying)
+    pub max_retries: u32,
+    /// Delay before the first retry; doubled for each following one
+    pub base_delay: Duration,
+    /// Upper bound for a single delay
+    pub max_delay: Duration,
+}
+
+impl RetryPolicy {
+    /// Policy that never retries
+    pub fn none() -> Self {
+        Self {
+            max_retries: 0,
+            ..Self::default()
+        }
+    }
+
+    /// Exponential delay before retry number `attempt` (0-based), without jitter
+    pub fn backoff(&self, attempt: u32) -> Duration {
+        let factor = 2u32.saturating_pow(attempt);
+        self.base_delay
+            .checked_mul(factor)
+            .unwrap_or(self.max_delay)
+            .min(self.max_delay)
+    }
+
+    /// Delay before retry number `attempt`, with "equal jitter"
+    ///
+    /// `jitter` is a random value in 0..1; the delay is half the backoff plus
+    /// up to another half, so parallel clients don't retry in lockstep.
+    pub fn delay(&self, attempt: u32, jitter: f64) -> Duration {
+        let backoff = self.backoff(attempt);
+        backoff / 2 + backoff.mul_f64(jitter.clamp(0.0, 1.0) / 2.0)
+    }
+}
+
+impl Default for RetryPolicy {
+    fn default() -> Self {
+        Self {
+            max_retries: 3,
+            base_delay: Duration::from_millis(250),
+            max_delay: Duration::from_secs(5),
+        }
+    }
+}
+
+/// Parse a `Retry-After` header: seconds, or an HTTP date relative to `now`
+///
+/// A date in the past means no wait. Returns `None` for values that are
+/// neither.
+pub fn parse_retry_after(value: &str, now: DateTime<Utc>) -> Option<Duration> {
+    let value = value.trim();
+    if let Ok(secs) = value.parse::<u64>() {
+        return Some(Duration::from_secs(secs));
+    }
+    let date = DateTime::parse_from_rfc2822(value).ok()?;
+    Some(
+        (date.with_timezone(&Utc) - now)
+            .to_std()
+            .unwrap_or(Duration::ZERO),
+    )
+}
@@ -0,0 +1,30 @@
+use crate::api::client::{ApiClient, ApiError};
+use crate::models::{Transfer, TransferCreate};
+
+pub struct TransfersApi<'a> {
+    client: &'a ApiClient,
+}
+
+impl<'a> TransfersApi<'a> {
+    pub fn new(client: &'a ApiClient) -> Self {
+        Self { client }
+    }
+
+    /// Get the transfers of a month, or all of them
+    pub async fn get_all(&self, month_id: Option<i32>) -> Result<Vec<Transfer>, ApiError> {
+        let params: Vec<(&str, String)> = month_id
+            .map(|id| vec![("month_id", id.to_string())])
+            .unwrap_or_default();
+        self.client.get_with_params("/transfers", &params).await
+    }
+
+    /// Record a transfer
+    pub async fn create(&self, transfer: &TransferCreate) -> Result<Transfer, ApiError> {
+        self.client.post("/transfers", transfer).await
+    }
+
+    /// Delete a transfer
+    pub async fn delete(&self, id: i32) -> Result<(), ApiError> {
+        self.client.delete(&format!("/transfers/{}", id)).await
+    }
+}
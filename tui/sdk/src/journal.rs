@@ -0,0 +1,122 @@
+//! Writes made while the server was unreachable, kept on disk until sent.
+
+use std::fs;
+use std::path::Path;
+
+use anyhow::{Context, Result};
+use serde::{Deserialize, Serialize};
+use serde_json::Value;
+
+use crate::api::new_idempotency_key;
+
+const JOURNAL_FILE: &str = "journal.json";
+
+/// A write made while the server was unreachable
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct JournalEntry {
+    pub id: u64,
+    /// HTTP method: POST, PUT, PATCH or DELETE
+    pub method: String,
+    /// Endpoint relative to `/api/v1`, e.g. `/expenses/12`
+    pub endpoint: String,
+    pub body: Option<Value>,
+    pub queued_at: String,
+    /// Sent with POSTs, the same on every replay, so one that went through
+    /// but wasn't confirmed isn't applied twice
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub idempotency_key: Option<String>,
+}
+
+impl JournalEntry {
+    /// Resource and item ID the write targets
+    ///
+    /// `/expenses` gives `("/expenses", None)`; `/expenses/12` and
+    /// `/expenses/12/pay` give `("/expenses", Some(12))`.
+    pub fn target(&self) -> (&str, Option<i32>) {
+        let path = self.endpoint.split('?').next().unwrap_or_default();
+        match path[1.min(path.len())..].split_once('/') {
+            Some((resource, rest)) => {
+                let id = rest.split('/').next().unwrap_or_default();
+                (&path[..resource.len() + 1], id.parse().ok())
+            }
+            None => (path, None),
+        }
+    }
+
+    /// Check if this write creates a new item
+    pub fn is_create(&self) -> bool {
+        self.method == "POST" && self.target().1.is_none()
+    }
+
+    /// Check if this write deletes an item
+    pub fn is_delete(&self) -> bool {
+        self.method == "DELETE"
+    }
+}
+
+/// Writes waiting to be replayed once the server is reachable again, oldest first
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct WriteJournal {
+    #[serde(default)]
+    next_id: u64,
+    #[serde(default)]
+    entries: Vec<JournalEntry>,
+}
+
+impl WriteJournal {
+    /// Load the journal from the given data directory
+    ///
+    /// A directory without a journal gives an empty one.
+    pub fn load(data_dir: &Path) -> Result<Self> {
+        let path = data_dir.join(JOURNAL_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = fs::read_to_string(&path)
+            .with_context(|| format!("Failed to read {}", path.display()))?;
+        serde_json::from_str(&content)
+            .with_context(|| format!("Failed to parse {}", path.display()))
+    }
+
+    /// Save the journal to the given data directory, creating it as needed
+    pub fn save(&self, data_dir: &Path) -> Result<()> {
+        fs::create_dir_all(data_dir).context("Failed to create data directory")?;
+        let path = data_dir.join(JOURNAL_FILE);
+        let content = serde_json::to_string_pretty(self).context("Failed to serialize data")?;
+        fs::write(&path, content).with_context(|| format!("Failed to write {}", path.display()))
+    }
+
+    /// Append a write and return its entry ID
+    pub fn push(&mut self, method: &str, endpoint: &str, body: Option<Value>) -> u64 {
+        self.next_id += 1;
+        self.entries.push(JournalEntry {
+            id: self.next_id,
+            method: method.to_string(),
+            endpoint: endpoint.to_string(),
+            body,
+            queued_at: chrono::Local::now().to_rfc3339(),
+            idempotency_key: (method == "POST").then(new_idempotency_key),
+        });
+        self.next_id
+    }
+
+    /// Remove an entry once it has been replayed
+    pub fn remove(&mut self, id: u64) {
+        self.entries.retain(|entry| entry.id != id);
+    }
+
+    /// Queued writes, oldest first
+    pub fn entries(&self) -> &[JournalEntry] {
+        &self.entries
+    }
+
+    /// Number of queued writes
+    pub fn len(&self) -> usize {
+        self.entries.len()
+    }
+
+    /// Check if nothing is waiting to sync
+    pub fn is_empty(&self) -> bool {
+        self.entries.is_empty()
+    }
+}
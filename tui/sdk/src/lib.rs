@@ -0,0 +1,24 @@
+//! Budget SDK - typed client for the Appz Budget API
+//!
+//! The HTTP client and data models behind the Budget TUI, for other tools
+//! (bots, exporters, scripts) that talk to the same server.
+//!
+//! ```no_run
+//! use budget_sdk::api::ApiClient;
+//!
+//! # async fn run() -> anyhow::Result<()> {
+//! let api = ApiClient::new("http://localhost:8000".to_string(), "api-key".to_string())?;
+//! let month = api.months().get_current().await?;
+//! let totals = api.summary().get_totals(None, Some(month.id)).await?;
+//! println!("{}: {:.2} left", month.display_name(), totals.total_current);
+//! # Ok(())
+//! # }
+//! ```
+//!
+//! [`api::BudgetApi`] is the interface the TUI is written against;
+//! [`api::MockApi`] implements it in memory for tests.
+
+pub mod api;
+pub mod journal;
+pub mod models;
+pub mod validate;
@@ -0,0 +1,59 @@
+use serde::{Deserialize, Serialize};
+
+/// What kind of place money is kept in
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum AccountKind {
+    #[default]
+    Checking,
+    CreditCard,
+    Cash,
+}
+
+impl AccountKind {
+    pub fn all() -> &'static [AccountKind] {
+        &[
+            AccountKind::Checking,
+            AccountKind::CreditCard,
+            AccountKind::Cash,
+        ]
+    }
+
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            AccountKind::Checking => "Checking",
+            AccountKind::CreditCard => "Credit card",
+            AccountKind::Cash => "Cash",
+        }
+    }
+
+    /// The kind after this one, past the last starting over
+    pub fn next(&self) -> Self {
+        let kinds = Self::all();
+        let idx = kinds.iter().position(|k| k == self).unwrap_or(0);
+        kinds[(idx + 1) % kinds.len()]
+    }
+}
+
+/// A bank account, card or wallet that expenses are paid from and incomes
+/// paid into
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Account {
+    pub id: i32,
+    pub name: String,
+    pub kind: AccountKind,
+}
+
+/// A new account
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct AccountCreate {
+    pub name: String,
+    pub kind: AccountKind,
+}
+
+/// New name and kind of an account
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct AccountUpdate {
+    pub name: String,
+    pub kind: AccountKind,
+}
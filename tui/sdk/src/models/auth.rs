@@ -0,0 +1,198 @@
+use serde::{Deserialize, Serialize};
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct User {
+    pub id: i32,
+    pub email: String,
+    pub full_name: Option<String>,
+    pub is_active: bool,
+    pub is_admin: bool,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct UserLogin {
+    pub email: String,
+    pub password: String,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct TokenResponse {
+    pub access_token: String,
+    pub token_type: String,
+    pub user_id: i32,
+    pub email: String,
+}
+
+/// Answer to a password login
+#[derive(Debug, Clone, Deserialize)]
+#[serde(untagged)]
+pub enum LoginResponse {
+    Token(TokenResponse),
+    /// The account has two-factor login on; answer with a code from the
+    /// authenticator app
+    TotpRequired(TotpChallenge),
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct TotpChallenge {
+    /// Ties the code to the password step
+    pub challenge: String,
+    /// Seconds the challenge can be answered for
+    pub expires_in: Option<u64>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct TotpVerify {
+    pub challenge: String,
+    pub code: String,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ChangePasswordRequest {
+    pub current_password: String,
+    pub new_password: String,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct ChangePasswordResponse {
+    pub message: String,
+}
+
+/// Start of a device login: the user approves it in a browser, on any
+/// device, while the client polls for the token
+#[derive(Debug, Clone, Deserialize)]
+pub struct DeviceCode {
+    /// Sent back when polling; not shown to the user
+    pub device_code: String,
+    /// Code the user enters at `verification_uri`
+    pub user_code: String,
+    pub verification_uri: String,
+    /// `verification_uri` with the code filled in, if the server offers it
+    pub verification_uri_complete: Option<String>,
+    /// Seconds the code can be approved for
+    pub expires_in: u64,
+    /// Seconds to wait between polls
+    #[serde(default = "default_poll_interval")]
+    pub interval: u64,
+}
+
+fn default_poll_interval() -> u64 {
+    5
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct DeviceTokenRequest {
+    pub device_code: String,
+}
+
+/// Where a device login stands after a poll
+#[derive(Debug, Clone)]
+pub enum DevicePoll {
+    Token(TokenResponse),
+    /// Not approved yet; poll again after the interval
+    Pending,
+    /// Polled too often; wait 5 seconds longer between polls from now on
+    SlowDown,
+    /// Refused on the verification page
+    Denied,
+    /// Not approved in time; a new code is needed
+    Expired,
+}
+
+/// A new account, created by an admin
+#[derive(Debug, Clone, Serialize)]
+pub struct UserCreate {
+    pub email: String,
+    pub password: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub full_name: Option<String>,
+    pub is_active: bool,
+    pub is_admin: bool,
+}
+
+/// Changes to an account; fields left out stay as they are
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct UserUpdate {
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub email: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub full_name: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub is_active: Option<bool>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub is_admin: Option<bool>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct AdminSetPassword {
+    pub new_password: String,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct MessageResponse {
+    pub message: String,
+}
+
+/// What the API key may change, from `GET /auth/key`
+///
+/// Each scope is `read`, `write` for everything, or `<area>:write` for one
+/// area; `*` allows everything too. Servers without scoped keys don't have
+/// the endpoint, and their keys may change anything.
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct KeyScopes {
+    pub scopes: Vec<String>,
+}
+
+impl KeyScopes {
+    /// Check if the key may change `scope`'s area
+    pub fn allows(&self, scope: Scope) -> bool {
+        self.scopes
+            .iter()
+            .any(|s| s == "*" || s == "write" || s == scope.as_str())
+    }
+
+    /// Check if the key may change nothing at all
+    pub fn is_read_only(&self) -> bool {
+        Scope::ALL.iter().all(|scope| !self.allows(*scope))
+    }
+}
+
+/// Area of the budget a scoped API key may be allowed to change
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub enum Scope {
+    Expenses,
+    Incomes,
+    /// Creating, closing and reopening months
+    Months,
+    /// Categories, periods and income types
+    Settings,
+}
+
+impl Scope {
+    pub const ALL: [Scope; 4] = [
+        Scope::Expenses,
+        Scope::Incomes,
+        Scope::Months,
+        Scope::Settings,
+    ];
+
+    /// Name of the scope as the server lists it
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            Scope::Expenses => "expenses:write",
+            Scope::Incomes => "incomes:write",
+            Scope::Months => "months:write",
+            Scope::Settings => "settings:write",
+        }
+    }
+
+    /// What the scope covers, for messages
+    pub fn label(&self) -> &'static str {
+        match self {
+            Scope::Expenses => "expenses",
+            Scope::Incomes => "incomes",
+            Scope::Months => "months",
+            Scope::Settings => "categories, periods and income types",
+        }
+    }
+}
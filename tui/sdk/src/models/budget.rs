@@ -0,0 +1,78 @@
+/// Where spending stands against its projection
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum BudgetStatus {
+    OnTrack,
+    /// Under the thresholds, but spent faster than the month is passing
+    Ahead,
+    /// Close to the projection, past the "near" threshold
+    Near,
+    /// Past the "over" threshold
+    Over,
+}
+
+impl BudgetStatus {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            BudgetStatus::OnTrack => "OK",
+            BudgetStatus::Ahead => "Ahead",
+            BudgetStatus::Near => "Near",
+            BudgetStatus::Over => "Over",
+        }
+    }
+}
+
+/// How far spending may run ahead of the month's pace before it is flagged
+pub const PACE_TOLERANCE: f64 = 0.1;
+
+/// Percent of the projection at which spending counts as near or over budget
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub struct BudgetThresholds {
+    pub near_percent: f64,
+    pub over_percent: f64,
+}
+
+impl Default for BudgetThresholds {
+    fn default() -> Self {
+        Self {
+            near_percent: 90.0,
+            over_percent: 100.0,
+        }
+    }
+}
+
+impl BudgetThresholds {
+    /// Status of an actual amount against its projection
+    ///
+    /// Anything spent on a zero projection is over budget. The over threshold
+    /// is exclusive, so spending exactly the projection is not over at 100%.
+    pub fn status(&self, actual: f64, projected: f64) -> BudgetStatus {
+        if projected <= 0.0 {
+            return if actual > 0.0 {
+                BudgetStatus::Over
+            } else {
+                BudgetStatus::OnTrack
+            };
+        }
+        let percent = actual / projected * 100.0;
+        if percent > self.over_percent {
+            BudgetStatus::Over
+        } else if percent >= self.near_percent {
+            BudgetStatus::Near
+        } else {
+            BudgetStatus::OnTrack
+        }
+    }
+
+    /// Status that also flags spending ahead of the month's pace
+    ///
+    /// `pace` is the share of the month that has passed (see `Month::pace`);
+    /// 60% spent is fine near the end of the month but flagged in its first week.
+    pub fn status_with_pace(&self, actual: f64, projected: f64, pace: f64) -> BudgetStatus {
+        match self.status(actual, projected) {
+            BudgetStatus::OnTrack if actual / projected > pace + PACE_TOLERANCE => {
+                BudgetStatus::Ahead
+            }
+            status => status,
+        }
+    }
+}
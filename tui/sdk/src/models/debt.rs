@@ -0,0 +1,51 @@
+use serde::{Deserialize, Serialize};
+
+/// A loan, card balance or other money owed
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Debt {
+    pub id: i32,
+    pub name: String,
+    /// What is still owed
+    pub principal: f64,
+    /// Yearly interest, as a percentage
+    pub interest_rate: f64,
+    pub minimum_payment: f64,
+    /// Category and period of the expenses payments are recorded as
+    pub category: String,
+    pub period: String,
+}
+
+/// A new debt
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct DebtCreate {
+    pub name: String,
+    pub principal: f64,
+    pub interest_rate: f64,
+    pub minimum_payment: f64,
+    pub category: String,
+    pub period: String,
+}
+
+/// Every field of a debt, as saved after editing or a payment
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct DebtUpdate {
+    pub name: String,
+    pub principal: f64,
+    pub interest_rate: f64,
+    pub minimum_payment: f64,
+    pub category: String,
+    pub period: String,
+}
+
+impl From<&Debt> for DebtUpdate {
+    fn from(debt: &Debt) -> Self {
+        Self {
+            name: debt.name.clone(),
+            principal: debt.principal,
+            interest_rate: debt.interest_rate,
+            minimum_payment: debt.minimum_payment,
+            category: debt.category.clone(),
+            period: debt.period.clone(),
+        }
+    }
+}
@@ -0,0 +1,246 @@
+use serde::{Deserialize, Serialize};
+
+use super::{CustomFields, PageRequest};
+
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Purchase {
+    pub name: String,
+    pub amount: f64,
+    pub date: Option<String>,
+}
+
+/// A file kept with an expense, e.g. a receipt
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Attachment {
+    pub id: i32,
+    pub file_name: String,
+    pub content_type: Option<String>,
+    /// Bytes
+    pub size: Option<u64>,
+    pub uploaded_at: Option<String>,
+}
+
+impl Attachment {
+    /// Content type to upload a file as, from the extension of its name
+    pub fn content_type_for(file_name: &str) -> &'static str {
+        let extension = file_name
+            .rsplit_once('.')
+            .map(|(_, extension)| extension.to_ascii_lowercase())
+            .unwrap_or_default();
+        match extension.as_str() {
+            "pdf" => "application/pdf",
+            "png" => "image/png",
+            "jpg" | "jpeg" => "image/jpeg",
+            "gif" => "image/gif",
+            "webp" => "image/webp",
+            "heic" => "image/heic",
+            "txt" => "text/plain",
+            "csv" => "text/csv",
+            _ => "application/octet-stream",
+        }
+    }
+
+    /// Check if a file of this name is a document or image that is safe to
+    /// hand to the system's viewer; anything else could be a program
+    pub fn is_viewable(file_name: &str) -> bool {
+        Self::content_type_for(file_name) != "application/octet-stream"
+    }
+
+    /// Name to save the file under locally: only the last part of the name,
+    /// so a server can't write elsewhere, with anything a shell or `cmd`
+    /// would read specially replaced, and the id keeping files with the same
+    /// name apart
+    pub fn local_file_name(&self) -> String {
+        let name: String = std::path::Path::new(&self.file_name)
+            .file_name()
+            .map(|name| name.to_string_lossy().into_owned())
+            .unwrap_or_default()
+            .chars()
+            .map(|c| {
+                if c.is_alphanumeric() || matches!(c, '.' | '-' | '_' | ' ') {
+                    c
+                } else {
+                    '_'
+                }
+            })
+            .collect();
+        let name = name.trim_start_matches('.');
+        if name.is_empty() {
+            format!("{}-attachment", self.id)
+        } else {
+            format!("{}-{}", self.id, name)
+        }
+    }
+}
+
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Expense {
+    pub id: i32,
+    pub expense_name: String,
+    pub period: String,
+    pub category: String,
+    pub projected: f64,
+    pub cost: f64,
+    pub notes: Option<String>,
+    pub month_id: i32,
+    pub purchases: Option<Vec<Purchase>>,
+    pub order: i32,
+    pub expense_date: Option<String>,
+    // Audit fields; older servers leave them out
+    pub created_at: Option<String>,
+    pub updated_at: Option<String>,
+    pub created_by: Option<String>,
+    pub updated_by: Option<String>,
+    /// Receipts and other files; servers without attachments leave it out
+    pub attachments: Option<Vec<Attachment>>,
+    /// Account it is paid from; servers without accounts leave it out
+    pub account_id: Option<i32>,
+    /// Currency code when not the budget's own, e.g. "EUR"; servers without
+    /// currencies leave it out
+    pub currency: Option<String>,
+    /// Fields a customized server adds
+    #[serde(flatten)]
+    pub custom_fields: CustomFields,
+}
+
+impl Expense {
+    /// How many files are attached
+    pub fn attachment_count(&self) -> usize {
+        self.attachments.as_ref().map_or(0, Vec::len)
+    }
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ExpenseCreate {
+    pub expense_name: String,
+    pub period: String,
+    pub category: String,
+    pub projected: f64,
+    pub cost: f64,
+    pub notes: Option<String>,
+    pub month_id: i32,
+    pub purchases: Option<Vec<Purchase>>,
+    pub expense_date: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub account_id: Option<i32>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub currency: Option<String>,
+    #[serde(flatten)]
+    pub custom_fields: CustomFields,
+}
+
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct ExpenseUpdate {
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub expense_name: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub period: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub category: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub projected: Option<f64>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub cost: Option<f64>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub notes: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub month_id: Option<i32>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub purchases: Option<Vec<Purchase>>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub expense_date: Option<String>,
+    /// `Some(None)` takes the expense off its account
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub account_id: Option<Option<i32>>,
+    /// `Some(None)` puts the expense back in the budget's currency
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub currency: Option<Option<String>>,
+    /// Custom fields to change; null clears one
+    #[serde(flatten)]
+    pub custom_fields: CustomFields,
+}
+
+impl ExpenseUpdate {
+    /// True when no field is set, i.e. there is nothing to send
+    pub fn is_empty(&self) -> bool {
+        self.expense_name.is_none()
+            && self.period.is_none()
+            && self.category.is_none()
+            && self.projected.is_none()
+            && self.cost.is_none()
+            && self.notes.is_none()
+            && self.month_id.is_none()
+            && self.purchases.is_none()
+            && self.expense_date.is_none()
+            && self.account_id.is_none()
+            && self.currency.is_none()
+            && self.custom_fields.is_empty()
+    }
+}
+
+/// Changes to one expense in a bulk update
+#[derive(Debug, Clone, Serialize)]
+pub struct ExpenseBulkUpdate {
+    pub id: i32,
+    #[serde(flatten)]
+    pub changes: ExpenseUpdate,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ExpenseBulkCreateRequest {
+    pub expenses: Vec<ExpenseCreate>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ExpenseBulkUpdateRequest {
+    pub updates: Vec<ExpenseBulkUpdate>,
+}
+
+#[derive(Debug, Clone, Default)]
+pub struct ExpenseFilters {
+    pub period: Option<String>,
+    pub category: Option<String>,
+    pub month_id: Option<i32>,
+    /// Only this slice of the matches; all of them when unset
+    pub page: Option<PageRequest>,
+}
+
+impl ExpenseFilters {
+    pub fn to_query_params(&self) -> Vec<(&str, String)> {
+        let mut params = Vec::new();
+        if let Some(ref period) = self.period {
+            params.push(("period", period.clone()));
+        }
+        if let Some(ref category) = self.category {
+            params.push(("category", category.clone()));
+        }
+        if let Some(month_id) = self.month_id {
+            params.push(("month_id", month_id.to_string()));
+        }
+        if let Some(page) = self.page {
+            params.push(("limit", page.limit.to_string()));
+            params.push(("offset", page.offset.to_string()));
+        }
+        params
+    }
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ExpenseReorderRequest {
+    pub expense_ids: Vec<i32>,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+pub struct CloneResponse {
+    pub message: String,
+    pub cloned_count: i32,
+    pub cloned_income_count: i32,
+    pub next_month_id: i32,
+    pub next_month_name: String,
+}
+
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct PayExpenseRequest {
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub amount: Option<f64>,
+}
@@ -1,5 +1,7 @@
 use serde::{Deserialize, Serialize};
 
+use super::PageRequest;
+
 #[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
 pub struct Income {
     pub id: i32,
@@ -12,6 +14,11 @@ pub struct Income {
     pub updated_at: String,
     pub created_by: Option<String>,
     pub updated_by: Option<String>,
+    /// Account it is paid into; servers without accounts leave it out
+    pub account_id: Option<i32>,
+    /// Currency code when not the budget's own, e.g. "EUR"; servers without
+    /// currencies leave it out
+    pub currency: Option<String>,
 }
 
 #[derive(Debug, Clone, Serialize)]
@@ -21,6 +28,10 @@ pub struct IncomeCreate {
     pub projected: f64,
     pub amount: f64,
     pub month_id: i32,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub account_id: Option<i32>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub currency: Option<String>,
 }
 
 #[derive(Debug, Clone, Default, Serialize)]
@@ -35,6 +46,25 @@ pub struct IncomeUpdate {
     pub amount: Option<f64>,
     #[serde(skip_serializing_if = "Option::is_none")]
     pub month_id: Option<i32>,
+    /// `Some(None)` takes the income off its account
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub account_id: Option<Option<i32>>,
+    /// `Some(None)` puts the income back in the budget's currency
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub currency: Option<Option<String>>,
+}
+
+impl IncomeUpdate {
+    /// True when no field is set, i.e. there is nothing to send
+    pub fn is_empty(&self) -> bool {
+        self.income_type_id.is_none()
+            && self.period.is_none()
+            && self.projected.is_none()
+            && self.amount.is_none()
+            && self.month_id.is_none()
+            && self.account_id.is_none()
+            && self.currency.is_none()
+    }
 }
 
 #[derive(Debug, Clone, Default)]
@@ -42,6 +72,8 @@ pub struct IncomeFilters {
     pub period: Option<String>,
     pub income_type_id: Option<i32>,
     pub month_id: Option<i32>,
+    /// Only this slice of the matches; all of them when unset
+    pub page: Option<PageRequest>,
 }
 
 impl IncomeFilters {
@@ -56,6 +88,10 @@ impl IncomeFilters {
         if let Some(month_id) = self.month_id {
             params.push(("month_id", month_id.to_string()));
         }
+        if let Some(page) = self.page {
+            params.push(("limit", page.limit.to_string()));
+            params.push(("offset", page.offset.to_string()));
+        }
         params
     }
 }
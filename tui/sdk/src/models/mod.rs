@@ -0,0 +1,27 @@
+mod account;
+mod auth;
+mod budget;
+mod custom;
+mod debt;
+mod expense;
+mod generated;
+mod income;
+mod money;
+mod month;
+mod page;
+mod summary;
+mod transfer;
+
+pub use account::*;
+pub use auth::*;
+pub use budget::*;
+pub use custom::*;
+pub use debt::*;
+pub use expense::*;
+pub use generated::*;
+pub use income::*;
+pub use money::*;
+pub use month::*;
+pub use page::*;
+pub use summary::*;
+pub use transfer::*;
@@ -0,0 +1,184 @@
+//! Fixed-point amounts
+//!
+//! The server sends amounts as JSON numbers, which the models keep as `f64`.
+//! Adding many of those up in floating point drifts off by fractions of a
+//! cent, enough for a column of even amounts to total `9.99` instead of
+//! `10.00`. `Money` holds whole cents: amounts are rounded to the cent once,
+//! on the way in, and added up exactly. Typed amounts are parsed straight
+//! to cents, without going through `f64` at all.
+
+use std::fmt;
+use std::iter::Sum;
+use std::ops::{Add, AddAssign, Mul, Neg, Sub, SubAssign};
+
+use serde::{Deserialize, Deserializer, Serialize, Serializer};
+
+/// An amount in whole cents
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, PartialOrd, Ord, Hash)]
+pub struct Money(i64);
+
+impl Money {
+    pub const ZERO: Money = Money(0);
+
+    pub const fn from_cents(cents: i64) -> Self {
+        Self(cents)
+    }
+
+    pub const fn cents(self) -> i64 {
+        self.0
+    }
+
+    /// The nearest cent to `amount`; halves round away from zero
+    pub fn from_f64(amount: f64) -> Self {
+        if !amount.is_finite() {
+            return Self::ZERO;
+        }
+        Self((amount * 100.0).round() as i64)
+    }
+
+    pub fn to_f64(self) -> f64 {
+        self.0 as f64 / 100.0
+    }
+
+    /// A plain decimal amount such as `12`, `12.5`, `.5` or `-3.25`
+    ///
+    /// Digits past the cents round to the nearest cent. Grouping, currency
+    /// symbols and exponents aren't amounts.
+    pub fn parse(text: &str) -> Option<Self> {
+        let text = text.trim();
+        let (negative, digits) = match text.strip_prefix('-') {
+            Some(rest) => (true, rest),
+            None => (false, text.strip_prefix('+').unwrap_or(text)),
+        };
+        let (whole, fraction) = digits.split_once('.').unwrap_or((digits, ""));
+        if whole.is_empty() && fraction.is_empty() {
+            return None;
+        }
+        if !whole
+            .chars()
+            .chain(fraction.chars())
+            .all(|c| c.is_ascii_digit())
+        {
+            return None;
+        }
+
+        let units: i64 = if whole.is_empty() {
+            0
+        } else {
+            whole.parse().ok()?
+        };
+        let mut fraction = fraction.bytes().map(|b| i64::from(b - b'0'));
+        let tenths = fraction.next().unwrap_or(0);
+        let hundredths = fraction.next().unwrap_or(0);
+        let round_up = fraction.next().is_some_and(|digit| digit >= 5);
+
+        let cents = units
+            .checked_mul(100)?
+            .checked_add(tenths * 10 + hundredths + i64::from(round_up))?;
+        Some(Self(if negative { -cents } else { cents }))
+    }
+
+    pub fn abs(self) -> Self {
+        Self(self.0.abs())
+    }
+
+    pub fn is_negative(self) -> bool {
+        self.0 < 0
+    }
+
+    pub fn is_zero(self) -> bool {
+        self.0 == 0
+    }
+}
+
+/// Two decimals with a `.`, e.g. `-1234.50`; for inputs and files, not for
+/// the screen
+impl fmt::Display for Money {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        let sign = if self.0 < 0 { "-" } else { "" };
+        let cents = self.0.unsigned_abs();
+        write!(f, "{}{}.{:02}", sign, cents / 100, cents % 100)
+    }
+}
+
+impl Add for Money {
+    type Output = Money;
+
+    fn add(self, other: Money) -> Money {
+        Money(self.0 + other.0)
+    }
+}
+
+impl AddAssign for Money {
+    fn add_assign(&mut self, other: Money) {
+        self.0 += other.0;
+    }
+}
+
+impl Sub for Money {
+    type Output = Money;
+
+    fn sub(self, other: Money) -> Money {
+        Money(self.0 - other.0)
+    }
+}
+
+impl SubAssign for Money {
+    fn sub_assign(&mut self, other: Money) {
+        self.0 -= other.0;
+    }
+}
+
+impl Neg for Money {
+    type Output = Money;
+
+    fn neg(self) -> Money {
+        Money(-self.0)
+    }
+}
+
+impl Mul<i64> for Money {
+    type Output = Money;
+
+    fn mul(self, times: i64) -> Money {
+        Money(self.0 * times)
+    }
+}
+
+impl Sum for Money {
+    fn sum<I: Iterator<Item = Money>>(iter: I) -> Money {
+        iter.fold(Money::ZERO, Add::add)
+    }
+}
+
+/// Totals amounts from the models, each rounded to the cent first
+impl Sum<f64> for Money {
+    fn sum<I: Iterator<Item = f64>>(iter: I) -> Money {
+        iter.map(Money::from_f64).sum()
+    }
+}
+
+impl<'a> Sum<&'a f64> for Money {
+    fn sum<I: Iterator<Item = &'a f64>>(iter: I) -> Money {
+        iter.copied().sum()
+    }
+}
+
+impl From<Money> for f64 {
+    fn from(money: Money) -> f64 {
+        money.to_f64()
+    }
+}
+
+/// A JSON number, as the server expects
+impl Serialize for Money {
+    fn serialize<S: Serializer>(&self, serializer: S) -> Result<S::Ok, S::Error> {
+        serializer.serialize_f64(self.to_f64())
+    }
+}
+
+impl<'de> Deserialize<'de> for Money {
+    fn deserialize<D: Deserializer<'de>>(deserializer: D) -> Result<Self, D::Error> {
+        f64::deserialize(deserializer).map(Money::from_f64)
+    }
+}
@@ -0,0 +1,98 @@
+use chrono::{Datelike, NaiveDate};
+
+use super::{Month, ShareLink};
+
+/// Whether entries of a month can still change
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum MonthStatus {
+    Open,
+    /// Closed once its books were done; servers refuse changes to its
+    /// entries unless asked to allow them
+    Closed,
+}
+
+impl MonthStatus {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            MonthStatus::Open => "Open",
+            MonthStatus::Closed => "Closed",
+        }
+    }
+}
+
+impl Month {
+    pub fn status(&self) -> MonthStatus {
+        match self.is_closed {
+            true => MonthStatus::Closed,
+            false => MonthStatus::Open,
+        }
+    }
+
+    /// Notes shared with everyone using the budget, unless blank
+    pub fn shared_notes(&self) -> Option<&str> {
+        self.notes
+            .as_deref()
+            .filter(|notes| !notes.trim().is_empty())
+    }
+
+    /// Get display name (e.g., "November 2024")
+    pub fn display_name(&self) -> String {
+        let month_name = match self.month {
+            1 => "January",
+            2 => "February",
+            3 => "March",
+            4 => "April",
+            5 => "May",
+            6 => "June",
+            7 => "July",
+            8 => "August",
+            9 => "September",
+            10 => "October",
+            11 => "November",
+            12 => "December",
+            _ => "Unknown",
+        };
+        format!("{} {}", month_name, self.year)
+    }
+
+    /// First and last day of the month
+    ///
+    /// Uses the month's start and end dates, or the calendar month if they
+    /// don't parse.
+    pub fn date_range(&self) -> Option<(NaiveDate, NaiveDate)> {
+        let parse = |date: &str| NaiveDate::parse_from_str(date.get(..10)?, "%Y-%m-%d").ok();
+        match (parse(&self.start_date), parse(&self.end_date)) {
+            (Some(start), Some(end)) if start <= end => Some((start, end)),
+            _ => {
+                let start = NaiveDate::from_ymd_opt(self.year, self.month as u32, 1)?;
+                let next = start
+                    .with_day(28)
+                    .and_then(|d| d.checked_add_days(chrono::Days::new(4)))
+                    .and_then(|d| d.with_day(1))
+                    .unwrap_or(start);
+                Some((start, next.pred_opt().unwrap_or(start)))
+            }
+        }
+    }
+
+    /// Share of the month that has passed by `today`, from 0 to 1
+    ///
+    /// Counts today as passed, so the last day of the month is 1.
+    pub fn pace(&self, today: NaiveDate) -> f64 {
+        let (start, end) = match self.date_range() {
+            Some(range) => range,
+            None => return 0.0,
+        };
+
+        let total = (end - start).num_days() + 1;
+        let passed = (today - start).num_days() + 1;
+        (passed as f64 / total as f64).clamp(0.0, 1.0)
+    }
+}
+
+impl ShareLink {
+    /// Link on the server at `base_url`, the address the user already reaches it by
+    pub fn url_for(&self, base_url: &str) -> String {
+        format!("{}{}", base_url.trim_end_matches('/'), self.path)
+    }
+}
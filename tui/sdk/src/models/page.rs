@@ -0,0 +1,124 @@
+/// Which slice of a list to request
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct PageRequest {
+    pub offset: usize,
+    pub limit: usize,
+}
+
+impl PageRequest {
+    /// The first page of the given size
+    pub fn first(limit: usize) -> Self {
+        Self { offset: 0, limit }
+    }
+
+    /// The page a `limit`/`offset` query asks for, e.g. from a `Link` URL
+    pub fn from_url(url: &str) -> Option<Self> {
+        let query = url.split_once('?')?.1;
+        let query = query.split('#').next().unwrap_or_default();
+        let (mut offset, mut limit) = (None, None);
+        for pair in query.split('&') {
+            match pair.split_once('=') {
+                Some(("offset", value)) => offset = value.parse().ok(),
+                Some(("limit", value)) => limit = value.parse().ok(),
+                _ => {}
+            }
+        }
+        Some(Self {
+            offset: offset.unwrap_or(0),
+            limit: limit?,
+        })
+    }
+}
+
+/// Paging headers of a list response, when the server sends them
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct PageInfo {
+    /// Items in all pages, from `X-Total-Count`
+    pub total: Option<usize>,
+    /// Whether `Link` has a `rel="next"` entry; `None` without the header
+    pub has_next: Option<bool>,
+    /// The next page, when the `rel="next"` URL has `limit`/`offset`
+    pub next: Option<PageRequest>,
+}
+
+impl PageInfo {
+    /// Read the `X-Total-Count` and `Link` header values
+    pub fn parse(total_count: Option<&str>, link: Option<&str>) -> Self {
+        let next_url = link.and_then(|link| {
+            link.split(',').find_map(|entry| {
+                let (url, params) = entry.split_once(';')?;
+                let is_next = params.split(';').any(|param| {
+                    let param = param.replace(' ', "");
+                    param == "rel=\"next\"" || param == "rel=next"
+                });
+                let url = url.trim().strip_prefix('<')?.strip_suffix('>')?;
+                is_next.then_some(url)
+            })
+        });
+        Self {
+            total: total_count.and_then(|total| total.trim().parse().ok()),
+            has_next: link.map(|_| next_url.is_some()),
+            next: next_url.and_then(PageRequest::from_url),
+        }
+    }
+}
+
+/// One page of a list, and whether the server may have more after it
+#[derive(Debug, Clone, PartialEq)]
+pub struct Page<T> {
+    pub items: Vec<T>,
+    pub offset: usize,
+    pub has_more: bool,
+    /// Items in all pages, when the server tells
+    pub total: Option<usize>,
+    /// Next page from the server's `Link` header
+    next_link: Option<PageRequest>,
+}
+
+impl<T> Page<T> {
+    /// Wrap the items returned for a request
+    ///
+    /// Only a full page can have more after it. A server without paging
+    /// returns the whole list, which is longer than the limit and complete.
+    pub fn from_items(items: Vec<T>, request: PageRequest) -> Self {
+        Self {
+            has_more: request.limit > 0 && items.len() == request.limit,
+            offset: request.offset,
+            items,
+            total: None,
+            next_link: None,
+        }
+    }
+
+    /// Wrap the items returned for a request, with its paging headers
+    ///
+    /// A `Link` header decides whether there is a next page; otherwise the
+    /// total count does, and without either only a full page may have more.
+    pub fn with_info(items: Vec<T>, request: PageRequest, info: PageInfo) -> Self {
+        let page = Self::from_items(items, request);
+        let has_more = if let Some(has_next) = info.has_next {
+            has_next
+        } else if let Some(total) = info.total {
+            page.offset + page.items.len() < total && !page.items.is_empty()
+        } else {
+            page.has_more
+        };
+        Self {
+            has_more,
+            total: info.total,
+            next_link: info.next,
+            ..page
+        }
+    }
+
+    /// Request for the page after this one, if there may be one
+    pub fn next(&self) -> Option<PageRequest> {
+        if !self.has_more {
+            return None;
+        }
+        self.next_link.or(Some(PageRequest {
+            offset: self.offset + self.items.len(),
+            limit: self.items.len(),
+        }))
+    }
+}
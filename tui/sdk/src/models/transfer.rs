@@ -0,0 +1,43 @@
+use serde::{Deserialize, Serialize};
+
+/// Money moved from one account to another, e.g. a savings top-up
+///
+/// Neither an income nor an expense, so budget totals leave it out.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Transfer {
+    pub id: i32,
+    pub from_account_id: i32,
+    pub to_account_id: i32,
+    pub amount: f64,
+    pub month_id: i32,
+    pub transfer_date: Option<String>,
+    pub notes: Option<String>,
+}
+
+impl Transfer {
+    /// What the transfer adds to `account_id`: the amount going in, minus it
+    /// going out, zero when the account isn't part of it
+    pub fn net_for(&self, account_id: i32) -> f64 {
+        let mut net = 0.0;
+        if self.to_account_id == account_id {
+            net += self.amount;
+        }
+        if self.from_account_id == account_id {
+            net -= self.amount;
+        }
+        net
+    }
+}
+
+/// A new transfer
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct TransferCreate {
+    pub from_account_id: i32,
+    pub to_account_id: i32,
+    pub amount: f64,
+    pub month_id: i32,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub transfer_date: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub notes: Option<String>,
+}
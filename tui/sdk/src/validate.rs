@@ -0,0 +1,312 @@
+//! Checks on request bodies before they are sent
+//!
+//! The server answers a blank name or a color that isn't hex with a 422.
+//! Checking first gives the same field-level [`FieldError`]s without the
+//! round trip, named by their field in the request body as the server's are,
+//! so a form can mark the field to fix.
+//!
+//! ```
+//! use budget_sdk::models::CategoryCreate;
+//! use budget_sdk::validate::Validate;
+//!
+//! let category = CategoryCreate {
+//!     name: "Groceries".to_string(),
+//!     color: Some("green".to_string()),
+//! };
+//! assert_eq!(category.validate()[0].field.as_deref(), Some("color"));
+//! ```
+
+use crate::api::{ApiError, FieldError};
+use crate::models::{
+    AccountCreate, AccountUpdate, CategoryCreate, CategoryUpdate, DebtCreate, DebtUpdate,
+    ExpenseCreate, ExpenseUpdate, IncomeCreate, IncomeTypeCreate, IncomeTypeUpdate, IncomeUpdate,
+    MonthUpdate, PeriodCreate, PeriodUpdate, Purchase, TransferCreate,
+};
+
+/// Longest name of an expense, category, period, income type, account or
+/// debt
+pub const MAX_NAME_LEN: usize = 100;
+/// Longest notes on an expense or transfer
+pub const MAX_NOTES_LEN: usize = 1000;
+/// Longest shared notes on a month
+pub const MAX_MONTH_NOTES_LEN: usize = 5000;
+
+/// A request body that can be checked before it is sent
+pub trait Validate {
+    /// What is wrong with the body; empty when it can be sent
+    fn validate(&self) -> Vec<FieldError>;
+
+    /// The errors as the server would answer them, as
+    /// [`ApiError::Validation`]
+    fn check(&self) -> Result<(), ApiError> {
+        let errors = self.validate();
+        match errors.is_empty() {
+            true => Ok(()),
+            false => Err(ApiError::Validation(errors)),
+        }
+    }
+}
+
+/// Check if `text` is a color like `#8b5cf6` or `#fff`
+pub fn is_hex_color(text: &str) -> bool {
+    match text.strip_prefix('#') {
+        Some(hex) => matches!(hex.len(), 3 | 6) && hex.chars().all(|c| c.is_ascii_hexdigit()),
+        None => false,
+    }
+}
+
+/// Errors collected field by field
+#[derive(Default)]
+struct Checks(Vec<FieldError>);
+
+impl Checks {
+    fn fail(&mut self, field: &str, message: String) {
+        self.0.push(FieldError {
+            field: Some(field.to_string()),
+            message,
+        });
+    }
+
+    /// A name that must be there and not be too long
+    fn name(&mut self, field: &str, label: &str, value: &str) {
+        if value.trim().is_empty() {
+            self.fail(field, format!("{} is required", label));
+        } else {
+            self.max_len(field, label, value, MAX_NAME_LEN);
+        }
+    }
+
+    fn required(&mut self, field: &str, label: &str, value: &str) {
+        if value.trim().is_empty() {
+            self.fail(field, format!("{} is required", label));
+        }
+    }
+
+    fn max_len(&mut self, field: &str, label: &str, value: &str, max: usize) {
+        if value.chars().count() > max {
+            self.fail(
+                field,
+                format!("{} must be at most {} characters", label, max),
+            );
+        }
+    }
+
+    fn non_negative(&mut self, field: &str, label: &str, value: f64) {
+        if !value.is_finite() {
+            self.fail(field, format!("{} must be a number", label));
+        } else if value < 0.0 {
+            self.fail(field, format!("{} can't be negative", label));
+        }
+    }
+
+    fn color(&mut self, field: &str, value: Option<&str>) {
+        if value.is_some_and(|color| !is_hex_color(color)) {
+            self.fail(field, "Color must be hex, like #8b5cf6".to_string());
+        }
+    }
+
+    fn currency(&mut self, field: &str, value: Option<&str>) {
+        let valid = |code: &str| code.len() == 3 && code.chars().all(|c| c.is_ascii_alphabetic());
+        if value.is_some_and(|code| !valid(code)) {
+            self.fail(
+                field,
+                "Currency must be a three-letter code, like EUR".to_string(),
+            );
+        }
+    }
+
+    fn purchases(&mut self, purchases: Option<&[Purchase]>) {
+        for purchase in purchases.unwrap_or_default() {
+            if purchase.name.trim().is_empty() {
+                self.fail("purchases", "Every purchase needs a name".to_string());
+                return;
+            }
+            if purchase.name.chars().count() > MAX_NAME_LEN {
+                self.fail(
+                    "purchases",
+                    format!("Purchase names must be at most {} characters", MAX_NAME_LEN),
+                );
+                return;
+            }
+        }
+    }
+
+    fn interest_rate(&mut self, value: f64) {
+        if !(0.0..=100.0).contains(&value) {
+            self.fail(
+                "interest_rate",
+                "Interest must be a percentage from 0 to 100".to_string(),
+            );
+        }
+    }
+}
+
+impl Validate for ExpenseCreate {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        checks.name("expense_name", "Name", &self.expense_name);
+        checks.required("period", "Period", &self.period);
+        checks.required("category", "Category", &self.category);
+        checks.non_negative("projected", "Projected", self.projected);
+        checks.non_negative("cost", "Cost", self.cost);
+        if let Some(notes) = &self.notes {
+            checks.max_len("notes", "Notes", notes, MAX_NOTES_LEN);
+        }
+        checks.purchases(self.purchases.as_deref());
+        checks.currency("currency", self.currency.as_deref());
+        checks.0
+    }
+}
+
+impl Validate for ExpenseUpdate {
+    /// Only the fields being changed
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        if let Some(name) = &self.expense_name {
+            checks.name("expense_name", "Name", name);
+        }
+        if let Some(period) = &self.period {
+            checks.required("period", "Period", period);
+        }
+        if let Some(category) = &self.category {
+            checks.required("category", "Category", category);
+        }
+        if let Some(projected) = self.projected {
+            checks.non_negative("projected", "Projected", projected);
+        }
+        if let Some(cost) = self.cost {
+            checks.non_negative("cost", "Cost", cost);
+        }
+        if let Some(notes) = &self.notes {
+            checks.max_len("notes", "Notes", notes, MAX_NOTES_LEN);
+        }
+        checks.purchases(self.purchases.as_deref());
+        checks.currency("currency", self.currency.clone().flatten().as_deref());
+        checks.0
+    }
+}
+
+impl Validate for IncomeCreate {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        checks.required("period", "Period", &self.period);
+        checks.non_negative("projected", "Projected", self.projected);
+        checks.non_negative("amount", "Amount", self.amount);
+        checks.currency("currency", self.currency.as_deref());
+        checks.0
+    }
+}
+
+impl Validate for IncomeUpdate {
+    /// Only the fields being changed
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        if let Some(period) = &self.period {
+            checks.required("period", "Period", period);
+        }
+        if let Some(projected) = self.projected {
+            checks.non_negative("projected", "Projected", projected);
+        }
+        if let Some(amount) = self.amount {
+            checks.non_negative("amount", "Amount", amount);
+        }
+        checks.currency("currency", self.currency.clone().flatten().as_deref());
+        checks.0
+    }
+}
+
+/// Categories, periods and income types all have a name and a color
+macro_rules! validate_named_color {
+    ($($body:ty),+) => {
+        $(
+            impl Validate for $body {
+                fn validate(&self) -> Vec<FieldError> {
+                    let mut checks = Checks::default();
+                    checks.name("name", "Name", &self.name);
+                    checks.color("color", self.color.as_deref());
+                    checks.0
+                }
+            }
+        )+
+    };
+}
+
+validate_named_color!(
+    CategoryCreate,
+    CategoryUpdate,
+    PeriodCreate,
+    PeriodUpdate,
+    IncomeTypeCreate,
+    IncomeTypeUpdate
+);
+
+impl Validate for AccountCreate {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        checks.name("name", "Name", &self.name);
+        checks.0
+    }
+}
+
+impl Validate for AccountUpdate {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        checks.name("name", "Name", &self.name);
+        checks.0
+    }
+}
+
+impl Validate for DebtCreate {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        checks.name("name", "Name", &self.name);
+        checks.non_negative("principal", "Owed", self.principal);
+        checks.interest_rate(self.interest_rate);
+        checks.non_negative("minimum_payment", "Minimum payment", self.minimum_payment);
+        checks.required("category", "Category", &self.category);
+        checks.required("period", "Period", &self.period);
+        checks.0
+    }
+}
+
+impl Validate for DebtUpdate {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        checks.name("name", "Name", &self.name);
+        checks.non_negative("principal", "Owed", self.principal);
+        checks.interest_rate(self.interest_rate);
+        checks.non_negative("minimum_payment", "Minimum payment", self.minimum_payment);
+        checks.required("category", "Category", &self.category);
+        checks.required("period", "Period", &self.period);
+        checks.0
+    }
+}
+
+impl Validate for MonthUpdate {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        if let Some(notes) = &self.notes {
+            checks.max_len("notes", "Notes", notes, MAX_MONTH_NOTES_LEN);
+        }
+        checks.0
+    }
+}
+
+impl Validate for TransferCreate {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut checks = Checks::default();
+        if self.from_account_id == self.to_account_id {
+            checks.fail(
+                "to_account_id",
+                "From and to must be different accounts".to_string(),
+            );
+        }
+        if !(self.amount.is_finite() && self.amount > 0.0) {
+            checks.fail("amount", "Amount must be more than zero".to_string());
+        }
+        if let Some(notes) = &self.notes {
+            checks.max_len("notes", "Notes", notes, MAX_NOTES_LEN);
+        }
+        checks.0
+    }
+}
@@ -1,19 +0,0 @@
-mod auth;
-mod categories;
-mod client;
-mod expenses;
-mod income_types;
-mod incomes;
-mod months;
-mod periods;
-mod summary;
-
-pub use auth::AuthApi;
-pub use categories::CategoriesApi;
-pub use client::ApiClient;
-pub use expenses::ExpensesApi;
-pub use income_types::IncomeTypesApi;
-pub use incomes::IncomesApi;
-pub use months::MonthsApi;
-pub use periods::PeriodsApi;
-pub use summary::SummaryApi;
@@ -1,46 +1,71 @@
 use anyhow::Result;
+use chrono::{DateTime, Local};
 use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
 use ratatui::{backend::CrosstermBackend, Terminal};
 use std::io::Stdout;
+use std::time::{Duration, Instant};
 
-use crate::api::ApiClient;
-use crate::config::Config;
+use crate::api::{ApiClient, ApiError, BudgetApi, ChangeEvent, LiveEvent, Subscription};
+use crate::changelog::{self, CHANGELOG};
+use crate::clock::Clock;
+use crate::config::{self, validate, Config, RateSource, Signal, StartupMonth, TaskKind};
 use crate::event::{Event, EventHandler};
-use crate::models::ExpenseFilters;
+use crate::export::{month_csv_file_name, TaxReport, YearReport};
+use crate::feedback::{self, ProblemReport};
+use crate::import::HistoryImport;
+use crate::integrations::{fetch_rates, month_rows, GoogleSheets, ServiceAccount};
+use crate::models::{
+    Attachment, DebtUpdate, DevicePoll, Expense, ExpenseFilters, ExpenseUpdate, Income,
+    IncomeFilters, IncomeUpdate, LoginResponse, Money, MonthShareRequest, MonthUpdate, Scope,
+    TokenResponse,
+};
+use crate::state::audit::AuditTrail;
+use crate::state::autofill::AutofillPreview;
+use crate::state::currency::Rates;
+use crate::state::debts;
 use crate::state::forms::{
-    CategoryFormState, ExpenseField, ExpenseFormState, IncomeFormState, IncomeTypeFormState,
-    PasswordFormState, PeriodFormState, PurchaseEditField,
+    self, AccountFormState, CategoryFormState, DebtField, DebtFormState, ExpenseField,
+    ExpenseFormState, IncomeFormState, IncomeTypeFormState, PasswordFormState, PeriodFormState,
+    PurchaseEditField, TransferField, TransferFormState,
+};
+use crate::state::reallocate::ReallocateBoard;
+use crate::state::rollover::{self, calendar_month};
+use crate::state::{
+    money_input, next_filter, Action, AppState, DashboardTab, EntityType, GroupKey, MergePreview,
+    Modal, ReimbursementReport, Screen, ServerFeature, SettingsTab, SortKey,
+};
+use crate::storage::{
+    self, ExpenseLedgers, LastView, Ledger, LocalState, MonthChecklist, MonthNotes, TaxFlags,
 };
-use crate::state::{AppState, DashboardTab, Modal, Screen, SettingsTab};
 use crate::ui;
-use crate::ui::api_config::{self, ApiConfigField};
-use crate::ui::login::{self, LoginField};
-
-/// Generate a random hex color
-fn generate_random_color() -> String {
-    use std::time::{SystemTime, UNIX_EPOCH};
-
-    // Simple random using system time
-    let seed = SystemTime::now()
-        .duration_since(UNIX_EPOCH)
-        .unwrap()
-        .as_nanos() as u64;
-
-    // Generate RGB values using simple linear congruential generator
-    let r = ((seed.wrapping_mul(1103515245).wrapping_add(12345)) % 256) as u8;
-    let g = ((seed.wrapping_mul(214013).wrapping_add(2531011)) % 256) as u8;
-    let b = ((seed.wrapping_mul(16807).wrapping_add(0)) % 256) as u8;
-
-    // Ensure colors are not too dark or too light
-    let r = r.clamp(50, 220);
-    let g = g.clamp(50, 220);
-    let b = b.clamp(50, 220);
-
-    format!("#{:02x}{:02x}{:02x}", r, g, b)
-}
+use crate::ui::api_config::{self, ApiConfigField, LockPrompt};
+use crate::ui::clipboard;
+use crate::ui::login::{self, DeviceLogin, LoginField, TotpPrompt};
+use crate::ui::notify;
+use crate::ui::palette;
+use crate::ui::theme::ColorMap;
+use crate::validate::Validate;
+
+/// Application version, embedded by the build script
+pub const VERSION: &str = env!("BUDGET_VERSION");
+
+/// Largest file `u` attaches to an expense
+const MAX_ATTACHMENT_BYTES: u64 = 10 * 1024 * 1024;
+
+/// How often to retry sending writes queued while offline
+const SYNC_INTERVAL: Duration = Duration::from_secs(30);
+
+/// How long the screen stays inverted for the flash signal
+const FLASH_DURATION: Duration = Duration::from_millis(400);
 
-/// Application version from VERSION file at project root
-pub const VERSION: &str = include_str!("../../VERSION");
+/// Rows before the end of a paged list at which the next page is fetched
+const PREFETCH_ROWS: usize = 5;
+/// Shown when the saved or current session is no longer accepted
+const SESSION_EXPIRED: &str = "Session expired - please log in again";
+/// Shown when a two-factor challenge ran out before a valid code was sent
+const EXPIRED_CODE_REQUEST: &str = "The code request expired - please log in again";
+/// Shown when a device login wasn't approved in time
+const EXPIRED_DEVICE_CODE: &str = "The device code expired - press Ctrl+D for a new one";
 
 /// Main application struct
 pub struct App {
@@ -51,15 +76,34 @@ pub struct App {
     /// API client
     pub api: ApiClient,
     /// API configuration state
+    pub api_profile: String,
     pub api_url: String,
     pub api_key: String,
     pub api_config_focused_field: usize,
     pub api_config_error: Option<String>,
+    /// Passphrase prompt for the locked API config screen
+    pub lock_prompt: Option<LockPrompt>,
+    pub lock_passphrase: String,
+    /// Lock screen covering every screen until the passphrase is entered
+    pub app_locked: bool,
+    pub unlock_error: Option<String>,
+    /// Notification of a task that ended while locked, sent once unlocked
+    held_notice: Option<(Signal, String)>,
+    /// When a key was last pressed, for locking when idle
+    last_input: Instant,
+    /// Long task the current key started, for signaling when it ends
+    task: Option<TaskKind>,
+    /// Screen inverted until then, for the flash signal
+    flash_until: Option<Instant>,
     /// Login form state - credentials
     pub login_email: String,
     pub login_password: String,
     pub login_focused_field: usize,
     pub login_error: Option<String>,
+    /// Second login step, while the server waits for a two-factor code
+    pub login_totp: Option<TotpPrompt>,
+    /// Device login waiting to be approved in a browser
+    pub login_device: Option<DeviceLogin>,
     /// Expense form state
     pub expense_form: ExpenseFormState,
     /// Income form state
@@ -72,64 +116,252 @@ pub struct App {
     pub income_type_form: IncomeTypeFormState,
     /// Password form state
     pub password_form: PasswordFormState,
+    /// Last attempt to replay the offline write queue
+    last_sync_attempt: Instant,
+    /// Change feed of the server, while logged in
+    live_updates: Option<Subscription>,
+    /// Changes from other clients not reloaded yet
+    remote_changes: Vec<ChangeEvent>,
+    /// Calendar month last followed with `pin_current`
+    calendar_month: Option<(i32, i32)>,
+    /// Month to open on from `--month`, until the first load
+    start_month: Option<(i32, i32)>,
+    /// Tab, month and filters the app was left on, until the first load;
+    /// only what the startup settings leave open
+    remembered_view: Option<LastView>,
+    /// Colors of the configured theme
+    pub theme: ColorMap,
+    /// Render with ANSI-16 colors and ASCII borders
+    pub low_color: bool,
     /// Should quit
     pub should_quit: bool,
 }
 
 impl App {
-    /// Create a new application instance
-    pub async fn new() -> Result<Self> {
-        let config = Config::load()?;
-        let api = ApiClient::new(config.server.url.clone(), config.server.api_key.clone())?;
-
-        // If we have a stored token, set it and try to validate
-        let mut state = AppState::default();
-        if let Some(ref token) = config.auth.token {
-            api.set_token(token.clone());
-            // Try to get current user to validate token
+    /// Create a new application instance, on the profile `profile` (from
+    /// `--profile`) or the config's active one
+    pub async fn new(profile: Option<&str>) -> Result<Self> {
+        let mut config = Config::load_profile(profile)?;
+        let api = ApiClient::with_options(
+            config.server.url.clone(),
+            config.server.api_key.clone(),
+            &config.network.client_options(),
+        )?;
+        api.set_retry_policy(config.network.retry_policy());
+        let headers = config.network.header_hook()?;
+        if !headers.is_empty() {
+            api.add_hook(headers);
+        }
+        if config.network.debug_enabled() {
+            api.enable_debug_log(&Config::debug_log_path()?)?;
+        }
+
+        // Local notes and checklist live next to the config, keyed by server.
+        // Without a config dir the app still starts, just without them.
+        let (data_dir, data_dir_error) = match config.data_dir() {
+            Ok(dir) => (Some(dir), None),
+            Err(e) => (None, Some(e)),
+        };
+        if let Some(dir) = &data_dir {
+            api.enable_write_queue(dir.clone())?;
+        }
+        let mut state = AppState {
+            notes: data_dir
+                .as_deref()
+                .and_then(|dir| MonthNotes::load(dir).ok())
+                .unwrap_or_default(),
+            checklist: data_dir
+                .as_deref()
+                .and_then(|dir| MonthChecklist::load(dir).ok())
+                .unwrap_or_default(),
+            tax_flags: data_dir
+                .as_deref()
+                .and_then(|dir| TaxFlags::load(dir).ok())
+                .unwrap_or_default(),
+            ledgers: data_dir
+                .as_deref()
+                .and_then(|dir| ExpenseLedgers::load(dir).ok())
+                .unwrap_or_default(),
+            thresholds: config.thresholds.clone(),
+            money: config.display.money.clone(),
+            rates: Rates::from_config(&config.currency),
+            dates: config.display.dates,
+            page_size: config.network.page_size,
+            envelope_categories: config.envelopes.weekly.clone(),
+            period_display: config.periods.clone(),
+            advisor: config.advisor.clone(),
+            clock: Clock::from_env()?,
+            ..Default::default()
+        };
+        state.set_profile(&config.profiles);
+        state.ui.large_text = config.display.large_text;
+        if let Some(tab) = config.startup.tab()? {
+            state.ui.selected_tab = tab;
+        }
+        let start_month = config.startup.opening_month()?;
+        let remembered_view = config
+            .startup
+            .remember
+            .then(|| {
+                data_dir
+                    .as_deref()
+                    .and_then(|dir| LastView::load(dir).ok())
+                    .unwrap_or_default()
+            })
+            .map(|mut view| {
+                // What's set to open on wins over what was left open
+                let startup = &config.startup;
+                if startup.tab.is_some() {
+                    view.tab = None;
+                }
+                if start_month.is_some()
+                    || startup.month != StartupMonth::Current
+                    || config.months.pin_current
+                {
+                    view.month = None;
+                }
+                if startup.period.is_some() {
+                    view.period = None;
+                }
+                if startup.category.is_some() {
+                    view.category = None;
+                }
+                view
+            });
+
+        // Reuse the saved session unless its token has expired or is for
+        // another server
+        let mut login_error = None;
+        if let Some(token) = config.session_token() {
+            api.set_token(token.to_string());
+            state.screen = Screen::Dashboard;
             match api.auth().me().await {
                 Ok(user) => {
                     state.user = Some(user);
-                    state.screen = Screen::Dashboard;
                 }
-                Err(_) => {
-                    // Token invalid, clear it
+                Err(ApiError::Unauthorized) => {
+                    // Revoked or signed with another key; log in again
                     api.clear_token();
+                    config.clear_token()?;
+                    state.screen = Screen::Login;
                 }
+                Err(_) => {
+                    // Server unreachable; keep the session and work offline
+                }
+            }
+        } else if config.token_expired() {
+            config.clear_token()?;
+            login_error = Some(SESSION_EXPIRED.to_string());
+        }
+        if let Some(message) = validate::summary(config.problems()) {
+            state.set_error(message);
+        }
+        if let Some(e) = data_dir_error {
+            state.set_error(format!(
+                "Local notes, checklist and offline changes won't be kept: {}",
+                e
+            ));
+        }
+
+        // After an upgrade, show what changed since the version that ran last
+        if let Ok(dir) = Config::config_dir() {
+            let mut local = LocalState::load(&dir).unwrap_or_default();
+            let releases =
+                changelog::releases_since(CHANGELOG, local.last_version.as_deref(), VERSION.trim());
+            if !releases.is_empty() {
+                state.ui.modal = Some(Modal::WhatsNew {
+                    releases,
+                    scroll: 0,
+                });
+            }
+            if local.last_version.as_deref() != Some(VERSION.trim()) {
+                local.last_version = Some(VERSION.trim().to_string());
+                let _ = local.save(&dir);
             }
         }
 
+        let theme = config.theme()?;
+        let low_color = config.display.colors.is_limited();
+
         Ok(Self {
             state,
+            api_profile: config.profiles.active.clone().unwrap_or_default(),
             api_url: config.server.url.clone(),
             api_key: config.server.api_key.clone(),
             api_config_focused_field: ApiConfigField::ApiUrl.index(),
             api_config_error: None,
+            lock_prompt: None,
+            lock_passphrase: String::new(),
+            app_locked: false,
+            unlock_error: None,
+            held_notice: None,
+            last_input: Instant::now(),
+            task: None,
+            flash_until: None,
             config,
             api,
             login_email: String::new(),
             login_password: String::new(),
             login_focused_field: LoginField::Email.index(),
-            login_error: None,
+            login_error,
+            login_totp: None,
+            login_device: None,
             expense_form: ExpenseFormState::default(),
             income_form: IncomeFormState::default(),
             category_form: CategoryFormState::default(),
             period_form: PeriodFormState::default(),
             income_type_form: IncomeTypeFormState::default(),
             password_form: PasswordFormState::default(),
+            last_sync_attempt: Instant::now(),
+            live_updates: None,
+            remote_changes: Vec::new(),
+            calendar_month: None,
+            start_month,
+            remembered_view,
+            theme,
+            low_color,
             should_quit: false,
         })
     }
 
+    /// Open on `month` (year, month) and/or `tab` instead of the current
+    /// month's Summary, from `--month` and `--tab`
+    ///
+    /// `month` replaces the one from `BUDGET_DEFAULT_MONTH` or
+    /// `default_month`.
+    pub fn start_on(&mut self, month: Option<(i32, i32)>, tab: Option<DashboardTab>) {
+        if month.is_some() {
+            self.start_month = month;
+            if let Some(view) = self.remembered_view.as_mut() {
+                view.month = None;
+            }
+        }
+        if let Some(tab) = tab {
+            self.state.ui.selected_tab = tab;
+            if let Some(view) = self.remembered_view.as_mut() {
+                view.tab = None;
+            }
+        }
+    }
+
     /// Run the main event loop
     pub async fn run(
         &mut self,
         terminal: &mut Terminal<CrosstermBackend<Stdout>>,
         events: EventHandler,
     ) -> Result<()> {
+        // Esc/Ctrl+C abort requests running under the current context
+        events.set_interrupt(self.api.context());
+        if let Ok(path) = Config::config_path() {
+            events.watch_config(path);
+        }
+
         // If already logged in, load initial data
         if self.state.screen == Screen::Dashboard {
             self.load_initial_data().await;
+            self.start_live_updates();
+        } else if self.state.screen == Screen::Login && self.config.auth.device_login {
+            self.start_device_login().await;
         }
 
         loop {
@@ -139,10 +371,31 @@ impl App {
             // Handle events
             match events.next()? {
                 Event::Tick => {
-                    // Clear messages after some time could be done here
+                    if self.state.screen == Screen::Dashboard
+                        && self.api.has_queued_writes()
+                        && self.last_sync_attempt.elapsed() >= SYNC_INTERVAL
+                    {
+                        self.sync_queued_writes().await;
+                    }
+                    if self.state.screen == Screen::Dashboard {
+                        self.apply_live_updates().await;
+                        self.follow_calendar_month().await;
+                    }
+                    if self.state.screen == Screen::Login {
+                        self.poll_device_login().await;
+                    }
+                    self.show_rate_limit_wait();
+                    self.end_rejected_session();
+                    self.lock_when_idle();
                 }
                 Event::Key(key) => {
+                    // Each key press gets a fresh context, cancelling any stale requests
+                    events.set_interrupt(self.api.new_context());
+                    let started = Instant::now();
                     self.handle_key_event(key).await;
+                    self.finish_task(started);
+                    self.show_rate_limit_wait();
+                    self.end_rejected_session();
                 }
                 Event::Mouse(_mouse) => {
                     // Mouse handling could be added here
@@ -150,9 +403,13 @@ impl App {
                 Event::Resize(_, _) => {
                     // Terminal resize is handled automatically by ratatui
                 }
+                Event::ConfigChanged => {
+                    self.reload_config().await;
+                }
             }
 
             if self.should_quit {
+                self.remember_view();
                 break;
             }
         }
@@ -163,8 +420,28 @@ impl App {
     /// Render the UI
     fn render(&mut self, frame: &mut ratatui::Frame) {
         match self.state.screen {
-            Screen::Login => {
-                login::render_with_state(
+            _ if self.app_locked => ui::lock::render(
+                frame,
+                &self.lock_passphrase,
+                self.unlock_error.as_deref(),
+                VERSION.trim(),
+            ),
+            Screen::Login => match (&self.login_device, &self.login_totp) {
+                (Some(device), _) => login::render_device_login(
+                    frame,
+                    device,
+                    self.login_error.as_deref(),
+                    VERSION.trim(),
+                ),
+                (None, Some(prompt)) => login::render_totp_prompt(
+                    frame,
+                    prompt,
+                    &self.login_email,
+                    self.login_error.as_deref(),
+                    self.state.ui.is_loading,
+                    VERSION.trim(),
+                ),
+                (None, None) => login::render_with_state(
                     frame,
                     &self.login_email,
                     &self.login_password,
@@ -173,18 +450,28 @@ impl App {
                     self.state.ui.is_loading,
                     VERSION.trim(),
                     &self.api_url,
-                );
-            }
-            Screen::ApiConfig => {
-                api_config::render(
+                    self.config.profiles.active.as_deref(),
+                ),
+            },
+            Screen::ApiConfig => match self.lock_prompt {
+                Some(prompt) => api_config::render_lock_prompt(
+                    frame,
+                    prompt,
+                    &self.lock_passphrase,
+                    self.api_config_error.as_deref(),
+                    VERSION.trim(),
+                ),
+                None => api_config::render(
                     frame,
+                    &self.api_profile,
+                    &self.config.profile_names(),
                     &self.api_url,
                     &self.api_key,
                     self.api_config_focused_field,
                     self.api_config_error.as_deref(),
                     VERSION.trim(),
-                );
-            }
+                ),
+            },
             Screen::Dashboard => {
                 ui::render_with_forms(
                     &self.state,
@@ -198,6 +485,53 @@ impl App {
                 );
             }
         }
+
+        self.theme.apply(frame.buffer_mut());
+        if self.low_color {
+            ui::low_color::simplify(frame.buffer_mut());
+        }
+        match self.flash_until {
+            Some(until) if Instant::now() < until => notify::flash(frame.buffer_mut()),
+            Some(_) => self.flash_until = None,
+            None => {}
+        }
+    }
+
+    /// Note that the running key started a long task of `kind`
+    fn begin_task(&mut self, kind: TaskKind) {
+        self.task = Some(kind);
+    }
+
+    /// Signal the end of the task begun since `started`, as `[notify]` says
+    /// for its kind, with the message it left as the notification
+    fn finish_task(&mut self, started: Instant) {
+        let Some(kind) = self.task.take() else {
+            return;
+        };
+        // A task that outlasted the idle timeout locks the app before it
+        // says anything
+        self.lock_when_idle();
+        match self.config.notify.signal(kind, started.elapsed()) {
+            Signal::None => {}
+            Signal::Flash => self.flash_until = Some(Instant::now() + FLASH_DURATION),
+            signal => {
+                let message = self
+                    .state
+                    .ui
+                    .error_message
+                    .as_deref()
+                    .or(self.state.ui.success_message.as_deref())
+                    .unwrap_or("Done");
+                let message = format!("Budget: {}", message);
+                // The notification tells what the task did, which the lock
+                // screen is there to hide
+                if self.app_locked {
+                    self.held_notice = Some((signal, message));
+                } else {
+                    let _ = notify::send(signal, &message);
+                }
+            }
+        }
     }
 
     /// Handle key events
@@ -208,9 +542,15 @@ impl App {
             return;
         }
 
+        self.last_input = Instant::now();
+        if self.app_locked {
+            self.handle_unlock_key(key);
+            return;
+        }
+
         match self.state.screen {
             Screen::Login => self.handle_login_key(key).await,
-            Screen::ApiConfig => self.handle_api_config_key(key),
+            Screen::ApiConfig => self.handle_api_config_key(key).await,
             Screen::Dashboard => self.handle_dashboard_key(key).await,
         }
     }
@@ -222,6 +562,32 @@ impl App {
             self.login_error = None;
         }
 
+        if self.login_device.is_some() {
+            self.handle_device_login_key(key);
+            return;
+        }
+
+        if self.login_totp.is_some() {
+            self.handle_totp_key(key).await;
+            return;
+        }
+
+        if key.code == KeyCode::Char('d') && key.modifiers.contains(KeyModifiers::CONTROL) {
+            self.start_device_login().await;
+            return;
+        }
+
+        if key.code == KeyCode::Char('p') && key.modifiers.contains(KeyModifiers::CONTROL) {
+            // The lock guards which server the app talks to
+            if self.config.lock.is_locked() {
+                self.login_error =
+                    Some("Server settings are locked - switch profiles under s".to_string());
+            } else if let Err(e) = self.switch_to_next_profile().await {
+                self.login_error = Some(e);
+            }
+            return;
+        }
+
         let field_count = LoginField::count();
 
         match key.code {
@@ -244,7 +610,7 @@ impl App {
             KeyCode::Char(c) => {
                 // 's' key without any text input goes to API config
                 if c == 's' && self.login_email.is_empty() && self.login_password.is_empty() {
-                    self.state.screen = Screen::ApiConfig;
+                    self.open_api_config();
                 } else {
                     match LoginField::from_index(self.login_focused_field) {
                         LoginField::Email => self.login_email.push(c),
@@ -269,13 +635,143 @@ impl App {
         }
     }
 
+    /// Handle keys for the two-factor code prompt of the login screen
+    async fn handle_totp_key(&mut self, key: KeyEvent) {
+        match key.code {
+            KeyCode::Enter => self.verify_totp().await,
+            KeyCode::Char(c) => {
+                if let Some(prompt) = self.login_totp.as_mut() {
+                    prompt.push(c);
+                }
+            }
+            KeyCode::Backspace => {
+                if let Some(prompt) = self.login_totp.as_mut() {
+                    prompt.code.pop();
+                }
+            }
+            // Back to the password, e.g. to log in as someone else
+            KeyCode::Esc => self.restart_login(None),
+            _ => {}
+        }
+    }
+
+    /// Handle keys while a device login waits for approval
+    fn handle_device_login_key(&mut self, key: KeyEvent) {
+        match key.code {
+            KeyCode::Char('c') => {
+                if let Some(login) = &self.login_device {
+                    if let Err(e) = clipboard::copy(login.link()) {
+                        self.login_error = Some(format!("Failed to copy: {}", e));
+                    }
+                }
+            }
+            KeyCode::Esc => self.login_device = None,
+            _ => {}
+        }
+    }
+
+    /// Ask the server for a device code and show it
+    async fn start_device_login(&mut self) {
+        self.state.ui.is_loading = true;
+        let result = self.api.auth().start_device_login().await;
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(code) => {
+                self.login_error = None;
+                self.login_device = Some(DeviceLogin::new(code));
+            }
+            Err(e) if e.is_unsupported() => {
+                self.login_error = Some("This server doesn't support device login".to_string());
+            }
+            Err(e) => self.login_error = Some(format!("Device login failed: {}", e)),
+        }
+    }
+
+    /// Check once whether the device login was approved, when its interval is up
+    async fn poll_device_login(&mut self) {
+        let device_code = match &self.login_device {
+            Some(login) if login.is_expired() => {
+                self.login_device = None;
+                self.login_error = Some(EXPIRED_DEVICE_CODE.to_string());
+                return;
+            }
+            Some(login) if login.is_due() => login.code.device_code.clone(),
+            _ => return,
+        };
+
+        let result = self.api.auth().poll_device_login(&device_code).await;
+        let slow_down = match result {
+            Ok(DevicePoll::Token(token_response)) => {
+                self.login_device = None;
+                self.complete_login(token_response).await;
+                return;
+            }
+            Ok(DevicePoll::Pending) => false,
+            Ok(DevicePoll::SlowDown) => true,
+            Ok(DevicePoll::Denied) => {
+                self.login_device = None;
+                self.login_error = Some("The login was denied".to_string());
+                return;
+            }
+            Ok(DevicePoll::Expired) => {
+                self.login_device = None;
+                self.login_error = Some(EXPIRED_DEVICE_CODE.to_string());
+                return;
+            }
+            // Keep polling through network trouble until the code expires
+            Err(e) => {
+                self.login_error = Some(format!("Waiting for approval: {}", e));
+                false
+            }
+        };
+        if let Some(login) = self.login_device.as_mut() {
+            login.schedule_poll(slow_down);
+        }
+    }
+
+    /// Drop a pending two-factor challenge and ask for the password again
+    fn restart_login(&mut self, error: Option<String>) {
+        self.login_totp = None;
+        self.login_password.clear();
+        self.login_focused_field = LoginField::Password.index();
+        self.login_error = error;
+    }
+
+    /// Open the API config screen, asking for the passphrase first if it is locked
+    fn open_api_config(&mut self) {
+        if self.config.lock.is_locked() {
+            self.lock_prompt = Some(LockPrompt::Unlock);
+            self.lock_passphrase.clear();
+        }
+        self.state.screen = Screen::ApiConfig;
+    }
+
     /// Handle API config screen keys
-    fn handle_api_config_key(&mut self, key: KeyEvent) {
+    async fn handle_api_config_key(&mut self, key: KeyEvent) {
         // Clear error on any key except Enter
         if self.api_config_error.is_some() && key.code != KeyCode::Enter {
             self.api_config_error = None;
         }
 
+        if let Some(prompt) = self.lock_prompt {
+            self.handle_lock_prompt_key(prompt, key);
+            return;
+        }
+
+        if key.code == KeyCode::Char('l') && key.modifiers.contains(KeyModifiers::CONTROL) {
+            self.lock_prompt = Some(LockPrompt::SetPassphrase);
+            self.lock_passphrase.clear();
+            return;
+        }
+
+        if key.code == KeyCode::Char('p') && key.modifiers.contains(KeyModifiers::CONTROL) {
+            if let Err(e) = self.switch_to_next_profile().await {
+                self.api_config_error = Some(e);
+            }
+            return;
+        }
+
         let field_count = ApiConfigField::count();
 
         match key.code {
@@ -292,15 +788,19 @@ impl App {
             }
             // Save and go back
             KeyCode::Enter => {
-                self.save_api_config();
+                self.save_api_config().await;
             }
             // Text input
             KeyCode::Char(c) => match ApiConfigField::from_index(self.api_config_focused_field) {
+                ApiConfigField::Profile => self.api_profile.push(c),
                 ApiConfigField::ApiUrl => self.api_url.push(c),
                 ApiConfigField::ApiKey => self.api_key.push(c),
             },
             // Delete character
             KeyCode::Backspace => match ApiConfigField::from_index(self.api_config_focused_field) {
+                ApiConfigField::Profile => {
+                    self.api_profile.pop();
+                }
                 ApiConfigField::ApiUrl => {
                     self.api_url.pop();
                 }
@@ -311,6 +811,7 @@ impl App {
             // Cancel and go back
             KeyCode::Esc => {
                 // Restore from config and go back
+                self.api_profile = self.config.profiles.active.clone().unwrap_or_default();
                 self.api_url = self.config.server.url.clone();
                 self.api_key = self.config.server.api_key.clone();
                 self.api_config_error = None;
@@ -320,8 +821,116 @@ impl App {
         }
     }
 
-    /// Save API config and return to login
-    fn save_api_config(&mut self) {
+    /// Cover the app with the lock screen, when there is a passphrase to
+    /// unlock it with
+    fn lock_app(&mut self) {
+        if !self.config.lock.is_locked() {
+            self.state
+                .set_error("Set a lock passphrase first: Ctrl+L on the server settings screen");
+            return;
+        }
+        self.app_locked = true;
+        self.lock_passphrase.clear();
+        self.unlock_error = None;
+    }
+
+    /// Lock the app once it has been left alone for `[lock] idle_minutes`
+    fn lock_when_idle(&mut self) {
+        if self.app_locked {
+            return;
+        }
+        if let Some(timeout) = self.config.lock.idle_timeout() {
+            if self.last_input.elapsed() >= timeout {
+                self.lock_app();
+            }
+        }
+    }
+
+    /// Handle keys on the lock screen
+    fn handle_unlock_key(&mut self, key: KeyEvent) {
+        match key.code {
+            KeyCode::Enter => {
+                if self.config.lock.verify(&self.lock_passphrase) {
+                    self.rehash_lock_passphrase();
+                    self.app_locked = false;
+                    self.unlock_error = None;
+                    if let Some((signal, message)) = self.held_notice.take() {
+                        let _ = notify::send(signal, &message);
+                    }
+                } else {
+                    self.unlock_error = Some("Wrong passphrase".to_string());
+                }
+                self.lock_passphrase.clear();
+            }
+            KeyCode::Char(c) => self.lock_passphrase.push(c),
+            KeyCode::Backspace => {
+                self.lock_passphrase.pop();
+            }
+            KeyCode::Esc => self.lock_passphrase.clear(),
+            _ => {}
+        }
+    }
+
+    /// Replace an unsalted hash from an older version with a salted one, now
+    /// that the passphrase it matched is known
+    fn rehash_lock_passphrase(&mut self) {
+        if !self.config.lock.has_legacy_hash() {
+            return;
+        }
+        let passphrase = self.lock_passphrase.clone();
+        if let Err(e) = self
+            .config
+            .lock
+            .set_passphrase(&passphrase)
+            .and_then(|_| self.config.save())
+        {
+            self.state
+                .set_error(format!("Failed to save the lock passphrase: {}", e));
+        }
+    }
+
+    /// Handle keys for the passphrase prompt of the API config screen
+    fn handle_lock_prompt_key(&mut self, prompt: LockPrompt, key: KeyEvent) {
+        match key.code {
+            KeyCode::Enter => match prompt {
+                LockPrompt::Unlock => {
+                    if self.config.lock.verify(&self.lock_passphrase) {
+                        self.rehash_lock_passphrase();
+                        self.lock_prompt = None;
+                    } else {
+                        self.api_config_error = Some("Wrong passphrase".to_string());
+                    }
+                    self.lock_passphrase.clear();
+                }
+                LockPrompt::SetPassphrase => {
+                    let set = self.config.lock.set_passphrase(&self.lock_passphrase);
+                    self.lock_passphrase.clear();
+                    if let Err(e) = set.and_then(|_| self.config.save()) {
+                        self.api_config_error = Some(format!("Failed to save: {}", e));
+                        return;
+                    }
+                    self.lock_prompt = None;
+                }
+            },
+            KeyCode::Char(c) => self.lock_passphrase.push(c),
+            KeyCode::Backspace => {
+                self.lock_passphrase.pop();
+            }
+            KeyCode::Esc => {
+                self.lock_passphrase.clear();
+                self.lock_prompt = None;
+                if prompt == LockPrompt::Unlock {
+                    self.api_config_error = None;
+                    self.state.screen = Screen::Login;
+                }
+            }
+            _ => {}
+        }
+    }
+
+    /// Save API config and return to login, or straight to the dashboard
+    /// when the profile has a session
+    async fn save_api_config(&mut self) {
         // Validate
         if self.api_url.is_empty() {
             self.api_config_error = Some("API URL is required".to_string());
@@ -329,8 +938,11 @@ impl App {
         }
 
         // Update config
-        self.config.server.url = self.api_url.clone();
-        self.config.server.api_key = self.api_key.clone();
+        self.config.save_profile(
+            &self.api_profile,
+            self.api_url.clone(),
+            self.api_key.clone(),
+        );
 
         // Save to file
         if let Err(e) = self.config.save() {
@@ -338,16 +950,243 @@ impl App {
             return;
         }
 
-        // Update API client
-        match ApiClient::new(self.api_url.clone(), self.api_key.clone()) {
-            Ok(new_api) => {
-                self.api = new_api;
-                self.api_config_error = None;
-                self.state.screen = Screen::Login;
+        if let Err(e) = self.connect_to_server() {
+            self.api_config_error = Some(e);
+            return;
+        }
+        self.api_config_error = None;
+        self.resume_session().await;
+    }
+
+    /// Make the next profile active, with its server and session
+    ///
+    /// Errors are for the screen it was pressed on.
+    async fn switch_to_next_profile(&mut self) -> Result<(), String> {
+        let name = self
+            .config
+            .next_profile()
+            .ok_or("No other profiles - add one by naming it in the server config")?;
+        self.config
+            .switch_profile(&name)
+            .and_then(|_| self.config.save())
+            .map_err(|e| format!("Failed to switch profile: {}", e))?;
+        self.connect_to_server()?;
+
+        self.api_profile = name;
+        self.api_url = self.config.server.url.clone();
+        self.api_key = self.config.server.api_key.clone();
+        if self.state.screen == Screen::Login {
+            self.resume_session().await;
+        }
+        Ok(())
+    }
+
+    /// Replace the API client with one for the configured server, along with
+    /// everything stored per server
+    fn connect_to_server(&mut self) -> Result<(), String> {
+        let new_api = ApiClient::with_options(
+            self.config.server.url.clone(),
+            self.config.server.api_key.clone(),
+            &self.config.network.client_options(),
+        )
+        .map_err(|e| format!("Failed to set up client: {:#}", e))?;
+
+        new_api.set_retry_policy(self.config.network.retry_policy());
+        if let Ok(headers) = self.config.network.header_hook() {
+            if !headers.is_empty() {
+                new_api.add_hook(headers);
+            }
+        }
+        if let Some(path) = self.api.debug_log_path() {
+            let _ = new_api.enable_debug_log(&path);
+        }
+        self.api = new_api;
+        self.state.set_profile(&self.config.profiles);
+        self.live_updates = None;
+        self.state.user = None;
+        self.state.data = Default::default();
+        self.state.ui.closed_month_override = None;
+        if let Ok(dir) = self.config.data_dir() {
+            let _ = self.api.enable_write_queue(dir.clone());
+            self.state.notes = MonthNotes::load(&dir).unwrap_or_default();
+            self.state.checklist = MonthChecklist::load(&dir).unwrap_or_default();
+            self.state.tax_flags = TaxFlags::load(&dir).unwrap_or_default();
+            self.state.ledgers = ExpenseLedgers::load(&dir).unwrap_or_default();
+        }
+        Ok(())
+    }
+
+    /// Apply the config file after it was edited in another terminal
+    ///
+    /// Display settings, thresholds and the theme take effect right away. A
+    /// new server URL or key reconnects and keeps the session when the file
+    /// still has a valid token for it. Our own saves change nothing and are
+    /// skipped; a file that doesn't load keeps the current config.
+    async fn reload_config(&mut self) {
+        let config = match self.config.reload() {
+            Ok(config) => config,
+            Err(e) => {
+                self.state
+                    .set_error(format!("Config not reloaded: {:#}", e));
+                return;
             }
+        };
+        if config.to_toml().ok() == self.config.to_toml().ok() {
+            return;
+        }
+        let theme = match config.theme() {
+            Ok(theme) => theme,
             Err(e) => {
-                self.api_config_error = Some(format!("Invalid URL: {}", e));
+                self.state
+                    .set_error(format!("Config not reloaded: {:#}", e));
+                return;
+            }
+        };
+
+        let reconnect = config.server.url != self.config.server.url
+            || config.server.api_key != self.config.server.api_key
+            || config.profiles.active != self.config.profiles.active;
+        if config.display.large_text != self.config.display.large_text {
+            self.state.ui.large_text = config.display.large_text;
+        }
+        self.config = config;
+        self.theme = theme;
+        self.low_color = self.config.display.colors.is_limited();
+        self.state.thresholds = self.config.thresholds.clone();
+        self.state.money = self.config.display.money.clone();
+        self.state.dates = self.config.display.dates;
+        self.state.page_size = self.config.network.page_size;
+        self.state.envelope_categories = self.config.envelopes.weekly.clone();
+        self.state.period_display = self.config.periods.clone();
+        self.state.advisor = self.config.advisor.clone();
+        self.load_exchange_rates().await;
+
+        if reconnect {
+            if let Err(e) = self.connect_to_server() {
+                self.state.set_error(e);
+                return;
+            }
+            self.api_profile = self.config.profiles.active.clone().unwrap_or_default();
+            self.api_url = self.config.server.url.clone();
+            self.api_key = self.config.server.api_key.clone();
+            if self.state.screen != Screen::ApiConfig {
+                self.resume_session().await;
+            }
+        }
+        match validate::summary(self.config.problems()) {
+            Some(message) => self.state.set_error(message),
+            None => self.state.set_success("Config reloaded"),
+        }
+    }
+
+    /// Continue the active profile's saved session, or ask for a login
+    async fn resume_session(&mut self) {
+        self.state.screen = Screen::Login;
+        let token = match self.config.session_token() {
+            Some(token) => token.to_string(),
+            None => {
+                if self.config.token_expired() {
+                    let _ = self.config.clear_token();
+                    self.login_error = Some(SESSION_EXPIRED.to_string());
+                }
+                return;
+            }
+        };
+
+        self.api.set_token(token);
+        match self.api.auth().me().await {
+            Ok(user) => self.state.user = Some(user),
+            Err(ApiError::Unauthorized) => {
+                self.api.clear_token();
+                let _ = self.config.clear_token();
+                self.login_error = Some(SESSION_EXPIRED.to_string());
+                return;
             }
+            // Server unreachable; keep the session and work offline
+            Err(_) => {}
+        }
+        self.login_error = None;
+        self.state.screen = Screen::Dashboard;
+        self.load_initial_data().await;
+        self.start_live_updates();
+    }
+
+    /// Debug panel with the current request metrics and client state
+    fn debug_panel(&self) -> Modal {
+        let mut slowest = self.api.request_metrics();
+        slowest.sort_by(|a, b| b.p95.cmp(&a.p95));
+        slowest.truncate(5);
+
+        let retry = self.api.retry_policy();
+        let unsupported: Vec<String> = self
+            .state
+            .data
+            .unsupported
+            .iter()
+            .map(|feature| format!("{:?}", feature))
+            .collect();
+        let details = vec![
+            ("Server", self.config.server.url.clone()),
+            (
+                "Profile",
+                self.config
+                    .profiles
+                    .active
+                    .clone()
+                    .unwrap_or_else(|| "-".to_string()),
+            ),
+            (
+                "Session",
+                match self.config.auth.expires_at {
+                    Some(expires) => format!(
+                        "expires {} UTC",
+                        self.state.dates.format_time(expires.naive_utc())
+                    ),
+                    None if self.api.has_token() => "no expiry".to_string(),
+                    None => "none".to_string(),
+                },
+            ),
+            (
+                "Retries",
+                format!(
+                    "{} after {}ms",
+                    retry.max_retries,
+                    retry.base_delay.as_millis()
+                ),
+            ),
+            (
+                "Live updates",
+                if self.live_updates.is_some() {
+                    "connected"
+                } else {
+                    "off"
+                }
+                .to_string(),
+            ),
+            ("Queued writes", self.api.queued_writes().len().to_string()),
+            (
+                "Debug log",
+                self.api
+                    .debug_log_path()
+                    .map_or("off".to_string(), |path| path.display().to_string()),
+            ),
+            (
+                "Fallbacks",
+                if unsupported.is_empty() {
+                    "none".to_string()
+                } else {
+                    unsupported.join(", ")
+                },
+            ),
+        ];
+
+        Modal::Debug {
+            totals: self.api.request_totals(),
+            slowest,
+            details: details
+                .into_iter()
+                .map(|(name, value)| (name.to_string(), value))
+                .collect(),
         }
     }
 
@@ -367,30 +1206,11 @@ impl App {
             .login(&self.login_email, &self.login_password)
             .await
         {
-            Ok(token_response) => {
-                // Store token
-                self.api.set_token(token_response.access_token.clone());
-                if let Err(e) = self.config.set_token(token_response.access_token) {
-                    // Log but don't fail - token is still in memory
-                    eprintln!("Failed to save token: {}", e);
-                }
-
-                // Get user info
-                if let Ok(user) = self.api.auth().me().await {
-                    self.state.user = Some(user);
-                }
-
-                // Clear login form (but keep API config)
-                self.login_email.clear();
-                self.login_password.clear();
-                self.login_error = None;
-
-                // Switch to dashboard
-                self.state.screen = Screen::Dashboard;
+            Ok(LoginResponse::Token(token_response)) => self.complete_login(token_response).await,
+            Ok(LoginResponse::TotpRequired(challenge)) => {
                 self.state.ui.is_loading = false;
-
-                // Load initial data
-                self.load_initial_data().await;
+                self.login_error = None;
+                self.login_totp = Some(TotpPrompt::new(challenge));
             }
             Err(e) => {
                 self.state.ui.is_loading = false;
@@ -399,6 +1219,71 @@ impl App {
         }
     }
 
+    /// Send the two-factor code, finishing the login
+    async fn verify_totp(&mut self) {
+        let (challenge, code) = match &self.login_totp {
+            Some(prompt) if prompt.is_expired() => {
+                self.restart_login(Some(EXPIRED_CODE_REQUEST.to_string()));
+                return;
+            }
+            Some(prompt) => (prompt.challenge.clone(), prompt.code.clone()),
+            None => return,
+        };
+        if code.is_empty() {
+            self.login_error = Some("Enter the code from your authenticator app".to_string());
+            return;
+        }
+
+        self.state.ui.is_loading = true;
+        let result = self.api.auth().verify_totp(&challenge, &code).await;
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(token_response) => {
+                self.login_totp = None;
+                self.complete_login(token_response).await;
+            }
+            Err(ApiError::Unauthorized) => {
+                if let Some(prompt) = self.login_totp.as_mut() {
+                    prompt.code.clear();
+                }
+                self.login_error = Some("Invalid code - try again".to_string());
+            }
+            Err(ApiError::Expired) => self.restart_login(Some(EXPIRED_CODE_REQUEST.to_string())),
+            Err(e) => {
+                self.login_error = Some(format!("Verification failed: {}", e));
+            }
+        }
+    }
+
+    /// Store the session token and open the dashboard
+    async fn complete_login(&mut self, token_response: TokenResponse) {
+        // Store token
+        self.api.set_token(token_response.access_token.clone());
+        if let Err(e) = self.config.set_token(token_response.access_token) {
+            // Log but don't fail - token is still in memory
+            eprintln!("Failed to save token: {}", e);
+        }
+
+        // Get user info
+        if let Ok(user) = self.api.auth().me().await {
+            self.state.user = Some(user);
+        }
+
+        // Clear login form (but keep API config)
+        self.login_email.clear();
+        self.login_password.clear();
+        self.login_error = None;
+
+        // Switch to dashboard
+        self.state.screen = Screen::Dashboard;
+        self.state.ui.is_loading = false;
+
+        // Load initial data
+        self.load_initial_data().await;
+        self.start_live_updates();
+    }
+
     /// Handle dashboard keys
     async fn handle_dashboard_key(&mut self, key: KeyEvent) {
         // Handle modal first if open
@@ -407,6 +1292,34 @@ impl App {
             return;
         }
 
+        if self.state.ui.searching {
+            self.handle_search_key(key);
+            return;
+        }
+
+        // Alt+number removes that chip from the view bar
+        if key.modifiers.contains(KeyModifiers::ALT) {
+            if let KeyCode::Char(c @ '1'..='9') = key.code {
+                self.remove_view_chip(c as usize - '1' as usize).await;
+                return;
+            }
+        }
+
+        // Hidden debug panel; not in the help
+        if key.code == KeyCode::Char('d') && key.modifiers.contains(KeyModifiers::CONTROL) {
+            self.state.ui.modal = Some(self.debug_panel());
+            return;
+        }
+
+        if key.code == KeyCode::Char('w') && key.modifiers.contains(KeyModifiers::CONTROL) {
+            if self.state.ui.selected_tab == DashboardTab::Expenses && self.state.ui.split.is_some()
+            {
+                self.state.switch_split_focus();
+                self.load_tab_data().await;
+            }
+            return;
+        }
+
         match key.code {
             KeyCode::Char('q') => {
                 self.should_quit = true;
@@ -449,15 +1362,25 @@ impl App {
             }
             KeyCode::Char('4') => {
                 if self.state.ui.selected_tab == DashboardTab::Settings {
-                    self.state.ui.settings_tab = SettingsTab::Password;
+                    self.state.ui.settings_tab = SettingsTab::Accounts;
                 } else {
                     self.state.ui.selected_tab = DashboardTab::Charts;
                     self.load_tab_data().await;
                 }
             }
             KeyCode::Char('5') => {
-                self.state.ui.selected_tab = DashboardTab::Settings;
-                self.load_tab_data().await;
+                if self.state.ui.selected_tab == DashboardTab::Settings {
+                    self.state.ui.settings_tab = SettingsTab::Password;
+                } else {
+                    self.state.ui.selected_tab = DashboardTab::Debts;
+                    self.load_tab_data().await;
+                }
+            }
+            KeyCode::Char('6') => {
+                if self.state.ui.selected_tab != DashboardTab::Settings {
+                    self.state.ui.selected_tab = DashboardTab::Settings;
+                    self.load_tab_data().await;
+                }
             }
             KeyCode::Char('h') | KeyCode::Left => {
                 self.state.previous_month();
@@ -468,6 +1391,7 @@ impl App {
                 self.load_month_data().await;
             }
             KeyCode::Char('j') | KeyCode::Down => {
+                self.load_more_near_end().await;
                 self.select_next_item();
             }
             KeyCode::Char('k') | KeyCode::Up => {
@@ -477,23 +1401,535 @@ impl App {
                 self.open_new_item_modal();
             }
             KeyCode::Char('e') | KeyCode::Enter => {
-                self.open_edit_item_modal();
+                if self.refresh_selected_item().await {
+                    self.open_edit_item_modal();
+                }
             }
             KeyCode::Char('d') => {
                 self.open_delete_confirmation();
             }
             KeyCode::Char('p') => {
-                self.open_pay_confirmation();
+                if self.state.ui.selected_tab == DashboardTab::Debts {
+                    self.open_debt_payment();
+                } else {
+                    self.open_pay_confirmation();
+                }
             }
             KeyCode::Char('c') => {
                 self.open_close_month_confirmation();
             }
+            KeyCode::Char('o') => {
+                self.open_notes();
+            }
+            KeyCode::Char('N') => {
+                self.open_shared_notes();
+            }
+            KeyCode::Char('U') => {
+                self.toggle_closed_month_override();
+            }
+            KeyCode::Char('x') => {
+                self.open_checklist();
+            }
+            KeyCode::Char('t') => {
+                if self.state.ui.selected_tab == DashboardTab::Settings
+                    && self.state.ui.settings_tab == SettingsTab::Accounts
+                {
+                    self.open_transfers().await;
+                } else {
+                    self.cycle_tax_flag();
+                }
+            }
+            KeyCode::Char('T') => {
+                self.export_tax_report().await;
+            }
+            KeyCode::Char('X') => {
+                self.export_year_xlsx().await;
+            }
+            KeyCode::Char('E') => {
+                self.export_month_csv().await;
+            }
+            KeyCode::Char('P') => {
+                self.state.ui.modal = Some(Modal::Performance {
+                    endpoints: self.api.request_metrics(),
+                });
+            }
+            KeyCode::Char('.') => {
+                self.repeat_last_action().await;
+            }
+            KeyCode::Char('H') => {
+                self.open_history();
+            }
+            KeyCode::Char('z') => {
+                self.state.toggle_large_text();
+            }
+            KeyCode::Char('C') => {
+                self.copy_previous_month().await;
+            }
+            KeyCode::Char('A') => {
+                self.open_autofill().await;
+            }
+            KeyCode::Char('B') => {
+                self.open_reallocate().await;
+            }
+            KeyCode::Char('i') => {
+                if !self.open_inspect().await {
+                    self.open_import_csv();
+                }
+            }
+            KeyCode::Char('G') => {
+                self.push_to_google_sheets().await;
+            }
+            KeyCode::Char('S') => {
+                self.share_month().await;
+            }
+            KeyCode::Char('b') => {
+                if self.state.ui.selected_tab == DashboardTab::Expenses {
+                    self.cycle_ledger();
+                }
+            }
+            KeyCode::Char('R') => {
+                self.open_reimbursements().await;
+            }
+            KeyCode::Char('L') => {
+                self.log_out();
+            }
+            KeyCode::Char('K') => {
+                self.lock_app();
+            }
+            KeyCode::Char('|') => {
+                if self.state.ui.selected_tab == DashboardTab::Expenses {
+                    self.toggle_split().await;
+                }
+            }
+            KeyCode::Char('M') => {
+                if self.state.ui.selected_tab == DashboardTab::Expenses {
+                    self.send_to_split(false).await;
+                }
+            }
+            KeyCode::Char('Y') => {
+                if self.state.ui.selected_tab == DashboardTab::Expenses {
+                    self.send_to_split(true).await;
+                }
+            }
+            KeyCode::Char('m') => {
+                if self.state.ui.selected_tab == DashboardTab::Settings {
+                    self.open_merge_select();
+                }
+            }
+            KeyCode::Char('O') => {
+                if self.state.ui.selected_tab == DashboardTab::Expenses {
+                    self.open_selected_attachment().await;
+                }
+            }
+            KeyCode::Char('v') => match self.state.ui.selected_tab {
+                DashboardTab::Settings => self.open_env_export(),
+                DashboardTab::Expenses => {
+                    if self.refresh_selected_item().await {
+                        self.open_expense_detail();
+                    }
+                }
+                _ => {}
+            },
+            KeyCode::Char('f') => {
+                if self.on_list_tab() {
+                    self.cycle_period_filter().await;
+                }
+            }
+            KeyCode::Char('F') => {
+                if self.state.ui.selected_tab == DashboardTab::Expenses {
+                    self.cycle_category_filter().await;
+                }
+            }
+            KeyCode::Char('a') => {
+                if self.on_list_tab() {
+                    self.cycle_account_filter();
+                }
+            }
+            KeyCode::Char('/') => {
+                if self.on_list_tab() {
+                    self.state.ui.searching = true;
+                }
+            }
+            KeyCode::Char('s') => {
+                if self.on_list_tab() {
+                    self.state.ui.sort = SortKey::cycle(self.state.ui.sort);
+                    self.reset_list_selection();
+                }
+            }
+            KeyCode::Char('g') => {
+                if self.on_list_tab() {
+                    self.state.ui.group =
+                        GroupKey::cycle(self.state.ui.group, self.state.ui.selected_tab);
+                    self.reset_list_selection();
+                }
+            }
             _ => {}
         }
     }
 
+    /// Whether the Expenses or Income table is shown, the tabs with a view bar
+    fn on_list_tab(&self) -> bool {
+        matches!(
+            self.state.ui.selected_tab,
+            DashboardTab::Expenses | DashboardTab::Income
+        )
+    }
+
+    /// Select the first row after the rows or their order changed
+    fn reset_list_selection(&mut self) {
+        self.state.ui.expense_table.select(Some(0));
+        self.state.ui.income_table.select(Some(0));
+    }
+
+    /// Handle keys while typing a search into the view bar
+    fn handle_search_key(&mut self, key: KeyEvent) {
+        match key.code {
+            KeyCode::Enter => self.state.ui.searching = false,
+            KeyCode::Esc => {
+                self.state.ui.search.clear();
+                self.state.ui.searching = false;
+            }
+            KeyCode::Char(c) => self.state.ui.search.push(c),
+            KeyCode::Backspace => {
+                self.state.ui.search.pop();
+            }
+            _ => return,
+        }
+        self.reset_list_selection();
+    }
+
+    /// Filter by the next period, past the last one showing all again
+    async fn cycle_period_filter(&mut self) {
+        let periods: Vec<String> = self
+            .state
+            .data
+            .periods
+            .iter()
+            .map(|p| p.name.clone())
+            .collect();
+        self.state.ui.period_filter = next_filter(&periods, self.state.ui.period_filter.as_deref());
+        if let Some(period) = self.state.ui.period_filter.clone() {
+            let label = format!("Filtered by period {}", self.state.period_label(&period));
+            self.record_action(Action::PeriodFilter(period), label);
+        }
+        self.reset_list_selection();
+        self.load_tab_data().await;
+    }
+
+    /// Filter by the next category, past the last one showing all again
+    async fn cycle_category_filter(&mut self) {
+        let categories: Vec<String> = self
+            .state
+            .data
+            .categories
+            .iter()
+            .map(|c| c.name.clone())
+            .collect();
+        self.state.ui.category_filter =
+            next_filter(&categories, self.state.ui.category_filter.as_deref());
+        if let Some(category) = self.state.ui.category_filter.clone() {
+            let label = format!("Filtered by category {}", category);
+            self.record_action(Action::CategoryFilter(category), label);
+        }
+        self.reset_list_selection();
+        self.load_tab_data().await;
+    }
+
+    /// Filter by the next account, past the last one showing all again
+    ///
+    /// Entries are matched to the account here, so nothing is reloaded.
+    fn cycle_account_filter(&mut self) {
+        if self.state.data.accounts.is_empty() {
+            self.state
+                .set_error("No accounts to filter by; add them under Settings > Accounts");
+            return;
+        }
+        self.state.ui.account_filter = forms::step_account(
+            &self.state.data.accounts,
+            self.state.ui.account_filter,
+            true,
+        );
+        self.reset_list_selection();
+    }
+
+    /// Note an action for the history panel and `.`
+    fn record_action(&mut self, action: Action, label: String) {
+        self.state
+            .history
+            .record(action, label, self.state.clock.now().time());
+    }
+
+    /// Do the most recent action again
+    async fn repeat_last_action(&mut self) {
+        match self.state.history.last() {
+            Some(entry) => {
+                let action = entry.action.clone();
+                self.repeat_action(action).await;
+            }
+            None => self.state.set_error("Nothing to repeat yet"),
+        }
+    }
+
+    /// Do an action from the history again
+    ///
+    /// New expenses and incomes open their form filled in the same way, so
+    /// the name or amount can be changed before saving.
+    async fn repeat_action(&mut self, action: Action) {
+        match action {
+            Action::CreateExpense(create) => {
+                if !self.check_scope(Some(Scope::Expenses)) {
+                    return;
+                }
+                if self.is_month_closed() {
+                    self.state
+                        .set_error("Cannot add items to a closed month. Reopen it or press U.");
+                    return;
+                }
+                self.expense_form = ExpenseFormState::from_create(&create);
+                self.expense_form
+                    .offer_custom_fields(&forms::custom_fields_in(&self.state.data.expenses));
+                self.state.ui.modal = Some(Modal::ExpenseForm { editing: None });
+            }
+            Action::CreateIncome(create) => {
+                if !self.check_scope(Some(Scope::Incomes)) {
+                    return;
+                }
+                if self.is_month_closed() {
+                    self.state
+                        .set_error("Cannot add items to a closed month. Reopen it or press U.");
+                    return;
+                }
+                self.income_form = IncomeFormState::from_create(&create);
+                self.state.ui.modal = Some(Modal::IncomeForm { editing: None });
+            }
+            Action::PeriodFilter(period) => {
+                self.state.ui.period_filter = Some(period);
+                self.reset_list_selection();
+                self.load_tab_data().await;
+            }
+            Action::CategoryFilter(category) => {
+                self.state.ui.category_filter = Some(category);
+                self.reset_list_selection();
+                self.load_tab_data().await;
+            }
+            Action::ExportMonthCsv => self.export_month_csv().await,
+            Action::ExportYearXlsx => self.export_year_xlsx().await,
+            Action::ExportTaxReport => self.export_tax_report().await,
+            Action::PushGoogleSheets => self.push_to_google_sheets().await,
+        }
+    }
+
+    /// Check that the selected month is open and has nothing in it, for
+    /// filling it by copying or importing
+    fn check_fillable_month(&mut self) -> bool {
+        if !self.check_scope(Some(Scope::Expenses)) || !self.check_scope(Some(Scope::Incomes)) {
+            return false;
+        }
+        if self.is_month_closed() {
+            self.state
+                .set_error("Cannot add items to a closed month. Reopen it or press U.");
+            return false;
+        }
+        if !self.state.month_is_empty() {
+            let name = self
+                .state
+                .selected_month()
+                .map(|m| m.display_name())
+                .unwrap_or_default();
+            self.state.set_error(format!(
+                "{} isn't empty; clear the filters or add items one by one",
+                name
+            ));
+            return false;
+        }
+        true
+    }
+
+    /// Fill the empty selected month with the previous month's expenses and
+    /// incomes, as starting a new month does
+    async fn copy_previous_month(&mut self) {
+        self.begin_task(TaskKind::Bulk);
+        if !self.check_fillable_month() {
+            return;
+        }
+        let (source, source_name) = match self.state.previous_of_selected() {
+            Some(previous) => (previous.id, previous.display_name()),
+            None => {
+                self.state.set_error("No previous month to copy");
+                return;
+            }
+        };
+        let target = match self.state.selected_month_id() {
+            Some(id) => id,
+            None => return,
+        };
+
+        self.state.ui.is_loading = true;
+        let result = rollover::copy_entries(&self.api, source, target).await;
+        self.state.ui.is_loading = false;
+        match result {
+            Ok(start) => self.state.set_success(format!(
+                "Copied {} expense(s) and {} income(s) from {}",
+                start.expenses, start.incomes, source_name
+            )),
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to copy {}: {}", source_name, e)),
+        }
+        self.load_month_data().await;
+    }
+
+    /// Ask for a CSV to fill the empty selected month from
+    fn open_import_csv(&mut self) {
+        if !self.check_fillable_month() {
+            return;
+        }
+        if let Some(month) = self.state.selected_month() {
+            self.state.ui.modal = Some(Modal::ImportCsv {
+                month_name: month.display_name(),
+                path: String::new(),
+            });
+        }
+    }
+
+    /// Add the selected month's rows of a CSV in the `--import` format
+    async fn import_csv_into_month(&mut self, path: &str) {
+        self.begin_task(TaskKind::Import);
+        let month = match self.state.selected_month() {
+            Some(month) => month.clone(),
+            None => return,
+        };
+        let import = match std::fs::read_to_string(path)
+            .map_err(|e| anyhow::anyhow!("Failed to read {}: {}", path, e))
+            .and_then(|text| HistoryImport::parse(&text))
+        {
+            Ok(import) => import,
+            Err(e) => {
+                self.state.set_error(e.to_string());
+                return;
+            }
+        };
+
+        self.state.ui.is_loading = true;
+        let data = &self.state.data;
+        let result = import
+            .fill_month(
+                &self.api,
+                &month,
+                &data.categories,
+                &data.periods,
+                &data.income_types,
+            )
+            .await;
+        self.state.ui.is_loading = false;
+        match result {
+            Ok(summary) => self.state.set_success(format!(
+                "Imported {} expense(s) and {} income(s) into {}",
+                summary.expenses,
+                summary.incomes,
+                month.display_name()
+            )),
+            Err(e) => self.state.set_error(format!("{:#}", e)),
+        }
+        self.load_month_data().await;
+    }
+
+    /// Show the session's actions, newest first
+    fn open_history(&mut self) {
+        let entries = self
+            .state
+            .history
+            .entries()
+            .map(|entry| (entry.at.format("%H:%M").to_string(), entry.label.clone()))
+            .collect();
+        self.state.ui.modal = Some(Modal::History {
+            entries,
+            selected: 0,
+        });
+    }
+
+    /// Remove chip `index` of the view bar, reloading if the server filtered by it
+    async fn remove_view_chip(&mut self, index: usize) {
+        if let Some(chip) = self.state.remove_view_chip(index) {
+            self.reset_list_selection();
+            if chip.is_server_filter() {
+                self.load_tab_data().await;
+            }
+        }
+    }
+
     /// Handle modal keys
     async fn handle_modal_key(&mut self, key: KeyEvent) {
+        // What's new, from the help
+        if matches!(self.state.ui.modal, Some(Modal::Help)) && key.code == KeyCode::Char('w') {
+            let releases = match changelog::release(CHANGELOG, VERSION.trim()) {
+                Some(release) => vec![release],
+                None => changelog::parse(CHANGELOG),
+            };
+            self.state.ui.modal = Some(Modal::WhatsNew {
+                releases,
+                scroll: 0,
+            });
+            return;
+        }
+
+        // Report a problem, from the help
+        if matches!(self.state.ui.modal, Some(Modal::Help)) && key.code == KeyCode::Char('r') {
+            self.open_problem_report();
+            return;
+        }
+
+        // Handle the problem report
+        if let Some(Modal::ReportProblem { ref body, ref url }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Enter | KeyCode::Char('o') => match feedback::open_in_browser(url) {
+                    Ok(()) => {
+                        self.state.ui.modal = None;
+                        self.state
+                            .set_success("Opened the issue page; submit it there to send it");
+                    }
+                    Err(e) => self.state.set_error(format!(
+                        "Couldn't open a browser ({}); press c to copy the report",
+                        e
+                    )),
+                },
+                KeyCode::Char('c') => match clipboard::copy(body) {
+                    Ok(()) => self.state.set_success(format!(
+                        "Report copied; paste it at {}",
+                        feedback::ISSUES_URL
+                    )),
+                    Err(e) => self.state.set_error(format!("Failed to copy: {}", e)),
+                },
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
+                }
+                _ => {}
+            }
+            return;
+        }
+
+        // Handle what's new
+        if let Some(Modal::WhatsNew {
+            ref releases,
+            ref mut scroll,
+        }) = self.state.ui.modal
+        {
+            match key.code {
+                KeyCode::Esc | KeyCode::Enter | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Char('j') | KeyCode::Down => {
+                    let lines: usize = releases.iter().map(|r| r.changes.len() + 2).sum();
+                    *scroll = (*scroll + 1).min(lines.saturating_sub(1));
+                }
+                KeyCode::Char('k') | KeyCode::Up => {
+                    *scroll = scroll.saturating_sub(1);
+                }
+                _ => {}
+            }
+            return;
+        }
+
         // Handle ExpenseForm modal
         if matches!(self.state.ui.modal, Some(Modal::ExpenseForm { .. })) {
             self.handle_expense_form_key(key).await;
@@ -524,403 +1960,1064 @@ impl App {
             return;
         }
 
-        // Handle PasswordForm modal
-        if matches!(self.state.ui.modal, Some(Modal::PasswordForm)) {
-            self.handle_password_form_key(key).await;
-            return;
+        // Handle AccountForm modal
+        if let Some(Modal::AccountForm { ref mut form }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Esc => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Tab | KeyCode::BackTab => {
+                    form.kind = form.kind.next();
+                }
+                KeyCode::Enter => {
+                    self.save_account().await;
+                }
+                KeyCode::Char(c) => form.name.push(c),
+                KeyCode::Backspace => {
+                    form.name.pop();
+                }
+                _ => {}
+            }
+            return;
         }
 
-        // Handle ConfirmPay modal with editable amount
-        if let Some(Modal::ConfirmPay {
-            ref mut amount_input,
-            ..
+        // Handle the month's transfers
+        if let Some(Modal::Transfers {
+            ref mut selected,
+            ref mut confirm_remove,
         }) = self.state.ui.modal
         {
+            let count = self.state.data.transfers.len();
+            let removing = *confirm_remove;
+            *confirm_remove = false;
             match key.code {
                 KeyCode::Esc => {
                     self.state.ui.modal = None;
                 }
-                KeyCode::Enter => {
-                    self.confirm_pay().await;
-                }
-                KeyCode::Char(c) if c.is_ascii_digit() || c == '.' => {
-                    // Only allow one decimal point
-                    if c == '.' && amount_input.contains('.') {
-                        return;
+                KeyCode::Char('j') | KeyCode::Down => {
+                    if *selected + 1 < count {
+                        *selected += 1;
                     }
-                    amount_input.push(c);
                 }
-                KeyCode::Backspace => {
-                    amount_input.pop();
+                KeyCode::Char('k') | KeyCode::Up => {
+                    *selected = selected.saturating_sub(1);
                 }
+                KeyCode::Char('n') => self.new_transfer(),
+                KeyCode::Char('d') if removing => self.delete_transfer().await,
+                KeyCode::Char('d') if count > 0 => *confirm_remove = true,
                 _ => {}
             }
             return;
         }
 
-        match key.code {
-            KeyCode::Esc => {
-                self.state.ui.modal = None;
-            }
-            KeyCode::Char('y') => {
-                if matches!(self.state.ui.modal, Some(Modal::ConfirmDelete { .. })) {
-                    self.confirm_delete().await;
-                } else if matches!(self.state.ui.modal, Some(Modal::ConfirmCloseMonth { .. })) {
-                    self.confirm_close_month().await;
+        // Handle the transfer form
+        if let Some(Modal::TransferForm { ref mut form }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Esc => self.show_transfers(0),
+                KeyCode::Tab | KeyCode::Down => form.focused = form.focused.next(),
+                KeyCode::BackTab | KeyCode::Up => form.focused = form.focused.previous(),
+                KeyCode::Left | KeyCode::Right => {
+                    let forward = key.code == KeyCode::Right;
+                    let accounts = &self.state.data.accounts;
+                    match form.focused {
+                        TransferField::From => {
+                            form.from_account_id =
+                                forms::step_account(accounts, form.from_account_id, forward);
+                        }
+                        TransferField::To => {
+                            form.to_account_id =
+                                forms::step_account(accounts, form.to_account_id, forward);
+                        }
+                        _ => {}
+                    }
+                }
+                KeyCode::Enter => {
+                    self.save_transfer().await;
                 }
+                KeyCode::Char(c) => match form.focused {
+                    TransferField::Amount if money_input::accepts_char(&form.amount, c) => {
+                        form.amount.push(c);
+                    }
+                    TransferField::Notes => form.notes.push(c),
+                    _ => {}
+                },
+                KeyCode::Backspace => match form.focused {
+                    TransferField::Amount => {
+                        form.amount.pop();
+                    }
+                    TransferField::Notes => {
+                        form.notes.pop();
+                    }
+                    _ => {}
+                },
+                _ => {}
             }
-            KeyCode::Char('n') => {
-                if matches!(self.state.ui.modal, Some(Modal::ConfirmDelete { .. }))
-                    || matches!(self.state.ui.modal, Some(Modal::ConfirmCloseMonth { .. }))
-                {
+            return;
+        }
+
+        // Handle the debt form
+        if let Some(Modal::DebtForm { ref mut form }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Esc => {
                     self.state.ui.modal = None;
                 }
+                KeyCode::Tab | KeyCode::Down => form.focused = form.focused.next(),
+                KeyCode::BackTab | KeyCode::Up => form.focused = form.focused.previous(),
+                KeyCode::Left | KeyCode::Right => form.step_choice(
+                    &self.state.data.categories,
+                    &self.state.data.periods,
+                    key.code == KeyCode::Right,
+                ),
+                KeyCode::Enter => {
+                    self.save_debt().await;
+                }
+                KeyCode::Char(c) => match form.focused {
+                    DebtField::Name => form.name.push(c),
+                    DebtField::Principal if money_input::accepts_char(&form.principal, c) => {
+                        form.principal.push(c);
+                    }
+                    DebtField::Rate if c.is_ascii_digit() || c == '.' => {
+                        form.interest_rate.push(c);
+                    }
+                    DebtField::Minimum if money_input::accepts_char(&form.minimum_payment, c) => {
+                        form.minimum_payment.push(c);
+                    }
+                    _ => {}
+                },
+                KeyCode::Backspace => {
+                    let text = match form.focused {
+                        DebtField::Name => Some(&mut form.name),
+                        DebtField::Principal => Some(&mut form.principal),
+                        DebtField::Rate => Some(&mut form.interest_rate),
+                        DebtField::Minimum => Some(&mut form.minimum_payment),
+                        DebtField::Category | DebtField::Period => None,
+                    };
+                    if let Some(text) = text {
+                        text.pop();
+                    }
+                }
+                _ => {}
             }
-            _ => {
-                // For help modal, any key closes it
-                if matches!(self.state.ui.modal, Some(Modal::Help)) {
+            return;
+        }
+
+        // Handle the amount of a debt payment
+        if let Some(Modal::DebtPayment { ref mut amount, .. }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Esc => {
                     self.state.ui.modal = None;
                 }
+                KeyCode::Enter => {
+                    self.record_debt_payment().await;
+                }
+                KeyCode::Char(c) if money_input::accepts_char(amount, c) => amount.push(c),
+                KeyCode::Backspace => {
+                    amount.pop();
+                }
+                _ => {}
             }
+            return;
         }
-    }
 
-    /// Handle expense form keys
-    async fn handle_expense_form_key(&mut self, key: KeyEvent) {
-        // Special handling for Purchases field
-        if self.expense_form.focused_field == ExpenseField::Purchases {
-            // Handle Ctrl+key combinations first
-            if key.modifiers.contains(KeyModifiers::CONTROL) {
-                match key.code {
-                    KeyCode::Char('n') => {
-                        // Ctrl+n: Add new purchase
-                        self.expense_form.add_purchase();
-                        return;
-                    }
-                    KeyCode::Char('d') => {
-                        // Ctrl+d: Delete selected purchase
-                        self.expense_form.remove_purchase();
-                        return;
+        // Handle PasswordForm modal
+        if matches!(self.state.ui.modal, Some(Modal::PasswordForm)) {
+            self.handle_password_form_key(key).await;
+            return;
+        }
+
+        // Handle duplicate name prompt
+        if matches!(self.state.ui.modal, Some(Modal::ConfirmDuplicate { .. })) {
+            self.handle_duplicate_key(key);
+            return;
+        }
+
+        // Handle merge target selection
+        if let Some(Modal::MergeSelect {
+            ref targets,
+            ref mut selected,
+            ..
+        }) = self.state.ui.modal
+        {
+            match key.code {
+                KeyCode::Esc => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Char('j') | KeyCode::Down => {
+                    if *selected + 1 < targets.len() {
+                        *selected += 1;
                     }
-                    _ => {}
                 }
+                KeyCode::Char('k') | KeyCode::Up => {
+                    *selected = selected.saturating_sub(1);
+                }
+                KeyCode::Enter => {
+                    self.prepare_merge().await;
+                }
+                _ => {}
             }
+            return;
+        }
 
+        // Handle merge confirmation
+        if matches!(self.state.ui.modal, Some(Modal::ConfirmMerge { .. })) {
             match key.code {
-                KeyCode::Esc => {
+                KeyCode::Char('y') => {
+                    self.execute_merge().await;
+                }
+                KeyCode::Char('n') | KeyCode::Esc => {
                     self.state.ui.modal = None;
                 }
-                KeyCode::Tab => {
-                    self.expense_form.focused_field = self.expense_form.focused_field.next();
+                _ => {}
+            }
+            return;
+        }
+
+        // Handle autofill preview
+        if matches!(self.state.ui.modal, Some(Modal::Autofill { .. })) {
+            match key.code {
+                KeyCode::Char('y') | KeyCode::Enter => {
+                    self.apply_autofill().await;
                 }
-                KeyCode::BackTab => {
-                    self.expense_form.focused_field = self.expense_form.focused_field.previous();
+                KeyCode::Char('n') | KeyCode::Esc => {
+                    self.state.ui.modal = None;
                 }
+                _ => {}
+            }
+            return;
+        }
+
+        // Handle the reallocation board
+        if let Some(Modal::Reallocate { ref mut board }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Char('j') | KeyCode::Down => board.select_next(),
+                KeyCode::Char('k') | KeyCode::Up => board.select_previous(),
+                KeyCode::Char('-') | KeyCode::Left => board.take(),
+                KeyCode::Char('+') | KeyCode::Char('=') | KeyCode::Right => board.give(),
+                KeyCode::Char('s') => board.next_step(),
                 KeyCode::Enter => {
-                    // If no purchases yet, add one; otherwise save
-                    if self.expense_form.purchases.is_empty() {
-                        self.expense_form.add_purchase();
+                    if !board.is_balanced() {
+                        self.state
+                            .set_error("Give the held money to a category before applying");
+                    } else if !board.has_changes() {
+                        self.state.ui.modal = None;
                     } else {
-                        self.save_expense().await;
+                        self.apply_reallocate().await;
                     }
                 }
-                KeyCode::Insert => {
-                    // Insert: Add new purchase
-                    self.expense_form.add_purchase();
-                }
-                KeyCode::Delete => {
-                    // Delete: Remove selected purchase
-                    self.expense_form.remove_purchase();
-                }
-                KeyCode::Up if self.expense_form.selected_purchase > 0 => {
-                    // Navigate up in purchases
-                    self.expense_form.selected_purchase -= 1;
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
                 }
-                KeyCode::Down
-                    if !self.expense_form.purchases.is_empty()
-                        && self.expense_form.selected_purchase
-                            < self.expense_form.purchases.len() - 1 =>
-                {
-                    // Navigate down in purchases
-                    self.expense_form.selected_purchase += 1;
+                _ => {}
+            }
+            return;
+        }
+
+        // Handle environment export snippet
+        if let Some(Modal::EnvExport {
+            ref url,
+            ref api_key,
+            ref mut reveal_key,
+        }) = self.state.ui.modal
+        {
+            match key.code {
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
                 }
-                KeyCode::Left | KeyCode::Right => {
-                    // Toggle between name and amount
-                    self.expense_form.purchase_edit_field =
-                        match self.expense_form.purchase_edit_field {
-                            PurchaseEditField::Name => PurchaseEditField::Amount,
-                            PurchaseEditField::Amount => PurchaseEditField::Name,
-                        };
+                KeyCode::Char('s') => {
+                    *reveal_key = !*reveal_key;
                 }
-                KeyCode::Char(c) => {
-                    // Text input for current purchase field
-                    let idx = self.expense_form.selected_purchase;
-                    match self.expense_form.purchase_edit_field {
-                        PurchaseEditField::Name => {
-                            if let Some(purchase) = self.expense_form.purchases.get_mut(idx) {
-                                purchase.name.push(c);
-                            }
-                        }
-                        PurchaseEditField::Amount => {
-                            if let Some(amount_str) =
-                                self.expense_form.purchase_amount_inputs.get_mut(idx)
-                            {
-                                if c.is_ascii_digit() || (c == '.' && !amount_str.contains('.')) {
-                                    amount_str.push(c);
-                                }
-                            }
-                        }
+                KeyCode::Char('c') | KeyCode::Enter => {
+                    let snippet = config::env_exports(url, api_key, !*reveal_key).join("\n");
+                    match clipboard::copy(&snippet) {
+                        Ok(()) => self.state.set_success("Copied to clipboard"),
+                        Err(e) => self.state.set_error(format!("Failed to copy: {}", e)),
                     }
                 }
-                KeyCode::Backspace => {
-                    let idx = self.expense_form.selected_purchase;
-                    match self.expense_form.purchase_edit_field {
-                        PurchaseEditField::Name => {
-                            if let Some(purchase) = self.expense_form.purchases.get_mut(idx) {
-                                purchase.name.pop();
-                            }
-                        }
-                        PurchaseEditField::Amount => {
-                            if let Some(amount_str) =
-                                self.expense_form.purchase_amount_inputs.get_mut(idx)
-                            {
-                                amount_str.pop();
-                            }
-                        }
-                    }
+                _ => {}
+            }
+            return;
+        }
+
+        // Handle share link
+        if let Some(Modal::ShareLink { ref url, .. }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
                 }
+                KeyCode::Char('c') | KeyCode::Enter => match clipboard::copy(url) {
+                    Ok(()) => self.state.set_success("Copied to clipboard"),
+                    Err(e) => self.state.set_error(format!("Failed to copy: {}", e)),
+                },
                 _ => {}
             }
             return;
         }
 
-        // Standard field handling
-        match key.code {
-            KeyCode::Esc => {
+        // Handle the inspect popup
+        if let Some(Modal::Inspect { .. }) = self.state.ui.modal {
+            if matches!(
+                key.code,
+                KeyCode::Esc | KeyCode::Enter | KeyCode::Char('q') | KeyCode::Char('i')
+            ) {
                 self.state.ui.modal = None;
             }
-            KeyCode::Tab => {
-                self.expense_form.focused_field = self.expense_form.focused_field.next();
+            return;
+        }
+
+        // Handle request performance panel
+        if let Some(Modal::Performance { ref mut endpoints }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Char('r') => {
+                    *endpoints = self.api.request_metrics();
+                }
+                KeyCode::Char('c') => {
+                    self.api.reset_request_metrics();
+                    endpoints.clear();
+                }
+                _ => {}
             }
-            KeyCode::BackTab => {
-                self.expense_form.focused_field = self.expense_form.focused_field.previous();
+            return;
+        }
+
+        // Handle debug panel
+        if let Some(Modal::Debug { .. }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Char('r') => {
+                    self.state.ui.modal = Some(self.debug_panel());
+                }
+                KeyCode::Char('c') => {
+                    self.api.reset_request_metrics();
+                    self.state.ui.modal = Some(self.debug_panel());
+                }
+                _ => {}
             }
-            KeyCode::Enter => {
-                self.save_expense().await;
+            return;
+        }
+
+        // Handle Notes modal with free text editing
+        if let Some(Modal::Notes {
+            ref mut text,
+            shared,
+            ..
+        }) = self.state.ui.modal
+        {
+            match key.code {
+                KeyCode::Esc if shared => {
+                    self.save_shared_notes().await;
+                }
+                KeyCode::Esc => {
+                    self.save_notes();
+                }
+                KeyCode::Enter => {
+                    text.push('\n');
+                }
+                KeyCode::Char(c) => {
+                    text.push(c);
+                }
+                KeyCode::Backspace => {
+                    text.pop();
+                }
+                _ => {}
             }
-            KeyCode::Left => {
-                // For select fields, cycle through options
-                match self.expense_form.focused_field {
-                    ExpenseField::Period => {
-                        if let Some(current_idx) = self
-                            .state
-                            .data
-                            .periods
-                            .iter()
-                            .position(|p| p.name == self.expense_form.period)
-                        {
-                            let new_idx = if current_idx == 0 {
-                                self.state.data.periods.len() - 1
-                            } else {
-                                current_idx - 1
-                            };
-                            if let Some(period) = self.state.data.periods.get(new_idx) {
-                                self.expense_form.period = period.name.clone();
-                            }
-                        }
+            return;
+        }
+
+        // Handle the CSV path prompt
+        if let Some(Modal::ImportCsv { ref mut path, .. }) = self.state.ui.modal {
+            match key.code {
+                KeyCode::Esc => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Enter => {
+                    let path = path.trim().to_string();
+                    self.state.ui.modal = None;
+                    self.import_csv_into_month(&path).await;
+                }
+                KeyCode::Char(c) => {
+                    path.push(c);
+                }
+                KeyCode::Backspace => {
+                    path.pop();
+                }
+                _ => {}
+            }
+            return;
+        }
+
+        // Handle history panel
+        if let Some(Modal::History {
+            ref entries,
+            ref mut selected,
+        }) = self.state.ui.modal
+        {
+            match key.code {
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Char('j') | KeyCode::Down => {
+                    if *selected + 1 < entries.len() {
+                        *selected += 1;
                     }
-                    ExpenseField::Category => {
-                        if let Some(current_idx) = self
-                            .state
-                            .data
-                            .categories
-                            .iter()
-                            .position(|c| c.name == self.expense_form.category)
-                        {
-                            let new_idx = if current_idx == 0 {
-                                self.state.data.categories.len() - 1
-                            } else {
-                                current_idx - 1
-                            };
-                            if let Some(category) = self.state.data.categories.get(new_idx) {
-                                self.expense_form.category = category.name.clone();
-                            }
-                        }
+                }
+                KeyCode::Char('k') | KeyCode::Up => {
+                    *selected = selected.saturating_sub(1);
+                }
+                KeyCode::Enter => {
+                    let index = *selected;
+                    self.state.ui.modal = None;
+                    if let Some(entry) = self.state.history.get(index) {
+                        let action = entry.action.clone();
+                        self.repeat_action(action).await;
                     }
-                    _ => {}
                 }
+                _ => {}
             }
-            KeyCode::Right => {
-                // For select fields, cycle through options
-                match self.expense_form.focused_field {
-                    ExpenseField::Period => {
-                        if let Some(current_idx) = self
-                            .state
-                            .data
-                            .periods
-                            .iter()
-                            .position(|p| p.name == self.expense_form.period)
-                        {
-                            let new_idx = (current_idx + 1) % self.state.data.periods.len();
-                            if let Some(period) = self.state.data.periods.get(new_idx) {
-                                self.expense_form.period = period.name.clone();
-                            }
-                        }
-                    }
-                    ExpenseField::Category => {
-                        if let Some(current_idx) = self
-                            .state
-                            .data
-                            .categories
-                            .iter()
-                            .position(|c| c.name == self.expense_form.category)
-                        {
-                            let new_idx = (current_idx + 1) % self.state.data.categories.len();
-                            if let Some(category) = self.state.data.categories.get(new_idx) {
-                                self.expense_form.category = category.name.clone();
-                            }
-                        }
+            return;
+        }
+
+        // Handle Checklist modal
+        if let Some(Modal::Checklist {
+            ref items,
+            ref mut selected,
+            ..
+        }) = self.state.ui.modal
+        {
+            match key.code {
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Char('j') | KeyCode::Down => {
+                    if *selected + 1 < items.len() {
+                        *selected += 1;
                     }
-                    _ => {}
                 }
+                KeyCode::Char('k') | KeyCode::Up => {
+                    *selected = selected.saturating_sub(1);
+                }
+                KeyCode::Char(' ') | KeyCode::Enter => {
+                    self.toggle_checklist_item();
+                }
+                _ => {}
             }
-            KeyCode::Char(c) => {
-                // Text input for text fields
-                match self.expense_form.focused_field {
-                    ExpenseField::Name => {
-                        self.expense_form.name.push(c);
+            return;
+        }
+
+        // Handle Reimbursements modal
+        if let Some(Modal::Reimbursements {
+            ref report,
+            ref mut selected,
+        }) = self.state.ui.modal
+        {
+            match key.code {
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Char('j') | KeyCode::Down => {
+                    if *selected + 1 < report.rows.len() {
+                        *selected += 1;
                     }
-                    ExpenseField::Projected
-                        if c.is_ascii_digit()
-                            || (c == '.' && !self.expense_form.projected.contains('.')) =>
-                    {
-                        self.expense_form.projected.push(c);
+                }
+                KeyCode::Char('k') | KeyCode::Up => {
+                    *selected = selected.saturating_sub(1);
+                }
+                KeyCode::Char(' ') | KeyCode::Enter | KeyCode::Char('r') => {
+                    self.toggle_reimbursed();
+                }
+                _ => {}
+            }
+            return;
+        }
+
+        if let Some(Modal::ExpenseDetail {
+            ref expense,
+            ref mut selected,
+            ref mut confirm_remove,
+            ref mut file,
+        }) = self.state.ui.modal
+        {
+            let count = expense.purchases.as_ref().map_or(0, Vec::len);
+            let files = expense.attachment_count();
+            let removing = *confirm_remove;
+            *confirm_remove = false;
+            match key.code {
+                KeyCode::Esc | KeyCode::Char('q') => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Char('j') | KeyCode::Down => {
+                    if *selected + 1 < count {
+                        *selected += 1;
                     }
-                    ExpenseField::Notes => {
-                        self.expense_form.notes.push(c);
+                }
+                KeyCode::Char('k') | KeyCode::Up => {
+                    *selected = selected.saturating_sub(1);
+                }
+                KeyCode::Char('a') | KeyCode::Char('n') => self.edit_purchases(None),
+                KeyCode::Char('e') | KeyCode::Enter if count > 0 => {
+                    let index = *selected;
+                    self.edit_purchases(Some(index));
+                }
+                KeyCode::Char('d') if removing => self.remove_purchase().await,
+                KeyCode::Char('d') if count > 0 => *confirm_remove = true,
+                KeyCode::Tab if files > 0 => *file = (*file + 1) % files,
+                KeyCode::Char('o') => {
+                    let expense = expense.clone();
+                    let index = *file;
+                    self.open_attachment(&expense, index).await;
+                }
+                KeyCode::Char('u') => {
+                    let expense = expense.clone();
+                    if self.check_scope(Some(Scope::Expenses)) {
+                        self.state.ui.modal = Some(Modal::AttachFile {
+                            expense,
+                            path: String::new(),
+                        });
                     }
-                    _ => {}
                 }
+                _ => {}
             }
-            KeyCode::Backspace => match self.expense_form.focused_field {
-                ExpenseField::Name => {
-                    self.expense_form.name.pop();
+            return;
+        }
+
+        // Handle the path prompt for a file to attach
+        if let Some(Modal::AttachFile {
+            ref expense,
+            ref mut path,
+        }) = self.state.ui.modal
+        {
+            match key.code {
+                KeyCode::Esc => {
+                    let expense = expense.clone();
+                    self.show_expense_detail(expense);
                 }
-                ExpenseField::Projected => {
-                    self.expense_form.projected.pop();
+                KeyCode::Enter => {
+                    let (expense, path) = (expense.clone(), path.trim().to_string());
+                    self.attach_file(expense, &path).await;
                 }
-                ExpenseField::Notes => {
-                    self.expense_form.notes.pop();
+                KeyCode::Char(c) => {
+                    path.push(c);
+                }
+                KeyCode::Backspace => {
+                    path.pop();
                 }
                 _ => {}
-            },
-            _ => {}
+            }
+            return;
         }
-    }
 
-    /// Handle income form keys
-    async fn handle_income_form_key(&mut self, key: KeyEvent) {
-        use crate::state::forms::IncomeField;
+        if let Some(Modal::Rollover {
+            year,
+            month,
+            previous,
+            template,
+            ..
+        }) = &self.state.ui.modal
+        {
+            let calendar_month = (*year, *month);
+            let autofill = key.code == KeyCode::Char('a');
+            let source = match key.code {
+                KeyCode::Char('c') | KeyCode::Char('a') if previous.is_some() => {
+                    previous.as_ref().map(|(id, _)| *id)
+                }
+                KeyCode::Char('t') if template.is_some() => template.as_ref().map(|(id, _)| *id),
+                KeyCode::Char('e') => None,
+                KeyCode::Esc => {
+                    self.state.ui.modal = None;
+                    return;
+                }
+                _ => return,
+            };
+            self.start_month(calendar_month, source).await;
+            // Copied budgets, then set from what was actually spent
+            if autofill && self.state.ui.error_message.is_none() {
+                self.open_autofill().await;
+            }
+            return;
+        }
+
+        // Handle ConfirmPay modal with editable amount
+        if let Some(Modal::ConfirmPay {
+            ref mut amount_input,
+            ..
+        }) = self.state.ui.modal
+        {
+            match key.code {
+                KeyCode::Esc => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Enter => {
+                    self.confirm_pay().await;
+                }
+                KeyCode::Char(c) if money_input::accepts_char(amount_input, c) => {
+                    amount_input.push(c);
+                }
+                KeyCode::Backspace => {
+                    amount_input.pop();
+                }
+                _ => {}
+            }
+            return;
+        }
 
         match key.code {
             KeyCode::Esc => {
                 self.state.ui.modal = None;
             }
-            KeyCode::Tab => {
-                self.income_form.focused_field = self.income_form.focused_field.next();
-            }
-            KeyCode::BackTab => {
-                self.income_form.focused_field = self.income_form.focused_field.previous();
+            KeyCode::Char('y') => {
+                if matches!(self.state.ui.modal, Some(Modal::ConfirmDelete { .. })) {
+                    self.confirm_delete().await;
+                } else if matches!(self.state.ui.modal, Some(Modal::ConfirmCloseMonth { .. })) {
+                    self.confirm_close_month().await;
+                }
             }
-            KeyCode::Enter => {
-                self.save_income().await;
+            KeyCode::Char('n') => {
+                if matches!(self.state.ui.modal, Some(Modal::ConfirmDelete { .. }))
+                    || matches!(self.state.ui.modal, Some(Modal::ConfirmCloseMonth { .. }))
+                {
+                    self.state.ui.modal = None;
+                }
             }
-            KeyCode::Left => match self.income_form.focused_field {
-                IncomeField::IncomeType => {
-                    if let Some(current_id) = self.income_form.income_type_id {
-                        if let Some(current_idx) = self
-                            .state
-                            .data
-                            .income_types
-                            .iter()
-                            .position(|it| it.id == current_id)
-                        {
-                            let new_idx = if current_idx == 0 {
-                                self.state.data.income_types.len() - 1
-                            } else {
-                                current_idx - 1
-                            };
-                            if let Some(it) = self.state.data.income_types.get(new_idx) {
-                                self.income_form.income_type_id = Some(it.id);
-                            }
-                        }
-                    }
+            _ => {
+                // For help modal, any key closes it
+                if matches!(self.state.ui.modal, Some(Modal::Help)) {
+                    self.state.ui.modal = None;
                 }
-                IncomeField::Period => {
-                    if let Some(current_idx) = self
-                        .state
-                        .data
-                        .periods
-                        .iter()
-                        .position(|p| p.name == self.income_form.period)
-                    {
-                        let new_idx = if current_idx == 0 {
-                            self.state.data.periods.len() - 1
-                        } else {
-                            current_idx - 1
+            }
+        }
+    }
+
+    /// Handle expense form keys
+    async fn handle_expense_form_key(&mut self, key: KeyEvent) {
+        // Special handling for Purchases field
+        if self.expense_form.focused_field == ExpenseField::Purchases {
+            // Handle Ctrl+key combinations first
+            if key.modifiers.contains(KeyModifiers::CONTROL) {
+                match key.code {
+                    KeyCode::Char('n') => {
+                        // Ctrl+n: Add new purchase
+                        self.expense_form.add_purchase();
+                        return;
+                    }
+                    KeyCode::Char('d') => {
+                        // Ctrl+d: Delete selected purchase
+                        self.expense_form.remove_purchase();
+                        return;
+                    }
+                    KeyCode::Char('t') => {
+                        // Ctrl+t: Switch between summing purchases and keeping the cost
+                        self.expense_form.sum_purchases = !self.expense_form.sum_purchases;
+                        return;
+                    }
+                    _ => {}
+                }
+            }
+
+            match key.code {
+                KeyCode::Esc => {
+                    self.state.ui.modal = None;
+                }
+                KeyCode::Tab => {
+                    self.expense_form.tidy_amounts();
+                    self.expense_form.focus_next();
+                }
+                KeyCode::BackTab => {
+                    self.expense_form.tidy_amounts();
+                    self.expense_form.focus_previous();
+                }
+                KeyCode::Enter => {
+                    // If no purchases yet, add one; otherwise save
+                    if self.expense_form.purchases.is_empty() {
+                        self.expense_form.add_purchase();
+                    } else {
+                        self.save_expense().await;
+                    }
+                }
+                KeyCode::Insert => {
+                    // Insert: Add new purchase
+                    self.expense_form.add_purchase();
+                }
+                KeyCode::Delete => {
+                    // Delete: Remove selected purchase
+                    self.expense_form.remove_purchase();
+                }
+                KeyCode::Up if self.expense_form.selected_purchase > 0 => {
+                    // Navigate up in purchases
+                    self.expense_form.selected_purchase -= 1;
+                }
+                KeyCode::Down
+                    if !self.expense_form.purchases.is_empty()
+                        && self.expense_form.selected_purchase
+                            < self.expense_form.purchases.len() - 1 =>
+                {
+                    // Navigate down in purchases
+                    self.expense_form.selected_purchase += 1;
+                }
+                KeyCode::Left | KeyCode::Right => {
+                    // Toggle between name and amount
+                    self.expense_form.purchase_edit_field =
+                        match self.expense_form.purchase_edit_field {
+                            PurchaseEditField::Name => PurchaseEditField::Amount,
+                            PurchaseEditField::Amount => PurchaseEditField::Name,
                         };
-                        if let Some(period) = self.state.data.periods.get(new_idx) {
-                            self.income_form.period = period.name.clone();
+                }
+                KeyCode::Char(c) => {
+                    // Text input for current purchase field
+                    let idx = self.expense_form.selected_purchase;
+                    match self.expense_form.purchase_edit_field {
+                        PurchaseEditField::Name => {
+                            if let Some(purchase) = self.expense_form.purchases.get_mut(idx) {
+                                purchase.name.push(c);
+                            }
+                        }
+                        PurchaseEditField::Amount => {
+                            if let Some(amount_str) =
+                                self.expense_form.purchase_amount_inputs.get_mut(idx)
+                            {
+                                if money_input::accepts_char(amount_str, c) {
+                                    amount_str.push(c);
+                                }
+                            }
+                        }
+                    }
+                }
+                KeyCode::Backspace => {
+                    let idx = self.expense_form.selected_purchase;
+                    match self.expense_form.purchase_edit_field {
+                        PurchaseEditField::Name => {
+                            if let Some(purchase) = self.expense_form.purchases.get_mut(idx) {
+                                purchase.name.pop();
+                            }
+                        }
+                        PurchaseEditField::Amount => {
+                            if let Some(amount_str) =
+                                self.expense_form.purchase_amount_inputs.get_mut(idx)
+                            {
+                                amount_str.pop();
+                            }
                         }
                     }
                 }
                 _ => {}
-            },
-            KeyCode::Right => match self.income_form.focused_field {
-                IncomeField::IncomeType => {
-                    if let Some(current_id) = self.income_form.income_type_id {
+            }
+            return;
+        }
+
+        // Custom fields: ↑/↓ pick one, typing edits it
+        if self.expense_form.focused_field == ExpenseField::Custom {
+            let form = &mut self.expense_form;
+            let last = form.custom_fields.len().saturating_sub(1);
+            match key.code {
+                KeyCode::Up => {
+                    form.selected_custom = form.selected_custom.saturating_sub(1);
+                    return;
+                }
+                KeyCode::Down => {
+                    form.selected_custom = (form.selected_custom + 1).min(last);
+                    return;
+                }
+                KeyCode::Char(c) => {
+                    if let Some(field) = form.custom_fields.get_mut(form.selected_custom) {
+                        field.text.push(c);
+                    }
+                    return;
+                }
+                KeyCode::Backspace => {
+                    if let Some(field) = form.custom_fields.get_mut(form.selected_custom) {
+                        field.text.pop();
+                    }
+                    return;
+                }
+                _ => {}
+            }
+        }
+
+        // Standard field handling
+        match key.code {
+            KeyCode::Esc => {
+                self.state.ui.modal = None;
+            }
+            KeyCode::Tab => {
+                self.expense_form.tidy_amounts();
+                self.expense_form.focus_next();
+            }
+            KeyCode::BackTab => {
+                self.expense_form.tidy_amounts();
+                self.expense_form.focus_previous();
+            }
+            KeyCode::Enter => {
+                self.save_expense().await;
+            }
+            KeyCode::Left => {
+                // For select fields, cycle through options
+                match self.expense_form.focused_field {
+                    ExpenseField::Period => {
                         if let Some(current_idx) = self
                             .state
                             .data
-                            .income_types
+                            .periods
                             .iter()
-                            .position(|it| it.id == current_id)
+                            .position(|p| p.name == self.expense_form.period)
                         {
-                            let new_idx = (current_idx + 1) % self.state.data.income_types.len();
-                            if let Some(it) = self.state.data.income_types.get(new_idx) {
-                                self.income_form.income_type_id = Some(it.id);
+                            let new_idx = if current_idx == 0 {
+                                self.state.data.periods.len() - 1
+                            } else {
+                                current_idx - 1
+                            };
+                            if let Some(period) = self.state.data.periods.get(new_idx) {
+                                self.expense_form.period = period.name.clone();
+                            }
+                        }
+                    }
+                    ExpenseField::Account => {
+                        self.expense_form.account_id = forms::step_account(
+                            &self.state.data.accounts,
+                            self.expense_form.account_id,
+                            false,
+                        );
+                    }
+                    ExpenseField::Currency => {
+                        self.expense_form.currency = forms::step_currency(
+                            &self.state.rates.codes(),
+                            self.expense_form.currency.as_deref(),
+                            false,
+                        );
+                    }
+                    ExpenseField::Category => {
+                        if let Some(current_idx) = self
+                            .state
+                            .data
+                            .categories
+                            .iter()
+                            .position(|c| c.name == self.expense_form.category)
+                        {
+                            let new_idx = if current_idx == 0 {
+                                self.state.data.categories.len() - 1
+                            } else {
+                                current_idx - 1
+                            };
+                            if let Some(category) = self.state.data.categories.get(new_idx) {
+                                self.expense_form.category = category.name.clone();
                             }
                         }
                     }
+                    _ => {}
                 }
-                IncomeField::Period => {
-                    if let Some(current_idx) = self
-                        .state
-                        .data
-                        .periods
-                        .iter()
-                        .position(|p| p.name == self.income_form.period)
-                    {
-                        let new_idx = (current_idx + 1) % self.state.data.periods.len();
-                        if let Some(period) = self.state.data.periods.get(new_idx) {
-                            self.income_form.period = period.name.clone();
+            }
+            KeyCode::Right => {
+                // For select fields, cycle through options
+                match self.expense_form.focused_field {
+                    ExpenseField::Period => {
+                        if let Some(current_idx) = self
+                            .state
+                            .data
+                            .periods
+                            .iter()
+                            .position(|p| p.name == self.expense_form.period)
+                        {
+                            let new_idx = (current_idx + 1) % self.state.data.periods.len();
+                            if let Some(period) = self.state.data.periods.get(new_idx) {
+                                self.expense_form.period = period.name.clone();
+                            }
+                        }
+                    }
+                    ExpenseField::Account => {
+                        self.expense_form.account_id = forms::step_account(
+                            &self.state.data.accounts,
+                            self.expense_form.account_id,
+                            true,
+                        );
+                    }
+                    ExpenseField::Currency => {
+                        self.expense_form.currency = forms::step_currency(
+                            &self.state.rates.codes(),
+                            self.expense_form.currency.as_deref(),
+                            true,
+                        );
+                    }
+                    ExpenseField::Category => {
+                        if let Some(current_idx) = self
+                            .state
+                            .data
+                            .categories
+                            .iter()
+                            .position(|c| c.name == self.expense_form.category)
+                        {
+                            let new_idx = (current_idx + 1) % self.state.data.categories.len();
+                            if let Some(category) = self.state.data.categories.get(new_idx) {
+                                self.expense_form.category = category.name.clone();
+                            }
                         }
                     }
+                    _ => {}
                 }
-                _ => {}
-            },
-            KeyCode::Char(c) => match self.income_form.focused_field {
-                IncomeField::Projected
-                    if c.is_ascii_digit()
-                        || (c == '.' && !self.income_form.projected.contains('.')) =>
-                {
-                    self.income_form.projected.push(c);
+            }
+            KeyCode::Char(c) => {
+                // Text input for text fields
+                match self.expense_form.focused_field {
+                    ExpenseField::Name => {
+                        self.expense_form.name.push(c);
+                    }
+                    ExpenseField::Projected
+                        if money_input::accepts_char(&self.expense_form.projected, c) =>
+                    {
+                        self.expense_form.projected.push(c);
+                    }
+                    ExpenseField::Notes => {
+                        self.expense_form.notes.push(c);
+                    }
+                    _ => {}
                 }
-                IncomeField::Amount
-                    if c.is_ascii_digit()
-                        || (c == '.' && !self.income_form.amount.contains('.')) =>
-                {
-                    self.income_form.amount.push(c);
+            }
+            KeyCode::Backspace => match self.expense_form.focused_field {
+                ExpenseField::Name => {
+                    self.expense_form.name.pop();
+                }
+                ExpenseField::Projected => {
+                    self.expense_form.projected.pop();
+                }
+                ExpenseField::Notes => {
+                    self.expense_form.notes.pop();
+                }
+                _ => {}
+            },
+            _ => {}
+        }
+    }
+
+    /// Handle income form keys
+    async fn handle_income_form_key(&mut self, key: KeyEvent) {
+        use crate::state::forms::IncomeField;
+
+        match key.code {
+            KeyCode::Esc => {
+                self.state.ui.modal = None;
+            }
+            KeyCode::Tab => {
+                self.income_form.tidy_amounts();
+                self.income_form.focused_field = self.income_form.focused_field.next();
+            }
+            KeyCode::BackTab => {
+                self.income_form.tidy_amounts();
+                self.income_form.focused_field = self.income_form.focused_field.previous();
+            }
+            KeyCode::Enter => {
+                self.save_income().await;
+            }
+            KeyCode::Left => match self.income_form.focused_field {
+                IncomeField::IncomeType => {
+                    if let Some(current_id) = self.income_form.income_type_id {
+                        if let Some(current_idx) = self
+                            .state
+                            .data
+                            .income_types
+                            .iter()
+                            .position(|it| it.id == current_id)
+                        {
+                            let new_idx = if current_idx == 0 {
+                                self.state.data.income_types.len() - 1
+                            } else {
+                                current_idx - 1
+                            };
+                            if let Some(it) = self.state.data.income_types.get(new_idx) {
+                                self.income_form.income_type_id = Some(it.id);
+                            }
+                        }
+                    }
+                }
+                IncomeField::Account => {
+                    self.income_form.account_id = forms::step_account(
+                        &self.state.data.accounts,
+                        self.income_form.account_id,
+                        false,
+                    );
+                }
+                IncomeField::Currency => {
+                    self.income_form.currency = forms::step_currency(
+                        &self.state.rates.codes(),
+                        self.income_form.currency.as_deref(),
+                        false,
+                    );
+                }
+                IncomeField::Period => {
+                    if let Some(current_idx) = self
+                        .state
+                        .data
+                        .periods
+                        .iter()
+                        .position(|p| p.name == self.income_form.period)
+                    {
+                        let new_idx = if current_idx == 0 {
+                            self.state.data.periods.len() - 1
+                        } else {
+                            current_idx - 1
+                        };
+                        if let Some(period) = self.state.data.periods.get(new_idx) {
+                            self.income_form.period = period.name.clone();
+                        }
+                    }
+                }
+                _ => {}
+            },
+            KeyCode::Right => match self.income_form.focused_field {
+                IncomeField::IncomeType => {
+                    if let Some(current_id) = self.income_form.income_type_id {
+                        if let Some(current_idx) = self
+                            .state
+                            .data
+                            .income_types
+                            .iter()
+                            .position(|it| it.id == current_id)
+                        {
+                            let new_idx = (current_idx + 1) % self.state.data.income_types.len();
+                            if let Some(it) = self.state.data.income_types.get(new_idx) {
+                                self.income_form.income_type_id = Some(it.id);
+                            }
+                        }
+                    }
+                }
+                IncomeField::Account => {
+                    self.income_form.account_id = forms::step_account(
+                        &self.state.data.accounts,
+                        self.income_form.account_id,
+                        true,
+                    );
+                }
+                IncomeField::Currency => {
+                    self.income_form.currency = forms::step_currency(
+                        &self.state.rates.codes(),
+                        self.income_form.currency.as_deref(),
+                        true,
+                    );
+                }
+                IncomeField::Period => {
+                    if let Some(current_idx) = self
+                        .state
+                        .data
+                        .periods
+                        .iter()
+                        .position(|p| p.name == self.income_form.period)
+                    {
+                        let new_idx = (current_idx + 1) % self.state.data.periods.len();
+                        if let Some(period) = self.state.data.periods.get(new_idx) {
+                            self.income_form.period = period.name.clone();
+                        }
+                    }
+                }
+                _ => {}
+            },
+            KeyCode::Char(c) => match self.income_form.focused_field {
+                IncomeField::Projected
+                    if money_input::accepts_char(&self.income_form.projected, c) =>
+                {
+                    self.income_form.projected.push(c);
+                }
+                IncomeField::Amount if money_input::accepts_char(&self.income_form.amount, c) => {
+                    self.income_form.amount.push(c);
                 }
                 _ => {}
             },
@@ -928,933 +3025,3313 @@ impl App {
                 IncomeField::Projected => {
                     self.income_form.projected.pop();
                 }
-                IncomeField::Amount => {
-                    self.income_form.amount.pop();
+                IncomeField::Amount => {
+                    self.income_form.amount.pop();
+                }
+                _ => {}
+            },
+            _ => {}
+        }
+    }
+
+    /// Save expense (create or update)
+    async fn save_expense(&mut self) {
+        self.expense_form.invalid = None;
+        // Validate using form's validate method
+        let errors = self.expense_form.validate();
+        if !errors.is_empty() {
+            // Mark a custom field that doesn't read as its kind of value
+            let invalid = self.expense_form.custom_field_errors();
+            self.expense_form.show_field_errors(&invalid);
+            self.state.set_error(errors.join(", "));
+            return;
+        }
+
+        let month_id = match self.state.selected_month_id() {
+            Some(id) => id,
+            None => {
+                self.state.set_error("No month selected");
+                return;
+            }
+        };
+
+        self.state.ui.is_loading = true;
+
+        let mut created = None;
+        let result = if let Some(id) = self.expense_form.editing_id {
+            // Update existing expense using form's to_update method
+            match self.expense_form.to_update() {
+                Some(update) if update.is_empty() => {
+                    self.close_unchanged_form();
+                    return;
+                }
+                Some(update) => match update.check() {
+                    Ok(()) => self.api.expenses().update(id, &update).await,
+                    Err(e) => Err(e),
+                },
+                None => {
+                    self.state.ui.is_loading = false;
+                    self.state.set_error("Invalid expense data");
+                    return;
+                }
+            }
+        } else {
+            // Create new expense using form's to_create method
+            match self.expense_form.to_create(month_id) {
+                Some(create) => match create.check() {
+                    Ok(()) => {
+                        let result = self.api.expenses().create(&create).await;
+                        created = Some(create);
+                        result
+                    }
+                    Err(e) => Err(e),
+                },
+                None => {
+                    self.state.ui.is_loading = false;
+                    self.state.set_error("Invalid expense data");
+                    return;
+                }
+            }
+        };
+
+        let editing_id = self.expense_form.editing_id;
+
+        self.state.ui.is_loading = false;
+        if let Err(e @ ApiError::Validation(_)) = &result {
+            // Keep the form open on the field to fix
+            self.expense_form.show_field_errors(e.field_errors());
+            self.state.set_error(e.to_string());
+            return;
+        }
+        self.state.ui.modal = None;
+        self.expense_form = ExpenseFormState::default();
+
+        match (result, editing_id) {
+            (Ok(_), _) => {
+                let action = if editing_id.is_some() {
+                    "updated"
+                } else {
+                    "created"
+                };
+                self.state
+                    .set_success(format!("Expense {} successfully", action));
+                if let Some(create) = created {
+                    let label = format!(
+                        "Created expense {} ({})",
+                        create.expense_name,
+                        self.state.money.format(create.projected)
+                    );
+                    self.record_action(Action::CreateExpense(create), label);
+                }
+                self.load_tab_data().await;
+            }
+            (Err(ApiError::Queued), _) => self.show_queued_write(),
+            (Err(ApiError::NotFound), Some(id)) => self.drop_missing(EntityType::Expense, id),
+            (Err(e), _) => {
+                self.state
+                    .set_error(format!("Failed to save expense: {}", e));
+            }
+        }
+    }
+
+    /// Close an edit form that has nothing to save
+    fn close_unchanged_form(&mut self) {
+        self.state.ui.is_loading = false;
+        self.state.ui.modal = None;
+        self.expense_form = ExpenseFormState::default();
+        self.income_form = IncomeFormState::default();
+        self.state.set_success("No changes to save");
+    }
+
+    /// Save income (create or update)
+    async fn save_income(&mut self) {
+        self.income_form.invalid = None;
+        let errors = self.income_form.validate();
+        if !errors.is_empty() {
+            self.state.set_error(errors.join(", "));
+            return;
+        }
+
+        let month_id = match self.state.selected_month_id() {
+            Some(id) => id,
+            None => {
+                self.state.set_error("No month selected");
+                return;
+            }
+        };
+
+        self.state.ui.is_loading = true;
+
+        let mut created = None;
+        let result = if let Some(id) = self.income_form.editing_id {
+            // Update existing income with what changed
+            match self.income_form.to_update() {
+                Some(update) if update.is_empty() => {
+                    self.close_unchanged_form();
+                    return;
+                }
+                Some(update) => match update.check() {
+                    Ok(()) => self.api.incomes().update(id, &update).await,
+                    Err(e) => Err(e),
+                },
+                None => {
+                    self.state.ui.is_loading = false;
+                    self.state.set_error("Invalid income data");
+                    return;
+                }
+            }
+        } else {
+            // Create new income
+            match self.income_form.to_create(month_id) {
+                Some(create) => match create.check() {
+                    Ok(()) => {
+                        let result = self.api.incomes().create(&create).await;
+                        created = Some(create);
+                        result
+                    }
+                    Err(e) => Err(e),
+                },
+                None => {
+                    self.state.ui.is_loading = false;
+                    self.state.set_error("Invalid income data");
+                    return;
+                }
+            }
+        };
+
+        self.state.ui.is_loading = false;
+        if let Err(e @ ApiError::Validation(_)) = &result {
+            // Keep the form open on the field to fix
+            self.income_form.show_field_errors(e.field_errors());
+            self.state.set_error(e.to_string());
+            return;
+        }
+        self.state.ui.modal = None;
+
+        match (result, self.income_form.editing_id) {
+            (Ok(_), editing_id) => {
+                let action = if editing_id.is_some() {
+                    "updated"
+                } else {
+                    "created"
+                };
+                self.state
+                    .set_success(format!("Income {} successfully", action));
+                if let Some(create) = created {
+                    let income_type = self
+                        .state
+                        .data
+                        .income_types
+                        .iter()
+                        .find(|t| t.id == create.income_type_id)
+                        .map_or("income", |t| t.name.as_str());
+                    let label = format!(
+                        "Created income {} ({})",
+                        income_type,
+                        self.state.money.format(create.amount)
+                    );
+                    self.record_action(Action::CreateIncome(create), label);
+                }
+                self.load_tab_data().await;
+            }
+            (Err(ApiError::Queued), _) => self.show_queued_write(),
+            (Err(ApiError::NotFound), Some(id)) => self.drop_missing(EntityType::Income, id),
+            (Err(e), _) => {
+                self.state
+                    .set_error(format!("Failed to save income: {}", e));
+            }
+        }
+    }
+
+    /// Handle entity form keys (category, period, income type)
+    async fn handle_entity_form_key(&mut self, key: KeyEvent, entity_type: &str) {
+        match key.code {
+            KeyCode::Esc => {
+                self.state.ui.modal = None;
+            }
+            KeyCode::Tab | KeyCode::BackTab => {
+                // Toggle between name (0) and color (1) - for now we only focus on name
+            }
+            KeyCode::Enter => {
+                self.save_entity(entity_type).await;
+            }
+            KeyCode::Char('r') => {
+                // Randomize color, steering away from colors already in use
+                let used = self.used_colors(entity_type);
+                let random_color =
+                    palette::generate_color(&mut rand::rng(), self.config.colors.mode, &used);
+                match entity_type {
+                    "category" => self.category_form.color = random_color,
+                    "period" => self.period_form.color = random_color,
+                    "income_type" => self.income_type_form.color = random_color,
+                    _ => {}
+                }
+            }
+            KeyCode::Char(c) => match entity_type {
+                "category" => self.category_form.name.push(c),
+                "period" => self.period_form.name.push(c),
+                "income_type" => self.income_type_form.name.push(c),
+                _ => {}
+            },
+            KeyCode::Backspace => match entity_type {
+                "category" => {
+                    self.category_form.name.pop();
+                }
+                "period" => {
+                    self.period_form.name.pop();
+                }
+                "income_type" => {
+                    self.income_type_form.name.pop();
+                }
+                _ => {}
+            },
+            _ => {}
+        }
+    }
+
+    /// Colors of the other entities of a type (excluding the one being edited)
+    fn used_colors(&self, entity_type: &str) -> Vec<String> {
+        match entity_type {
+            "category" => self
+                .state
+                .data
+                .categories
+                .iter()
+                .filter(|c| Some(c.id) != self.category_form.editing_id)
+                .map(|c| c.color.clone())
+                .collect(),
+            "period" => self
+                .state
+                .data
+                .periods
+                .iter()
+                .filter(|p| Some(p.id) != self.period_form.editing_id)
+                .map(|p| p.color.clone())
+                .collect(),
+            "income_type" => self
+                .state
+                .data
+                .income_types
+                .iter()
+                .filter(|t| Some(t.id) != self.income_type_form.editing_id)
+                .map(|t| t.color.clone())
+                .collect(),
+            _ => Vec::new(),
+        }
+    }
+
+    /// Save entity (category, period, income type)
+    async fn save_entity(&mut self, entity_type: &str) {
+        // Offer the existing item instead of creating a near-duplicate
+        let (kind, name, editing_id) = match entity_type {
+            "category" => (
+                EntityType::Category,
+                &self.category_form.name,
+                self.category_form.editing_id,
+            ),
+            "period" => (
+                EntityType::Period,
+                &self.period_form.name,
+                self.period_form.editing_id,
+            ),
+            _ => (
+                EntityType::IncomeType,
+                &self.income_type_form.name,
+                self.income_type_form.editing_id,
+            ),
+        };
+        if let Some((existing_id, existing_name)) =
+            self.state.find_duplicate_name(kind, name, editing_id)
+        {
+            if let Some(form) = self.state.ui.modal.take() {
+                self.state.ui.modal = Some(Modal::ConfirmDuplicate {
+                    entity_type: kind,
+                    existing_id,
+                    existing_name,
+                    form: Box::new(form),
+                });
+            }
+            return;
+        }
+
+        self.state.ui.is_loading = true;
+
+        let result = match entity_type {
+            "category" => {
+                if let Some(id) = self.category_form.editing_id {
+                    let update = self.category_form.to_update();
+                    match update.check() {
+                        Ok(()) => self.api.categories().update(id, &update).await.map(|_| ()),
+                        Err(e) => Err(e),
+                    }
+                } else {
+                    let create = self.category_form.to_create();
+                    match create.check() {
+                        Ok(()) => self.api.categories().create(&create).await.map(|_| ()),
+                        Err(e) => Err(e),
+                    }
+                }
+            }
+            "period" => {
+                if let Some(id) = self.period_form.editing_id {
+                    let update = self.period_form.to_update();
+                    match update.check() {
+                        Ok(()) => self.api.periods().update(id, &update).await.map(|_| ()),
+                        Err(e) => Err(e),
+                    }
+                } else {
+                    let create = self.period_form.to_create();
+                    match create.check() {
+                        Ok(()) => self.api.periods().create(&create).await.map(|_| ()),
+                        Err(e) => Err(e),
+                    }
+                }
+            }
+            "income_type" => {
+                if let Some(id) = self.income_type_form.editing_id {
+                    let update = self.income_type_form.to_update();
+                    match update.check() {
+                        Ok(()) => self
+                            .api
+                            .income_types()
+                            .update(id, &update)
+                            .await
+                            .map(|_| ()),
+                        Err(e) => Err(e),
+                    }
+                } else {
+                    let create = self.income_type_form.to_create();
+                    match create.check() {
+                        Ok(()) => self.api.income_types().create(&create).await.map(|_| ()),
+                        Err(e) => Err(e),
+                    }
+                }
+            }
+            _ => Ok(()),
+        };
+
+        self.state.ui.is_loading = false;
+        let form = self.state.ui.modal.take();
+
+        match result {
+            Ok(_) => {
+                let entity_display = match entity_type {
+                    "category" => "Category",
+                    "period" => "Period",
+                    "income_type" => "Income type",
+                    _ => "Entity",
+                };
+                self.state
+                    .set_success(format!("{} saved successfully", entity_display));
+                // Reload settings data
+                self.load_settings_data().await;
+            }
+            Err(
+                e @ (ApiError::BadRequest(_) | ApiError::Conflict(_) | ApiError::Validation(_)),
+            ) => {
+                // Validation errors (e.g. name already exists): keep the form open to fix it
+                self.state.ui.modal = form;
+                self.state.set_error(e.to_string());
+            }
+            Err(e) => {
+                self.state.set_error(format!("Failed to save: {}", e));
+            }
+        }
+    }
+
+    /// Save the account form, creating the account or updating it
+    async fn save_account(&mut self) {
+        let Some(Modal::AccountForm { form }) = self.state.ui.modal.clone() else {
+            return;
+        };
+        let errors = form.validate();
+        if !errors.is_empty() {
+            self.state.set_error(errors.join(", "));
+            return;
+        }
+        if let Some((existing_id, existing_name)) =
+            self.state
+                .find_duplicate_name(EntityType::Account, &form.name, form.editing_id)
+        {
+            if let Some(form) = self.state.ui.modal.take() {
+                self.state.ui.modal = Some(Modal::ConfirmDuplicate {
+                    entity_type: EntityType::Account,
+                    existing_id,
+                    existing_name,
+                    form: Box::new(form),
+                });
+            }
+            return;
+        }
+
+        self.state.ui.is_loading = true;
+        let result = match form.editing_id {
+            Some(id) => {
+                let update = form.to_update();
+                match update.check() {
+                    Ok(()) => self.api.accounts().update(id, &update).await,
+                    Err(e) => Err(e),
+                }
+            }
+            None => {
+                let create = form.to_create();
+                match create.check() {
+                    Ok(()) => self.api.accounts().create(&create).await,
+                    Err(e) => Err(e),
+                }
+            }
+        };
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(_) => {
+                self.state.ui.modal = None;
+                self.state.set_success("Account saved successfully");
+                self.load_settings_data().await;
+            }
+            Err(
+                e @ (ApiError::BadRequest(_) | ApiError::Conflict(_) | ApiError::Validation(_)),
+            ) => {
+                // Keep the form open to fix it
+                self.state.set_error(e.to_string());
+            }
+            Err(e) if e.is_unsupported() => {
+                self.state.ui.modal = None;
+                self.state.data.unsupported.insert(ServerFeature::Accounts);
+                self.state
+                    .set_error("This server doesn't support accounts - update it first");
+            }
+            Err(e) => {
+                self.state.ui.modal = None;
+                self.state.set_error(format!("Failed to save: {}", e));
+            }
+        }
+    }
+
+    /// Show the selected month's transfers between accounts
+    async fn open_transfers(&mut self) {
+        self.state.load_transfers(&self.api).await;
+        if self
+            .state
+            .data
+            .unsupported
+            .contains(&ServerFeature::Transfers)
+        {
+            self.state
+                .set_error("This server doesn't support transfers - update it first");
+            return;
+        }
+        self.show_transfers(0);
+    }
+
+    /// Show the loaded transfers with the one at `selected` highlighted
+    fn show_transfers(&mut self, selected: usize) {
+        self.state.ui.modal = Some(Modal::Transfers {
+            selected: selected.min(self.state.data.transfers.len().saturating_sub(1)),
+            confirm_remove: false,
+        });
+    }
+
+    /// Start a transfer out of the account selected in Settings
+    fn new_transfer(&mut self) {
+        if self.state.data.accounts.len() < 2 {
+            self.state
+                .set_error("Transfers need two accounts; add them under Settings > Accounts");
+            return;
+        }
+        let from = self
+            .state
+            .ui
+            .account_table
+            .selected()
+            .and_then(|idx| self.state.data.accounts.get(idx))
+            .map(|account| account.id);
+        self.state.ui.modal = Some(Modal::TransferForm {
+            form: TransferFormState::new(&self.state.data.accounts, from),
+        });
+    }
+
+    /// Record the transfer in the form in the selected month
+    async fn save_transfer(&mut self) {
+        let Some(Modal::TransferForm { form }) = self.state.ui.modal.clone() else {
+            return;
+        };
+        let errors = form.validate();
+        if !errors.is_empty() {
+            self.state.set_error(errors.join(", "));
+            return;
+        }
+        let Some(transfer) = self
+            .state
+            .selected_month_id()
+            .and_then(|month_id| form.to_create(month_id))
+        else {
+            self.state
+                .set_error("Select a month to record the transfer in");
+            return;
+        };
+
+        self.state.ui.is_loading = true;
+        let result = match transfer.check() {
+            Ok(()) => self.api.transfers().create(&transfer).await,
+            Err(e) => Err(e),
+        };
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(_) => {
+                self.state.set_success("Transfer recorded");
+                self.state.load_transfers(&self.api).await;
+                self.show_transfers(self.state.data.transfers.len());
+            }
+            Err(
+                e @ (ApiError::BadRequest(_) | ApiError::Conflict(_) | ApiError::Validation(_)),
+            ) => {
+                // Keep the form open on the field to fix
+                if let Some(Modal::TransferForm { form }) = &mut self.state.ui.modal {
+                    form.show_field_errors(e.field_errors());
+                }
+                self.state.set_error(e.to_string());
+            }
+            Err(e) if e.is_unsupported() => {
+                self.state.ui.modal = None;
+                self.state.data.unsupported.insert(ServerFeature::Transfers);
+                self.state
+                    .set_error("This server doesn't support transfers - update it first");
+            }
+            Err(e) => {
+                self.state.ui.modal = None;
+                self.state.set_error(format!("Failed to save: {}", e));
+            }
+        }
+    }
+
+    /// Delete the transfer selected in the list
+    async fn delete_transfer(&mut self) {
+        let Some(Modal::Transfers { selected, .. }) = self.state.ui.modal else {
+            return;
+        };
+        let Some(transfer) = self.state.data.transfers.get(selected).cloned() else {
+            return;
+        };
+
+        self.state.ui.is_loading = true;
+        let result = self.api.transfers().delete(transfer.id).await;
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(()) => {
+                self.state.set_success("Transfer deleted");
+                self.state.load_transfers(&self.api).await;
+                self.show_transfers(selected);
+            }
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to delete transfer: {}", e)),
+        }
+    }
+
+    /// Save the debt in the form
+    async fn save_debt(&mut self) {
+        let Some(Modal::DebtForm { form }) = self.state.ui.modal.clone() else {
+            return;
+        };
+        let errors = form.validate();
+        if !errors.is_empty() {
+            self.state.set_error(errors.join(", "));
+            return;
+        }
+        if let Some((existing_id, existing_name)) =
+            self.state
+                .find_duplicate_name(EntityType::Debt, &form.name, form.editing_id)
+        {
+            if let Some(form) = self.state.ui.modal.take() {
+                self.state.ui.modal = Some(Modal::ConfirmDuplicate {
+                    entity_type: EntityType::Debt,
+                    existing_id,
+                    existing_name,
+                    form: Box::new(form),
+                });
+            }
+            return;
+        }
+
+        self.state.ui.is_loading = true;
+        let result = match (form.editing_id, form.to_create(), form.to_update()) {
+            (Some(id), _, Some(update)) => match update.check() {
+                Ok(()) => self.api.debts().update(id, &update).await,
+                Err(e) => Err(e),
+            },
+            (None, Some(create), _) => match create.check() {
+                Ok(()) => self.api.debts().create(&create).await,
+                Err(e) => Err(e),
+            },
+            _ => {
+                self.state.ui.is_loading = false;
+                self.state.set_error("Invalid debt data");
+                return;
+            }
+        };
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(_) => {
+                self.state.ui.modal = None;
+                self.state.set_success("Debt saved successfully");
+                self.state.load_debts(&self.api).await;
+            }
+            Err(
+                e @ (ApiError::BadRequest(_) | ApiError::Conflict(_) | ApiError::Validation(_)),
+            ) => {
+                // Keep the form open on the field to fix
+                if let Some(Modal::DebtForm { form }) = &mut self.state.ui.modal {
+                    form.show_field_errors(e.field_errors());
+                }
+                self.state.set_error(e.to_string());
+            }
+            Err(e) if e.is_unsupported() => {
+                self.state.ui.modal = None;
+                self.state.data.unsupported.insert(ServerFeature::Debts);
+                self.state
+                    .set_error("This server doesn't support debts - update it first");
+            }
+            Err(e) => {
+                self.state.ui.modal = None;
+                self.state.set_error(format!("Failed to save: {}", e));
+            }
+        }
+    }
+
+    /// Check the server keeps debts before offering to add one
+    fn debts_supported(&mut self) -> bool {
+        if self.state.data.unsupported.contains(&ServerFeature::Debts) {
+            self.state
+                .set_error("This server doesn't support debts - update it first");
+            return false;
+        }
+        true
+    }
+
+    /// Ask how much was paid on the selected debt, starting from its minimum
+    fn open_debt_payment(&mut self) {
+        if !self.check_scope(Some(Scope::Expenses)) {
+            return;
+        }
+        if self.is_month_closed() {
+            self.state
+                .set_error("Cannot add payments to a closed month. Reopen it or press U.");
+            return;
+        }
+        if let Some(debt) = self.state.selected_debt() {
+            self.state.ui.modal = Some(Modal::DebtPayment {
+                amount: money_input::input_text(debt.minimum_payment),
+                debt: debt.clone(),
+            });
+        }
+    }
+
+    /// Record the payment as an expense in the selected month, then lower
+    /// what is owed on the debt
+    async fn record_debt_payment(&mut self) {
+        let Some(Modal::DebtPayment { debt, amount }) = self.state.ui.modal.clone() else {
+            return;
+        };
+        let amount = match money_input::parse_money(&amount, debt.minimum_payment) {
+            Some(amount) if amount > 0.0 => amount,
+            _ => {
+                self.state.set_error("Payment must be an amount over zero");
+                return;
+            }
+        };
+        let Some(month_id) = self.state.selected_month_id() else {
+            self.state.set_error("No month selected");
+            return;
+        };
+
+        self.state.ui.is_loading = true;
+        let expense = debts::payment_expense(&debt, amount, month_id);
+        let result = self.api.expenses().create(&expense).await;
+        self.state.ui.is_loading = false;
+        self.state.ui.modal = None;
+
+        match result {
+            Ok(_) | Err(ApiError::Queued) => {}
+            Err(e) => {
+                self.state
+                    .set_error(format!("Failed to record payment: {}", e));
+                return;
+            }
+        }
+
+        // The expense is in, so a failure here only leaves the balance behind
+        let mut update = DebtUpdate::from(&debt);
+        update.principal = debts::after_payment(&debt, amount);
+        match self.api.debts().update(debt.id, &update).await {
+            Ok(_) => self.state.set_success(format!(
+                "Paid {} on {}; {} left",
+                self.state.money.format(amount),
+                debt.name,
+                self.state.money.format(update.principal)
+            )),
+            Err(e) => self.state.set_error(format!(
+                "Recorded the payment as an expense, but couldn't update what's owed on {}: {}",
+                debt.name, e
+            )),
+        }
+        self.state.load_debts(&self.api).await;
+    }
+
+    /// Check the server keeps accounts before offering to add one
+    fn accounts_supported(&mut self) -> bool {
+        if self
+            .state
+            .data
+            .unsupported
+            .contains(&ServerFeature::Accounts)
+        {
+            self.state
+                .set_error("This server doesn't support accounts - update it first");
+            return false;
+        }
+        true
+    }
+
+    /// Handle the duplicate name prompt: jump to the existing item or go back to the form
+    fn handle_duplicate_key(&mut self, key: KeyEvent) {
+        match key.code {
+            KeyCode::Char('y') | KeyCode::Enter => {
+                if let Some(Modal::ConfirmDuplicate {
+                    entity_type,
+                    existing_id,
+                    ..
+                }) = self.state.ui.modal.take()
+                {
+                    match entity_type {
+                        EntityType::Category => self.category_form = CategoryFormState::default(),
+                        EntityType::Period => self.period_form = PeriodFormState::default(),
+                        EntityType::IncomeType => {
+                            self.income_type_form = IncomeTypeFormState::default()
+                        }
+                        EntityType::Expense
+                        | EntityType::Income
+                        | EntityType::Account
+                        | EntityType::Debt => {}
+                    }
+                    self.state.jump_to_entity(entity_type, existing_id);
+                }
+            }
+            KeyCode::Char('n') | KeyCode::Esc => {
+                if let Some(Modal::ConfirmDuplicate { form, .. }) = self.state.ui.modal.take() {
+                    self.state.ui.modal = Some(*form);
+                }
+            }
+            _ => {}
+        }
+    }
+
+    /// Handle password form keys
+    async fn handle_password_form_key(&mut self, key: KeyEvent) {
+        match key.code {
+            KeyCode::Esc => {
+                self.state.ui.modal = None;
+                self.password_form = PasswordFormState::default();
+            }
+            KeyCode::Tab => {
+                self.password_form.focused_field = (self.password_form.focused_field + 1) % 3;
+            }
+            KeyCode::BackTab => {
+                self.password_form.focused_field = if self.password_form.focused_field == 0 {
+                    2
+                } else {
+                    self.password_form.focused_field - 1
+                };
+            }
+            KeyCode::Enter => {
+                self.save_password().await;
+            }
+            KeyCode::Char(c) => match self.password_form.focused_field {
+                0 => self.password_form.current_password.push(c),
+                1 => self.password_form.new_password.push(c),
+                2 => self.password_form.confirm_password.push(c),
+                _ => {}
+            },
+            KeyCode::Backspace => match self.password_form.focused_field {
+                0 => {
+                    self.password_form.current_password.pop();
+                }
+                1 => {
+                    self.password_form.new_password.pop();
+                }
+                2 => {
+                    self.password_form.confirm_password.pop();
+                }
+                _ => {}
+            },
+            _ => {}
+        }
+    }
+
+    /// Save password
+    async fn save_password(&mut self) {
+        // Validate
+        let errors = self.password_form.validate();
+        if !errors.is_empty() {
+            self.state.set_error(errors.join(", "));
+            return;
+        }
+
+        self.state.ui.is_loading = true;
+
+        let result = self
+            .api
+            .auth()
+            .change_password(
+                &self.password_form.current_password,
+                &self.password_form.new_password,
+            )
+            .await;
+
+        self.state.ui.is_loading = false;
+        self.state.ui.modal = None;
+        self.password_form = PasswordFormState::default();
+
+        match result {
+            Ok(_) => {
+                self.state.set_success("Password changed successfully");
+            }
+            Err(e) => {
+                self.state
+                    .set_error(format!("Failed to change password: {}", e));
+            }
+        }
+    }
+
+    /// Load settings data (categories, periods, income types)
+    async fn load_settings_data(&mut self) {
+        if let Ok(categories) = self.api.categories().get_all().await {
+            self.state.data.categories = categories;
+        }
+        if let Ok(periods) = self.api.periods().get_all().await {
+            self.state.set_periods(periods);
+        }
+        if let Ok(income_types) = self.api.income_types().get_all().await {
+            self.state.data.income_types = income_types;
+        }
+        self.state.load_accounts(&self.api).await;
+        self.state.load_transfers(&self.api).await;
+    }
+
+    /// Select next item in current list
+    /// Fetch the next page of the tab's list when the selection nears its end
+    async fn load_more_near_end(&mut self) {
+        let loaded = match self.state.ui.selected_tab {
+            DashboardTab::Expenses if self.state.data.more_expenses.is_some() => {
+                let len = self.state.filtered_expenses().len();
+                let selected = self.state.ui.expense_table.selected().unwrap_or(0);
+                selected + PREFETCH_ROWS >= len && self.state.load_more_expenses(&self.api).await
+            }
+            DashboardTab::Income if self.state.data.more_incomes.is_some() => {
+                let len = self.state.filtered_incomes().len();
+                let selected = self.state.ui.income_table.selected().unwrap_or(0);
+                selected + PREFETCH_ROWS >= len && self.state.load_more_incomes(&self.api).await
+            }
+            _ => false,
+        };
+        if loaded {
+            self.state.apply_queued_writes(&self.api.queued_writes());
+        }
+    }
+
+    fn select_next_item(&mut self) {
+        match self.state.ui.selected_tab {
+            DashboardTab::Expenses => {
+                let len = self.state.filtered_expenses().len();
+                if len > 0 {
+                    let i = self.state.ui.expense_table.selected().unwrap_or(0);
+                    let next = if i >= len - 1 { 0 } else { i + 1 };
+                    self.state.ui.expense_table.select(Some(next));
+                }
+            }
+            DashboardTab::Income => {
+                let len = self.state.filtered_incomes().len();
+                if len > 0 {
+                    let i = self.state.ui.income_table.selected().unwrap_or(0);
+                    let next = if i >= len - 1 { 0 } else { i + 1 };
+                    self.state.ui.income_table.select(Some(next));
+                }
+            }
+            DashboardTab::Debts => {
+                let len = self.state.data.debts.len();
+                if len > 0 {
+                    let i = self.state.ui.debt_table.selected().unwrap_or(0);
+                    let next = if i >= len - 1 { 0 } else { i + 1 };
+                    self.state.ui.debt_table.select(Some(next));
+                }
+            }
+            DashboardTab::Settings => {
+                match self.state.ui.settings_tab {
+                    SettingsTab::Categories => {
+                        let len = self.state.data.categories.len();
+                        if len > 0 {
+                            let i = self.state.ui.category_table.selected().unwrap_or(0);
+                            let next = if i >= len - 1 { 0 } else { i + 1 };
+                            self.state.ui.category_table.select(Some(next));
+                        }
+                    }
+                    SettingsTab::Periods => {
+                        let len = self.state.data.periods.len();
+                        if len > 0 {
+                            let i = self.state.ui.period_table.selected().unwrap_or(0);
+                            let next = if i >= len - 1 { 0 } else { i + 1 };
+                            self.state.ui.period_table.select(Some(next));
+                        }
+                    }
+                    SettingsTab::IncomeTypes => {
+                        let len = self.state.data.income_types.len();
+                        if len > 0 {
+                            let i = self.state.ui.income_type_table.selected().unwrap_or(0);
+                            let next = if i >= len - 1 { 0 } else { i + 1 };
+                            self.state.ui.income_type_table.select(Some(next));
+                        }
+                    }
+                    SettingsTab::Accounts => {
+                        let len = self.state.data.accounts.len();
+                        if len > 0 {
+                            let i = self.state.ui.account_table.selected().unwrap_or(0);
+                            let next = if i >= len - 1 { 0 } else { i + 1 };
+                            self.state.ui.account_table.select(Some(next));
+                        }
+                    }
+                    _ => {
+                        // Switch settings sub-tab
+                        self.state.ui.settings_tab = self.state.ui.settings_tab.next();
+                    }
+                }
+            }
+            _ => {}
+        }
+    }
+
+    /// Select previous item in current list
+    fn select_previous_item(&mut self) {
+        match self.state.ui.selected_tab {
+            DashboardTab::Expenses => {
+                let len = self.state.filtered_expenses().len();
+                if len > 0 {
+                    let i = self.state.ui.expense_table.selected().unwrap_or(0);
+                    let prev = if i == 0 { len - 1 } else { i - 1 };
+                    self.state.ui.expense_table.select(Some(prev));
+                }
+            }
+            DashboardTab::Income => {
+                let len = self.state.filtered_incomes().len();
+                if len > 0 {
+                    let i = self.state.ui.income_table.selected().unwrap_or(0);
+                    let prev = if i == 0 { len - 1 } else { i - 1 };
+                    self.state.ui.income_table.select(Some(prev));
+                }
+            }
+            DashboardTab::Debts => {
+                let len = self.state.data.debts.len();
+                if len > 0 {
+                    let i = self.state.ui.debt_table.selected().unwrap_or(0);
+                    let prev = if i == 0 { len - 1 } else { i - 1 };
+                    self.state.ui.debt_table.select(Some(prev));
+                }
+            }
+            DashboardTab::Settings => match self.state.ui.settings_tab {
+                SettingsTab::Categories => {
+                    let len = self.state.data.categories.len();
+                    if len > 0 {
+                        let i = self.state.ui.category_table.selected().unwrap_or(0);
+                        let prev = if i == 0 { len - 1 } else { i - 1 };
+                        self.state.ui.category_table.select(Some(prev));
+                    }
+                }
+                SettingsTab::Periods => {
+                    let len = self.state.data.periods.len();
+                    if len > 0 {
+                        let i = self.state.ui.period_table.selected().unwrap_or(0);
+                        let prev = if i == 0 { len - 1 } else { i - 1 };
+                        self.state.ui.period_table.select(Some(prev));
+                    }
+                }
+                SettingsTab::IncomeTypes => {
+                    let len = self.state.data.income_types.len();
+                    if len > 0 {
+                        let i = self.state.ui.income_type_table.selected().unwrap_or(0);
+                        let prev = if i == 0 { len - 1 } else { i - 1 };
+                        self.state.ui.income_type_table.select(Some(prev));
+                    }
+                }
+                SettingsTab::Accounts => {
+                    let len = self.state.data.accounts.len();
+                    if len > 0 {
+                        let i = self.state.ui.account_table.selected().unwrap_or(0);
+                        let prev = if i == 0 { len - 1 } else { i - 1 };
+                        self.state.ui.account_table.select(Some(prev));
+                    }
+                }
+                _ => {
+                    self.state.ui.settings_tab = self.state.ui.settings_tab.previous();
+                }
+            },
+            _ => {}
+        }
+    }
+
+    /// Check if the selected month is closed and not unlocked with `U`
+    fn is_month_closed(&self) -> bool {
+        self.state.selected_month_locked()
+    }
+
+    /// Edit the selected closed month anyway, or lock it again
+    fn toggle_closed_month_override(&mut self) {
+        let name = self
+            .state
+            .selected_month()
+            .map(|m| m.display_name())
+            .unwrap_or_default();
+        match self.state.toggle_closed_month_override() {
+            Some(true) => self.state.set_success(format!(
+                "{} is closed - editing it anyway. U locks it again.",
+                name
+            )),
+            Some(false) => self.state.set_success(format!("{} is locked again", name)),
+            None => self.state.set_error("This month isn't closed"),
+        }
+        self.api
+            .set_allow_closed_month(self.state.ui.closed_month_override.is_some());
+    }
+
+    /// Refuse an operation the API key has no scope for, saying which
+    /// scope it needs; `None` needs none
+    fn check_scope(&mut self, scope: Option<Scope>) -> bool {
+        match scope.and_then(|scope| self.state.scope_denied(scope)) {
+            Some(message) => {
+                self.state.set_error(message);
+                false
+            }
+            None => true,
+        }
+    }
+
+    /// Open modal for new item
+    fn open_new_item_modal(&mut self) {
+        if !self.check_scope(self.state.edit_scope()) {
+            return;
+        }
+
+        // Check if month is closed for expense/income tabs
+        if matches!(
+            self.state.ui.selected_tab,
+            DashboardTab::Expenses | DashboardTab::Income
+        ) && self.is_month_closed()
+        {
+            self.state
+                .set_error("Cannot add items to a closed month. Reopen it or press U.");
+            return;
+        }
+
+        match self.state.ui.selected_tab {
+            DashboardTab::Expenses => {
+                // Initialize empty expense form
+                self.expense_form = ExpenseFormState::default();
+                // Set default period and category if available
+                if let Some(period) = self.state.data.periods.first() {
+                    self.expense_form.period = period.name.clone();
+                }
+                if let Some(category) = self.state.data.categories.first() {
+                    self.expense_form.category = category.name.clone();
+                }
+                // Offer the fields the server keeps on other expenses
+                self.expense_form
+                    .offer_custom_fields(&forms::custom_fields_in(&self.state.data.expenses));
+                self.state.ui.modal = Some(Modal::ExpenseForm { editing: None });
+            }
+            DashboardTab::Income => {
+                // Initialize empty income form
+                self.income_form = IncomeFormState::default();
+                // Set default period and income type if available
+                if let Some(period) = self.state.data.periods.first() {
+                    self.income_form.period = period.name.clone();
+                }
+                if let Some(income_type) = self.state.data.income_types.first() {
+                    self.income_form.income_type_id = Some(income_type.id);
+                }
+                self.state.ui.modal = Some(Modal::IncomeForm { editing: None });
+            }
+            DashboardTab::Debts => {
+                if !self.debts_supported() {
+                    return;
+                }
+                self.state.ui.modal = Some(Modal::DebtForm {
+                    form: DebtFormState::new(&self.state.data.categories, &self.state.data.periods),
+                });
+            }
+            DashboardTab::Settings => match self.state.ui.settings_tab {
+                SettingsTab::Categories => {
+                    self.category_form = CategoryFormState::default();
+                    self.state.ui.modal = Some(Modal::CategoryForm { editing: None });
+                }
+                SettingsTab::Periods => {
+                    self.period_form = PeriodFormState::default();
+                    self.state.ui.modal = Some(Modal::PeriodForm { editing: None });
+                }
+                SettingsTab::IncomeTypes => {
+                    self.income_type_form = IncomeTypeFormState::default();
+                    self.state.ui.modal = Some(Modal::IncomeTypeForm { editing: None });
+                }
+                SettingsTab::Accounts => {
+                    if !self.accounts_supported() {
+                        return;
+                    }
+                    self.state.ui.modal = Some(Modal::AccountForm {
+                        form: AccountFormState::default(),
+                    });
+                }
+                SettingsTab::Password => {
+                    self.password_form = PasswordFormState::default();
+                    self.state.ui.modal = Some(Modal::PasswordForm);
+                }
+            },
+            _ => {}
+        }
+    }
+
+    /// Open modal for editing selected item
+    fn open_edit_item_modal(&mut self) {
+        if !self.check_scope(self.state.edit_scope()) {
+            return;
+        }
+
+        if self.is_unsynced_selection() {
+            self.state
+                .set_error("This item hasn't synced yet. Try again once the server is back.");
+            return;
+        }
+
+        // Check if month is closed for expense/income tabs
+        if matches!(
+            self.state.ui.selected_tab,
+            DashboardTab::Expenses | DashboardTab::Income
+        ) && self.is_month_closed()
+        {
+            self.state
+                .set_error("Cannot edit items in a closed month. Reopen it or press U.");
+            return;
+        }
+
+        match self.state.ui.selected_tab {
+            DashboardTab::Expenses => {
+                if let Some(idx) = self.state.ui.expense_table.selected() {
+                    let filtered = self.state.filtered_expenses();
+                    if let Some(expense) = filtered.get(idx) {
+                        // Initialize form from existing expense
+                        self.expense_form = ExpenseFormState::from_expense(expense);
+                        self.expense_form
+                            .offer_custom_fields(&forms::custom_fields_in(
+                                &self.state.data.expenses,
+                            ));
+                        self.state.ui.modal = Some(Modal::ExpenseForm {
+                            editing: Some((*expense).clone()),
+                        });
+                    }
+                }
+            }
+            DashboardTab::Income => {
+                if let Some(idx) = self.state.ui.income_table.selected() {
+                    let filtered = self.state.filtered_incomes();
+                    if let Some(income) = filtered.get(idx) {
+                        // Initialize form from existing income
+                        self.income_form = IncomeFormState::from_income(income);
+                        self.state.ui.modal = Some(Modal::IncomeForm {
+                            editing: Some((*income).clone()),
+                        });
+                    }
+                }
+            }
+            DashboardTab::Debts => {
+                if let Some(debt) = self.state.selected_debt() {
+                    self.state.ui.modal = Some(Modal::DebtForm {
+                        form: DebtFormState::from_debt(debt),
+                    });
+                }
+            }
+            DashboardTab::Settings => match self.state.ui.settings_tab {
+                SettingsTab::Categories => {
+                    if let Some(idx) = self.state.ui.category_table.selected() {
+                        if let Some(cat) = self.state.data.categories.get(idx) {
+                            self.category_form = CategoryFormState::from_category(cat);
+                            self.state.ui.modal = Some(Modal::CategoryForm {
+                                editing: Some(cat.clone()),
+                            });
+                        }
+                    }
+                }
+                SettingsTab::Periods => {
+                    if let Some(idx) = self.state.ui.period_table.selected() {
+                        if let Some(period) = self.state.data.periods.get(idx) {
+                            self.period_form = PeriodFormState::from_period(period);
+                            self.state.ui.modal = Some(Modal::PeriodForm {
+                                editing: Some(period.clone()),
+                            });
+                        }
+                    }
+                }
+                SettingsTab::IncomeTypes => {
+                    if let Some(idx) = self.state.ui.income_type_table.selected() {
+                        if let Some(it) = self.state.data.income_types.get(idx) {
+                            self.income_type_form = IncomeTypeFormState::from_income_type(it);
+                            self.state.ui.modal = Some(Modal::IncomeTypeForm {
+                                editing: Some(it.clone()),
+                            });
+                        }
+                    }
+                }
+                SettingsTab::Accounts => {
+                    if let Some(idx) = self.state.ui.account_table.selected() {
+                        if let Some(account) = self.state.data.accounts.get(idx) {
+                            self.state.ui.modal = Some(Modal::AccountForm {
+                                form: AccountFormState::from_account(account),
+                            });
+                        }
+                    }
+                }
+                SettingsTab::Password => {
+                    self.password_form = PasswordFormState::default();
+                    self.state.ui.modal = Some(Modal::PasswordForm);
+                }
+            },
+            _ => {}
+        }
+    }
+
+    /// Open delete confirmation dialog
+    fn open_delete_confirmation(&mut self) {
+        if !self.check_scope(self.state.edit_scope()) {
+            return;
+        }
+
+        if self.is_unsynced_selection() {
+            self.state
+                .set_error("This item hasn't synced yet. Try again once the server is back.");
+            return;
+        }
+
+        // Check if month is closed for expense/income tabs
+        if matches!(
+            self.state.ui.selected_tab,
+            DashboardTab::Expenses | DashboardTab::Income
+        ) && self.is_month_closed()
+        {
+            self.state
+                .set_error("Cannot delete items in a closed month. Reopen it or press U.");
+            return;
+        }
+
+        match self.state.ui.selected_tab {
+            DashboardTab::Expenses => {
+                if let Some(idx) = self.state.ui.expense_table.selected() {
+                    let filtered = self.state.filtered_expenses();
+                    if let Some(expense) = filtered.get(idx) {
+                        self.state.ui.modal = Some(Modal::ConfirmDelete {
+                            message: format!("Delete expense '{}'?", expense.expense_name),
+                            id: expense.id,
+                            entity_type: EntityType::Expense,
+                        });
+                    }
+                }
+            }
+            DashboardTab::Income => {
+                if let Some(idx) = self.state.ui.income_table.selected() {
+                    let filtered = self.state.filtered_incomes();
+                    if let Some(income) = filtered.get(idx) {
+                        self.state.ui.modal = Some(Modal::ConfirmDelete {
+                            message: "Delete this income entry?".to_string(),
+                            id: income.id,
+                            entity_type: EntityType::Income,
+                        });
+                    }
+                }
+            }
+            DashboardTab::Debts => {
+                if let Some(debt) = self.state.selected_debt() {
+                    self.state.ui.modal = Some(Modal::ConfirmDelete {
+                        message: format!(
+                            "Delete debt '{}'? Payments already recorded stay as expenses.",
+                            debt.name
+                        ),
+                        id: debt.id,
+                        entity_type: EntityType::Debt,
+                    });
+                }
+            }
+            DashboardTab::Settings => match self.state.ui.settings_tab {
+                SettingsTab::Categories => {
+                    if let Some(idx) = self.state.ui.category_table.selected() {
+                        if let Some(cat) = self.state.data.categories.get(idx) {
+                            self.state.ui.modal = Some(Modal::ConfirmDelete {
+                                message: format!("Delete category '{}'?", cat.name),
+                                id: cat.id,
+                                entity_type: EntityType::Category,
+                            });
+                        }
+                    }
+                }
+                SettingsTab::Periods => {
+                    if let Some(idx) = self.state.ui.period_table.selected() {
+                        if let Some(period) = self.state.data.periods.get(idx) {
+                            self.state.ui.modal = Some(Modal::ConfirmDelete {
+                                message: format!("Delete period '{}'?", period.name),
+                                id: period.id,
+                                entity_type: EntityType::Period,
+                            });
+                        }
+                    }
+                }
+                SettingsTab::IncomeTypes => {
+                    if let Some(idx) = self.state.ui.income_type_table.selected() {
+                        if let Some(it) = self.state.data.income_types.get(idx) {
+                            self.state.ui.modal = Some(Modal::ConfirmDelete {
+                                message: format!("Delete income type '{}'?", it.name),
+                                id: it.id,
+                                entity_type: EntityType::IncomeType,
+                            });
+                        }
+                    }
+                }
+                SettingsTab::Accounts => {
+                    if let Some(idx) = self.state.ui.account_table.selected() {
+                        if let Some(account) = self.state.data.accounts.get(idx) {
+                            self.state.ui.modal = Some(Modal::ConfirmDelete {
+                                message: format!(
+                                    "Delete account '{}'? Its entries are kept without one.",
+                                    account.name
+                                ),
+                                id: account.id,
+                                entity_type: EntityType::Account,
+                            });
+                        }
+                    }
+                }
+                _ => {}
+            },
+            _ => {}
+        }
+    }
+
+    /// Confirm and execute delete
+    async fn confirm_delete(&mut self) {
+        if let Some(Modal::ConfirmDelete {
+            id, entity_type, ..
+        }) = &self.state.ui.modal
+        {
+            let id = *id;
+            let entity_type = *entity_type;
+
+            self.state.ui.is_loading = true;
+
+            let result = match entity_type {
+                EntityType::Expense => self.api.expenses().delete(id).await,
+                EntityType::Income => self.api.incomes().delete(id).await,
+                EntityType::Category => self.api.categories().delete(id).await,
+                EntityType::Period => self.api.periods().delete(id).await,
+                EntityType::IncomeType => self.api.income_types().delete(id).await,
+                EntityType::Account => self.api.accounts().delete(id).await,
+                EntityType::Debt => self.api.debts().delete(id).await,
+            };
+
+            self.state.ui.is_loading = false;
+            self.state.ui.modal = None;
+
+            match result {
+                Ok(_) => {
+                    self.state.set_success("Item deleted successfully");
+                    self.load_tab_data().await;
+                }
+                Err(ApiError::Queued) => self.show_queued_write(),
+                Err(ApiError::NotFound) => {
+                    // Already gone; nothing left to delete
+                    self.state.remove_missing(entity_type, id);
+                    self.state.set_success("Item was already deleted");
+                }
+                Err(e) => {
+                    self.state.set_error(format!("Failed to delete: {}", e));
+                }
+            }
+        }
+    }
+
+    /// Drop an item the server says no longer exists and tell the user
+    fn drop_missing(&mut self, entity_type: EntityType, id: i32) {
+        self.state.remove_missing(entity_type, id);
+        self.state.set_error(format!(
+            "{} no longer exists - it was deleted elsewhere",
+            entity_type.as_str()
+        ));
+    }
+
+    /// Open pay confirmation dialog for an expense
+    fn open_pay_confirmation(&mut self) {
+        // Only available in Expenses tab
+        if self.state.ui.selected_tab != DashboardTab::Expenses {
+            return;
+        }
+        if !self.check_scope(Some(Scope::Expenses)) {
+            return;
+        }
+
+        // Check if month is closed
+        if self.is_month_closed() {
+            self.state
+                .set_error("Cannot pay expenses in a closed month. Reopen it or press U.");
+            return;
+        }
+
+        if self.is_unsynced_selection() {
+            self.state
+                .set_error("This item hasn't synced yet. Try again once the server is back.");
+            return;
+        }
+
+        if let Some(idx) = self.state.ui.expense_table.selected() {
+            let filtered = self.state.filtered_expenses();
+            if let Some(expense) = filtered.get(idx) {
+                // Don't show pay option if expense already has purchases
+                if expense.purchases.as_ref().is_some_and(|p| !p.is_empty()) {
+                    self.state.set_error("Expense already has purchases");
+                    return;
+                }
+
+                self.state.ui.modal = Some(Modal::ConfirmPay {
+                    expense_name: expense.expense_name.clone(),
+                    expense_id: expense.id,
+                    amount: expense.projected,
+                    amount_input: money_input::input_text(expense.projected),
+                });
+            }
+        }
+    }
+
+    /// Show the selected expense with its purchases
+    fn open_expense_detail(&mut self) {
+        if self.is_unsynced_selection() {
+            self.state
+                .set_error("This item hasn't synced yet. Try again once the server is back.");
+            return;
+        }
+        if let Some(expense) = self.selected_expense() {
+            self.show_expense_detail(expense);
+        }
+    }
+
+    /// Show who created and last changed the selected expense or income,
+    /// as the server has it now
+    ///
+    /// False when no row is selected, leaving `i` to import a CSV. A
+    /// selection kept from a longer list, as in an empty month, is no row.
+    async fn open_inspect(&mut self) -> bool {
+        let selected = self.state.selected_entry_row();
+        if selected.is_none() {
+            return false;
+        }
+        if !self.refresh_selected_item().await {
+            return true;
+        }
+        let modal = match self.state.ui.selected_tab {
+            DashboardTab::Expenses => self.selected_expense().map(|expense| Modal::Inspect {
+                entity_type: EntityType::Expense,
+                name: expense.expense_name.clone(),
+                audit: AuditTrail::from(&expense),
+            }),
+            _ => {
+                let incomes = self.state.filtered_incomes();
+                selected.and_then(|idx| incomes.get(idx)).map(|income| {
+                    let name = self
+                        .state
+                        .data
+                        .income_types
+                        .iter()
+                        .find(|t| t.id == income.income_type_id)
+                        .map_or_else(|| "Income".to_string(), |t| t.name.clone());
+                    Modal::Inspect {
+                        entity_type: EntityType::Income,
+                        name,
+                        audit: AuditTrail::from(*income),
+                    }
+                })
+            }
+        };
+        self.state.ui.modal = modal;
+        true
+    }
+
+    /// The expense selected in the table
+    fn selected_expense(&self) -> Option<Expense> {
+        let idx = self.state.ui.expense_table.selected()?;
+        self.state
+            .filtered_expenses()
+            .get(idx)
+            .map(|expense| (*expense).clone())
+    }
+
+    /// Show `expense` with its purchases and attachments
+    fn show_expense_detail(&mut self, expense: Expense) {
+        self.state.ui.modal = Some(Modal::ExpenseDetail {
+            expense,
+            selected: 0,
+            confirm_remove: false,
+            file: 0,
+        });
+    }
+
+    /// Open the selected expense's attachment, or its detail view to pick
+    /// one when it has several
+    async fn open_selected_attachment(&mut self) {
+        let Some(expense) = self.selected_expense() else {
+            return;
+        };
+        match expense.attachment_count() {
+            0 => self
+                .state
+                .set_error(format!("{} has no attachments", expense.expense_name)),
+            1 => self.open_attachment(&expense, 0).await,
+            _ => self.show_expense_detail(expense),
+        }
+    }
+
+    /// Download attachment `index` of `expense` to the temp directory and open
+    /// it with the system's viewer
+    ///
+    /// Only documents and images are opened; any other file is just saved, as
+    /// the viewer would run a program the server sent.
+    async fn open_attachment(&mut self, expense: &Expense, index: usize) {
+        let Some(attachment) = expense.attachments.as_ref().and_then(|a| a.get(index)) else {
+            self.state
+                .set_error(format!("{} has no attachments", expense.expense_name));
+            return;
+        };
+        let dir = std::env::temp_dir().join("budget-attachments");
+        if let Err(e) = std::fs::create_dir_all(&dir) {
+            self.state
+                .set_error(format!("Failed to create {}: {}", dir.display(), e));
+            return;
+        }
+        let name = attachment.local_file_name();
+        let path = dir.join(&name);
+
+        self.state.ui.is_loading = true;
+        let result = self
+            .api
+            .expenses()
+            .download_attachment(expense.id, attachment.id, &path, |_, _| {})
+            .await;
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(_) if !Attachment::is_viewable(&name) => self.state.set_success(format!(
+                "Saved {} - not opened, as it may be a program",
+                path.display()
+            )),
+            Ok(_) => match feedback::open_file(&path) {
+                Ok(()) => self
+                    .state
+                    .set_success(format!("Opened {}", attachment.file_name)),
+                Err(e) => self.state.set_error(format!(
+                    "Saved {} but couldn't open it: {}",
+                    path.display(),
+                    e
+                )),
+            },
+            Err(e) if e.is_unsupported() => {
+                self.state
+                    .set_error("This server doesn't keep attachments - update it first");
+            }
+            Err(e) => self.state.set_error(format!(
+                "Failed to download {}: {}",
+                attachment.file_name, e
+            )),
+        }
+    }
+
+    /// Upload the file at `path` to `expense` and go back to its detail view
+    async fn attach_file(&mut self, mut expense: Expense, path: &str) {
+        let path = std::path::Path::new(path);
+        let contents = match std::fs::read(path) {
+            Ok(contents) => contents,
+            Err(e) => {
+                self.state
+                    .set_error(format!("Failed to read {}: {}", path.display(), e));
+                return;
+            }
+        };
+        if contents.len() as u64 > MAX_ATTACHMENT_BYTES {
+            self.state.set_error(format!(
+                "{} is over the {} limit for attachments",
+                path.display(),
+                ui::format_size(MAX_ATTACHMENT_BYTES)
+            ));
+            return;
+        }
+        let file_name = path
+            .file_name()
+            .map(|name| name.to_string_lossy().into_owned())
+            .unwrap_or_else(|| "attachment".to_string());
+
+        self.state.ui.is_loading = true;
+        let result = self
+            .api
+            .expenses()
+            .upload_attachment(expense.id, &file_name, contents)
+            .await;
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(attachment) => {
+                self.state.set_success(format!(
+                    "Attached {} to {}",
+                    attachment.file_name, expense.expense_name
+                ));
+                expense
+                    .attachments
+                    .get_or_insert_with(Vec::new)
+                    .push(attachment);
+                self.show_expense_detail(expense);
+                self.load_tab_data().await;
+            }
+            Err(e) if e.is_unsupported() => {
+                self.state
+                    .set_error("This server doesn't keep attachments - update it first");
+            }
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to attach {}: {}", file_name, e)),
+        }
+    }
+
+    /// Whether the purchases of the expense shown can be changed, saying why
+    /// not when they can't
+    fn can_change_purchases(&mut self) -> bool {
+        if !self.check_scope(Some(Scope::Expenses)) {
+            return false;
+        }
+        if self.is_month_closed() {
+            self.state
+                .set_error("Cannot change purchases in a closed month. Reopen it or press U.");
+            return false;
+        }
+        true
+    }
+
+    /// Open the expense form on the detail view's purchases, on purchase
+    /// `index` or on a new one
+    fn edit_purchases(&mut self, index: Option<usize>) {
+        let Some(Modal::ExpenseDetail { expense, .. }) = &self.state.ui.modal else {
+            return;
+        };
+        let expense = expense.clone();
+        if !self.can_change_purchases() {
+            return;
+        }
+        self.expense_form = ExpenseFormState::from_expense(&expense);
+        self.expense_form.focused_field = ExpenseField::Purchases;
+        match index {
+            Some(index) => {
+                self.expense_form.selected_purchase = index;
+                self.expense_form.purchase_edit_field = PurchaseEditField::Amount;
+            }
+            None => self.expense_form.add_purchase(),
+        }
+        self.state.ui.modal = Some(Modal::ExpenseForm {
+            editing: Some(expense),
+        });
+    }
+
+    /// Remove the purchase selected in the detail view, along with its amount
+    /// from the cost when the cost is the purchases' total
+    async fn remove_purchase(&mut self) {
+        let Some(Modal::ExpenseDetail {
+            expense, selected, ..
+        }) = &self.state.ui.modal
+        else {
+            return;
+        };
+        let (id, index) = (expense.id, *selected);
+        let mut purchases = expense.purchases.clone().unwrap_or_default();
+        if index >= purchases.len() {
+            return;
+        }
+        let sums = forms::sums_purchases(expense.cost, expense.purchases.as_deref());
+        if !self.can_change_purchases() {
+            return;
+        }
+
+        let removed = purchases.remove(index);
+        let update = ExpenseUpdate {
+            cost: sums.then(|| purchases.iter().map(|p| p.amount).sum::<Money>().to_f64()),
+            purchases: Some(purchases),
+            ..Default::default()
+        };
+        self.state.ui.is_loading = true;
+        let result = self.api.expenses().update(id, &update).await;
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(updated) => {
+                self.state.set_success(format!(
+                    "Removed purchase {} ({})",
+                    removed.name,
+                    self.state.money.format(removed.amount)
+                ));
+                if let Some(Modal::ExpenseDetail {
+                    expense, selected, ..
+                }) = &mut self.state.ui.modal
+                {
+                    let count = updated.purchases.as_ref().map_or(0, Vec::len);
+                    *selected = (*selected).min(count.saturating_sub(1));
+                    *expense = updated;
+                }
+                self.load_tab_data().await;
+            }
+            Err(ApiError::Queued) => {
+                self.state.ui.modal = None;
+                self.show_queued_write();
+            }
+            Err(ApiError::NotFound) => {
+                self.state.ui.modal = None;
+                self.drop_missing(EntityType::Expense, id);
+            }
+            Err(e) => {
+                self.state
+                    .set_error(format!("Failed to remove purchase: {}", e));
+            }
+        }
+    }
+
+    /// Confirm and execute pay
+    async fn confirm_pay(&mut self) {
+        if let Some(Modal::ConfirmPay {
+            expense_id,
+            amount,
+            amount_input,
+            ..
+        }) = &self.state.ui.modal
+        {
+            let id = *expense_id;
+            // `+`/`-` adjust the projected amount the input starts with
+            let amount = money_input::parse_money(amount_input, *amount).unwrap_or(0.0);
+
+            self.state.ui.is_loading = true;
+
+            let request = crate::models::PayExpenseRequest {
+                amount: Some(amount),
+            };
+            let result = self.api.expenses().pay(id, Some(&request)).await;
+
+            self.state.ui.is_loading = false;
+            self.state.ui.modal = None;
+
+            match result {
+                Ok(_) => {
+                    self.state.set_success(format!(
+                        "Payment of {} added successfully",
+                        self.state.money.format(amount)
+                    ));
+                    self.load_tab_data().await;
+                }
+                Err(ApiError::Queued) => self.show_queued_write(),
+                Err(ApiError::NotFound) => self.drop_missing(EntityType::Expense, id),
+                Err(e) => {
+                    self.state.set_error(format!("Failed to pay: {}", e));
                 }
-                _ => {}
-            },
-            _ => {}
+            }
         }
     }
 
-    /// Save expense (create or update)
-    async fn save_expense(&mut self) {
-        // Validate using form's validate method
-        let errors = self.expense_form.validate();
-        if !errors.is_empty() {
-            self.state.set_error(errors.join(", "));
+    /// Open close/open month confirmation dialog
+    fn open_close_month_confirmation(&mut self) {
+        if !self.check_scope(Some(Scope::Months)) {
             return;
         }
+        if let Some(month) = self.state.selected_month() {
+            self.state.ui.modal = Some(Modal::ConfirmCloseMonth {
+                month_name: month.display_name(),
+                month_id: month.id,
+                is_closing: !month.is_closed,
+            });
+        }
+    }
 
-        let month_id = match self.state.selected_month_id() {
-            Some(id) => id,
-            None => {
-                self.state.set_error("No month selected");
-                return;
-            }
-        };
+    /// Confirm and execute close/open month
+    async fn confirm_close_month(&mut self) {
+        if let Some(Modal::ConfirmCloseMonth {
+            month_id,
+            is_closing,
+            ..
+        }) = &self.state.ui.modal
+        {
+            let id = *month_id;
+            let closing = *is_closing;
 
-        self.state.ui.is_loading = true;
+            self.state.ui.is_loading = true;
 
-        let result = if let Some(id) = self.expense_form.editing_id {
-            // Update existing expense using form's to_update method
-            match self.expense_form.to_update() {
-                Some(update) => self.api.expenses().update(id, &update).await,
-                None => {
-                    self.state.ui.is_loading = false;
-                    self.state.set_error("Invalid expense data");
-                    return;
+            let result = if closing {
+                self.api.months().close(id).await
+            } else {
+                self.api.months().open(id).await
+            };
+
+            self.state.ui.is_loading = false;
+            self.state.ui.modal = None;
+
+            match result {
+                Ok(_) => {
+                    // Closing or reopening ends editing the month anyway
+                    if self.state.ui.closed_month_override == Some(id) {
+                        self.state.ui.closed_month_override = None;
+                        self.api.set_allow_closed_month(false);
+                    }
+                    let action = if closing { "closed" } else { "reopened" };
+                    self.state
+                        .set_success(format!("Month {} successfully", action));
+                    // Reload months to update the is_closed status
+                    if let Ok(months) = self.api.months().get_all().await {
+                        self.state.data.months = months;
+                    }
+                    if closing
+                        && self.config.google_sheets.push_on_close
+                        && self.config.google_sheets.is_configured()
+                    {
+                        self.push_to_google_sheets().await;
+                    }
                 }
-            }
-        } else {
-            // Create new expense using form's to_create method
-            match self.expense_form.to_create(month_id) {
-                Some(create) => self.api.expenses().create(&create).await,
-                None => {
-                    self.state.ui.is_loading = false;
-                    self.state.set_error("Invalid expense data");
-                    return;
+                Err(e) => {
+                    let action = if closing { "close" } else { "reopen" };
+                    self.state
+                        .set_error(format!("Failed to {} month: {}", action, e));
                 }
             }
-        };
-
-        let was_editing = self.expense_form.editing_id.is_some();
+        }
+    }
 
+    /// Create the calendar month from the rollover prompt, copying `source`
+    async fn start_month(&mut self, calendar_month: (i32, i32), source: Option<i32>) {
+        self.begin_task(TaskKind::Bulk);
+        let name = match &self.state.ui.modal {
+            Some(Modal::Rollover { name, .. }) => name.clone(),
+            _ => return,
+        };
+        self.state.ui.is_loading = true;
+        let result = self
+            .state
+            .start_month(&self.api, calendar_month, source)
+            .await;
         self.state.ui.is_loading = false;
         self.state.ui.modal = None;
-        self.expense_form = ExpenseFormState::default();
 
         match result {
-            Ok(_) => {
-                let action = if was_editing { "updated" } else { "created" };
-                self.state
-                    .set_success(format!("Expense {} successfully", action));
-                self.load_tab_data().await;
+            Ok(start) if source.is_some() => {
+                self.state.set_success(format!(
+                    "Started {} with {} expense(s) and {} income(s)",
+                    name, start.expenses, start.incomes
+                ));
             }
+            Ok(_) => self.state.set_success(format!("Started {}", name)),
             Err(e) => {
                 self.state
-                    .set_error(format!("Failed to save expense: {}", e));
+                    .set_error(format!("Failed to create {}: {}", name, e));
             }
         }
+        self.load_month_data().await;
     }
 
-    /// Save income (create or update)
-    async fn save_income(&mut self) {
-        // Validate
-        if self.income_form.income_type_id.is_none() {
-            self.state.set_error("Income type is required");
+    /// With `pin_current` set, follow the calendar month: select it once it
+    /// begins, or ask to create it when it doesn't exist
+    ///
+    /// Waits for open dialogs to close.
+    async fn follow_calendar_month(&mut self) {
+        if !self.config.months.pin_current || self.state.ui.modal.is_some() {
             return;
         }
-        if self.income_form.period.is_empty() {
-            self.state.set_error("Period is required");
+        let today = self.state.clock.today();
+        let current = calendar_month(today);
+        if self.calendar_month == Some(current) {
             return;
         }
+        self.calendar_month = Some(current);
 
-        let projected: f64 = self.income_form.projected.parse().unwrap_or(0.0);
-        let amount: f64 = self.income_form.amount.parse().unwrap_or(0.0);
-        let month_id = match self.state.selected_month_id() {
-            Some(id) => id,
-            None => {
-                self.state.set_error("No month selected");
-                return;
+        match self.state.month_index(current) {
+            Some(index) if index == self.state.ui.selected_month_index => {}
+            Some(_) => {
+                self.state.select_calendar_month(current);
+                self.load_month_data().await;
             }
-        };
-
-        self.state.ui.is_loading = true;
+            // A key that can't create months can't start it either
+            None if self.state.key_allows(Scope::Months) => {
+                let template = self.config.months.template.as_deref();
+                self.state.ui.modal = self.state.rollover_prompt(today, template);
+            }
+            None => {}
+        }
+    }
 
-        let result = if let Some(id) = self.income_form.editing_id {
-            // Update existing income
-            let update = crate::models::IncomeUpdate {
-                income_type_id: self.income_form.income_type_id,
-                period: Some(self.income_form.period.clone()),
-                projected: Some(projected),
-                amount: Some(amount),
-                ..Default::default()
+    /// Start merging the selected category, period or income type into another one
+    fn open_merge_select(&mut self) {
+        if let Some((entity_type, source_id, source_name)) = self.state.selected_settings_entity() {
+            // There's no asking the server for an account's entries across months
+            if entity_type == EntityType::Account {
+                self.state
+                    .set_error("Accounts can't be merged; move their entries, then delete it");
+                return;
+            }
+            // Merging moves the rows over, so it changes them too
+            let rows = match entity_type {
+                EntityType::Category => vec![Scope::Expenses],
+                EntityType::Period => vec![Scope::Expenses, Scope::Incomes],
+                _ => vec![Scope::Incomes],
             };
-            self.api.incomes().update(id, &update).await
-        } else {
-            // Create new income
-            let create = crate::models::IncomeCreate {
-                income_type_id: self.income_form.income_type_id.unwrap(),
-                period: self.income_form.period.clone(),
-                projected,
-                amount,
-                month_id,
+            if !std::iter::once(Scope::Settings)
+                .chain(rows)
+                .all(|scope| self.check_scope(Some(scope)))
+            {
+                return;
+            }
+            let targets: Vec<(i32, String)> = self
+                .state
+                .entity_names(entity_type)
+                .into_iter()
+                .filter(|(id, _)| *id != source_id)
+                .collect();
+            if targets.is_empty() {
+                self.state.set_error(format!(
+                    "No other {} to merge into",
+                    entity_type.as_str().to_lowercase()
+                ));
+                return;
+            }
+            self.state.ui.modal = Some(Modal::MergeSelect {
+                entity_type,
+                source_id,
+                source_name,
+                targets,
+                selected: 0,
+            });
+        }
+    }
+
+    /// Load the rows affected by the chosen merge and ask for confirmation
+    async fn prepare_merge(&mut self) {
+        let (entity_type, source_id, source_name, target_id, target_name) =
+            match &self.state.ui.modal {
+                Some(Modal::MergeSelect {
+                    entity_type,
+                    source_id,
+                    source_name,
+                    targets,
+                    selected,
+                }) => match targets.get(*selected) {
+                    Some((target_id, target_name)) => (
+                        *entity_type,
+                        *source_id,
+                        source_name.clone(),
+                        *target_id,
+                        target_name.clone(),
+                    ),
+                    None => return,
+                },
+                _ => return,
             };
-            self.api.incomes().create(&create).await
-        };
 
+        self.state.ui.is_loading = true;
+        let result = self
+            .fetch_merge_rows(entity_type, source_id, &source_name)
+            .await;
         self.state.ui.is_loading = false;
-        self.state.ui.modal = None;
 
         match result {
-            Ok(_) => {
-                let action = if self.income_form.editing_id.is_some() {
-                    "updated"
-                } else {
-                    "created"
-                };
-                self.state
-                    .set_success(format!("Income {} successfully", action));
-                self.load_tab_data().await;
+            Ok((expenses, incomes)) => {
+                let preview = MergePreview::build(
+                    &expenses,
+                    &incomes,
+                    &self.state.data.months,
+                    &self.state.data.income_types,
+                );
+                self.state.ui.modal = Some(Modal::ConfirmMerge {
+                    entity_type,
+                    source_id,
+                    source_name,
+                    target_id,
+                    target_name,
+                    preview,
+                });
             }
             Err(e) => {
+                self.state.ui.modal = None;
                 self.state
-                    .set_error(format!("Failed to save income: {}", e));
+                    .set_error(format!("Failed to load affected rows: {}", e));
             }
         }
     }
 
-    /// Handle entity form keys (category, period, income type)
-    async fn handle_entity_form_key(&mut self, key: KeyEvent, entity_type: &str) {
-        match key.code {
-            KeyCode::Esc => {
-                self.state.ui.modal = None;
+    /// Fetch the expenses and incomes (across all months) that reference a merge source
+    async fn fetch_merge_rows(
+        &self,
+        entity_type: EntityType,
+        source_id: i32,
+        source_name: &str,
+    ) -> Result<(Vec<Expense>, Vec<Income>), ApiError> {
+        match entity_type {
+            EntityType::Category => {
+                let filters = ExpenseFilters {
+                    category: Some(source_name.to_string()),
+                    ..Default::default()
+                };
+                Ok((self.api.expenses().get_all(&filters).await?, Vec::new()))
             }
-            KeyCode::Tab | KeyCode::BackTab => {
-                // Toggle between name (0) and color (1) - for now we only focus on name
+            EntityType::Period => {
+                let expense_filters = ExpenseFilters {
+                    period: Some(source_name.to_string()),
+                    ..Default::default()
+                };
+                let income_filters = IncomeFilters {
+                    period: Some(source_name.to_string()),
+                    ..Default::default()
+                };
+                Ok((
+                    self.api.expenses().get_all(&expense_filters).await?,
+                    self.api.incomes().get_all(&income_filters).await?,
+                ))
             }
-            KeyCode::Enter => {
-                self.save_entity(entity_type).await;
+            EntityType::IncomeType => {
+                let filters = IncomeFilters {
+                    income_type_id: Some(source_id),
+                    ..Default::default()
+                };
+                Ok((Vec::new(), self.api.incomes().get_all(&filters).await?))
             }
-            KeyCode::Char('r') => {
-                // Randomize color
-                let random_color = generate_random_color();
-                match entity_type {
-                    "category" => self.category_form.color = random_color,
-                    "period" => self.period_form.color = random_color,
-                    "income_type" => self.income_type_form.color = random_color,
-                    _ => {}
-                }
+            EntityType::Expense | EntityType::Income | EntityType::Account | EntityType::Debt => {
+                Ok((Vec::new(), Vec::new()))
             }
-            KeyCode::Char(c) => match entity_type {
-                "category" => self.category_form.name.push(c),
-                "period" => self.period_form.name.push(c),
-                "income_type" => self.income_type_form.name.push(c),
-                _ => {}
-            },
-            KeyCode::Backspace => match entity_type {
-                "category" => {
-                    self.category_form.name.pop();
-                }
-                "period" => {
-                    self.period_form.name.pop();
-                }
-                "income_type" => {
-                    self.income_type_form.name.pop();
-                }
-                _ => {}
-            },
-            _ => {}
         }
     }
 
-    /// Save entity (category, period, income type)
-    async fn save_entity(&mut self, entity_type: &str) {
-        self.state.ui.is_loading = true;
+    /// Move every affected row to the merge target, then delete the source
+    async fn execute_merge(&mut self) {
+        self.begin_task(TaskKind::Bulk);
+        if let Some(Modal::ConfirmMerge {
+            entity_type,
+            source_id,
+            source_name,
+            target_id,
+            target_name,
+            preview,
+        }) = self.state.ui.modal.take()
+        {
+            if preview.is_blocked() {
+                self.state.set_error(format!(
+                    "{} row(s) are in closed months. Reopen them before merging.",
+                    preview.closed_count()
+                ));
+                return;
+            }
 
-        let result = match entity_type {
-            "category" => {
-                if self.category_form.name.trim().is_empty() {
-                    self.state.ui.is_loading = false;
-                    self.state.set_error("Name is required");
-                    return;
-                }
-                if let Some(id) = self.category_form.editing_id {
-                    self.api
-                        .categories()
-                        .update(id, &self.category_form.to_update())
-                        .await
-                        .map(|_| ())
-                } else {
-                    self.api
-                        .categories()
-                        .create(&self.category_form.to_create())
-                        .await
-                        .map(|_| ())
+            self.state.ui.is_loading = true;
+
+            let mut moved = 0;
+            let mut failure = None;
+            for row in &preview.rows {
+                let result = match (row.kind, entity_type) {
+                    (EntityType::Expense, EntityType::Category) => {
+                        let update = ExpenseUpdate {
+                            category: Some(target_name.clone()),
+                            ..Default::default()
+                        };
+                        self.api
+                            .expenses()
+                            .update(row.id, &update)
+                            .await
+                            .map(|_| ())
+                    }
+                    (EntityType::Expense, _) => {
+                        let update = ExpenseUpdate {
+                            period: Some(target_name.clone()),
+                            ..Default::default()
+                        };
+                        self.api
+                            .expenses()
+                            .update(row.id, &update)
+                            .await
+                            .map(|_| ())
+                    }
+                    (EntityType::Income, EntityType::IncomeType) => {
+                        let update = IncomeUpdate {
+                            income_type_id: Some(target_id),
+                            ..Default::default()
+                        };
+                        self.api.incomes().update(row.id, &update).await.map(|_| ())
+                    }
+                    (EntityType::Income, _) => {
+                        let update = IncomeUpdate {
+                            period: Some(target_name.clone()),
+                            ..Default::default()
+                        };
+                        self.api.incomes().update(row.id, &update).await.map(|_| ())
+                    }
+                    _ => Ok(()),
+                };
+                if let Err(e) = result {
+                    failure = Some(format!("'{}' ({}): {}", row.label, row.month_name, e));
+                    break;
                 }
+                moved += 1;
             }
-            "period" => {
-                if self.period_form.name.trim().is_empty() {
-                    self.state.ui.is_loading = false;
-                    self.state.set_error("Name is required");
-                    return;
-                }
-                if let Some(id) = self.period_form.editing_id {
-                    self.api
-                        .periods()
-                        .update(id, &self.period_form.to_update())
-                        .await
-                        .map(|_| ())
-                } else {
-                    self.api
-                        .periods()
-                        .create(&self.period_form.to_create())
-                        .await
-                        .map(|_| ())
+
+            // Only remove the source once nothing references it anymore
+            let result = match failure {
+                Some(failure) => Err(failure),
+                None => match entity_type {
+                    EntityType::Category => self.api.categories().delete(source_id).await,
+                    EntityType::Period => self.api.periods().delete(source_id).await,
+                    EntityType::IncomeType => self.api.income_types().delete(source_id).await,
+                    EntityType::Expense
+                    | EntityType::Income
+                    | EntityType::Account
+                    | EntityType::Debt => Ok(()),
                 }
-            }
-            "income_type" => {
-                if self.income_type_form.name.trim().is_empty() {
-                    self.state.ui.is_loading = false;
-                    self.state.set_error("Name is required");
-                    return;
+                .map_err(|e| format!("deleting '{}': {}", source_name, e)),
+            };
+
+            self.state.ui.is_loading = false;
+
+            match result {
+                Ok(()) => {
+                    self.state.set_success(format!(
+                        "Merged '{}' into '{}' ({} row(s) moved)",
+                        source_name, target_name, moved
+                    ));
                 }
-                if let Some(id) = self.income_type_form.editing_id {
-                    self.api
-                        .income_types()
-                        .update(id, &self.income_type_form.to_update())
-                        .await
-                        .map(|_| ())
-                } else {
-                    self.api
-                        .income_types()
-                        .create(&self.income_type_form.to_create())
-                        .await
-                        .map(|_| ())
+                Err(e) => {
+                    self.state.set_error(format!(
+                        "Merge stopped after {} of {} row(s), failed at {}",
+                        moved,
+                        preview.rows.len(),
+                        e
+                    ));
                 }
             }
-            _ => Ok(()),
+
+            self.load_settings_data().await;
+            self.load_month_data().await;
+        }
+    }
+
+    /// Open the scratchpad notes for the selected month
+    fn open_notes(&mut self) {
+        if let Some(month) = self.state.selected_month() {
+            self.state.ui.modal = Some(Modal::Notes {
+                month_id: month.id,
+                month_name: month.display_name(),
+                text: self
+                    .state
+                    .notes
+                    .get(month.id)
+                    .unwrap_or_default()
+                    .to_string(),
+                shared: false,
+            });
+        }
+    }
+
+    /// Open the selected month's notes kept on the server
+    fn open_shared_notes(&mut self) {
+        if !self.check_scope(Some(Scope::Months)) {
+            return;
+        }
+        if let Some(month) = self.state.selected_month() {
+            self.state.ui.modal = Some(Modal::Notes {
+                month_id: month.id,
+                month_name: month.display_name(),
+                text: month.notes.clone().unwrap_or_default(),
+                shared: true,
+            });
+        }
+    }
+
+    /// Show the export lines for the current server settings
+    fn open_env_export(&mut self) {
+        self.state.ui.modal = Some(Modal::EnvExport {
+            url: self.config.server.url.clone(),
+            api_key: self.config.server.api_key.clone(),
+            reveal_key: false,
+        });
+    }
+
+    /// Create a read-only link to the selected month's summary and show it
+    async fn share_month(&mut self) {
+        let month = match self.state.selected_month() {
+            Some(month) => month.clone(),
+            None => return,
         };
 
+        self.state.ui.is_loading = true;
+        let result = self
+            .api
+            .months()
+            .share(month.id, &MonthShareRequest::default())
+            .await;
         self.state.ui.is_loading = false;
-        self.state.ui.modal = None;
 
         match result {
-            Ok(_) => {
-                let entity_display = match entity_type {
-                    "category" => "Category",
-                    "period" => "Period",
-                    "income_type" => "Income type",
-                    _ => "Entity",
-                };
+            Ok(link) => {
+                let expires_at = DateTime::parse_from_rfc3339(&link.expires_at)
+                    .map(|t| {
+                        self.state
+                            .dates
+                            .format_time(t.with_timezone(&Local).naive_local())
+                    })
+                    .unwrap_or(link.expires_at.clone());
+                self.state.ui.modal = Some(Modal::ShareLink {
+                    month_name: month.display_name(),
+                    url: link.url_for(&self.config.server.url),
+                    expires_at,
+                });
+            }
+            Err(e) if e.is_unsupported() => {
                 self.state
-                    .set_success(format!("{} saved successfully", entity_display));
-                // Reload settings data
-                self.load_settings_data().await;
+                    .set_error("This server doesn't support share links - update it first");
             }
             Err(e) => {
-                self.state.set_error(format!("Failed to save: {}", e));
+                self.state
+                    .set_error(format!("Failed to create share link: {}", e));
             }
         }
     }
 
-    /// Handle password form keys
-    async fn handle_password_form_key(&mut self, key: KeyEvent) {
-        match key.code {
-            KeyCode::Esc => {
-                self.state.ui.modal = None;
-                self.password_form = PasswordFormState::default();
-            }
-            KeyCode::Tab => {
-                self.password_form.focused_field = (self.password_form.focused_field + 1) % 3;
-            }
-            KeyCode::BackTab => {
-                self.password_form.focused_field = if self.password_form.focused_field == 0 {
-                    2
-                } else {
-                    self.password_form.focused_field - 1
-                };
-            }
-            KeyCode::Enter => {
-                self.save_password().await;
+    /// Store the edited notes locally and close the modal
+    fn save_notes(&mut self) {
+        if let Some(Modal::Notes { month_id, text, .. }) = self.state.ui.modal.take() {
+            self.state.notes.set(month_id, text);
+            let result = self
+                .config
+                .data_dir()
+                .and_then(|dir| self.state.notes.save(&dir));
+            if let Err(e) = result {
+                self.state.set_error(format!("Failed to save notes: {}", e));
             }
-            KeyCode::Char(c) => match self.password_form.focused_field {
-                0 => self.password_form.current_password.push(c),
-                1 => self.password_form.new_password.push(c),
-                2 => self.password_form.confirm_password.push(c),
-                _ => {}
-            },
-            KeyCode::Backspace => match self.password_form.focused_field {
-                0 => {
-                    self.password_form.current_password.pop();
-                }
-                1 => {
-                    self.password_form.new_password.pop();
-                }
-                2 => {
-                    self.password_form.confirm_password.pop();
-                }
-                _ => {}
-            },
-            _ => {}
         }
     }
 
-    /// Save password
-    async fn save_password(&mut self) {
-        // Validate
-        let errors = self.password_form.validate();
-        if !errors.is_empty() {
-            self.state.set_error(errors.join(", "));
+    /// Send the edited shared notes to the server and close the modal
+    ///
+    /// Notes the server refuses stay open for fixing.
+    async fn save_shared_notes(&mut self) {
+        let (month_id, text) = match &self.state.ui.modal {
+            Some(Modal::Notes { month_id, text, .. }) => (*month_id, text.clone()),
+            _ => return,
+        };
+        let saved = self
+            .state
+            .data
+            .months
+            .iter()
+            .find(|m| m.id == month_id)
+            .and_then(|m| m.notes.clone())
+            .unwrap_or_default();
+        if text == saved {
+            self.state.ui.modal = None;
             return;
         }
 
+        let update = MonthUpdate {
+            notes: Some(text),
+            ..Default::default()
+        };
         self.state.ui.is_loading = true;
-
-        let result = self
-            .api
-            .auth()
-            .change_password(
-                &self.password_form.current_password,
-                &self.password_form.new_password,
-            )
-            .await;
-
+        let result = match update.check() {
+            Ok(()) => self.api.months().update(month_id, &update).await,
+            Err(e) => Err(e),
+        };
         self.state.ui.is_loading = false;
-        self.state.ui.modal = None;
-        self.password_form = PasswordFormState::default();
 
+        let sent_notes = update
+            .notes
+            .as_deref()
+            .is_some_and(|n| !n.trim().is_empty());
         match result {
-            Ok(_) => {
-                self.state.set_success("Password changed successfully");
+            // Older servers drop fields they don't know
+            Ok(month) if sent_notes && month.notes.is_none() => {
+                self.state.ui.modal = None;
+                self.state
+                    .set_error("This server doesn't keep shared notes - update it first");
+            }
+            Ok(month) => {
+                self.state.ui.modal = None;
+                for stored in self
+                    .state
+                    .data
+                    .months
+                    .iter_mut()
+                    .chain(self.state.data.current_month.as_mut())
+                    .filter(|m| m.id == month.id)
+                {
+                    *stored = month.clone();
+                }
+                self.state.set_success("Shared notes saved");
+            }
+            Err(e @ (ApiError::BadRequest(_) | ApiError::Validation(_))) => {
+                self.state
+                    .set_error(format!("Failed to save shared notes: {}", e));
             }
             Err(e) => {
+                self.state.ui.modal = None;
                 self.state
-                    .set_error(format!("Failed to change password: {}", e));
+                    .set_error(format!("Failed to save shared notes: {}", e));
             }
         }
     }
 
-    /// Load settings data (categories, periods, income types)
-    async fn load_settings_data(&mut self) {
-        if let Ok(categories) = self.api.categories().get_all().await {
-            self.state.data.categories = categories;
+    /// Move the selected expense or income to the next tax flag (or back to none)
+    fn cycle_tax_flag(&mut self) {
+        // Expenses are flagged by name, incomes by income type
+        let target = match self.state.ui.selected_tab {
+            DashboardTab::Expenses => self
+                .state
+                .ui
+                .expense_table
+                .selected()
+                .and_then(|idx| self.state.filtered_expenses().get(idx).copied())
+                .map(|expense| (EntityType::Expense, expense.expense_name.clone())),
+            DashboardTab::Income => self
+                .state
+                .ui
+                .income_table
+                .selected()
+                .and_then(|idx| self.state.filtered_incomes().get(idx).copied())
+                .and_then(|income| {
+                    self.state
+                        .data
+                        .income_types
+                        .iter()
+                        .find(|t| t.id == income.income_type_id)
+                })
+                .map(|income_type| (EntityType::Income, income_type.name.clone())),
+            _ => None,
+        };
+        let (entity_type, name) = match target {
+            Some(target) => target,
+            None => return,
+        };
+
+        let tax_flags = &mut self.state.tax_flags;
+        let flag = if entity_type == EntityType::Income {
+            let flag = storage::next_flag(&self.config.tax.flags, tax_flags.income_flag(&name));
+            tax_flags.set_income_flag(&name, flag.clone());
+            flag
+        } else {
+            let flag = storage::next_flag(&self.config.tax.flags, tax_flags.expense_flag(&name));
+            tax_flags.set_expense_flag(&name, flag.clone());
+            flag
+        };
+
+        let result = self
+            .config
+            .data_dir()
+            .and_then(|dir| self.state.tax_flags.save(&dir));
+        match result {
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to save tax flags: {}", e)),
+            Ok(()) => match flag {
+                Some(flag) => self.state.set_success(format!("Tax flag: {}", flag)),
+                None => self.state.set_success("Tax flag removed"),
+            },
         }
-        if let Ok(periods) = self.api.periods().get_all().await {
-            self.state.data.periods = periods;
+    }
+
+    /// Write the tax report for the selected month's year to a CSV file
+    ///
+    /// Loads every month of that year, so flagged items are totaled across the
+    /// whole year. The file goes to the current directory.
+    async fn export_tax_report(&mut self) {
+        self.begin_task(TaskKind::Export);
+        let year = match self.state.selected_month() {
+            Some(month) => month.year,
+            None => return,
+        };
+        if self.state.tax_flags.is_empty() {
+            self.state
+                .set_error("Nothing is tax-flagged yet. Flag expenses or incomes with t.");
+            return;
         }
-        if let Ok(income_types) = self.api.income_types().get_all().await {
-            self.state.data.income_types = income_types;
+
+        let (expenses, incomes) = match self.load_year(year, "the tax report").await {
+            Some(items) => items,
+            None => return,
+        };
+
+        let report = TaxReport::build(
+            year,
+            &expenses,
+            &incomes,
+            &self.state.data.income_types,
+            &self.state.tax_flags,
+        );
+        let path = std::path::PathBuf::from(report.file_name());
+        match std::fs::write(&path, report.to_csv()) {
+            Ok(()) => {
+                let shown = std::fs::canonicalize(&path).unwrap_or(path);
+                self.state.set_success(format!(
+                    "Tax report ({} rows) saved to {}",
+                    report.rows.len(),
+                    shown.display()
+                ));
+                self.record_action(
+                    Action::ExportTaxReport,
+                    format!("Exported the {} tax report", year),
+                );
+            }
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to write tax report: {}", e)),
         }
     }
 
-    /// Select next item in current list
-    fn select_next_item(&mut self) {
-        match self.state.ui.selected_tab {
-            DashboardTab::Expenses => {
-                let len = self.state.filtered_expenses().len();
-                if len > 0 {
-                    let i = self.state.ui.expense_table.selected().unwrap_or(0);
-                    let next = if i >= len - 1 { 0 } else { i + 1 };
-                    self.state.ui.expense_table.select(Some(next));
-                }
-            }
-            DashboardTab::Income => {
-                let len = self.state.filtered_incomes().len();
-                if len > 0 {
-                    let i = self.state.ui.income_table.selected().unwrap_or(0);
-                    let next = if i >= len - 1 { 0 } else { i + 1 };
-                    self.state.ui.income_table.select(Some(next));
-                }
-            }
-            DashboardTab::Settings => {
-                match self.state.ui.settings_tab {
-                    SettingsTab::Categories => {
-                        let len = self.state.data.categories.len();
-                        if len > 0 {
-                            let i = self.state.ui.category_table.selected().unwrap_or(0);
-                            let next = if i >= len - 1 { 0 } else { i + 1 };
-                            self.state.ui.category_table.select(Some(next));
-                        }
-                    }
-                    SettingsTab::Periods => {
-                        let len = self.state.data.periods.len();
-                        if len > 0 {
-                            let i = self.state.ui.period_table.selected().unwrap_or(0);
-                            let next = if i >= len - 1 { 0 } else { i + 1 };
-                            self.state.ui.period_table.select(Some(next));
-                        }
-                    }
-                    SettingsTab::IncomeTypes => {
-                        let len = self.state.data.income_types.len();
-                        if len > 0 {
-                            let i = self.state.ui.income_type_table.selected().unwrap_or(0);
-                            let next = if i >= len - 1 { 0 } else { i + 1 };
-                            self.state.ui.income_type_table.select(Some(next));
-                        }
-                    }
-                    _ => {
-                        // Switch settings sub-tab
-                        self.state.ui.settings_tab = self.state.ui.settings_tab.next();
-                    }
-                }
+    /// Write the selected month's year to an XLSX spreadsheet
+    ///
+    /// One sheet per month plus an overview sheet with the year's totals. The
+    /// file goes to the current directory.
+    async fn export_year_xlsx(&mut self) {
+        self.begin_task(TaskKind::Export);
+        let year = match self.state.selected_month() {
+            Some(month) => month.year,
+            None => return,
+        };
+        let (expenses, incomes) = match self.load_year(year, "the spreadsheet").await {
+            Some(items) => items,
+            None => return,
+        };
+
+        let report = YearReport::build(
+            year,
+            &self.state.data.months,
+            &expenses,
+            &incomes,
+            &self.state.data.income_types,
+        );
+        let path = std::path::PathBuf::from(report.file_name());
+        match std::fs::write(&path, report.to_xlsx()) {
+            Ok(()) => {
+                let shown = std::fs::canonicalize(&path).unwrap_or(path);
+                self.state.set_success(format!(
+                    "{} ({} months) saved to {}",
+                    year,
+                    report.months.len(),
+                    shown.display()
+                ));
+                self.record_action(Action::ExportYearXlsx, format!("Exported {} to XLSX", year));
             }
-            _ => {}
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to write spreadsheet: {}", e)),
         }
     }
 
-    /// Select previous item in current list
-    fn select_previous_item(&mut self) {
-        match self.state.ui.selected_tab {
-            DashboardTab::Expenses => {
-                let len = self.state.filtered_expenses().len();
-                if len > 0 {
-                    let i = self.state.ui.expense_table.selected().unwrap_or(0);
-                    let prev = if i == 0 { len - 1 } else { i - 1 };
-                    self.state.ui.expense_table.select(Some(prev));
-                }
+    /// Download the selected month as CSV from the server's export endpoint
+    ///
+    /// Saved to the current directory, in the format `--import` reads.
+    async fn export_month_csv(&mut self) {
+        self.begin_task(TaskKind::Export);
+        let month = match self.state.selected_month() {
+            Some(month) => month.clone(),
+            None => return,
+        };
+        let path = std::path::PathBuf::from(month_csv_file_name(&month));
+
+        self.state.ui.is_loading = true;
+        let result = self
+            .api
+            .months()
+            .export_csv(month.id, &path, |_, _| {})
+            .await;
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(size) => {
+                let shown = std::fs::canonicalize(&path).unwrap_or(path);
+                self.state.set_success(format!(
+                    "{} saved to {} ({})",
+                    month.display_name(),
+                    shown.display(),
+                    ui::format_size(size)
+                ));
+                self.record_action(
+                    Action::ExportMonthCsv,
+                    format!("Exported {} to CSV", month.display_name()),
+                );
             }
-            DashboardTab::Income => {
-                let len = self.state.filtered_incomes().len();
-                if len > 0 {
-                    let i = self.state.ui.income_table.selected().unwrap_or(0);
-                    let prev = if i == 0 { len - 1 } else { i - 1 };
-                    self.state.ui.income_table.select(Some(prev));
-                }
+            Err(e) if e.is_unsupported() => {
+                self.state
+                    .set_error("This server can't export months - update it first");
             }
-            DashboardTab::Settings => match self.state.ui.settings_tab {
-                SettingsTab::Categories => {
-                    let len = self.state.data.categories.len();
-                    if len > 0 {
-                        let i = self.state.ui.category_table.selected().unwrap_or(0);
-                        let prev = if i == 0 { len - 1 } else { i - 1 };
-                        self.state.ui.category_table.select(Some(prev));
-                    }
-                }
-                SettingsTab::Periods => {
-                    let len = self.state.data.periods.len();
-                    if len > 0 {
-                        let i = self.state.ui.period_table.selected().unwrap_or(0);
-                        let prev = if i == 0 { len - 1 } else { i - 1 };
-                        self.state.ui.period_table.select(Some(prev));
-                    }
-                }
-                SettingsTab::IncomeTypes => {
-                    let len = self.state.data.income_types.len();
-                    if len > 0 {
-                        let i = self.state.ui.income_type_table.selected().unwrap_or(0);
-                        let prev = if i == 0 { len - 1 } else { i - 1 };
-                        self.state.ui.income_type_table.select(Some(prev));
-                    }
-                }
-                _ => {
-                    self.state.ui.settings_tab = self.state.ui.settings_tab.previous();
-                }
-            },
-            _ => {}
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to export month: {}", e)),
         }
     }
 
-    /// Check if the selected month is closed
-    fn is_month_closed(&self) -> bool {
-        self.state
-            .selected_month()
-            .map(|m| m.is_closed)
-            .unwrap_or(false)
+    /// Push the selected month's summary and expenses to the configured Google Sheet
+    ///
+    /// Writes to a tab named after the month, replacing what it held.
+    async fn push_to_google_sheets(&mut self) {
+        self.begin_task(TaskKind::Export);
+        let settings = self.config.google_sheets.clone();
+        let key_file = match settings.service_account_path() {
+            Some(path) if settings.is_configured() => path,
+            _ => {
+                self.state
+                    .set_error("Google Sheets isn't set up. Add [google_sheets] to config.toml.");
+                return;
+            }
+        };
+        let month = match self.state.selected_month() {
+            Some(month) => month.clone(),
+            None => return,
+        };
+
+        self.state.ui.is_loading = true;
+        let result = async {
+            let account = ServiceAccount::load(&key_file)?;
+            let client = self.config.network.client_options().build_client()?;
+            // The tab's list may only hold its first page
+            let filters = ExpenseFilters {
+                month_id: Some(month.id),
+                ..Default::default()
+            };
+            let expenses = self.api.expenses().get_all(&filters).await?;
+            let rows = month_rows(
+                &month,
+                self.state.data.summary_totals.as_ref(),
+                &self.state.data.category_summary,
+                &expenses,
+            );
+            GoogleSheets::new(client, account, settings.spreadsheet_id.clone())
+                .push(&month.display_name(), &rows)
+                .await
+        }
+        .await;
+        self.state.ui.is_loading = false;
+
+        match result {
+            Ok(()) => {
+                self.state
+                    .set_success(format!("{} pushed to Google Sheets", month.display_name()));
+                self.record_action(
+                    Action::PushGoogleSheets,
+                    format!("Pushed {} to Google Sheets", month.display_name()),
+                );
+            }
+            Err(e) => self
+                .state
+                .set_error(format!("Google Sheets push failed: {:#}", e)),
+        }
     }
 
-    /// Open modal for new item
-    fn open_new_item_modal(&mut self) {
-        // Check if month is closed for expense/income tabs
-        if matches!(
-            self.state.ui.selected_tab,
-            DashboardTab::Expenses | DashboardTab::Income
-        ) && self.is_month_closed()
-        {
-            self.state
-                .set_error("Cannot add items to a closed month. Reopen the month first.");
-            return;
-        }
+    /// Fetch the expenses and incomes of every month in a year, a few months
+    /// at a time
+    ///
+    /// A partial year would understate the totals, so any failure is reported
+    /// (naming `purpose`) and nothing is returned.
+    async fn load_year(&mut self, year: i32, purpose: &str) -> Option<(Vec<Expense>, Vec<Income>)> {
+        self.state.ui.is_loading = true;
+        let result = self.api.get_month_range_data((year, 1), (year, 12)).await;
+        self.state.ui.is_loading = false;
 
-        match self.state.ui.selected_tab {
-            DashboardTab::Expenses => {
-                // Initialize empty expense form
-                self.expense_form = ExpenseFormState::default();
-                // Set default period and category if available
-                if let Some(period) = self.state.data.periods.first() {
-                    self.expense_form.period = period.name.clone();
-                }
-                if let Some(category) = self.state.data.categories.first() {
-                    self.expense_form.category = category.name.clone();
+        match result {
+            Ok(months) => {
+                let mut expenses = Vec::new();
+                let mut incomes = Vec::new();
+                for month in months {
+                    expenses.extend(month.expenses);
+                    incomes.extend(month.incomes);
                 }
-                self.state.ui.modal = Some(Modal::ExpenseForm { editing: None });
+                Some((expenses, incomes))
             }
-            DashboardTab::Income => {
-                // Initialize empty income form
-                self.income_form = IncomeFormState::default();
-                // Set default period and income type if available
-                if let Some(period) = self.state.data.periods.first() {
-                    self.income_form.period = period.name.clone();
-                }
-                if let Some(income_type) = self.state.data.income_types.first() {
-                    self.income_form.income_type_id = Some(income_type.id);
-                }
-                self.state.ui.modal = Some(Modal::IncomeForm { editing: None });
+            Err(e) => {
+                self.state
+                    .set_error(format!("Failed to load {} for {}: {}", year, purpose, e));
+                None
             }
-            DashboardTab::Settings => match self.state.ui.settings_tab {
-                SettingsTab::Categories => {
-                    self.category_form = CategoryFormState::default();
-                    self.state.ui.modal = Some(Modal::CategoryForm { editing: None });
-                }
-                SettingsTab::Periods => {
-                    self.period_form = PeriodFormState::default();
-                    self.state.ui.modal = Some(Modal::PeriodForm { editing: None });
-                }
-                SettingsTab::IncomeTypes => {
-                    self.income_type_form = IncomeTypeFormState::default();
-                    self.state.ui.modal = Some(Modal::IncomeTypeForm { editing: None });
-                }
-                SettingsTab::Password => {
-                    self.password_form = PasswordFormState::default();
-                    self.state.ui.modal = Some(Modal::PasswordForm);
-                }
-            },
-            _ => {}
         }
     }
 
-    /// Open modal for editing selected item
-    fn open_edit_item_modal(&mut self) {
-        // Check if month is closed for expense/income tabs
-        if matches!(
-            self.state.ui.selected_tab,
-            DashboardTab::Expenses | DashboardTab::Income
-        ) && self.is_month_closed()
-        {
+    /// Open the monthly routine checklist for the selected month
+    fn open_checklist(&mut self) {
+        if self.config.checklist.items.is_empty() {
             self.state
-                .set_error("Cannot edit items in a closed month. Reopen the month first.");
+                .set_error("No checklist items configured in config.toml");
             return;
         }
+        if let Some(month) = self.state.selected_month() {
+            let items: Vec<(String, bool)> = self
+                .config
+                .checklist
+                .items
+                .iter()
+                .map(|item| (item.clone(), self.state.checklist.is_done(month.id, item)))
+                .collect();
+            // Start at the first item still to do
+            let selected = items.iter().position(|(_, done)| !done).unwrap_or(0);
+            self.state.ui.modal = Some(Modal::Checklist {
+                month_id: month.id,
+                month_name: month.display_name(),
+                items,
+                selected,
+            });
+        }
+    }
 
-        match self.state.ui.selected_tab {
-            DashboardTab::Expenses => {
-                if let Some(idx) = self.state.ui.expense_table.selected() {
-                    let filtered = self.state.filtered_expenses();
-                    if let Some(expense) = filtered.get(idx) {
-                        // Initialize form from existing expense
-                        self.expense_form = ExpenseFormState::from_expense(expense);
-                        self.state.ui.modal = Some(Modal::ExpenseForm {
-                            editing: Some((*expense).clone()),
-                        });
-                    }
-                }
-            }
-            DashboardTab::Income => {
-                if let Some(idx) = self.state.ui.income_table.selected() {
-                    let filtered = self.state.filtered_incomes();
-                    if let Some(income) = filtered.get(idx) {
-                        // Initialize form from existing income
-                        self.income_form = IncomeFormState::from_income(income);
-                        self.state.ui.modal = Some(Modal::IncomeForm {
-                            editing: Some((*income).clone()),
-                        });
+    /// Toggle the selected checklist item, persist it, and move to the next open item
+    fn toggle_checklist_item(&mut self) {
+        if let Some(Modal::Checklist {
+            month_id,
+            ref mut items,
+            ref mut selected,
+            ..
+        }) = self.state.ui.modal
+        {
+            if let Some((label, done)) = items.get_mut(*selected) {
+                *done = self.state.checklist.toggle(month_id, label);
+                if *done {
+                    if let Some(next) = items.iter().position(|(_, done)| !done) {
+                        *selected = next;
                     }
                 }
             }
-            DashboardTab::Settings => match self.state.ui.settings_tab {
-                SettingsTab::Categories => {
-                    if let Some(idx) = self.state.ui.category_table.selected() {
-                        if let Some(cat) = self.state.data.categories.get(idx) {
-                            self.category_form = CategoryFormState::from_category(cat);
-                            self.state.ui.modal = Some(Modal::CategoryForm {
-                                editing: Some(cat.clone()),
-                            });
-                        }
-                    }
-                }
-                SettingsTab::Periods => {
-                    if let Some(idx) = self.state.ui.period_table.selected() {
-                        if let Some(period) = self.state.data.periods.get(idx) {
-                            self.period_form = PeriodFormState::from_period(period);
-                            self.state.ui.modal = Some(Modal::PeriodForm {
-                                editing: Some(period.clone()),
-                            });
-                        }
-                    }
-                }
-                SettingsTab::IncomeTypes => {
-                    if let Some(idx) = self.state.ui.income_type_table.selected() {
-                        if let Some(it) = self.state.data.income_types.get(idx) {
-                            self.income_type_form = IncomeTypeFormState::from_income_type(it);
-                            self.state.ui.modal = Some(Modal::IncomeTypeForm {
-                                editing: Some(it.clone()),
-                            });
-                        }
-                    }
-                }
-                SettingsTab::Password => {
-                    self.password_form = PasswordFormState::default();
-                    self.state.ui.modal = Some(Modal::PasswordForm);
-                }
-            },
-            _ => {}
+        }
+
+        let result = self
+            .config
+            .data_dir()
+            .and_then(|dir| self.state.checklist.save(&dir));
+        if let Err(e) = result {
+            self.state
+                .set_error(format!("Failed to save checklist: {}", e));
         }
     }
 
-    /// Open delete confirmation dialog
-    fn open_delete_confirmation(&mut self) {
-        use crate::state::EntityType;
+    /// Show a second month beside the selected one, or go back to one
+    async fn toggle_split(&mut self) {
+        if self.state.ui.split.is_some() {
+            self.state.close_split();
+            return;
+        }
+        if !self.state.open_split() {
+            self.state
+                .set_error("No other month to show beside this one");
+            return;
+        }
+        self.state.load_split_pane(&self.api).await;
+    }
 
-        // Check if month is closed for expense/income tabs
-        if matches!(
-            self.state.ui.selected_tab,
-            DashboardTab::Expenses | DashboardTab::Income
-        ) && self.is_month_closed()
-        {
+    /// Move or copy the selected expense to the other pane's month
+    ///
+    /// A copy keeps the name, category, period and projection, with nothing
+    /// paid yet.
+    async fn send_to_split(&mut self, copy: bool) {
+        if !self.check_scope(Some(Scope::Expenses)) {
+            return;
+        }
+        if self.is_unsynced_selection() {
             self.state
-                .set_error("Cannot delete items in a closed month. Reopen the month first.");
+                .set_error("This item hasn't synced yet. Try again once the server is back.");
             return;
         }
+        let expense = match self
+            .state
+            .ui
+            .expense_table
+            .selected()
+            .and_then(|idx| self.state.filtered_expenses().get(idx).copied())
+        {
+            Some(expense) => expense.clone(),
+            None => return,
+        };
+        let target = self
+            .state
+            .split_month()
+            .map(|m| m.display_name())
+            .unwrap_or_default();
 
-        match self.state.ui.selected_tab {
-            DashboardTab::Expenses => {
-                if let Some(idx) = self.state.ui.expense_table.selected() {
-                    let filtered = self.state.filtered_expenses();
-                    if let Some(expense) = filtered.get(idx) {
-                        self.state.ui.modal = Some(Modal::ConfirmDelete {
-                            message: format!("Delete expense '{}'?", expense.expense_name),
-                            id: expense.id,
-                            entity_type: EntityType::Expense,
-                        });
-                    }
+        let result = if copy {
+            match self.state.copy_to_split(&expense) {
+                Ok(create) => self.api.expenses().create(&create).await.map(|_| ()),
+                Err(message) => {
+                    self.state.set_error(message);
+                    return;
                 }
             }
-            DashboardTab::Income => {
-                if let Some(idx) = self.state.ui.income_table.selected() {
-                    let filtered = self.state.filtered_incomes();
-                    if let Some(income) = filtered.get(idx) {
-                        self.state.ui.modal = Some(Modal::ConfirmDelete {
-                            message: "Delete this income entry?".to_string(),
-                            id: income.id,
-                            entity_type: EntityType::Income,
-                        });
-                    }
+        } else {
+            match self.state.move_to_split() {
+                Ok(update) => self
+                    .api
+                    .expenses()
+                    .update(expense.id, &update)
+                    .await
+                    .map(|_| ()),
+                Err(message) => {
+                    self.state.set_error(message);
+                    return;
                 }
             }
-            DashboardTab::Settings => match self.state.ui.settings_tab {
-                SettingsTab::Categories => {
-                    if let Some(idx) = self.state.ui.category_table.selected() {
-                        if let Some(cat) = self.state.data.categories.get(idx) {
-                            self.state.ui.modal = Some(Modal::ConfirmDelete {
-                                message: format!("Delete category '{}'?", cat.name),
-                                id: cat.id,
-                                entity_type: EntityType::Category,
-                            });
-                        }
-                    }
-                }
-                SettingsTab::Periods => {
-                    if let Some(idx) = self.state.ui.period_table.selected() {
-                        if let Some(period) = self.state.data.periods.get(idx) {
-                            self.state.ui.modal = Some(Modal::ConfirmDelete {
-                                message: format!("Delete period '{}'?", period.name),
-                                id: period.id,
-                                entity_type: EntityType::Period,
-                            });
-                        }
-                    }
-                }
-                SettingsTab::IncomeTypes => {
-                    if let Some(idx) = self.state.ui.income_type_table.selected() {
-                        if let Some(it) = self.state.data.income_types.get(idx) {
-                            self.state.ui.modal = Some(Modal::ConfirmDelete {
-                                message: format!("Delete income type '{}'?", it.name),
-                                id: it.id,
-                                entity_type: EntityType::IncomeType,
-                            });
-                        }
-                    }
-                }
-                _ => {}
-            },
-            _ => {}
+        };
+        match result {
+            Ok(()) => {
+                let verb = if copy { "Copied" } else { "Moved" };
+                self.state
+                    .set_success(format!("{} {} to {}", verb, expense.expense_name, target));
+                self.load_tab_data().await;
+            }
+            Err(ApiError::Queued) => self.show_queued_write(),
+            Err(ApiError::NotFound) => self.drop_missing(EntityType::Expense, expense.id),
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to send to {}: {}", target, e)),
         }
     }
 
-    /// Confirm and execute delete
-    async fn confirm_delete(&mut self) {
-        use crate::state::EntityType;
+    /// Work out the selected month's budgets from the spending of the months
+    /// before it, and show them for confirming
+    async fn open_autofill(&mut self) {
+        if !self.check_scope(Some(Scope::Expenses)) {
+            return;
+        }
+        if self.is_month_closed() {
+            self.state
+                .set_error("Cannot change budgets in a closed month. Reopen it or press U.");
+            return;
+        }
+        let month = match self.state.selected_month() {
+            Some(month) => month.clone(),
+            None => return,
+        };
 
-        if let Some(Modal::ConfirmDelete {
-            id, entity_type, ..
-        }) = &self.state.ui.modal
-        {
-            let id = *id;
-            let entity_type = *entity_type;
+        let to = rollover::previous_month((month.year, month.month));
+        let from = (1..self.config.months.autofill_months.max(1))
+            .fold(to, |from, _| rollover::previous_month(from));
+        self.state.ui.is_loading = true;
+        let history = self.api.get_month_range_data(from, to).await;
+        let expenses = self.month_expenses(month.id).await;
+        self.state.ui.is_loading = false;
 
-            self.state.ui.is_loading = true;
+        let (history, expenses) = match (history, expenses) {
+            (Ok(history), Ok(expenses)) => (history, expenses),
+            (Err(e), _) | (_, Err(e)) => {
+                self.state
+                    .set_error(format!("Failed to load spending for autofill: {}", e));
+                return;
+            }
+        };
+        if history.is_empty() {
+            self.state.set_error(format!(
+                "No months before {} to average",
+                month.display_name()
+            ));
+            return;
+        }
+
+        let preview = AutofillPreview::build(
+            &month,
+            &expenses,
+            &history,
+            self.config.months.autofill_round,
+        );
+        if !preview.has_changes() {
+            self.state.set_success(format!(
+                "Budgets for {} already match recent spending",
+                month.display_name()
+            ));
+            return;
+        }
+        self.state.ui.modal = Some(Modal::Autofill { preview });
+    }
+
+    /// Every expense of a month, whatever the list shows
+    async fn month_expenses(&self, month_id: i32) -> Result<Vec<Expense>, ApiError> {
+        let filters = ExpenseFilters {
+            month_id: Some(month_id),
+            ..Default::default()
+        };
+        self.api.get_expenses(&filters).await
+    }
 
-            let result = match entity_type {
-                EntityType::Expense => self.api.expenses().delete(id).await,
-                EntityType::Income => self.api.incomes().delete(id).await,
-                EntityType::Category => self.api.categories().delete(id).await,
-                EntityType::Period => self.api.periods().delete(id).await,
-                EntityType::IncomeType => self.api.income_types().delete(id).await,
-            };
+    /// Write the budgets from the autofill preview
+    async fn apply_autofill(&mut self) {
+        self.begin_task(TaskKind::Bulk);
+        let preview = match self.state.ui.modal.take() {
+            Some(Modal::Autofill { preview }) => preview,
+            other => {
+                self.state.ui.modal = other;
+                return;
+            }
+        };
 
-            self.state.ui.is_loading = false;
-            self.state.ui.modal = None;
+        self.state.ui.is_loading = true;
+        // Expenses may have changed since the preview
+        let expenses = match self.month_expenses(preview.month_id).await {
+            Ok(expenses) => expenses,
+            Err(e) => {
+                self.state.ui.is_loading = false;
+                self.state.set_error(format!("Failed to autofill: {}", e));
+                return;
+            }
+        };
+        let period = self
+            .state
+            .data
+            .periods
+            .first()
+            .map(|p| p.name.clone())
+            .unwrap_or_default();
+        let changes = preview.changes(&expenses, &period);
 
-            match result {
-                Ok(_) => {
-                    self.state.set_success("Item deleted successfully");
-                    self.load_tab_data().await;
-                }
+        let mut queued = false;
+        let mut failure = None;
+        for (id, update) in &changes.updates {
+            match self.api.expenses().update(*id, update).await {
+                Ok(_) => {}
+                Err(ApiError::Queued) => queued = true,
                 Err(e) => {
-                    self.state.set_error(format!("Failed to delete: {}", e));
+                    failure = Some(e);
+                    break;
                 }
             }
         }
+        if failure.is_none() && !changes.creates.is_empty() {
+            match self.api.create_expenses_bulk(&changes.creates).await {
+                Ok(_) => {}
+                Err(ApiError::Queued) => queued = true,
+                Err(e) => failure = Some(e),
+            }
+        }
+        self.state.ui.is_loading = false;
+
+        match failure {
+            Some(e) => self
+                .state
+                .set_error(format!("Failed to autofill {}: {}", preview.month_name, e)),
+            None if queued => self.show_queued_write(),
+            None => self.state.set_success(format!(
+                "Budgets for {} set from {} month(s) of spending",
+                preview.month_name, preview.months_used
+            )),
+        }
+        self.load_tab_data().await;
+    }
+
+    /// Compose an issue about a problem from the version, system, config
+    /// and recent errors
+    fn open_problem_report(&mut self) {
+        match ProblemReport::new(VERSION, &self.config, &self.state.recent_errors) {
+            Ok(report) => {
+                self.state.ui.modal = Some(Modal::ReportProblem {
+                    body: report.body(),
+                    url: report.issue_url(),
+                });
+            }
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to compose the report: {:#}", e)),
+        }
     }
 
-    /// Open pay confirmation dialog for an expense
-    fn open_pay_confirmation(&mut self) {
-        // Only available in Expenses tab
-        if self.state.ui.selected_tab != DashboardTab::Expenses {
+    /// Open the board for moving budget between the selected month's
+    /// categories
+    async fn open_reallocate(&mut self) {
+        if !self.check_scope(Some(Scope::Expenses)) {
             return;
         }
-
-        // Check if month is closed
         if self.is_month_closed() {
             self.state
-                .set_error("Cannot pay expenses in a closed month. Reopen the month first.");
+                .set_error("Cannot change budgets in a closed month. Reopen it or press U.");
             return;
         }
+        let month = match self.state.selected_month() {
+            Some(month) => month.clone(),
+            None => return,
+        };
 
-        if let Some(idx) = self.state.ui.expense_table.selected() {
-            let filtered = self.state.filtered_expenses();
-            if let Some(expense) = filtered.get(idx) {
-                // Don't show pay option if expense already has purchases
-                if expense.purchases.as_ref().is_some_and(|p| !p.is_empty()) {
-                    self.state.set_error("Expense already has purchases");
-                    return;
-                }
-
-                self.state.ui.modal = Some(Modal::ConfirmPay {
-                    expense_name: expense.expense_name.clone(),
-                    expense_id: expense.id,
-                    amount: expense.projected,
-                    amount_input: format!("{:.2}", expense.projected),
-                });
+        self.state.ui.is_loading = true;
+        let expenses = self.month_expenses(month.id).await;
+        self.state.ui.is_loading = false;
+        let expenses = match expenses {
+            Ok(expenses) => expenses,
+            Err(e) => {
+                self.state
+                    .set_error(format!("Failed to load budgets: {}", e));
+                return;
             }
+        };
+        let board = ReallocateBoard::build(&month, &expenses);
+        if board.rows.len() < 2 {
+            self.state.set_error(format!(
+                "{} needs budget in two categories to move it between them",
+                month.display_name()
+            ));
+            return;
         }
+        self.state.ui.modal = Some(Modal::Reallocate { board });
     }
 
-    /// Confirm and execute pay
-    async fn confirm_pay(&mut self) {
-        if let Some(Modal::ConfirmPay {
-            expense_id,
-            amount_input,
-            ..
-        }) = &self.state.ui.modal
-        {
-            let id = *expense_id;
-            let amount: f64 = amount_input.parse().unwrap_or(0.0);
-
-            self.state.ui.is_loading = true;
-
-            let request = crate::models::PayExpenseRequest {
-                amount: Some(amount),
-            };
-            let result = self.api.expenses().pay(id, Some(&request)).await;
+    /// Save the budgets on the reallocation board
+    ///
+    /// The month's expenses are read again first; if a category on the
+    /// board was changed elsewhere since it opened, nothing is saved.
+    async fn apply_reallocate(&mut self) {
+        self.begin_task(TaskKind::Bulk);
+        let board = match self.state.ui.modal.take() {
+            Some(Modal::Reallocate { board }) => board,
+            other => {
+                self.state.ui.modal = other;
+                return;
+            }
+        };
 
+        self.state.ui.is_loading = true;
+        let expenses = match self.month_expenses(board.month_id).await {
+            Ok(expenses) => expenses,
+            Err(e) => {
+                self.state.ui.is_loading = false;
+                self.state
+                    .set_error(format!("Failed to move budget: {}", e));
+                self.state.ui.modal = Some(Modal::Reallocate { board });
+                return;
+            }
+        };
+        let conflicts = board.conflicts(&expenses);
+        if !conflicts.is_empty() {
             self.state.ui.is_loading = false;
-            self.state.ui.modal = None;
+            self.state.set_error(format!(
+                "Budgets for {} changed meanwhile; press B to start over",
+                conflicts.join(", ")
+            ));
+            self.load_tab_data().await;
+            return;
+        }
 
-            match result {
-                Ok(_) => {
-                    self.state
-                        .set_success(format!("Payment of ${:.2} added successfully", amount));
-                    self.load_tab_data().await;
-                }
+        let mut queued = false;
+        let mut failure = None;
+        for (id, update) in &board.changes(&expenses) {
+            match self.api.expenses().update(*id, update).await {
+                Ok(_) => {}
+                Err(ApiError::Queued) => queued = true,
                 Err(e) => {
-                    self.state.set_error(format!("Failed to pay: {}", e));
+                    failure = Some(e);
+                    break;
                 }
             }
         }
-    }
+        self.state.ui.is_loading = false;
 
-    /// Open close/open month confirmation dialog
-    fn open_close_month_confirmation(&mut self) {
-        if let Some(month) = self.state.selected_month() {
-            self.state.ui.modal = Some(Modal::ConfirmCloseMonth {
-                month_name: month.display_name(),
-                month_id: month.id,
-                is_closing: !month.is_closed,
-            });
+        let moved = board.rows.iter().filter(|row| row.changes()).count();
+        match failure {
+            Some(e) => self.state.set_error(format!(
+                "Failed to move budget in {}: {}",
+                board.month_name, e
+            )),
+            None if queued => self.show_queued_write(),
+            None => self.state.set_success(format!(
+                "Budget moved between {} categories in {}",
+                moved, board.month_name
+            )),
         }
+        self.load_tab_data().await;
     }
 
-    /// Confirm and execute close/open month
-    async fn confirm_close_month(&mut self) {
-        if let Some(Modal::ConfirmCloseMonth {
-            month_id,
-            is_closing,
-            ..
-        }) = &self.state.ui.modal
+    /// Move the selected expense to the next ledger (personal, business, reimbursable)
+    fn cycle_ledger(&mut self) {
+        let (expense_id, month_id) = match self
+            .state
+            .ui
+            .expense_table
+            .selected()
+            .and_then(|idx| self.state.filtered_expenses().get(idx).copied())
         {
-            let id = *month_id;
-            let closing = *is_closing;
+            Some(expense) => (expense.id, expense.month_id),
+            None => return,
+        };
 
-            self.state.ui.is_loading = true;
+        let ledger = self.state.ledgers.ledger(expense_id).next();
+        self.state.ledgers.set_ledger(expense_id, month_id, ledger);
 
-            let result = if closing {
-                self.api.months().close(id).await
-            } else {
-                self.api.months().open(id).await
-            };
+        let result = self
+            .config
+            .data_dir()
+            .and_then(|dir| self.state.ledgers.save(&dir));
+        match result {
+            Err(e) => self
+                .state
+                .set_error(format!("Failed to save ledger: {}", e)),
+            Ok(()) => self
+                .state
+                .set_success(format!("Ledger: {}", ledger.as_str())),
+        }
+    }
 
-            self.state.ui.is_loading = false;
-            self.state.ui.modal = None;
+    /// Open the reimbursement tracker
+    ///
+    /// Loads every month that has business or reimbursable expenses, so the
+    /// tracker covers them all and not just the selected month.
+    async fn open_reimbursements(&mut self) {
+        if self.state.ledgers.is_empty() {
+            self.state
+                .set_error("No business or reimbursable expenses yet. Mark expenses with b.");
+            return;
+        }
 
-            match result {
-                Ok(_) => {
-                    let action = if closing { "closed" } else { "reopened" };
-                    self.state
-                        .set_success(format!("Month {} successfully", action));
-                    // Reload months to update the is_closed status
-                    if let Ok(months) = self.api.months().get_all().await {
-                        self.state.data.months = months;
-                    }
-                }
+        self.state.ui.is_loading = true;
+        let mut expenses = Vec::new();
+        for month_id in self.state.ledgers.month_ids() {
+            let filters = ExpenseFilters {
+                month_id: Some(month_id),
+                ..Default::default()
+            };
+            match self.api.expenses().get_all(&filters).await {
+                Ok(month_expenses) => expenses.extend(month_expenses),
                 Err(e) => {
-                    let action = if closing { "close" } else { "reopen" };
+                    // Missing months would understate what is owed
+                    self.state.ui.is_loading = false;
                     self.state
-                        .set_error(format!("Failed to {} month: {}", action, e));
+                        .set_error(format!("Failed to load reimbursements: {}", e));
+                    return;
+                }
+            }
+        }
+        self.state.ui.is_loading = false;
+
+        let report =
+            ReimbursementReport::build(&expenses, &self.state.data.months, &self.state.ledgers);
+        self.state.ui.modal = Some(Modal::Reimbursements {
+            report,
+            selected: 0,
+        });
+    }
+
+    /// Mark the selected reimbursable expense as reimbursed, or outstanding again
+    fn toggle_reimbursed(&mut self) {
+        let today = self.state.clock.today();
+        if let Some(Modal::Reimbursements {
+            ref mut report,
+            selected,
+        }) = self.state.ui.modal
+        {
+            match report.rows.get_mut(selected) {
+                Some(row) if row.ledger == Ledger::Reimbursable => {
+                    self.state.ledgers.toggle_reimbursed(row.expense_id, today);
+                    row.reimbursed_on = self
+                        .state
+                        .ledgers
+                        .get(row.expense_id)
+                        .and_then(|entry| entry.reimbursed_on.clone());
                 }
+                // Business expenses are not paid back
+                _ => return,
             }
         }
+
+        let result = self
+            .config
+            .data_dir()
+            .and_then(|dir| self.state.ledgers.save(&dir));
+        if let Err(e) = result {
+            self.state
+                .set_error(format!("Failed to save reimbursement: {}", e));
+        }
     }
 
     /// Load initial data after login
     async fn load_initial_data(&mut self) {
         self.state.ui.is_loading = true;
 
-        // Load months
-        if let Ok(months) = self.api.months().get_all().await {
-            self.state.data.months = months;
-        }
-
-        // Get current month
-        if let Ok(current) = self.api.months().get_current().await {
-            self.state.data.current_month = Some(current);
-            self.state.select_current_month();
+        self.state.load_reference_data(&self.api).await;
+        self.load_exchange_rates().await;
+        let problems = self.state.apply_startup(&self.config.startup);
+        if !problems.is_empty() {
+            self.state
+                .set_error(format!("[startup] {}", problems.join("; ")));
         }
-
-        // Load categories, periods, income types
-        if let Ok(categories) = self.api.categories().get_all().await {
-            self.state.data.categories = categories;
+        if let Some(view) = self.remembered_view.take() {
+            self.state.restore_view(&view);
         }
-        if let Ok(periods) = self.api.periods().get_all().await {
-            self.state.data.periods = periods;
+        if self.config.months.pin_current {
+            self.state
+                .select_calendar_month(calendar_month(self.state.clock.today()));
         }
-        if let Ok(income_types) = self.api.income_types().get_all().await {
-            self.state.data.income_types = income_types;
+        if let Some((year, month)) = self.start_month.take() {
+            if !self.state.select_calendar_month((year, month)) {
+                self.state
+                    .set_error(format!("No month {}-{:02} on the server", year, month));
+            }
+            // pin_current moves on when the next month begins, not right away
+            self.calendar_month = Some(calendar_month(self.state.clock.today()));
         }
-
-        // Load data for current month
         self.load_month_data().await;
 
         self.state.ui.is_loading = false;
     }
 
-    /// Load data for the selected month
-    async fn load_month_data(&mut self) {
-        let month_id = self.state.selected_month_id();
-
-        // Load expenses
-        let filters = ExpenseFilters {
-            month_id,
-            ..Default::default()
-        };
-        if let Ok(expenses) = self.api.expenses().get_all(&filters).await {
-            self.state.data.expenses = expenses;
+    /// Rates from `[currency]`, with fetched ones on top when it uses the API
+    ///
+    /// A failed fetch keeps the manual rates and says so.
+    async fn load_exchange_rates(&mut self) {
+        let settings = self.config.currency.clone();
+        self.state.rates = Rates::from_config(&settings);
+        if settings.source != RateSource::Api {
+            return;
+        }
+        let result = async {
+            let client = self.config.network.client_options().build_client()?;
+            fetch_rates(&client, &settings.rates_url()).await
         }
+        .await;
+        match result {
+            Ok(fetched) => self
+                .state
+                .rates
+                .extend(fetched.iter().map(|(code, rate)| (code.as_str(), *rate))),
+            Err(e) => self
+                .state
+                .set_error(format!("{:#}; using the rates in config.toml", e)),
+        }
+    }
 
-        // Load incomes
-        let income_filters = crate::models::IncomeFilters {
-            month_id,
-            ..Default::default()
-        };
-        if let Ok(incomes) = self.api.incomes().get_all(&income_filters).await {
-            self.state.data.incomes = incomes;
+    /// Save the tab, month and filters for the next start
+    fn remember_view(&self) {
+        if !self.config.startup.remember || self.state.screen != Screen::Dashboard {
+            return;
+        }
+        if let Ok(dir) = self.config.data_dir() {
+            let _ = self.state.last_view().save(&dir);
         }
+    }
 
-        // Load summary
-        if let Ok(totals) = self.api.summary().get_totals(None, month_id).await {
-            self.state.data.summary_totals = Some(totals);
+    /// Load the current month for a one-off inline render
+    ///
+    /// Needs a saved session; logging in is only possible in the full-screen UI.
+    pub async fn load_inline(&mut self) -> Result<()> {
+        if self.state.screen != Screen::Dashboard {
+            anyhow::bail!("Not logged in - run budget-tui once to log in");
         }
+        // A printed view is always of the month asked for, unfiltered
+        self.remembered_view = None;
+        self.load_initial_data().await;
+        Ok(())
+    }
 
-        // Load category summary
-        if let Ok(summary) = self.api.categories().get_summary(month_id).await {
-            self.state.data.category_summary = summary;
+    /// Say why the last action was slow if the server rate limited it
+    ///
+    /// Requests are retried after the server's `Retry-After` without
+    /// failing; messages from the action itself take precedence.
+    fn show_rate_limit_wait(&mut self) {
+        let wait = match self.api.take_rate_limit_wait() {
+            Some(wait) => wait,
+            None => return,
+        };
+        if self.state.ui.error_message.is_none() && self.state.ui.success_message.is_none() {
+            self.state.set_success(format!(
+                "Rate limited by the server - retried after {}s",
+                wait.as_secs().max(1)
+            ));
         }
+    }
+
+    /// End the active profile's session and go back to the login screen
+    ///
+    /// Only this profile's token is forgotten; other profiles stay logged in.
+    fn log_out(&mut self) {
+        self.api.clear_token();
+        let saved = self.config.clear_token();
+        self.live_updates = None;
+        self.state.user = None;
+        self.state.data = Default::default();
+        self.state.ui.modal = None;
+        self.state.screen = Screen::Login;
+        self.login_error = match saved {
+            Ok(()) => None,
+            Err(e) => Some(format!("Logged out, but the config wasn't saved: {}", e)),
+        };
+    }
 
-        // Load income type summary
-        if let Ok(summary) = self.api.income_types().get_summary(None, month_id).await {
-            self.state.data.income_type_summary = summary;
+    /// Go back to the login screen once the server refuses the token, e.g.
+    /// after it expired or was revoked
+    fn end_rejected_session(&mut self) {
+        if !self.api.take_token_rejected() || self.state.screen != Screen::Dashboard {
+            return;
         }
+        self.api.clear_token();
+        // Logging in again replaces it anyway
+        let _ = self.config.clear_token();
+        self.live_updates = None;
+        self.state.ui.modal = None;
+        self.state.screen = Screen::Login;
+        self.login_error = Some(SESSION_EXPIRED.to_string());
+    }
 
-        // Load period summary
-        if let Ok(summary) = self.api.summary().get_by_period(month_id).await {
-            self.state.data.period_summary = Some(summary);
+    /// Follow the server's change feed, unless turned off in the config
+    fn start_live_updates(&mut self) {
+        self.live_updates = if self.config.network.live_updates {
+            Some(self.api.subscribe())
+        } else {
+            None
+        };
+    }
+
+    /// Reload what other clients changed since the last tick
+    ///
+    /// Waits while a form or dialog is open so edits in progress aren't
+    /// disturbed; the changes are applied once it closes.
+    async fn apply_live_updates(&mut self) {
+        let events = match &self.live_updates {
+            Some(live) => live.drain(),
+            None => return,
+        };
+        let mut resync = false;
+        for event in events {
+            match event {
+                LiveEvent::Change(change) => self.remote_changes.push(change),
+                LiveEvent::Reconnected => resync = true,
+            }
+        }
+        if self.state.ui.modal.is_some() || (self.remote_changes.is_empty() && !resync) {
+            return;
         }
 
-        // Load insights
-        if let Ok(insights) = self.api.summary().get_insights(month_id).await {
-            self.state.data.insights = Some(insights);
+        let changes = std::mem::take(&mut self.remote_changes);
+        if resync || changes.iter().any(|c| c.resource == "months") {
+            if let Ok(months) = self.api.months().get_all().await {
+                self.state.data.months = months;
+            }
+        }
+        if resync || changes.iter().any(ChangeEvent::affects_settings) {
+            self.state.load_settings_data(&self.api).await;
         }
+        let month_changed = [
+            self.state.selected_month_id(),
+            self.state.split_month().map(|m| m.id),
+        ]
+        .into_iter()
+        .flatten()
+        .any(|month_id| changes.iter().any(|c| c.affects_month(month_id)));
+        // Other tabs are reloaded when switched to
+        if resync || month_changed {
+            self.load_tab_data().await;
+        }
+    }
+
+    /// Load data for the selected month
+    async fn load_month_data(&mut self) {
+        self.state.load_month_data(&self.api).await;
+        self.state.apply_queued_writes(&self.api.queued_writes());
     }
 
     /// Load data for current tab
@@ -1864,41 +6341,115 @@ impl App {
                 self.load_month_data().await;
             }
             DashboardTab::Expenses => {
-                let filters = ExpenseFilters {
-                    month_id: self.state.selected_month_id(),
-                    period: self.state.ui.period_filter.clone(),
-                    category: self.state.ui.category_filter.clone(),
-                };
-                if let Ok(expenses) = self.api.expenses().get_all(&filters).await {
-                    self.state.data.expenses = expenses;
-                }
+                self.state.load_filtered_expenses(&self.api).await;
+                self.state.load_split_pane(&self.api).await;
             }
             DashboardTab::Income => {
-                let filters = crate::models::IncomeFilters {
-                    month_id: self.state.selected_month_id(),
-                    period: self.state.ui.period_filter.clone(),
-                    ..Default::default()
-                };
-                if let Ok(incomes) = self.api.incomes().get_all(&filters).await {
-                    self.state.data.incomes = incomes;
-                }
+                self.state.load_filtered_incomes(&self.api).await;
             }
             DashboardTab::Charts => {
                 // Charts use same data as summary
                 self.load_month_data().await;
             }
+            DashboardTab::Debts => {
+                self.state.load_debts(&self.api).await;
+            }
             DashboardTab::Settings => {
-                // Reload settings data
-                if let Ok(categories) = self.api.categories().get_all().await {
-                    self.state.data.categories = categories;
-                }
-                if let Ok(periods) = self.api.periods().get_all().await {
-                    self.state.data.periods = periods;
+                self.state.load_settings_data(&self.api).await;
+            }
+        }
+
+        self.state.apply_queued_writes(&self.api.queued_writes());
+    }
+
+    /// Replay writes queued while the server was unreachable
+    async fn sync_queued_writes(&mut self) {
+        self.last_sync_attempt = Instant::now();
+        let report = self.api.sync_queued_writes().await;
+
+        if let Some(failure) = report.failed.first() {
+            self.state.set_error(format!(
+                "{} offline change(s) rejected by the server: {}",
+                report.failed.len(),
+                failure
+            ));
+        } else if report.synced > 0 {
+            self.state
+                .set_success(format!("Synced {} offline change(s)", report.synced));
+        }
+        if report.synced > 0 || !report.failed.is_empty() {
+            self.load_tab_data().await;
+        }
+    }
+
+    /// Show a write that was queued because the server is unreachable
+    fn show_queued_write(&mut self) {
+        self.last_sync_attempt = Instant::now();
+        self.state.set_success(ApiError::Queued.to_string());
+        self.state.apply_queued_writes(&self.api.queued_writes());
+    }
+
+    /// Fetch the latest version of the selected expense or income
+    ///
+    /// Edits then start from what the server has now, not from the list as it
+    /// was loaded. Returns false if it was deleted meanwhile.
+    async fn refresh_selected_item(&mut self) -> bool {
+        if self.is_unsynced_selection() {
+            return true;
+        }
+        match self.state.ui.selected_tab {
+            DashboardTab::Expenses => {
+                let id = self
+                    .state
+                    .ui
+                    .expense_table
+                    .selected()
+                    .and_then(|idx| self.state.filtered_expenses().get(idx).map(|e| e.id));
+                if let Some(id) = id {
+                    if !self.state.refresh_expense(&self.api, id).await {
+                        self.state
+                            .set_error("This expense was deleted on another device");
+                        return false;
+                    }
                 }
-                if let Ok(income_types) = self.api.income_types().get_all().await {
-                    self.state.data.income_types = income_types;
+            }
+            DashboardTab::Income => {
+                let id = self
+                    .state
+                    .ui
+                    .income_table
+                    .selected()
+                    .and_then(|idx| self.state.filtered_incomes().get(idx).map(|i| i.id));
+                if let Some(id) = id {
+                    if !self.state.refresh_income(&self.api, id).await {
+                        self.state
+                            .set_error("This income was deleted on another device");
+                        return false;
+                    }
                 }
             }
+            _ => {}
         }
+        true
+    }
+
+    /// Check if the selected expense or income only exists in the offline queue
+    fn is_unsynced_selection(&self) -> bool {
+        let id = match self.state.ui.selected_tab {
+            DashboardTab::Expenses => self
+                .state
+                .ui
+                .expense_table
+                .selected()
+                .and_then(|idx| self.state.filtered_expenses().get(idx).map(|e| e.id)),
+            DashboardTab::Income => self
+                .state
+                .ui
+                .income_table
+                .selected()
+                .and_then(|idx| self.state.filtered_incomes().get(idx).map(|i| i.id)),
+            _ => None,
+        };
+        id.is_some_and(|id| id < 0)
     }
 }
@@ -0,0 +1,80 @@
+//! The changelog shipped with the app, for the "What's new" screen
+//!
+//! `CHANGELOG.md` is embedded at build time. After an upgrade the releases
+//! since the last version this machine ran are shown once; `w` in the help
+//! brings them back.
+
+/// Release notes, newest first
+pub const CHANGELOG: &str = include_str!("../CHANGELOG.md");
+
+/// One release and its changes
+#[derive(Debug, Clone, PartialEq)]
+pub struct Release {
+    pub version: String,
+    pub changes: Vec<String>,
+}
+
+/// Releases in a changelog, in the order listed
+///
+/// A release starts with `## <version>` (a leading `v` is dropped); its
+/// changes are `- ` bullets, continued on indented lines. Anything else is
+/// ignored.
+pub fn parse(text: &str) -> Vec<Release> {
+    let mut releases: Vec<Release> = Vec::new();
+    for line in text.lines() {
+        if let Some(heading) = line.strip_prefix("## ") {
+            let version = heading.split_whitespace().next().unwrap_or_default();
+            releases.push(Release {
+                version: version.trim_start_matches('v').to_string(),
+                changes: Vec::new(),
+            });
+            continue;
+        }
+        let release = match releases.last_mut() {
+            Some(release) => release,
+            None => continue,
+        };
+        if let Some(change) = line.strip_prefix("- ") {
+            release.changes.push(change.trim().to_string());
+        } else if line.starts_with(' ') && !line.trim().is_empty() {
+            if let Some(change) = release.changes.last_mut() {
+                change.push(' ');
+                change.push_str(line.trim());
+            }
+        }
+    }
+    releases
+}
+
+/// Releases after `last_seen`, up to and including `current`
+///
+/// Nothing is new on a first run (no `last_seen`) or when `last_seen` isn't a
+/// version; a downgrade shows nothing either.
+pub fn releases_since(text: &str, last_seen: Option<&str>, current: &str) -> Vec<Release> {
+    let (last_seen, current) = match (last_seen.and_then(version_key), version_key(current)) {
+        (Some(last_seen), Some(current)) => (last_seen, current),
+        _ => return Vec::new(),
+    };
+    parse(text)
+        .into_iter()
+        .filter(|release| {
+            version_key(&release.version).is_some_and(|v| last_seen < v && v <= current)
+        })
+        .collect()
+}
+
+/// The release notes of `current` alone, for viewing them again
+pub fn release(text: &str, current: &str) -> Option<Release> {
+    let current = current.trim().trim_start_matches('v');
+    parse(text).into_iter().find(|r| r.version == current)
+}
+
+/// Numeric parts of a version, e.g. "1.0.11" is [1, 0, 11]
+fn version_key(version: &str) -> Option<Vec<u64>> {
+    version
+        .trim()
+        .trim_start_matches('v')
+        .split('.')
+        .map(|part| part.parse().ok())
+        .collect()
+}
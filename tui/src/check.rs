@@ -0,0 +1,358 @@
+//! Step-by-step check of the configured server, for `--check-server`
+//!
+//! Each step builds on the one before: the URL has to parse before its host
+//! can be looked up, and so on. A failed step skips the ones after it, so the
+//! first failure is the one to fix.
+
+use std::time::Duration;
+
+use reqwest::{Method, StatusCode, Url};
+use tokio::net::{lookup_host, TcpStream};
+
+use crate::api::ClientOptions;
+
+/// Endpoints the app can't work without, relative to `/api/v1`
+pub const REQUIRED_ENDPOINTS: &[&str] = &[
+    "/months",
+    "/categories",
+    "/periods",
+    "/income-types",
+    "/expenses",
+    "/incomes",
+    "/summary/totals",
+];
+
+const CONNECT_TIMEOUT: Duration = Duration::from_secs(5);
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum CheckStatus {
+    Pass,
+    /// Works, but worth a look
+    Warn,
+    Fail,
+    /// Not run, because an earlier step failed or it doesn't apply
+    Skip,
+}
+
+impl CheckStatus {
+    pub fn label(&self) -> &'static str {
+        match self {
+            CheckStatus::Pass => "PASS",
+            CheckStatus::Warn => "WARN",
+            CheckStatus::Fail => "FAIL",
+            CheckStatus::Skip => "SKIP",
+        }
+    }
+}
+
+/// Outcome of one step
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct CheckResult {
+    pub name: &'static str,
+    pub status: CheckStatus,
+    pub detail: String,
+}
+
+/// All steps, in order
+const STEPS: &[&str] = &[
+    "URL",
+    "DNS",
+    "Connection",
+    "TLS",
+    "Health",
+    "API key",
+    "Auth endpoint",
+    "Endpoints",
+];
+
+/// Run every step against `url`, calling `report` as each one finishes
+///
+/// Requests go out with `options`, so the CA bundle and proxy settings are
+/// checked too.
+pub async fn check_server(
+    url: &str,
+    api_key: &str,
+    options: &ClientOptions,
+    mut report: impl FnMut(&CheckResult),
+) -> Vec<CheckResult> {
+    let mut results = Vec::new();
+    let outcome = run_steps(url, api_key, options, &mut |result| {
+        report(&result);
+        results.push(result);
+    })
+    .await;
+
+    if let Err(name) = outcome {
+        let first_skipped = STEPS.iter().position(|step| *step == name).unwrap_or(0) + 1;
+        for step in &STEPS[first_skipped..] {
+            let result = CheckResult {
+                name: step,
+                status: CheckStatus::Skip,
+                detail: format!("{} failed", name),
+            };
+            report(&result);
+            results.push(result);
+        }
+    }
+    results
+}
+
+/// The steps; stops with the name of the first one that failed
+async fn run_steps(
+    url: &str,
+    api_key: &str,
+    options: &ClientOptions,
+    report: &mut impl FnMut(CheckResult),
+) -> Result<(), &'static str> {
+    let mut step = |name: &'static str, status: CheckStatus, detail: String| {
+        report(CheckResult {
+            name,
+            status,
+            detail,
+        });
+        match status {
+            CheckStatus::Fail => Err(name),
+            _ => Ok(()),
+        }
+    };
+
+    // URL
+    let parsed = match Url::parse(url.trim()) {
+        Ok(parsed) if matches!(parsed.scheme(), "http" | "https") && parsed.host().is_some() => {
+            parsed
+        }
+        Ok(parsed) => {
+            return step(
+                "URL",
+                CheckStatus::Fail,
+                format!(
+                    "{} is not an http:// or https:// address with a host",
+                    parsed
+                ),
+            )
+        }
+        Err(e) => return step("URL", CheckStatus::Fail, format!("{}: {}", url, e)),
+    };
+    let base_url = url.trim().trim_end_matches('/').to_string();
+    let https = parsed.scheme() == "https";
+    if parsed.path().trim_end_matches('/').ends_with("/api/v1") {
+        step(
+            "URL",
+            CheckStatus::Warn,
+            "Ends in /api/v1, which the app adds itself; use the server's root".to_string(),
+        )?;
+    } else {
+        step("URL", CheckStatus::Pass, base_url.clone())?;
+    }
+
+    // DNS
+    let host = parsed.host_str().unwrap_or_default().to_string();
+    let port = parsed.port_or_known_default().unwrap_or(80);
+    let addresses: Vec<_> = match lookup_host((host.trim_matches(['[', ']']), port)).await {
+        Ok(addresses) => addresses.collect(),
+        Err(e) => {
+            return step(
+                "DNS",
+                CheckStatus::Fail,
+                format!("{} doesn't resolve: {}", host, e),
+            )
+        }
+    };
+    match addresses.first() {
+        Some(address) => step(
+            "DNS",
+            CheckStatus::Pass,
+            format!("{} resolves to {}", host, address.ip()),
+        )?,
+        None => {
+            return step(
+                "DNS",
+                CheckStatus::Fail,
+                format!("{} resolves to no address", host),
+            )
+        }
+    }
+
+    // Connection
+    match tokio::time::timeout(CONNECT_TIMEOUT, TcpStream::connect(&addresses[..])).await {
+        Ok(Ok(stream)) => {
+            let peer = stream
+                .peer_addr()
+                .map_or_else(|_| format!("port {}", port), |addr| addr.to_string());
+            step(
+                "Connection",
+                CheckStatus::Pass,
+                format!("Connected to {}", peer),
+            )?
+        }
+        Ok(Err(e)) => {
+            return step(
+                "Connection",
+                CheckStatus::Fail,
+                format!("Port {} refused or unreachable: {}", port, e),
+            )
+        }
+        Err(_) => {
+            return step(
+                "Connection",
+                CheckStatus::Fail,
+                format!(
+                    "No answer on port {} within {}s (firewall?)",
+                    port,
+                    CONNECT_TIMEOUT.as_secs()
+                ),
+            )
+        }
+    }
+
+    // TLS, with the first request
+    let client = match options.build_client() {
+        Ok(client) => client,
+        Err(e) => return step("TLS", CheckStatus::Fail, format!("{:#}", e)),
+    };
+    let send = |method: Method, path: &str, with_key: bool| {
+        let mut req = client.request(method, format!("{}{}", base_url, path));
+        if with_key {
+            req = req.header("X-API-Key", api_key);
+        }
+        req.header("Content-Type", "application/json").send()
+    };
+    let health = send(Method::GET, "/api/v1/health", false).await;
+    match (&health, https) {
+        (Err(e), true) => {
+            return step(
+                "TLS",
+                CheckStatus::Fail,
+                format!(
+                    "{} - set ca_bundle under [network] for an internal CA",
+                    error_chain(e)
+                ),
+            )
+        }
+        (_, true) if options.insecure_skip_verify => step(
+            "TLS",
+            CheckStatus::Warn,
+            "Certificate not checked (insecure_skip_verify = true)".to_string(),
+        )?,
+        (_, true) => step("TLS", CheckStatus::Pass, "Certificate accepted".to_string())?,
+        (_, false) => step(
+            "TLS",
+            CheckStatus::Warn,
+            "Plain HTTP - the API key and logins travel unencrypted".to_string(),
+        )?,
+    }
+
+    // Health
+    match health {
+        Ok(response) if response.status().is_success() => {
+            step("Health", CheckStatus::Pass, "Server is healthy".to_string())?
+        }
+        Ok(response) if response.status() == StatusCode::NOT_FOUND => {
+            return step(
+                "Health",
+                CheckStatus::Fail,
+                "No /api/v1/health here - is this the Budget server, or behind a path prefix?"
+                    .to_string(),
+            )
+        }
+        Ok(response) => {
+            return step(
+                "Health",
+                CheckStatus::Fail,
+                format!("Answered {}", response.status()),
+            )
+        }
+        Err(e) => return step("Health", CheckStatus::Fail, error_chain(&e)),
+    }
+
+    // API key: /auth/me turns away a wrong key with 403, a missing login with 401
+    let me = send(Method::GET, "/api/v1/auth/me", true).await;
+    let me_status = match me {
+        Ok(response) => response.status(),
+        Err(e) => return step("API key", CheckStatus::Fail, error_chain(&e)),
+    };
+    match me_status {
+        StatusCode::FORBIDDEN if api_key.is_empty() => {
+            return step(
+                "API key",
+                CheckStatus::Fail,
+                "No API key configured; set api_key under [server]".to_string(),
+            )
+        }
+        StatusCode::FORBIDDEN => {
+            return step(
+                "API key",
+                CheckStatus::Fail,
+                "Rejected - it must match the server's API_KEY".to_string(),
+            )
+        }
+        _ => step("API key", CheckStatus::Pass, "Accepted".to_string())?,
+    }
+
+    // Auth endpoint
+    let login = send(Method::POST, "/api/v1/auth/login", true).await;
+    match (me_status, login.map(|r| r.status())) {
+        (StatusCode::UNAUTHORIZED, Ok(status)) if status != StatusCode::NOT_FOUND => step(
+            "Auth endpoint",
+            CheckStatus::Pass,
+            "Login and session check answer".to_string(),
+        )?,
+        (_, Ok(StatusCode::NOT_FOUND)) | (StatusCode::NOT_FOUND, _) => {
+            return step(
+                "Auth endpoint",
+                CheckStatus::Fail,
+                "No /api/v1/auth/login or /auth/me".to_string(),
+            )
+        }
+        (status, Ok(_)) => step(
+            "Auth endpoint",
+            CheckStatus::Warn,
+            format!("Session check answered {} without a login", status),
+        )?,
+        (_, Err(e)) => return step("Auth endpoint", CheckStatus::Fail, error_chain(&e)),
+    }
+
+    // Required endpoints: anything but a 404 means the route exists
+    let mut missing = Vec::new();
+    for endpoint in REQUIRED_ENDPOINTS {
+        match send(Method::GET, &format!("/api/v1{}", endpoint), true).await {
+            Ok(response) if response.status() == StatusCode::NOT_FOUND => missing.push(*endpoint),
+            Ok(_) => {}
+            Err(e) => {
+                return step(
+                    "Endpoints",
+                    CheckStatus::Fail,
+                    format!("{}: {}", endpoint, error_chain(&e)),
+                )
+            }
+        }
+    }
+    if missing.is_empty() {
+        step(
+            "Endpoints",
+            CheckStatus::Pass,
+            format!("All {} present", REQUIRED_ENDPOINTS.len()),
+        )
+    } else {
+        step(
+            "Endpoints",
+            CheckStatus::Fail,
+            format!("Missing {} - update the server", missing.join(", ")),
+        )
+    }
+}
+
+/// An error with its causes, which hold the useful part for TLS failures
+fn error_chain(error: &(dyn std::error::Error + 'static)) -> String {
+    let mut message = error.to_string();
+    let mut source = error.source();
+    while let Some(cause) = source {
+        let text = cause.to_string();
+        if !message.contains(&text) {
+            message.push_str(": ");
+            message.push_str(&text);
+        }
+        source = cause.source();
+    }
+    message
+}
@@ -0,0 +1,86 @@
+//! The app's idea of "now"
+//!
+//! Everything that depends on the date - the current month, pacing markers,
+//! end-of-month suggestions, when an action was taken - asks a [`Clock`]
+//! instead of the system, so tests and demos can stop time. Setting
+//! `BUDGET_NOW=2024-03-28` (or `2024-03-28T17:30`) freezes it for a run.
+
+use anyhow::{anyhow, Result};
+use chrono::{Local, NaiveDate, NaiveDateTime, NaiveTime};
+
+/// Environment variable that freezes the clock, e.g. for a demo or screenshots
+pub const ENV_NOW: &str = "BUDGET_NOW";
+
+/// Date and time forms `BUDGET_NOW` takes besides a plain date
+const DATETIME_FORMATS: [&str; 4] = [
+    "%Y-%m-%dT%H:%M:%S",
+    "%Y-%m-%dT%H:%M",
+    "%Y-%m-%d %H:%M:%S",
+    "%Y-%m-%d %H:%M",
+];
+
+/// Source of the current date and time
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct Clock {
+    /// Fixed time, or `None` for the system clock
+    frozen: Option<NaiveDateTime>,
+}
+
+impl Clock {
+    /// The system clock, in local time
+    pub fn system() -> Self {
+        Self::default()
+    }
+
+    /// A clock stopped at `at`
+    pub fn frozen(at: NaiveDateTime) -> Self {
+        Self { frozen: Some(at) }
+    }
+
+    /// A clock stopped at midnight on `date`
+    pub fn frozen_on(date: NaiveDate) -> Self {
+        Self::frozen(date.and_time(NaiveTime::MIN))
+    }
+
+    /// Frozen at `BUDGET_NOW` when it is set, the system clock otherwise
+    pub fn from_env() -> Result<Self> {
+        match std::env::var(ENV_NOW) {
+            Ok(value) if !value.trim().is_empty() => Self::parse(&value).ok_or_else(|| {
+                anyhow!(
+                    "{} must be a date like 2024-03-28 or 2024-03-28T17:30, not '{}'",
+                    ENV_NOW,
+                    value
+                )
+            }),
+            _ => Ok(Self::system()),
+        }
+    }
+
+    /// A clock frozen at `2024-03-28`, `2024-03-28T17:30` or
+    /// `2024-03-28 17:30:00`
+    pub fn parse(value: &str) -> Option<Self> {
+        let value = value.trim();
+        if let Ok(date) = NaiveDate::parse_from_str(value, "%Y-%m-%d") {
+            return Some(Self::frozen_on(date));
+        }
+        DATETIME_FORMATS
+            .iter()
+            .find_map(|format| NaiveDateTime::parse_from_str(value, format).ok())
+            .map(Self::frozen)
+    }
+
+    /// Check if time is stopped
+    pub fn is_frozen(&self) -> bool {
+        self.frozen.is_some()
+    }
+
+    /// The current local date and time
+    pub fn now(&self) -> NaiveDateTime {
+        self.frozen.unwrap_or_else(|| Local::now().naive_local())
+    }
+
+    /// Today's local date
+    pub fn today(&self) -> NaiveDate {
+        self.now().date()
+    }
+}
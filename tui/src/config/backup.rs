@@ -0,0 +1,173 @@
+//! Config exports for other machines
+//!
+//! The whole config - every server profile and all settings - in one file
+//! for setting up another machine. Unlike a settings bundle it covers the
+//! servers too. With API keys and sessions it is only ever written
+//! encrypted: a standard [age](https://age-encryption.org) file under a
+//! passphrase, which `age -d` opens as well. Without them it is plain TOML
+//! that can be shared; importing it keeps the keys this machine already has
+//! for the same servers.
+
+use std::fs;
+use std::io::{Read, Write};
+
+use age::secrecy::Secret;
+use anyhow::{anyhow, bail, Context, Result};
+use chrono::{DateTime, Utc};
+
+use super::crypt::Passphrase;
+use super::keyring::Keyring;
+use super::{same_server, Config, CredentialStore};
+
+/// File a backup is exported to by default
+pub const BACKUP_FILE_NAME: &str = "budget-config.age";
+
+/// File a config without secrets is exported to by default
+pub const SHARED_FILE_NAME: &str = "budget-config.toml";
+
+/// Check if an exported file is an encrypted backup rather than plain TOML
+pub fn is_encrypted(data: &[u8]) -> bool {
+    data.starts_with(b"age-encryption.org/")
+}
+
+/// Encrypt `config` with its secrets, as the file would have them without a
+/// keyring
+pub fn export(config: &Config, passphrase: &Passphrase) -> Result<Vec<u8>> {
+    let plain =
+        toml::to_string_pretty(&config.as_saved()?).context("Failed to serialize config")?;
+
+    let encryptor = age::Encryptor::with_user_passphrase(Secret::new(passphrase.expose().into()));
+    let mut sealed = Vec::new();
+    let mut writer = encryptor
+        .wrap_output(&mut sealed)
+        .context("Failed to encrypt the backup")?;
+    writer.write_all(plain.as_bytes())?;
+    writer.finish().context("Failed to encrypt the backup")?;
+    Ok(sealed)
+}
+
+/// `config` without API keys or sessions, as plain TOML to share
+pub fn export_shared(config: &Config) -> Result<String> {
+    let mut config = config.as_saved()?;
+    config.server.api_key = String::new();
+    config.auth.token = None;
+    config.auth.expires_at = None;
+    config.auth.server_url = None;
+    for profile in config.profiles.servers.values_mut() {
+        profile.api_key = String::new();
+        profile.token = None;
+        profile.expires_at = None;
+    }
+    toml::to_string_pretty(&config).context("Failed to serialize config")
+}
+
+/// The config in an exported file without secrets, checked like a config
+/// file
+pub fn import_shared(text: &str) -> Result<Config> {
+    Ok(Config::parse(text)?.0)
+}
+
+/// The config in a backup, checked like a config file
+pub fn import(sealed: &[u8], passphrase: &Passphrase) -> Result<Config> {
+    let decryptor = match age::Decryptor::new(sealed).context("Not a config backup")? {
+        age::Decryptor::Passphrase(decryptor) => decryptor,
+        _ => bail!("The backup is encrypted to a key, not a passphrase"),
+    };
+    let mut reader = decryptor
+        .decrypt(&Secret::new(passphrase.expose().into()), None)
+        .map_err(|_| anyhow!("Wrong passphrase, or the backup is damaged"))?;
+    let mut plain = String::new();
+    reader
+        .read_to_string(&mut plain)
+        .map_err(|_| anyhow!("The backup is damaged"))?;
+    Ok(Config::parse(&plain)?.0)
+}
+
+/// Save `imported` as this machine's config, replacing `current`
+///
+/// The old file is kept next to it as `config.toml.bak`. Servers the import
+/// has no API key for keep the one `current` has for the same URL, with its
+/// session. The file stays encrypted if it was, and the secrets go to the
+/// keyring when there is one.
+pub fn install(mut imported: Config, current: Option<&Config>) -> Result<()> {
+    let path = Config::config_path()?;
+    if path.exists() {
+        fs::copy(&path, path.with_extension("toml.bak"))
+            .context("Failed to back up the config file")?;
+    }
+    if let Some(current) = current {
+        imported.keep_secrets(current);
+        imported.set_passphrase(current.passphrase.clone());
+    }
+    if imported.credentials.store == CredentialStore::Auto {
+        imported.set_keyring(Keyring::detect());
+    }
+    imported.save()
+}
+
+/// API key and session saved for a server
+struct Secrets {
+    url: String,
+    api_key: String,
+    token: Option<String>,
+    expires_at: Option<DateTime<Utc>>,
+}
+
+impl Config {
+    /// Every server's secrets, the active one first
+    fn secrets(&self) -> Vec<Secrets> {
+        let server = self.saved_server();
+        let mut secrets = vec![Secrets {
+            url: server.url.clone(),
+            api_key: server.api_key.clone(),
+            // A session from an env override's server isn't this one's
+            token: self
+                .auth
+                .token
+                .clone()
+                .filter(|_| self.auth.issued_by(&server.url)),
+            expires_at: self.auth.expires_at,
+        }];
+        secrets.extend(self.profiles.servers.values().map(|profile| Secrets {
+            url: profile.url.clone(),
+            api_key: profile.api_key.clone(),
+            token: profile.token.clone(),
+            expires_at: profile.expires_at,
+        }));
+        secrets
+    }
+
+    /// Fill in API keys left out of this config from `current`'s for the
+    /// same servers
+    fn keep_secrets(&mut self, current: &Config) {
+        let known = current.secrets();
+        let find = |url: &str| {
+            known
+                .iter()
+                .find(|secrets| same_server(&secrets.url, url) && !secrets.api_key.is_empty())
+        };
+
+        if self.server.api_key.is_empty() {
+            if let Some(secrets) = find(&self.server.url) {
+                self.server.api_key = secrets.api_key.clone();
+                if self.auth.token.is_none() && secrets.token.is_some() {
+                    self.auth.token = secrets.token.clone();
+                    self.auth.expires_at = secrets.expires_at;
+                    self.auth.server_url = Some(secrets.url.clone());
+                }
+            }
+        }
+        for profile in self.profiles.servers.values_mut() {
+            if !profile.api_key.is_empty() {
+                continue;
+            }
+            if let Some(secrets) = find(&profile.url) {
+                profile.api_key = secrets.api_key.clone();
+                if profile.token.is_none() {
+                    profile.token = secrets.token.clone();
+                    profile.expires_at = secrets.expires_at;
+                }
+            }
+        }
+    }
+}
@@ -0,0 +1,278 @@
+//! Settings bundle
+//!
+//! The server's categories, periods and income types, together with the
+//! app's look and local settings, in one TOML file. Importing it on a new
+//! machine or against a fresh server sets both up in one go. Server
+//! addresses, credentials and per-machine options (network, lock, keyring,
+//! Google Sheets) are never included.
+
+use std::collections::BTreeMap;
+
+use anyhow::{Context, Result};
+use serde::{Deserialize, Serialize};
+
+use super::{
+    AdvisorConfig, ChecklistConfig, ColorConfig, Config, DisplayConfig, EnvelopeConfig,
+    MonthsConfig, PeriodsConfig, TaxConfig, ThresholdConfig,
+};
+use crate::api::ApiClient;
+use crate::models::{Category, CategoryCreate, IncomeType, IncomeTypeCreate, Period, PeriodCreate};
+use crate::state::normalize_name;
+use crate::ui::theme::Theme;
+
+/// Bundle format written by this version; newer ones are refused
+pub const BUNDLE_VERSION: u32 = 1;
+
+/// File the bundle is exported to by default
+pub const BUNDLE_FILE_NAME: &str = "budget-settings.toml";
+
+/// Everything needed to set up the app the same way elsewhere
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct SettingsBundle {
+    pub version: u32,
+    #[serde(default)]
+    pub categories: Vec<NamedColor>,
+    #[serde(default)]
+    pub periods: Vec<NamedColor>,
+    #[serde(default)]
+    pub income_types: Vec<NamedColor>,
+    /// Local settings; sections left out keep their current values on import
+    #[serde(default)]
+    pub settings: BundleSettings,
+}
+
+/// A category, period or income type by name
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct NamedColor {
+    pub name: String,
+    /// Hex color; the server's default when left out
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub color: Option<String>,
+}
+
+/// Config sections carried in a bundle
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct BundleSettings {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub display: Option<DisplayConfig>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub colors: Option<ColorConfig>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub thresholds: Option<ThresholdConfig>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub checklist: Option<ChecklistConfig>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tax: Option<TaxConfig>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub envelopes: Option<EnvelopeConfig>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub advisor: Option<AdvisorConfig>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub periods: Option<PeriodsConfig>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub themes: Option<BTreeMap<String, Theme>>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub months: Option<MonthsConfig>,
+}
+
+/// What importing a bundle would add to the server
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct BundlePlan {
+    pub categories: Vec<NamedColor>,
+    pub periods: Vec<NamedColor>,
+    pub income_types: Vec<NamedColor>,
+    /// Items the server already has (by name, ignoring case and spacing)
+    pub existing: usize,
+}
+
+impl BundlePlan {
+    pub fn is_empty(&self) -> bool {
+        self.categories.is_empty() && self.periods.is_empty() && self.income_types.is_empty()
+    }
+}
+
+impl SettingsBundle {
+    /// Bundle of the server's reference data and `config`'s local settings
+    pub fn new(
+        config: &Config,
+        categories: &[Category],
+        periods: &[Period],
+        income_types: &[IncomeType],
+    ) -> Self {
+        let named = |name: &str, color: &str| NamedColor {
+            name: name.to_string(),
+            color: Some(color.to_string()),
+        };
+        Self {
+            version: BUNDLE_VERSION,
+            categories: categories
+                .iter()
+                .map(|c| named(&c.name, &c.color))
+                .collect(),
+            periods: periods.iter().map(|p| named(&p.name, &p.color)).collect(),
+            income_types: income_types
+                .iter()
+                .map(|t| named(&t.name, &t.color))
+                .collect(),
+            settings: BundleSettings {
+                display: Some(config.display.clone()),
+                colors: Some(config.colors.clone()),
+                thresholds: Some(config.thresholds.clone()),
+                checklist: Some(config.checklist.clone()),
+                tax: Some(config.tax.clone()),
+                envelopes: Some(config.envelopes.clone()),
+                advisor: Some(config.advisor.clone()),
+                periods: Some(config.periods.clone()),
+                themes: Some(config.themes.clone()),
+                months: Some(config.months.clone()),
+            },
+        }
+    }
+
+    pub fn parse(text: &str) -> Result<Self> {
+        let bundle: Self = toml::from_str(text).context("Not a settings bundle")?;
+        if bundle.version > BUNDLE_VERSION {
+            anyhow::bail!(
+                "Settings bundle version {} needs a newer budget-tui",
+                bundle.version
+            );
+        }
+        Ok(bundle)
+    }
+
+    pub fn to_toml(&self) -> Result<String> {
+        toml::to_string_pretty(self).context("Failed to serialize settings bundle")
+    }
+
+    /// Items the server doesn't have yet
+    pub fn plan(
+        &self,
+        categories: &[Category],
+        periods: &[Period],
+        income_types: &[IncomeType],
+    ) -> BundlePlan {
+        let mut plan = BundlePlan::default();
+        let mut missing = |items: &[NamedColor], names: Vec<&str>| {
+            let mut known: Vec<String> = names.into_iter().map(normalize_name).collect();
+            let mut added = Vec::new();
+            for item in items {
+                let name = normalize_name(&item.name);
+                if name.is_empty() {
+                    continue;
+                }
+                if known.contains(&name) {
+                    plan.existing += 1;
+                } else {
+                    known.push(name);
+                    added.push(item.clone());
+                }
+            }
+            added
+        };
+        let categories = missing(
+            &self.categories,
+            categories.iter().map(|c| c.name.as_str()).collect(),
+        );
+        let periods = missing(
+            &self.periods,
+            periods.iter().map(|p| p.name.as_str()).collect(),
+        );
+        let income_types = missing(
+            &self.income_types,
+            income_types.iter().map(|t| t.name.as_str()).collect(),
+        );
+        BundlePlan {
+            categories,
+            periods,
+            income_types,
+            ..plan
+        }
+    }
+
+    /// Replace `config`'s sections with the bundle's; returns the names of
+    /// those replaced
+    pub fn apply_settings(&self, config: &mut Config) -> Vec<&'static str> {
+        let settings = self.settings.clone();
+        let mut applied = Vec::new();
+        if let Some(display) = settings.display {
+            config.display = display;
+            applied.push("display");
+        }
+        if let Some(colors) = settings.colors {
+            config.colors = colors;
+            applied.push("colors");
+        }
+        if let Some(thresholds) = settings.thresholds {
+            config.thresholds = thresholds;
+            applied.push("thresholds");
+        }
+        if let Some(checklist) = settings.checklist {
+            config.checklist = checklist;
+            applied.push("checklist");
+        }
+        if let Some(tax) = settings.tax {
+            config.tax = tax;
+            applied.push("tax");
+        }
+        if let Some(envelopes) = settings.envelopes {
+            config.envelopes = envelopes;
+            applied.push("envelopes");
+        }
+        if let Some(advisor) = settings.advisor {
+            config.advisor = advisor;
+            applied.push("advisor");
+        }
+        if let Some(periods) = settings.periods {
+            config.periods = periods;
+            applied.push("periods");
+        }
+        if let Some(themes) = settings.themes {
+            config.themes = themes;
+            applied.push("themes");
+        }
+        if let Some(months) = settings.months {
+            config.months = months;
+            applied.push("months");
+        }
+        applied
+    }
+}
+
+/// Create the planned items on the server, returning how many were added
+///
+/// Stops at the first failure; items created before it stay, and running
+/// the import again skips them.
+pub async fn create_missing(api: &ApiClient, plan: &BundlePlan) -> Result<usize> {
+    let mut created = 0;
+    for item in &plan.categories {
+        api.categories()
+            .create(&CategoryCreate {
+                name: item.name.trim().to_string(),
+                color: item.color.clone(),
+            })
+            .await
+            .with_context(|| format!("Failed to create category '{}'", item.name))?;
+        created += 1;
+    }
+    for item in &plan.periods {
+        api.periods()
+            .create(&PeriodCreate {
+                name: item.name.trim().to_string(),
+                color: item.color.clone(),
+            })
+            .await
+            .with_context(|| format!("Failed to create period '{}'", item.name))?;
+        created += 1;
+    }
+    for item in &plan.income_types {
+        api.income_types()
+            .create(&IncomeTypeCreate {
+                name: item.name.trim().to_string(),
+                color: item.color.clone(),
+            })
+            .await
+            .with_context(|| format!("Failed to create income type '{}'", item.name))?;
+        created += 1;
+    }
+    Ok(created)
+}
@@ -0,0 +1,284 @@
+//! Config file encryption
+//!
+//! With a passphrase set, the whole config file - server, API keys, login
+//! tokens and settings - is stored encrypted with ChaCha20-Poly1305, under a
+//! key derived from the passphrase with PBKDF2-HMAC-SHA256. The passphrase is
+//! asked once per run (or read from `BUDGET_CONFIG_PASSPHRASE`) and kept in
+//! memory to write the file back; it is never saved anywhere.
+
+use std::fmt;
+use std::io::{self, IsTerminal, Write};
+use std::num::NonZeroU32;
+use std::sync::{Mutex, PoisonError};
+
+use anyhow::{anyhow, bail, Context, Result};
+use base64::engine::general_purpose::STANDARD;
+use base64::Engine;
+use crossterm::event::{self, Event, KeyCode, KeyEventKind, KeyModifiers};
+use crossterm::terminal::{disable_raw_mode, enable_raw_mode};
+use ring::aead::{Aad, LessSafeKey, Nonce, UnboundKey, CHACHA20_POLY1305, NONCE_LEN};
+use ring::pbkdf2;
+use ring::rand::{SecureRandom, SystemRandom};
+
+/// Environment variable holding the passphrase, for scripts and CI
+pub const ENV_PASSPHRASE: &str = "BUDGET_CONFIG_PASSPHRASE";
+
+/// First line of an encrypted file, also authenticated with the contents
+const HEADER: &str = "budget-tui encrypted config v1";
+
+/// PBKDF2 rounds for new files; stored in the file so it can be raised later
+const ITERATIONS: u32 = 600_000;
+
+/// Most rounds a file or hash may ask for, so a tampered one can't stall
+/// startup for hours
+const MAX_ITERATIONS: u32 = 10 * ITERATIONS;
+
+const SALT_LEN: usize = 16;
+
+/// Scheme named at the start of a stored passphrase hash
+const HASH_SCHEME: &str = "pbkdf2-sha256";
+
+const HASH_LEN: usize = 32;
+
+/// Wrong passphrases allowed at the prompt before giving up
+const ATTEMPTS: usize = 3;
+
+/// Passphrase that unlocked the file in this run
+static UNLOCKED: Mutex<Option<Passphrase>> = Mutex::new(None);
+
+/// A config passphrase, kept out of debug output
+#[derive(Clone, PartialEq, Eq)]
+pub struct Passphrase(String);
+
+impl Passphrase {
+    pub fn new(passphrase: impl Into<String>) -> Self {
+        Self(passphrase.into())
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.0.is_empty()
+    }
+
+    /// The passphrase itself, for handing to another cipher
+    pub(super) fn expose(&self) -> &str {
+        &self.0
+    }
+}
+
+impl fmt::Debug for Passphrase {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str("Passphrase(..)")
+    }
+}
+
+/// Check if a config file's contents are encrypted
+pub fn is_encrypted(text: &str) -> bool {
+    text.starts_with(HEADER)
+}
+
+/// Encrypt a config file's contents
+pub fn encrypt(plain: &str, passphrase: &Passphrase) -> Result<String> {
+    let rng = SystemRandom::new();
+    let mut salt = [0u8; SALT_LEN];
+    let mut nonce = [0u8; NONCE_LEN];
+    rng.fill(&mut salt)
+        .and_then(|_| rng.fill(&mut nonce))
+        .map_err(|_| anyhow!("No random source to encrypt the config"))?;
+
+    let key = derive_key(passphrase, &salt, ITERATIONS)?;
+    let mut sealed = plain.as_bytes().to_vec();
+    key.seal_in_place_append_tag(
+        Nonce::assume_unique_for_key(nonce),
+        Aad::from(HEADER.as_bytes()),
+        &mut sealed,
+    )
+    .map_err(|_| anyhow!("Failed to encrypt the config"))?;
+
+    Ok(format!(
+        "{HEADER}\n{ITERATIONS} {} {} {}\n",
+        STANDARD.encode(salt),
+        STANDARD.encode(nonce),
+        STANDARD.encode(sealed)
+    ))
+}
+
+/// Decrypt a config file's contents
+pub fn decrypt(text: &str, passphrase: &Passphrase) -> Result<String> {
+    let body = text
+        .strip_prefix(HEADER)
+        .context("The config file isn't encrypted")?;
+    let damaged = || anyhow!("The encrypted config file is damaged");
+    let fields: Vec<&str> = body.split_whitespace().collect();
+    let [iterations, salt, nonce, sealed] = fields[..] else {
+        return Err(damaged());
+    };
+    let iterations: u32 = iterations.parse().map_err(|_| damaged())?;
+    let salt = STANDARD.decode(salt).map_err(|_| damaged())?;
+    let nonce: [u8; NONCE_LEN] = STANDARD
+        .decode(nonce)
+        .ok()
+        .and_then(|nonce| nonce.try_into().ok())
+        .ok_or_else(damaged)?;
+    let mut sealed = STANDARD.decode(sealed).map_err(|_| damaged())?;
+
+    let key = derive_key(passphrase, &salt, iterations)?;
+    let plain = key
+        .open_in_place(
+            Nonce::assume_unique_for_key(nonce),
+            Aad::from(HEADER.as_bytes()),
+            &mut sealed,
+        )
+        .map_err(|_| anyhow!("Wrong passphrase, or the config file is damaged"))?;
+    String::from_utf8(plain.to_vec()).map_err(|_| damaged())
+}
+
+/// Hash a passphrase for storing, salted and stretched like the file key
+///
+/// The result reads `pbkdf2-sha256$<rounds>$<salt>$<hash>`, so checking it
+/// needs nothing else.
+pub fn hash_passphrase(passphrase: &str) -> Result<String> {
+    let mut salt = [0u8; SALT_LEN];
+    SystemRandom::new()
+        .fill(&mut salt)
+        .map_err(|_| anyhow!("No random source to hash the passphrase"))?;
+    let iterations = NonZeroU32::new(ITERATIONS).context("PBKDF2 needs at least one round")?;
+    let mut hash = [0u8; HASH_LEN];
+    pbkdf2::derive(
+        pbkdf2::PBKDF2_HMAC_SHA256,
+        iterations,
+        &salt,
+        passphrase.as_bytes(),
+        &mut hash,
+    );
+    Ok(format!(
+        "{HASH_SCHEME}${ITERATIONS}${}${}",
+        STANDARD.encode(salt),
+        STANDARD.encode(hash)
+    ))
+}
+
+/// Check a passphrase against a hash from [`hash_passphrase`]; a damaged
+/// hash matches nothing
+pub fn verify_passphrase(stored: &str, passphrase: &str) -> bool {
+    let fields: Vec<&str> = stored.split('$').collect();
+    let [HASH_SCHEME, iterations, salt, hash] = fields[..] else {
+        return false;
+    };
+    let Some(iterations) = iterations
+        .parse()
+        .ok()
+        .filter(|&rounds| rounds <= MAX_ITERATIONS)
+        .and_then(NonZeroU32::new)
+    else {
+        return false;
+    };
+    let (Ok(salt), Ok(hash)) = (STANDARD.decode(salt), STANDARD.decode(hash)) else {
+        return false;
+    };
+    pbkdf2::verify(
+        pbkdf2::PBKDF2_HMAC_SHA256,
+        iterations,
+        &salt,
+        passphrase.as_bytes(),
+        &hash,
+    )
+    .is_ok()
+}
+
+fn derive_key(passphrase: &Passphrase, salt: &[u8], iterations: u32) -> Result<LessSafeKey> {
+    if iterations > MAX_ITERATIONS {
+        bail!("The encrypted config file asks for too many PBKDF2 rounds ({iterations})");
+    }
+    let iterations = NonZeroU32::new(iterations)
+        .ok_or_else(|| anyhow!("The encrypted config file is damaged"))?;
+    let mut key = [0u8; 32];
+    pbkdf2::derive(
+        pbkdf2::PBKDF2_HMAC_SHA256,
+        iterations,
+        salt,
+        passphrase.0.as_bytes(),
+        &mut key,
+    );
+    let key = UnboundKey::new(&CHACHA20_POLY1305, &key)
+        .map_err(|_| anyhow!("Failed to derive the config key"))?;
+    Ok(LessSafeKey::new(key))
+}
+
+/// Decrypt `text`, asking for the passphrase unless this run already has it
+///
+/// `BUDGET_CONFIG_PASSPHRASE` is tried first; otherwise the terminal is asked
+/// up to three times.
+pub fn unlock(text: &str) -> Result<(Passphrase, String)> {
+    let mut unlocked = UNLOCKED.lock().unwrap_or_else(PoisonError::into_inner);
+    if let Some(passphrase) = unlocked.clone() {
+        let plain = decrypt(text, &passphrase)?;
+        return Ok((passphrase, plain));
+    }
+
+    if let Ok(value) = std::env::var(ENV_PASSPHRASE) {
+        let passphrase = Passphrase::new(value);
+        let plain = decrypt(text, &passphrase).context(format!("{ENV_PASSPHRASE} is wrong"))?;
+        *unlocked = Some(passphrase.clone());
+        return Ok((passphrase, plain));
+    }
+
+    let mut attempt = 1;
+    loop {
+        let passphrase = prompt("Config passphrase: ")?;
+        match decrypt(text, &passphrase) {
+            Ok(plain) => {
+                *unlocked = Some(passphrase.clone());
+                return Ok((passphrase, plain));
+            }
+            Err(err) if attempt < ATTEMPTS => {
+                eprintln!("{err}");
+                attempt += 1;
+            }
+            Err(err) => return Err(err),
+        }
+    }
+}
+
+/// Remember the passphrase a file was just encrypted with
+pub fn remember(passphrase: Option<&Passphrase>) {
+    *UNLOCKED.lock().unwrap_or_else(PoisonError::into_inner) = passphrase.cloned();
+}
+
+/// Read a passphrase from the terminal without echoing it
+pub fn prompt(label: &str) -> Result<Passphrase> {
+    if !io::stdin().is_terminal() {
+        bail!("The config file is encrypted; set {ENV_PASSPHRASE} to unlock it");
+    }
+    let mut stderr = io::stderr();
+    write!(stderr, "{label}")?;
+    stderr.flush()?;
+
+    enable_raw_mode()?;
+    let read = read_hidden();
+    disable_raw_mode()?;
+    writeln!(stderr)?;
+    read.map(Passphrase::new)
+}
+
+fn read_hidden() -> Result<String> {
+    let mut input = String::new();
+    loop {
+        if let Event::Key(key) = event::read()? {
+            if key.kind != KeyEventKind::Press {
+                continue;
+            }
+            match key.code {
+                KeyCode::Enter => return Ok(input),
+                KeyCode::Esc => bail!("Cancelled"),
+                KeyCode::Char('c') if key.modifiers.contains(KeyModifiers::CONTROL) => {
+                    bail!("Cancelled")
+                }
+                KeyCode::Backspace => {
+                    input.pop();
+                }
+                KeyCode::Char(c) => input.push(c),
+                _ => {}
+            }
+        }
+    }
+}
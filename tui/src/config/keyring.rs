@@ -0,0 +1,128 @@
+use std::io::Write;
+use std::process::{Command, Stdio};
+
+use anyhow::{bail, Context, Result};
+
+/// Service name the secrets are filed under in the keyring
+const SERVICE: &str = "budget-tui";
+
+/// Keyring entry for the server API key
+pub const API_KEY_ACCOUNT: &str = "api_key";
+/// Keyring entry for the saved session token
+pub const TOKEN_ACCOUNT: &str = "token";
+
+/// The system keyring, driven through the platform's command line tool
+///
+/// Windows Credential Manager has no tool that can read secrets back, so on
+/// Windows (and wherever no tool is installed) secrets stay in the config file.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Keyring {
+    /// macOS Keychain, via `security`
+    MacOs,
+    /// Secret Service (GNOME Keyring, KWallet), via `secret-tool` from libsecret
+    SecretService,
+}
+
+impl Keyring {
+    /// The keyring of this system, if one is available
+    pub fn detect() -> Option<Self> {
+        if cfg!(target_os = "macos") && on_path("security") {
+            Some(Keyring::MacOs)
+        } else if cfg!(unix)
+            && on_path("secret-tool")
+            && std::env::var_os("DBUS_SESSION_BUS_ADDRESS").is_some()
+        {
+            // Secret Service lives on the session bus; without one (SSH, cron)
+            // secret-tool would just fail
+            Some(Keyring::SecretService)
+        } else {
+            None
+        }
+    }
+
+    /// Read a secret, `None` if it isn't stored
+    pub fn get(self, account: &str) -> Result<Option<String>> {
+        let output = match self {
+            Keyring::MacOs => Command::new("security")
+                .args(["find-generic-password", "-s", SERVICE, "-a", account, "-w"])
+                .output(),
+            Keyring::SecretService => Command::new("secret-tool")
+                .args(["lookup", "service", SERVICE, "account", account])
+                .output(),
+        }
+        .context("Failed to run the keyring tool")?;
+
+        // Both tools exit with an error when nothing matches
+        if !output.status.success() {
+            return Ok(None);
+        }
+        let secret = String::from_utf8(output.stdout).context("Keyring secret is not UTF-8")?;
+        Ok(Some(secret.trim_end_matches('\n').to_string()))
+    }
+
+    /// Store a secret, replacing any previous value
+    ///
+    /// The secret is passed on stdin so it never shows up in the process list.
+    pub fn set(self, account: &str, secret: &str) -> Result<()> {
+        if secret.contains(['\n', '"', '\\']) {
+            bail!("Secret contains characters the keyring tool can't take");
+        }
+        let (mut command, input) = match self {
+            Keyring::MacOs => {
+                // `security -i` reads commands from stdin
+                let mut command = Command::new("security");
+                command.arg("-i");
+                let input =
+                    format!("add-generic-password -U -s {SERVICE} -a {account} -w \"{secret}\"\n");
+                (command, input)
+            }
+            Keyring::SecretService => {
+                let mut command = Command::new("secret-tool");
+                command.args([
+                    "store",
+                    "--label",
+                    &format!("Budget TUI {account}"),
+                    "service",
+                    SERVICE,
+                    "account",
+                    account,
+                ]);
+                (command, secret.to_string())
+            }
+        };
+
+        let mut child = command
+            .stdin(Stdio::piped())
+            .stdout(Stdio::null())
+            .stderr(Stdio::null())
+            .spawn()
+            .context("Failed to run the keyring tool")?;
+        if let Some(mut stdin) = child.stdin.take() {
+            stdin.write_all(input.as_bytes())?;
+        }
+        if !child.wait()?.success() {
+            bail!("Keyring tool failed to store the secret");
+        }
+        Ok(())
+    }
+
+    /// Remove a secret (not an error if it isn't stored)
+    pub fn delete(self, account: &str) -> Result<()> {
+        match self {
+            Keyring::MacOs => Command::new("security")
+                .args(["delete-generic-password", "-s", SERVICE, "-a", account])
+                .output(),
+            Keyring::SecretService => Command::new("secret-tool")
+                .args(["clear", "service", SERVICE, "account", account])
+                .output(),
+        }
+        .context("Failed to run the keyring tool")?;
+        Ok(())
+    }
+}
+
+/// Check if an executable is on `PATH`
+fn on_path(program: &str) -> bool {
+    std::env::var_os("PATH")
+        .is_some_and(|paths| std::env::split_paths(&paths).any(|dir| dir.join(program).is_file()))
+}
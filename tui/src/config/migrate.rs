@@ -0,0 +1,70 @@
+//! Config file versions
+//!
+//! The file carries a `version` so settings can be moved or renamed without
+//! breaking older files. Loading runs every step from the file's version up to
+//! [`CONFIG_VERSION`] on the raw TOML before it is read, and the upgraded file
+//! is written back with the original kept next to it.
+
+use anyhow::{bail, Result};
+use toml::{Table, Value};
+
+use super::{DEFAULT_API_KEY, DEFAULT_API_URL};
+
+/// Config format written by this version; newer ones are refused
+pub const CONFIG_VERSION: u32 = 1;
+
+/// Upgrade steps, by the version they start from
+const STEPS: [fn(&mut Table); CONFIG_VERSION as usize] = [v0_to_v1];
+
+/// Upgrade `table` to [`CONFIG_VERSION`], returning the version it had
+pub fn migrate(table: &mut Table) -> Result<u32> {
+    let version = match table.get("version") {
+        None => 0,
+        Some(Value::Integer(version)) => match u32::try_from(*version) {
+            Ok(version) => version,
+            Err(_) => bail!("Config version {} isn't valid", version),
+        },
+        Some(other) => bail!("Config version must be a number, not {}", other),
+    };
+    if version > CONFIG_VERSION {
+        bail!("Config file version {} needs a newer budget-tui", version);
+    }
+    for step in &STEPS[version as usize..] {
+        step(table);
+    }
+    table.insert("version".to_string(), Value::from(CONFIG_VERSION as i64));
+    Ok(version)
+}
+
+/// Unversioned files: the first releases kept `url`, `api_key` (or `key`) and
+/// `token` at the top instead of in `[server]` and `[auth]`
+fn v0_to_v1(table: &mut Table) {
+    let mut server = match table.remove("server") {
+        Some(Value::Table(server)) => server,
+        _ => Table::new(),
+    };
+    for (old, new) in [
+        ("url", "url"),
+        ("api_url", "url"),
+        ("api_key", "api_key"),
+        ("key", "api_key"),
+    ] {
+        if let Some(value) = table.remove(old) {
+            server.entry(new).or_insert(value);
+        }
+    }
+    server.entry("url").or_insert(Value::from(DEFAULT_API_URL));
+    server
+        .entry("api_key")
+        .or_insert(Value::from(DEFAULT_API_KEY));
+    table.insert("server".to_string(), Value::Table(server));
+
+    if let Some(token) = table.remove("token") {
+        let auth = table
+            .entry("auth")
+            .or_insert_with(|| Value::Table(Table::new()));
+        if let Value::Table(auth) = auth {
+            auth.entry("token").or_insert(token);
+        }
+    }
+}
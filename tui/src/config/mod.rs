@@ -1,15 +1,98 @@
+use std::collections::BTreeMap;
 use std::fs;
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
+use std::sync::OnceLock;
+use std::time::Duration;
 
 use anyhow::{Context, Result};
+use base64::engine::general_purpose::URL_SAFE_NO_PAD;
+use base64::Engine;
+use chrono::{DateTime, Utc, Weekday};
+use ring::digest;
 use serde::{Deserialize, Serialize};
 
+use crate::api::{ClientOptions, RetryPolicy, StaticHeaders};
+use crate::import::parse_month;
+use crate::models::BudgetThresholds;
+use crate::state::DashboardTab;
+use crate::ui::dates::DateFormat;
+use crate::ui::low_color;
+use crate::ui::money::MoneyFormat;
+use crate::ui::theme::{ColorMap, Theme, BUILTIN_THEMES};
+
+pub mod backup;
+pub mod bundle;
+pub mod crypt;
+pub mod keyring;
+pub mod migrate;
+pub mod validate;
+
+use crypt::Passphrase;
+use keyring::{Keyring, API_KEY_ACCOUNT, TOKEN_ACCOUNT};
+use migrate::CONFIG_VERSION;
+use validate::Problem;
+
 /// Application configuration
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct Config {
+    /// Format of the file, upgraded on load (see [`migrate`])
+    #[serde(default)]
+    pub version: u32,
     pub server: ServerConfig,
     #[serde(default)]
     pub auth: AuthConfig,
+    #[serde(default)]
+    pub checklist: ChecklistConfig,
+    #[serde(default)]
+    pub tax: TaxConfig,
+    #[serde(default)]
+    pub thresholds: ThresholdConfig,
+    #[serde(default)]
+    pub colors: ColorConfig,
+    #[serde(default)]
+    pub network: NetworkConfig,
+    #[serde(default)]
+    pub lock: LockConfig,
+    #[serde(default)]
+    pub display: DisplayConfig,
+    #[serde(default)]
+    pub credentials: CredentialsConfig,
+    #[serde(default)]
+    pub google_sheets: GoogleSheetsConfig,
+    #[serde(default)]
+    pub months: MonthsConfig,
+    #[serde(default)]
+    pub startup: StartupConfig,
+    #[serde(default)]
+    pub envelopes: EnvelopeConfig,
+    #[serde(default)]
+    pub advisor: AdvisorConfig,
+    #[serde(default)]
+    pub periods: PeriodsConfig,
+    #[serde(default)]
+    pub notify: NotifyConfig,
+    #[serde(default)]
+    pub currency: CurrencyConfig,
+    /// Color themes by name, picked with `theme` in `[display]`
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub themes: BTreeMap<String, Theme>,
+    #[serde(default)]
+    pub profiles: ProfilesConfig,
+    /// Server settings from the config file while env overrides replace them
+    #[serde(skip)]
+    file_server: Option<ServerConfig>,
+    /// Profile active in the file while `--profile` picks another for this run
+    #[serde(skip)]
+    file_profile: Option<String>,
+    /// Keyring holding the API key and token instead of the file
+    #[serde(skip)]
+    keyring: Option<Keyring>,
+    /// Passphrase the file is encrypted with
+    #[serde(skip)]
+    passphrase: Option<Passphrase>,
+    /// Mistakes found in the file when it was read
+    #[serde(skip)]
+    problems: Vec<Problem>,
 }
 
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -21,8 +104,956 @@ pub struct ServerConfig {
 #[derive(Debug, Clone, Default, Serialize, Deserialize)]
 pub struct AuthConfig {
     pub token: Option<String>,
+    /// When the saved token stops working, read from its `exp` claim
+    #[serde(default)]
+    pub expires_at: Option<DateTime<Utc>>,
+    /// Server that issued the token; tokens saved before it was recorded
+    /// belong to the file's server
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub server_url: Option<String>,
+    /// Start with a device login (approved in a browser) instead of the
+    /// password form, for servers behind single sign-on
+    #[serde(default)]
+    pub device_login: bool,
+}
+
+impl AuthConfig {
+    /// The saved token, unless it has expired
+    pub fn valid_token(&self) -> Option<&str> {
+        let expired = self
+            .expires_at
+            .is_some_and(|expires_at| expires_at <= Utc::now());
+        self.token.as_deref().filter(|_| !expired)
+    }
+
+    /// Check if the token was issued by `url`; one without a recorded server
+    /// counts as issued by any
+    pub fn issued_by(&self, url: &str) -> bool {
+        self.server_url
+            .as_deref()
+            .map_or(true, |issuer| same_server(issuer, url))
+    }
+}
+
+/// Check if two server URLs point to the same server, ignoring a trailing `/`
+fn same_server(a: &str, b: &str) -> bool {
+    a.trim_end_matches('/') == b.trim_end_matches('/')
+}
+
+/// Named servers to switch between, e.g. "home" and "demo"
+///
+/// `[server]` and `[auth]` hold the active profile; the others wait here with
+/// their own API keys and sessions.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct ProfilesConfig {
+    /// Name of the profile in `[server]` and `[auth]`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub active: Option<String>,
+    /// The other profiles, by name
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub servers: BTreeMap<String, ServerProfile>,
+    /// Look of each profile, active or not, by name
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub themes: BTreeMap<String, ProfileTheme>,
+}
+
+impl ProfilesConfig {
+    /// Theme of the active profile, if it has one
+    pub fn active_theme(&self) -> Option<&ProfileTheme> {
+        self.themes.get(self.active.as_deref()?)
+    }
+}
+
+/// A server with its key and session, while another profile is active
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct ServerProfile {
+    pub url: String,
+    #[serde(default)]
+    pub api_key: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub token: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub expires_at: Option<DateTime<Utc>>,
+}
+
+/// How a profile looks, so a test server isn't mistaken for the real one
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ProfileTheme {
+    /// Hex color of the profile's name and the header line on the dashboard,
+    /// e.g. `#ef4444`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub accent: Option<String>,
+}
+
+/// Name the unnamed server gets once a second profile is added
+pub const DEFAULT_PROFILE: &str = "default";
+
+/// Expiry of a JWT, from the `exp` claim of its payload
+///
+/// The signature isn't checked; this is only used to avoid sending a token the
+/// server is known to reject.
+pub fn token_expiry(token: &str) -> Option<DateTime<Utc>> {
+    let payload = token.split('.').nth(1)?;
+    let bytes = URL_SAFE_NO_PAD.decode(payload.trim_end_matches('=')).ok()?;
+    let claims: serde_json::Value = serde_json::from_slice(&bytes).ok()?;
+    DateTime::from_timestamp(claims.get("exp")?.as_i64()?, 0)
+}
+
+/// Monthly routine shown in the checklist (x)
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ChecklistConfig {
+    pub items: Vec<String>,
+}
+
+impl Default for ChecklistConfig {
+    fn default() -> Self {
+        Self {
+            items: vec![
+                "Enter paychecks".to_string(),
+                "Reconcile credit card".to_string(),
+                "Clone to next month".to_string(),
+            ],
+        }
+    }
+}
+
+/// Tax flags that `t` cycles through on expenses and incomes
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct TaxConfig {
+    pub flags: Vec<String>,
+}
+
+impl Default for TaxConfig {
+    fn default() -> Self {
+        Self {
+            flags: vec![
+                "Deductible".to_string(),
+                "Medical".to_string(),
+                "Charity".to_string(),
+                "Taxable".to_string(),
+            ],
+        }
+    }
+}
+
+/// Google Sheet that `G` pushes the selected month's summary and expenses to
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct GoogleSheetsConfig {
+    /// ID from the sheet's URL (`docs.google.com/spreadsheets/d/<id>/edit`)
+    #[serde(default)]
+    pub spreadsheet_id: String,
+    /// Service account key file (JSON); the sheet must be shared with its email
+    #[serde(default)]
+    pub service_account: Option<PathBuf>,
+    /// Also push a month when it is closed
+    #[serde(default)]
+    pub push_on_close: bool,
+}
+
+impl GoogleSheetsConfig {
+    /// Check if a sheet and key file are set
+    pub fn is_configured(&self) -> bool {
+        !self.spreadsheet_id.trim().is_empty() && self.service_account.is_some()
+    }
+
+    /// Key file path with a leading `~/` expanded
+    pub fn service_account_path(&self) -> Option<PathBuf> {
+        self.service_account.as_deref().map(expand_home)
+    }
+}
+
+/// When spending shows as near or over budget, as a percent of the projection
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ThresholdConfig {
+    #[serde(default = "default_near_percent")]
+    pub near_percent: f64,
+    #[serde(default = "default_over_percent")]
+    pub over_percent: f64,
+    /// Mark the month's pace on progress bars and flag spending ahead of it
+    #[serde(default = "default_pace")]
+    pub pace: bool,
+    /// Overrides by category name (matched ignoring case)
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub categories: BTreeMap<String, CategoryThresholds>,
+}
+
+/// Thresholds for one category; unset ones fall back to the global ones
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct CategoryThresholds {
+    pub near_percent: Option<f64>,
+    pub over_percent: Option<f64>,
+}
+
+fn default_near_percent() -> f64 {
+    BudgetThresholds::default().near_percent
+}
+
+fn default_over_percent() -> f64 {
+    BudgetThresholds::default().over_percent
+}
+
+fn default_pace() -> bool {
+    true
+}
+
+impl Default for ThresholdConfig {
+    fn default() -> Self {
+        Self {
+            near_percent: default_near_percent(),
+            over_percent: default_over_percent(),
+            pace: default_pace(),
+            categories: BTreeMap::new(),
+        }
+    }
+}
+
+impl ThresholdConfig {
+    /// Thresholds for totals that span categories
+    pub fn global(&self) -> BudgetThresholds {
+        BudgetThresholds {
+            near_percent: self.near_percent,
+            over_percent: self.over_percent,
+        }
+    }
+
+    /// Thresholds for a category, with its overrides applied
+    pub fn for_category(&self, category: &str) -> BudgetThresholds {
+        let global = self.global();
+        let category = category.trim();
+        match self
+            .categories
+            .iter()
+            .find(|(name, _)| name.trim().eq_ignore_ascii_case(category))
+        {
+            Some((_, overrides)) => BudgetThresholds {
+                near_percent: overrides.near_percent.unwrap_or(global.near_percent),
+                over_percent: overrides.over_percent.unwrap_or(global.over_percent),
+            },
+            None => global,
+        }
+    }
+}
+
+/// How colors are generated when pressing `r` in an entity form
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct ColorConfig {
+    #[serde(default)]
+    pub mode: ColorMode,
+}
+
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum ColorMode {
+    /// Any random hue, retried if it clashes with an existing color
+    #[default]
+    Random,
+    /// Evenly spaced hues, picking the one farthest from existing colors
+    Palette,
+}
+
+/// Which month the dashboard opens on, and how new months start
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct MonthsConfig {
+    /// Open on the calendar month, even when it's closed, and offer to create
+    /// it when it's missing, also when a new month begins while running
+    #[serde(default)]
+    pub pin_current: bool,
+    /// Name of a month ("January 2025") whose expenses and incomes new months
+    /// can start from, instead of the previous month's
+    #[serde(default)]
+    pub template: Option<String>,
+    /// Months of spending autofill (A) averages each category's budget over
+    #[serde(default = "default_autofill_months")]
+    pub autofill_months: u32,
+    /// What autofilled budgets are rounded to, e.g. 10 for whole tens
+    #[serde(default = "default_autofill_round")]
+    pub autofill_round: f64,
+}
+
+impl Default for MonthsConfig {
+    fn default() -> Self {
+        Self {
+            pin_current: false,
+            template: None,
+            autofill_months: default_autofill_months(),
+            autofill_round: default_autofill_round(),
+        }
+    }
+}
+
+fn default_autofill_months() -> u32 {
+    3
+}
+
+fn default_autofill_round() -> f64 {
+    10.0
+}
+
+/// Where the dashboard opens, for landing straight where the daily work is
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct StartupConfig {
+    /// Tab to open on: "summary", "expenses", "income", "charts" or
+    /// "settings"
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tab: Option<String>,
+    /// Month to open on
+    #[serde(default)]
+    pub month: StartupMonth,
+    /// A specific month to open on instead, like "2024-03"; also
+    /// `BUDGET_DEFAULT_MONTH`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub default_month: Option<String>,
+    /// Period to filter by from the start, by name
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub period: Option<String>,
+    /// Category to filter by from the start, by name
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub category: Option<String>,
+    /// Open on the tab, month and filters the app was left on, where the
+    /// settings above don't pick one
+    #[serde(default = "default_remember")]
+    pub remember: bool,
+}
+
+fn default_remember() -> bool {
+    true
+}
+
+impl Default for StartupConfig {
+    fn default() -> Self {
+        Self {
+            tab: None,
+            month: StartupMonth::default(),
+            default_month: None,
+            period: None,
+            category: None,
+            remember: default_remember(),
+        }
+    }
+}
+
+impl StartupConfig {
+    /// The specific month to open on, from `BUDGET_DEFAULT_MONTH` or else
+    /// `default_month`, as (year, month)
+    pub fn opening_month(&self) -> Result<Option<(i32, i32)>> {
+        let (value, source) = match std::env::var(ENV_DEFAULT_MONTH) {
+            Ok(value) if !value.trim().is_empty() => (value, ENV_DEFAULT_MONTH),
+            _ => match self.default_month.as_deref().map(str::trim) {
+                Some(value) if !value.is_empty() => (value.to_string(), "default_month"),
+                _ => return Ok(None),
+            },
+        };
+        match parse_month(&value) {
+            Some(month) => Ok(Some(month)),
+            None => anyhow::bail!(
+                "{} must be a month like 2024-03, not '{}'",
+                source,
+                value.trim()
+            ),
+        }
+    }
+
+    /// The tab to open on, if one is set
+    pub fn tab(&self) -> Result<Option<DashboardTab>> {
+        let name = match self.tab.as_deref().map(str::trim) {
+            Some(name) if !name.is_empty() => name,
+            _ => return Ok(None),
+        };
+        match DashboardTab::from_name(name) {
+            Some(tab) => Ok(Some(tab)),
+            None => {
+                let names: Vec<String> = DashboardTab::all()
+                    .iter()
+                    .map(|tab| tab.as_str().to_lowercase())
+                    .collect();
+                anyhow::bail!("No tab named '{}' (expected {})", name, names.join(", "))
+            }
+        }
+    }
+}
+
+/// Which month the dashboard opens on
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum StartupMonth {
+    /// The server's current month, or the next open one if it's closed
+    #[default]
+    Current,
+    /// The month today falls in, when it exists
+    Calendar,
+    /// The newest month on the server
+    Latest,
+}
+
+/// Categories whose budget is split into weekly envelopes in the Summary
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct EnvelopeConfig {
+    /// Category names, matched ignoring case
+    #[serde(default)]
+    pub weekly: Vec<String>,
+}
+
+/// End-of-month suggestions in the Summary
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct AdvisorConfig {
+    /// Last days of a month the suggestions show in; 0 turns them off
+    #[serde(default = "default_advisor_days")]
+    pub days: u32,
+    /// Categories that used less than this percent of their budget are
+    /// suggested for a smaller one next month
+    #[serde(default = "default_under_percent")]
+    pub under_percent: f64,
+}
+
+fn default_advisor_days() -> u32 {
+    5
+}
+
+fn default_under_percent() -> f64 {
+    50.0
+}
+
+impl Default for AdvisorConfig {
+    fn default() -> Self {
+        Self {
+            days: default_advisor_days(),
+            under_percent: default_under_percent(),
+        }
+    }
+}
+
+/// How periods are ordered and named on screen, and when weeks start
+///
+/// Only the display changes: expenses and incomes keep the server's period
+/// names.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PeriodsConfig {
+    /// Period names in the order selectors, filters, groups and summaries list
+    /// them; periods not listed follow in the server's order
+    #[serde(default)]
+    pub order: Vec<String>,
+    /// Names to show instead of the server's, e.g. `"Fixed/1st Period" =
+    /// "Quincena 1"`
+    #[serde(default)]
+    pub labels: BTreeMap<String, String>,
+    /// First day of weekly envelopes, e.g. "Sun"
+    #[serde(default = "default_week_start")]
+    pub week_start: Weekday,
+}
+
+fn default_week_start() -> Weekday {
+    Weekday::Mon
+}
+
+impl PeriodsConfig {
+    /// Position of a period in `order`; unlisted periods come after the
+    /// listed ones
+    pub fn rank(&self, name: &str) -> usize {
+        self.order
+            .iter()
+            .position(|listed| listed.trim().eq_ignore_ascii_case(name))
+            .unwrap_or(self.order.len())
+    }
+
+    /// Name to show for a period, its label if it has one
+    pub fn label<'a>(&'a self, name: &'a str) -> &'a str {
+        self.labels
+            .iter()
+            .find(|(period, _)| period.trim().eq_ignore_ascii_case(name))
+            .map_or(name, |(_, label)| label.as_str())
+    }
+}
+
+impl Default for PeriodsConfig {
+    fn default() -> Self {
+        Self {
+            order: Vec::new(),
+            labels: BTreeMap::new(),
+            week_start: default_week_start(),
+        }
+    }
+}
+
+/// The budget's own currency and the rates other currencies convert at
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct CurrencyConfig {
+    /// Code of the currency amounts without one are in, e.g. "USD"
+    #[serde(default = "default_base_currency")]
+    pub base: String,
+    #[serde(default)]
+    pub source: RateSource,
+    /// Rates API for `source = "api"`, with `{base}` replaced by the base
+    /// code; it must answer like open.er-api.com, with units of each currency
+    /// per one of the base under `rates`
+    #[serde(default = "default_rates_url")]
+    pub api_url: String,
+    /// What one unit of each currency is worth in the base currency, e.g.
+    /// `EUR = 1.08`; with the API they fill in currencies it doesn't have
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub rates: BTreeMap<String, f64>,
+}
+
+fn default_base_currency() -> String {
+    "USD".to_string()
+}
+
+fn default_rates_url() -> String {
+    "https://open.er-api.com/v6/latest/{base}".to_string()
+}
+
+impl Default for CurrencyConfig {
+    fn default() -> Self {
+        Self {
+            base: default_base_currency(),
+            source: RateSource::default(),
+            api_url: default_rates_url(),
+            rates: BTreeMap::new(),
+        }
+    }
+}
+
+impl CurrencyConfig {
+    /// Rates API address for the base currency
+    pub fn rates_url(&self) -> String {
+        self.api_url
+            .replace("{base}", &self.base.trim().to_uppercase())
+    }
+}
+
+/// Where exchange rates come from
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum RateSource {
+    /// Only the `rates` in the config
+    #[default]
+    Manual,
+    /// Fetched from `api_url` once a session
+    Api,
+}
+
+/// How the app says a long task finished, for noticing it from another
+/// window or tmux pane
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct NotifyConfig {
+    /// Importing a CSV into a month
+    #[serde(default)]
+    pub import: Signal,
+    /// Exports: month CSV, year spreadsheet, tax report, Google Sheets
+    #[serde(default)]
+    pub export: Signal,
+    /// Writes to many rows: copying a month, merging, autofill
+    #[serde(default)]
+    pub bulk: Signal,
+    /// Quicker tasks finish without a signal
+    #[serde(default = "default_notify_min_seconds")]
+    pub min_seconds: u64,
+}
+
+fn default_notify_min_seconds() -> u64 {
+    5
+}
+
+impl Default for NotifyConfig {
+    fn default() -> Self {
+        Self {
+            import: Signal::default(),
+            export: Signal::default(),
+            bulk: Signal::default(),
+            min_seconds: default_notify_min_seconds(),
+        }
+    }
+}
+
+impl NotifyConfig {
+    /// Signal for a task of `kind` that ran for `elapsed`
+    pub fn signal(&self, kind: TaskKind, elapsed: Duration) -> Signal {
+        if elapsed < Duration::from_secs(self.min_seconds) {
+            return Signal::None;
+        }
+        match kind {
+            TaskKind::Import => self.import,
+            TaskKind::Export => self.export,
+            TaskKind::Bulk => self.bulk,
+        }
+    }
+}
+
+/// Ways to say a task finished
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum Signal {
+    #[default]
+    None,
+    /// The terminal bell, which tmux marks on the window
+    Bell,
+    /// Invert the screen for a moment
+    Flash,
+    /// A desktop notification through the terminal (OSC 9), with the result
+    Notify,
 }
 
+/// Kinds of long tasks, each with its own signal
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum TaskKind {
+    Import,
+    Export,
+    Bulk,
+}
+
+/// Terminal rendering options
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct DisplayConfig {
+    #[serde(default)]
+    pub colors: ColorSupport,
+    #[serde(default)]
+    pub money: MoneyFormat,
+    /// Order of day, month and year in dates
+    #[serde(default)]
+    pub dates: DateFormat,
+    /// Name of a theme in `[themes]` or a built-in one ("solarized",
+    /// "gruvbox"); the default colors when not set
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub theme: Option<String>,
+    /// Start in the large-text layout (two lines per row, bold amounts);
+    /// `z` toggles it while running
+    #[serde(default)]
+    pub large_text: bool,
+}
+
+/// Colors the terminal can show
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum ColorSupport {
+    /// Detect from `TERM`/`COLORTERM`
+    #[default]
+    Auto,
+    /// 256 colors or truecolor
+    Full,
+    /// Basic ANSI colors with ASCII borders, for SSH sessions and older terminals
+    Ansi16,
+}
+
+impl ColorSupport {
+    /// Check if rendering should fall back to ANSI-16 colors
+    pub fn is_limited(self) -> bool {
+        match self {
+            ColorSupport::Auto => low_color::is_limited_terminal(
+                std::env::var("TERM").ok().as_deref(),
+                std::env::var("COLORTERM").ok().as_deref(),
+            ),
+            ColorSupport::Full => false,
+            ColorSupport::Ansi16 => true,
+        }
+    }
+}
+
+/// Where the API key and session token are stored
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct CredentialsConfig {
+    #[serde(default)]
+    pub store: CredentialStore,
+}
+
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum CredentialStore {
+    /// The OS keyring when one is available, otherwise the config file
+    #[default]
+    Auto,
+    /// Always the config file
+    File,
+}
+
+/// HTTP behavior when talking to the server
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct NetworkConfig {
+    /// Retries for 5xx responses and dropped connections (0 disables)
+    #[serde(default = "default_max_retries")]
+    pub max_retries: u32,
+    /// Delay before the first retry in milliseconds, doubled for each retry
+    #[serde(default = "default_retry_delay_ms")]
+    pub retry_delay_ms: u64,
+    /// Log every request and response to `debug.log` (also `BUDGET_DEBUG=1`)
+    #[serde(default)]
+    pub debug: bool,
+    /// Limit for a whole request in seconds
+    #[serde(default = "default_timeout_secs")]
+    pub timeout_secs: u64,
+    /// PEM file with extra CA certificates, for servers behind an internal CA
+    #[serde(default)]
+    pub ca_bundle: Option<PathBuf>,
+    /// Skip certificate verification entirely (unsafe; for testing only)
+    #[serde(default)]
+    pub insecure_skip_verify: bool,
+    /// Proxy URL, or "none" to ignore `HTTP_PROXY`/`HTTPS_PROXY`
+    #[serde(default)]
+    pub proxy: Option<String>,
+    /// Hosts that bypass the proxy, comma separated
+    #[serde(default)]
+    pub no_proxy: Option<String>,
+    /// Expenses or incomes fetched per request; 0 fetches a month's at once
+    #[serde(default = "default_page_size")]
+    pub page_size: usize,
+    /// Follow the server's change feed to pick up other clients' edits
+    #[serde(default = "default_live_updates")]
+    pub live_updates: bool,
+    /// Extra headers sent with every request, e.g. for a gateway in front of
+    /// the server
+    #[serde(default)]
+    pub headers: BTreeMap<String, String>,
+}
+
+fn default_max_retries() -> u32 {
+    3
+}
+
+fn default_retry_delay_ms() -> u64 {
+    250
+}
+
+fn default_timeout_secs() -> u64 {
+    30
+}
+
+fn default_page_size() -> usize {
+    200
+}
+
+fn default_live_updates() -> bool {
+    true
+}
+
+impl Default for NetworkConfig {
+    fn default() -> Self {
+        Self {
+            max_retries: default_max_retries(),
+            retry_delay_ms: default_retry_delay_ms(),
+            debug: false,
+            timeout_secs: default_timeout_secs(),
+            ca_bundle: None,
+            insecure_skip_verify: false,
+            proxy: None,
+            no_proxy: None,
+            page_size: default_page_size(),
+            live_updates: default_live_updates(),
+            headers: BTreeMap::new(),
+        }
+    }
+}
+
+impl NetworkConfig {
+    /// Retry policy for the API client
+    pub fn retry_policy(&self) -> RetryPolicy {
+        RetryPolicy {
+            max_retries: self.max_retries,
+            base_delay: Duration::from_millis(self.retry_delay_ms),
+            ..RetryPolicy::default()
+        }
+    }
+
+    /// Timeout and TLS settings for the API client
+    ///
+    /// A leading `~/` in the CA bundle path is expanded to the home directory.
+    pub fn client_options(&self) -> ClientOptions {
+        ClientOptions {
+            // A zero timeout would fail every request
+            timeout: Duration::from_secs(self.timeout_secs.max(1)),
+            ca_bundle: self.ca_bundle.as_deref().map(expand_home),
+            insecure_skip_verify: self.insecure_skip_verify,
+            proxy: self.proxy.clone().filter(|proxy| !proxy.trim().is_empty()),
+            no_proxy: self.no_proxy.clone(),
+            client_info: format!("TUI/{}", crate::app::VERSION),
+        }
+    }
+
+    /// Hook adding the configured headers to requests
+    pub fn header_hook(&self) -> Result<StaticHeaders> {
+        StaticHeaders::new(
+            self.headers
+                .iter()
+                .map(|(name, value)| (name.as_str(), value.as_str())),
+        )
+        .context("Invalid header in [network.headers]")
+    }
+
+    /// Check if debug logging is on, in the config or with `BUDGET_DEBUG`
+    pub fn debug_enabled(&self) -> bool {
+        self.debug || is_truthy(std::env::var(ENV_DEBUG).ok().as_deref())
+    }
+}
+
+/// Move the config directory from `old` to `new`, with the local data and
+/// logs in it, unless `new` already exists; true if it was moved
+///
+/// Tried as a rename first and copied over when that fails, e.g. across
+/// file systems; a failed copy is removed so the next start tries again.
+pub fn migrate_config_dir(old: &Path, new: &Path) -> Result<bool> {
+    if old == new || !old.is_dir() || new.exists() {
+        return Ok(false);
+    }
+    if let Some(parent) = new.parent() {
+        fs::create_dir_all(parent).context("Failed to create config directory")?;
+    }
+    if fs::rename(old, new).is_err() {
+        if let Err(e) = copy_dir(old, new) {
+            // Half a copy would be taken for the config on the next start
+            let _ = fs::remove_dir_all(new);
+            return Err(e).with_context(|| {
+                format!(
+                    "Failed to move the config from {} to {}",
+                    old.display(),
+                    new.display()
+                )
+            });
+        }
+        fs::remove_dir_all(old).context("Failed to remove the old config directory")?;
+    }
+    Ok(true)
+}
+
+fn copy_dir(from: &Path, to: &Path) -> std::io::Result<()> {
+    fs::create_dir_all(to)?;
+    for entry in fs::read_dir(from)? {
+        let entry = entry?;
+        let target = to.join(entry.file_name());
+        if entry.file_type()?.is_dir() {
+            copy_dir(&entry.path(), &target)?;
+        } else {
+            fs::copy(entry.path(), target)?;
+        }
+    }
+    Ok(())
+}
+
+/// Expand a leading `~/` to the home directory
+fn expand_home(path: &Path) -> PathBuf {
+    match (path.strip_prefix("~"), std::env::var("HOME")) {
+        (Ok(rest), Ok(home)) => PathBuf::from(home).join(rest),
+        _ => path.to_path_buf(),
+    }
+}
+
+/// Check if an env flag is set to something like `1` or `true`
+pub fn is_truthy(value: Option<&str>) -> bool {
+    matches!(
+        value.map(|v| v.trim().to_lowercase()).as_deref(),
+        Some("1" | "true" | "yes" | "on")
+    )
+}
+
+/// Locks the server settings screen behind a passphrase
+///
+/// Meant for shared machines, so the server URL and key aren't changed by
+/// accident. Admins can still point the app elsewhere with `BUDGET_API_URL`
+/// and `BUDGET_API_KEY`.
+///
+/// With `idle_minutes` set, the same passphrase also unlocks the app after it
+/// locked itself for being left alone.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct LockConfig {
+    /// Salted PBKDF2 hash of the passphrase; unset leaves the settings open
+    pub passphrase_hash: Option<String>,
+    /// Unsalted SHA-256 from older versions, hex encoded; still accepted, and
+    /// replaced by `passphrase_hash` the next time the passphrase is entered
+    pub passphrase_sha256: Option<String>,
+    /// Lock the whole app after this many minutes without a key press, until
+    /// the passphrase is entered; 0 never does
+    #[serde(default)]
+    pub idle_minutes: u64,
+}
+
+impl LockConfig {
+    /// Idle time after which the app locks itself, with a passphrase to
+    /// unlock it
+    pub fn idle_timeout(&self) -> Option<Duration> {
+        (self.is_locked() && self.idle_minutes > 0)
+            .then(|| Duration::from_secs(self.idle_minutes * 60))
+    }
+
+    /// Check if the server settings are locked
+    pub fn is_locked(&self) -> bool {
+        self.passphrase_hash.is_some() || self.passphrase_sha256.is_some()
+    }
+
+    /// Check if the lock still has an unsalted hash from an older version
+    pub fn has_legacy_hash(&self) -> bool {
+        self.passphrase_hash.is_none() && self.passphrase_sha256.is_some()
+    }
+
+    /// Lock with a new passphrase, or remove the lock if it is empty
+    pub fn set_passphrase(&mut self, passphrase: &str) -> Result<()> {
+        self.passphrase_hash = if passphrase.is_empty() {
+            None
+        } else {
+            Some(crypt::hash_passphrase(passphrase)?)
+        };
+        self.passphrase_sha256 = None;
+        Ok(())
+    }
+
+    /// Check a passphrase against the lock (always true when unlocked)
+    pub fn verify(&self, passphrase: &str) -> bool {
+        match (&self.passphrase_hash, &self.passphrase_sha256) {
+            (Some(hash), _) => crypt::verify_passphrase(hash, passphrase),
+            (None, Some(hash)) => *hash == sha256_hex(passphrase),
+            (None, None) => true,
+        }
+    }
+}
+
+fn sha256_hex(value: &str) -> String {
+    digest::digest(&digest::SHA256, value.as_bytes())
+        .as_ref()
+        .iter()
+        .map(|b| format!("{:02x}", b))
+        .collect()
+}
+
+/// Environment variables that override the configured server
+pub const ENV_API_URL: &str = "BUDGET_API_URL";
+pub const ENV_API_KEY: &str = "BUDGET_API_KEY";
+/// Environment variable that turns on debug logging for a single run
+pub const ENV_DEBUG: &str = "BUDGET_DEBUG";
+/// Environment variable with a month to open on, like `2024-03`
+pub const ENV_DEFAULT_MONTH: &str = "BUDGET_DEFAULT_MONTH";
+
+/// Shell `export` lines that point another machine or a CI script at a server
+pub fn env_exports(url: &str, api_key: &str, mask_key: bool) -> Vec<String> {
+    let api_key = if mask_key {
+        mask_secret(api_key)
+    } else {
+        api_key.to_string()
+    };
+    vec![
+        format!("export {}={}", ENV_API_URL, shell_quote(url)),
+        format!("export {}={}", ENV_API_KEY, shell_quote(&api_key)),
+    ]
+}
+
+/// Keep the first few characters of a secret so it can still be recognized
+fn mask_secret(secret: &str) -> String {
+    let visible: String = secret.chars().take(4).collect();
+    if secret.chars().count() > 8 {
+        format!("{}****", visible)
+    } else {
+        "****".to_string()
+    }
+}
+
+/// Quote a value for POSIX shells
+fn shell_quote(value: &str) -> String {
+    format!("'{}'", value.replace('\'', "'\\''"))
+}
+
+/// Name of the app's directory in the platform config dir
+const CONFIG_DIR_NAME: &str = "budget-tui";
+
+/// Config file from `--config`, used instead of the one in the config dir
+static CONFIG_FILE: OnceLock<PathBuf> = OnceLock::new();
+
+/// Server URL and key from `--api-url`/`--api-key`
+static SERVER_FLAGS: OnceLock<(Option<String>, Option<String>)> = OnceLock::new();
+
 // Default values matching mobile app
 pub const DEFAULT_API_URL: &str = "https://budget.appz.wtf";
 pub const DEFAULT_API_KEY: &str = "your-secret-api-key-change-this";
@@ -30,72 +1061,629 @@ pub const DEFAULT_API_KEY: &str = "your-secret-api-key-change-this";
 impl Default for Config {
     fn default() -> Self {
         Self {
+            version: CONFIG_VERSION,
             server: ServerConfig {
                 url: DEFAULT_API_URL.to_string(),
                 api_key: DEFAULT_API_KEY.to_string(),
             },
             auth: AuthConfig::default(),
+            checklist: ChecklistConfig::default(),
+            tax: TaxConfig::default(),
+            thresholds: ThresholdConfig::default(),
+            colors: ColorConfig::default(),
+            network: NetworkConfig::default(),
+            lock: LockConfig::default(),
+            display: DisplayConfig::default(),
+            credentials: CredentialsConfig::default(),
+            google_sheets: GoogleSheetsConfig::default(),
+            months: MonthsConfig::default(),
+            startup: StartupConfig::default(),
+            envelopes: EnvelopeConfig::default(),
+            advisor: AdvisorConfig::default(),
+            periods: PeriodsConfig::default(),
+            notify: NotifyConfig::default(),
+            currency: CurrencyConfig::default(),
+            themes: BTreeMap::new(),
+            profiles: ProfilesConfig::default(),
+            file_server: None,
+            file_profile: None,
+            keyring: None,
+            passphrase: None,
+            problems: Vec::new(),
         }
     }
 }
 
 impl Config {
-    /// Get the config directory path (~/.config/budget-tui)
+    /// Get the config directory path
+    ///
+    /// `$XDG_CONFIG_HOME/budget-tui` (`~/.config/budget-tui` when unset) on
+    /// Linux, `~/Library/Application Support/budget-tui` on macOS and
+    /// `%APPDATA%\budget-tui` on Windows.
     pub fn config_dir() -> Result<PathBuf> {
-        let home = std::env::var("HOME").context("Could not get HOME directory")?;
-        Ok(PathBuf::from(home).join(".config").join("budget-tui"))
+        let dirs = directories::BaseDirs::new().context("Could not get HOME directory")?;
+        Ok(dirs.config_dir().join(CONFIG_DIR_NAME))
+    }
+
+    /// Where versions before platform config dirs kept everything
+    fn legacy_config_dir() -> Option<PathBuf> {
+        let home = std::env::var_os("HOME")?;
+        Some(PathBuf::from(home).join(".config").join(CONFIG_DIR_NAME))
     }
 
     /// Get the config file path
     pub fn config_path() -> Result<PathBuf> {
-        Ok(Self::config_dir()?.join("config.toml"))
+        match CONFIG_FILE.get() {
+            Some(path) => Ok(path.clone()),
+            None => Ok(Self::config_dir()?.join("config.toml")),
+        }
+    }
+
+    /// Read and write the config at `path` for this run (`--config`)
+    ///
+    /// Local data and logs stay in the config dir. Only the first call
+    /// counts.
+    pub fn use_path(path: PathBuf) {
+        let _ = CONFIG_FILE.set(expand_home(&path));
+    }
+
+    /// Use `url` and/or `api_key` for this run (`--api-url`/`--api-key`),
+    /// ahead of the env vars; never saved. Only the first call counts.
+    pub fn use_server(url: Option<String>, api_key: Option<String>) {
+        let _ = SERVER_FLAGS.set((url, api_key));
+    }
+
+    /// Get the debug log path
+    pub fn debug_log_path() -> Result<PathBuf> {
+        Ok(Self::config_dir()?.join("debug.log"))
+    }
+
+    /// Get the directory for local data (notes, checklist, etc.) of the configured server
+    pub fn data_dir(&self) -> Result<PathBuf> {
+        Ok(Self::config_dir()?.join("data").join(self.server_key()))
+    }
+
+    /// Filesystem-safe identifier for the configured server
+    fn server_key(&self) -> String {
+        let host = self
+            .server
+            .url
+            .split("://")
+            .last()
+            .unwrap_or_default()
+            .trim_end_matches('/');
+        host.chars()
+            .map(|c| if c.is_ascii_alphanumeric() { c } else { '_' })
+            .collect()
     }
 
     /// Load config from file, or create default if it doesn't exist
     pub fn load() -> Result<Self> {
+        Self::load_profile(None)
+    }
+
+    /// Load config from file on the profile `profile` (from `--profile`), or
+    /// the file's active one
+    pub fn load_profile(profile: Option<&str>) -> Result<Self> {
+        if let Some(legacy) = Self::legacy_config_dir() {
+            migrate_config_dir(&legacy, &Self::config_dir()?)?;
+        }
         let config_path = Self::config_path()?;
 
         if config_path.exists() {
             let content = fs::read_to_string(&config_path).context("Failed to read config file")?;
-            let config: Config = toml::from_str(&content).context("Failed to parse config file")?;
+            let (mut config, version) = if crypt::is_encrypted(&content) {
+                let (passphrase, plain) = crypt::unlock(&content)?;
+                let (mut config, version) = Config::parse(&plain)?;
+                config.passphrase = Some(passphrase);
+                (config, version)
+            } else {
+                Config::parse(&content)?
+            };
+            if version < CONFIG_VERSION {
+                // Keep the original next to the upgraded file
+                let backup = config_path.with_extension(format!("toml.v{}", version));
+                fs::write(&backup, &content).context("Failed to back up config file")?;
+                config.save()?;
+            }
+            config.load_secrets()?;
+            if let Some(profile) = profile {
+                config.use_profile(profile)?;
+            }
+            config.apply_env_overrides();
             Ok(config)
         } else {
-            let config = Config::default();
+            if let Some(profile) = profile {
+                anyhow::bail!("No profile named '{}'", profile);
+            }
+            let mut config = Config::default();
+            if config.credentials.store == CredentialStore::Auto {
+                config.set_keyring(Keyring::detect());
+            }
             config.save()?;
+            config.apply_env_overrides();
             Ok(config)
         }
     }
 
+    /// Read a config file of any version, upgrading it to the current one
+    ///
+    /// Returns the config and the version the file had. Settings that parse
+    /// but are wrong, like unknown keys or unreadable files, are kept in
+    /// [`Config::problems`].
+    pub fn parse(text: &str) -> Result<(Self, u32)> {
+        let mut table: toml::Table = toml::from_str(text).context("Failed to parse config file")?;
+        let version = migrate::migrate(&mut table)?;
+        let mut config =
+            Config::deserialize(table.clone()).context("Failed to parse config file")?;
+        config.problems = validate::check(&table, &config);
+        Ok((config, version))
+    }
+
+    /// Mistakes found in the file, empty when it's fine
+    pub fn problems(&self) -> &[Problem] {
+        &self.problems
+    }
+
+    /// Read the config file again after it was edited outside the app
+    pub fn reload(&self) -> Result<Self> {
+        let content =
+            fs::read_to_string(Self::config_path()?).context("Failed to read config file")?;
+        self.reload_from(&content)
+    }
+
+    /// The config in `content`, kept on this run's profile and env overrides
+    ///
+    /// Unlike loading, nothing is asked or written: an encrypted file must
+    /// open with this run's passphrase, and an older format is only upgraded
+    /// in memory.
+    pub fn reload_from(&self, content: &str) -> Result<Self> {
+        let mut config = if crypt::is_encrypted(content) {
+            let passphrase = self
+                .passphrase
+                .clone()
+                .context("The config file was encrypted; restart to unlock it")?;
+            let (mut config, _) = Config::parse(&crypt::decrypt(content, &passphrase)?)?;
+            config.passphrase = Some(passphrase);
+            config
+        } else {
+            Config::parse(content)?.0
+        };
+        if self.keyring.is_some() {
+            config.load_secrets()?;
+        }
+        if self.file_profile.is_some() {
+            if let Some(profile) = &self.profiles.active {
+                config.use_profile(profile)?;
+            }
+        }
+        config.apply_env_overrides();
+        Ok(config)
+    }
+
+    /// Ask for the passphrase of an encrypted config file, if it is one
+    ///
+    /// Called before the terminal is taken over, so the prompt shows; loading
+    /// the config afterwards reuses the passphrase.
+    pub fn unlock() -> Result<()> {
+        let config_path = Self::config_path()?;
+        if let Ok(content) = fs::read_to_string(config_path) {
+            if crypt::is_encrypted(&content) {
+                crypt::unlock(&content)?;
+            }
+        }
+        Ok(())
+    }
+
+    /// Encrypt the file with `passphrase` from now on, or store it in plain
+    /// text with `None`; takes effect on the next save
+    pub fn set_passphrase(&mut self, passphrase: Option<Passphrase>) {
+        crypt::remember(passphrase.as_ref());
+        self.passphrase = passphrase;
+    }
+
+    /// Check if the file is stored encrypted
+    pub fn is_encrypted(&self) -> bool {
+        self.passphrase.is_some()
+    }
+
+    /// Read the API key and token from the OS keyring, if there is one
+    ///
+    /// Secrets still in the file (from older versions, or edited in by hand)
+    /// are moved into the keyring. If the keyring can't be used, the file keeps
+    /// holding them as before.
+    fn load_secrets(&mut self) -> Result<()> {
+        if self.credentials.store == CredentialStore::File {
+            return Ok(());
+        }
+        let keyring = match Keyring::detect() {
+            Some(keyring) => keyring,
+            None => return Ok(()),
+        };
+
+        let moved = match self.sync_secrets(keyring) {
+            Ok(moved) => moved,
+            Err(_) => return Ok(()),
+        };
+
+        self.set_keyring(Some(keyring));
+        if moved {
+            // Rewrite the file without the secrets
+            self.save()?;
+        }
+        Ok(())
+    }
+
+    /// Fill in missing secrets from the keyring and store the ones in the file
+    ///
+    /// Returns whether any secret was moved out of the file.
+    fn sync_secrets(&mut self, keyring: Keyring) -> Result<bool> {
+        let mut moved = false;
+        if self.server.api_key.is_empty() {
+            self.server.api_key = keyring.get(API_KEY_ACCOUNT)?.unwrap_or_default();
+        } else {
+            keyring.set(API_KEY_ACCOUNT, &self.server.api_key)?;
+            moved = true;
+        }
+        match &self.auth.token {
+            Some(token) => {
+                keyring.set(TOKEN_ACCOUNT, token)?;
+                moved = true;
+            }
+            None => self.auth.token = keyring.get(TOKEN_ACCOUNT)?,
+        }
+        for (name, profile) in &mut self.profiles.servers {
+            let (key_account, token_account) = profile_accounts(name);
+            if profile.api_key.is_empty() {
+                profile.api_key = keyring.get(&key_account)?.unwrap_or_default();
+            } else {
+                keyring.set(&key_account, &profile.api_key)?;
+                moved = true;
+            }
+            match &profile.token {
+                Some(token) => {
+                    keyring.set(&token_account, token)?;
+                    moved = true;
+                }
+                None => profile.token = keyring.get(&token_account)?,
+            }
+        }
+        Ok(moved)
+    }
+
+    /// Keep the API key and token in a keyring (or back in the file with `None`)
+    pub fn set_keyring(&mut self, keyring: Option<Keyring>) {
+        self.keyring = keyring;
+    }
+
+    /// Keyring holding the secrets, if they aren't in the file
+    pub fn keyring(&self) -> Option<Keyring> {
+        self.keyring
+    }
+
+    /// Use the server from `--api-url`/`--api-key` or
+    /// `BUDGET_API_URL`/`BUDGET_API_KEY` when set
+    pub fn apply_env_overrides(&mut self) {
+        let (url, api_key) = SERVER_FLAGS.get().cloned().unwrap_or_default();
+        self.override_server(
+            url.or_else(|| std::env::var(ENV_API_URL).ok()),
+            api_key.or_else(|| std::env::var(ENV_API_KEY).ok()),
+        );
+    }
+
+    /// Replace the server URL and/or key for this session without saving them
+    pub fn override_server(&mut self, url: Option<String>, api_key: Option<String>) {
+        let url = url.filter(|url| !url.is_empty());
+        if url.is_none() && api_key.is_none() {
+            return;
+        }
+        if self.file_server.is_none() {
+            self.file_server = Some(self.server.clone());
+        }
+        if let Some(url) = url {
+            self.server.url = url;
+        }
+        if let Some(api_key) = api_key {
+            self.server.api_key = api_key;
+        }
+    }
+
+    /// Check if the server comes from environment variables instead of the file
+    pub fn has_env_override(&self) -> bool {
+        self.file_server.is_some()
+    }
+
+    /// Set the server URL and key, replacing any env override
+    pub fn set_server(&mut self, url: String, api_key: String) {
+        self.server = ServerConfig { url, api_key };
+        self.file_server = None;
+    }
+
     /// Save config to file
     pub fn save(&self) -> Result<()> {
         let config_path = Self::config_path()?;
-        let config_dir = Self::config_dir()?;
 
         // Create directory if it doesn't exist
-        if !config_dir.exists() {
-            fs::create_dir_all(&config_dir).context("Failed to create config directory")?;
+        if let Some(config_dir) = config_path.parent().filter(|dir| !dir.exists()) {
+            fs::create_dir_all(config_dir).context("Failed to create config directory")?;
+        }
+
+        let saved = self.as_saved()?;
+        if let Some(keyring) = saved.keyring {
+            saved
+                .save_secrets(keyring)
+                .context("Failed to store credentials in the OS keyring")?;
         }
 
-        let content = toml::to_string_pretty(self).context("Failed to serialize config")?;
+        let mut content = saved.to_toml()?;
+        if let Some(passphrase) = &self.passphrase {
+            content = crypt::encrypt(&content, passphrase)?;
+        }
         fs::write(&config_path, content).context("Failed to write config file")?;
 
         Ok(())
     }
 
-    /// Set the auth token and save
+    /// Write the API keys and tokens to the keyring
+    fn save_secrets(&self, keyring: Keyring) -> Result<()> {
+        keyring.set(API_KEY_ACCOUNT, &self.server.api_key)?;
+        match &self.auth.token {
+            Some(token) => keyring.set(TOKEN_ACCOUNT, token)?,
+            None => keyring.delete(TOKEN_ACCOUNT)?,
+        }
+        for (name, profile) in &self.profiles.servers {
+            let (key_account, token_account) = profile_accounts(name);
+            keyring.set(&key_account, &profile.api_key)?;
+            match &profile.token {
+                Some(token) => keyring.set(&token_account, token)?,
+                None => keyring.delete(&token_account)?,
+            }
+        }
+        Ok(())
+    }
+
+    /// Serialize the config as saved to the file
+    pub fn to_toml(&self) -> Result<String> {
+        let mut config = self.as_saved()?;
+        if self.keyring.is_some() {
+            config.server.api_key = String::new();
+            config.auth.token = None;
+            for profile in config.profiles.servers.values_mut() {
+                profile.api_key = String::new();
+                profile.token = None;
+            }
+        }
+        toml::to_string_pretty(&config).context("Failed to serialize config")
+    }
+
+    /// The config as the file keeps it
+    ///
+    /// Env overrides and `--profile` are per session: the file keeps its own
+    /// server settings and active profile, while the session's profile keeps
+    /// its login.
+    fn as_saved(&self) -> Result<Config> {
+        let mut config = Config {
+            server: self.saved_server().clone(),
+            file_server: None,
+            file_profile: None,
+            ..self.clone()
+        };
+        if let Some(name) = &self.file_profile {
+            config.switch_profile(name)?;
+        }
+        Ok(config)
+    }
+
+    /// Server settings as configured in the file, ignoring env overrides
+    fn saved_server(&self) -> &ServerConfig {
+        self.file_server.as_ref().unwrap_or(&self.server)
+    }
+
+    /// Set the auth token, its expiry and the server it is for, and save
     pub fn set_token(&mut self, token: String) -> Result<()> {
+        self.auth.expires_at = token_expiry(&token);
+        self.auth.server_url = Some(self.server.url.clone());
         self.auth.token = Some(token);
         self.save()
     }
 
     /// Clear the auth token and save
+    ///
+    /// Only the active profile's session ends; other profiles keep theirs.
     pub fn clear_token(&mut self) -> Result<()> {
         self.auth.token = None;
+        self.auth.expires_at = None;
+        self.auth.server_url = None;
         self.save()
     }
 
-    /// Check if user is authenticated (has token)
+    /// The saved token for the server in use, unless it has expired
+    ///
+    /// A token from another server - the file's, while `--api-url` or
+    /// `BUDGET_API_URL` points elsewhere - is never sent.
+    pub fn session_token(&self) -> Option<&str> {
+        let issuer = self
+            .auth
+            .server_url
+            .as_deref()
+            .unwrap_or(&self.saved_server().url);
+        self.auth
+            .valid_token()
+            .filter(|_| same_server(issuer, &self.server.url))
+    }
+
+    /// Check if the saved token has expired, rather than being for another
+    /// server or missing
+    pub fn token_expired(&self) -> bool {
+        self.auth.token.is_some() && self.auth.valid_token().is_none()
+    }
+
+    /// Check if user is authenticated (has a token for this server that
+    /// hasn't expired)
     pub fn is_authenticated(&self) -> bool {
-        self.auth.token.is_some()
+        self.session_token().is_some()
+    }
+
+    /// The selected theme, from `[themes]` or built in
+    pub fn theme(&self) -> Result<ColorMap> {
+        let name = match self.display.theme.as_deref().map(str::trim) {
+            Some(name) if !name.is_empty() => name,
+            _ => return Ok(ColorMap::default()),
+        };
+        let theme = match self.themes.get(name) {
+            Some(theme) => theme.clone(),
+            None => Theme::builtin(name).ok_or_else(|| {
+                let mut names: Vec<&str> = BUILTIN_THEMES.to_vec();
+                names.extend(self.themes.keys().map(String::as_str));
+                anyhow::anyhow!("No theme named '{}' (expected {})", name, names.join(", "))
+            })?,
+        };
+        theme
+            .color_map()
+            .with_context(|| format!("Invalid theme '{}'", name))
+    }
+
+    /// Names of all profiles, sorted, including the active one
+    pub fn profile_names(&self) -> Vec<String> {
+        let mut names: Vec<String> = self.profiles.servers.keys().cloned().collect();
+        if let Some(active) = &self.profiles.active {
+            names.push(active.clone());
+        }
+        names.sort();
+        names
+    }
+
+    /// The profile after the active one, wrapping around; `None` with a
+    /// single server
+    pub fn next_profile(&self) -> Option<String> {
+        let names = self.profile_names();
+        if self.profiles.servers.is_empty() {
+            return None;
+        }
+        let next = match &self.profiles.active {
+            Some(active) => names
+                .iter()
+                .position(|name| name == active)
+                .map_or(0, |i| (i + 1) % names.len()),
+            None => 0,
+        };
+        names.get(next).cloned()
+    }
+
+    /// Make `name` the active profile, keeping the current server and
+    /// session under the active profile's name
+    ///
+    /// Like `set_server`, this drops env overrides. The server that was in use
+    /// before profiles existed is kept as "default".
+    pub fn switch_profile(&mut self, name: &str) -> Result<()> {
+        let name = name.trim();
+        let target = match self.profiles.servers.remove(name) {
+            Some(target) => target,
+            None if self.profiles.active.as_deref() == Some(name) => return Ok(()),
+            None => anyhow::bail!("No profile named '{}'", name),
+        };
+        self.stash_active_profile();
+        self.server = ServerConfig {
+            url: target.url,
+            api_key: target.api_key,
+        };
+        self.file_server = None;
+        self.file_profile = None;
+        self.auth.server_url = target.token.as_ref().map(|_| self.server.url.clone());
+        self.auth.token = target.token;
+        self.auth.expires_at = target.expires_at;
+        self.profiles.active = Some(name.to_string());
+        Ok(())
+    }
+
+    /// Make `name` the active profile for this run only
+    ///
+    /// Saving keeps the file's active profile, so the next start without
+    /// `--profile` opens on it as before. Switching profiles in the app makes
+    /// the switch stick again.
+    pub fn use_profile(&mut self, name: &str) -> Result<()> {
+        let name = name.trim();
+        if self.profiles.active.as_deref() == Some(name) {
+            return Ok(());
+        }
+        let file_profile = self
+            .profiles
+            .active
+            .clone()
+            .unwrap_or_else(|| DEFAULT_PROFILE.to_string());
+        self.switch_profile(name)?;
+        self.file_profile = Some(file_profile);
+        Ok(())
     }
+
+    /// Save `url` and `api_key` as the profile `name` and make it active
+    ///
+    /// An existing profile of that name is updated, keeping its session if
+    /// the server stays the same; a new name adds a profile. Naming the
+    /// server in use before profiles existed just names it.
+    pub fn save_profile(&mut self, name: &str, url: String, api_key: String) {
+        let name = name.trim();
+        self.file_profile = None;
+        let names_current = self.profiles.active.is_none()
+            && !self.profiles.servers.contains_key(name)
+            && (self.saved_server().url == url || name == DEFAULT_PROFILE);
+        if name.is_empty() || names_current || self.profiles.active.as_deref() == Some(name) {
+            if self.saved_server().url != url {
+                self.auth.token = None;
+                self.auth.expires_at = None;
+                self.auth.server_url = None;
+            }
+            self.set_server(url, api_key);
+            if !name.is_empty() {
+                self.profiles.active = Some(name.to_string());
+            }
+            return;
+        }
+        let previous = self.profiles.servers.remove(name).unwrap_or_default();
+        self.stash_active_profile();
+        let keeps_session = previous.url == url && previous.token.is_some();
+        self.auth.server_url = Some(url.clone()).filter(|_| keeps_session);
+        self.set_server(url, api_key);
+        self.auth.token = previous.token.filter(|_| keeps_session);
+        self.auth.expires_at = previous.expires_at.filter(|_| keeps_session);
+        self.profiles.active = Some(name.to_string());
+    }
+
+    /// Move the active server and session into the saved profiles
+    ///
+    /// A token another server issued, e.g. while `--api-url` pointed
+    /// elsewhere, isn't kept for it.
+    fn stash_active_profile(&mut self) {
+        let server = self.saved_server().clone();
+        if server.url.is_empty() {
+            return;
+        }
+        if !self.auth.issued_by(&server.url) {
+            self.auth.token = None;
+            self.auth.expires_at = None;
+        }
+        self.auth.server_url = None;
+        let name = self
+            .profiles
+            .active
+            .clone()
+            .unwrap_or_else(|| DEFAULT_PROFILE.to_string());
+        self.profiles.servers.insert(
+            name,
+            ServerProfile {
+                url: server.url,
+                api_key: server.api_key,
+                token: self.auth.token.take(),
+                expires_at: self.auth.expires_at.take(),
+            },
+        );
+    }
+}
+
+/// Keyring entries for a profile's API key and token
+fn profile_accounts(name: &str) -> (String, String) {
+    (
+        format!("{}:{}", API_KEY_ACCOUNT, name),
+        format!("{}:{}", TOKEN_ACCOUNT, name),
+    )
 }
@@ -0,0 +1,250 @@
+//! Checking a config file for mistakes
+//!
+//! Reading the file fills in defaults for anything missing and skips keys it
+//! doesn't know, so a misspelled section or setting quietly does nothing.
+//! Validation reports those, along with URLs that won't parse and key files
+//! that can't be read, each by its place in the file. Loading keeps the
+//! problems for the app to show; `--check-config` prints them.
+
+use std::fmt;
+use std::fs;
+use std::path::Path;
+
+use reqwest::Url;
+use toml::{Table, Value};
+
+use super::{expand_home, Config, RateSource};
+use crate::integrations::ServiceAccount;
+use crate::state::currency::normalize_code;
+
+/// Something wrong in the config file
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Problem {
+    /// Dotted path of the setting, e.g. `network.ca_bundle`
+    pub key: String,
+    pub message: String,
+}
+
+impl Problem {
+    fn new(key: impl Into<String>, message: impl Into<String>) -> Self {
+        Self {
+            key: key.into(),
+            message: message.into(),
+        }
+    }
+}
+
+impl fmt::Display for Problem {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "{}: {}", self.key, self.message)
+    }
+}
+
+/// One line for the status bar about `problems`, or `None` without any
+pub fn summary(problems: &[Problem]) -> Option<String> {
+    let first = problems.first()?;
+    Some(match problems.len() {
+        1 => format!("Config: {}", first),
+        n => format!("Config: {} (and {} more; run --check-config)", first, n - 1),
+    })
+}
+
+/// Problems in a parsed config; `table` is the file as read (after upgrading
+/// its format), for finding keys that weren't used
+pub fn check(table: &Table, config: &Config) -> Vec<Problem> {
+    let mut problems = Vec::new();
+    // What the config writes back has every key it knows about
+    if let Ok(Value::Table(known)) = Value::try_from(config) {
+        unknown_keys(table, &known, "", &mut problems);
+    }
+    problems.extend(check_values(config));
+    problems
+}
+
+/// Report keys in `table` that `known` doesn't have, with the closest known
+/// one as a suggestion
+fn unknown_keys(table: &Table, known: &Table, prefix: &str, problems: &mut Vec<Problem>) {
+    for (key, value) in table {
+        let path = format!("{}{}", prefix, key);
+        match (value, known.get(key)) {
+            (Value::Table(table), Some(Value::Table(known))) => {
+                unknown_keys(table, known, &format!("{}.", path), problems);
+            }
+            (_, Some(_)) => {}
+            // An empty map serializes to nothing
+            (Value::Table(table), None) if table.is_empty() => {}
+            (_, None) => {
+                let message = match closest(key, known.keys()) {
+                    Some(near) => format!("Unknown setting; did you mean '{}'?", near),
+                    None => "Unknown setting; it is ignored".to_string(),
+                };
+                problems.push(Problem::new(path, message));
+            }
+        }
+    }
+}
+
+/// The name in `names` nearest to `key`, when it is a likely typo
+fn closest<'a>(key: &str, names: impl Iterator<Item = &'a String>) -> Option<&'a str> {
+    names
+        .map(|name| (edit_distance(key, name), name))
+        .filter(|(distance, _)| *distance <= 2)
+        .min_by_key(|(distance, _)| *distance)
+        .map(|(_, name)| name.as_str())
+}
+
+/// Levenshtein distance between `a` and `b`
+fn edit_distance(a: &str, b: &str) -> usize {
+    let b: Vec<char> = b.chars().collect();
+    let mut row: Vec<usize> = (0..=b.len()).collect();
+    for (i, ca) in a.chars().enumerate() {
+        let mut diagonal = row[0];
+        row[0] = i + 1;
+        for (j, cb) in b.iter().enumerate() {
+            let above = row[j + 1];
+            row[j + 1] = if ca == *cb {
+                diagonal
+            } else {
+                1 + diagonal.min(above).min(row[j])
+            };
+            diagonal = above;
+        }
+    }
+    row[b.len()]
+}
+
+/// Values that parse but can't work: URLs, files, names
+fn check_values(config: &Config) -> Vec<Problem> {
+    let mut problems = Vec::new();
+
+    problems.extend(check_url("server.url", &config.server.url));
+    for (name, profile) in &config.profiles.servers {
+        problems.extend(check_url(
+            &format!("profiles.servers.{}.url", name),
+            &profile.url,
+        ));
+    }
+
+    let network = &config.network;
+    if let Some(proxy) = network.proxy.as_deref().map(str::trim) {
+        if !proxy.is_empty() && !proxy.eq_ignore_ascii_case("none") {
+            if let Err(e) = Url::parse(proxy) {
+                problems.push(Problem::new(
+                    "network.proxy",
+                    format!(
+                        "'{}' isn't a URL ({}); use e.g. http://proxy:3128, or \"none\"",
+                        proxy, e
+                    ),
+                ));
+            }
+        }
+    }
+    if let Some(path) = &network.ca_bundle {
+        problems.extend(check_file("network.ca_bundle", path));
+    }
+    if let Err(e) = network.header_hook() {
+        problems.push(Problem::new("network.headers", format!("{:#}", e)));
+    }
+
+    if let Some(path) = &config.google_sheets.service_account {
+        let key = "google_sheets.service_account";
+        match check_file(key, path) {
+            Some(problem) => problems.push(problem),
+            None => {
+                if let Err(e) = ServiceAccount::load(&expand_home(path)) {
+                    problems.push(Problem::new(key, format!("{:#}", e)));
+                }
+            }
+        }
+    }
+
+    problems.extend(check_currency(config));
+
+    if let Err(e) = config.startup.tab() {
+        problems.push(Problem::new("startup.tab", e.to_string()));
+    }
+    if let Err(e) = config.startup.opening_month() {
+        problems.push(Problem::new("startup.default_month", e.to_string()));
+    }
+    if let Err(e) = config.theme() {
+        problems.push(Problem::new("display.theme", format!("{:#}", e)));
+    }
+    problems
+}
+
+/// Currency codes must be three letters and rates positive
+fn check_currency(config: &Config) -> Vec<Problem> {
+    let currency = &config.currency;
+    let mut problems = Vec::new();
+    if normalize_code(&currency.base).is_none() {
+        problems.push(Problem::new(
+            "currency.base",
+            format!(
+                "'{}' isn't a currency code; use three letters, like USD",
+                currency.base
+            ),
+        ));
+    }
+    for (code, rate) in &currency.rates {
+        let key = format!("currency.rates.{}", code);
+        if normalize_code(code).is_none() {
+            problems.push(Problem::new(
+                key,
+                "Not a currency code; use three letters, like EUR",
+            ));
+        } else if !rate.is_finite() || *rate <= 0.0 {
+            problems.push(Problem::new(
+                key,
+                format!("Rate {} is ignored; it must be above 0", rate),
+            ));
+        }
+    }
+    if currency.source == RateSource::Api {
+        let url = currency.rates_url();
+        match Url::parse(&url) {
+            Ok(parsed) if matches!(parsed.scheme(), "http" | "https") => {}
+            _ => problems.push(Problem::new(
+                "currency.api_url",
+                format!("'{}' isn't an http(s) URL", url),
+            )),
+        }
+    }
+    problems
+}
+
+/// A server address must be a full http(s) URL
+///
+/// An empty one is left for the server settings screen to ask for.
+fn check_url(key: &str, url: &str) -> Option<Problem> {
+    if url.trim().is_empty() {
+        return None;
+    }
+    let message = match Url::parse(url.trim()) {
+        Ok(parsed) if !matches!(parsed.scheme(), "http" | "https") => format!(
+            "'{}' uses {}; the server needs http:// or https://",
+            url,
+            parsed.scheme()
+        ),
+        Ok(parsed) if parsed.host_str().is_none() => {
+            format!("'{}' has no host name", url)
+        }
+        Ok(_) => return None,
+        Err(e) => format!(
+            "'{}' isn't a URL ({}); use the full address, like https://budget.example.com",
+            url, e
+        ),
+    };
+    Some(Problem::new(key, message))
+}
+
+/// A file the config points at must exist and be readable
+fn check_file(key: &str, path: &Path) -> Option<Problem> {
+    let expanded = expand_home(path);
+    match fs::File::open(&expanded) {
+        Ok(_) => None,
+        Err(e) => Some(Problem::new(
+            key,
+            format!("Can't read {} ({})", expanded.display(), e),
+        )),
+    }
+}
@@ -1,7 +1,19 @@
-use std::time::Duration;
+use std::fs;
+use std::path::{Path, PathBuf};
+use std::sync::mpsc::{self, Receiver, RecvTimeoutError, Sender};
+use std::sync::{Arc, Mutex};
+use std::thread;
+use std::time::{Duration, SystemTime};
 
 use anyhow::Result;
-use crossterm::event::{self, Event as CrosstermEvent, KeyEvent, MouseEvent};
+use crossterm::event::{
+    self, Event as CrosstermEvent, KeyCode, KeyEvent, KeyEventKind, KeyModifiers, MouseEvent,
+};
+
+use crate::api::RequestContext;
+
+/// How often a watched file is checked for changes
+const WATCH_INTERVAL: Duration = Duration::from_secs(1);
 
 /// Terminal event types
 #[derive(Clone, Debug)]
@@ -14,33 +26,117 @@ pub enum Event {
     Mouse(MouseEvent),
     /// Terminal resize
     Resize(u16, u16),
+    /// The config file changed on disk
+    ConfigChanged,
 }
 
 /// Handles terminal events
+///
+/// Events are read on a background thread so that keys are still seen while
+/// the app is waiting on the server. Esc and Ctrl+C cancel the request context
+/// set with `set_interrupt` before being delivered as normal key events.
 pub struct EventHandler {
     /// Event polling timeout
     tick_rate: Duration,
+    /// Events read by the input and watcher threads
+    receiver: Receiver<Result<Event, String>>,
+    /// Sender handed to watcher threads
+    sender: Sender<Result<Event, String>>,
+    /// Context cancelled by interrupt keys
+    interrupt: Arc<Mutex<Option<RequestContext>>>,
 }
 
 impl EventHandler {
     /// Create a new event handler with the given tick rate in milliseconds
     pub fn new(tick_rate_ms: u64) -> Self {
+        let (sender, receiver) = mpsc::channel();
+        let interrupt: Arc<Mutex<Option<RequestContext>>> = Arc::new(Mutex::new(None));
+
+        let thread_interrupt = Arc::clone(&interrupt);
+        let input = sender.clone();
+        thread::spawn(move || loop {
+            let event = match event::read() {
+                Ok(CrosstermEvent::Key(key)) => {
+                    if is_interrupt_key(&key) {
+                        if let Some(context) = thread_interrupt.lock().unwrap().as_ref() {
+                            context.cancel();
+                        }
+                    }
+                    Ok(Event::Key(key))
+                }
+                Ok(CrosstermEvent::Mouse(mouse)) => Ok(Event::Mouse(mouse)),
+                Ok(CrosstermEvent::Resize(width, height)) => Ok(Event::Resize(width, height)),
+                Ok(_) => continue,
+                Err(e) => Err(e.to_string()),
+            };
+            if input.send(event).is_err() {
+                break;
+            }
+        });
+
         Self {
             tick_rate: Duration::from_millis(tick_rate_ms),
+            receiver,
+            sender,
+            interrupt,
         }
     }
 
+    /// Deliver `Event::ConfigChanged` whenever the file at `path` changes
+    ///
+    /// The file is checked every second, and the event is sent once it has
+    /// stayed the same for a check, so an editor's save is seen once and
+    /// never half written.
+    pub fn watch_config(&self, path: PathBuf) {
+        let sender = self.sender.clone();
+        thread::spawn(move || {
+            let mut seen = file_stamp(&path);
+            let mut changed = false;
+            loop {
+                thread::sleep(WATCH_INTERVAL);
+                let stamp = file_stamp(&path);
+                if stamp != seen {
+                    seen = stamp;
+                    changed = true;
+                } else if changed {
+                    changed = false;
+                    if sender.send(Ok(Event::ConfigChanged)).is_err() {
+                        break;
+                    }
+                }
+            }
+        });
+    }
+
+    /// Set the request context cancelled when the user presses Esc or Ctrl+C
+    pub fn set_interrupt(&self, context: RequestContext) {
+        *self.interrupt.lock().unwrap() = Some(context);
+    }
+
     /// Poll for the next event
     pub fn next(&self) -> Result<Event> {
-        if event::poll(self.tick_rate)? {
-            match event::read()? {
-                CrosstermEvent::Key(key) => Ok(Event::Key(key)),
-                CrosstermEvent::Mouse(mouse) => Ok(Event::Mouse(mouse)),
-                CrosstermEvent::Resize(width, height) => Ok(Event::Resize(width, height)),
-                _ => Ok(Event::Tick),
+        match self.receiver.recv_timeout(self.tick_rate) {
+            Ok(Ok(event)) => Ok(event),
+            Ok(Err(e)) => Err(anyhow::anyhow!("Failed to read terminal event: {}", e)),
+            Err(RecvTimeoutError::Timeout) => Ok(Event::Tick),
+            Err(RecvTimeoutError::Disconnected) => {
+                Err(anyhow::anyhow!("Terminal input thread stopped"))
             }
-        } else {
-            Ok(Event::Tick)
         }
     }
 }
+
+/// Modification time and size of a file, to notice when it is rewritten
+fn file_stamp(path: &Path) -> Option<(SystemTime, u64)> {
+    let metadata = fs::metadata(path).ok()?;
+    Some((metadata.modified().ok()?, metadata.len()))
+}
+
+/// Keys that abort whatever the app is waiting on
+fn is_interrupt_key(key: &KeyEvent) -> bool {
+    if key.kind == KeyEventKind::Release {
+        return false;
+    }
+    key.code == KeyCode::Esc
+        || (key.code == KeyCode::Char('c') && key.modifiers.contains(KeyModifiers::CONTROL))
+}
@@ -0,0 +1,26 @@
+//! Reports written to files for use outside the app.
+
+mod tax;
+pub mod xlsx;
+mod year;
+
+pub use tax::{TaxReport, TaxReportRow};
+pub use year::{MonthTotals, YearReport};
+
+use crate::models::Month;
+
+/// File a month's CSV export is saved to, e.g. `budget-2024-03.csv`
+///
+/// The same name the server suggests for it.
+pub fn month_csv_file_name(month: &Month) -> String {
+    format!("budget-{}-{:02}.csv", month.year, month.month)
+}
+
+/// Quote a CSV field if it contains a separator, quote or line break
+pub fn csv_field(value: &str) -> String {
+    if value.contains([',', '"', '\n', '\r']) {
+        format!("\"{}\"", value.replace('"', "\"\""))
+    } else {
+        value.to_string()
+    }
+}
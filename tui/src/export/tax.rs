@@ -0,0 +1,137 @@
+use std::collections::BTreeMap;
+
+use crate::models::{Expense, Income, IncomeType, Money};
+use crate::storage::TaxFlags;
+
+use super::csv_field;
+
+/// Totals of one flag and category (or income type) over a year
+#[derive(Debug, Clone, PartialEq)]
+pub struct TaxReportRow {
+    /// "Expense" or "Income"
+    pub kind: &'static str,
+    pub flag: String,
+    /// Category for expenses, income type for incomes
+    pub group: String,
+    pub items: usize,
+    pub projected: f64,
+    pub actual: f64,
+}
+
+/// Annual totals of tax-flagged expenses and incomes, for an accountant
+#[derive(Debug, Clone, PartialEq)]
+pub struct TaxReport {
+    pub year: i32,
+    /// Sorted by kind, flag and group; unflagged items are left out
+    pub rows: Vec<TaxReportRow>,
+}
+
+impl TaxReport {
+    /// Total the flagged items among a year's expenses and incomes
+    pub fn build(
+        year: i32,
+        expenses: &[Expense],
+        incomes: &[Income],
+        income_types: &[IncomeType],
+        flags: &TaxFlags,
+    ) -> Self {
+        let mut totals: BTreeMap<(&'static str, String, String), (usize, f64, f64)> =
+            BTreeMap::new();
+
+        for expense in expenses {
+            if let Some(flag) = flags.expense_flag(&expense.expense_name) {
+                let entry = totals
+                    .entry(("Expense", flag.to_string(), expense.category.clone()))
+                    .or_default();
+                entry.0 += 1;
+                entry.1 += expense.projected;
+                entry.2 += expense.cost;
+            }
+        }
+
+        for income in incomes {
+            let income_type = match income_types.iter().find(|t| t.id == income.income_type_id) {
+                Some(income_type) => &income_type.name,
+                None => continue,
+            };
+            if let Some(flag) = flags.income_flag(income_type) {
+                let entry = totals
+                    .entry(("Income", flag.to_string(), income_type.clone()))
+                    .or_default();
+                entry.0 += 1;
+                entry.1 += income.projected;
+                entry.2 += income.amount;
+            }
+        }
+
+        let rows = totals
+            .into_iter()
+            .map(
+                |((kind, flag, group), (items, projected, actual))| TaxReportRow {
+                    kind,
+                    flag,
+                    group,
+                    items,
+                    projected,
+                    actual,
+                },
+            )
+            .collect();
+
+        Self { year, rows }
+    }
+
+    /// Suggested file name, e.g. `tax-report-2024.csv`
+    pub fn file_name(&self) -> String {
+        format!("tax-report-{}.csv", self.year)
+    }
+
+    /// Render as CSV, with a total row after each flag's categories
+    pub fn to_csv(&self) -> String {
+        let mut out = String::from("Type,Flag,Category,Items,Projected,Actual\n");
+        let mut push =
+            |kind: &str, flag: &str, group: &str, items: usize, projected: f64, actual: f64| {
+                out.push_str(&format!(
+                    "{},{},{},{},{:.2},{:.2}\n",
+                    kind,
+                    csv_field(flag),
+                    csv_field(group),
+                    items,
+                    projected,
+                    actual
+                ));
+            };
+
+        for (index, row) in self.rows.iter().enumerate() {
+            push(
+                row.kind,
+                &row.flag,
+                &row.group,
+                row.items,
+                row.projected,
+                row.actual,
+            );
+
+            let last_of_flag = self
+                .rows
+                .get(index + 1)
+                .is_none_or(|next| next.kind != row.kind || next.flag != row.flag);
+            if last_of_flag {
+                let group: Vec<&TaxReportRow> = self
+                    .rows
+                    .iter()
+                    .filter(|r| r.kind == row.kind && r.flag == row.flag)
+                    .collect();
+                push(
+                    row.kind,
+                    &row.flag,
+                    "Total",
+                    group.iter().map(|r| r.items).sum(),
+                    group.iter().map(|r| r.projected).sum::<Money>().to_f64(),
+                    group.iter().map(|r| r.actual).sum::<Money>().to_f64(),
+                );
+            }
+        }
+        out
+    }
+}
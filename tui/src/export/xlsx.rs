@@ -0,0 +1,332 @@
+//! Minimal XLSX writer: plain cells, no formulas or shared strings.
+//!
+//! An XLSX file is a zip of XML parts. Entries are stored uncompressed, which
+//! every spreadsheet app reads and keeps this free of a compression library.
+
+/// One spreadsheet cell
+#[derive(Debug, Clone, PartialEq)]
+pub enum Cell {
+    Empty,
+    Text(String),
+    /// Shown with two decimals
+    Number(f64),
+    /// Bold text, for headers and total labels
+    Heading(String),
+}
+
+impl Cell {
+    pub fn text(value: impl Into<String>) -> Self {
+        Cell::Text(value.into())
+    }
+
+    pub fn heading(value: impl Into<String>) -> Self {
+        Cell::Heading(value.into())
+    }
+}
+
+/// One worksheet
+#[derive(Debug, Clone, PartialEq)]
+pub struct Sheet {
+    pub name: String,
+    pub rows: Vec<Vec<Cell>>,
+}
+
+impl Sheet {
+    pub fn new(name: impl Into<String>) -> Self {
+        Self {
+            name: name.into(),
+            rows: Vec::new(),
+        }
+    }
+
+    pub fn push(&mut self, row: Vec<Cell>) {
+        self.rows.push(row);
+    }
+
+    /// Add a row of bold headings
+    pub fn push_headings(&mut self, headings: &[&str]) {
+        self.rows
+            .push(headings.iter().map(|h| Cell::heading(*h)).collect());
+    }
+}
+
+/// Sheets written to a single `.xlsx` file, in order
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct Workbook {
+    pub sheets: Vec<Sheet>,
+}
+
+/// Style indexes in `styles.xml`
+const STYLE_NUMBER: usize = 1;
+const STYLE_HEADING: usize = 2;
+
+const STYLES: &str = r#"<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>"#;
+
+impl Workbook {
+    pub fn push(&mut self, sheet: Sheet) {
+        self.sheets.push(sheet);
+    }
+
+    /// Sheet names as written: made valid for Excel and unique
+    ///
+    /// Excel limits names to 31 characters and rejects `[]:*?/\`.
+    pub fn sheet_names(&self) -> Vec<String> {
+        let mut names: Vec<String> = Vec::new();
+        for (index, sheet) in self.sheets.iter().enumerate() {
+            let cleaned: String = sheet
+                .name
+                .chars()
+                .map(|c| if "[]:*?/\\".contains(c) { '-' } else { c })
+                .collect();
+            let cleaned = match cleaned.trim().trim_matches('\'') {
+                "" => format!("Sheet{}", index + 1),
+                name => name.to_string(),
+            };
+            let mut name: String = cleaned.chars().take(31).collect();
+            let mut suffix = 2;
+            while names.iter().any(|n| n.eq_ignore_ascii_case(&name)) {
+                let tail = format!(" ({})", suffix);
+                let keep = 31 - tail.len();
+                name = format!("{}{}", cleaned.chars().take(keep).collect::<String>(), tail);
+                suffix += 1;
+            }
+            names.push(name);
+        }
+        names
+    }
+
+    /// Encode as an `.xlsx` file
+    pub fn to_bytes(&self) -> Vec<u8> {
+        let names = self.sheet_names();
+        let mut zip = ZipWriter::default();
+
+        let mut content_types = String::from(
+            r#"<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>"#,
+        );
+        for index in 1..=names.len() {
+            content_types.push_str(&format!(
+                r#"<Override PartName="/xl/worksheets/sheet{}.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>"#,
+                index
+            ));
+        }
+        content_types.push_str("</Types>");
+        zip.add("[Content_Types].xml", content_types.as_bytes());
+
+        zip.add(
+            "_rels/.rels",
+            br#"<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>"#,
+        );
+
+        let mut workbook = String::from(
+            r#"<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>"#,
+        );
+        let mut workbook_rels = String::from(
+            r#"<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">"#,
+        );
+        for (index, name) in names.iter().enumerate() {
+            let id = index + 1;
+            workbook.push_str(&format!(
+                r#"<sheet name="{}" sheetId="{}" r:id="rId{}"/>"#,
+                escape_xml(name),
+                id,
+                id
+            ));
+            workbook_rels.push_str(&format!(
+                r#"<Relationship Id="rId{}" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet{}.xml"/>"#,
+                id, id
+            ));
+        }
+        workbook.push_str("</sheets></workbook>");
+        workbook_rels.push_str(&format!(
+            r#"<Relationship Id="rId{}" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>"#,
+            names.len() + 1
+        ));
+        zip.add("xl/workbook.xml", workbook.as_bytes());
+        zip.add("xl/_rels/workbook.xml.rels", workbook_rels.as_bytes());
+        zip.add("xl/styles.xml", STYLES.as_bytes());
+
+        for (index, sheet) in self.sheets.iter().enumerate() {
+            zip.add(
+                &format!("xl/worksheets/sheet{}.xml", index + 1),
+                sheet_xml(sheet).as_bytes(),
+            );
+        }
+        zip.finish()
+    }
+}
+
+fn sheet_xml(sheet: &Sheet) -> String {
+    let mut xml = String::from(
+        r#"<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">"#,
+    );
+
+    // Size columns to their longest value; Excel doesn't do this on open
+    let columns = sheet.rows.iter().map(Vec::len).max().unwrap_or(0);
+    if columns > 0 {
+        xml.push_str("<cols>");
+        for column in 0..columns {
+            let width = sheet
+                .rows
+                .iter()
+                .filter_map(|row| row.get(column))
+                .map(|cell| match cell {
+                    Cell::Empty => 0,
+                    Cell::Text(text) | Cell::Heading(text) => text.chars().count(),
+                    Cell::Number(value) => format!("{:.2}", value).len() + 2,
+                })
+                .max()
+                .unwrap_or(0)
+                .clamp(8, 50);
+            xml.push_str(&format!(
+                r#"<col min="{}" max="{}" width="{}" customWidth="1"/>"#,
+                column + 1,
+                column + 1,
+                width + 2
+            ));
+        }
+        xml.push_str("</cols>");
+    }
+
+    xml.push_str("<sheetData>");
+    for (row_index, row) in sheet.rows.iter().enumerate() {
+        xml.push_str(&format!(r#"<row r="{}">"#, row_index + 1));
+        for (column, cell) in row.iter().enumerate() {
+            let reference = format!("{}{}", column_name(column), row_index + 1);
+            match cell {
+                Cell::Empty => {}
+                Cell::Text(text) => xml.push_str(&format!(
+                    r#"<c r="{}" t="inlineStr"><is><t xml:space="preserve">{}</t></is></c>"#,
+                    reference,
+                    escape_xml(text)
+                )),
+                Cell::Heading(text) => xml.push_str(&format!(
+                    r#"<c r="{}" s="{}" t="inlineStr"><is><t xml:space="preserve">{}</t></is></c>"#,
+                    reference,
+                    STYLE_HEADING,
+                    escape_xml(text)
+                )),
+                Cell::Number(value) if value.is_finite() => xml.push_str(&format!(
+                    r#"<c r="{}" s="{}"><v>{}</v></c>"#,
+                    reference, STYLE_NUMBER, value
+                )),
+                Cell::Number(_) => {}
+            }
+        }
+        xml.push_str("</row>");
+    }
+    xml.push_str("</sheetData></worksheet>");
+    xml
+}
+
+/// Spreadsheet column letters for a 0-based index: A, B, ..., Z, AA, ...
+pub fn column_name(index: usize) -> String {
+    let mut name = Vec::new();
+    let mut n = index + 1;
+    while n > 0 {
+        let rem = (n - 1) % 26;
+        name.push(b'A' + rem as u8);
+        n = (n - 1) / 26;
+    }
+    name.reverse();
+    String::from_utf8(name).unwrap_or_default()
+}
+
+/// Escape text for XML, dropping control characters XML can't hold
+fn escape_xml(value: &str) -> String {
+    let mut out = String::with_capacity(value.len());
+    for c in value.chars() {
+        match c {
+            '&' => out.push_str("&amp;"),
+            '<' => out.push_str("&lt;"),
+            '>' => out.push_str("&gt;"),
+            '"' => out.push_str("&quot;"),
+            '\t' | '\n' | '\r' => out.push(c),
+            c if c.is_control() => {}
+            c => out.push(c),
+        }
+    }
+    out
+}
+
+/// Writes a zip archive with stored (uncompressed) entries
+#[derive(Default)]
+struct ZipWriter {
+    data: Vec<u8>,
+    central: Vec<u8>,
+    entries: u16,
+}
+
+/// 1980-01-01 00:00, the earliest date zip can hold; a fixed date keeps
+/// exports of the same data byte-identical
+const DOS_TIME: u16 = 0;
+const DOS_DATE: u16 = (1 << 5) | 1;
+/// Bit 11: file names are UTF-8
+const FLAG_UTF8: u16 = 1 << 11;
+
+impl ZipWriter {
+    fn add(&mut self, name: &str, contents: &[u8]) {
+        let offset = self.data.len() as u32;
+        let crc = crc32fast::hash(contents);
+        let size = contents.len() as u32;
+
+        let data = &mut self.data;
+        data.extend_from_slice(&0x0403_4b50u32.to_le_bytes());
+        data.extend_from_slice(&20u16.to_le_bytes()); // version needed
+        data.extend_from_slice(&FLAG_UTF8.to_le_bytes());
+        data.extend_from_slice(&0u16.to_le_bytes()); // stored
+        data.extend_from_slice(&DOS_TIME.to_le_bytes());
+        data.extend_from_slice(&DOS_DATE.to_le_bytes());
+        data.extend_from_slice(&crc.to_le_bytes());
+        data.extend_from_slice(&size.to_le_bytes()); // compressed
+        data.extend_from_slice(&size.to_le_bytes()); // uncompressed
+        data.extend_from_slice(&(name.len() as u16).to_le_bytes());
+        data.extend_from_slice(&0u16.to_le_bytes()); // extra field
+        data.extend_from_slice(name.as_bytes());
+        data.extend_from_slice(contents);
+
+        let central = &mut self.central;
+        central.extend_from_slice(&0x0201_4b50u32.to_le_bytes());
+        central.extend_from_slice(&20u16.to_le_bytes()); // version made by
+        central.extend_from_slice(&20u16.to_le_bytes()); // version needed
+        central.extend_from_slice(&FLAG_UTF8.to_le_bytes());
+        central.extend_from_slice(&0u16.to_le_bytes()); // stored
+        central.extend_from_slice(&DOS_TIME.to_le_bytes());
+        central.extend_from_slice(&DOS_DATE.to_le_bytes());
+        central.extend_from_slice(&crc.to_le_bytes());
+        central.extend_from_slice(&size.to_le_bytes());
+        central.extend_from_slice(&size.to_le_bytes());
+        central.extend_from_slice(&(name.len() as u16).to_le_bytes());
+        central.extend_from_slice(&0u16.to_le_bytes()); // extra field
+        central.extend_from_slice(&0u16.to_le_bytes()); // comment
+        central.extend_from_slice(&0u16.to_le_bytes()); // disk
+        central.extend_from_slice(&0u16.to_le_bytes()); // internal attributes
+        central.extend_from_slice(&0u32.to_le_bytes()); // external attributes
+        central.extend_from_slice(&offset.to_le_bytes());
+        central.extend_from_slice(name.as_bytes());
+
+        self.entries += 1;
+    }
+
+    fn finish(mut self) -> Vec<u8> {
+        let central_offset = self.data.len() as u32;
+        let central_size = self.central.len() as u32;
+        self.data.extend_from_slice(&self.central);
+
+        let data = &mut self.data;
+        data.extend_from_slice(&0x0605_4b50u32.to_le_bytes());
+        data.extend_from_slice(&0u16.to_le_bytes()); // this disk
+        data.extend_from_slice(&0u16.to_le_bytes()); // disk with the directory
+        data.extend_from_slice(&self.entries.to_le_bytes());
+        data.extend_from_slice(&self.entries.to_le_bytes());
+        data.extend_from_slice(&central_size.to_le_bytes());
+        data.extend_from_slice(&central_offset.to_le_bytes());
+        data.extend_from_slice(&0u16.to_le_bytes()); // comment
+        self.data
+    }
+}
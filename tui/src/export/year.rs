@@ -0,0 +1,268 @@
+use std::collections::BTreeMap;
+
+use crate::models::{Expense, Income, IncomeType, Money, Month};
+
+use super::xlsx::{Cell, Sheet, Workbook};
+
+/// Income and expense totals of one month
+#[derive(Debug, Clone, PartialEq)]
+pub struct MonthTotals {
+    pub name: String,
+    pub income_projected: f64,
+    pub income_actual: f64,
+    pub expenses_projected: f64,
+    pub expenses_actual: f64,
+}
+
+impl MonthTotals {
+    /// Actual income left after actual expenses
+    pub fn balance(&self) -> f64 {
+        self.income_actual - self.expenses_actual
+    }
+}
+
+/// A whole year as a spreadsheet: an overview sheet, then one sheet per month
+///
+/// Totals are computed here and written as values, so the file reads the
+/// same in any spreadsheet app.
+#[derive(Debug, Clone)]
+pub struct YearReport {
+    pub year: i32,
+    /// The year's months, January first
+    pub months: Vec<Month>,
+    pub expenses: Vec<Expense>,
+    pub incomes: Vec<Income>,
+    pub income_types: Vec<IncomeType>,
+}
+
+impl YearReport {
+    /// Collect a year's months and their expenses and incomes
+    pub fn build(
+        year: i32,
+        months: &[Month],
+        expenses: &[Expense],
+        incomes: &[Income],
+        income_types: &[IncomeType],
+    ) -> Self {
+        let mut months: Vec<Month> = months.iter().filter(|m| m.year == year).cloned().collect();
+        months.sort_by_key(|m| m.month);
+        let in_year = |month_id: i32| months.iter().any(|m| m.id == month_id);
+
+        let mut expenses: Vec<Expense> = expenses
+            .iter()
+            .filter(|e| in_year(e.month_id))
+            .cloned()
+            .collect();
+        expenses.sort_by_key(|e| e.order);
+
+        Self {
+            year,
+            expenses,
+            incomes: incomes
+                .iter()
+                .filter(|i| in_year(i.month_id))
+                .cloned()
+                .collect(),
+            income_types: income_types.to_vec(),
+            months,
+        }
+    }
+
+    /// Suggested file name, e.g. `budget-2024.xlsx`
+    pub fn file_name(&self) -> String {
+        format!("budget-{}.xlsx", self.year)
+    }
+
+    /// Totals of each month, January first
+    pub fn month_totals(&self) -> Vec<MonthTotals> {
+        self.months
+            .iter()
+            .map(|month| {
+                let expenses = self.expenses.iter().filter(|e| e.month_id == month.id);
+                let incomes = self.incomes.iter().filter(|i| i.month_id == month.id);
+                MonthTotals {
+                    name: month.display_name(),
+                    income_projected: incomes.clone().map(|i| i.projected).sum::<Money>().to_f64(),
+                    income_actual: incomes.map(|i| i.amount).sum::<Money>().to_f64(),
+                    expenses_projected: expenses
+                        .clone()
+                        .map(|e| e.projected)
+                        .sum::<Money>()
+                        .to_f64(),
+                    expenses_actual: expenses.map(|e| e.cost).sum::<Money>().to_f64(),
+                }
+            })
+            .collect()
+    }
+
+    /// Projected and actual spending per category over the year, by name
+    pub fn category_totals(&self) -> Vec<(String, f64, f64)> {
+        let mut totals: BTreeMap<&str, (f64, f64)> = BTreeMap::new();
+        for expense in &self.expenses {
+            let entry = totals.entry(&expense.category).or_default();
+            entry.0 += expense.projected;
+            entry.1 += expense.cost;
+        }
+        totals
+            .into_iter()
+            .map(|(category, (projected, actual))| (category.to_string(), projected, actual))
+            .collect()
+    }
+
+    /// Lay out the overview and month sheets
+    pub fn to_workbook(&self) -> Workbook {
+        let mut workbook = Workbook::default();
+        workbook.push(self.overview_sheet());
+        for month in &self.months {
+            workbook.push(self.month_sheet(month));
+        }
+        workbook
+    }
+
+    /// Encode as an `.xlsx` file
+    pub fn to_xlsx(&self) -> Vec<u8> {
+        self.to_workbook().to_bytes()
+    }
+
+    fn overview_sheet(&self) -> Sheet {
+        let mut sheet = Sheet::new(format!("{} Overview", self.year));
+        sheet.push_headings(&[
+            "Month",
+            "Income (projected)",
+            "Income (actual)",
+            "Expenses (projected)",
+            "Expenses (actual)",
+            "Balance",
+        ]);
+
+        let totals = self.month_totals();
+        let row = |label: Cell, t: &MonthTotals| {
+            vec![
+                label,
+                Cell::Number(t.income_projected),
+                Cell::Number(t.income_actual),
+                Cell::Number(t.expenses_projected),
+                Cell::Number(t.expenses_actual),
+                Cell::Number(t.balance()),
+            ]
+        };
+        for month in &totals {
+            sheet.push(row(Cell::text(&month.name), month));
+        }
+        let year = MonthTotals {
+            name: "Total".to_string(),
+            income_projected: totals
+                .iter()
+                .map(|t| t.income_projected)
+                .sum::<Money>()
+                .to_f64(),
+            income_actual: totals
+                .iter()
+                .map(|t| t.income_actual)
+                .sum::<Money>()
+                .to_f64(),
+            expenses_projected: totals
+                .iter()
+                .map(|t| t.expenses_projected)
+                .sum::<Money>()
+                .to_f64(),
+            expenses_actual: totals
+                .iter()
+                .map(|t| t.expenses_actual)
+                .sum::<Money>()
+                .to_f64(),
+        };
+        sheet.push(row(Cell::heading("Total"), &year));
+
+        sheet.push(Vec::new());
+        sheet.push_headings(&["Category", "Projected", "Actual", "Difference"]);
+        let categories = self.category_totals();
+        for (category, projected, actual) in &categories {
+            sheet.push(vec![
+                Cell::text(category),
+                Cell::Number(*projected),
+                Cell::Number(*actual),
+                Cell::Number(projected - actual),
+            ]);
+        }
+        let projected: f64 = categories.iter().map(|c| c.1).sum::<Money>().to_f64();
+        let actual: f64 = categories.iter().map(|c| c.2).sum::<Money>().to_f64();
+        sheet.push(vec![
+            Cell::heading("Total"),
+            Cell::Number(projected),
+            Cell::Number(actual),
+            Cell::Number(projected - actual),
+        ]);
+        sheet
+    }
+
+    fn month_sheet(&self, month: &Month) -> Sheet {
+        let mut sheet = Sheet::new(month.display_name());
+        let expenses: Vec<&Expense> = self
+            .expenses
+            .iter()
+            .filter(|e| e.month_id == month.id)
+            .collect();
+        let incomes: Vec<&Income> = self
+            .incomes
+            .iter()
+            .filter(|i| i.month_id == month.id)
+            .collect();
+
+        sheet.push_headings(&[
+            "Expense",
+            "Category",
+            "Period",
+            "Projected",
+            "Actual",
+            "Date",
+            "Notes",
+        ]);
+        for expense in &expenses {
+            let optional = |value: &Option<String>| match value {
+                Some(value) => Cell::text(value),
+                None => Cell::Empty,
+            };
+            sheet.push(vec![
+                Cell::text(&expense.expense_name),
+                Cell::text(&expense.category),
+                Cell::text(&expense.period),
+                Cell::Number(expense.projected),
+                Cell::Number(expense.cost),
+                optional(&expense.expense_date),
+                optional(&expense.notes),
+            ]);
+        }
+        sheet.push(vec![
+            Cell::heading("Total"),
+            Cell::Empty,
+            Cell::Empty,
+            Cell::Number(expenses.iter().map(|e| e.projected).sum::<Money>().to_f64()),
+            Cell::Number(expenses.iter().map(|e| e.cost).sum::<Money>().to_f64()),
+        ]);
+
+        sheet.push(Vec::new());
+        sheet.push_headings(&["Income Type", "Period", "Projected", "Actual"]);
+        for income in &incomes {
+            let income_type = self
+                .income_types
+                .iter()
+                .find(|t| t.id == income.income_type_id)
+                .map(|t| t.name.as_str())
+                .unwrap_or("Unknown");
+            sheet.push(vec![
+                Cell::text(income_type),
+                Cell::text(&income.period),
+                Cell::Number(income.projected),
+                Cell::Number(income.amount),
+            ]);
+        }
+        sheet.push(vec![
+            Cell::heading("Total"),
+            Cell::Empty,
+            Cell::Number(incomes.iter().map(|i| i.projected).sum::<Money>().to_f64()),
+            Cell::Number(incomes.iter().map(|i| i.amount).sum::<Money>().to_f64()),
+        ]);
+        sheet
+    }
+}
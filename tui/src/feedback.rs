@@ -0,0 +1,193 @@
+//! Reporting a problem
+//!
+//! `r` in the help composes an issue for the project's tracker with what is
+//! needed to look into it: the version, the system, the config with anything
+//! personal taken out, and the last errors the app showed. It opens the
+//! tracker's new-issue page with that filled in; nothing is sent until the
+//! issue is submitted there.
+
+use std::io;
+use std::path::Path;
+use std::process::{Command, Stdio};
+
+use anyhow::{Context, Result};
+use chrono::NaiveDateTime;
+use reqwest::Url;
+use toml::{Table, Value};
+
+use crate::config::{backup, Config};
+
+/// Page for opening a new issue
+pub const ISSUES_URL: &str = "https://github.com/kleyson/appz-budget/issues/new";
+
+/// Longest body put in the link; GitHub turns away much longer URLs
+const MAX_LINK_BODY: usize = 6000;
+
+/// Settings that point at people's servers, files or accounts, shown as
+/// `<redacted>`; API keys and sessions are left out altogether
+const REDACTED_KEYS: &[&str] = &[
+    "url",
+    "server_url",
+    "proxy",
+    "no_proxy",
+    "ca_bundle",
+    "headers",
+    "spreadsheet_id",
+    "service_account",
+    "passphrase_hash",
+    "passphrase_sha256",
+];
+
+/// What goes in an issue about a problem
+#[derive(Debug, Clone, PartialEq)]
+pub struct ProblemReport {
+    pub version: String,
+    /// OS and architecture, e.g. `linux x86_64`
+    pub system: String,
+    /// `TERM`, for drawing problems
+    pub terminal: String,
+    /// The config as TOML, without secrets or addresses
+    pub config: String,
+    /// Errors shown this session, oldest first
+    pub errors: Vec<(NaiveDateTime, String)>,
+}
+
+impl ProblemReport {
+    /// A report for this machine
+    pub fn new(version: &str, config: &Config, errors: &[(NaiveDateTime, String)]) -> Result<Self> {
+        Ok(Self {
+            version: version.trim().to_string(),
+            system: format!("{} {}", std::env::consts::OS, std::env::consts::ARCH),
+            terminal: std::env::var("TERM").unwrap_or_else(|_| "unknown".to_string()),
+            config: redacted_config(config)?,
+            errors: errors.to_vec(),
+        })
+    }
+
+    /// The issue text, in Markdown
+    pub fn body(&self) -> String {
+        let mut body = String::from(
+            "**What happened?**\n\n\
+             <!-- What were you doing, and what did you expect instead? -->\n\n\
+             **Environment**\n\n",
+        );
+        body.push_str(&format!("- Version: {}\n", self.version));
+        body.push_str(&format!("- System: {}\n", self.system));
+        body.push_str(&format!("- Terminal: {}\n\n", self.terminal));
+
+        body.push_str("**Recent errors**\n\n");
+        if self.errors.is_empty() {
+            body.push_str("None\n");
+        }
+        for (at, message) in &self.errors {
+            body.push_str(&format!("- {} {}\n", at.format("%Y-%m-%d %H:%M"), message));
+        }
+
+        body.push_str(
+            "\n<details><summary>Config (secrets and addresses removed)</summary>\n\n```toml\n",
+        );
+        body.push_str(self.config.trim_end());
+        body.push_str("\n```\n\n</details>\n");
+        body
+    }
+
+    /// New-issue link with the report filled in, cut short if the whole of
+    /// it won't fit
+    pub fn issue_url(&self) -> String {
+        let mut body = self.body();
+        if body.len() > MAX_LINK_BODY {
+            let mut end = MAX_LINK_BODY;
+            while !body.is_char_boundary(end) {
+                end -= 1;
+            }
+            body.truncate(end);
+            body.push_str(
+                "\n```\n\n</details>\n\n(Cut short; press c on the report in the app to copy all of it.)\n",
+            );
+        }
+        let title = match self.errors.last() {
+            Some((_, message)) => format!("Problem: {}", message),
+            None => "Problem: ".to_string(),
+        };
+        Url::parse_with_params(ISSUES_URL, &[("title", title), ("body", body)])
+            .map(String::from)
+            .unwrap_or_else(|_| ISSUES_URL.to_string())
+    }
+}
+
+/// `config` as TOML without secrets, with addresses and paths replaced by
+/// `<redacted>`
+pub fn redacted_config(config: &Config) -> Result<String> {
+    let mut table: Table = toml::from_str(&backup::export_shared(config)?)
+        .context("Failed to read back the config")?;
+    redact(&mut table);
+    toml::to_string_pretty(&table).context("Failed to serialize config")
+}
+
+fn redact(table: &mut Table) {
+    for (key, value) in table.iter_mut() {
+        if REDACTED_KEYS.contains(&key.as_str()) {
+            *value = Value::String("<redacted>".to_string());
+            continue;
+        }
+        match value {
+            Value::Table(table) => redact(table),
+            Value::Array(items) => {
+                for item in items {
+                    if let Value::Table(table) = item {
+                        redact(table);
+                    }
+                }
+            }
+            _ => {}
+        }
+    }
+}
+
+/// Open `url` in the default browser, or the one in `BROWSER`; without
+/// `BROWSER`, a file path opens in the app the system has for it
+pub fn open_in_browser(url: &str) -> io::Result<()> {
+    let mut command = match std::env::var("BROWSER") {
+        Ok(browser) if !browser.trim().is_empty() => Command::new(browser.trim()),
+        _ if cfg!(target_os = "macos") => Command::new("open"),
+        _ if cfg!(windows) => {
+            // Not `start`, which cmd would split at the `&` in the link
+            let mut command = Command::new("rundll32");
+            command.arg("url.dll,FileProtocolHandler");
+            command
+        }
+        _ => Command::new("xdg-open"),
+    };
+    // The browser's own output would draw over the screen
+    command
+        .arg(url)
+        .stdin(Stdio::null())
+        .stdout(Stdio::null())
+        .stderr(Stdio::null())
+        .spawn()
+        .map(|_| ())
+}
+
+/// Open the file at `path` in the app the system has for it
+///
+/// Unlike [`open_in_browser`], `BROWSER` is not used: it names a browser,
+/// which is the wrong viewer for most files.
+pub fn open_file(path: &Path) -> io::Result<()> {
+    let mut command = if cfg!(target_os = "macos") {
+        Command::new("open")
+    } else if cfg!(windows) {
+        // The empty title keeps `start` from taking a quoted path as one
+        let mut command = Command::new("cmd");
+        command.args(["/C", "start", ""]);
+        command
+    } else {
+        Command::new("xdg-open")
+    };
+    command
+        .arg(path)
+        .stdin(Stdio::null())
+        .stdout(Stdio::null())
+        .stderr(Stdio::null())
+        .spawn()
+        .map(|_| ())
+}
@@ -0,0 +1,574 @@
+use std::collections::BTreeMap;
+
+use anyhow::{bail, Context, Result};
+use serde::Deserialize;
+
+use crate::api::BudgetApi;
+use crate::models::{
+    Category, CustomFields, ExpenseCreate, IncomeCreate, IncomeType, Month, MonthCreate, Period,
+};
+use crate::state::EntityType;
+
+use super::parse_csv_lines;
+
+/// One expense or income of a past month
+#[derive(Debug, Clone, PartialEq)]
+pub struct ImportRow {
+    /// Line in the file, for error messages
+    pub line: usize,
+    pub year: i32,
+    pub month: i32,
+    pub kind: EntityType,
+    /// Expense name, or income type for incomes
+    pub name: String,
+    /// Empty for incomes
+    pub category: String,
+    pub period: String,
+    pub projected: f64,
+    pub actual: f64,
+}
+
+/// Past months read from a single spreadsheet, ready to backfill
+///
+/// The file is a CSV with a header row. Columns are matched by name, in any
+/// order: `month` (e.g. `2019-03` or `03/2019`), `type` (`expense` or
+/// `income`; expense if left out), `name`, `category` (expenses only),
+/// `period`, `projected` and `actual` (or `cost`/`amount`). A missing
+/// `projected` is taken to equal the actual amount.
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct HistoryImport {
+    /// Oldest month first; file order within a month
+    pub rows: Vec<ImportRow>,
+}
+
+/// What an import would do against the server's current data
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct ImportPlan {
+    /// Months to create and fill, oldest first, as (year, month)
+    pub new_months: Vec<(i32, i32)>,
+    /// Months that already exist; their rows are skipped
+    pub existing_months: Vec<(i32, i32)>,
+    /// Expenses and incomes in the new months
+    pub expenses: usize,
+    pub incomes: usize,
+    /// Categories, periods and income types the server doesn't have yet
+    pub missing: Vec<String>,
+}
+
+impl ImportPlan {
+    /// Check if there is something to import and nothing is missing
+    pub fn is_ready(&self) -> bool {
+        self.missing.is_empty() && !self.new_months.is_empty()
+    }
+}
+
+/// Reported after each month is imported
+#[derive(Debug, Clone, PartialEq)]
+pub struct ImportProgress {
+    /// 1-based position of the month in the import
+    pub index: usize,
+    pub total: usize,
+    pub month_name: String,
+    pub expenses: usize,
+    pub incomes: usize,
+}
+
+/// What an import created
+#[derive(Debug, Clone, Copy, Default, PartialEq)]
+pub struct ImportSummary {
+    pub months: usize,
+    pub expenses: usize,
+    pub incomes: usize,
+}
+
+impl HistoryImport {
+    /// Read rows from CSV text
+    pub fn parse(text: &str) -> Result<Self> {
+        let records = parse_csv_lines(text);
+        let ((_, header), records) = match records.split_first() {
+            Some(split) => split,
+            None => bail!("The file is empty"),
+        };
+
+        let column = |names: &[&str]| {
+            header
+                .iter()
+                .position(|h| names.contains(&h.trim().to_lowercase().as_str()))
+        };
+        let month_col = column(&["month"]).context("Missing a 'month' column")?;
+        let name_col = column(&["name"]).context("Missing a 'name' column")?;
+        let period_col = column(&["period"]).context("Missing a 'period' column")?;
+        let type_col = column(&["type"]);
+        let category_col = column(&["category"]);
+        let projected_col = column(&["projected"]);
+        let actual_col = column(&["actual", "cost", "amount"]);
+        if projected_col.is_none() && actual_col.is_none() {
+            bail!("Missing a 'projected' or 'actual' column");
+        }
+
+        let mut rows = Vec::new();
+        for (line, record) in records {
+            let line = *line;
+            let field = |col: Option<usize>| {
+                col.and_then(|col| record.get(col))
+                    .map(|value| value.trim())
+                    .unwrap_or_default()
+            };
+            let amount = |col: Option<usize>| -> Result<Option<f64>> {
+                let value = field(col);
+                if value.is_empty() {
+                    return Ok(None);
+                }
+                let cleaned: String = value.chars().filter(|c| !matches!(c, '$' | ',')).collect();
+                match cleaned.trim().parse() {
+                    Ok(amount) => Ok(Some(amount)),
+                    Err(_) => bail!("line {}: '{}' is not an amount", line, value),
+                }
+            };
+
+            let month = match parse_month(field(Some(month_col))) {
+                Some(month) => month,
+                None => bail!(
+                    "line {}: '{}' is not a month (use e.g. 2019-03)",
+                    line,
+                    field(Some(month_col))
+                ),
+            };
+            rows.push(
+                RawRow {
+                    month,
+                    kind: field(type_col),
+                    name: field(Some(name_col)),
+                    category: field(category_col),
+                    period: field(Some(period_col)),
+                    projected: amount(projected_col)?,
+                    actual: amount(actual_col)?,
+                }
+                .check(line)?,
+            );
+        }
+
+        // Stable, so rows keep their file order within a month
+        rows.sort_by_key(|row| (row.year, row.month));
+        Ok(Self { rows })
+    }
+
+    /// Read rows from newline-delimited JSON, one object per line
+    ///
+    /// Objects have the CSV's columns as keys, with amounts as numbers, e.g.
+    /// `{"name": "Groceries", "category": "Food", "period": "Monthly",
+    /// "cost": 84.2}`. Rows without a `month` go in `default_month`.
+    pub fn parse_jsonl(text: &str, default_month: (i32, i32)) -> Result<Self> {
+        let mut rows = Vec::new();
+        for (index, text) in text.lines().enumerate() {
+            let line = index + 1;
+            if text.trim().is_empty() {
+                continue;
+            }
+            let row: JsonRow =
+                serde_json::from_str(text).map_err(|e| anyhow::anyhow!("line {}: {}", line, e))?;
+            let month = match row.month.as_deref().map(str::trim) {
+                None | Some("") => default_month,
+                Some(value) => match parse_month(value) {
+                    Some(month) => month,
+                    None => bail!(
+                        "line {}: '{}' is not a month (use e.g. 2019-03)",
+                        line,
+                        value
+                    ),
+                },
+            };
+            rows.push(
+                RawRow {
+                    month,
+                    kind: row.kind.as_deref().unwrap_or_default().trim(),
+                    name: row.name.as_deref().unwrap_or_default().trim(),
+                    category: row.category.as_deref().unwrap_or_default().trim(),
+                    period: row.period.as_deref().unwrap_or_default().trim(),
+                    projected: row.projected,
+                    actual: row.actual,
+                }
+                .check(line)?,
+            );
+        }
+        if rows.is_empty() {
+            bail!("Nothing to import");
+        }
+
+        rows.sort_by_key(|row| (row.year, row.month));
+        Ok(Self { rows })
+    }
+
+    /// Months in the file, oldest first
+    pub fn months(&self) -> Vec<(i32, i32)> {
+        let mut months: Vec<(i32, i32)> = self.rows.iter().map(|r| (r.year, r.month)).collect();
+        months.dedup();
+        months
+    }
+
+    /// Check the rows against the server's months and settings
+    pub fn plan(
+        &self,
+        months: &[Month],
+        categories: &[Category],
+        periods: &[Period],
+        income_types: &[IncomeType],
+    ) -> ImportPlan {
+        let mut plan = ImportPlan::default();
+        for (year, month) in self.months() {
+            if months.iter().any(|m| m.year == year && m.month == month) {
+                plan.existing_months.push((year, month));
+            } else {
+                plan.new_months.push((year, month));
+            }
+        }
+
+        for row in self.rows_in(&plan.new_months) {
+            if row.kind == EntityType::Income {
+                plan.incomes += 1;
+            } else {
+                plan.expenses += 1;
+            }
+        }
+        plan.missing = missing_names(
+            self.rows_in(&plan.new_months),
+            categories,
+            periods,
+            income_types,
+        );
+        plan
+    }
+
+    /// Create the planned months in order, each with its expenses and incomes
+    ///
+    /// Stops at the first failure; months created before it are complete, and
+    /// are skipped as existing if the import is run again.
+    pub async fn run(
+        &self,
+        api: &impl BudgetApi,
+        plan: &ImportPlan,
+        categories: &[Category],
+        periods: &[Period],
+        income_types: &[IncomeType],
+        mut progress: impl FnMut(&ImportProgress),
+    ) -> Result<ImportSummary> {
+        let mut summary = ImportSummary::default();
+
+        for (index, &(year, month)) in plan.new_months.iter().enumerate() {
+            let created = api
+                .create_month(&MonthCreate { year, month })
+                .await
+                .with_context(|| format!("Failed to create {}-{:02}", year, month))?;
+            let (expenses, incomes) = self
+                .add_rows(api, &created, categories, periods, income_types)
+                .await?;
+
+            summary.months += 1;
+            summary.expenses += expenses;
+            summary.incomes += incomes;
+            progress(&ImportProgress {
+                index: index + 1,
+                total: plan.new_months.len(),
+                month_name: created.display_name(),
+                expenses,
+                incomes,
+            });
+        }
+        Ok(summary)
+    }
+
+    /// The server's months for every month in the file, checking they can
+    /// take new rows
+    ///
+    /// Unlike a backfill, nothing is created: every month must exist and be
+    /// open, and the server must have every category, period and income type
+    /// the rows use.
+    pub fn existing_months(
+        &self,
+        months: &[Month],
+        categories: &[Category],
+        periods: &[Period],
+        income_types: &[IncomeType],
+    ) -> Result<Vec<Month>> {
+        let mut targets = Vec::new();
+        for (year, number) in self.months() {
+            match months.iter().find(|m| m.year == year && m.month == number) {
+                Some(month) if month.is_closed => {
+                    bail!("{} is closed - reopen it first", month.display_name())
+                }
+                Some(month) => targets.push(month.clone()),
+                None => bail!("There is no {}-{:02} on the server", year, number),
+            }
+        }
+        let missing = missing_names(self.rows.iter(), categories, periods, income_types);
+        if !missing.is_empty() {
+            bail!("Add these in Settings first: {}", missing.join(", "));
+        }
+        Ok(targets)
+    }
+
+    /// Add the rows to `months` (from [`existing_months`](Self::existing_months))
+    /// alongside what they already have
+    ///
+    /// Stops at the first failure; months reported before it are complete.
+    pub async fn add_to_months(
+        &self,
+        api: &impl BudgetApi,
+        months: &[Month],
+        categories: &[Category],
+        periods: &[Period],
+        income_types: &[IncomeType],
+        mut progress: impl FnMut(&ImportProgress),
+    ) -> Result<ImportSummary> {
+        let mut summary = ImportSummary::default();
+        for (index, month) in months.iter().enumerate() {
+            let (expenses, incomes) = self
+                .add_rows(api, month, categories, periods, income_types)
+                .await?;
+            summary.months += 1;
+            summary.expenses += expenses;
+            summary.incomes += incomes;
+            progress(&ImportProgress {
+                index: index + 1,
+                total: months.len(),
+                month_name: month.display_name(),
+                expenses,
+                incomes,
+            });
+        }
+        Ok(summary)
+    }
+
+    /// Add the file's rows for an existing month that has nothing in it yet
+    ///
+    /// Rows of other months are ignored. Checks first that the server has
+    /// every category, period and income type the rows use.
+    pub async fn fill_month(
+        &self,
+        api: &impl BudgetApi,
+        month: &Month,
+        categories: &[Category],
+        periods: &[Period],
+        income_types: &[IncomeType],
+    ) -> Result<ImportSummary> {
+        let months = [(month.year, month.month)];
+        if self.rows_in(&months).next().is_none() {
+            bail!("The file has no rows for {}", month.display_name());
+        }
+        let missing = missing_names(self.rows_in(&months), categories, periods, income_types);
+        if !missing.is_empty() {
+            bail!("Add these in Settings first: {}", missing.join(", "));
+        }
+        let (expenses, incomes) = self
+            .add_rows(api, month, categories, periods, income_types)
+            .await?;
+        Ok(ImportSummary {
+            months: 1,
+            expenses,
+            incomes,
+        })
+    }
+
+    /// Create the rows of `month` in it, returning how many expenses and
+    /// incomes were added
+    async fn add_rows(
+        &self,
+        api: &impl BudgetApi,
+        month: &Month,
+        categories: &[Category],
+        periods: &[Period],
+        income_types: &[IncomeType],
+    ) -> Result<(usize, usize)> {
+        let month_name = month.display_name();
+        let mut expenses = Vec::new();
+        let mut incomes = 0;
+        for row in self.rows_in(&[(month.year, month.month)]) {
+            let period = find_name(periods.iter().map(|p| &p.name), &row.period)
+                .unwrap_or(&row.period)
+                .clone();
+            if row.kind == EntityType::Income {
+                let income_type_id = income_types
+                    .iter()
+                    .find(|t| t.name.trim().eq_ignore_ascii_case(row.name.trim()))
+                    .map(|t| t.id)
+                    .with_context(|| format!("Unknown income type '{}'", row.name))?;
+                incomes += 1;
+                api.create_income(&IncomeCreate {
+                    income_type_id,
+                    period,
+                    projected: row.projected,
+                    amount: row.actual,
+                    month_id: month.id,
+                    account_id: None,
+                    currency: None,
+                })
+                .await
+                .with_context(|| {
+                    format!(
+                        "Failed on line {} ({} is incomplete - delete it before importing again)",
+                        row.line, month_name
+                    )
+                })?;
+            } else {
+                let category = find_name(categories.iter().map(|c| &c.name), &row.category)
+                    .unwrap_or(&row.category)
+                    .clone();
+                expenses.push(ExpenseCreate {
+                    expense_name: row.name.clone(),
+                    period,
+                    category,
+                    projected: row.projected,
+                    cost: row.actual,
+                    notes: None,
+                    month_id: month.id,
+                    purchases: None,
+                    expense_date: None,
+                    account_id: None,
+                    currency: None,
+                    custom_fields: CustomFields::new(),
+                });
+            }
+        }
+
+        // One request for the month's expenses instead of one per row
+        if !expenses.is_empty() {
+            api.create_expenses_bulk(&expenses)
+                .await
+                .with_context(|| {
+                    format!(
+                        "Failed to add the expenses of {} (it is incomplete - delete it before importing again)",
+                        month_name
+                    )
+                })?;
+        }
+        Ok((expenses.len(), incomes))
+    }
+
+    fn rows_in<'a>(&'a self, months: &'a [(i32, i32)]) -> impl Iterator<Item = &'a ImportRow> {
+        self.rows
+            .iter()
+            .filter(move |row| months.contains(&(row.year, row.month)))
+    }
+}
+
+/// A row's fields as read, before they are checked
+struct RawRow<'a> {
+    month: (i32, i32),
+    kind: &'a str,
+    name: &'a str,
+    category: &'a str,
+    period: &'a str,
+    projected: Option<f64>,
+    actual: Option<f64>,
+}
+
+impl RawRow<'_> {
+    /// The row, or why it can't be imported; `line` is for the message
+    fn check(self, line: usize) -> Result<ImportRow> {
+        let kind = match self.kind.to_lowercase().as_str() {
+            "" | "expense" | "e" => EntityType::Expense,
+            "income" | "i" => EntityType::Income,
+            other => bail!(
+                "line {}: type '{}' is not 'expense' or 'income'",
+                line,
+                other
+            ),
+        };
+        if self.name.is_empty() {
+            bail!("line {}: the name is empty", line);
+        }
+        let category = match kind {
+            EntityType::Income => String::new(),
+            _ => match self.category {
+                "" => bail!("line {}: expenses need a category", line),
+                category => category.to_string(),
+            },
+        };
+        if self.period.is_empty() {
+            bail!("line {}: the period is empty", line);
+        }
+
+        let (year, month) = self.month;
+        Ok(ImportRow {
+            line,
+            year,
+            month,
+            kind,
+            name: self.name.to_string(),
+            category,
+            period: self.period.to_string(),
+            projected: self.projected.or(self.actual).unwrap_or(0.0),
+            actual: self.actual.unwrap_or(0.0),
+        })
+    }
+}
+
+/// One line of a JSONL import
+#[derive(Deserialize)]
+struct JsonRow {
+    #[serde(default)]
+    month: Option<String>,
+    #[serde(default, rename = "type")]
+    kind: Option<String>,
+    #[serde(default, alias = "expense_name", alias = "income_type")]
+    name: Option<String>,
+    #[serde(default)]
+    category: Option<String>,
+    #[serde(default)]
+    period: Option<String>,
+    #[serde(default)]
+    projected: Option<f64>,
+    #[serde(default, alias = "cost", alias = "amount")]
+    actual: Option<f64>,
+}
+
+/// Parse `2019-03`, `2019/3`, `2019-03-15` or `03/2019` as (year, month)
+pub fn parse_month(value: &str) -> Option<(i32, i32)> {
+    let parts: Vec<&str> = value.split(['-', '/', '.']).map(str::trim).collect();
+    let (year, month) = match parts.as_slice() {
+        [first, second, ..] if first.len() == 4 => (*first, *second),
+        [first, second] if second.len() == 4 => (*second, *first),
+        _ => return None,
+    };
+    let year = year.parse().ok()?;
+    let month = month.parse().ok()?;
+    (1..=12).contains(&month).then_some((year, month))
+}
+
+/// Categories, periods and income types `rows` use that the server doesn't
+/// have, each named once
+fn missing_names<'a>(
+    rows: impl Iterator<Item = &'a ImportRow>,
+    categories: &[Category],
+    periods: &[Period],
+    income_types: &[IncomeType],
+) -> Vec<String> {
+    let mut missing = BTreeMap::new();
+    for row in rows {
+        if find_name(periods.iter().map(|p| &p.name), &row.period).is_none() {
+            note_missing(&mut missing, "period", &row.period);
+        }
+        if row.kind == EntityType::Income {
+            if find_name(income_types.iter().map(|t| &t.name), &row.name).is_none() {
+                note_missing(&mut missing, "income type", &row.name);
+            }
+        } else if find_name(categories.iter().map(|c| &c.name), &row.category).is_none() {
+            note_missing(&mut missing, "category", &row.category);
+        }
+    }
+    missing.into_values().collect()
+}
+
+/// Note a missing name once, however it is capitalized
+fn note_missing(missing: &mut BTreeMap<String, String>, kind: &str, name: &str) {
+    missing
+        .entry(format!("{} {}", kind, name.trim().to_lowercase()))
+        .or_insert_with(|| format!("{} '{}'", kind, name.trim()));
+}
+
+/// The server's spelling of a name, matched ignoring case and surrounding spaces
+fn find_name<'a>(names: impl Iterator<Item = &'a String>, name: &str) -> Option<&'a String> {
+    names
+        .into_iter()
+        .find(|candidate| candidate.trim().eq_ignore_ascii_case(name.trim()))
+}
@@ -0,0 +1,70 @@
+//! Data brought in from files made outside the app, or made up to try it
+//! at scale.
+
+mod history;
+mod seed;
+
+pub use history::{
+    parse_month, HistoryImport, ImportPlan, ImportProgress, ImportRow, ImportSummary,
+};
+pub use seed::{synthetic_history, SeedOptions};
+
+/// Split CSV text into records of fields
+///
+/// Handles quoted fields (with `""` escapes and line breaks inside quotes) and
+/// both `\n` and `\r\n` line endings. Blank lines are skipped.
+pub fn parse_csv(text: &str) -> Vec<Vec<String>> {
+    parse_csv_lines(text)
+        .into_iter()
+        .map(|(_, record)| record)
+        .collect()
+}
+
+/// Split CSV text into records like [`parse_csv`], each with the line of the
+/// file it starts on, counting from 1
+///
+/// A byte order mark, as spreadsheets put before "CSV UTF-8", is dropped.
+pub fn parse_csv_lines(text: &str) -> Vec<(usize, Vec<String>)> {
+    let text = text.strip_prefix('\u{feff}').unwrap_or(text);
+    let mut records = Vec::new();
+    let mut record = Vec::new();
+    let mut field = String::new();
+    let mut in_quotes = false;
+    let mut line = 1;
+    let mut start = 1;
+    let mut chars = text.chars().peekable();
+
+    while let Some(c) = chars.next() {
+        match (c, in_quotes) {
+            ('"', true) if chars.peek() == Some(&'"') => {
+                field.push('"');
+                chars.next();
+            }
+            ('"', true) => in_quotes = false,
+            ('"', false) if field.is_empty() => in_quotes = true,
+            (',', false) => record.push(std::mem::take(&mut field)),
+            ('\r', false) if chars.peek() == Some(&'\n') => {}
+            ('\n', false) => {
+                record.push(std::mem::take(&mut field));
+                if record.iter().any(|f| !f.is_empty()) {
+                    records.push((start, std::mem::take(&mut record)));
+                }
+                record.clear();
+                line += 1;
+                start = line;
+            }
+            (c, _) => {
+                if c == '\n' {
+                    line += 1;
+                }
+                field.push(c);
+            }
+        }
+    }
+
+    record.push(field);
+    if record.iter().any(|f| !f.is_empty()) {
+        records.push((start, record));
+    }
+    records
+}
@@ -0,0 +1,138 @@
+use anyhow::{bail, Result};
+use rand::Rng;
+
+use crate::models::{Category, IncomeType, Period};
+use crate::state::rollover::previous_month;
+use crate::state::EntityType;
+
+use super::{HistoryImport, ImportRow};
+
+/// Expense names the made-up entries cycle through; later rounds get a number
+const EXPENSE_NAMES: &[&str] = &[
+    "Groceries",
+    "Rent",
+    "Electricity",
+    "Water",
+    "Internet",
+    "Phone",
+    "Fuel",
+    "Coffee",
+    "Restaurant",
+    "Pharmacy",
+    "Gym",
+    "Streaming",
+    "Insurance",
+    "Clothes",
+    "Books",
+    "Gifts",
+    "Parking",
+    "Taxi",
+    "Haircut",
+    "Pet food",
+];
+
+/// Income types that get an income each month, in the server's order
+const INCOMES_PER_MONTH: usize = 3;
+
+/// Share of expenses left unpaid (actual of 0)
+const UNPAID_SHARE: f64 = 0.1;
+
+/// Size of a made-up history, for `--seed`
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct SeedOptions {
+    pub months: usize,
+    /// Expenses in each month
+    pub expenses: usize,
+}
+
+impl Default for SeedOptions {
+    fn default() -> Self {
+        Self {
+            months: 12,
+            expenses: 100,
+        }
+    }
+}
+
+/// Made-up months of expenses and incomes, for trying tables, summaries and
+/// charts with a lot of data
+///
+/// The months end at `last` and go back from there. Entries use the server's
+/// categories, periods and income types, so the result imports like a file
+/// would. Each name keeps its projection across months, while the actual
+/// amount varies around it and some expenses are left unpaid.
+pub fn synthetic_history<R: Rng + ?Sized>(
+    rng: &mut R,
+    options: SeedOptions,
+    last: (i32, i32),
+    categories: &[Category],
+    periods: &[Period],
+    income_types: &[IncomeType],
+) -> Result<HistoryImport> {
+    if categories.is_empty() || periods.is_empty() {
+        bail!("Add at least one category and one period in Settings first");
+    }
+
+    let mut months = vec![last];
+    while months.len() < options.months {
+        months.push(previous_month(months[months.len() - 1]));
+    }
+    months.truncate(options.months);
+    months.reverse();
+
+    // Fixed per name, so the same expense looks alike from month to month
+    let expenses: Vec<(String, &Category, &Period, f64)> = (0..options.expenses)
+        .map(|index| {
+            let base = EXPENSE_NAMES[index % EXPENSE_NAMES.len()];
+            let name = match index / EXPENSE_NAMES.len() {
+                0 => base.to_string(),
+                round => format!("{} {}", base, round + 1),
+            };
+            let category = &categories[rng.random_range(0..categories.len())];
+            let period = &periods[index % periods.len()];
+            let projected = rng.random_range(5..500) as f64;
+            (name, category, period, projected)
+        })
+        .collect();
+    let incomes: Vec<(&IncomeType, f64)> = income_types
+        .iter()
+        .take(INCOMES_PER_MONTH)
+        .map(|income_type| (income_type, rng.random_range(10..50) as f64 * 100.0))
+        .collect();
+
+    let mut rows = Vec::new();
+    for (year, month) in months {
+        for (name, category, period, projected) in &expenses {
+            let actual = if rng.random_bool(UNPAID_SHARE) {
+                0.0
+            } else {
+                (projected * rng.random_range(0.6..1.4) * 100.0).round() / 100.0
+            };
+            rows.push(ImportRow {
+                line: rows.len() + 1,
+                year,
+                month,
+                kind: EntityType::Expense,
+                name: name.clone(),
+                category: category.name.clone(),
+                period: period.name.clone(),
+                projected: *projected,
+                actual,
+            });
+        }
+        for (index, (income_type, projected)) in incomes.iter().enumerate() {
+            rows.push(ImportRow {
+                line: rows.len() + 1,
+                year,
+                month,
+                kind: EntityType::Income,
+                name: income_type.name.clone(),
+                category: String::new(),
+                period: periods[index % periods.len()].name.clone(),
+                projected: *projected,
+                actual: *projected,
+            });
+        }
+    }
+    Ok(HistoryImport { rows })
+}
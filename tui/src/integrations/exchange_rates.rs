@@ -0,0 +1,46 @@
+use std::collections::BTreeMap;
+
+use anyhow::{bail, Context, Result};
+use reqwest::Client;
+use serde_json::Value;
+
+/// Rates from an exchange-rate API, as what one unit of each currency is
+/// worth in the base currency the URL asks for
+pub async fn fetch_rates(client: &Client, url: &str) -> Result<BTreeMap<String, f64>> {
+    let response = client
+        .get(url)
+        .send()
+        .await
+        .context("Exchange rates unreachable")?;
+    let status = response.status();
+    if !status.is_success() {
+        bail!("Exchange rates unavailable ({})", status.as_u16());
+    }
+    let body: Value = response
+        .json()
+        .await
+        .context("Exchange rates weren't JSON")?;
+    parse_rates(&body)
+}
+
+/// Invert the `rates` of a response, which give units of each currency per
+/// one of the base, e.g. `{"rates": {"EUR": 0.8}}` is EUR = 1.25
+pub fn parse_rates(body: &Value) -> Result<BTreeMap<String, f64>> {
+    let rates = match body["rates"].as_object() {
+        Some(rates) => rates,
+        None => {
+            let message = body["error-type"]
+                .as_str()
+                .or_else(|| body["error"].as_str())
+                .unwrap_or("no rates in the response");
+            bail!("Exchange rates unavailable: {}", message);
+        }
+    };
+    Ok(rates
+        .iter()
+        .filter_map(|(code, per_base)| {
+            let per_base = per_base.as_f64().filter(|r| *r > 0.0)?;
+            Some((code.to_ascii_uppercase(), 1.0 / per_base))
+        })
+        .collect())
+}
@@ -0,0 +1,290 @@
+use std::path::Path;
+
+use anyhow::{bail, Context, Result};
+use base64::engine::general_purpose::{STANDARD, URL_SAFE_NO_PAD};
+use base64::Engine;
+use reqwest::{Client, Response};
+use ring::rand::SystemRandom;
+use ring::signature::{RsaKeyPair, RSA_PKCS1_SHA256};
+use serde::Deserialize;
+use serde_json::{json, Value};
+
+use crate::models::{CategorySummary, Expense, Month, SummaryTotals};
+
+/// Sheets API the pushes go to
+pub const SHEETS_API: &str = "https://sheets.googleapis.com/v4";
+/// Permission to edit spreadsheets the service account has access to
+const SCOPE: &str = "https://www.googleapis.com/auth/spreadsheets";
+/// Lifetime of the signed token request; Google allows at most an hour
+const ASSERTION_SECS: i64 = 3600;
+
+/// Credentials from a Google service account key file
+#[derive(Debug, Clone, Deserialize)]
+pub struct ServiceAccount {
+    pub client_email: String,
+    /// PKCS#8 RSA key in PEM form
+    pub private_key: String,
+    #[serde(default = "default_token_uri")]
+    pub token_uri: String,
+}
+
+fn default_token_uri() -> String {
+    "https://oauth2.googleapis.com/token".to_string()
+}
+
+impl ServiceAccount {
+    /// Read a key file downloaded from the Google Cloud console
+    pub fn load(path: &Path) -> Result<Self> {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read {}", path.display()))?;
+        Self::parse(&content).with_context(|| format!("Invalid key file {}", path.display()))
+    }
+
+    pub fn parse(json: &str) -> Result<Self> {
+        Ok(serde_json::from_str(json)?)
+    }
+
+    /// Signed JWT that exchanges for an access token with the Sheets scope
+    ///
+    /// `now` is the current Unix time; the assertion is valid for an hour.
+    pub fn assertion(&self, now: i64) -> Result<String> {
+        let header = URL_SAFE_NO_PAD.encode(r#"{"alg":"RS256","typ":"JWT"}"#);
+        let claims = json!({
+            "iss": self.client_email,
+            "scope": SCOPE,
+            "aud": self.token_uri,
+            "iat": now,
+            "exp": now + ASSERTION_SECS,
+        });
+        let claims = URL_SAFE_NO_PAD.encode(claims.to_string());
+        let message = format!("{}.{}", header, claims);
+
+        let key = RsaKeyPair::from_pkcs8(&pem_to_der(&self.private_key)?)
+            .map_err(|e| anyhow::anyhow!("Invalid private key: {}", e))?;
+        let mut signature = vec![0; key.public().modulus_len()];
+        key.sign(
+            &RSA_PKCS1_SHA256,
+            &SystemRandom::new(),
+            message.as_bytes(),
+            &mut signature,
+        )
+        .map_err(|_| anyhow::anyhow!("Failed to sign the token request"))?;
+
+        Ok(format!("{}.{}", message, URL_SAFE_NO_PAD.encode(signature)))
+    }
+}
+
+/// Decode the base64 body of a PEM block
+fn pem_to_der(pem: &str) -> Result<Vec<u8>> {
+    let body: String = pem
+        .lines()
+        .map(str::trim)
+        .filter(|line| !line.starts_with("-----"))
+        .collect();
+    if body.is_empty() {
+        bail!("Invalid private key: no PEM data");
+    }
+    STANDARD
+        .decode(body)
+        .context("Invalid private key: bad base64")
+}
+
+/// Writes rows to tabs of one Google Sheet as a service account
+pub struct GoogleSheets {
+    client: Client,
+    account: ServiceAccount,
+    spreadsheet_id: String,
+    api_base: String,
+}
+
+impl GoogleSheets {
+    pub fn new(client: Client, account: ServiceAccount, spreadsheet_id: String) -> Self {
+        Self {
+            client,
+            account,
+            spreadsheet_id: spreadsheet_id.trim().to_string(),
+            api_base: SHEETS_API.to_string(),
+        }
+    }
+
+    /// Send API requests somewhere other than `SHEETS_API`
+    pub fn with_api_base(mut self, api_base: String) -> Self {
+        self.api_base = api_base.trim_end_matches('/').to_string();
+        self
+    }
+
+    /// Replace everything on the tab named `title` with `rows`
+    ///
+    /// The tab is added if the sheet doesn't have it yet; other tabs are left
+    /// alone.
+    pub async fn push(&self, title: &str, rows: &[Vec<Value>]) -> Result<()> {
+        let token = self.access_token().await?;
+        let spreadsheet = format!("{}/spreadsheets/{}", self.api_base, self.spreadsheet_id);
+
+        let response = self
+            .client
+            .get(&spreadsheet)
+            .bearer_auth(&token)
+            .query(&[("fields", "sheets.properties.title")])
+            .send()
+            .await?;
+        let metadata = checked(response, "read the sheet").await?;
+        let exists = metadata["sheets"]
+            .as_array()
+            .into_iter()
+            .flatten()
+            .any(|sheet| sheet["properties"]["title"] == title);
+
+        if !exists {
+            let body = json!({
+                "requests": [{ "addSheet": { "properties": { "title": title } } }]
+            });
+            let response = self
+                .client
+                .post(format!("{}:batchUpdate", spreadsheet))
+                .bearer_auth(&token)
+                .json(&body)
+                .send()
+                .await?;
+            checked(response, "add the tab").await?;
+        }
+
+        // Clear first, so a month with fewer rows leaves no stale ones behind
+        let range = a1_range(title);
+        let response = self
+            .client
+            .post(format!(
+                "{}/values/{}:clear",
+                spreadsheet,
+                urlencoding::encode(&range)
+            ))
+            .bearer_auth(&token)
+            .json(&json!({}))
+            .send()
+            .await?;
+        checked(response, "clear the tab").await?;
+
+        let response = self
+            .client
+            .put(format!(
+                "{}/values/{}",
+                spreadsheet,
+                urlencoding::encode(&format!("{}!A1", range))
+            ))
+            .bearer_auth(&token)
+            .query(&[("valueInputOption", "RAW")])
+            .json(&json!({ "majorDimension": "ROWS", "values": rows }))
+            .send()
+            .await?;
+        checked(response, "write the rows").await?;
+        Ok(())
+    }
+
+    /// Exchange a signed assertion for a short-lived access token
+    async fn access_token(&self) -> Result<String> {
+        let assertion = self.account.assertion(chrono::Utc::now().timestamp())?;
+        let response = self
+            .client
+            .post(&self.account.token_uri)
+            .form(&[
+                ("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer"),
+                ("assertion", assertion.as_str()),
+            ])
+            .send()
+            .await?;
+        let body = checked(response, "sign in with the service account").await?;
+        match body["access_token"].as_str() {
+            Some(token) => Ok(token.to_string()),
+            None => bail!("Google sign-in returned no access token"),
+        }
+    }
+}
+
+/// The JSON body of a successful response, or Google's error message
+async fn checked(response: Response, action: &str) -> Result<Value> {
+    let status = response.status();
+    let body: Value = response.json().await.unwrap_or(Value::Null);
+    if status.is_success() {
+        return Ok(body);
+    }
+    // Token errors use error_description, API errors error.message
+    let message = body["error"]["message"]
+        .as_str()
+        .or_else(|| body["error_description"].as_str())
+        .unwrap_or_else(|| status.canonical_reason().unwrap_or("request failed"));
+    bail!("Failed to {} ({}): {}", action, status.as_u16(), message)
+}
+
+/// A whole tab in A1 notation, e.g. `'March 2024'`
+fn a1_range(title: &str) -> String {
+    format!("'{}'", title.replace('\'', "''"))
+}
+
+/// Rows for one month's tab: totals, spending per category, then every expense
+pub fn month_rows(
+    month: &Month,
+    totals: Option<&SummaryTotals>,
+    categories: &[CategorySummary],
+    expenses: &[Expense],
+) -> Vec<Vec<Value>> {
+    let mut rows = vec![vec![json!(month.display_name())], Vec::new()];
+
+    if let Some(totals) = totals {
+        rows.push(vec![json!("Summary"), json!("Projected"), json!("Actual")]);
+        rows.push(vec![
+            json!("Income"),
+            json!(totals.total_projected_income),
+            json!(totals.total_current_income),
+        ]);
+        rows.push(vec![
+            json!("Expenses"),
+            json!(totals.total_projected_expenses),
+            json!(totals.total_current_expenses),
+        ]);
+        rows.push(vec![
+            json!("Balance"),
+            json!(totals.total_projected),
+            json!(totals.total_current),
+        ]);
+        rows.push(Vec::new());
+    }
+
+    if !categories.is_empty() {
+        rows.push(vec![json!("Category"), json!("Projected"), json!("Actual")]);
+        for category in categories {
+            rows.push(vec![
+                json!(category.category),
+                json!(category.projected),
+                json!(category.total),
+            ]);
+        }
+        rows.push(Vec::new());
+    }
+
+    rows.push(
+        [
+            "Expense",
+            "Category",
+            "Period",
+            "Projected",
+            "Actual",
+            "Date",
+            "Notes",
+        ]
+        .iter()
+        .map(|heading| json!(heading))
+        .collect(),
+    );
+    for expense in expenses {
+        rows.push(vec![
+            json!(expense.expense_name),
+            json!(expense.category),
+            json!(expense.period),
+            json!(expense.projected),
+            json!(expense.cost),
+            json!(expense.expense_date.as_deref().unwrap_or_default()),
+            json!(expense.notes.as_deref().unwrap_or_default()),
+        ]);
+    }
+    rows
+}
@@ -0,0 +1,8 @@
+//! Services outside the budget server that data can be pushed to or
+//! fetched from.
+
+pub mod exchange_rates;
+pub mod google_sheets;
+
+pub use exchange_rates::{fetch_rates, parse_rates};
+pub use google_sheets::{month_rows, GoogleSheets, ServiceAccount};
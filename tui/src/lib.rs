@@ -1,14 +1,24 @@
 //! Budget TUI - Terminal User Interface for Budget Management
 //!
 //! This library provides the core components for a terminal-based budget
-//! management application built with Ratatui.
+//! management application built with Ratatui. The API client and models
+//! come from the `budget-sdk` crate and are re-exported here, as are the
+//! checks forms run on request bodies before sending them.
+
+pub use budget_sdk::{api, models, validate};
 
-pub mod api;
 pub mod app;
+pub mod changelog;
+pub mod check;
+pub mod clock;
 pub mod config;
 pub mod event;
-pub mod models;
+pub mod export;
+pub mod feedback;
+pub mod import;
+pub mod integrations;
 pub mod state;
+pub mod storage;
 pub mod ui;
 
 pub use models::*;
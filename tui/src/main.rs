@@ -1,4 +1,4 @@
-use std::io;
+use std::io::{self, IsTerminal};
 
 use anyhow::Result;
 use crossterm::{
@@ -8,11 +8,238 @@ use crossterm::{
 };
 use ratatui::{backend::CrosstermBackend, Terminal};
 
-use budget_tui::app::App;
+use budget_tui::api::BudgetApi;
+use budget_tui::app::{App, VERSION};
+use budget_tui::check::{check_server, CheckStatus};
+use budget_tui::clock::Clock;
+use budget_tui::config::backup::{self, BACKUP_FILE_NAME, SHARED_FILE_NAME};
+use budget_tui::config::bundle::{self, SettingsBundle, BUNDLE_FILE_NAME};
+use budget_tui::config::{crypt, Config};
 use budget_tui::event::EventHandler;
+use budget_tui::export::month_csv_file_name;
+use budget_tui::import::{parse_month, synthetic_history, HistoryImport, SeedOptions};
+use budget_tui::state::rollover::{calendar_month, previous_month};
+use budget_tui::state::{DashboardTab, Screen};
+use budget_tui::ui::format_size;
+use budget_tui::ui::inline::{self, InlineView};
+use budget_tui::ui::low_color;
+
+const USAGE: &str = "Usage: budget-tui [OPTIONS] [--inline [summary|expenses|income]]
+       budget-tui --import FILE [--yes]
+       budget-tui --import --stdin [--format jsonl|csv]
+       budget-tui --export YYYY-MM [FILE]
+       budget-tui --export-settings [FILE]
+       budget-tui --import-settings FILE [--yes]
+       budget-tui --check-server
+       budget-tui --check-config
+       budget-tui --encrypt-config | --decrypt-config
+       budget-tui --export-config [FILE] [--no-secrets]
+       budget-tui --import-config FILE [--yes]
+       budget-tui --seed [--months N] [--expenses N] [--yes]
+
+Options:
+  --config FILE                    Read and save this config file instead of
+                                   the one in the config dir
+  --profile NAME                   Use a server profile for this run only
+  --api-url URL, --api-key KEY     Use this server and/or key for this run
+                                   only, ahead of BUDGET_API_URL/BUDGET_API_KEY;
+                                   these three go with any of the options below
+  --month YYYY-MM                  Open on this month instead of the current
+                                   one (or BUDGET_DEFAULT_MONTH's), also with
+                                   --inline
+  --tab NAME                       Open on a tab: summary, expenses, income,
+                                   charts, debts or settings
+  --inline, --no-altscreen [VIEW]  Print a view of the current month to the
+                                   terminal and exit (default: summary)
+  --import FILE [--yes]            Backfill past months from a CSV file,
+                                   asking first unless --yes is given
+  --import --stdin [--format F]    Add expenses and incomes piped in as JSON
+                                   lines (or CSV) to existing months, without
+                                   asking (default format: jsonl)
+  --export YYYY-MM [FILE]          Download a month as CSV, in the format
+                                   --import reads (default: budget-YYYY-MM.csv)
+  --export-settings [FILE]         Save categories, periods, income types and
+                                   local settings to a bundle
+                                   (default: budget-settings.toml)
+  --import-settings FILE [--yes]   Add a bundle's missing categories, periods
+                                   and income types to the server and use its
+                                   local settings, asking first unless --yes
+  --check-server                   Check the configured server step by step:
+                                   DNS, TLS, API key, login and endpoints
+  --check-config                   Check the config file for unknown settings,
+                                   bad URLs and unreadable key files; exits
+                                   with 1 when it finds any
+  --encrypt-config                 Encrypt the config file (server, keys, login
+                                   and settings) with a passphrase asked at
+                                   every start, or change the passphrase
+  --decrypt-config                 Store the config file in plain text again
+  --export-config [FILE] [--no-secrets]
+                                   Save every profile, key, session and setting
+                                   to an age file under a passphrase, for
+                                   another machine (default: budget-config.age);
+                                   with --no-secrets, plain TOML without keys
+                                   or sessions (default: budget-config.toml)
+  --import-config FILE [--yes]     Replace this machine's config with a file
+                                   from --export-config, asking first unless
+                                   --yes; the old file is kept as .bak
+  --seed [--months N] [--expenses N] [--yes]
+                                   Fill a test server with made-up months
+                                   before its oldest one (default: 12 months
+                                   of 100 expenses), asking first unless --yes
+  -V, --version                    Show the version
+  -h, --help                       Show this help";
 
 #[tokio::main]
 async fn main() -> Result<()> {
+    let mut args: Vec<String> = std::env::args().skip(1).collect();
+    // These go with any of the other options, so they're taken out first
+    let profile = take_option(&mut args, "--profile", "a name");
+    let profile = profile.as_deref();
+    if let Some(path) = take_option(&mut args, "--config", "a file") {
+        Config::use_path(path.into());
+    }
+    let api_url = take_option(&mut args, "--api-url", "a URL");
+    let api_key = take_option(&mut args, "--api-key", "a key");
+    if api_url.is_some() || api_key.is_some() {
+        Config::use_server(api_url, api_key);
+    }
+    let start_month = take_option(&mut args, "--month", "a month").map(|month| {
+        parse_month(&month).unwrap_or_else(|| {
+            eprintln!("--month needs a month like 2024-03, not '{month}'\n\n{USAGE}");
+            std::process::exit(2);
+        })
+    });
+    let start_tab = take_option(&mut args, "--tab", "a tab name").map(|name| {
+        DashboardTab::from_name(&name).unwrap_or_else(|| {
+            let names: Vec<&str> = DashboardTab::all().iter().map(|t| t.as_str()).collect();
+            eprintln!("Unknown tab '{name}' (expected {})", names.join(", "));
+            std::process::exit(2);
+        })
+    });
+    match args.first().map(String::as_str) {
+        None => {}
+        Some("--inline") | Some("--no-altscreen") => {
+            return run_inline(args.get(1).map(String::as_str), profile, start_month).await;
+        }
+        Some("--import") if args.iter().any(|arg| arg == "--stdin") => {
+            let format = args
+                .iter()
+                .position(|arg| arg == "--format")
+                .map(|i| args.get(i + 1).map_or("", String::as_str))
+                .unwrap_or("jsonl");
+            return run_batch_import(format, profile).await;
+        }
+        Some("--import") => {
+            let path = match args.get(1) {
+                Some(path) => path,
+                None => {
+                    eprintln!("--import needs a file\n\n{USAGE}");
+                    std::process::exit(2);
+                }
+            };
+            let yes = args.iter().skip(2).any(|arg| arg == "--yes" || arg == "-y");
+            return run_import(path, yes, profile).await;
+        }
+        Some("--export") => {
+            let month = match args.get(1) {
+                Some(month) => month,
+                None => {
+                    eprintln!("--export needs a month\n\n{USAGE}");
+                    std::process::exit(2);
+                }
+            };
+            return run_export(month, args.get(2).map(String::as_str), profile).await;
+        }
+        Some("--export-settings") => {
+            return run_settings_export(args.get(1).map(String::as_str), profile).await;
+        }
+        Some("--import-settings") => {
+            let path = match args.get(1) {
+                Some(path) => path,
+                None => {
+                    eprintln!("--import-settings needs a file\n\n{USAGE}");
+                    std::process::exit(2);
+                }
+            };
+            let yes = args.iter().skip(2).any(|arg| arg == "--yes" || arg == "-y");
+            return run_settings_import(path, yes, profile).await;
+        }
+        Some("--check-server") => {
+            return run_check_server(profile).await;
+        }
+        Some("--check-config") => {
+            return run_check_config(profile);
+        }
+        Some("--export-config") => {
+            let secrets = !args.iter().any(|arg| arg == "--no-secrets");
+            let path = args
+                .iter()
+                .skip(1)
+                .find(|arg| !arg.starts_with("--"))
+                .map(String::as_str);
+            return run_config_export(path, secrets);
+        }
+        Some("--import-config") => {
+            let path = match args.get(1) {
+                Some(path) => path,
+                None => {
+                    eprintln!("--import-config needs a file\n\n{USAGE}");
+                    std::process::exit(2);
+                }
+            };
+            let yes = args.iter().skip(2).any(|arg| arg == "--yes" || arg == "-y");
+            return run_config_import(path, yes);
+        }
+        Some("--encrypt-config") => {
+            return run_encrypt_config(true);
+        }
+        Some("--decrypt-config") => {
+            return run_encrypt_config(false);
+        }
+        Some("--seed") => {
+            let mut options = SeedOptions::default();
+            let mut yes = false;
+            let mut rest = args.iter().skip(1);
+            while let Some(arg) = rest.next() {
+                let count = match arg.as_str() {
+                    "--yes" | "-y" => {
+                        yes = true;
+                        continue;
+                    }
+                    "--months" => &mut options.months,
+                    "--expenses" => &mut options.expenses,
+                    other => {
+                        eprintln!("Unknown --seed option: {other}\n\n{USAGE}");
+                        std::process::exit(2);
+                    }
+                };
+                *count = match rest.next().and_then(|n| n.parse().ok()) {
+                    Some(n) => n,
+                    None => {
+                        eprintln!("{arg} needs a number\n\n{USAGE}");
+                        std::process::exit(2);
+                    }
+                };
+            }
+            return run_seed(options, yes, profile).await;
+        }
+        Some("-h") | Some("--help") => {
+            println!("{USAGE}");
+            return Ok(());
+        }
+        Some("-V") | Some("--version") => {
+            println!("budget-tui {VERSION}");
+            return Ok(());
+        }
+        Some(arg) => {
+            eprintln!("Unknown argument: {arg}\n\n{USAGE}");
+            std::process::exit(2);
+        }
+    }
+
+    // Ask for an encrypted config's passphrase while the terminal is still normal
+    Config::unlock()?;
+
     // Setup terminal
     enable_raw_mode()?;
     let mut stdout = io::stdout();
@@ -21,7 +248,8 @@ async fn main() -> Result<()> {
     let mut terminal = Terminal::new(backend)?;
 
     // Create app and run it
-    let mut app = App::new().await?;
+    let mut app = App::new(profile).await?;
+    app.start_on(start_month, start_tab);
     let event_handler = EventHandler::new(250);
     let res = app.run(&mut terminal, event_handler).await;
 
@@ -41,3 +269,536 @@ async fn main() -> Result<()> {
 
     Ok(())
 }
+
+/// Take `flag` and the value after it out of `args`
+fn take_option(args: &mut Vec<String>, flag: &str, what: &str) -> Option<String> {
+    let i = args.iter().position(|arg| arg == flag)?;
+    if i + 1 >= args.len() {
+        eprintln!("{flag} needs {what}\n\n{USAGE}");
+        std::process::exit(2);
+    }
+    let value = args.remove(i + 1);
+    args.remove(i);
+    Some(value)
+}
+
+/// Print one view into the normal scrollback instead of taking over the screen
+async fn run_inline(
+    view: Option<&str>,
+    profile: Option<&str>,
+    month: Option<(i32, i32)>,
+) -> Result<()> {
+    let view = match view {
+        Some(name) => InlineView::parse(name).ok_or_else(|| {
+            let names: Vec<&str> = InlineView::ALL.iter().map(InlineView::name).collect();
+            anyhow::anyhow!("Unknown view '{name}' (expected {})", names.join(", "))
+        })?,
+        None => InlineView::Summary,
+    };
+
+    let mut app = App::new(profile).await?;
+    app.start_on(month, None);
+    app.load_inline().await?;
+
+    let width = crossterm::terminal::size()
+        .map(|(width, _)| width)
+        .unwrap_or(inline::DEFAULT_WIDTH);
+    let mut buffer = inline::render(&app.state, view, width)?;
+    app.theme.apply(&mut buffer);
+    if app.low_color {
+        low_color::simplify(&mut buffer);
+    }
+
+    if let Some(month) = app.state.selected_month() {
+        println!("{}", month.name);
+    }
+    let color = io::stdout().is_terminal() && std::env::var_os("NO_COLOR").is_none();
+    print!("{}", inline::to_ansi(&buffer, color));
+    Ok(())
+}
+
+/// Backfill past months from a spreadsheet, oldest first
+async fn run_import(path: &str, yes: bool, profile: Option<&str>) -> Result<()> {
+    let text =
+        std::fs::read_to_string(path).map_err(|e| anyhow::anyhow!("Failed to read {path}: {e}"))?;
+    let import = HistoryImport::parse(&text)?;
+
+    let app = App::new(profile).await?;
+    if app.state.screen != Screen::Dashboard {
+        anyhow::bail!("Not logged in - run budget-tui once to log in");
+    }
+    let months = app.api.get_months().await?;
+    let categories = app.api.get_categories().await?;
+    let periods = app.api.get_periods().await?;
+    let income_types = app.api.get_income_types().await?;
+
+    let plan = import.plan(&months, &categories, &periods, &income_types);
+    println!(
+        "{} months to create ({} expenses, {} incomes)",
+        plan.new_months.len(),
+        plan.expenses,
+        plan.incomes
+    );
+    if !plan.existing_months.is_empty() {
+        let existing: Vec<String> = plan
+            .existing_months
+            .iter()
+            .map(|(year, month)| format!("{year}-{month:02}"))
+            .collect();
+        println!(
+            "Skipping months that already exist: {}",
+            existing.join(", ")
+        );
+    }
+    if !plan.missing.is_empty() {
+        anyhow::bail!("Add these in Settings first: {}", plan.missing.join(", "));
+    }
+    if !plan.is_ready() {
+        println!("Nothing to import");
+        return Ok(());
+    }
+
+    if !yes {
+        print!("Proceed? [y/N] ");
+        io::Write::flush(&mut io::stdout())?;
+        let mut answer = String::new();
+        io::stdin().read_line(&mut answer)?;
+        if !matches!(answer.trim().to_lowercase().as_str(), "y" | "yes") {
+            println!("Cancelled");
+            return Ok(());
+        }
+    }
+
+    let summary = import
+        .run(
+            &app.api,
+            &plan,
+            &categories,
+            &periods,
+            &income_types,
+            |progress| {
+                println!(
+                    "[{}/{}] {}: {} expenses, {} incomes",
+                    progress.index,
+                    progress.total,
+                    progress.month_name,
+                    progress.expenses,
+                    progress.incomes
+                )
+            },
+        )
+        .await?;
+    println!(
+        "Imported {} months, {} expenses and {} incomes",
+        summary.months, summary.expenses, summary.incomes
+    );
+    Ok(())
+}
+
+/// Add expenses and incomes piped in on stdin to the months they name
+///
+/// Meant for scripts, so nothing is asked: the input can't also answer a
+/// prompt. Rows without a month go in the current calendar month.
+async fn run_batch_import(format: &str, profile: Option<&str>) -> Result<()> {
+    let mut text = String::new();
+    io::Read::read_to_string(&mut io::stdin(), &mut text)?;
+    let current = calendar_month(Clock::from_env()?.today());
+    let import = match format {
+        "jsonl" | "ndjson" => HistoryImport::parse_jsonl(&text, current)?,
+        "csv" => HistoryImport::parse(&text)?,
+        other => anyhow::bail!("Unknown format '{other}' (expected jsonl or csv)"),
+    };
+
+    let app = App::new(profile).await?;
+    if app.state.screen != Screen::Dashboard {
+        anyhow::bail!("Not logged in - run budget-tui once to log in");
+    }
+    let months = app.api.get_months().await?;
+    let categories = app.api.get_categories().await?;
+    let periods = app.api.get_periods().await?;
+    let income_types = app.api.get_income_types().await?;
+
+    let targets = import.existing_months(&months, &categories, &periods, &income_types)?;
+    let summary = import
+        .add_to_months(
+            &app.api,
+            &targets,
+            &categories,
+            &periods,
+            &income_types,
+            |progress| {
+                println!(
+                    "{}: {} expenses, {} incomes",
+                    progress.month_name, progress.expenses, progress.incomes
+                )
+            },
+        )
+        .await?;
+    println!(
+        "Added {} expenses and {} incomes",
+        summary.expenses, summary.incomes
+    );
+    Ok(())
+}
+
+/// Fill the server with made-up history, to try the app with a lot of data
+async fn run_seed(options: SeedOptions, yes: bool, profile: Option<&str>) -> Result<()> {
+    let app = App::new(profile).await?;
+    if app.state.screen != Screen::Dashboard {
+        anyhow::bail!("Not logged in - run budget-tui once to log in");
+    }
+    let months = app.api.get_months().await?;
+    let categories = app.api.get_categories().await?;
+    let periods = app.api.get_periods().await?;
+    let income_types = app.api.get_income_types().await?;
+
+    // Before the oldest month, so real months are never touched
+    let last = match months.iter().map(|m| (m.year, m.month)).min() {
+        Some(oldest) => previous_month(oldest),
+        None => calendar_month(app.state.clock.today()),
+    };
+    let import = synthetic_history(
+        &mut rand::rng(),
+        options,
+        last,
+        &categories,
+        &periods,
+        &income_types,
+    )?;
+    let plan = import.plan(&months, &categories, &periods, &income_types);
+    if !plan.is_ready() {
+        println!("Nothing to seed");
+        return Ok(());
+    }
+    let (first_year, first_month) = plan.new_months[0];
+    println!(
+        "{} made-up months to create, {first_year}-{first_month:02} to {}-{:02} ({} expenses, {} incomes)",
+        plan.new_months.len(),
+        last.0,
+        last.1,
+        plan.expenses,
+        plan.incomes
+    );
+    println!("Server: {}", app.config.server.url);
+
+    if !yes {
+        print!("Proceed? Use a test server, the data is hard to remove [y/N] ");
+        io::Write::flush(&mut io::stdout())?;
+        let mut answer = String::new();
+        io::stdin().read_line(&mut answer)?;
+        if !matches!(answer.trim().to_lowercase().as_str(), "y" | "yes") {
+            println!("Cancelled");
+            return Ok(());
+        }
+    }
+
+    let started = std::time::Instant::now();
+    let summary = import
+        .run(
+            &app.api,
+            &plan,
+            &categories,
+            &periods,
+            &income_types,
+            |progress| {
+                println!(
+                    "[{}/{}] {}: {} expenses, {} incomes",
+                    progress.index,
+                    progress.total,
+                    progress.month_name,
+                    progress.expenses,
+                    progress.incomes
+                )
+            },
+        )
+        .await?;
+    println!(
+        "Seeded {} months, {} expenses and {} incomes in {:.1}s",
+        summary.months,
+        summary.expenses,
+        summary.incomes,
+        started.elapsed().as_secs_f64()
+    );
+    Ok(())
+}
+
+/// Download one month as CSV, showing progress on a terminal
+async fn run_export(month: &str, path: Option<&str>, profile: Option<&str>) -> Result<()> {
+    let (year, number) = parse_month(month)
+        .ok_or_else(|| anyhow::anyhow!("'{month}' is not a month (expected YYYY-MM)"))?;
+
+    let app = App::new(profile).await?;
+    if app.state.screen != Screen::Dashboard {
+        anyhow::bail!("Not logged in - run budget-tui once to log in");
+    }
+    let months = app.api.get_months().await?;
+    let month = months
+        .iter()
+        .find(|m| m.year == year && m.month == number)
+        .ok_or_else(|| anyhow::anyhow!("{year}-{number:02} doesn't exist on the server"))?;
+    let path = path
+        .map(str::to_string)
+        .unwrap_or_else(|| month_csv_file_name(month));
+
+    let show_progress = io::stderr().is_terminal();
+    let size = app
+        .api
+        .months()
+        .export_csv(month.id, path.as_ref(), |received, total| {
+            if show_progress {
+                match total {
+                    Some(total) => eprint!("\r{} of {}", format_size(received), format_size(total)),
+                    None => eprint!("\r{}", format_size(received)),
+                }
+            }
+        })
+        .await;
+    if show_progress {
+        eprint!("\r\x1b[K");
+    }
+    let size = size?;
+    println!(
+        "{} saved to {path} ({})",
+        month.display_name(),
+        format_size(size)
+    );
+    Ok(())
+}
+
+/// Save the server's reference data and the local settings to one file
+async fn run_settings_export(path: Option<&str>, profile: Option<&str>) -> Result<()> {
+    let path = path.unwrap_or(BUNDLE_FILE_NAME);
+
+    let app = App::new(profile).await?;
+    if app.state.screen != Screen::Dashboard {
+        anyhow::bail!("Not logged in - run budget-tui once to log in");
+    }
+    let categories = app.api.get_categories().await?;
+    let periods = app.api.get_periods().await?;
+    let income_types = app.api.get_income_types().await?;
+
+    let bundle = SettingsBundle::new(&app.config, &categories, &periods, &income_types);
+    std::fs::write(path, bundle.to_toml()?)
+        .map_err(|e| anyhow::anyhow!("Failed to write {path}: {e}"))?;
+    println!(
+        "Saved {} categories, {} periods, {} income types and local settings to {path}",
+        categories.len(),
+        periods.len(),
+        income_types.len()
+    );
+    Ok(())
+}
+
+/// Set up the server and this machine from a settings bundle
+async fn run_settings_import(path: &str, yes: bool, profile: Option<&str>) -> Result<()> {
+    let text =
+        std::fs::read_to_string(path).map_err(|e| anyhow::anyhow!("Failed to read {path}: {e}"))?;
+    let bundle = SettingsBundle::parse(&text)?;
+
+    let mut app = App::new(profile).await?;
+    if app.state.screen != Screen::Dashboard {
+        anyhow::bail!("Not logged in - run budget-tui once to log in");
+    }
+    let categories = app.api.get_categories().await?;
+    let periods = app.api.get_periods().await?;
+    let income_types = app.api.get_income_types().await?;
+
+    let plan = bundle.plan(&categories, &periods, &income_types);
+    println!(
+        "{} categories, {} periods and {} income types to add ({} already there)",
+        plan.categories.len(),
+        plan.periods.len(),
+        plan.income_types.len(),
+        plan.existing
+    );
+
+    if !yes {
+        print!("Proceed? Local settings in the bundle replace yours [y/N] ");
+        io::Write::flush(&mut io::stdout())?;
+        let mut answer = String::new();
+        io::stdin().read_line(&mut answer)?;
+        if !matches!(answer.trim().to_lowercase().as_str(), "y" | "yes") {
+            println!("Cancelled");
+            return Ok(());
+        }
+    }
+
+    let created = bundle::create_missing(&app.api, &plan).await?;
+    let applied = bundle.apply_settings(&mut app.config);
+    if !applied.is_empty() {
+        app.config.save()?;
+    }
+    println!(
+        "Added {created} items; settings replaced: {}",
+        if applied.is_empty() {
+            "none".to_string()
+        } else {
+            applied.join(", ")
+        }
+    );
+    Ok(())
+}
+
+/// Encrypt the config file with a new passphrase, or decrypt it
+fn run_encrypt_config(encrypt: bool) -> Result<()> {
+    let mut config = Config::load()?;
+    if !encrypt {
+        if !config.is_encrypted() {
+            println!("The config file isn't encrypted");
+            return Ok(());
+        }
+        config.set_passphrase(None);
+        config.save()?;
+        println!("Config file decrypted");
+        return Ok(());
+    }
+
+    let passphrase = ask_new_passphrase()?;
+    if passphrase.is_empty() {
+        anyhow::bail!("The passphrase can't be empty");
+    }
+    config.set_passphrase(Some(passphrase));
+    config.save()?;
+    println!(
+        "Config file encrypted; set {} to start without the prompt",
+        crypt::ENV_PASSPHRASE
+    );
+    Ok(())
+}
+
+/// Save the whole config to an encrypted backup, or without its secrets to
+/// plain TOML
+fn run_config_export(path: Option<&str>, secrets: bool) -> Result<()> {
+    let config = Config::load()?;
+    if !secrets {
+        let path = path.unwrap_or(SHARED_FILE_NAME);
+        std::fs::write(path, backup::export_shared(&config)?)
+            .map_err(|e| anyhow::anyhow!("Failed to write {path}: {e}"))?;
+        println!("Saved the config without keys or sessions to {path}");
+        return Ok(());
+    }
+
+    let path = path.unwrap_or(BACKUP_FILE_NAME);
+    let passphrase = ask_new_passphrase()?;
+    if passphrase.is_empty() {
+        anyhow::bail!("The passphrase can't be empty");
+    }
+    let sealed = backup::export(&config, &passphrase)?;
+    std::fs::write(path, sealed).map_err(|e| anyhow::anyhow!("Failed to write {path}: {e}"))?;
+    println!("Saved the config to {path}; it holds your keys, so keep the passphrase safe");
+    Ok(())
+}
+
+/// Replace this machine's config with an exported one
+fn run_config_import(path: &str, yes: bool) -> Result<()> {
+    let data = std::fs::read(path).map_err(|e| anyhow::anyhow!("Failed to read {path}: {e}"))?;
+    let imported = if backup::is_encrypted(&data) {
+        let passphrase = crypt::prompt("Backup passphrase: ")?;
+        backup::import(&data, &passphrase)?
+    } else {
+        let text = String::from_utf8(data)
+            .map_err(|_| anyhow::anyhow!("{path} is neither a config backup nor TOML"))?;
+        backup::import_shared(&text)?
+    };
+    for problem in imported.problems() {
+        eprintln!("Warning: {problem}");
+    }
+    let profiles = imported.profile_names();
+    if profiles.is_empty() {
+        println!("Config for {}", imported.server.url);
+    } else {
+        println!("Config with profiles: {}", profiles.join(", "));
+    }
+
+    if !yes {
+        print!("Replace this machine's config? The current file is kept as .bak [y/N] ");
+        io::Write::flush(&mut io::stdout())?;
+        let mut answer = String::new();
+        io::stdin().read_line(&mut answer)?;
+        if !matches!(answer.trim().to_lowercase().as_str(), "y" | "yes") {
+            println!("Cancelled");
+            return Ok(());
+        }
+    }
+
+    // Keeps this machine's keys and file encryption
+    let current = if Config::config_path()?.exists() {
+        Some(Config::load()?)
+    } else {
+        None
+    };
+    backup::install(imported, current.as_ref())?;
+    println!("Config imported");
+    Ok(())
+}
+
+/// Ask for a new passphrase twice
+fn ask_new_passphrase() -> Result<crypt::Passphrase> {
+    let passphrase = crypt::prompt("New passphrase: ")?;
+    if crypt::prompt("Repeat it: ")? != passphrase {
+        anyhow::bail!("The passphrases don't match");
+    }
+    Ok(passphrase)
+}
+
+/// Check the config file, printing a line per problem
+///
+/// Exits with 1 when the file doesn't load or has problems.
+fn run_check_config(profile: Option<&str>) -> Result<()> {
+    let path = Config::config_path()?;
+    if !path.exists() {
+        println!(
+            "No config file at {}; the defaults are used",
+            path.display()
+        );
+        return Ok(());
+    }
+    let config = match Config::load_profile(profile) {
+        Ok(config) => config,
+        Err(e) => {
+            eprintln!("{}: {:#}", path.display(), e);
+            std::process::exit(1);
+        }
+    };
+    let problems = config.problems();
+    if problems.is_empty() {
+        println!("{} looks good", path.display());
+        return Ok(());
+    }
+    for problem in problems {
+        eprintln!("{}", problem);
+    }
+    eprintln!("{} problem(s) in {}", problems.len(), path.display());
+    std::process::exit(1);
+}
+
+/// Check the configured server, printing a line per step
+async fn run_check_server(profile: Option<&str>) -> Result<()> {
+    let config = Config::load_profile(profile)?;
+    println!("Checking {}", config.server.url);
+
+    let color = io::stdout().is_terminal() && std::env::var_os("NO_COLOR").is_none();
+    let results = check_server(
+        &config.server.url,
+        &config.server.api_key,
+        &config.network.client_options(),
+        |result| {
+            let label = result.status.label();
+            let label = match (color, result.status) {
+                (false, _) => label.to_string(),
+                (true, CheckStatus::Pass) => format!("\x1b[32m{label}\x1b[0m"),
+                (true, CheckStatus::Warn) => format!("\x1b[33m{label}\x1b[0m"),
+                (true, CheckStatus::Fail) => format!("\x1b[31m{label}\x1b[0m"),
+                (true, CheckStatus::Skip) => format!("\x1b[90m{label}\x1b[0m"),
+            };
+            println!("[{label}] {:<14} {}", result.name, result.detail);
+        },
+    )
+    .await;
+
+    if results.iter().any(|r| r.status == CheckStatus::Fail) {
+        std::process::exit(1);
+    }
+    println!("Server looks good");
+    Ok(())
+}
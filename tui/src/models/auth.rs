@@ -1,35 +0,0 @@
-use serde::{Deserialize, Serialize};
-
-#[derive(Debug, Clone, Serialize, Deserialize)]
-pub struct User {
-    pub id: i32,
-    pub email: String,
-    pub full_name: Option<String>,
-    pub is_active: bool,
-    pub is_admin: bool,
-}
-
-#[derive(Debug, Clone, Serialize)]
-pub struct UserLogin {
-    pub email: String,
-    pub password: String,
-}
-
-#[derive(Debug, Clone, Serialize, Deserialize)]
-pub struct TokenResponse {
-    pub access_token: String,
-    pub token_type: String,
-    pub user_id: i32,
-    pub email: String,
-}
-
-#[derive(Debug, Clone, Serialize)]
-pub struct ChangePasswordRequest {
-    pub current_password: String,
-    pub new_password: String,
-}
-
-#[derive(Debug, Clone, Deserialize)]
-pub struct ChangePasswordResponse {
-    pub message: String,
-}
@@ -1,22 +0,0 @@
-use serde::{Deserialize, Serialize};
-
-#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
-pub struct Category {
-    pub id: i32,
-    pub name: String,
-    pub color: String,
-}
-
-#[derive(Debug, Clone, Serialize)]
-pub struct CategoryCreate {
-    pub name: String,
-    #[serde(skip_serializing_if = "Option::is_none")]
-    pub color: Option<String>,
-}
-
-#[derive(Debug, Clone, Serialize)]
-pub struct CategoryUpdate {
-    pub name: String,
-    #[serde(skip_serializing_if = "Option::is_none")]
-    pub color: Option<String>,
-}
@@ -1,17 +0,0 @@
-mod auth;
-mod category;
-mod expense;
-mod income;
-mod income_type;
-mod month;
-mod period;
-mod summary;
-
-pub use auth::*;
-pub use category::*;
-pub use expense::*;
-pub use income::*;
-pub use income_type::*;
-pub use month::*;
-pub use period::*;
-pub use summary::*;
@@ -0,0 +1,117 @@
+//! End-of-month suggestions
+//!
+//! In the last days of an open month the Summary lists what is usually left
+//! before closing it: incomes not received yet, expenses still without a cost,
+//! and categories far under budget, whose budget could shrink next month.
+//! Each rule looks at the loaded data only, so filters on the Expenses tab
+//! narrow the expense suggestions too.
+
+use chrono::NaiveDate;
+
+use crate::state::AppState;
+
+/// What a suggestion is about
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum SuggestionKind {
+    /// An income with nothing received
+    NotReceived,
+    /// An expense with a projection but no cost
+    NoCost,
+    /// A category that used little of its budget
+    UnderBudget,
+}
+
+#[derive(Debug, Clone, PartialEq)]
+pub struct Suggestion {
+    pub kind: SuggestionKind,
+    pub message: String,
+}
+
+type Rule = fn(&AppState) -> Vec<Suggestion>;
+
+/// Rules in the order their suggestions are listed
+const RULES: [Rule; 3] = [
+    incomes_not_received,
+    expenses_without_cost,
+    categories_under_budget,
+];
+
+fn incomes_not_received(app: &AppState) -> Vec<Suggestion> {
+    app.data
+        .incomes
+        .iter()
+        .filter(|income| income.amount == 0.0 && income.projected > 0.0)
+        .map(|income| {
+            let name = app
+                .data
+                .income_types
+                .iter()
+                .find(|t| t.id == income.income_type_id)
+                .map_or("Income", |t| t.name.as_str());
+            Suggestion {
+                kind: SuggestionKind::NotReceived,
+                message: format!(
+                    "{} ({}) not received - {} expected",
+                    name,
+                    app.period_label(&income.period),
+                    app.money.format(income.projected)
+                ),
+            }
+        })
+        .collect()
+}
+
+fn expenses_without_cost(app: &AppState) -> Vec<Suggestion> {
+    app.data
+        .expenses
+        .iter()
+        .filter(|expense| expense.cost == 0.0 && expense.projected > 0.0)
+        .map(|expense| Suggestion {
+            kind: SuggestionKind::NoCost,
+            message: format!(
+                "{} has no cost yet - {} projected",
+                expense.expense_name,
+                app.money.format(expense.projected)
+            ),
+        })
+        .collect()
+}
+
+fn categories_under_budget(app: &AppState) -> Vec<Suggestion> {
+    let under = app.advisor.under_percent / 100.0;
+    app.data
+        .category_summary
+        .iter()
+        .filter(|cs| cs.projected > 0.0 && cs.total < cs.projected * under)
+        .map(|cs| Suggestion {
+            kind: SuggestionKind::UnderBudget,
+            message: format!(
+                "{} used {:.0}% of {} - budget less next month?",
+                cs.category,
+                cs.total / cs.projected * 100.0,
+                app.money.format(cs.projected)
+            ),
+        })
+        .collect()
+}
+
+impl AppState {
+    /// Days left in the selected month, counting `today`, while it is open
+    /// and in its last `days` from `[advisor]`
+    pub fn advisor_days_left(&self, today: NaiveDate) -> Option<i64> {
+        let month = self.selected_month().filter(|m| !m.is_closed)?;
+        let (start, end) = month.date_range()?;
+        let days_left = (end - today).num_days() + 1;
+        let window = i64::from(self.advisor.days);
+        (today >= start && days_left >= 1 && days_left <= window).then_some(days_left)
+    }
+
+    /// Suggestions for the selected month on `today`; empty outside its
+    /// last days
+    pub fn end_of_month_suggestions(&self, today: NaiveDate) -> Vec<Suggestion> {
+        if self.advisor_days_left(today).is_none() {
+            return Vec::new();
+        }
+        RULES.iter().flat_map(|rule| rule(self)).collect()
+    }
+}
@@ -1,9 +1,35 @@
+use std::collections::HashSet;
+
+use chrono::NaiveDateTime;
 use ratatui::widgets::TableState;
 
+use crate::api::EndpointMetrics;
+use crate::changelog::Release;
+use crate::clock::Clock;
+use crate::config::{
+    AdvisorConfig, PeriodsConfig, ProfilesConfig, StartupConfig, StartupMonth, ThresholdConfig,
+};
+use crate::import::parse_month;
 use crate::models::{
-    Category, CategorySummary, Expense, Income, IncomeType, IncomeTypeSummary, Month, Period,
-    PeriodSummaryResponse, SummaryInsights, SummaryTotals, User,
+    Account, Category, CategorySummary, Debt, Expense, ExpenseFilters, Income, IncomeFilters,
+    IncomeType, IncomeTypeSummary, KeyScopes, Month, MonthStatus, PageRequest, Period,
+    PeriodSummaryResponse, SummaryInsights, SummaryTotals, Transfer, User,
 };
+use crate::state::audit::AuditTrail;
+use crate::state::autofill::AutofillPreview;
+use crate::state::currency::{ConvertedTotals, Rates};
+use crate::state::reallocate::ReallocateBoard;
+use crate::state::rollover::calendar_month;
+use crate::state::{
+    AccountFormState, ActionHistory, DebtFormState, GroupKey, MergePreview, ReimbursementReport,
+    ServerFeature, SortKey, SplitView, TransferFormState,
+};
+use crate::storage::{ExpenseLedgers, LastView, MonthChecklist, MonthNotes, TaxFlags};
+use crate::ui::dates::DateFormat;
+use crate::ui::money::MoneyFormat;
+
+/// Errors kept for reporting a problem
+const RECENT_ERRORS: usize = 10;
 
 /// Current screen/view
 #[derive(Debug, Clone, PartialEq, Eq)]
@@ -20,6 +46,7 @@ pub enum DashboardTab {
     Expenses,
     Income,
     Charts,
+    Debts,
     Settings,
 }
 
@@ -30,6 +57,7 @@ impl DashboardTab {
             DashboardTab::Expenses,
             DashboardTab::Income,
             DashboardTab::Charts,
+            DashboardTab::Debts,
             DashboardTab::Settings,
         ]
     }
@@ -40,6 +68,7 @@ impl DashboardTab {
             DashboardTab::Expenses => "Expenses",
             DashboardTab::Income => "Income",
             DashboardTab::Charts => "Charts",
+            DashboardTab::Debts => "Debts",
             DashboardTab::Settings => "Settings",
         }
     }
@@ -50,17 +79,27 @@ impl DashboardTab {
             DashboardTab::Expenses => 1,
             DashboardTab::Income => 2,
             DashboardTab::Charts => 3,
-            DashboardTab::Settings => 4,
+            DashboardTab::Debts => 4,
+            DashboardTab::Settings => 5,
         }
     }
 
+    /// The tab named `name`, ignoring case
+    pub fn from_name(name: &str) -> Option<Self> {
+        Self::all()
+            .iter()
+            .copied()
+            .find(|tab| tab.as_str().eq_ignore_ascii_case(name.trim()))
+    }
+
     pub fn from_index(index: usize) -> Self {
         match index {
             0 => DashboardTab::Summary,
             1 => DashboardTab::Expenses,
             2 => DashboardTab::Income,
             3 => DashboardTab::Charts,
-            4 => DashboardTab::Settings,
+            4 => DashboardTab::Debts,
+            5 => DashboardTab::Settings,
             _ => DashboardTab::Summary,
         }
     }
@@ -88,6 +127,7 @@ pub enum SettingsTab {
     Categories,
     Periods,
     IncomeTypes,
+    Accounts,
     Password,
 }
 
@@ -97,6 +137,7 @@ impl SettingsTab {
             SettingsTab::Categories,
             SettingsTab::Periods,
             SettingsTab::IncomeTypes,
+            SettingsTab::Accounts,
             SettingsTab::Password,
         ]
     }
@@ -106,6 +147,7 @@ impl SettingsTab {
             SettingsTab::Categories => "Categories",
             SettingsTab::Periods => "Periods",
             SettingsTab::IncomeTypes => "Income Types",
+            SettingsTab::Accounts => "Accounts",
             SettingsTab::Password => "Password",
         }
     }
@@ -115,7 +157,8 @@ impl SettingsTab {
             SettingsTab::Categories => 0,
             SettingsTab::Periods => 1,
             SettingsTab::IncomeTypes => 2,
-            SettingsTab::Password => 3,
+            SettingsTab::Accounts => 3,
+            SettingsTab::Password => 4,
         }
     }
 
@@ -124,7 +167,8 @@ impl SettingsTab {
             0 => SettingsTab::Categories,
             1 => SettingsTab::Periods,
             2 => SettingsTab::IncomeTypes,
-            3 => SettingsTab::Password,
+            3 => SettingsTab::Accounts,
+            4 => SettingsTab::Password,
             _ => SettingsTab::Categories,
         }
     }
@@ -171,6 +215,29 @@ pub enum Modal {
     IncomeTypeForm {
         editing: Option<IncomeType>,
     },
+    /// Adding or editing an account, with what's typed so far
+    AccountForm {
+        form: AccountFormState,
+    },
+    /// The selected month's transfers between accounts
+    Transfers {
+        selected: usize,
+        /// `d` was pressed once on the selected transfer
+        confirm_remove: bool,
+    },
+    /// Recording a transfer, back to the list after
+    TransferForm {
+        form: TransferFormState,
+    },
+    /// Adding or editing a debt, with what's typed so far
+    DebtForm {
+        form: DebtFormState,
+    },
+    /// Amount of a payment on a debt, recorded as an expense
+    DebtPayment {
+        debt: Debt,
+        amount: String,
+    },
     PasswordForm,
     ConfirmDelete {
         message: String,
@@ -188,16 +255,158 @@ pub enum Modal {
         month_id: i32,
         is_closing: bool, // true = closing, false = opening
     },
+    EnvExport {
+        url: String,
+        api_key: String,
+        reveal_key: bool,
+    },
+    ShareLink {
+        month_name: String,
+        url: String,
+        expires_at: String,
+    },
+    Notes {
+        month_id: i32,
+        month_name: String,
+        text: String,
+        /// The month's notes on the server rather than the local ones
+        shared: bool,
+    },
+    Checklist {
+        month_id: i32,
+        month_name: String,
+        items: Vec<(String, bool)>,
+        selected: usize,
+    },
+    Reimbursements {
+        report: ReimbursementReport,
+        selected: usize,
+    },
+    /// An expense and its purchases, to add, edit or remove them one at a time
+    ExpenseDetail {
+        expense: Expense,
+        selected: usize,
+        /// `d` was pressed once on the selected purchase
+        confirm_remove: bool,
+        /// Attachment `o` opens
+        file: usize,
+    },
+    /// Path of a file to attach to an expense, back to its detail view after
+    AttachFile {
+        expense: Expense,
+        path: String,
+    },
+    /// Who created and last changed an expense or income, and when
+    Inspect {
+        entity_type: EntityType,
+        name: String,
+        audit: AuditTrail,
+    },
+    /// Request latency per endpoint, as measured by the client
+    Performance {
+        endpoints: Vec<EndpointMetrics>,
+    },
+    /// Release notes since the last version that ran
+    WhatsNew {
+        releases: Vec<Release>,
+        scroll: usize,
+    },
+    /// Hidden panel for diagnosing a slow or flaky server: request totals,
+    /// the slowest endpoints and the client's state
+    Debug {
+        totals: EndpointMetrics,
+        slowest: Vec<EndpointMetrics>,
+        details: Vec<(String, String)>,
+    },
+    ConfirmDuplicate {
+        entity_type: EntityType,
+        existing_id: i32,
+        existing_name: String,
+        form: Box<Modal>, // form to return to when not jumping
+    },
+    MergeSelect {
+        entity_type: EntityType,
+        source_id: i32,
+        source_name: String,
+        targets: Vec<(i32, String)>,
+        selected: usize,
+    },
+    ConfirmMerge {
+        entity_type: EntityType,
+        source_id: i32,
+        source_name: String,
+        target_id: i32,
+        target_name: String,
+        preview: MergePreview,
+    },
+    /// The calendar month doesn't exist yet; start it from `previous` or
+    /// `template` (id and name), or empty
+    Rollover {
+        year: i32,
+        month: i32,
+        name: String,
+        previous: Option<(i32, String)>,
+        template: Option<(i32, String)>,
+    },
+    /// Budgets from recent spending, to apply with `y`
+    Autofill {
+        preview: AutofillPreview,
+    },
+    /// An issue about a problem, to open in the browser with `Enter`
+    ReportProblem {
+        body: String,
+        url: String,
+    },
+    /// Budget moved between categories, to apply with `Enter` once nothing
+    /// is held
+    Reallocate {
+        board: ReallocateBoard,
+    },
+    /// The session's actions as (time, label), newest first; `Enter`
+    /// repeats the selected one
+    History {
+        entries: Vec<(String, String)>,
+        selected: usize,
+    },
+    /// Path of a CSV to fill the empty selected month from
+    ImportCsv {
+        month_name: String,
+        path: String,
+    },
     Help,
 }
 
-#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
 pub enum EntityType {
     Expense,
     Income,
     Category,
     Period,
     IncomeType,
+    Account,
+    Debt,
+}
+
+impl EntityType {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            EntityType::Expense => "Expense",
+            EntityType::Income => "Income",
+            EntityType::Category => "Category",
+            EntityType::Period => "Period",
+            EntityType::IncomeType => "Income type",
+            EntityType::Account => "Account",
+            EntityType::Debt => "Debt",
+        }
+    }
+}
+
+/// Normalize a name for duplicate detection, so " Food " and "food" match
+pub fn normalize_name(name: &str) -> String {
+    name.split_whitespace()
+        .collect::<Vec<_>>()
+        .join(" ")
+        .to_lowercase()
 }
 
 /// Cached data from the API
@@ -208,13 +417,36 @@ pub struct DataState {
     pub categories: Vec<Category>,
     pub periods: Vec<Period>,
     pub income_types: Vec<IncomeType>,
+    /// Empty on servers without accounts
+    pub accounts: Vec<Account>,
+    /// The selected month's transfers; empty on servers without them
+    pub transfers: Vec<Transfer>,
+    /// Empty on servers without debts
+    pub debts: Vec<Debt>,
     pub months: Vec<Month>,
     pub current_month: Option<Month>,
     pub summary_totals: Option<SummaryTotals>,
+    /// Totals with amounts in other currencies converted, when the month
+    /// has any
+    pub converted_totals: Option<ConvertedTotals>,
     pub category_summary: Vec<CategorySummary>,
     pub income_type_summary: Vec<IncomeTypeSummary>,
     pub period_summary: Option<PeriodSummaryResponse>,
     pub insights: Option<SummaryInsights>,
+    /// Items with writes waiting in the offline queue
+    pub pending_sync: HashSet<(EntityType, i32)>,
+    /// Next page of expenses to fetch, while the server may have more
+    pub more_expenses: Option<(ExpenseFilters, PageRequest)>,
+    /// Next page of incomes to fetch, while the server may have more
+    pub more_incomes: Option<(IncomeFilters, PageRequest)>,
+    /// Expenses in all pages, when the server sends a total count
+    pub expenses_total: Option<usize>,
+    /// Incomes in all pages, when the server sends a total count
+    pub incomes_total: Option<usize>,
+    /// Endpoints the server answered 404/501 for; cleared when it changes
+    pub unsupported: HashSet<ServerFeature>,
+    /// What the API key may change; `None` when it isn't scoped
+    pub key_scopes: Option<KeyScopes>,
 }
 
 /// UI-specific state
@@ -228,6 +460,21 @@ pub struct UIState {
     // Filters
     pub period_filter: Option<String>,
     pub category_filter: Option<String>,
+    /// Account ID; applied to the loaded rows rather than by the server
+    pub account_filter: Option<i32>,
+
+    // Local view settings of the Expenses and Income tables
+    pub sort: Option<SortKey>,
+    pub group: Option<GroupKey>,
+    pub search: String,
+    /// Typing into `search`
+    pub searching: bool,
+    /// Two lines per row with bigger padding and bold amounts
+    pub large_text: bool,
+    /// A second month's expenses beside the selected one's
+    pub split: Option<SplitView>,
+    /// Closed month the user chose to edit anyway, by ID
+    pub closed_month_override: Option<i32>,
 
     // Table states
     pub expense_table: TableState,
@@ -235,6 +482,8 @@ pub struct UIState {
     pub category_table: TableState,
     pub period_table: TableState,
     pub income_type_table: TableState,
+    pub account_table: TableState,
+    pub debt_table: TableState,
     pub category_summary_table: TableState,
 
     // Modal
@@ -257,11 +506,21 @@ impl Default for UIState {
             settings_tab: SettingsTab::Categories,
             period_filter: None,
             category_filter: None,
+            account_filter: None,
+            sort: None,
+            group: None,
+            search: String::new(),
+            searching: false,
+            large_text: false,
+            split: None,
+            closed_month_override: None,
             expense_table: TableState::default(),
             income_table: TableState::default(),
             category_table: TableState::default(),
             period_table: TableState::default(),
             income_type_table: TableState::default(),
+            account_table: TableState::default(),
+            debt_table: TableState::default(),
             category_summary_table: TableState::default(),
             modal: None,
             input_mode: InputMode::Normal,
@@ -279,6 +538,41 @@ pub struct AppState {
     pub user: Option<User>,
     pub data: DataState,
     pub ui: UIState,
+    /// Local scratchpad notes per month
+    pub notes: MonthNotes,
+    /// Local monthly routine progress
+    pub checklist: MonthChecklist,
+    /// Local tax flags on expenses and incomes
+    pub tax_flags: TaxFlags,
+    /// Local business/reimbursable ledgers on expenses
+    pub ledgers: ExpenseLedgers,
+    /// Near/over budget levels from the config
+    pub thresholds: ThresholdConfig,
+    /// How amounts are shown
+    pub money: MoneyFormat,
+    /// Exchange rates into the budget's currency
+    pub rates: Rates,
+    /// How dates are shown
+    pub dates: DateFormat,
+    /// Expenses or incomes fetched per request (0 fetches all at once)
+    pub page_size: usize,
+    /// Categories split into weekly envelopes in the Summary
+    pub envelope_categories: Vec<String>,
+    /// Order and labels of periods, and the first day of the week
+    pub period_display: PeriodsConfig,
+    /// When end-of-month suggestions show
+    pub advisor: AdvisorConfig,
+    /// Name of the active server profile, shown in the header when there
+    /// are several
+    pub profile: Option<String>,
+    /// Hex color of the active profile's theme
+    pub profile_accent: Option<String>,
+    /// Actions done this session, for `H` and `.`
+    pub history: ActionHistory,
+    /// Today's date and the time, frozen in tests and demos
+    pub clock: Clock,
+    /// The last errors shown, oldest first, for reporting a problem
+    pub recent_errors: Vec<(NaiveDateTime, String)>,
 }
 
 impl Default for AppState {
@@ -288,21 +582,222 @@ impl Default for AppState {
             user: None,
             data: DataState::default(),
             ui: UIState::default(),
+            notes: MonthNotes::default(),
+            checklist: MonthChecklist::default(),
+            tax_flags: TaxFlags::default(),
+            ledgers: ExpenseLedgers::default(),
+            thresholds: ThresholdConfig::default(),
+            money: MoneyFormat::default(),
+            rates: Rates::default(),
+            dates: DateFormat::default(),
+            page_size: 0,
+            envelope_categories: Vec::new(),
+            period_display: PeriodsConfig::default(),
+            advisor: AdvisorConfig::default(),
+            profile: None,
+            profile_accent: None,
+            history: ActionHistory::default(),
+            clock: Clock::system(),
+            recent_errors: Vec::new(),
         }
     }
 }
 
 impl AppState {
+    /// Show the active profile from the config
+    pub fn set_profile(&mut self, profiles: &ProfilesConfig) {
+        self.profile = profiles
+            .active
+            .clone()
+            .filter(|_| !profiles.servers.is_empty());
+        self.profile_accent = profiles
+            .active_theme()
+            .and_then(|theme| theme.accent.clone());
+    }
+
     /// Get the currently selected month
     pub fn selected_month(&self) -> Option<&Month> {
         self.data.months.get(self.ui.selected_month_index)
     }
 
+    /// Share of the selected month that has passed, while it is in progress
+    /// and pacing is on
+    pub fn month_pace(&self) -> Option<f64> {
+        if !self.thresholds.pace {
+            return None;
+        }
+        let today = self.clock.today();
+        // Only the month in progress has a pace to keep up with
+        self.selected_month()
+            .map(|month| month.pace(today))
+            .filter(|pace| *pace > 0.0 && *pace < 1.0)
+    }
+
+    /// Find another category, period or income type with an equivalent name
+    ///
+    /// Returns the ID and name of the existing item. `exclude_id` skips the item being edited.
+    pub fn find_duplicate_name(
+        &self,
+        entity_type: EntityType,
+        name: &str,
+        exclude_id: Option<i32>,
+    ) -> Option<(i32, String)> {
+        let key = normalize_name(name);
+        self.entity_names(entity_type)
+            .into_iter()
+            .find(|(id, existing)| Some(*id) != exclude_id && normalize_name(existing) == key)
+    }
+
+    /// Drop an item the server no longer has, e.g. one deleted on another device
+    pub fn remove_missing(&mut self, entity_type: EntityType, id: i32) {
+        match entity_type {
+            EntityType::Expense => self.data.expenses.retain(|e| e.id != id),
+            EntityType::Income => self.data.incomes.retain(|i| i.id != id),
+            EntityType::Category => self.data.categories.retain(|c| c.id != id),
+            EntityType::Period => self.data.periods.retain(|p| p.id != id),
+            EntityType::IncomeType => self.data.income_types.retain(|t| t.id != id),
+            EntityType::Account => self.data.accounts.retain(|a| a.id != id),
+            EntityType::Debt => self.data.debts.retain(|d| d.id != id),
+        }
+    }
+
+    /// IDs and names of all categories, periods, income types, accounts or
+    /// debts
+    pub fn entity_names(&self, entity_type: EntityType) -> Vec<(i32, String)> {
+        match entity_type {
+            EntityType::Category => self
+                .data
+                .categories
+                .iter()
+                .map(|c| (c.id, c.name.clone()))
+                .collect(),
+            EntityType::Period => self
+                .data
+                .periods
+                .iter()
+                .map(|p| (p.id, p.name.clone()))
+                .collect(),
+            EntityType::IncomeType => self
+                .data
+                .income_types
+                .iter()
+                .map(|t| (t.id, t.name.clone()))
+                .collect(),
+            EntityType::Account => self
+                .data
+                .accounts
+                .iter()
+                .map(|a| (a.id, a.name.clone()))
+                .collect(),
+            EntityType::Debt => self
+                .data
+                .debts
+                .iter()
+                .map(|d| (d.id, d.name.clone()))
+                .collect(),
+            EntityType::Expense | EntityType::Income => Vec::new(),
+        }
+    }
+
+    /// The category, period, income type or account selected in the settings tab
+    pub fn selected_settings_entity(&self) -> Option<(EntityType, i32, String)> {
+        let (entity_type, index) = match self.ui.settings_tab {
+            SettingsTab::Categories => (EntityType::Category, self.ui.category_table.selected()),
+            SettingsTab::Periods => (EntityType::Period, self.ui.period_table.selected()),
+            SettingsTab::IncomeTypes => {
+                (EntityType::IncomeType, self.ui.income_type_table.selected())
+            }
+            SettingsTab::Accounts => (EntityType::Account, self.ui.account_table.selected()),
+            SettingsTab::Password => return None,
+        };
+        let (id, name) = self.entity_names(entity_type).into_iter().nth(index?)?;
+        Some((entity_type, id, name))
+    }
+
+    /// Show a category, period, income type or account in the settings tab,
+    /// or a debt in its tab, and select it
+    pub fn jump_to_entity(&mut self, entity_type: EntityType, id: i32) {
+        let (settings_tab, index, table) = match entity_type {
+            EntityType::Category => (
+                SettingsTab::Categories,
+                self.data.categories.iter().position(|c| c.id == id),
+                &mut self.ui.category_table,
+            ),
+            EntityType::Period => (
+                SettingsTab::Periods,
+                self.data.periods.iter().position(|p| p.id == id),
+                &mut self.ui.period_table,
+            ),
+            EntityType::IncomeType => (
+                SettingsTab::IncomeTypes,
+                self.data.income_types.iter().position(|t| t.id == id),
+                &mut self.ui.income_type_table,
+            ),
+            EntityType::Account => (
+                SettingsTab::Accounts,
+                self.data.accounts.iter().position(|a| a.id == id),
+                &mut self.ui.account_table,
+            ),
+            EntityType::Debt => {
+                if let Some(index) = self.data.debts.iter().position(|d| d.id == id) {
+                    self.ui.debt_table.select(Some(index));
+                }
+                self.ui.selected_tab = DashboardTab::Debts;
+                return;
+            }
+            EntityType::Expense | EntityType::Income => return,
+        };
+
+        if index.is_some() {
+            table.select(index);
+        }
+        self.ui.selected_tab = DashboardTab::Settings;
+        self.ui.settings_tab = settings_tab;
+    }
+
+    /// Row selected in the Expenses or Income table, when the selection
+    /// points at one; it is kept while the list shrinks or empties
+    pub fn selected_entry_row(&self) -> Option<usize> {
+        match self.ui.selected_tab {
+            DashboardTab::Expenses => self
+                .ui
+                .expense_table
+                .selected()
+                .filter(|idx| *idx < self.filtered_expenses().len()),
+            DashboardTab::Income => self
+                .ui
+                .income_table
+                .selected()
+                .filter(|idx| *idx < self.filtered_incomes().len()),
+            _ => None,
+        }
+    }
+
     /// Get the selected month ID
     pub fn selected_month_id(&self) -> Option<i32> {
         self.selected_month().map(|m| m.id)
     }
 
+    /// Check if the selected month is closed, unless the user chose to edit
+    /// it anyway
+    pub fn selected_month_locked(&self) -> bool {
+        self.selected_month().is_some_and(|month| {
+            month.status() == MonthStatus::Closed && self.ui.closed_month_override != Some(month.id)
+        })
+    }
+
+    /// Start or stop editing the selected closed month anyway; whether it is
+    /// now unlocked, or `None` when it isn't closed
+    pub fn toggle_closed_month_override(&mut self) -> Option<bool> {
+        let id = self
+            .selected_month()
+            .filter(|month| month.status() == MonthStatus::Closed)?
+            .id;
+        let unlocked = self.ui.closed_month_override != Some(id);
+        self.ui.closed_month_override = unlocked.then_some(id);
+        Some(unlocked)
+    }
+
     /// Select next month
     pub fn next_month(&mut self) {
         if !self.data.months.is_empty() && self.ui.selected_month_index < self.data.months.len() - 1
@@ -365,9 +860,88 @@ impl AppState {
         }
     }
 
-    /// Get filtered expenses
-    pub fn filtered_expenses(&self) -> Vec<&Expense> {
+    /// Open on the month and filters `[startup]` asks for, once months,
+    /// periods and categories are loaded
+    ///
+    /// Returns what couldn't be applied, e.g. a period the server doesn't
+    /// have; the default stays for those.
+    pub fn apply_startup(&mut self, startup: &StartupConfig) -> Vec<String> {
+        let mut problems = Vec::new();
+        match startup.month {
+            StartupMonth::Current => {}
+            StartupMonth::Calendar => {
+                let (year, month) = calendar_month(self.clock.today());
+                if !self.select_calendar_month((year, month)) {
+                    problems.push(format!("No month {}-{:02} yet", year, month));
+                }
+            }
+            StartupMonth::Latest => {
+                self.ui.selected_month_index = self.data.months.len().saturating_sub(1);
+            }
+        }
+
+        if let Some(name) = startup.period.as_deref() {
+            match find_named(self.data.periods.iter().map(|p| &p.name), name) {
+                Some(period) => self.ui.period_filter = Some(period.clone()),
+                None => problems.push(format!("No period named '{}'", name.trim())),
+            }
+        }
+        if let Some(name) = startup.category.as_deref() {
+            match find_named(self.data.categories.iter().map(|c| &c.name), name) {
+                Some(category) => self.ui.category_filter = Some(category.clone()),
+                None => problems.push(format!("No category named '{}'", name.trim())),
+            }
+        }
+        problems
+    }
+
+    /// The tab, month and filters to remember for next time
+    pub fn last_view(&self) -> LastView {
+        LastView {
+            tab: Some(self.ui.selected_tab.as_str().to_string()),
+            month: self
+                .selected_month()
+                .map(|m| format!("{}-{:02}", m.year, m.month)),
+            period: self.ui.period_filter.clone(),
+            category: self.ui.category_filter.clone(),
+        }
+    }
+
+    /// Go back to a remembered view; a month or filter the server no longer
+    /// has is skipped
+    pub fn restore_view(&mut self, view: &LastView) {
+        if let Some(tab) = view.tab.as_deref().and_then(DashboardTab::from_name) {
+            self.ui.selected_tab = tab;
+        }
+        if let Some(month) = view.month.as_deref().and_then(parse_month) {
+            self.select_calendar_month(month);
+        }
+        if let Some(name) = view.period.as_deref() {
+            if let Some(period) = find_named(self.data.periods.iter().map(|p| &p.name), name) {
+                self.ui.period_filter = Some(period.clone());
+            }
+        }
+        if let Some(name) = view.category.as_deref() {
+            if let Some(category) = find_named(self.data.categories.iter().map(|c| &c.name), name) {
+                self.ui.category_filter = Some(category.clone());
+            }
+        }
+    }
+
+    /// Name of the account with `id`, if it is one the server has
+    pub fn account_name(&self, id: Option<i32>) -> Option<&str> {
+        let id = id?;
         self.data
+            .accounts
+            .iter()
+            .find(|a| a.id == id)
+            .map(|a| a.name.as_str())
+    }
+
+    /// Get filtered expenses, as the Expenses table lists them
+    pub fn filtered_expenses(&self) -> Vec<&Expense> {
+        let expenses = self
+            .data
             .expenses
             .iter()
             .filter(|e| {
@@ -381,23 +955,36 @@ impl AppState {
                     .category_filter
                     .as_ref()
                     .is_none_or(|c| &e.category == c);
-                period_match && category_match
+                let account_match = self
+                    .ui
+                    .account_filter
+                    .is_none_or(|a| e.account_id == Some(a));
+                period_match && category_match && account_match
             })
-            .collect()
+            .collect();
+        self.arrange_expenses(expenses)
     }
 
-    /// Get filtered incomes
+    /// Get filtered incomes, as the Income table lists them
     pub fn filtered_incomes(&self) -> Vec<&Income> {
-        self.data
+        let incomes = self
+            .data
             .incomes
             .iter()
             .filter(|i| {
-                self.ui
+                let period_match = self
+                    .ui
                     .period_filter
                     .as_ref()
-                    .is_none_or(|p| &i.period == p)
+                    .is_none_or(|p| &i.period == p);
+                let account_match = self
+                    .ui
+                    .account_filter
+                    .is_none_or(|a| i.account_id == Some(a));
+                period_match && account_match
             })
-            .collect()
+            .collect();
+        self.arrange_incomes(incomes)
     }
 
     /// Clear messages after displaying
@@ -406,9 +993,24 @@ impl AppState {
         self.ui.success_message = None;
     }
 
+    /// Switch between the normal and large-text layouts
+    pub fn toggle_large_text(&mut self) {
+        self.ui.large_text = !self.ui.large_text;
+        self.set_success(if self.ui.large_text {
+            "Large text on"
+        } else {
+            "Large text off"
+        });
+    }
+
     /// Set error message
     pub fn set_error(&mut self, message: impl Into<String>) {
-        self.ui.error_message = Some(message.into());
+        let message = message.into();
+        if self.recent_errors.len() == RECENT_ERRORS {
+            self.recent_errors.remove(0);
+        }
+        self.recent_errors.push((self.clock.now(), message.clone()));
+        self.ui.error_message = Some(message);
         self.ui.success_message = None;
     }
 
@@ -418,3 +1020,8 @@ impl AppState {
         self.ui.error_message = None;
     }
 }
+
+/// The server's spelling of `name`, matched ignoring case and surrounding spaces
+fn find_named<'a>(mut names: impl Iterator<Item = &'a String>, name: &str) -> Option<&'a String> {
+    names.find(|candidate| candidate.trim().eq_ignore_ascii_case(name.trim()))
+}
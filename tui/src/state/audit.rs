@@ -0,0 +1,110 @@
+//! Who changed a record and when
+//!
+//! The server stamps expenses and incomes with when they were created and
+//! last updated, and by whom. In a budget shared by a household that answers
+//! "who moved this?", so `i` on a row shows it.
+
+use chrono::{DateTime, Local, NaiveDateTime};
+
+use crate::models::{Expense, Income};
+use crate::ui::dates::DateFormat;
+
+/// Creation and last update of a record
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct AuditTrail {
+    pub created_at: Option<String>,
+    pub created_by: Option<String>,
+    pub updated_at: Option<String>,
+    pub updated_by: Option<String>,
+}
+
+impl From<&Expense> for AuditTrail {
+    fn from(expense: &Expense) -> Self {
+        Self {
+            created_at: expense.created_at.clone(),
+            created_by: expense.created_by.clone(),
+            updated_at: expense.updated_at.clone(),
+            updated_by: expense.updated_by.clone(),
+        }
+        .without_blanks()
+    }
+}
+
+impl From<&Income> for AuditTrail {
+    fn from(income: &Income) -> Self {
+        Self {
+            created_at: Some(income.created_at.clone()),
+            created_by: income.created_by.clone(),
+            updated_at: Some(income.updated_at.clone()),
+            updated_by: income.updated_by.clone(),
+        }
+        .without_blanks()
+    }
+}
+
+impl AuditTrail {
+    /// Empty text, as records queued offline have, counts as unknown
+    fn without_blanks(self) -> Self {
+        let known = |value: Option<String>| value.filter(|v| !v.trim().is_empty());
+        Self {
+            created_at: known(self.created_at),
+            created_by: known(self.created_by),
+            updated_at: known(self.updated_at),
+            updated_by: known(self.updated_by),
+        }
+    }
+
+    /// Check if the record changed after it was created
+    pub fn was_updated(&self) -> bool {
+        match (&self.updated_at, &self.created_at) {
+            (Some(updated), Some(created)) => updated != created,
+            (updated, _) => updated.is_some() || self.updated_by.is_some(),
+        }
+    }
+
+    /// (label, value) lines for the inspect popup, e.g. ("Created",
+    /// "2024-03-01 14:05 by ana")
+    pub fn lines(&self, dates: DateFormat) -> Vec<(String, String)> {
+        let mut lines = vec![(
+            "Created".to_string(),
+            describe(
+                self.created_at.as_deref(),
+                self.created_by.as_deref(),
+                dates,
+            ),
+        )];
+        let updated = if self.was_updated() {
+            describe(
+                self.updated_at.as_deref(),
+                self.updated_by.as_deref(),
+                dates,
+            )
+        } else {
+            "Never".to_string()
+        };
+        lines.push(("Updated".to_string(), updated));
+        lines
+    }
+}
+
+/// When and by whom, with either left out when unknown
+fn describe(at: Option<&str>, by: Option<&str>, dates: DateFormat) -> String {
+    match (at.map(|at| timestamp(at, dates)), by) {
+        (Some(at), Some(by)) => format!("{} by {}", at, by),
+        (Some(at), None) => at,
+        (None, Some(by)) => format!("by {}", by),
+        (None, None) => "Unknown".to_string(),
+    }
+}
+
+/// A server timestamp to the minute; one with an offset is shown in local
+/// time, one without as sent
+pub fn timestamp(text: &str, dates: DateFormat) -> String {
+    if let Ok(time) = DateTime::parse_from_rfc3339(text) {
+        return dates.format_time(time.with_timezone(&Local).naive_local());
+    }
+    match NaiveDateTime::parse_from_str(text, "%Y-%m-%dT%H:%M:%S%.f") {
+        Ok(time) => dates.format_time(time),
+        Err(_) => dates.format_text(text),
+    }
+}
@@ -0,0 +1,158 @@
+//! Budgets from recent spending
+//!
+//! A category's budget is what its expenses project for the month. Autofill
+//! sets it to the category's average actual spend over the months before,
+//! rounded to `[months] autofill_round`: the month's expenses in the category
+//! are scaled to add up to it, keeping their shares, and a category without
+//! any gets one expense named after it. Categories with no spending in those
+//! months are left alone.
+
+use std::collections::BTreeMap;
+
+use crate::api::MonthData;
+use crate::models::{CustomFields, Expense, ExpenseCreate, ExpenseUpdate, Money, Month};
+
+/// A category's budget now and after autofill
+#[derive(Debug, Clone, PartialEq)]
+pub struct AutofillRow {
+    pub category: String,
+    /// Projected total of the month's expenses in the category
+    pub current: f64,
+    /// Rounded average actual spend
+    pub budget: f64,
+}
+
+impl AutofillRow {
+    pub fn changes(&self) -> bool {
+        (self.budget - self.current).abs() >= 0.005
+    }
+}
+
+/// What autofill would change in a month, for confirming first
+#[derive(Debug, Clone, PartialEq)]
+pub struct AutofillPreview {
+    pub month_id: i32,
+    pub month_name: String,
+    /// Months the averages were taken over
+    pub months_used: usize,
+    pub rows: Vec<AutofillRow>,
+}
+
+/// Writes that apply an autofill
+#[derive(Debug, Clone, Default)]
+pub struct AutofillChanges {
+    pub updates: Vec<(i32, ExpenseUpdate)>,
+    pub creates: Vec<ExpenseCreate>,
+}
+
+/// Round `amount` to the nearest multiple of `step`; cents for 0 or less
+pub fn round_to(amount: f64, step: f64) -> f64 {
+    let step = if step > 0.0 { step } else { 0.01 };
+    (amount / step).round() * step
+}
+
+impl AutofillPreview {
+    /// Budgets for `month` from the spending in `history`, the months before
+    /// it
+    pub fn build(month: &Month, expenses: &[Expense], history: &[MonthData], step: f64) -> Self {
+        let mut spent: BTreeMap<&str, f64> = BTreeMap::new();
+        for expense in history.iter().flat_map(|m| &m.expenses) {
+            *spent.entry(expense.category.as_str()).or_default() += expense.cost;
+        }
+        let months_used = history.len().max(1) as f64;
+
+        let rows = spent
+            .into_iter()
+            .filter(|(_, total)| *total > 0.0)
+            .map(|(category, total)| AutofillRow {
+                category: category.to_string(),
+                current: expenses
+                    .iter()
+                    .filter(|e| e.category == category)
+                    .map(|e| e.projected)
+                    .sum::<Money>()
+                    .to_f64(),
+                budget: round_to(total / months_used, step),
+            })
+            .collect();
+
+        Self {
+            month_id: month.id,
+            month_name: month.display_name(),
+            months_used: history.len(),
+            rows,
+        }
+    }
+
+    /// Check if applying would change anything
+    pub fn has_changes(&self) -> bool {
+        self.rows.iter().any(AutofillRow::changes)
+    }
+
+    /// Updates scaling `expenses` to the new budgets, and new expenses for
+    /// categories without any; those go in `period`
+    pub fn changes(&self, expenses: &[Expense], period: &str) -> AutofillChanges {
+        let mut changes = AutofillChanges::default();
+        for row in self.rows.iter().filter(|row| row.changes()) {
+            let listed: Vec<&Expense> = expenses
+                .iter()
+                .filter(|e| e.category == row.category)
+                .collect();
+            if listed.is_empty() {
+                changes.creates.push(ExpenseCreate {
+                    expense_name: row.category.clone(),
+                    period: period.to_string(),
+                    category: row.category.clone(),
+                    projected: row.budget,
+                    cost: 0.0,
+                    notes: None,
+                    month_id: self.month_id,
+                    purchases: None,
+                    expense_date: None,
+                    account_id: None,
+                    currency: None,
+                    custom_fields: CustomFields::new(),
+                });
+                continue;
+            }
+            changes
+                .updates
+                .extend(scale_projected(&listed, row.current, row.budget));
+        }
+        changes
+    }
+}
+
+/// Updates setting the projected total of `listed` from `current` to
+/// `budget`, keeping each expense's share; evenly when nothing is projected
+/// yet
+pub fn scale_projected(
+    listed: &[&Expense],
+    current: f64,
+    budget: f64,
+) -> Vec<(i32, ExpenseUpdate)> {
+    let shares: Vec<f64> = if current > 0.0 {
+        listed.iter().map(|e| e.projected / current).collect()
+    } else {
+        vec![1.0 / listed.len() as f64; listed.len()]
+    };
+    let mut left = budget;
+    let mut updates = Vec::new();
+    for (i, (expense, share)) in listed.iter().zip(shares).enumerate() {
+        // The last one takes what rounding left, so the total is exact
+        let projected = if i + 1 == listed.len() {
+            round_to(left, 0.01)
+        } else {
+            round_to(budget * share, 0.01)
+        };
+        left -= projected;
+        updates.push((
+            expense.id,
+            ExpenseUpdate {
+                projected: Some(projected),
+                ..Default::default()
+            },
+        ));
+    }
+    updates
+}
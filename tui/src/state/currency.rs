@@ -0,0 +1,178 @@
+//! Amounts in other currencies
+//!
+//! Expenses and incomes may carry a currency code; those without one are in
+//! the budget's own (`base` in `[currency]`). The server adds amounts up as
+//! they are, so when a month has entries in other currencies the Summary
+//! converts them at the known rates: the config's, or fetched ones on top.
+
+use std::collections::BTreeMap;
+
+use crate::config::CurrencyConfig;
+use crate::models::{Expense, Income, Money, SummaryTotals};
+use crate::state::AppState;
+
+/// Code in capitals, when `code` is three letters like "eur"
+pub fn normalize_code(code: &str) -> Option<String> {
+    let code = code.trim();
+    (code.len() == 3 && code.chars().all(|c| c.is_ascii_alphabetic()))
+        .then(|| code.to_ascii_uppercase())
+}
+
+/// What one unit of each currency is worth in the base currency
+#[derive(Debug, Clone, PartialEq)]
+pub struct Rates {
+    base: String,
+    rates: BTreeMap<String, f64>,
+}
+
+impl Default for Rates {
+    fn default() -> Self {
+        Self::from_config(&CurrencyConfig::default())
+    }
+}
+
+impl Rates {
+    /// The base and manual rates from the config; invalid ones are left out
+    pub fn from_config(config: &CurrencyConfig) -> Self {
+        let base = normalize_code(&config.base).unwrap_or_else(|| "USD".to_string());
+        let mut rates = Self {
+            base,
+            rates: BTreeMap::new(),
+        };
+        rates.extend(
+            config
+                .rates
+                .iter()
+                .map(|(code, rate)| (code.as_str(), *rate)),
+        );
+        rates
+    }
+
+    pub fn base(&self) -> &str {
+        &self.base
+    }
+
+    /// Add or replace rates, skipping the base and anything not positive
+    pub fn extend<'a>(&mut self, rates: impl IntoIterator<Item = (&'a str, f64)>) {
+        for (code, rate) in rates {
+            let Some(code) = normalize_code(code) else {
+                continue;
+            };
+            if code != self.base && rate.is_finite() && rate > 0.0 {
+                self.rates.insert(code, rate);
+            }
+        }
+    }
+
+    /// Currencies with a rate, in order, for picking one on a form
+    pub fn codes(&self) -> Vec<String> {
+        self.rates.keys().cloned().collect()
+    }
+
+    pub fn rate(&self, code: &str) -> Option<f64> {
+        self.rates.get(code).copied()
+    }
+
+    /// `currency` unless it is the base, for showing an amount with its code
+    pub fn foreign<'a>(&self, currency: Option<&'a str>) -> Option<&'a str> {
+        currency.filter(|code| !code.eq_ignore_ascii_case(&self.base))
+    }
+
+    /// `amount` in `currency` worth in the base currency; `None` without a
+    /// rate
+    pub fn convert(&self, amount: f64, currency: Option<&str>) -> Option<f64> {
+        match self.foreign(currency) {
+            None => Some(amount),
+            Some(code) => {
+                let rate = self.rate(&code.to_ascii_uppercase())?;
+                Some(Money::from_f64(amount * rate).to_f64())
+            }
+        }
+    }
+}
+
+/// A month's totals with every amount in the base currency
+#[derive(Debug, Clone)]
+pub struct ConvertedTotals {
+    pub totals: SummaryTotals,
+    /// Other currencies the month has amounts in
+    pub currencies: Vec<String>,
+    /// Of those, ones without a rate; their amounts are left out
+    pub missing: Vec<String>,
+}
+
+/// Totals of `expenses` and `incomes` in the base currency; `None` when all
+/// of them are already in it
+pub fn converted_totals(
+    expenses: &[Expense],
+    incomes: &[Income],
+    rates: &Rates,
+) -> Option<ConvertedTotals> {
+    let mut currencies = Vec::new();
+    let mut missing = Vec::new();
+    let mut convert = |amount: f64, currency: Option<&str>| {
+        if let Some(code) = rates.foreign(currency) {
+            let code = code.to_ascii_uppercase();
+            if rates.rate(&code).is_none() && !missing.contains(&code) {
+                missing.push(code.clone());
+            }
+            if !currencies.contains(&code) {
+                currencies.push(code);
+            }
+        }
+        rates.convert(amount, currency).unwrap_or(0.0)
+    };
+
+    let mut projected_expenses = Money::ZERO;
+    let mut current_expenses = Money::ZERO;
+    for expense in expenses {
+        let currency = expense.currency.as_deref();
+        projected_expenses += Money::from_f64(convert(expense.projected, currency));
+        current_expenses += Money::from_f64(convert(expense.cost, currency));
+    }
+    let mut projected_income = Money::ZERO;
+    let mut current_income = Money::ZERO;
+    for income in incomes {
+        let currency = income.currency.as_deref();
+        projected_income += Money::from_f64(convert(income.projected, currency));
+        current_income += Money::from_f64(convert(income.amount, currency));
+    }
+    if currencies.is_empty() {
+        return None;
+    }
+
+    currencies.sort();
+    missing.sort();
+    let totals = SummaryTotals {
+        total_projected_expenses: projected_expenses.to_f64(),
+        total_current_expenses: current_expenses.to_f64(),
+        total_projected_income: projected_income.to_f64(),
+        total_current_income: current_income.to_f64(),
+        total_projected: (projected_income - projected_expenses).to_f64(),
+        total_current: (current_income - current_expenses).to_f64(),
+    };
+    Some(ConvertedTotals {
+        totals,
+        currencies,
+        missing,
+    })
+}
+
+impl AppState {
+    /// Check if the month's totals may need converting: rates are set up, or
+    /// a listed entry is in another currency
+    pub fn may_mix_currencies(&self) -> bool {
+        let foreign = |currency: Option<&str>| self.rates.foreign(currency).is_some();
+        !self.rates.codes().is_empty()
+            || self
+                .data
+                .expenses
+                .iter()
+                .any(|e| foreign(e.currency.as_deref()))
+            || self
+                .data
+                .incomes
+                .iter()
+                .any(|i| foreign(i.currency.as_deref()))
+    }
+}
@@ -0,0 +1,99 @@
+//! Debts and their payoff
+//!
+//! Each month a debt gains a twelfth of its yearly interest on what is owed,
+//! rounded to the cent, and the payment comes off after. Recording a payment
+//! adds it as an expense in the debt's category and period, so it counts
+//! toward the month like any other bill, and lowers what is owed by the
+//! payment less that month's interest.
+
+use crate::models::{CustomFields, Debt, ExpenseCreate, Money};
+use crate::state::AppState;
+
+/// Longest payoff worked out; anything longer is shown as never
+pub const MAX_PAYOFF_MONTHS: usize = 600;
+
+/// Notes of the expenses payments are recorded as
+pub const PAYMENT_NOTE: &str = "Debt payment";
+
+/// How paying a debt off goes, month by month
+#[derive(Debug, Clone, PartialEq)]
+pub struct Payoff {
+    /// What is owed after each month's payment; the last is zero
+    pub balances: Vec<f64>,
+    /// Interest paid along the way
+    pub interest: f64,
+}
+
+impl Payoff {
+    /// Months until nothing is owed
+    pub fn months(&self) -> usize {
+        self.balances.len()
+    }
+}
+
+/// Interest one month adds to `principal` at `rate` percent a year
+pub fn monthly_interest(principal: f64, rate: f64) -> f64 {
+    Money::from_f64(principal * rate / 1200.0).to_f64()
+}
+
+/// Pay `payment` a month on `debt` until nothing is owed
+///
+/// `None` when the payment doesn't cover the interest, or it would take more
+/// than `MAX_PAYOFF_MONTHS`.
+pub fn payoff(debt: &Debt, payment: f64) -> Option<Payoff> {
+    let payment = Money::from_f64(payment);
+    let mut balance = Money::from_f64(debt.principal);
+    let mut interest = Money::ZERO;
+    let mut balances = Vec::new();
+    while balance > Money::ZERO {
+        if balances.len() == MAX_PAYOFF_MONTHS {
+            return None;
+        }
+        let charged = Money::from_f64(monthly_interest(balance.to_f64(), debt.interest_rate));
+        if payment <= charged {
+            return None;
+        }
+        interest += charged;
+        balance = (balance + charged - payment).max(Money::ZERO);
+        balances.push(balance.to_f64());
+    }
+    Some(Payoff {
+        balances,
+        interest: interest.to_f64(),
+    })
+}
+
+/// What is owed on `debt` after paying `amount` this month
+pub fn after_payment(debt: &Debt, amount: f64) -> f64 {
+    let owed = Money::from_f64(debt.principal)
+        + Money::from_f64(monthly_interest(debt.principal, debt.interest_rate));
+    (owed - Money::from_f64(amount)).max(Money::ZERO).to_f64()
+}
+
+/// The expense a payment of `amount` on `debt` is recorded as in `month_id`
+pub fn payment_expense(debt: &Debt, amount: f64, month_id: i32) -> ExpenseCreate {
+    ExpenseCreate {
+        expense_name: debt.name.clone(),
+        period: debt.period.clone(),
+        category: debt.category.clone(),
+        projected: debt.minimum_payment,
+        cost: amount,
+        notes: Some(PAYMENT_NOTE.to_string()),
+        month_id,
+        purchases: None,
+        expense_date: None,
+        account_id: None,
+        currency: None,
+        custom_fields: CustomFields::new(),
+    }
+}
+
+impl AppState {
+    /// The debt selected on the Debts tab
+    pub fn selected_debt(&self) -> Option<&Debt> {
+        self.ui
+            .debt_table
+            .selected()
+            .and_then(|idx| self.data.debts.get(idx))
+    }
+}
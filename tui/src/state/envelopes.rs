@@ -0,0 +1,173 @@
+//! Weekly envelopes
+//!
+//! For categories like groceries, where keeping pace week by week matters
+//! more than the monthly total, the category's budget for the month is split
+//! into one envelope per week, by the number of days each week has in the
+//! month. Spending lands in the week of its purchase date, or the expense's
+//! date when a purchase has none.
+
+use chrono::{Datelike, Days, NaiveDate, Weekday};
+
+use crate::models::{Expense, Money};
+use crate::state::AppState;
+
+/// One week of a category's budget
+#[derive(Debug, Clone, PartialEq)]
+pub struct WeekEnvelope {
+    pub start: NaiveDate,
+    /// Last day, inclusive
+    pub end: NaiveDate,
+    pub budget: f64,
+    pub spent: f64,
+}
+
+impl WeekEnvelope {
+    pub fn contains(&self, date: NaiveDate) -> bool {
+        self.start <= date && date <= self.end
+    }
+
+    /// Days of the month it covers, e.g. "8-14"
+    pub fn label(&self) -> String {
+        if self.start == self.end {
+            self.start.day().to_string()
+        } else {
+            format!("{}-{}", self.start.day(), self.end.day())
+        }
+    }
+
+    /// Share of the week that has passed by `today`, from 0 to 1
+    pub fn pace(&self, today: NaiveDate) -> f64 {
+        let total = (self.end - self.start).num_days() + 1;
+        let passed = (today - self.start).num_days() + 1;
+        (passed as f64 / total as f64).clamp(0.0, 1.0)
+    }
+}
+
+/// A category's budget split into weeks
+#[derive(Debug, Clone, PartialEq)]
+pub struct CategoryEnvelopes {
+    pub category: String,
+    pub weeks: Vec<WeekEnvelope>,
+    /// Spending without a date, or dated outside the month
+    pub undated: f64,
+}
+
+/// Weeks from `start` to `end` beginning on `first_day`, with the first and
+/// last cut off at the month's edges
+pub fn month_weeks(
+    start: NaiveDate,
+    end: NaiveDate,
+    first_day: Weekday,
+) -> Vec<(NaiveDate, NaiveDate)> {
+    let mut weeks = Vec::new();
+    let mut week_start = start;
+    while week_start <= end {
+        let days_left = 6 - week_start.weekday().days_since(first_day) as u64;
+        let week_end = week_start
+            .checked_add_days(Days::new(days_left))
+            .map_or(end, |d| d.min(end));
+        weeks.push((week_start, week_end));
+        week_start = match week_end.succ_opt() {
+            Some(next) => next,
+            None => break,
+        };
+    }
+    weeks
+}
+
+/// Split `budget` over the weeks from `start` to `end` and add up each week's
+/// spending in `expenses`
+pub fn category_envelopes(
+    category: &str,
+    budget: f64,
+    (start, end): (NaiveDate, NaiveDate),
+    first_day: Weekday,
+    expenses: &[&Expense],
+) -> CategoryEnvelopes {
+    let total_days = ((end - start).num_days() + 1).max(1) as f64;
+    let mut weeks: Vec<WeekEnvelope> = month_weeks(start, end, first_day)
+        .into_iter()
+        .map(|(start, end)| WeekEnvelope {
+            start,
+            end,
+            budget: budget * ((end - start).num_days() + 1) as f64 / total_days,
+            spent: 0.0,
+        })
+        .collect();
+
+    let mut undated = 0.0;
+    let mut spend = |date: Option<&str>, amount: f64| match date
+        .and_then(parse_date)
+        .and_then(|date| weeks.iter_mut().find(|w| w.contains(date)))
+    {
+        Some(week) => week.spent += amount,
+        None => undated += amount,
+    };
+    for expense in expenses {
+        match expense.purchases.as_deref() {
+            Some(purchases) if !purchases.is_empty() => {
+                for purchase in purchases {
+                    let date = purchase.date.as_deref().or(expense.expense_date.as_deref());
+                    spend(date, purchase.amount);
+                }
+            }
+            _ => spend(expense.expense_date.as_deref(), expense.cost),
+        }
+    }
+
+    CategoryEnvelopes {
+        category: category.to_string(),
+        weeks,
+        undated,
+    }
+}
+
+fn parse_date(date: &str) -> Option<NaiveDate> {
+    NaiveDate::parse_from_str(date.get(..10)?, "%Y-%m-%d").ok()
+}
+
+impl AppState {
+    /// Weekly envelopes of the configured categories in the selected month
+    ///
+    /// The budget is the category's projected total from the summary. Only
+    /// the loaded expenses count, so a category filter on the Expenses tab
+    /// leaves other categories' envelopes empty until it's removed.
+    pub fn weekly_envelopes(&self) -> Vec<CategoryEnvelopes> {
+        let range = match self.selected_month().and_then(|m| m.date_range()) {
+            Some(range) => range,
+            None => return Vec::new(),
+        };
+        self.envelope_categories
+            .iter()
+            .filter_map(|name| {
+                let name = name.trim();
+                let expenses: Vec<&Expense> = self
+                    .data
+                    .expenses
+                    .iter()
+                    .filter(|e| e.category.eq_ignore_ascii_case(name))
+                    .collect();
+                let (category, budget) = match self
+                    .data
+                    .category_summary
+                    .iter()
+                    .find(|cs| cs.category.eq_ignore_ascii_case(name))
+                {
+                    Some(cs) => (cs.category.as_str(), cs.projected),
+                    None if !expenses.is_empty() => (
+                        expenses[0].category.as_str(),
+                        expenses.iter().map(|e| e.projected).sum::<Money>().to_f64(),
+                    ),
+                    None => return None,
+                };
+                Some(category_envelopes(
+                    category,
+                    budget,
+                    range,
+                    self.period_display.week_start,
+                    &expenses,
+                ))
+            })
+            .collect()
+    }
+}
@@ -0,0 +1,154 @@
+use std::collections::BTreeMap;
+
+use crate::models::{
+    CategorySummary, Expense, Income, IncomeType, IncomeTypeSummary, Money, Period, PeriodSummary,
+    PeriodSummaryResponse, SummaryTotals,
+};
+
+/// Endpoints older servers may not have
+///
+/// Once one answers 404 or 501 it isn't asked again until the server changes;
+/// what it would return is computed from the month's expenses and incomes
+/// instead, or its panel is hidden.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub enum ServerFeature {
+    SummaryTotals,
+    CategorySummary,
+    IncomeTypeSummary,
+    PeriodSummary,
+    Insights,
+    /// Scoped API keys; without them the key may change anything
+    KeyScopes,
+    /// Accounts; without them entries aren't tied to one
+    Accounts,
+    /// Transfers between accounts
+    Transfers,
+    /// Debts and their payoff
+    Debts,
+}
+
+impl ServerFeature {
+    /// Check if what the endpoint returns is computed from the month's data
+    /// when it is missing
+    pub fn is_summary(&self) -> bool {
+        !matches!(
+            self,
+            ServerFeature::KeyScopes
+                | ServerFeature::Accounts
+                | ServerFeature::Transfers
+                | ServerFeature::Debts
+        )
+    }
+}
+
+/// Projected and actual totals, as `GET /summary/totals` computes them
+pub fn summary_totals(expenses: &[Expense], incomes: &[Income]) -> SummaryTotals {
+    let total_projected_expenses = expenses.iter().map(|e| e.projected).sum::<Money>().to_f64();
+    let total_current_expenses = expenses.iter().map(|e| e.cost).sum::<Money>().to_f64();
+    let total_projected_income = incomes.iter().map(|i| i.projected).sum::<Money>().to_f64();
+    let total_current_income = incomes.iter().map(|i| i.amount).sum::<Money>().to_f64();
+    SummaryTotals {
+        total_projected_expenses,
+        total_current_expenses,
+        total_projected_income,
+        total_current_income,
+        total_projected: total_projected_income - total_projected_expenses,
+        total_current: total_current_income - total_current_expenses,
+    }
+}
+
+/// Spending per category, by name
+pub fn category_summary(expenses: &[Expense]) -> Vec<CategorySummary> {
+    let mut totals: BTreeMap<&str, (f64, f64)> = BTreeMap::new();
+    for expense in expenses {
+        let entry = totals.entry(&expense.category).or_default();
+        entry.0 += expense.projected;
+        entry.1 += expense.cost;
+    }
+    totals
+        .into_iter()
+        .map(|(category, (projected, total))| CategorySummary {
+            category: category.to_string(),
+            projected,
+            total,
+            over_projected: total > projected,
+        })
+        .collect()
+}
+
+/// Income per income type, by name
+///
+/// Incomes of a type that isn't loaded are counted as "Unknown".
+pub fn income_type_summary(
+    incomes: &[Income],
+    income_types: &[IncomeType],
+) -> Vec<IncomeTypeSummary> {
+    let mut totals: BTreeMap<&str, (f64, f64)> = BTreeMap::new();
+    for income in incomes {
+        let name = income_types
+            .iter()
+            .find(|t| t.id == income.income_type_id)
+            .map(|t| t.name.as_str())
+            .unwrap_or("Unknown");
+        let entry = totals.entry(name).or_default();
+        entry.0 += income.projected;
+        entry.1 += income.amount;
+    }
+    totals
+        .into_iter()
+        .map(|(income_type, (projected, total))| IncomeTypeSummary {
+            income_type: income_type.to_string(),
+            projected,
+            total,
+        })
+        .collect()
+}
+
+/// Actual income and spending per period, in the order of `periods`
+pub fn period_summary(
+    expenses: &[Expense],
+    incomes: &[Income],
+    periods: &[Period],
+) -> PeriodSummaryResponse {
+    let periods: Vec<PeriodSummary> = periods
+        .iter()
+        .map(|period| {
+            let total_income = incomes
+                .iter()
+                .filter(|i| i.period == period.name)
+                .map(|i| i.amount)
+                .sum::<Money>()
+                .to_f64();
+            let total_expenses = expenses
+                .iter()
+                .filter(|e| e.period == period.name)
+                .map(|e| e.cost)
+                .sum::<Money>()
+                .to_f64();
+            PeriodSummary {
+                period: period.name.clone(),
+                color: period.color.clone(),
+                total_income,
+                total_expenses,
+                difference: total_income - total_expenses,
+            }
+        })
+        .collect();
+
+    let grand_total_income = periods
+        .iter()
+        .map(|p| p.total_income)
+        .sum::<Money>()
+        .to_f64();
+    let grand_total_expenses = periods
+        .iter()
+        .map(|p| p.total_expenses)
+        .sum::<Money>()
+        .to_f64();
+    PeriodSummaryResponse {
+        periods,
+        grand_total_income,
+        grand_total_expenses,
+        grand_total_difference: grand_total_income - grand_total_expenses,
+    }
+}
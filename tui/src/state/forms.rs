@@ -1,7 +1,12 @@
+use serde_json::Value;
+
+use super::money_input::{input_text, parse_money, tidy};
+use crate::api::FieldError;
 use crate::models::{
-    Category, CategoryCreate, CategoryUpdate, Expense, ExpenseCreate, ExpenseUpdate, Income,
-    IncomeCreate, IncomeType, IncomeTypeCreate, IncomeTypeUpdate, IncomeUpdate, Period,
-    PeriodCreate, PeriodUpdate, Purchase,
+    Account, AccountCreate, AccountKind, AccountUpdate, Category, CategoryCreate, CategoryUpdate,
+    CustomFields, Debt, DebtCreate, DebtUpdate, Expense, ExpenseCreate, ExpenseUpdate, Income,
+    IncomeCreate, IncomeType, IncomeTypeCreate, IncomeTypeUpdate, IncomeUpdate, Money, Period,
+    PeriodCreate, PeriodUpdate, Purchase, TransferCreate,
 };
 
 /// Form field indices for expense form
@@ -11,9 +16,13 @@ pub enum ExpenseField {
     Name,
     Period,
     Category,
+    Account,
+    Currency,
     Projected,
     Purchases,
     Notes,
+    /// Fields a customized server adds; skipped when it adds none
+    Custom,
 }
 
 impl ExpenseField {
@@ -22,9 +31,12 @@ impl ExpenseField {
             ExpenseField::Name,
             ExpenseField::Period,
             ExpenseField::Category,
+            ExpenseField::Account,
+            ExpenseField::Currency,
             ExpenseField::Projected,
             ExpenseField::Purchases,
             ExpenseField::Notes,
+            ExpenseField::Custom,
         ]
     }
 
@@ -33,9 +45,12 @@ impl ExpenseField {
             ExpenseField::Name => 0,
             ExpenseField::Period => 1,
             ExpenseField::Category => 2,
-            ExpenseField::Projected => 3,
-            ExpenseField::Purchases => 4,
-            ExpenseField::Notes => 5,
+            ExpenseField::Account => 3,
+            ExpenseField::Currency => 4,
+            ExpenseField::Projected => 5,
+            ExpenseField::Purchases => 6,
+            ExpenseField::Notes => 7,
+            ExpenseField::Custom => 8,
         }
     }
 
@@ -44,9 +59,12 @@ impl ExpenseField {
             0 => ExpenseField::Name,
             1 => ExpenseField::Period,
             2 => ExpenseField::Category,
-            3 => ExpenseField::Projected,
-            4 => ExpenseField::Purchases,
-            5 => ExpenseField::Notes,
+            3 => ExpenseField::Account,
+            4 => ExpenseField::Currency,
+            5 => ExpenseField::Projected,
+            6 => ExpenseField::Purchases,
+            7 => ExpenseField::Notes,
+            8 => ExpenseField::Custom,
             _ => ExpenseField::Name,
         }
     }
@@ -66,6 +84,81 @@ impl ExpenseField {
         };
         fields[idx]
     }
+
+    /// The field for a name in the expense request body
+    pub fn from_api_field(name: &str) -> Option<Self> {
+        match name {
+            "expense_name" => Some(ExpenseField::Name),
+            "period" => Some(ExpenseField::Period),
+            "category" => Some(ExpenseField::Category),
+            "account_id" => Some(ExpenseField::Account),
+            "currency" => Some(ExpenseField::Currency),
+            "projected" | "budget" => Some(ExpenseField::Projected),
+            "purchases" | "cost" => Some(ExpenseField::Purchases),
+            "notes" => Some(ExpenseField::Notes),
+            _ => None,
+        }
+    }
+}
+
+/// A field a customized server adds to expenses, as typed on the form
+#[derive(Debug, Clone, PartialEq)]
+pub struct CustomFieldInput {
+    pub name: String,
+    /// A value the server keeps in the field, which the text is read like
+    pub like: Value,
+    pub text: String,
+}
+
+impl CustomFieldInput {
+    fn new(name: &str, value: &Value) -> Self {
+        Self {
+            name: name.to_string(),
+            like: value.clone(),
+            text: CustomFields::text(value),
+        }
+    }
+
+    /// The text as the kind of value the field holds: a number stays a
+    /// number and a yes/no a bool; no text is null
+    pub fn value(&self) -> Result<Value, FieldError> {
+        let text = self.text.trim();
+        if text.is_empty() {
+            return Ok(Value::Null);
+        }
+        let invalid = |kind: &str| FieldError {
+            field: Some(self.name.clone()),
+            message: format!("{} must be {}", self.name, kind),
+        };
+        match &self.like {
+            Value::Number(_) => text
+                .parse::<i64>()
+                .map(Value::from)
+                .or_else(|_| text.parse::<f64>().map(Value::from))
+                .map_err(|_| invalid("a number")),
+            Value::Bool(_) => match text.to_ascii_lowercase().as_str() {
+                "true" | "yes" | "y" => Ok(Value::Bool(true)),
+                "false" | "no" | "n" => Ok(Value::Bool(false)),
+                _ => Err(invalid("yes or no")),
+            },
+            Value::Array(_) | Value::Object(_) => {
+                serde_json::from_str(text).map_err(|_| invalid("JSON"))
+            }
+            Value::Null | Value::String(_) => Ok(Value::String(self.text.clone())),
+        }
+    }
+}
+
+/// Custom fields on `expenses`, each with the first value it has, for
+/// offering them on a new expense
+pub fn custom_fields_in(expenses: &[Expense]) -> CustomFields {
+    let mut fields = CustomFields::new();
+    for (name, value) in expenses.iter().flat_map(|e| e.custom_fields.iter()) {
+        if fields.get(name).map_or(true, Value::is_null) {
+            fields.insert(name, value.clone());
+        }
+    }
+    fields
 }
 
 /// Purchase editing mode within expense form
@@ -82,7 +175,12 @@ pub struct ExpenseFormState {
     pub name: String,
     pub period: String,
     pub category: String,
+    pub account_id: Option<i32>,
+    /// Currency code when not the budget's own
+    pub currency: Option<String>,
     pub projected: String,
+    /// Projection when the form opened, which `+`/`-` amounts adjust
+    pub original_projected: f64,
     pub cost: String,
     pub notes: String,
     pub purchases: Vec<Purchase>,
@@ -93,6 +191,18 @@ pub struct ExpenseFormState {
     pub selected_purchase: usize,
     /// Which field in the purchase is being edited
     pub purchase_edit_field: PurchaseEditField,
+    /// Whether the cost is the purchases' total; off keeps the cost the
+    /// expense had, e.g. paid in one go with purchases only for the record
+    pub sum_purchases: bool,
+    /// Fields a customized server adds, in name order
+    pub custom_fields: Vec<CustomFieldInput>,
+    /// Currently selected custom field when in the Custom field
+    pub selected_custom: usize,
+    /// Field the server rejected on the last save, with its message
+    pub invalid: Option<(ExpenseField, String)>,
+    /// The expense as it was when the form opened, so saving sends only
+    /// what changed
+    pub original: Option<Expense>,
 }
 
 impl Default for ExpenseFormState {
@@ -102,7 +212,10 @@ impl Default for ExpenseFormState {
             name: String::new(),
             period: String::new(),
             category: String::new(),
+            account_id: None,
+            currency: None,
             projected: String::new(),
+            original_projected: 0.0,
             cost: "0".to_string(),
             notes: String::new(),
             purchases: Vec::new(),
@@ -110,6 +223,11 @@ impl Default for ExpenseFormState {
             focused_field: ExpenseField::Name,
             selected_purchase: 0,
             purchase_edit_field: PurchaseEditField::Name,
+            sum_purchases: true,
+            custom_fields: Vec::new(),
+            selected_custom: 0,
+            invalid: None,
+            original: None,
         }
     }
 }
@@ -123,7 +241,7 @@ impl ExpenseFormState {
                 if p.amount == 0.0 {
                     String::new()
                 } else {
-                    format!("{}", p.amount)
+                    input_text(p.amount)
                 }
             })
             .collect();
@@ -132,15 +250,103 @@ impl ExpenseFormState {
             name: expense.expense_name.clone(),
             period: expense.period.clone(),
             category: expense.category.clone(),
-            projected: expense.projected.to_string(),
-            cost: expense.cost.to_string(),
+            account_id: expense.account_id,
+            currency: expense.currency.clone(),
+            projected: input_text(expense.projected),
+            original_projected: expense.projected,
+            cost: input_text(expense.cost),
             notes: expense.notes.clone().unwrap_or_default(),
             purchases,
             purchase_amount_inputs,
             focused_field: ExpenseField::Name,
             selected_purchase: 0,
             purchase_edit_field: PurchaseEditField::Name,
+            sum_purchases: sums_purchases(expense.cost, expense.purchases.as_deref()),
+            custom_fields: expense
+                .custom_fields
+                .iter()
+                .map(|(name, value)| CustomFieldInput::new(name, value))
+                .collect(),
+            selected_custom: 0,
+            invalid: None,
+            original: Some(expense.clone()),
+        }
+    }
+
+    /// A new expense filled in like `create`, as `.` repeats it
+    pub fn from_create(create: &ExpenseCreate) -> Self {
+        let purchases = create.purchases.clone().unwrap_or_default();
+        let purchase_amount_inputs = purchases.iter().map(|p| input_text(p.amount)).collect();
+        Self {
+            name: create.expense_name.clone(),
+            period: create.period.clone(),
+            category: create.category.clone(),
+            account_id: create.account_id,
+            currency: create.currency.clone(),
+            projected: input_text(create.projected),
+            original_projected: create.projected,
+            cost: input_text(create.cost),
+            notes: create.notes.clone().unwrap_or_default(),
+            purchases,
+            purchase_amount_inputs,
+            sum_purchases: sums_purchases(create.cost, create.purchases.as_deref()),
+            custom_fields: create
+                .custom_fields
+                .iter()
+                .map(|(name, value)| CustomFieldInput::new(name, value))
+                .collect(),
+            ..Default::default()
+        }
+    }
+
+    /// Add blank inputs for the fields in `fields` the form doesn't have,
+    /// e.g. those other expenses have on a new one
+    pub fn offer_custom_fields(&mut self, fields: &CustomFields) {
+        for (name, value) in fields.iter() {
+            if !self.custom_fields.iter().any(|field| field.name == name) {
+                let mut input = CustomFieldInput::new(name, value);
+                input.text.clear();
+                self.custom_fields.push(input);
+            }
         }
+        self.custom_fields.sort_by(|a, b| a.name.cmp(&b.name));
+    }
+
+    /// Move focus to the next field, past Custom when there are none
+    pub fn focus_next(&mut self) {
+        self.focused_field = self.focused_field.next();
+        if self.focused_field == ExpenseField::Custom && self.custom_fields.is_empty() {
+            self.focused_field = self.focused_field.next();
+        }
+    }
+
+    /// Like `focus_next`, backwards
+    pub fn focus_previous(&mut self) {
+        self.focused_field = self.focused_field.previous();
+        if self.focused_field == ExpenseField::Custom && self.custom_fields.is_empty() {
+            self.focused_field = self.focused_field.previous();
+        }
+    }
+
+    /// Custom fields whose text doesn't read as their kind of value
+    pub fn custom_field_errors(&self) -> Vec<FieldError> {
+        self.custom_fields
+            .iter()
+            .filter_map(|field| field.value().err())
+            .collect()
+    }
+
+    /// The custom fields' values that `keep` picks; `None` when one doesn't
+    /// read
+    fn custom_values(&self, keep: impl Fn(&str, &Value) -> bool) -> Option<CustomFields> {
+        let mut values = CustomFields::new();
+        for field in &self.custom_fields {
+            let value = field.value().ok()?;
+            if keep(&field.name, &value) {
+                values.insert(field.name.clone(), value);
+            }
+        }
+        Some(values)
     }
 
     /// Add a new empty purchase
@@ -168,10 +374,30 @@ impl ExpenseFormState {
 
     /// Sync purchase amounts from string inputs to Purchase structs
     pub fn sync_purchase_amounts(&mut self) {
-        for (i, amount_str) in self.purchase_amount_inputs.iter().enumerate() {
-            if let Some(purchase) = self.purchases.get_mut(i) {
-                purchase.amount = amount_str.parse().unwrap_or(0.0);
-            }
+        for i in 0..self.purchases.len() {
+            self.purchases[i].amount = self.purchase_amount(i);
+        }
+    }
+
+    /// Amount typed for a purchase; `+`/`-` adjust what it was saved with
+    fn purchase_amount(&self, index: usize) -> f64 {
+        let current = self.purchases.get(index).map_or(0.0, |p| p.amount);
+        self.purchase_amount_inputs
+            .get(index)
+            .and_then(|s| parse_money(s, current))
+            .unwrap_or(0.0)
+    }
+
+    pub fn projected_value(&self) -> Option<f64> {
+        parse_money(&self.projected, self.original_projected)
+    }
+
+    /// Write out shorthand in the amount fields, e.g. `1.2k` as `1200.00`
+    pub fn tidy_amounts(&mut self) {
+        tidy(&mut self.projected, self.original_projected);
+        for (i, input) in self.purchase_amount_inputs.iter_mut().enumerate() {
+            let current = self.purchases.get(i).map_or(0.0, |p| p.amount);
+            tidy(input, current);
         }
     }
 
@@ -185,10 +411,20 @@ impl ExpenseFormState {
 
     /// Calculate cost from purchases (always calculated, never manually editable)
     pub fn calculated_cost(&self) -> f64 {
-        self.purchase_amount_inputs
-            .iter()
-            .map(|s| s.parse::<f64>().unwrap_or(0.0))
-            .sum()
+        (0..self.purchase_amount_inputs.len())
+            .map(|i| self.purchase_amount(i))
+            .sum::<Money>()
+            .to_f64()
+    }
+
+    /// The cost to save: the purchases' total, or the cost typed when the
+    /// form doesn't sum them
+    pub fn cost_value(&self) -> f64 {
+        if self.sum_purchases {
+            self.calculated_cost()
+        } else {
+            parse_money(&self.cost, 0.0).unwrap_or(0.0)
+        }
     }
 
     /// Build purchases with synced amounts from string inputs
@@ -196,25 +432,19 @@ impl ExpenseFormState {
         self.purchases
             .iter()
             .enumerate()
-            .map(|(i, p)| {
-                let amount = self
-                    .purchase_amount_inputs
-                    .get(i)
-                    .and_then(|s| s.parse().ok())
-                    .unwrap_or(0.0);
-                Purchase {
-                    name: p.name.clone(),
-                    amount,
-                    date: p.date.clone(),
-                }
+            .map(|(i, p)| Purchase {
+                name: p.name.clone(),
+                amount: self.purchase_amount(i),
+                date: p.date.clone(),
             })
             .collect()
     }
 
     pub fn to_create(&self, month_id: i32) -> Option<ExpenseCreate> {
-        let projected = self.projected.parse().ok()?;
+        let projected = self.projected_value()?;
         let purchases = self.build_purchases();
-        let cost: f64 = purchases.iter().map(|p| p.amount).sum();
+        let cost = self.cost_value();
+        let custom_fields = self.custom_values(|_, value| !value.is_null())?;
         Some(ExpenseCreate {
             expense_name: self.name.clone(),
             period: self.period.clone(),
@@ -233,42 +463,122 @@ impl ExpenseFormState {
                 Some(purchases)
             },
             expense_date: None,
+            account_id: self.account_id,
+            currency: self.currency.clone(),
+            custom_fields,
         })
     }
 
+    /// The fields that differ from the expense the form was opened with;
+    /// all of them for a form without one
     pub fn to_update(&self) -> Option<ExpenseUpdate> {
-        let projected = self.projected.parse().ok()?;
+        let projected = self.projected_value()?;
         let purchases = self.build_purchases();
-        let cost: f64 = purchases.iter().map(|p| p.amount).sum();
+        let cost = self.cost_value();
+        let original = match &self.original {
+            Some(original) => original,
+            None => {
+                return Some(ExpenseUpdate {
+                    expense_name: Some(self.name.clone()),
+                    period: Some(self.period.clone()),
+                    category: Some(self.category.clone()),
+                    projected: Some(projected),
+                    cost: Some(cost),
+                    notes: Some(self.notes.clone()),
+                    purchases: Some(purchases),
+                    account_id: self.account_id.map(Some),
+                    currency: self.currency.clone().map(Some),
+                    custom_fields: self.custom_values(|_, value| !value.is_null())?,
+                    ..Default::default()
+                })
+            }
+        };
+
+        let custom_fields = self.custom_values(|name, value| {
+            original.custom_fields.get(name).unwrap_or(&Value::Null) != value
+        })?;
+        let purchases_changed =
+            purchases.as_slice() != original.purchases.as_deref().unwrap_or_default();
+        let cost_changed = Money::from_f64(cost) != Money::from_f64(original.cost);
         Some(ExpenseUpdate {
-            expense_name: Some(self.name.clone()),
-            period: Some(self.period.clone()),
-            category: Some(self.category.clone()),
-            projected: Some(projected),
-            cost: Some(cost),
-            notes: Some(self.notes.clone()),
-            purchases: Some(purchases),
+            expense_name: changed(&self.name, &original.expense_name),
+            period: changed(&self.period, &original.period),
+            category: changed(&self.category, &original.category),
+            projected: (projected != original.projected).then_some(projected),
+            cost: cost_changed.then_some(cost),
+            notes: changed(&self.notes, original.notes.as_deref().unwrap_or_default()),
+            purchases: purchases_changed.then_some(purchases),
+            account_id: (self.account_id != original.account_id).then_some(self.account_id),
+            currency: (self.currency != original.currency).then(|| self.currency.clone()),
+            custom_fields,
             ..Default::default()
         })
     }
 
+    /// Check the typed amounts; names and lengths are checked on the request
+    /// body (`validate::Validate`), which says which field to fix
     pub fn validate(&self) -> Vec<String> {
         let mut errors = Vec::new();
-        if self.name.trim().is_empty() {
-            errors.push("Name is required".to_string());
-        }
-        if self.period.trim().is_empty() {
-            errors.push("Period is required".to_string());
-        }
-        if self.category.trim().is_empty() {
-            errors.push("Category is required".to_string());
-        }
-        if self.projected.parse::<f64>().is_err() {
+        if self.projected_value().is_none() {
             errors.push("Projected must be a valid number".to_string());
         }
         // Purchases are optional - no validation required
+        errors.extend(self.custom_field_errors().into_iter().map(|e| e.message));
         errors
     }
+
+    /// Focus and mark the first field among `errors`; false if none of them
+    /// is on the form
+    pub fn show_field_errors(&mut self, errors: &[FieldError]) -> bool {
+        let invalid = errors.iter().find_map(|error| {
+            let name = error.field.as_deref()?;
+            if let Some(i) = self.custom_fields.iter().position(|f| f.name == name) {
+                self.selected_custom = i;
+                return Some((ExpenseField::Custom, error.message.clone()));
+            }
+            let field = ExpenseField::from_api_field(name)?;
+            Some((field, error.message.clone()))
+        });
+        match invalid {
+            Some((field, message)) => {
+                self.focused_field = field;
+                self.invalid = Some((field, message));
+                true
+            }
+            None => false,
+        }
+    }
+}
+
+/// Whether an expense's cost is its purchases' total, so editing them
+/// should keep it that way; an expense without either starts out summing
+pub fn sums_purchases(cost: f64, purchases: Option<&[Purchase]>) -> bool {
+    let total = purchases.iter().flatten().map(|p| p.amount).sum::<Money>();
+    total == Money::from_f64(cost)
+}
+
+/// The account before or after `current` in `accounts`, with no account
+/// between the last and the first
+pub fn step_account(accounts: &[Account], current: Option<i32>, forward: bool) -> Option<i32> {
+    let options: Vec<Option<i32>> = std::iter::once(None)
+        .chain(accounts.iter().map(|a| Some(a.id)))
+        .collect();
+    let len = options.len();
+    let i = options.iter().position(|o| *o == current).unwrap_or(0);
+    let next = if forward { i + 1 } else { i + len - 1 };
+    options[next % len]
+}
+
+/// The currency before or after `current` in `codes`, with the budget's own
+/// (`None`) between the last and the first
+pub fn step_currency(codes: &[String], current: Option<&str>, forward: bool) -> Option<String> {
+    let options: Vec<Option<&str>> = std::iter::once(None)
+        .chain(codes.iter().map(|c| Some(c.as_str())))
+        .collect();
+    let len = options.len();
+    let i = options.iter().position(|o| *o == current).unwrap_or(0);
+    let next = if forward { i + 1 } else { i + len - 1 };
+    options[next % len].map(str::to_string)
 }
 
 /// Income form field indices
@@ -276,6 +586,8 @@ impl ExpenseFormState {
 pub enum IncomeField {
     IncomeType,
     Period,
+    Account,
+    Currency,
     Projected,
     Amount,
 }
@@ -285,6 +597,8 @@ impl IncomeField {
         &[
             IncomeField::IncomeType,
             IncomeField::Period,
+            IncomeField::Account,
+            IncomeField::Currency,
             IncomeField::Projected,
             IncomeField::Amount,
         ]
@@ -294,8 +608,10 @@ impl IncomeField {
         match self {
             IncomeField::IncomeType => 0,
             IncomeField::Period => 1,
-            IncomeField::Projected => 2,
-            IncomeField::Amount => 3,
+            IncomeField::Account => 2,
+            IncomeField::Currency => 3,
+            IncomeField::Projected => 4,
+            IncomeField::Amount => 5,
         }
     }
 
@@ -303,8 +619,10 @@ impl IncomeField {
         match index {
             0 => IncomeField::IncomeType,
             1 => IncomeField::Period,
-            2 => IncomeField::Projected,
-            3 => IncomeField::Amount,
+            2 => IncomeField::Account,
+            3 => IncomeField::Currency,
+            4 => IncomeField::Projected,
+            5 => IncomeField::Amount,
             _ => IncomeField::IncomeType,
         }
     }
@@ -324,6 +642,19 @@ impl IncomeField {
         };
         fields[idx]
     }
+
+    /// The field for a name in the income request body
+    pub fn from_api_field(name: &str) -> Option<Self> {
+        match name {
+            "income_type_id" => Some(IncomeField::IncomeType),
+            "period" => Some(IncomeField::Period),
+            "account_id" => Some(IncomeField::Account),
+            "currency" => Some(IncomeField::Currency),
+            "projected" | "budget" => Some(IncomeField::Projected),
+            "amount" => Some(IncomeField::Amount),
+            _ => None,
+        }
+    }
 }
 
 /// Income form state
@@ -332,9 +663,18 @@ pub struct IncomeFormState {
     pub editing_id: Option<i32>,
     pub income_type_id: Option<i32>,
     pub period: String,
+    pub account_id: Option<i32>,
+    pub currency: Option<String>,
     pub projected: String,
     pub amount: String,
+    /// Values when the form opened, which `+`/`-` amounts adjust
+    pub original_projected: f64,
+    pub original_amount: f64,
     pub focused_field: IncomeField,
+    /// Field the server rejected on the last save, with its message
+    pub invalid: Option<(IncomeField, String)>,
+    /// Like `ExpenseFormState::original`
+    pub original: Option<Income>,
 }
 
 impl Default for IncomeFormState {
@@ -343,9 +683,15 @@ impl Default for IncomeFormState {
             editing_id: None,
             income_type_id: None,
             period: String::new(),
+            account_id: None,
+            currency: None,
             projected: String::new(),
             amount: "0".to_string(),
+            original_projected: 0.0,
+            original_amount: 0.0,
             focused_field: IncomeField::IncomeType,
+            invalid: None,
+            original: None,
         }
     }
 }
@@ -356,53 +702,123 @@ impl IncomeFormState {
             editing_id: Some(income.id),
             income_type_id: Some(income.income_type_id),
             period: income.period.clone(),
-            projected: income.projected.to_string(),
-            amount: income.amount.to_string(),
+            account_id: income.account_id,
+            currency: income.currency.clone(),
+            projected: input_text(income.projected),
+            amount: input_text(income.amount),
+            original_projected: income.projected,
+            original_amount: income.amount,
             focused_field: IncomeField::IncomeType,
+            invalid: None,
+            original: Some(income.clone()),
         }
     }
 
+    /// A new income filled in like `create`, as `.` repeats it
+    pub fn from_create(create: &IncomeCreate) -> Self {
+        Self {
+            income_type_id: Some(create.income_type_id),
+            period: create.period.clone(),
+            account_id: create.account_id,
+            currency: create.currency.clone(),
+            projected: input_text(create.projected),
+            amount: input_text(create.amount),
+            original_projected: create.projected,
+            original_amount: create.amount,
+            ..Default::default()
+        }
+    }
+
+    pub fn projected_value(&self) -> Option<f64> {
+        parse_money(&self.projected, self.original_projected)
+    }
+
+    pub fn amount_value(&self) -> Option<f64> {
+        parse_money(&self.amount, self.original_amount)
+    }
+
+    /// Write out shorthand in the amount fields, e.g. `1.2k` as `1200.00`
+    pub fn tidy_amounts(&mut self) {
+        tidy(&mut self.projected, self.original_projected);
+        tidy(&mut self.amount, self.original_amount);
+    }
+
     pub fn to_create(&self, month_id: i32) -> Option<IncomeCreate> {
         let income_type_id = self.income_type_id?;
-        let projected = self.projected.parse().ok()?;
-        let amount = self.amount.parse().ok()?;
+        let projected = self.projected_value()?;
+        let amount = self.amount_value()?;
         Some(IncomeCreate {
             income_type_id,
             period: self.period.clone(),
             projected,
             amount,
             month_id,
+            account_id: self.account_id,
+            currency: self.currency.clone(),
         })
     }
 
+    /// Like `ExpenseFormState::to_update`
     pub fn to_update(&self) -> Option<IncomeUpdate> {
-        let projected = self.projected.parse().ok()?;
-        let amount = self.amount.parse().ok()?;
+        let projected = self.projected_value()?;
+        let amount = self.amount_value()?;
+        let original = match &self.original {
+            Some(original) => original,
+            None => {
+                return Some(IncomeUpdate {
+                    income_type_id: self.income_type_id,
+                    period: Some(self.period.clone()),
+                    projected: Some(projected),
+                    amount: Some(amount),
+                    account_id: self.account_id.map(Some),
+                    currency: self.currency.clone().map(Some),
+                    ..Default::default()
+                })
+            }
+        };
         Some(IncomeUpdate {
-            income_type_id: self.income_type_id,
-            period: Some(self.period.clone()),
-            projected: Some(projected),
-            amount: Some(amount),
+            income_type_id: self
+                .income_type_id
+                .filter(|id| *id != original.income_type_id),
+            period: changed(&self.period, &original.period),
+            projected: (projected != original.projected).then_some(projected),
+            amount: (amount != original.amount).then_some(amount),
+            account_id: (self.account_id != original.account_id).then_some(self.account_id),
+            currency: (self.currency != original.currency).then(|| self.currency.clone()),
             ..Default::default()
         })
     }
 
+    /// Like `ExpenseFormState::validate`
     pub fn validate(&self) -> Vec<String> {
         let mut errors = Vec::new();
         if self.income_type_id.is_none() {
             errors.push("Income type is required".to_string());
         }
-        if self.period.trim().is_empty() {
-            errors.push("Period is required".to_string());
-        }
-        if self.projected.parse::<f64>().is_err() {
+        if self.projected_value().is_none() {
             errors.push("Projected must be a valid number".to_string());
         }
-        if self.amount.parse::<f64>().is_err() {
+        if self.amount_value().is_none() {
             errors.push("Amount must be a valid number".to_string());
         }
         errors
     }
+
+    /// Like `ExpenseFormState::show_field_errors`
+    pub fn show_field_errors(&mut self, errors: &[FieldError]) -> bool {
+        let invalid = errors.iter().find_map(|error| {
+            let field = IncomeField::from_api_field(error.field.as_deref()?)?;
+            Some((field, error.message.clone()))
+        });
+        match invalid {
+            Some((field, message)) => {
+                self.focused_field = field;
+                self.invalid = Some((field, message));
+                true
+            }
+            None => false,
+        }
+    }
 }
 
 /// Category form state
@@ -549,6 +965,367 @@ impl IncomeTypeFormState {
     }
 }
 
+/// Account form state
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct AccountFormState {
+    pub editing_id: Option<i32>,
+    pub name: String,
+    pub kind: AccountKind,
+}
+
+impl AccountFormState {
+    pub fn from_account(account: &Account) -> Self {
+        Self {
+            editing_id: Some(account.id),
+            name: account.name.clone(),
+            kind: account.kind,
+        }
+    }
+
+    pub fn to_create(&self) -> AccountCreate {
+        AccountCreate {
+            name: self.name.trim().to_string(),
+            kind: self.kind,
+        }
+    }
+
+    pub fn to_update(&self) -> AccountUpdate {
+        AccountUpdate {
+            name: self.name.trim().to_string(),
+            kind: self.kind,
+        }
+    }
+
+    pub fn validate(&self) -> Vec<String> {
+        let mut errors = Vec::new();
+        if self.name.trim().is_empty() {
+            errors.push("Name is required".to_string());
+        }
+        errors
+    }
+}
+
+/// Transfer form field indices
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum TransferField {
+    #[default]
+    From,
+    To,
+    Amount,
+    Notes,
+}
+
+impl TransferField {
+    pub fn all() -> &'static [TransferField] {
+        &[
+            TransferField::From,
+            TransferField::To,
+            TransferField::Amount,
+            TransferField::Notes,
+        ]
+    }
+
+    pub fn index(&self) -> usize {
+        match self {
+            TransferField::From => 0,
+            TransferField::To => 1,
+            TransferField::Amount => 2,
+            TransferField::Notes => 3,
+        }
+    }
+
+    pub fn next(&self) -> Self {
+        let fields = Self::all();
+        fields[(self.index() + 1) % fields.len()]
+    }
+
+    pub fn previous(&self) -> Self {
+        let fields = Self::all();
+        fields[(self.index() + fields.len() - 1) % fields.len()]
+    }
+
+    /// The field for a name in the transfer request body
+    pub fn from_api_field(name: &str) -> Option<Self> {
+        match name {
+            "from_account_id" => Some(TransferField::From),
+            "to_account_id" => Some(TransferField::To),
+            "amount" => Some(TransferField::Amount),
+            "notes" => Some(TransferField::Notes),
+            _ => None,
+        }
+    }
+}
+
+/// Transfer form state
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct TransferFormState {
+    pub from_account_id: Option<i32>,
+    pub to_account_id: Option<i32>,
+    pub amount: String,
+    pub notes: String,
+    pub focused: TransferField,
+}
+
+impl TransferFormState {
+    /// A transfer out of `from`, into the first of the other accounts
+    pub fn new(accounts: &[Account], from: Option<i32>) -> Self {
+        Self {
+            from_account_id: from,
+            to_account_id: accounts.iter().map(|a| a.id).find(|id| Some(*id) != from),
+            ..Default::default()
+        }
+    }
+
+    pub fn validate(&self) -> Vec<String> {
+        let mut errors = Vec::new();
+        match (self.from_account_id, self.to_account_id) {
+            (None, _) => errors.push("From account is required".to_string()),
+            (_, None) => errors.push("To account is required".to_string()),
+            (from, to) if from == to => {
+                errors.push("From and to must be different accounts".to_string())
+            }
+            _ => {}
+        }
+        match parse_money(&self.amount, 0.0) {
+            None => errors.push("Amount must be a number".to_string()),
+            Some(amount) if amount <= 0.0 => {
+                errors.push("Amount must be more than zero".to_string())
+            }
+            Some(_) => {}
+        }
+        errors
+    }
+
+    /// Focus the first field among `errors`; false if none of them is on
+    /// the form
+    pub fn show_field_errors(&mut self, errors: &[FieldError]) -> bool {
+        let field = errors
+            .iter()
+            .find_map(|error| TransferField::from_api_field(error.field.as_deref()?));
+        match field {
+            Some(field) => {
+                self.focused = field;
+                true
+            }
+            None => false,
+        }
+    }
+
+    /// The transfer to record in `month_id`; `None` until the form validates
+    pub fn to_create(&self, month_id: i32) -> Option<TransferCreate> {
+        if !self.validate().is_empty() {
+            return None;
+        }
+        let notes = self.notes.trim();
+        Some(TransferCreate {
+            from_account_id: self.from_account_id?,
+            to_account_id: self.to_account_id?,
+            amount: parse_money(&self.amount, 0.0)?,
+            month_id,
+            transfer_date: None,
+            notes: (!notes.is_empty()).then(|| notes.to_string()),
+        })
+    }
+}
+
+/// Debt form field indices
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum DebtField {
+    #[default]
+    Name,
+    Principal,
+    Rate,
+    Minimum,
+    Category,
+    Period,
+}
+
+impl DebtField {
+    pub fn all() -> &'static [DebtField] {
+        &[
+            DebtField::Name,
+            DebtField::Principal,
+            DebtField::Rate,
+            DebtField::Minimum,
+            DebtField::Category,
+            DebtField::Period,
+        ]
+    }
+
+    pub fn index(&self) -> usize {
+        match self {
+            DebtField::Name => 0,
+            DebtField::Principal => 1,
+            DebtField::Rate => 2,
+            DebtField::Minimum => 3,
+            DebtField::Category => 4,
+            DebtField::Period => 5,
+        }
+    }
+
+    pub fn next(&self) -> Self {
+        let fields = Self::all();
+        fields[(self.index() + 1) % fields.len()]
+    }
+
+    pub fn previous(&self) -> Self {
+        let fields = Self::all();
+        fields[(self.index() + fields.len() - 1) % fields.len()]
+    }
+
+    /// The field for a name in the debt request body
+    pub fn from_api_field(name: &str) -> Option<Self> {
+        match name {
+            "name" => Some(DebtField::Name),
+            "principal" => Some(DebtField::Principal),
+            "interest_rate" => Some(DebtField::Rate),
+            "minimum_payment" => Some(DebtField::Minimum),
+            "category" => Some(DebtField::Category),
+            "period" => Some(DebtField::Period),
+            _ => None,
+        }
+    }
+}
+
+/// Debt form state
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct DebtFormState {
+    pub editing_id: Option<i32>,
+    pub name: String,
+    pub principal: String,
+    /// Yearly, as a percentage
+    pub interest_rate: String,
+    pub minimum_payment: String,
+    pub category: String,
+    pub period: String,
+    pub focused: DebtField,
+}
+
+impl DebtFormState {
+    /// An empty form paying into the first category and period
+    pub fn new(categories: &[Category], periods: &[Period]) -> Self {
+        Self {
+            category: categories
+                .first()
+                .map(|c| c.name.clone())
+                .unwrap_or_default(),
+            period: periods.first().map(|p| p.name.clone()).unwrap_or_default(),
+            ..Default::default()
+        }
+    }
+
+    pub fn from_debt(debt: &Debt) -> Self {
+        Self {
+            editing_id: Some(debt.id),
+            name: debt.name.clone(),
+            principal: input_text(debt.principal),
+            interest_rate: debt.interest_rate.to_string(),
+            minimum_payment: input_text(debt.minimum_payment),
+            category: debt.category.clone(),
+            period: debt.period.clone(),
+            focused: DebtField::Name,
+        }
+    }
+
+    /// Move the focused category or period to the one before or after it
+    pub fn step_choice(&mut self, categories: &[Category], periods: &[Period], forward: bool) {
+        let (names, current): (Vec<&str>, &mut String) = match self.focused {
+            DebtField::Category => (
+                categories.iter().map(|c| c.name.as_str()).collect(),
+                &mut self.category,
+            ),
+            DebtField::Period => (
+                periods.iter().map(|p| p.name.as_str()).collect(),
+                &mut self.period,
+            ),
+            _ => return,
+        };
+        if names.is_empty() {
+            return;
+        }
+        let len = names.len();
+        let next = match names.iter().position(|name| *name == current.as_str()) {
+            Some(i) if forward => (i + 1) % len,
+            Some(i) => (i + len - 1) % len,
+            None => 0,
+        };
+        *current = names[next].to_string();
+    }
+
+    fn rate(&self) -> Option<f64> {
+        self.interest_rate
+            .trim()
+            .parse::<f64>()
+            .ok()
+            .filter(|rate| (0.0..=100.0).contains(rate))
+    }
+
+    pub fn validate(&self) -> Vec<String> {
+        let mut errors = Vec::new();
+        if self.name.trim().is_empty() {
+            errors.push("Name is required".to_string());
+        }
+        if parse_money(&self.principal, 0.0).is_none() {
+            errors.push("Owed must be a number".to_string());
+        }
+        if self.rate().is_none() {
+            errors.push("Interest must be a percentage from 0 to 100".to_string());
+        }
+        if parse_money(&self.minimum_payment, 0.0).is_none() {
+            errors.push("Minimum payment must be a number".to_string());
+        }
+        if self.category.is_empty() {
+            errors.push("Category is required".to_string());
+        }
+        if self.period.is_empty() {
+            errors.push("Period is required".to_string());
+        }
+        errors
+    }
+
+    /// Like `TransferFormState::show_field_errors`
+    pub fn show_field_errors(&mut self, errors: &[FieldError]) -> bool {
+        let field = errors
+            .iter()
+            .find_map(|error| DebtField::from_api_field(error.field.as_deref()?));
+        match field {
+            Some(field) => {
+                self.focused = field;
+                true
+            }
+            None => false,
+        }
+    }
+
+    /// The new debt; `None` until the form validates
+    pub fn to_create(&self) -> Option<DebtCreate> {
+        if !self.validate().is_empty() {
+            return None;
+        }
+        Some(DebtCreate {
+            name: self.name.trim().to_string(),
+            principal: parse_money(&self.principal, 0.0)?,
+            interest_rate: self.rate()?,
+            minimum_payment: parse_money(&self.minimum_payment, 0.0)?,
+            category: self.category.clone(),
+            period: self.period.clone(),
+        })
+    }
+
+    /// The edited debt; `None` until the form validates
+    pub fn to_update(&self) -> Option<DebtUpdate> {
+        let create = self.to_create()?;
+        Some(DebtUpdate {
+            name: create.name,
+            principal: create.principal,
+            interest_rate: create.interest_rate,
+            minimum_payment: create.minimum_payment,
+            category: create.category,
+            period: create.period,
+        })
+    }
+}
+
 /// Password change form state
 #[derive(Debug, Clone, Default)]
 pub struct PasswordFormState {
@@ -606,3 +1383,8 @@ impl LoginFormState {
         self.focused_field = if self.focused_field == 0 { 1 } else { 0 };
     }
 }
+
+/// `value` if it differs from `original`
+fn changed(value: &str, original: &str) -> Option<String> {
+    (value != original).then(|| value.to_string())
+}
@@ -0,0 +1,78 @@
+//! What was done this session
+//!
+//! `H` lists the last actions and `.` repeats the most recent one, e.g. to
+//! add several similar expenses or export again after a fix.
+
+use std::collections::VecDeque;
+
+use chrono::NaiveTime;
+
+use crate::models::{ExpenseCreate, IncomeCreate};
+
+/// Actions kept for the history panel
+pub const HISTORY_LEN: usize = 20;
+
+/// Something done on the dashboard that `.` can do again
+#[derive(Debug, Clone)]
+pub enum Action {
+    /// Repeating opens the form filled in the same way, for the selected month
+    CreateExpense(ExpenseCreate),
+    CreateIncome(IncomeCreate),
+    PeriodFilter(String),
+    CategoryFilter(String),
+    /// Exports run again for the selected month (or its year)
+    ExportMonthCsv,
+    ExportYearXlsx,
+    ExportTaxReport,
+    PushGoogleSheets,
+}
+
+/// One action in the history
+#[derive(Debug, Clone)]
+pub struct HistoryEntry {
+    pub action: Action,
+    /// What was done, e.g. "Created expense Rent ($1200.00)"
+    pub label: String,
+    pub at: NaiveTime,
+}
+
+/// The last actions of this session, newest first; only kept in memory
+#[derive(Debug, Clone, Default)]
+pub struct ActionHistory {
+    entries: VecDeque<HistoryEntry>,
+}
+
+impl ActionHistory {
+    /// Add an action, dropping the oldest past `HISTORY_LEN`
+    pub fn record(&mut self, action: Action, label: impl Into<String>, at: NaiveTime) {
+        self.entries.push_front(HistoryEntry {
+            action,
+            label: label.into(),
+            at,
+        });
+        self.entries.truncate(HISTORY_LEN);
+    }
+
+    /// The most recent action, which `.` repeats
+    pub fn last(&self) -> Option<&HistoryEntry> {
+        self.entries.front()
+    }
+
+    /// Action `index`, counting from the newest
+    pub fn get(&self, index: usize) -> Option<&HistoryEntry> {
+        self.entries.get(index)
+    }
+
+    /// All actions, newest first
+    pub fn entries(&self) -> impl Iterator<Item = &HistoryEntry> {
+        self.entries.iter()
+    }
+
+    pub fn len(&self) -> usize {
+        self.entries.len()
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.entries.is_empty()
+    }
+}
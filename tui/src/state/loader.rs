@@ -0,0 +1,391 @@
+use std::future::Future;
+
+use crate::api::{ApiError, BudgetApi};
+use crate::models::{ExpenseFilters, IncomeFilters, PageRequest};
+use crate::state::{currency, fallback, AppState, ServerFeature};
+
+impl AppState {
+    /// Load months, the current month and the lists used by forms and filters
+    ///
+    /// Selects the current month. Failed requests leave what was loaded before.
+    pub async fn load_reference_data(&mut self, api: &impl BudgetApi) {
+        if let Ok(months) = api.get_months().await {
+            self.data.months = months;
+        }
+
+        if let Ok(current) = api.get_current_month().await {
+            self.data.current_month = Some(current);
+            self.select_current_month();
+        }
+
+        self.load_settings_data(api).await;
+        self.load_key_scopes(api).await;
+    }
+
+    /// Load what the API key may change
+    ///
+    /// Servers without scoped keys don't have the endpoint, so their keys
+    /// keep every operation.
+    pub async fn load_key_scopes(&mut self, api: &impl BudgetApi) {
+        if let Some(scopes) = self
+            .fetch_feature(ServerFeature::KeyScopes, api.get_key_scopes())
+            .await
+        {
+            self.data.key_scopes = Some(scopes);
+        }
+    }
+
+    /// Load categories, periods and income types
+    pub async fn load_settings_data(&mut self, api: &impl BudgetApi) {
+        if let Ok(categories) = api.get_categories().await {
+            self.data.categories = categories;
+        }
+        if let Ok(periods) = api.get_periods().await {
+            self.set_periods(periods);
+        }
+        if let Ok(income_types) = api.get_income_types().await {
+            self.data.income_types = income_types;
+        }
+        self.load_accounts(api).await;
+        self.load_transfers(api).await;
+    }
+
+    /// Load the accounts, on servers that have them
+    pub async fn load_accounts(&mut self, api: &impl BudgetApi) {
+        if let Some(accounts) = self
+            .fetch_feature(ServerFeature::Accounts, api.get_accounts())
+            .await
+        {
+            self.data.accounts = accounts;
+        }
+    }
+
+    /// Load the debts, on servers that have them
+    pub async fn load_debts(&mut self, api: &impl BudgetApi) {
+        if let Some(debts) = self
+            .fetch_feature(ServerFeature::Debts, api.get_debts())
+            .await
+        {
+            self.data.debts = debts;
+        }
+    }
+
+    /// Load the selected month's transfers, on servers that have them
+    pub async fn load_transfers(&mut self, api: &impl BudgetApi) {
+        let month_id = self.selected_month_id();
+        if let Some(transfers) = self
+            .fetch_feature(ServerFeature::Transfers, api.get_transfers(month_id))
+            .await
+        {
+            self.data.transfers = transfers;
+        }
+    }
+
+    /// Load expenses, incomes and summaries of the selected month
+    pub async fn load_month_data(&mut self, api: &impl BudgetApi) {
+        let month_id = self.selected_month_id();
+
+        let filters = ExpenseFilters {
+            month_id,
+            ..Default::default()
+        };
+        self.fetch_expenses(api, filters).await;
+
+        let income_filters = IncomeFilters {
+            month_id,
+            ..Default::default()
+        };
+        self.fetch_incomes(api, income_filters).await;
+
+        self.load_summaries(api, month_id).await;
+    }
+
+    /// Load the summaries of a month
+    ///
+    /// Summaries the server doesn't have are computed from the month's
+    /// expenses and incomes; insights can't be, so they are hidden.
+    async fn load_summaries(&mut self, api: &impl BudgetApi, month_id: Option<i32>) {
+        let totals = api.get_summary_totals(month_id);
+        if let Some(totals) = self
+            .fetch_feature(ServerFeature::SummaryTotals, totals)
+            .await
+        {
+            self.data.summary_totals = Some(totals);
+        }
+        let summary = api.get_category_summary(month_id);
+        if let Some(summary) = self
+            .fetch_feature(ServerFeature::CategorySummary, summary)
+            .await
+        {
+            self.data.category_summary = summary;
+        }
+        let summary = api.get_income_type_summary(month_id);
+        if let Some(summary) = self
+            .fetch_feature(ServerFeature::IncomeTypeSummary, summary)
+            .await
+        {
+            self.data.income_type_summary = summary;
+        }
+        let summary = api.get_period_summary(month_id);
+        if let Some(summary) = self
+            .fetch_feature(ServerFeature::PeriodSummary, summary)
+            .await
+        {
+            self.data.period_summary = Some(summary);
+        }
+        let insights = api.get_insights(month_id);
+        if let Some(insights) = self.fetch_feature(ServerFeature::Insights, insights).await {
+            self.data.insights = Some(insights);
+        }
+
+        let convert = self.may_mix_currencies();
+        if !convert {
+            self.data.converted_totals = None;
+        }
+        if !convert && !self.data.unsupported.iter().any(ServerFeature::is_summary) {
+            return;
+        }
+
+        // Only the first page may be listed; the summaries cover the whole month
+        let mut expenses = self.data.expenses.clone();
+        if self.data.more_expenses.is_some() {
+            let filters = ExpenseFilters {
+                month_id,
+                ..Default::default()
+            };
+            if let Ok(all) = api.get_expenses(&filters).await {
+                expenses = all;
+            }
+        }
+        let mut incomes = self.data.incomes.clone();
+        if self.data.more_incomes.is_some() {
+            let filters = IncomeFilters {
+                month_id,
+                ..Default::default()
+            };
+            if let Ok(all) = api.get_incomes(&filters).await {
+                incomes = all;
+            }
+        }
+
+        if convert {
+            self.data.converted_totals =
+                currency::converted_totals(&expenses, &incomes, &self.rates);
+        }
+        let unsupported = &self.data.unsupported;
+        if unsupported.contains(&ServerFeature::SummaryTotals) {
+            self.data.summary_totals = Some(fallback::summary_totals(&expenses, &incomes));
+        }
+        if unsupported.contains(&ServerFeature::CategorySummary) {
+            self.data.category_summary = fallback::category_summary(&expenses);
+        }
+        if unsupported.contains(&ServerFeature::IncomeTypeSummary) {
+            self.data.income_type_summary =
+                fallback::income_type_summary(&incomes, &self.data.income_types);
+        }
+        if unsupported.contains(&ServerFeature::PeriodSummary) {
+            self.data.period_summary = Some(fallback::period_summary(
+                &expenses,
+                &incomes,
+                &self.data.periods,
+            ));
+        }
+        if unsupported.contains(&ServerFeature::Insights) {
+            self.data.insights = None;
+        }
+    }
+
+    /// Await a request for an endpoint the server may not have
+    ///
+    /// Skipped once the server answered 404/501 for it, which is remembered.
+    /// `None` when skipped or failed, leaving what was loaded before.
+    async fn fetch_feature<T>(
+        &mut self,
+        feature: ServerFeature,
+        request: impl Future<Output = Result<T, ApiError>>,
+    ) -> Option<T> {
+        if self.data.unsupported.contains(&feature) {
+            return None;
+        }
+        match request.await {
+            Ok(value) => Some(value),
+            Err(e) => {
+                if e.is_unsupported() {
+                    self.data.unsupported.insert(feature);
+                }
+                None
+            }
+        }
+    }
+
+    /// Load the expenses of the selected month, with the tab's filters
+    pub async fn load_filtered_expenses(&mut self, api: &impl BudgetApi) {
+        let filters = ExpenseFilters {
+            month_id: self.selected_month_id(),
+            period: self.ui.period_filter.clone(),
+            category: self.ui.category_filter.clone(),
+            ..Default::default()
+        };
+        self.fetch_expenses(api, filters).await;
+    }
+
+    /// Load the incomes of the selected month, with the tab's period filter
+    pub async fn load_filtered_incomes(&mut self, api: &impl BudgetApi) {
+        let filters = IncomeFilters {
+            month_id: self.selected_month_id(),
+            period: self.ui.period_filter.clone(),
+            ..Default::default()
+        };
+        self.fetch_incomes(api, filters).await;
+    }
+
+    /// Load the expenses of the split view's other pane, with its filters
+    ///
+    /// The whole list is fetched at once; a failure keeps what it shows.
+    pub async fn load_split_pane(&mut self, api: &impl BudgetApi) {
+        let Some(split) = &self.ui.split else {
+            return;
+        };
+        let filters = ExpenseFilters {
+            month_id: self.data.months.get(split.other.month_index).map(|m| m.id),
+            period: split.other.period_filter.clone(),
+            category: split.other.category_filter.clone(),
+            ..Default::default()
+        };
+        if let Ok(expenses) = api.get_expenses(&filters).await {
+            if let Some(split) = self.ui.split.as_mut() {
+                split.other.expenses = expenses;
+            }
+        }
+    }
+
+    /// Replace a listed expense with its current version from the server
+    ///
+    /// One deleted on the server is dropped from the list. Returns whether it
+    /// still exists; other failures keep the listed version.
+    pub async fn refresh_expense(&mut self, api: &impl BudgetApi, id: i32) -> bool {
+        match api.get_expense(id).await {
+            Ok(expense) => {
+                if let Some(listed) = self.data.expenses.iter_mut().find(|e| e.id == id) {
+                    *listed = expense;
+                }
+                true
+            }
+            Err(ApiError::NotFound) => {
+                self.data.expenses.retain(|e| e.id != id);
+                false
+            }
+            Err(_) => true,
+        }
+    }
+
+    /// Replace a listed income with its current version from the server
+    ///
+    /// Like `refresh_expense`.
+    pub async fn refresh_income(&mut self, api: &impl BudgetApi, id: i32) -> bool {
+        match api.get_income(id).await {
+            Ok(income) => {
+                if let Some(listed) = self.data.incomes.iter_mut().find(|i| i.id == id) {
+                    *listed = income;
+                }
+                true
+            }
+            Err(ApiError::NotFound) => {
+                self.data.incomes.retain(|i| i.id != id);
+                false
+            }
+            Err(_) => true,
+        }
+    }
+
+    /// Fetch the next page of expenses, if the server may have more
+    ///
+    /// Items already listed (e.g. shifted onto this page by a new expense) are
+    /// skipped. Returns whether anything was fetched.
+    pub async fn load_more_expenses(&mut self, api: &impl BudgetApi) -> bool {
+        let (filters, page) = match self.data.more_expenses.take() {
+            Some(more) => more,
+            None => return false,
+        };
+        match api.get_expenses_page(&filters, page).await {
+            Ok(result) => {
+                self.data.more_expenses = result.next().map(|next| (filters, next));
+                self.data.expenses_total = result.total;
+                for expense in result.items {
+                    if !self.data.expenses.iter().any(|e| e.id == expense.id) {
+                        self.data.expenses.push(expense);
+                    }
+                }
+                // Queued new expenses stay last
+                self.data.expenses.sort_by_key(|e| e.order);
+                true
+            }
+            Err(_) => {
+                self.data.more_expenses = Some((filters, page));
+                false
+            }
+        }
+    }
+
+    /// Fetch the next page of incomes, if the server may have more
+    ///
+    /// Items already listed are skipped. Returns whether anything was fetched.
+    pub async fn load_more_incomes(&mut self, api: &impl BudgetApi) -> bool {
+        let (filters, page) = match self.data.more_incomes.take() {
+            Some(more) => more,
+            None => return false,
+        };
+        match api.get_incomes_page(&filters, page).await {
+            Ok(result) => {
+                self.data.more_incomes = result.next().map(|next| (filters, next));
+                self.data.incomes_total = result.total;
+                for income in result.items {
+                    if !self.data.incomes.iter().any(|i| i.id == income.id) {
+                        self.data.incomes.push(income);
+                    }
+                }
+                true
+            }
+            Err(_) => {
+                self.data.more_incomes = Some((filters, page));
+                false
+            }
+        }
+    }
+
+    /// Replace the expenses with the first page (or all) matching the filters
+    async fn fetch_expenses(&mut self, api: &impl BudgetApi, filters: ExpenseFilters) {
+        if self.page_size == 0 {
+            if let Ok(expenses) = api.get_expenses(&filters).await {
+                self.data.expenses = expenses;
+                self.data.more_expenses = None;
+                self.data.expenses_total = None;
+            }
+            return;
+        }
+        let page = PageRequest::first(self.page_size);
+        if let Ok(result) = api.get_expenses_page(&filters, page).await {
+            self.data.more_expenses = result.next().map(|next| (filters, next));
+            self.data.expenses_total = result.total;
+            self.data.expenses = result.items;
+        }
+    }
+
+    /// Replace the incomes with the first page (or all) matching the filters
+    async fn fetch_incomes(&mut self, api: &impl BudgetApi, filters: IncomeFilters) {
+        if self.page_size == 0 {
+            if let Ok(incomes) = api.get_incomes(&filters).await {
+                self.data.incomes = incomes;
+                self.data.more_incomes = None;
+                self.data.incomes_total = None;
+            }
+            return;
+        }
+        let page = PageRequest::first(self.page_size);
+        if let Ok(result) = api.get_incomes_page(&filters, page).await {
+            self.data.more_incomes = result.next().map(|next| (filters, next));
+            self.data.incomes_total = result.total;
+            self.data.incomes = result.items;
+        }
+    }
+}
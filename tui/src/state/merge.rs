@@ -0,0 +1,104 @@
+use crate::models::{Expense, Income, IncomeType, Month};
+use crate::state::EntityType;
+
+/// An expense or income that moves to the merge target
+#[derive(Debug, Clone, PartialEq)]
+pub struct MergeRow {
+    pub kind: EntityType,
+    pub id: i32,
+    pub label: String,
+    pub month_name: String,
+    pub amount: f64,
+    pub month_closed: bool,
+}
+
+/// Rows affected by merging one category, period or income type into another
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct MergePreview {
+    pub rows: Vec<MergeRow>,
+}
+
+impl MergePreview {
+    /// Build a preview from the expenses and incomes that reference the merge source
+    pub fn build(
+        expenses: &[Expense],
+        incomes: &[Income],
+        months: &[Month],
+        income_types: &[IncomeType],
+    ) -> Self {
+        let month_info = |month_id: i32| {
+            months
+                .iter()
+                .find(|m| m.id == month_id)
+                .map(|m| (m.display_name(), m.is_closed))
+                .unwrap_or_else(|| (format!("Month #{}", month_id), false))
+        };
+
+        let expense_rows = expenses.iter().map(|e| {
+            let (month_name, month_closed) = month_info(e.month_id);
+            MergeRow {
+                kind: EntityType::Expense,
+                id: e.id,
+                label: e.expense_name.clone(),
+                month_name,
+                amount: e.cost,
+                month_closed,
+            }
+        });
+
+        let income_rows = incomes.iter().map(|i| {
+            let (month_name, month_closed) = month_info(i.month_id);
+            let type_name = income_types
+                .iter()
+                .find(|t| t.id == i.income_type_id)
+                .map(|t| t.name.as_str())
+                .unwrap_or("Unknown");
+            MergeRow {
+                kind: EntityType::Income,
+                id: i.id,
+                label: format!("{} ({})", type_name, i.period),
+                month_name,
+                amount: i.amount,
+                month_closed,
+            }
+        });
+
+        Self {
+            rows: expense_rows.chain(income_rows).collect(),
+        }
+    }
+
+    /// Number of expenses that move
+    pub fn expense_count(&self) -> usize {
+        self.rows
+            .iter()
+            .filter(|r| r.kind == EntityType::Expense)
+            .count()
+    }
+
+    /// Number of incomes that move
+    pub fn income_count(&self) -> usize {
+        self.rows
+            .iter()
+            .filter(|r| r.kind == EntityType::Income)
+            .count()
+    }
+
+    /// Number of distinct months touched
+    pub fn month_count(&self) -> usize {
+        let mut months: Vec<&str> = self.rows.iter().map(|r| r.month_name.as_str()).collect();
+        months.sort_unstable();
+        months.dedup();
+        months.len()
+    }
+
+    /// Rows in closed months, which the server won't let us change
+    pub fn closed_count(&self) -> usize {
+        self.rows.iter().filter(|r| r.month_closed).count()
+    }
+
+    /// Whether the merge can't run until closed months are reopened
+    pub fn is_blocked(&self) -> bool {
+        self.closed_count() > 0
+    }
+}
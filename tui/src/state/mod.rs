@@ -1,5 +1,31 @@
+pub mod advisor;
 mod app_state;
+pub mod audit;
+pub mod autofill;
+pub mod currency;
+pub mod debts;
+pub mod envelopes;
+pub mod fallback;
 pub mod forms;
+pub mod history;
+mod loader;
+pub mod merge;
+pub mod money_input;
+mod pending;
+mod periods;
+pub mod reallocate;
+pub mod reimbursements;
+pub mod rollover;
+mod scopes;
+pub mod split;
+mod view;
 
+pub use advisor::{Suggestion, SuggestionKind};
 pub use app_state::*;
+pub use fallback::ServerFeature;
 pub use forms::*;
+pub use history::{Action, ActionHistory, HistoryEntry};
+pub use merge::*;
+pub use reimbursements::*;
+pub use split::{Pane, SplitPane, SplitView};
+pub use view::{next_filter, EmptyList, GroupKey, SortKey, ViewChip};
@@ -0,0 +1,76 @@
+//! Shorthand accepted by money fields
+//!
+//! Besides plain amounts, `1.2k` means 1200 and `.5` means 0.50. A leading
+//! `+` or `-` adjusts the field's current value, so `+10` on an expense
+//! projected at 90 saves 100. Amounts are read as whole cents, rounding
+//! anything past them.
+
+use crate::models::Money;
+
+/// Whether typing `c` after `input` can still make an amount
+pub fn accepts_char(input: &str, c: char) -> bool {
+    let thousands = input.ends_with(['k', 'K']);
+    match c {
+        '0'..='9' => !thousands,
+        '.' => !thousands && !input.contains('.'),
+        'k' | 'K' => !thousands && input.chars().any(|c| c.is_ascii_digit()),
+        '+' | '-' => input.is_empty(),
+        _ => false,
+    }
+}
+
+/// The amount typed into a money field whose value was `current`
+///
+/// `None` for anything that isn't an amount, including adjustments that
+/// would go below zero.
+pub fn parse_money(input: &str, current: f64) -> Option<f64> {
+    let input = input.trim();
+    let (sign, rest) = match input.chars().next() {
+        Some('+') => (Some(1), &input[1..]),
+        Some('-') => (Some(-1), &input[1..]),
+        _ => (None, input),
+    };
+    // Signs, grouping and exponents aren't part of the number itself
+    if !rest
+        .chars()
+        .all(|c| c.is_ascii_digit() || matches!(c, '.' | 'k' | 'K'))
+    {
+        return None;
+    }
+    let value = match rest.strip_suffix(['k', 'K']) {
+        Some(number) => Money::parse(&thousands(number)?)?,
+        None => Money::parse(rest)?,
+    };
+    let value = match sign {
+        Some(sign) => Money::from_f64(current) + value * sign,
+        None => value,
+    };
+    if value.is_negative() {
+        return None;
+    }
+    Some(value.to_f64())
+}
+
+/// `number` with the point moved three places right, so thousands keep
+/// their cents: `1.2345` is `1234.5`
+fn thousands(number: &str) -> Option<String> {
+    if !number.chars().any(|c| c.is_ascii_digit()) {
+        return None;
+    }
+    let (whole, fraction) = number.split_once('.').unwrap_or((number, ""));
+    let fraction = format!("{:0<3}", fraction);
+    Some(format!("{}{}.{}", whole, &fraction[..3], &fraction[3..]))
+}
+
+/// `amount` as a money field shows it, e.g. `1200.00`
+pub fn input_text(amount: f64) -> String {
+    Money::from_f64(amount).to_string()
+}
+
+/// Replace shorthand in `input` with the amount it stands for, e.g. `1.2k`
+/// with `1200.00`; left as is when it isn't an amount
+pub fn tidy(input: &mut String, current: f64) {
+    if let Some(value) = parse_money(input, current) {
+        *input = input_text(value);
+    }
+}
@@ -0,0 +1,125 @@
+use std::collections::HashSet;
+
+use serde::{de::DeserializeOwned, Serialize};
+use serde_json::{json, Value};
+
+use crate::state::{AppState, EntityType};
+use crate::storage::JournalEntry;
+
+impl AppState {
+    /// Show writes waiting in the offline queue on top of the data loaded from the server
+    ///
+    /// Queued creates appear with negative IDs, queued updates are applied to their item,
+    /// and every affected expense or income is marked as pending sync. Safe to call again
+    /// after each reload.
+    pub fn apply_queued_writes(&mut self, entries: &[JournalEntry]) {
+        let month_id = self.selected_month_id();
+        let pending = &mut self.data.pending_sync;
+        pending.clear();
+
+        for entry in entries {
+            match entry.target() {
+                ("/expenses", target_id) => apply_entry(
+                    &mut self.data.expenses,
+                    pending,
+                    EntityType::Expense,
+                    entry,
+                    target_id,
+                    month_id,
+                    json!({
+                        "notes": null,
+                        "purchases": null,
+                        "order": i32::MAX,
+                        "expense_date": null,
+                    }),
+                    |expense| expense.id,
+                ),
+                ("/incomes", target_id) => apply_entry(
+                    &mut self.data.incomes,
+                    pending,
+                    EntityType::Income,
+                    entry,
+                    target_id,
+                    month_id,
+                    json!({
+                        "created_at": entry.queued_at,
+                        "updated_at": entry.queued_at,
+                        "created_by": null,
+                        "updated_by": null,
+                    }),
+                    |income| income.id,
+                ),
+                _ => {}
+            }
+        }
+    }
+
+    /// Check if an expense or income has changes that haven't reached the server yet
+    pub fn is_pending_sync(&self, entity_type: EntityType, id: i32) -> bool {
+        self.data.pending_sync.contains(&(entity_type, id))
+    }
+}
+
+/// Apply one queued write to a list of items
+///
+/// `template` fills the fields a create request doesn't send.
+#[allow(clippy::too_many_arguments)]
+fn apply_entry<T: Serialize + DeserializeOwned>(
+    items: &mut Vec<T>,
+    pending: &mut HashSet<(EntityType, i32)>,
+    entity_type: EntityType,
+    entry: &JournalEntry,
+    target_id: Option<i32>,
+    month_id: Option<i32>,
+    template: Value,
+    id_of: fn(&T) -> i32,
+) {
+    if entry.is_create() {
+        let body = match &entry.body {
+            Some(body) => body,
+            None => return,
+        };
+        if body.get("month_id").and_then(Value::as_i64) != month_id.map(i64::from) {
+            return;
+        }
+
+        let id = -(entry.id as i32);
+        if !items.iter().any(|item| id_of(item) == id) {
+            let mut value = template;
+            merge(&mut value, body);
+            value["id"] = json!(id);
+            match serde_json::from_value(value) {
+                Ok(item) => items.push(item),
+                Err(_) => return,
+            }
+        }
+        pending.insert((entity_type, id));
+        return;
+    }
+
+    let id = match target_id {
+        Some(id) => id,
+        None => return,
+    };
+    if let Some(item) = items.iter_mut().find(|item| id_of(item) == id) {
+        // Deleted items stay listed, marked, until the server confirms
+        if let Some(body) = entry.body.as_ref().filter(|_| !entry.is_delete()) {
+            if let Ok(mut value) = serde_json::to_value(&*item) {
+                merge(&mut value, body);
+                if let Ok(updated) = serde_json::from_value(value) {
+                    *item = updated;
+                }
+            }
+        }
+        pending.insert((entity_type, id));
+    }
+}
+
+/// Copy the fields of a JSON object onto another
+fn merge(target: &mut Value, patch: &Value) {
+    if let (Some(target), Some(patch)) = (target.as_object_mut(), patch.as_object()) {
+        for (key, value) in patch {
+            target.insert(key.clone(), value.clone());
+        }
+    }
+}
@@ -0,0 +1,32 @@
+//! How periods are shown
+//!
+//! The config can list periods in a display order and give them labels, e.g.
+//! "Quincena 1" for "Fixed/1st Period". Expenses and incomes keep the
+//! server's names; only what's on screen and the order of lists change.
+
+use std::cmp::Ordering;
+
+use crate::models::Period;
+use crate::state::AppState;
+
+impl AppState {
+    /// Keep the server's periods, in the configured order
+    ///
+    /// Selectors, filters and the Settings list all go through this list, so
+    /// they show the same order.
+    pub fn set_periods(&mut self, mut periods: Vec<Period>) {
+        periods.sort_by_key(|period| self.period_display.rank(&period.name));
+        self.data.periods = periods;
+    }
+
+    /// Compare periods by the configured order, then by name
+    pub fn compare_periods(&self, a: &str, b: &str) -> Ordering {
+        let display = &self.period_display;
+        display.rank(a).cmp(&display.rank(b)).then_with(|| a.cmp(b))
+    }
+
+    /// Name to show for a period, its label if it has one
+    pub fn period_label<'a>(&'a self, name: &'a str) -> &'a str {
+        self.period_display.label(name)
+    }
+}
@@ -0,0 +1,165 @@
+//! Moving budget between categories
+//!
+//! The board lists every category with budget in the month and what is left
+//! of it. `-` takes a step of what's left from the selected category and
+//! holds it; `+` gives held money to the selected one. The month's total
+//! budget never changes, and nothing is saved until the held amount is back
+//! to zero and the board is applied, which sets every changed category at
+//! once, scaling its expenses like autofill does.
+
+use std::collections::BTreeMap;
+
+use super::autofill::{round_to, scale_projected};
+use crate::models::{Expense, ExpenseUpdate, Money, Month};
+
+/// Amounts `s` cycles the step through
+pub const STEPS: [f64; 4] = [1.0, 10.0, 50.0, 100.0];
+
+/// A category's budget on the board
+#[derive(Debug, Clone, PartialEq)]
+pub struct ReallocateRow {
+    pub category: String,
+    /// Already spent; the budget can't go below it
+    pub spent: f64,
+    /// Projected total when the board opened
+    pub original: f64,
+    /// Projected total after the moves so far
+    pub budget: f64,
+}
+
+impl ReallocateRow {
+    /// Budget not spent yet
+    pub fn remaining(&self) -> f64 {
+        self.budget - self.spent
+    }
+
+    pub fn changes(&self) -> bool {
+        (self.budget - self.original).abs() >= 0.005
+    }
+}
+
+/// The month's budgets being moved around
+#[derive(Debug, Clone, PartialEq)]
+pub struct ReallocateBoard {
+    pub month_id: i32,
+    pub month_name: String,
+    pub rows: Vec<ReallocateRow>,
+    pub selected: usize,
+    /// Index into [`STEPS`]
+    pub step: usize,
+    /// Taken from one category and not given to another yet
+    pub held: f64,
+}
+
+impl ReallocateBoard {
+    /// The board for `month`, with a row per category of `expenses`
+    pub fn build(month: &Month, expenses: &[Expense]) -> Self {
+        let mut totals: BTreeMap<&str, (f64, f64)> = BTreeMap::new();
+        for expense in expenses {
+            let (projected, spent) = totals.entry(expense.category.as_str()).or_default();
+            *projected += expense.projected;
+            *spent += expense.cost;
+        }
+        let rows = totals
+            .into_iter()
+            .map(|(category, (projected, spent))| ReallocateRow {
+                category: category.to_string(),
+                spent,
+                original: projected,
+                budget: projected,
+            })
+            .collect();
+
+        Self {
+            month_id: month.id,
+            month_name: month.display_name(),
+            rows,
+            selected: 0,
+            step: 1,
+            held: 0.0,
+        }
+    }
+
+    pub fn step_amount(&self) -> f64 {
+        STEPS[self.step % STEPS.len()]
+    }
+
+    pub fn next_step(&mut self) {
+        self.step = (self.step + 1) % STEPS.len();
+    }
+
+    pub fn select_next(&mut self) {
+        if self.selected + 1 < self.rows.len() {
+            self.selected += 1;
+        }
+    }
+
+    pub fn select_previous(&mut self) {
+        self.selected = self.selected.saturating_sub(1);
+    }
+
+    /// Take a step from the selected category's remaining budget and hold
+    /// it; less when less is left
+    pub fn take(&mut self) {
+        let step = self.step_amount();
+        if let Some(row) = self.rows.get_mut(self.selected) {
+            let amount = round_to(step.min(row.remaining().max(0.0)), 0.01);
+            row.budget = round_to(row.budget - amount, 0.01);
+            self.held = round_to(self.held + amount, 0.01);
+        }
+    }
+
+    /// Give a step of the held money to the selected category; what's held
+    /// when it is less
+    pub fn give(&mut self) {
+        let amount = round_to(self.step_amount().min(self.held), 0.01);
+        if let Some(row) = self.rows.get_mut(self.selected) {
+            row.budget = round_to(row.budget + amount, 0.01);
+            self.held = round_to(self.held - amount, 0.01);
+        }
+    }
+
+    /// Check if all the money taken has been given somewhere
+    pub fn is_balanced(&self) -> bool {
+        self.held.abs() < 0.005
+    }
+
+    /// Check if applying would change anything
+    pub fn has_changes(&self) -> bool {
+        self.rows.iter().any(ReallocateRow::changes)
+    }
+
+    /// Categories whose budget in `expenses` no longer matches the board's
+    /// starting point, because it was changed somewhere else meanwhile
+    pub fn conflicts(&self, expenses: &[Expense]) -> Vec<String> {
+        self.rows
+            .iter()
+            .filter(|row| row.changes())
+            .filter(|row| {
+                let now: f64 = expenses
+                    .iter()
+                    .filter(|e| e.category == row.category)
+                    .map(|e| e.projected)
+                    .sum::<Money>()
+                    .to_f64();
+                (now - row.original).abs() >= 0.005
+            })
+            .map(|row| row.category.clone())
+            .collect()
+    }
+
+    /// Updates scaling `expenses` to the new budgets
+    pub fn changes(&self, expenses: &[Expense]) -> Vec<(i32, ExpenseUpdate)> {
+        let mut updates = Vec::new();
+        for row in self.rows.iter().filter(|row| row.changes()) {
+            let listed: Vec<&Expense> = expenses
+                .iter()
+                .filter(|e| e.category == row.category)
+                .collect();
+            if !listed.is_empty() {
+                updates.extend(scale_projected(&listed, row.original, row.budget));
+            }
+        }
+        updates
+    }
+}
@@ -0,0 +1,107 @@
+use crate::models::{Expense, Money, Month};
+use crate::storage::{ExpenseLedgers, Ledger};
+
+/// A business or reimbursable expense
+#[derive(Debug, Clone, PartialEq)]
+pub struct ReimbursementRow {
+    pub expense_id: i32,
+    pub month_name: String,
+    pub expense_name: String,
+    pub category: String,
+    pub ledger: Ledger,
+    pub amount: f64,
+    /// Date it was paid back, for reimbursable expenses
+    pub reimbursed_on: Option<String>,
+}
+
+impl ReimbursementRow {
+    /// Reimbursable and not paid back yet
+    pub fn is_outstanding(&self) -> bool {
+        self.ledger == Ledger::Reimbursable && self.reimbursed_on.is_none()
+    }
+}
+
+/// Expenses kept out of the personal ledger, with what is still owed
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct ReimbursementReport {
+    /// Outstanding first, then by month and name
+    pub rows: Vec<ReimbursementRow>,
+}
+
+impl ReimbursementReport {
+    /// Build the report from the expenses of every month that has tracked entries
+    ///
+    /// Tracked expenses that no longer exist (deleted on the server) are left out.
+    pub fn build(expenses: &[Expense], months: &[Month], ledgers: &ExpenseLedgers) -> Self {
+        let month_name = |month_id: i32| {
+            months
+                .iter()
+                .find(|m| m.id == month_id)
+                .map(|m| m.display_name())
+                .unwrap_or_else(|| format!("Month #{}", month_id))
+        };
+
+        let mut rows: Vec<(i32, ReimbursementRow)> = ledgers
+            .entries()
+            .filter_map(|(id, entry)| {
+                let expense = expenses.iter().find(|e| e.id == id)?;
+                Some((
+                    expense.month_id,
+                    ReimbursementRow {
+                        expense_id: id,
+                        month_name: month_name(expense.month_id),
+                        expense_name: expense.expense_name.clone(),
+                        category: expense.category.clone(),
+                        ledger: entry.ledger,
+                        amount: expense.cost,
+                        reimbursed_on: entry.reimbursed_on.clone(),
+                    },
+                ))
+            })
+            .collect();
+
+        rows.sort_by(|(a_month, a), (b_month, b)| {
+            b.is_outstanding()
+                .cmp(&a.is_outstanding())
+                .then(a_month.cmp(b_month))
+                .then(a.expense_name.cmp(&b.expense_name))
+        });
+
+        Self {
+            rows: rows.into_iter().map(|(_, row)| row).collect(),
+        }
+    }
+
+    /// Total still to be paid back
+    pub fn outstanding(&self) -> f64 {
+        self.rows
+            .iter()
+            .filter(|r| r.is_outstanding())
+            .map(|r| r.amount)
+            .sum::<Money>()
+            .to_f64()
+    }
+
+    /// Total already paid back
+    pub fn reimbursed(&self) -> f64 {
+        self.rows
+            .iter()
+            .filter(|r| r.reimbursed_on.is_some())
+            .map(|r| r.amount)
+            .sum::<Money>()
+            .to_f64()
+    }
+}
+
+/// Actual spending of some expenses per ledger, in `Ledger::ALL` order
+pub fn ledger_split(expenses: &[&Expense], ledgers: &ExpenseLedgers) -> [(Ledger, f64); 3] {
+    Ledger::ALL.map(|ledger| {
+        let total = expenses
+            .iter()
+            .filter(|e| ledgers.ledger(e.id) == ledger)
+            .map(|e| e.cost)
+            .sum::<Money>()
+            .to_f64();
+        (ledger, total)
+    })
+}
@@ -0,0 +1,176 @@
+//! Moving to a new calendar month
+//!
+//! With `pin_current` set, the dashboard opens on the month today falls in.
+//! When that month doesn't exist yet, at startup or once a new month begins,
+//! the user is asked whether to start it from the previous month, from the
+//! template month, or empty.
+
+use chrono::{Datelike, NaiveDate};
+
+use crate::api::{expense_pages, income_pages, ApiError, BudgetApi, MAX_BULK_EXPENSES};
+use crate::models::{
+    ExpenseCreate, ExpenseFilters, IncomeCreate, IncomeFilters, Month, MonthCreate,
+};
+use crate::state::{AppState, Modal};
+
+/// `(year, month)` that `date` falls in
+pub fn calendar_month(date: NaiveDate) -> (i32, i32) {
+    (date.year(), date.month() as i32)
+}
+
+/// `(year, month)` before `(year, month)`
+pub fn previous_month((year, month): (i32, i32)) -> (i32, i32) {
+    if month == 1 {
+        (year - 1, 12)
+    } else {
+        (year, month - 1)
+    }
+}
+
+/// Expenses and incomes copied into a new month
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct MonthStart {
+    pub expenses: usize,
+    pub incomes: usize,
+}
+
+/// Copy the expenses and incomes of month `source` into month `target`,
+/// with their projections but nothing spent or received yet
+pub async fn copy_entries(
+    api: &impl BudgetApi,
+    source: i32,
+    target: i32,
+) -> Result<MonthStart, ApiError> {
+    let mut start = MonthStart::default();
+    let filters = ExpenseFilters {
+        month_id: Some(source),
+        ..Default::default()
+    };
+    // A page at a time, so a long month isn't held all at once
+    let mut pages = expense_pages(api, filters, MAX_BULK_EXPENSES);
+    while let Some(page) = pages.next_page().await {
+        let expenses: Vec<ExpenseCreate> = page?
+            .into_iter()
+            .map(|e| ExpenseCreate {
+                expense_name: e.expense_name,
+                period: e.period,
+                category: e.category,
+                projected: e.projected,
+                cost: 0.0,
+                notes: e.notes,
+                month_id: target,
+                purchases: None,
+                expense_date: None,
+                account_id: e.account_id,
+                currency: e.currency.clone(),
+                custom_fields: e.custom_fields.clone(),
+            })
+            .collect();
+        start.expenses += api.create_expenses_bulk(&expenses).await?.len();
+    }
+
+    let filters = IncomeFilters {
+        month_id: Some(source),
+        ..Default::default()
+    };
+    let mut pages = income_pages(api, filters, MAX_BULK_EXPENSES);
+    while let Some(page) = pages.next_page().await {
+        for income in page? {
+            api.create_income(&IncomeCreate {
+                income_type_id: income.income_type_id,
+                period: income.period,
+                projected: income.projected,
+                amount: 0.0,
+                month_id: target,
+                account_id: income.account_id,
+                currency: income.currency.clone(),
+            })
+            .await?;
+            start.incomes += 1;
+        }
+    }
+    Ok(start)
+}
+
+impl AppState {
+    /// The month before the selected one, if it exists
+    pub fn previous_of_selected(&self) -> Option<&Month> {
+        let selected = self.selected_month()?;
+        let index = self.month_index(previous_month((selected.year, selected.month)))?;
+        self.data.months.get(index)
+    }
+
+    /// Index of the loaded month for `(year, month)`
+    pub fn month_index(&self, (year, month): (i32, i32)) -> Option<usize> {
+        self.data
+            .months
+            .iter()
+            .position(|m| m.year == year && m.month == month)
+    }
+
+    /// Select the month for `(year, month)`, even when it's closed; false
+    /// when it doesn't exist
+    pub fn select_calendar_month(&mut self, calendar_month: (i32, i32)) -> bool {
+        match self.month_index(calendar_month) {
+            Some(index) => {
+                self.ui.selected_month_index = index;
+                true
+            }
+            None => false,
+        }
+    }
+
+    /// Prompt to create the month `today` falls in, or `None` when it exists
+    ///
+    /// Offers the previous month and the month named `template`, if they
+    /// exist, as a start.
+    pub fn rollover_prompt(&self, today: NaiveDate, template: Option<&str>) -> Option<Modal> {
+        let (year, month) = calendar_month(today);
+        if self.month_index((year, month)).is_some() {
+            return None;
+        }
+        let named = |m: &Month| (m.id, m.display_name());
+        let previous = self
+            .month_index(previous_month((year, month)))
+            .map(|i| named(&self.data.months[i]));
+        let template = template.and_then(|name| {
+            self.data
+                .months
+                .iter()
+                .find(|m| m.name.eq_ignore_ascii_case(name.trim()))
+                .map(named)
+        });
+        Some(Modal::Rollover {
+            year,
+            month,
+            name: today.format("%B %Y").to_string(),
+            previous,
+            template,
+        })
+    }
+
+    /// Create `(year, month)` and select it
+    ///
+    /// The expenses and incomes of `source`, if given, are copied over as
+    /// `copy_entries` does.
+    pub async fn start_month(
+        &mut self,
+        api: &impl BudgetApi,
+        (year, month): (i32, i32),
+        source: Option<i32>,
+    ) -> Result<MonthStart, ApiError> {
+        let created = api.create_month(&MonthCreate { year, month }).await?;
+
+        let start = match source {
+            Some(source) => copy_entries(api, source, created.id).await?,
+            None => MonthStart::default(),
+        };
+
+        match api.get_months().await {
+            Ok(months) => self.data.months = months,
+            Err(_) => self.data.months.push(created),
+        }
+        self.select_calendar_month((year, month));
+        Ok(start)
+    }
+}
@@ -0,0 +1,53 @@
+//! What a scoped API key may change
+//!
+//! Servers with scoped keys say which areas the key may write. Operations
+//! outside them are refused up front with the scope they need, instead of
+//! failing with 403 once the form is filled in.
+
+use crate::models::Scope;
+use crate::state::{AppState, DashboardTab, SettingsTab};
+
+impl AppState {
+    /// Check if the API key may change `scope`'s area
+    pub fn key_allows(&self, scope: Scope) -> bool {
+        self.data
+            .key_scopes
+            .as_ref()
+            .map_or(true, |scopes| scopes.allows(scope))
+    }
+
+    /// Why the API key can't change `scope`'s area, or `None` when it can
+    pub fn scope_denied(&self, scope: Scope) -> Option<String> {
+        (!self.key_allows(scope)).then(|| {
+            format!(
+                "This API key can't change {} - it needs the {} scope",
+                scope.label(),
+                scope.as_str()
+            )
+        })
+    }
+
+    /// Area that adding, editing or deleting on the current tab changes
+    ///
+    /// `None` where the key doesn't matter, like the password form.
+    pub fn edit_scope(&self) -> Option<Scope> {
+        match self.ui.selected_tab {
+            DashboardTab::Expenses => Some(Scope::Expenses),
+            DashboardTab::Income => Some(Scope::Incomes),
+            DashboardTab::Debts => Some(Scope::Settings),
+            DashboardTab::Settings => match self.ui.settings_tab {
+                SettingsTab::Password => None,
+                _ => Some(Scope::Settings),
+            },
+            _ => None,
+        }
+    }
+
+    /// Check if the API key may change nothing at all, for the header
+    pub fn key_is_read_only(&self) -> bool {
+        self.data
+            .key_scopes
+            .as_ref()
+            .is_some_and(|scopes| scopes.is_read_only())
+    }
+}
@@ -0,0 +1,157 @@
+//! Two months' expenses side by side
+//!
+//! The focused pane is the dashboard's own month, list and filters, so every
+//! key works on it as usual; the other pane is parked here with filters of
+//! its own. Switching focus swaps the two, and the header follows the
+//! focused month.
+
+use ratatui::widgets::TableState;
+
+use crate::models::{Expense, ExpenseCreate, ExpenseUpdate, Month};
+use crate::state::AppState;
+
+/// Side of the Expenses tab a pane is shown on
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Pane {
+    Left,
+    Right,
+}
+
+impl Pane {
+    pub fn other(self) -> Self {
+        match self {
+            Pane::Left => Pane::Right,
+            Pane::Right => Pane::Left,
+        }
+    }
+}
+
+/// The pane out of focus
+#[derive(Debug, Default)]
+pub struct SplitPane {
+    pub month_index: usize,
+    pub expenses: Vec<Expense>,
+    pub table: TableState,
+    pub period_filter: Option<String>,
+    pub category_filter: Option<String>,
+}
+
+/// Split mode of the Expenses tab
+#[derive(Debug)]
+pub struct SplitView {
+    /// What the pane out of focus shows
+    pub other: SplitPane,
+    /// Side of the focused pane
+    pub focus: Pane,
+}
+
+impl AppState {
+    /// Show the next month (or the previous one for the last) beside the
+    /// selected one, with focus staying on the selected month
+    ///
+    /// `false` without a second month to show.
+    pub fn open_split(&mut self) -> bool {
+        let selected = self.ui.selected_month_index;
+        let other = if selected + 1 < self.data.months.len() {
+            selected + 1
+        } else if selected > 0 {
+            selected - 1
+        } else {
+            return false;
+        };
+        // The older month goes on the left
+        let focus = if other > selected {
+            Pane::Left
+        } else {
+            Pane::Right
+        };
+        self.ui.split = Some(SplitView {
+            other: SplitPane {
+                month_index: other,
+                ..Default::default()
+            },
+            focus,
+        });
+        true
+    }
+
+    pub fn close_split(&mut self) {
+        self.ui.split = None;
+    }
+
+    /// Move focus to the other pane, swapping its month, list and filters
+    /// with the dashboard's
+    pub fn switch_split_focus(&mut self) {
+        let Some(split) = self.ui.split.as_mut() else {
+            return;
+        };
+        let other = &mut split.other;
+        std::mem::swap(&mut self.ui.selected_month_index, &mut other.month_index);
+        std::mem::swap(&mut self.data.expenses, &mut other.expenses);
+        std::mem::swap(&mut self.ui.expense_table, &mut other.table);
+        std::mem::swap(&mut self.ui.period_filter, &mut other.period_filter);
+        std::mem::swap(&mut self.ui.category_filter, &mut other.category_filter);
+        split.focus = split.focus.other();
+        // The parked list may have been one page of many
+        self.data.more_expenses = None;
+        self.data.expenses_total = None;
+    }
+
+    /// Month of the pane out of focus
+    pub fn split_month(&self) -> Option<&Month> {
+        let split = self.ui.split.as_ref()?;
+        self.data.months.get(split.other.month_index)
+    }
+
+    /// Update moving an expense to the other pane's month
+    ///
+    /// Errors say why nothing can move: no split, the same month in both
+    /// panes, or a closed month.
+    pub fn move_to_split(&self) -> Result<ExpenseUpdate, String> {
+        let target = self.split_target()?;
+        Ok(ExpenseUpdate {
+            month_id: Some(target.id),
+            ..Default::default()
+        })
+    }
+
+    /// A copy of an expense for the other pane's month, with nothing paid
+    pub fn copy_to_split(&self, expense: &Expense) -> Result<ExpenseCreate, String> {
+        let target = self.split_target()?;
+        Ok(ExpenseCreate {
+            expense_name: expense.expense_name.clone(),
+            period: expense.period.clone(),
+            category: expense.category.clone(),
+            projected: expense.projected,
+            cost: 0.0,
+            notes: expense.notes.clone(),
+            month_id: target.id,
+            purchases: None,
+            expense_date: None,
+            account_id: expense.account_id,
+            currency: expense.currency.clone(),
+            custom_fields: expense.custom_fields.clone(),
+        })
+    }
+
+    /// The other pane's month, when expenses can be moved or copied into it
+    fn split_target(&self) -> Result<&Month, String> {
+        let target = self
+            .split_month()
+            .ok_or_else(|| "Split the view first (|)".to_string())?;
+        if self.selected_month().map(|m| m.id) == Some(target.id) {
+            return Err(format!("Both panes show {}", target.display_name()));
+        }
+        if let Some(closed) = [self.selected_month(), Some(target)]
+            .into_iter()
+            .flatten()
+            .find(|m| m.is_closed && self.ui.closed_month_override != Some(m.id))
+        {
+            return Err(format!(
+                "{} is closed. Reopen it or press U.",
+                closed.display_name()
+            ));
+        }
+        Ok(target)
+    }
+}
@@ -0,0 +1,288 @@
+use std::cmp::Ordering;
+
+use crate::models::{Expense, Income};
+use crate::state::{AppState, DashboardTab};
+
+/// Column the Expenses and Income tables are sorted by
+///
+/// Names sort A to Z, amounts largest first. Unsorted keeps the server's
+/// order.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum SortKey {
+    Name,
+    Projected,
+    Actual,
+}
+
+impl SortKey {
+    /// Next sort for `s`, ending with none
+    pub fn cycle(current: Option<SortKey>) -> Option<SortKey> {
+        match current {
+            None => Some(SortKey::Name),
+            Some(SortKey::Name) => Some(SortKey::Projected),
+            Some(SortKey::Projected) => Some(SortKey::Actual),
+            Some(SortKey::Actual) => None,
+        }
+    }
+
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            SortKey::Name => "Name",
+            SortKey::Projected => "Projected",
+            SortKey::Actual => "Actual",
+        }
+    }
+}
+
+/// Rows kept together in the Expenses and Income tables
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum GroupKey {
+    /// Expenses only
+    Category,
+    Period,
+}
+
+impl GroupKey {
+    /// Next grouping for `g` on `tab`, ending with none
+    pub fn cycle(current: Option<GroupKey>, tab: DashboardTab) -> Option<GroupKey> {
+        match current {
+            None if tab == DashboardTab::Expenses => Some(GroupKey::Category),
+            None | Some(GroupKey::Category) => Some(GroupKey::Period),
+            Some(GroupKey::Period) => None,
+        }
+    }
+
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            GroupKey::Category => "Category",
+            GroupKey::Period => "Period",
+        }
+    }
+}
+
+/// A setting that changes which rows a table shows, or their order
+///
+/// Shown above the table, numbered in this order, so Alt+number removes one.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum ViewChip {
+    Period(String),
+    Category(String),
+    Account(String),
+    Search(String),
+    Group(GroupKey),
+    Sort(SortKey),
+}
+
+impl ViewChip {
+    pub fn label(&self) -> String {
+        match self {
+            ViewChip::Period(period) => format!("Period: {}", period),
+            ViewChip::Category(category) => format!("Category: {}", category),
+            ViewChip::Account(account) => format!("Account: {}", account),
+            ViewChip::Search(text) => format!("Search: \"{}\"", text),
+            ViewChip::Group(group) => format!("Group: {}", group.as_str()),
+            ViewChip::Sort(sort) => format!("Sort: {}", sort.as_str()),
+        }
+    }
+
+    /// Whether the server applies it, so removing it needs a reload
+    pub fn is_server_filter(&self) -> bool {
+        matches!(self, ViewChip::Period(_) | ViewChip::Category(_))
+    }
+}
+
+/// Why the Expenses or Income table has no rows, which decides what it
+/// offers to do instead
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum EmptyList {
+    /// There are no months yet
+    NoMonth,
+    /// The selected month has no expenses or incomes at all, so it can be
+    /// filled from the previous month or a file
+    EmptyMonth,
+    /// The month has entries, just none of this kind
+    NoneYet,
+    /// Filters or the search hide every row
+    Filtered,
+}
+
+impl AppState {
+    /// Why the selected tab's table is empty, or `None` when it shows rows
+    pub fn empty_list(&self) -> Option<EmptyList> {
+        let is_empty = match self.ui.selected_tab {
+            DashboardTab::Expenses => self.filtered_expenses().is_empty(),
+            DashboardTab::Income => self.filtered_incomes().is_empty(),
+            _ => return None,
+        };
+        if !is_empty {
+            return None;
+        }
+        Some(if self.selected_month().is_none() {
+            EmptyList::NoMonth
+        } else if self.view_chips().iter().any(|chip| {
+            matches!(
+                chip,
+                ViewChip::Period(_)
+                    | ViewChip::Category(_)
+                    | ViewChip::Account(_)
+                    | ViewChip::Search(_)
+            )
+        }) {
+            EmptyList::Filtered
+        } else if self.month_is_empty() {
+            EmptyList::EmptyMonth
+        } else {
+            EmptyList::NoneYet
+        })
+    }
+
+    /// Check if the selected month has no expenses or incomes yet
+    ///
+    /// Only known while no period or category filter narrows what's loaded.
+    pub fn month_is_empty(&self) -> bool {
+        self.selected_month().is_some()
+            && self.ui.period_filter.is_none()
+            && self.ui.category_filter.is_none()
+            && self.data.expenses.is_empty()
+            && self.data.incomes.is_empty()
+            && self.data.more_expenses.is_none()
+            && self.data.more_incomes.is_none()
+    }
+
+    /// Active view settings of the selected tab, in the order they're shown
+    pub fn view_chips(&self) -> Vec<ViewChip> {
+        let tab = self.ui.selected_tab;
+        if !matches!(tab, DashboardTab::Expenses | DashboardTab::Income) {
+            return Vec::new();
+        }
+        let is_expenses = tab == DashboardTab::Expenses;
+
+        let mut chips = Vec::new();
+        if let Some(period) = &self.ui.period_filter {
+            chips.push(ViewChip::Period(self.period_label(period).to_string()));
+        }
+        if let Some(category) = self.ui.category_filter.as_ref().filter(|_| is_expenses) {
+            chips.push(ViewChip::Category(category.clone()));
+        }
+        if let Some(id) = self.ui.account_filter {
+            let name = self.account_name(Some(id)).map(str::to_string);
+            chips.push(ViewChip::Account(
+                name.unwrap_or_else(|| format!("#{}", id)),
+            ));
+        }
+        if self.ui.searching || !self.ui.search.is_empty() {
+            chips.push(ViewChip::Search(self.ui.search.clone()));
+        }
+        if let Some(group) = self
+            .ui
+            .group
+            .filter(|g| is_expenses || *g != GroupKey::Category)
+        {
+            chips.push(ViewChip::Group(group));
+        }
+        if let Some(sort) = self.ui.sort {
+            chips.push(ViewChip::Sort(sort));
+        }
+        chips
+    }
+
+    /// Turn off the view setting shown as chip `index` (from 0)
+    pub fn remove_view_chip(&mut self, index: usize) -> Option<ViewChip> {
+        let chip = self.view_chips().into_iter().nth(index)?;
+        match chip {
+            ViewChip::Period(_) => self.ui.period_filter = None,
+            ViewChip::Category(_) => self.ui.category_filter = None,
+            ViewChip::Account(_) => self.ui.account_filter = None,
+            ViewChip::Search(_) => {
+                self.ui.search.clear();
+                self.ui.searching = false;
+            }
+            ViewChip::Group(_) => self.ui.group = None,
+            ViewChip::Sort(_) => self.ui.sort = None,
+        }
+        Some(chip)
+    }
+
+    /// Expenses matching the search, grouped and sorted
+    pub(crate) fn arrange_expenses<'a>(&self, expenses: Vec<&'a Expense>) -> Vec<&'a Expense> {
+        let search = self.ui.search.to_lowercase();
+        let mut expenses: Vec<&Expense> = expenses
+            .into_iter()
+            .filter(|e| {
+                search.is_empty()
+                    || e.expense_name.to_lowercase().contains(&search)
+                    || e.category.to_lowercase().contains(&search)
+            })
+            .collect();
+
+        expenses.sort_by(|a, b| {
+            let grouped = match self.ui.group {
+                Some(GroupKey::Category) => a.category.cmp(&b.category),
+                Some(GroupKey::Period) => self.compare_periods(&a.period, &b.period),
+                None => Ordering::Equal,
+            };
+            grouped.then_with(|| match self.ui.sort {
+                Some(SortKey::Name) => compare_names(&a.expense_name, &b.expense_name),
+                Some(SortKey::Projected) => b.projected.total_cmp(&a.projected),
+                Some(SortKey::Actual) => b.cost.total_cmp(&a.cost),
+                None => Ordering::Equal,
+            })
+        });
+        expenses
+    }
+
+    /// Incomes matching the search, grouped and sorted; incomes go by their
+    /// income type's name
+    pub(crate) fn arrange_incomes<'a>(&self, incomes: Vec<&'a Income>) -> Vec<&'a Income> {
+        let name = |income: &Income| {
+            self.data
+                .income_types
+                .iter()
+                .find(|t| t.id == income.income_type_id)
+                .map_or("", |t| t.name.as_str())
+        };
+        let search = self.ui.search.to_lowercase();
+        let mut incomes: Vec<&Income> = incomes
+            .into_iter()
+            .filter(|i| {
+                search.is_empty()
+                    || name(i).to_lowercase().contains(&search)
+                    || i.period.to_lowercase().contains(&search)
+            })
+            .collect();
+
+        incomes.sort_by(|a, b| {
+            let grouped = match self.ui.group {
+                Some(GroupKey::Period) => self.compare_periods(&a.period, &b.period),
+                Some(GroupKey::Category) | None => Ordering::Equal,
+            };
+            grouped.then_with(|| match self.ui.sort {
+                Some(SortKey::Name) => compare_names(name(a), name(b)),
+                Some(SortKey::Projected) => b.projected.total_cmp(&a.projected),
+                Some(SortKey::Actual) => b.amount.total_cmp(&a.amount),
+                None => Ordering::Equal,
+            })
+        });
+        incomes
+    }
+}
+
+/// The option after `current` for a filter key, cycling through `options`;
+/// after the last one, or if `current` is gone, no filter
+pub fn next_filter(options: &[String], current: Option<&str>) -> Option<String> {
+    match current {
+        None => options.first().cloned(),
+        Some(current) => options
+            .iter()
+            .position(|option| option == current)
+            .and_then(|i| options.get(i + 1))
+            .cloned(),
+    }
+}
+
+/// Compare ignoring case, without allocating: sorting calls this n log n times
+fn compare_names(a: &str, b: &str) -> Ordering {
+    a.chars()
+        .flat_map(char::to_lowercase)
+        .cmp(b.chars().flat_map(char::to_lowercase))
+}
@@ -0,0 +1,60 @@
+use std::collections::{BTreeMap, BTreeSet};
+use std::path::Path;
+
+use anyhow::Result;
+use serde::{Deserialize, Serialize};
+
+use super::{read_json, write_json};
+
+const CHECKLIST_FILE: &str = "checklist.json";
+
+/// Checked-off routine items keyed by month ID
+///
+/// Items are stored by label so reordering the configured list keeps progress.
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct MonthChecklist {
+    #[serde(default)]
+    done: BTreeMap<i32, BTreeSet<String>>,
+}
+
+impl MonthChecklist {
+    /// Load checklist progress from the given data directory
+    pub fn load(data_dir: &Path) -> Result<Self> {
+        read_json(&data_dir.join(CHECKLIST_FILE))
+    }
+
+    /// Save checklist progress to the given data directory
+    pub fn save(&self, data_dir: &Path) -> Result<()> {
+        write_json(&data_dir.join(CHECKLIST_FILE), self)
+    }
+
+    /// Check if an item is done for a month
+    pub fn is_done(&self, month_id: i32, item: &str) -> bool {
+        self.done
+            .get(&month_id)
+            .is_some_and(|items| items.contains(item))
+    }
+
+    /// Toggle an item for a month, returning the new state
+    pub fn toggle(&mut self, month_id: i32, item: &str) -> bool {
+        let items = self.done.entry(month_id).or_default();
+        let done = if items.remove(item) {
+            false
+        } else {
+            items.insert(item.to_string());
+            true
+        };
+        if items.is_empty() {
+            self.done.remove(&month_id);
+        }
+        done
+    }
+
+    /// Count how many of the given items are done for a month
+    pub fn completed(&self, month_id: i32, items: &[String]) -> usize {
+        items
+            .iter()
+            .filter(|item| self.is_done(month_id, item))
+            .count()
+    }
+}
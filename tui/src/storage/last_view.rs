@@ -0,0 +1,37 @@
+use std::path::Path;
+
+use anyhow::Result;
+use serde::{Deserialize, Serialize};
+
+use super::{read_json, write_json};
+
+const LAST_VIEW_FILE: &str = "last_view.json";
+
+/// Where the dashboard was when the app last quit, to open there again
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct LastView {
+    /// Tab name, e.g. "Expenses"
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tab: Option<String>,
+    /// Selected month as `YYYY-MM`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub month: Option<String>,
+    /// Period filter, by name
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub period: Option<String>,
+    /// Category filter, by name
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub category: Option<String>,
+}
+
+impl LastView {
+    /// Load the last view from the given data directory
+    pub fn load(data_dir: &Path) -> Result<Self> {
+        read_json(&data_dir.join(LAST_VIEW_FILE))
+    }
+
+    /// Save the view to the given data directory
+    pub fn save(&self, data_dir: &Path) -> Result<()> {
+        write_json(&data_dir.join(LAST_VIEW_FILE), self)
+    }
+}
@@ -0,0 +1,132 @@
+use std::collections::{BTreeMap, BTreeSet};
+use std::path::Path;
+
+use anyhow::Result;
+use chrono::NaiveDate;
+use serde::{Deserialize, Serialize};
+
+use super::{read_json, write_json};
+
+const LEDGERS_FILE: &str = "ledgers.json";
+
+/// Who an expense is really for
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, PartialOrd, Ord, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum Ledger {
+    #[default]
+    Personal,
+    /// Paid for the business, kept apart from personal spending
+    Business,
+    /// Paid out of pocket, to be paid back (e.g. by an employer)
+    Reimbursable,
+}
+
+impl Ledger {
+    pub const ALL: [Ledger; 3] = [Ledger::Personal, Ledger::Business, Ledger::Reimbursable];
+
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            Ledger::Personal => "Personal",
+            Ledger::Business => "Business",
+            Ledger::Reimbursable => "Reimbursable",
+        }
+    }
+
+    /// The ledger `b` switches to next
+    pub fn next(&self) -> Self {
+        match self {
+            Ledger::Personal => Ledger::Business,
+            Ledger::Business => Ledger::Reimbursable,
+            Ledger::Reimbursable => Ledger::Personal,
+        }
+    }
+}
+
+/// An expense moved out of the personal ledger
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct LedgerEntry {
+    pub ledger: Ledger,
+    /// Month of the expense, to find it again for the reimbursement report
+    pub month_id: i32,
+    /// Date the money came back, if it has
+    #[serde(default)]
+    pub reimbursed_on: Option<String>,
+}
+
+/// Ledger of each expense, keyed by expense ID; expenses not listed are personal
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct ExpenseLedgers {
+    #[serde(default)]
+    entries: BTreeMap<i32, LedgerEntry>,
+}
+
+impl ExpenseLedgers {
+    /// Load ledgers from the given data directory
+    pub fn load(data_dir: &Path) -> Result<Self> {
+        read_json(&data_dir.join(LEDGERS_FILE))
+    }
+
+    /// Save ledgers to the given data directory
+    pub fn save(&self, data_dir: &Path) -> Result<()> {
+        write_json(&data_dir.join(LEDGERS_FILE), self)
+    }
+
+    /// Ledger of an expense
+    pub fn ledger(&self, expense_id: i32) -> Ledger {
+        self.entries
+            .get(&expense_id)
+            .map_or(Ledger::Personal, |entry| entry.ledger)
+    }
+
+    /// Ledger entry of an expense, if it isn't personal
+    pub fn get(&self, expense_id: i32) -> Option<&LedgerEntry> {
+        self.entries.get(&expense_id)
+    }
+
+    /// Move an expense to a ledger (personal removes the entry)
+    pub fn set_ledger(&mut self, expense_id: i32, month_id: i32, ledger: Ledger) {
+        if ledger == Ledger::Personal {
+            self.entries.remove(&expense_id);
+            return;
+        }
+        self.entries
+            .entry(expense_id)
+            .and_modify(|entry| entry.ledger = ledger)
+            .or_insert(LedgerEntry {
+                ledger,
+                month_id,
+                reimbursed_on: None,
+            });
+    }
+
+    /// Mark an expense as reimbursed on `today`, or as outstanding again
+    ///
+    /// Returns whether the expense is now reimbursed.
+    pub fn toggle_reimbursed(&mut self, expense_id: i32, today: NaiveDate) -> bool {
+        match self.entries.get_mut(&expense_id) {
+            Some(entry) => {
+                entry.reimbursed_on = match entry.reimbursed_on {
+                    Some(_) => None,
+                    None => Some(today.format("%Y-%m-%d").to_string()),
+                };
+                entry.reimbursed_on.is_some()
+            }
+            None => false,
+        }
+    }
+
+    /// All non-personal expenses by ID
+    pub fn entries(&self) -> impl Iterator<Item = (i32, &LedgerEntry)> {
+        self.entries.iter().map(|(id, entry)| (*id, entry))
+    }
+
+    /// Months that have non-personal expenses
+    pub fn month_ids(&self) -> BTreeSet<i32> {
+        self.entries.values().map(|entry| entry.month_id).collect()
+    }
+
+    /// Check if every expense is personal
+    pub fn is_empty(&self) -> bool {
+        self.entries.is_empty()
+    }
+}
@@ -0,0 +1,29 @@
+use std::path::Path;
+
+use anyhow::Result;
+use serde::{Deserialize, Serialize};
+
+use super::{read_json, write_json};
+
+const STATE_FILE: &str = "state.json";
+
+/// What the app remembers about this machine between runs, whatever the
+/// server
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct LocalState {
+    /// Version that last ran, to show what's new after an upgrade
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub last_version: Option<String>,
+}
+
+impl LocalState {
+    /// Load the state from the given directory
+    pub fn load(dir: &Path) -> Result<Self> {
+        read_json(&dir.join(STATE_FILE))
+    }
+
+    /// Save the state to the given directory
+    pub fn save(&self, dir: &Path) -> Result<()> {
+        write_json(&dir.join(STATE_FILE), self)
+    }
+}
@@ -0,0 +1,41 @@
+//! Local, per-profile data that never leaves this machine.
+
+mod checklist;
+mod last_view;
+mod ledger;
+mod local_state;
+mod notes;
+mod tax;
+
+pub use budget_sdk::journal::{JournalEntry, WriteJournal};
+pub use checklist::MonthChecklist;
+pub use last_view::LastView;
+pub use ledger::{ExpenseLedgers, Ledger, LedgerEntry};
+pub use local_state::LocalState;
+pub use notes::MonthNotes;
+pub use tax::{next_flag, TaxFlags};
+
+use std::fs;
+use std::path::Path;
+
+use anyhow::{Context, Result};
+use serde::{de::DeserializeOwned, Serialize};
+
+/// Read a JSON file, returning the default value if it doesn't exist yet
+pub fn read_json<T: DeserializeOwned + Default>(path: &Path) -> Result<T> {
+    if !path.exists() {
+        return Ok(T::default());
+    }
+    let content =
+        fs::read_to_string(path).with_context(|| format!("Failed to read {}", path.display()))?;
+    serde_json::from_str(&content).with_context(|| format!("Failed to parse {}", path.display()))
+}
+
+/// Write a value as pretty JSON, creating parent directories as needed
+pub fn write_json<T: Serialize>(path: &Path, value: &T) -> Result<()> {
+    if let Some(parent) = path.parent() {
+        fs::create_dir_all(parent).context("Failed to create data directory")?;
+    }
+    let content = serde_json::to_string_pretty(value).context("Failed to serialize data")?;
+    fs::write(path, content).with_context(|| format!("Failed to write {}", path.display()))
+}
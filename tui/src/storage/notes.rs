@@ -0,0 +1,47 @@
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use anyhow::Result;
+use serde::{Deserialize, Serialize};
+
+use super::{read_json, write_json};
+
+const NOTES_FILE: &str = "notes.json";
+
+/// Scratchpad notes keyed by month ID
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct MonthNotes {
+    #[serde(default)]
+    notes: BTreeMap<i32, String>,
+}
+
+impl MonthNotes {
+    /// Load notes from the given data directory
+    pub fn load(data_dir: &Path) -> Result<Self> {
+        read_json(&data_dir.join(NOTES_FILE))
+    }
+
+    /// Save notes to the given data directory
+    pub fn save(&self, data_dir: &Path) -> Result<()> {
+        write_json(&data_dir.join(NOTES_FILE), self)
+    }
+
+    /// Get the note for a month, if any
+    pub fn get(&self, month_id: i32) -> Option<&str> {
+        self.notes.get(&month_id).map(|s| s.as_str())
+    }
+
+    /// Check if a month has a non-empty note
+    pub fn has_note(&self, month_id: i32) -> bool {
+        self.notes.contains_key(&month_id)
+    }
+
+    /// Set the note for a month (blank text removes it)
+    pub fn set(&mut self, month_id: i32, text: String) {
+        if text.trim().is_empty() {
+            self.notes.remove(&month_id);
+        } else {
+            self.notes.insert(month_id, text);
+        }
+    }
+}
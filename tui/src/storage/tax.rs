@@ -0,0 +1,79 @@
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use anyhow::Result;
+use serde::{Deserialize, Serialize};
+
+use super::{read_json, write_json};
+
+const TAX_FLAGS_FILE: &str = "tax_flags.json";
+
+/// Tax flags (e.g. "Deductible") on expenses and incomes
+///
+/// Expenses are flagged by name and incomes by income type, so a recurring
+/// item only needs flagging once and keeps its flag in every month.
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct TaxFlags {
+    #[serde(default)]
+    expenses: BTreeMap<String, String>,
+    #[serde(default)]
+    income_types: BTreeMap<String, String>,
+}
+
+impl TaxFlags {
+    /// Load flags from the given data directory
+    pub fn load(data_dir: &Path) -> Result<Self> {
+        read_json(&data_dir.join(TAX_FLAGS_FILE))
+    }
+
+    /// Save flags to the given data directory
+    pub fn save(&self, data_dir: &Path) -> Result<()> {
+        write_json(&data_dir.join(TAX_FLAGS_FILE), self)
+    }
+
+    /// Flag of an expense, by expense name
+    pub fn expense_flag(&self, expense_name: &str) -> Option<&str> {
+        self.expenses.get(&key(expense_name)).map(|s| s.as_str())
+    }
+
+    /// Flag of an income, by income type name
+    pub fn income_flag(&self, income_type: &str) -> Option<&str> {
+        self.income_types.get(&key(income_type)).map(|s| s.as_str())
+    }
+
+    /// Set or clear (`None`) the flag of an expense name
+    pub fn set_expense_flag(&mut self, expense_name: &str, flag: Option<String>) {
+        set(&mut self.expenses, expense_name, flag);
+    }
+
+    /// Set or clear (`None`) the flag of an income type
+    pub fn set_income_flag(&mut self, income_type: &str, flag: Option<String>) {
+        set(&mut self.income_types, income_type, flag);
+    }
+
+    /// Check if nothing is flagged
+    pub fn is_empty(&self) -> bool {
+        self.expenses.is_empty() && self.income_types.is_empty()
+    }
+}
+
+/// The flag after `current` in the configured list, wrapping back to no flag
+pub fn next_flag(flags: &[String], current: Option<&str>) -> Option<String> {
+    match current.and_then(|current| flags.iter().position(|flag| flag == current)) {
+        Some(index) => flags.get(index + 1).cloned(),
+        // Unflagged, or flagged with something no longer configured
+        None => flags.first().cloned(),
+    }
+}
+
+fn set(map: &mut BTreeMap<String, String>, name: &str, flag: Option<String>) {
+    match flag {
+        Some(flag) => map.insert(key(name), flag),
+        None => map.remove(&key(name)),
+    };
+}
+
+/// Names are matched ignoring case and surrounding whitespace
+fn key(name: &str) -> String {
+    name.trim().to_lowercase()
+}
@@ -11,6 +11,8 @@ use super::centered_rect_fixed;
 /// API config form fields
 #[derive(Debug, Clone, Copy, PartialEq, Eq)]
 pub enum ApiConfigField {
+    /// Name the server is saved under
+    Profile,
     ApiUrl,
     ApiKey,
 }
@@ -18,23 +20,34 @@ pub enum ApiConfigField {
 impl ApiConfigField {
     pub fn index(self) -> usize {
         match self {
-            ApiConfigField::ApiUrl => 0,
-            ApiConfigField::ApiKey => 1,
+            ApiConfigField::Profile => 0,
+            ApiConfigField::ApiUrl => 1,
+            ApiConfigField::ApiKey => 2,
         }
     }
 
     pub fn from_index(index: usize) -> Self {
         match index {
-            0 => ApiConfigField::ApiUrl,
+            0 => ApiConfigField::Profile,
+            1 => ApiConfigField::ApiUrl,
             _ => ApiConfigField::ApiKey,
         }
     }
 
     pub fn count() -> usize {
-        2
+        3
     }
 }
 
+/// Passphrase prompt shown instead of the form when the config is locked
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum LockPrompt {
+    /// Enter the passphrase to open the settings
+    Unlock,
+    /// Choose a new passphrase (empty removes the lock)
+    SetPassphrase,
+}
+
 // Colors
 const CYAN: Color = Color::Cyan;
 const GREEN: Color = Color::Green;
@@ -48,6 +61,8 @@ const WHITE: Color = Color::White;
 #[allow(clippy::too_many_arguments)]
 pub fn render(
     frame: &mut Frame,
+    profile: &str,
+    profiles: &[String],
     api_url: &str,
     api_key: &str,
     focused_field: usize,
@@ -62,7 +77,7 @@ pub fn render(
 
     // Card size
     let card_width = 54u16;
-    let card_height = 14u16;
+    let card_height = 17u16;
     let card_area = centered_rect_fixed(card_width, card_height, area);
 
     // Main card
@@ -80,7 +95,8 @@ pub fn render(
     // Layout
     let chunks = Layout::vertical([
         Constraint::Length(1), // Header
-        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Saved profiles
+        Constraint::Length(3), // Profile
         Constraint::Length(3), // API URL
         Constraint::Length(3), // API Key
         Constraint::Length(1), // Error
@@ -95,6 +111,43 @@ pub fn render(
         .alignment(Alignment::Center);
     frame.render_widget(header, chunks[0]);
 
+    // Saved profiles, the active one highlighted
+    let mut saved = vec![Span::styled("Profiles: ", Style::default().fg(GRAY))];
+    if profiles.is_empty() {
+        saved.push(Span::styled("none saved", Style::default().fg(DARK_GRAY)));
+    }
+    for (i, name) in profiles.iter().enumerate() {
+        if i > 0 {
+            saved.push(Span::raw(" "));
+        }
+        let style = if name == profile {
+            Style::default().fg(GREEN).add_modifier(Modifier::BOLD)
+        } else {
+            Style::default().fg(WHITE)
+        };
+        saved.push(Span::styled(name.as_str(), style));
+    }
+    frame.render_widget(
+        Paragraph::new(Line::from(saved)).alignment(Alignment::Center),
+        chunks[1],
+    );
+
+    // Profile field
+    let profile_focused = focused_field == ApiConfigField::Profile.index();
+    let profile_border = if profile_focused { CYAN } else { GRAY };
+    let profile_block = Block::default()
+        .title(" Profile ")
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(profile_border));
+
+    let profile_text = if profile.is_empty() {
+        Span::styled("default", Style::default().fg(DARK_GRAY))
+    } else {
+        Span::styled(profile, Style::default().fg(WHITE))
+    };
+    let profile_widget = Paragraph::new(profile_text).block(profile_block);
+    frame.render_widget(profile_widget, chunks[2]);
+
     // API URL field
     let url_focused = focused_field == ApiConfigField::ApiUrl.index();
     let url_border = if url_focused { CYAN } else { GRAY };
@@ -109,7 +162,7 @@ pub fn render(
         Span::styled(api_url, Style::default().fg(GREEN))
     };
     let url_widget = Paragraph::new(url_text).block(url_block);
-    frame.render_widget(url_widget, chunks[2]);
+    frame.render_widget(url_widget, chunks[3]);
 
     // API Key field
     let key_focused = focused_field == ApiConfigField::ApiKey.index();
@@ -131,13 +184,18 @@ pub fn render(
         Span::styled(display, Style::default().fg(WHITE))
     };
     let key_widget = Paragraph::new(key_text).block(key_block);
-    frame.render_widget(key_widget, chunks[3]);
+    frame.render_widget(key_widget, chunks[4]);
 
     // Cursor position
-    if url_focused {
-        frame.set_cursor_position((chunks[2].x + 1 + api_url.len() as u16, chunks[2].y + 1));
+    if profile_focused {
+        frame.set_cursor_position((
+            chunks[2].x + 1 + profile.chars().count() as u16,
+            chunks[2].y + 1,
+        ));
+    } else if url_focused {
+        frame.set_cursor_position((chunks[3].x + 1 + api_url.len() as u16, chunks[3].y + 1));
     } else if key_focused {
-        frame.set_cursor_position((chunks[3].x + 1 + api_key.len() as u16, chunks[3].y + 1));
+        frame.set_cursor_position((chunks[4].x + 1 + api_key.len() as u16, chunks[4].y + 1));
     }
 
     // Error message
@@ -149,7 +207,7 @@ pub fn render(
             ),
             Span::styled(err, Style::default().fg(RED)),
         ]);
-        frame.render_widget(Paragraph::new(error_line), chunks[4]);
+        frame.render_widget(Paragraph::new(error_line), chunks[5]);
     }
 
     // Instructions
@@ -159,12 +217,103 @@ pub fn render(
         Span::styled("Enter", Style::default().fg(CYAN)),
         Span::raw(" save  "),
         Span::styled("Esc", Style::default().fg(CYAN)),
+        Span::raw(" cancel  "),
+        Span::styled("^P", Style::default().fg(CYAN)),
+        Span::raw(" next  "),
+        Span::styled("^L", Style::default().fg(CYAN)),
+        Span::raw(" lock"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(instructions)
+            .alignment(Alignment::Center)
+            .style(Style::default().fg(GRAY)),
+        chunks[6],
+    );
+}
+
+/// Render the passphrase prompt for the locked API configuration screen
+pub fn render_lock_prompt(
+    frame: &mut Frame,
+    prompt: LockPrompt,
+    passphrase: &str,
+    error: Option<&str>,
+    version: &str,
+) {
+    let area = frame.area();
+
+    // Black background
+    let bg = Block::default().style(Style::default().bg(Color::Black));
+    frame.render_widget(bg, area);
+
+    let card_area = centered_rect_fixed(54, 11, area);
+
+    let card_block = Block::default()
+        .title(format!(" Server Config v{} ", version))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(YELLOW));
+
+    frame.render_widget(Clear, card_area);
+    frame.render_widget(card_block.clone(), card_area);
+
+    let inner = card_block.inner(card_area);
+
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Header
+        Constraint::Length(1), // Spacer
+        Constraint::Length(3), // Passphrase
+        Constraint::Length(1), // Error
+        Constraint::Min(1),    // Instructions
+    ])
+    .horizontal_margin(1)
+    .split(inner);
+
+    let header_text = match prompt {
+        LockPrompt::Unlock => "Server settings are locked",
+        LockPrompt::SetPassphrase => "Choose a passphrase (empty removes the lock)",
+    };
+    let header = Paragraph::new(header_text)
+        .style(Style::default().fg(WHITE))
+        .alignment(Alignment::Center);
+    frame.render_widget(header, chunks[0]);
+
+    let input_block = Block::default()
+        .title(" Passphrase ")
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(CYAN));
+    let masked = "*".repeat(passphrase.chars().count());
+    let input = Paragraph::new(Span::styled(masked, Style::default().fg(WHITE))).block(input_block);
+    frame.render_widget(input, chunks[2]);
+    frame.set_cursor_position((
+        chunks[2].x + 1 + passphrase.chars().count() as u16,
+        chunks[2].y + 1,
+    ));
+
+    if let Some(err) = error {
+        let error_line = Line::from(vec![
+            Span::styled(
+                "Error: ",
+                Style::default().fg(RED).add_modifier(Modifier::BOLD),
+            ),
+            Span::styled(err, Style::default().fg(RED)),
+        ]);
+        frame.render_widget(Paragraph::new(error_line), chunks[3]);
+    }
+
+    let action = match prompt {
+        LockPrompt::Unlock => " unlock  ",
+        LockPrompt::SetPassphrase => " save  ",
+    };
+    let instructions = Line::from(vec![
+        Span::styled("Enter", Style::default().fg(CYAN)),
+        Span::raw(action),
+        Span::styled("Esc", Style::default().fg(CYAN)),
         Span::raw(" cancel"),
     ]);
     frame.render_widget(
         Paragraph::new(instructions)
             .alignment(Alignment::Center)
             .style(Style::default().fg(GRAY)),
-        chunks[5],
+        chunks[4],
     );
 }
@@ -0,0 +1,19 @@
+use std::io::{self, Write};
+
+use base64::engine::general_purpose::STANDARD;
+use base64::Engine;
+
+/// Copy text to the system clipboard through the terminal (OSC 52)
+///
+/// Works over SSH and without a clipboard library, in terminals that support
+/// it (most modern ones do; tmux needs `set -g set-clipboard on`).
+pub fn copy(text: &str) -> io::Result<()> {
+    let mut stdout = io::stdout();
+    write!(stdout, "{}", osc52_sequence(text))?;
+    stdout.flush()
+}
+
+/// Escape sequence that sets the clipboard to `text`
+pub fn osc52_sequence(text: &str) -> String {
+    format!("\x1b]52;c;{}\x07", STANDARD.encode(text))
+}
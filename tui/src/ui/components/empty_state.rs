@@ -0,0 +1,91 @@
+//! What lists show with nothing in them
+//!
+//! Instead of a blank table: why it's empty and the keys that would fill it,
+//! e.g. `n` to add an expense or `C` to copy last month into an empty one.
+
+use ratatui::{
+    layout::{Alignment, Rect},
+    style::{Color, Modifier, Style},
+    text::{Line, Span},
+    widgets::{Paragraph, Wrap},
+    Frame,
+};
+
+use crate::state::{AppState, EmptyList};
+
+/// Render what a list shows with nothing in it: why, and the keys that
+/// would fill it, centered in `area`
+pub fn render(frame: &mut Frame, area: Rect, message: &str, actions: &[(&str, String)]) {
+    let mut lines = vec![
+        Line::from(Span::styled(
+            message.to_string(),
+            Style::default().fg(Color::Gray),
+        )),
+        Line::from(""),
+    ];
+    for (key, action) in actions {
+        lines.push(Line::from(vec![
+            Span::styled(
+                format!("{:>6}", key),
+                Style::default()
+                    .fg(Color::Cyan)
+                    .add_modifier(Modifier::BOLD),
+            ),
+            Span::styled(
+                format!("  {:<36}", action),
+                Style::default().fg(Color::DarkGray),
+            ),
+        ]));
+    }
+
+    let height = (lines.len() as u16).min(area.height);
+    let top = area.y + area.height.saturating_sub(height) / 2;
+    let area = Rect::new(area.x, top, area.width, height);
+    let text = Paragraph::new(lines)
+        .alignment(Alignment::Center)
+        .wrap(Wrap { trim: false });
+    frame.render_widget(text, area);
+}
+
+/// Render the empty Expenses or Income table's message in `area`, below
+/// its header
+///
+/// `plural` names the entries ("expenses") and `add` the action adding one.
+pub fn render_list(
+    app: &AppState,
+    frame: &mut Frame,
+    area: Rect,
+    reason: EmptyList,
+    plural: &str,
+    add: &str,
+) {
+    let month = app
+        .selected_month()
+        .map(|m| m.display_name())
+        .unwrap_or_default();
+    let is_closed = app.selected_month_locked();
+    let (message, actions) = match reason {
+        EmptyList::NoMonth => ("No months yet".to_string(), Vec::new()),
+        EmptyList::Filtered => (
+            format!("No {} match the filters", plural),
+            vec![("Alt+1-9", "Remove a filter".to_string())],
+        ),
+        _ if is_closed => (
+            format!("No {} in {}, which is closed", plural, month),
+            vec![("U", "Edit it anyway".to_string())],
+        ),
+        EmptyList::NoneYet => (
+            format!("No {} in {} yet", plural, month),
+            vec![("n", add.to_string())],
+        ),
+        EmptyList::EmptyMonth => {
+            let mut actions = vec![("n", add.to_string())];
+            if let Some(previous) = app.previous_of_selected() {
+                actions.push(("C", format!("Copy {}", previous.display_name())));
+            }
+            actions.push(("i", "Import a CSV file".to_string()));
+            (format!("{} is empty", month), actions)
+        }
+    };
+    render(frame, area, &message, &actions);
+}
@@ -1 +1,3 @@
+pub mod empty_state;
 pub mod modal;
+pub mod view_bar;
@@ -2,16 +2,28 @@ use ratatui::{
     layout::{Alignment, Constraint, Layout},
     style::{Color, Modifier, Style},
     text::{Line, Span},
-    widgets::{Block, Borders, Clear, Paragraph},
+    widgets::{Block, Borders, Clear, Paragraph, Wrap},
     Frame,
 };
 
+use crate::api::EndpointMetrics;
+use crate::changelog::Release;
+use crate::config::{self, PeriodsConfig};
+use crate::models::{Debt, Expense, Money};
+use crate::state::audit::AuditTrail;
+use crate::state::autofill::AutofillPreview;
+use crate::state::currency::Rates;
 use crate::state::forms::{
-    CategoryFormState, ExpenseField, ExpenseFormState, IncomeFormState, IncomeTypeFormState,
-    PasswordFormState, PeriodFormState, PurchaseEditField,
+    self, AccountFormState, CategoryFormState, DebtField, DebtFormState, ExpenseField,
+    ExpenseFormState, IncomeFormState, IncomeTypeFormState, PasswordFormState, PeriodFormState,
+    PurchaseEditField, TransferField, TransferFormState,
 };
-use crate::state::{DataState, EntityType, Modal};
-use crate::ui::{centered_rect_fixed, hex_to_color};
+use crate::state::reallocate::ReallocateBoard;
+use crate::state::{DataState, EntityType, MergePreview, Modal, ReimbursementReport};
+use crate::storage::Ledger;
+use crate::ui::dates::DateFormat;
+use crate::ui::money::MoneyFormat;
+use crate::ui::{self, centered_rect_fixed, hex_to_color, progress_bar};
 
 /// Render a modal dialog
 pub fn render(frame: &mut Frame, modal: &Modal) {
@@ -25,6 +37,9 @@ pub fn render(frame: &mut Frame, modal: &Modal) {
         &IncomeTypeFormState::default(),
         &PasswordFormState::default(),
         &DataState::default(),
+        &PeriodsConfig::default(),
+        &MoneyFormat::default(),
+        DateFormat::default(),
     );
 }
 
@@ -40,13 +55,27 @@ pub fn render_with_forms(
     income_type_form: &IncomeTypeFormState,
     password_form: &PasswordFormState,
     data: &DataState,
+    periods: &PeriodsConfig,
+    money: &MoneyFormat,
+    rates: &Rates,
+    dates: DateFormat,
 ) {
     match modal {
-        Modal::ExpenseForm { .. } => render_expense_form(frame, expense_form, data),
-        Modal::IncomeForm { .. } => render_income_form_with_state(frame, income_form, data),
+        Modal::ExpenseForm { .. } => render_expense_form(frame, expense_form, data, periods, rates),
+        Modal::IncomeForm { .. } => {
+            render_income_form_with_state(frame, income_form, data, periods, rates)
+        }
         Modal::CategoryForm { .. } => render_category_form(frame, category_form),
         Modal::PeriodForm { .. } => render_period_form(frame, period_form),
         Modal::IncomeTypeForm { .. } => render_income_type_form(frame, income_type_form),
+        Modal::AccountForm { form } => render_account_form(frame, form),
+        Modal::Transfers {
+            selected,
+            confirm_remove,
+        } => render_transfers(frame, data, *selected, *confirm_remove, money),
+        Modal::TransferForm { form } => render_transfer_form(frame, form, data),
+        Modal::DebtForm { form } => render_debt_form(frame, form),
+        Modal::DebtPayment { debt, amount } => render_debt_payment(frame, debt, amount, money),
         Modal::PasswordForm => render_password_form_with_state(frame, password_form),
         Modal::ConfirmDelete {
             message,
@@ -64,12 +93,98 @@ pub fn render_with_forms(
             is_closing,
             ..
         } => render_confirm_close_month(frame, month_name, *is_closing),
+        Modal::EnvExport {
+            url,
+            api_key,
+            reveal_key,
+        } => render_env_export(frame, url, api_key, *reveal_key),
+        Modal::ShareLink {
+            month_name,
+            url,
+            expires_at,
+        } => render_share_link(frame, month_name, url, expires_at),
+        Modal::Notes {
+            month_name,
+            text,
+            shared,
+            ..
+        } => render_notes(frame, month_name, text, *shared),
+        Modal::Checklist {
+            month_name,
+            items,
+            selected,
+            ..
+        } => render_checklist(frame, month_name, items, *selected),
+        Modal::Reimbursements { report, selected } => {
+            render_reimbursements(frame, report, *selected, money, dates)
+        }
+        Modal::ExpenseDetail {
+            expense,
+            selected,
+            confirm_remove,
+            file,
+        } => render_expense_detail(
+            frame,
+            expense,
+            (*selected, *file),
+            *confirm_remove,
+            money,
+            dates,
+        ),
+        Modal::AttachFile { expense, path } => render_attach_file(frame, expense, path),
+        Modal::Inspect {
+            entity_type,
+            name,
+            audit,
+        } => render_inspect(frame, *entity_type, name, audit, dates),
+        Modal::Performance { endpoints } => render_performance(frame, endpoints),
+        Modal::WhatsNew { releases, scroll } => render_whats_new(frame, releases, *scroll),
+        Modal::Debug {
+            totals,
+            slowest,
+            details,
+        } => render_debug(frame, totals, slowest, details),
+        Modal::ConfirmDuplicate {
+            entity_type,
+            existing_name,
+            ..
+        } => render_confirm_duplicate(frame, *entity_type, existing_name),
+        Modal::MergeSelect {
+            entity_type,
+            source_name,
+            targets,
+            selected,
+            ..
+        } => render_merge_select(frame, *entity_type, source_name, targets, *selected),
+        Modal::ConfirmMerge {
+            source_name,
+            target_name,
+            preview,
+            ..
+        } => render_confirm_merge(frame, source_name, target_name, preview, money),
+        Modal::Rollover {
+            name,
+            previous,
+            template,
+            ..
+        } => render_rollover(frame, name, previous.as_ref(), template.as_ref()),
+        Modal::Autofill { preview } => render_autofill(frame, preview, money),
+        Modal::Reallocate { board } => render_reallocate(frame, board, money),
+        Modal::ReportProblem { body, .. } => render_report_problem(frame, body),
+        Modal::History { entries, selected } => render_history(frame, entries, *selected),
+        Modal::ImportCsv { month_name, path } => render_import_csv(frame, month_name, path),
         Modal::Help => render_help(frame),
     }
 }
 
 /// Render expense form modal with actual form state
-fn render_expense_form(frame: &mut Frame, form: &ExpenseFormState, data: &DataState) {
+fn render_expense_form(
+    frame: &mut Frame,
+    form: &ExpenseFormState,
+    data: &DataState,
+    periods: &PeriodsConfig,
+    rates: &Rates,
+) {
     let is_edit = form.editing_id.is_some();
     let title = if is_edit {
         "Edit Expense"
@@ -78,7 +193,8 @@ fn render_expense_form(frame: &mut Frame, form: &ExpenseFormState, data: &DataSt
     };
     // Increase height to accommodate purchases
     let purchases_height = form.purchases.len().max(1) as u16 + 2; // +2 for header and total
-    let total_height = 16 + purchases_height.min(8); // Cap purchases display
+    let custom_height = custom_fields_height(form);
+    let total_height = 20 + purchases_height.min(8) + custom_height; // Cap purchases display
     let area = centered_rect_fixed(65, total_height, frame.area());
 
     let block = Block::default()
@@ -96,9 +212,12 @@ fn render_expense_form(frame: &mut Frame, form: &ExpenseFormState, data: &DataSt
         Constraint::Length(2),                       // Name
         Constraint::Length(2),                       // Period
         Constraint::Length(2),                       // Category
+        Constraint::Length(2),                       // Account
+        Constraint::Length(2),                       // Currency
         Constraint::Length(2),                       // Projected
         Constraint::Length(purchases_height.min(8)), // Purchases
         Constraint::Length(2),                       // Notes
+        Constraint::Length(custom_height),           // Custom fields
         Constraint::Min(1),                          // Spacer
         Constraint::Length(1),                       // Instructions
     ])
@@ -163,7 +282,7 @@ fn render_expense_form(frame: &mut Frame, form: &ExpenseFormState, data: &DataSt
             format!("← → ({} available)", data.periods.len())
         }
     } else {
-        form.period.clone()
+        periods.label(&form.period).to_string()
     };
     render_field(
         frame,
@@ -196,9 +315,29 @@ fn render_expense_form(frame: &mut Frame, form: &ExpenseFormState, data: &DataSt
     render_field(
         frame,
         chunks[3],
+        "Account:",
+        &account_display(data, form.account_id),
+        form.focused_field == ExpenseField::Account,
+        true,
+    );
+
+    render_field(
+        frame,
+        chunks[4],
+        "Currency:",
+        &currency_display(rates, form.currency.as_deref()),
+        form.focused_field == ExpenseField::Currency,
+        true,
+    );
+
+    let symbol = currency_symbol(rates, form.currency.as_deref());
+    render_field(
+        frame,
+        chunks[5],
         "Projected:",
         &format!(
-            "${}",
+            "{}{}",
+            symbol,
             if form.projected.is_empty() {
                 "0.00"
             } else {
@@ -211,17 +350,23 @@ fn render_expense_form(frame: &mut Frame, form: &ExpenseFormState, data: &DataSt
 
     // Render purchases section
     let is_purchases_focused = form.focused_field == ExpenseField::Purchases;
-    render_purchases_section(frame, chunks[4], form, is_purchases_focused);
+    render_purchases_section(frame, chunks[6], form, is_purchases_focused);
 
     render_field(
         frame,
-        chunks[5],
+        chunks[7],
         "Notes:",
         &form.notes,
         form.focused_field == ExpenseField::Notes,
         false,
     );
 
+    render_custom_fields_section(frame, chunks[8], form);
+
+    if let Some((field, message)) = &form.invalid {
+        render_field_error(frame, chunks[field.index()], message);
+    }
+
     // Instructions - different when on purchases
     let instructions = if is_purchases_focused {
         Line::from(vec![
@@ -229,6 +374,12 @@ fn render_expense_form(frame: &mut Frame, form: &ExpenseFormState, data: &DataSt
             Span::raw(":Add "),
             Span::styled("^D", Style::default().fg(Color::Cyan)),
             Span::raw(":Del "),
+            Span::styled("^T", Style::default().fg(Color::Cyan)),
+            Span::raw(if form.sum_purchases {
+                ":Keep cost "
+            } else {
+                ":Sum cost "
+            }),
             Span::styled("↑/↓", Style::default().fg(Color::Cyan)),
             Span::raw(":Nav "),
             Span::styled("←/→", Style::default().fg(Color::Cyan)),
@@ -238,6 +389,15 @@ fn render_expense_form(frame: &mut Frame, form: &ExpenseFormState, data: &DataSt
             Span::styled("Enter", Style::default().fg(Color::Cyan)),
             Span::raw(":Save"),
         ])
+    } else if form.focused_field == ExpenseField::Custom {
+        Line::from(vec![
+            Span::styled("↑/↓", Style::default().fg(Color::Cyan)),
+            Span::raw(": Field  "),
+            Span::styled("Tab", Style::default().fg(Color::Cyan)),
+            Span::raw(": Next  "),
+            Span::styled("Enter", Style::default().fg(Color::Cyan)),
+            Span::raw(": Save"),
+        ])
     } else {
         Line::from(vec![
             Span::styled("Tab", Style::default().fg(Color::Cyan)),
@@ -251,7 +411,103 @@ fn render_expense_form(frame: &mut Frame, form: &ExpenseFormState, data: &DataSt
     let instructions_para = Paragraph::new(instructions)
         .alignment(Alignment::Center)
         .style(Style::default().fg(Color::DarkGray));
-    frame.render_widget(instructions_para, chunks[7]);
+    frame.render_widget(instructions_para, chunks[10]);
+}
+
+/// Most custom fields shown at once; the list scrolls past them
+const CUSTOM_FIELD_ROWS: usize = 5;
+
+/// Header, the fields shown and a line for an error; none without fields
+fn custom_fields_height(form: &ExpenseFormState) -> u16 {
+    match form.custom_fields.len() {
+        0 => 0,
+        count => count.min(CUSTOM_FIELD_ROWS) as u16 + 2,
+    }
+}
+
+/// Render the fields a customized server adds, each as typed
+fn render_custom_fields_section(
+    frame: &mut Frame,
+    area: ratatui::layout::Rect,
+    form: &ExpenseFormState,
+) {
+    if form.custom_fields.is_empty() {
+        return;
+    }
+    let is_focused = form.focused_field == ExpenseField::Custom;
+    let label_style = if is_focused {
+        Style::default()
+            .fg(Color::Cyan)
+            .add_modifier(Modifier::BOLD)
+    } else {
+        Style::default().fg(Color::DarkGray)
+    };
+    let width = form
+        .custom_fields
+        .iter()
+        .map(|field| field.name.chars().count())
+        .max()
+        .unwrap_or(0)
+        .min(20);
+    // Keep the selected field in view
+    let first = form
+        .selected_custom
+        .saturating_sub(CUSTOM_FIELD_ROWS - 1)
+        .min(form.custom_fields.len().saturating_sub(CUSTOM_FIELD_ROWS));
+
+    let mut lines = vec![Line::from(Span::styled("Custom:", label_style))];
+    for (i, field) in form
+        .custom_fields
+        .iter()
+        .enumerate()
+        .skip(first)
+        .take(CUSTOM_FIELD_ROWS)
+    {
+        let is_selected = is_focused && i == form.selected_custom;
+        let prefix = if is_selected { "  > " } else { "    " };
+        let value_style = if is_selected {
+            Style::default()
+                .fg(Color::Yellow)
+                .add_modifier(Modifier::UNDERLINED)
+        } else {
+            Style::default().fg(Color::Gray)
+        };
+        let value = if field.text.is_empty() && !is_selected {
+            "-".to_string()
+        } else {
+            field.text.clone()
+        };
+        lines.push(Line::from(vec![
+            Span::raw(prefix),
+            Span::styled(
+                format!("{:width$}  ", field.name, width = width),
+                Style::default().fg(Color::DarkGray),
+            ),
+            Span::styled(value, value_style),
+            Span::styled(
+                if is_selected { "_" } else { "" },
+                Style::default().fg(Color::Cyan),
+            ),
+        ]));
+    }
+    frame.render_widget(Paragraph::new(lines), area);
+}
+
+/// Show why the server rejected a field on the last line of its area
+fn render_field_error(frame: &mut Frame, area: ratatui::layout::Rect, message: &str) {
+    if area.height == 0 {
+        return;
+    }
+    let line_area = ratatui::layout::Rect {
+        y: area.y + area.height - 1,
+        height: 1,
+        ..area
+    };
+    let line = Line::from(vec![
+        Span::raw(format!("{:12}", "")),
+        Span::styled(format!("! {}", message), Style::default().fg(Color::Red)),
+    ]);
+    frame.render_widget(Paragraph::new(line), line_area);
 }
 
 /// Render purchases section within expense form
@@ -271,9 +527,9 @@ fn render_purchases_section(
 
     let mut lines: Vec<Line> = vec![];
 
-    // Header with total
+    // Header with total, and the cost when it doesn't follow the purchases
     let total = form.calculated_cost();
-    let header = Line::from(vec![
+    let mut header = vec![
         Span::styled(format!("{:12}", "Purchases:"), label_style),
         Span::styled(
             format!("(Total: ${:.2})", total),
@@ -283,8 +539,14 @@ fn render_purchases_section(
                 Color::DarkGray
             }),
         ),
-    ]);
-    lines.push(header);
+    ];
+    if !form.sum_purchases {
+        header.push(Span::styled(
+            format!("  Cost stays ${:.2}", form.cost_value()),
+            Style::default().fg(Color::Yellow),
+        ));
+    }
+    lines.push(Line::from(header));
 
     if form.purchases.is_empty() {
         let hint = Line::from(vec![
@@ -369,12 +631,18 @@ fn render_purchases_section(
 }
 
 /// Render income form modal with form state
-fn render_income_form_with_state(frame: &mut Frame, form: &IncomeFormState, data: &DataState) {
+fn render_income_form_with_state(
+    frame: &mut Frame,
+    form: &IncomeFormState,
+    data: &DataState,
+    periods: &PeriodsConfig,
+    rates: &Rates,
+) {
     use crate::state::forms::IncomeField;
 
     let is_edit = form.editing_id.is_some();
     let title = if is_edit { "Edit Income" } else { "Add Income" };
-    let area = centered_rect_fixed(60, 16, frame.area());
+    let area = centered_rect_fixed(60, 20, frame.area());
 
     let block = Block::default()
         .title(format!(" {} ", title))
@@ -390,6 +658,8 @@ fn render_income_form_with_state(frame: &mut Frame, form: &IncomeFormState, data
     let chunks = Layout::vertical([
         Constraint::Length(2), // Income Type
         Constraint::Length(2), // Period
+        Constraint::Length(2), // Account
+        Constraint::Length(2), // Currency
         Constraint::Length(2), // Projected
         Constraint::Length(2), // Amount
         Constraint::Min(2),    // Spacer
@@ -467,7 +737,7 @@ fn render_income_form_with_state(frame: &mut Frame, form: &IncomeFormState, data
             format!("← → ({} available)", data.periods.len())
         }
     } else {
-        form.period.clone()
+        periods.label(&form.period).to_string()
     };
     render_field(
         frame,
@@ -481,9 +751,29 @@ fn render_income_form_with_state(frame: &mut Frame, form: &IncomeFormState, data
     render_field(
         frame,
         chunks[2],
+        "Account:",
+        &account_display(data, form.account_id),
+        form.focused_field == IncomeField::Account,
+        true,
+    );
+
+    render_field(
+        frame,
+        chunks[3],
+        "Currency:",
+        &currency_display(rates, form.currency.as_deref()),
+        form.focused_field == IncomeField::Currency,
+        true,
+    );
+
+    let symbol = currency_symbol(rates, form.currency.as_deref());
+    render_field(
+        frame,
+        chunks[4],
         "Projected:",
         &format!(
-            "${}",
+            "{}{}",
+            symbol,
             if form.projected.is_empty() {
                 "0.00"
             } else {
@@ -496,10 +786,11 @@ fn render_income_form_with_state(frame: &mut Frame, form: &IncomeFormState, data
 
     render_field(
         frame,
-        chunks[3],
+        chunks[5],
         "Amount:",
         &format!(
-            "${}",
+            "{}{}",
+            symbol,
             if form.amount.is_empty() {
                 "0.00"
             } else {
@@ -510,6 +801,10 @@ fn render_income_form_with_state(frame: &mut Frame, form: &IncomeFormState, data
         false,
     );
 
+    if let Some((field, message)) = &form.invalid {
+        render_field_error(frame, chunks[field.index()], message);
+    }
+
     let instructions = Line::from(vec![
         Span::styled("Tab", Style::default().fg(Color::Cyan)),
         Span::raw(": Next  "),
@@ -521,7 +816,7 @@ fn render_income_form_with_state(frame: &mut Frame, form: &IncomeFormState, data
     let instructions_para = Paragraph::new(instructions)
         .alignment(Alignment::Center)
         .style(Style::default().fg(Color::DarkGray));
-    frame.render_widget(instructions_para, chunks[5]);
+    frame.render_widget(instructions_para, chunks[7]);
 }
 
 /// Render category form modal with actual state
@@ -664,12 +959,45 @@ fn render_entity_form_impl(
     frame.render_widget(instructions_para, chunks[3]);
 }
 
-/// Render password form modal with actual state
-fn render_password_form_with_state(frame: &mut Frame, form: &PasswordFormState) {
-    let area = centered_rect_fixed(50, 14, frame.area());
+/// What an account select shows: the account, or how to pick one
+fn account_display(data: &DataState, account_id: Option<i32>) -> String {
+    match account_id.and_then(|id| data.accounts.iter().find(|a| a.id == id)) {
+        Some(account) => format!("{} ({})", account.name, account.kind.as_str()),
+        None if data.accounts.is_empty() => "None (add accounts in Settings)".to_string(),
+        None => format!("None  ← → ({} available)", data.accounts.len()),
+    }
+}
+
+/// What a currency select shows: the currency, or how to pick another
+fn currency_display(rates: &Rates, currency: Option<&str>) -> String {
+    match rates.foreign(currency) {
+        Some(code) => code.to_string(),
+        None if rates.codes().is_empty() => {
+            format!("{} (add rates under [currency])", rates.base())
+        }
+        None => format!("{}  ← → ({} more)", rates.base(), rates.codes().len()),
+    }
+}
+
+/// What amounts on a form start with: `$`, or the code of another currency
+fn currency_symbol(rates: &Rates, currency: Option<&str>) -> String {
+    match rates.foreign(currency) {
+        Some(code) => format!("{} ", code),
+        None => "$".to_string(),
+    }
+}
+
+/// Render account form modal
+fn render_account_form(frame: &mut Frame, form: &AccountFormState) {
+    let title = if form.editing_id.is_some() {
+        "Edit Account"
+    } else {
+        "Add Account"
+    };
+    let area = centered_rect_fixed(50, 12, frame.area());
 
     let block = Block::default()
-        .title(" Change Password ")
+        .title(format!(" {} ", title))
         .title_alignment(Alignment::Center)
         .borders(Borders::ALL)
         .border_style(Style::default().fg(Color::Cyan))
@@ -680,93 +1008,79 @@ fn render_password_form_with_state(frame: &mut Frame, form: &PasswordFormState)
 
     let inner = block.inner(area);
     let chunks = Layout::vertical([
-        Constraint::Length(2), // Current
-        Constraint::Length(2), // New
-        Constraint::Length(2), // Confirm
+        Constraint::Length(2), // Name
+        Constraint::Length(2), // Kind
         Constraint::Min(2),    // Spacer
         Constraint::Length(1), // Instructions
     ])
     .split(inner);
 
-    let render_password_field = |frame: &mut Frame,
-                                 area: ratatui::layout::Rect,
-                                 label: &str,
-                                 value: &str,
-                                 is_focused: bool| {
-        let display_owned = if value.is_empty() {
-            "Enter password...".to_string()
-        } else {
-            "*".repeat(value.len().min(20))
-        };
-
-        let (label_style, value_style) = if is_focused {
-            (
-                Style::default()
-                    .fg(Color::Cyan)
-                    .add_modifier(Modifier::BOLD),
-                Style::default().fg(Color::White),
-            )
-        } else {
-            (
-                Style::default().fg(Color::DarkGray),
-                Style::default().fg(Color::Gray),
-            )
-        };
-        let cursor = if is_focused { "_" } else { "" };
-
-        let line = Line::from(vec![
-            Span::styled(format!("{:12}", label), label_style),
-            Span::styled(display_owned, value_style),
-            Span::styled(cursor, Style::default().fg(Color::Cyan)),
-        ]);
-        frame.render_widget(Paragraph::new(line), area);
+    let name_display = if form.name.is_empty() {
+        "Type name..."
+    } else {
+        form.name.as_str()
     };
+    let name_line = Line::from(vec![
+        Span::styled(
+            format!("{:12}", "Name:"),
+            Style::default()
+                .fg(Color::Cyan)
+                .add_modifier(Modifier::BOLD),
+        ),
+        Span::styled(name_display, Style::default().fg(Color::White)),
+        Span::styled("_", Style::default().fg(Color::Cyan)),
+    ]);
+    frame.render_widget(Paragraph::new(name_line), chunks[0]);
 
-    render_password_field(
-        frame,
-        chunks[0],
-        "Current:",
-        &form.current_password,
-        form.focused_field == 0,
-    );
-    render_password_field(
-        frame,
-        chunks[1],
-        "New:",
-        &form.new_password,
-        form.focused_field == 1,
-    );
-    render_password_field(
-        frame,
-        chunks[2],
-        "Confirm:",
-        &form.confirm_password,
-        form.focused_field == 2,
-    );
+    let kind_line = Line::from(vec![
+        Span::styled(
+            format!("{:12}", "Kind:"),
+            Style::default().fg(Color::DarkGray),
+        ),
+        Span::styled(form.kind.as_str(), Style::default().fg(Color::Gray)),
+    ]);
+    frame.render_widget(Paragraph::new(kind_line), chunks[1]);
 
     let instructions = Line::from(vec![
         Span::styled("Tab", Style::default().fg(Color::Cyan)),
-        Span::raw(": Next  "),
+        Span::raw(":Kind "),
         Span::styled("Enter", Style::default().fg(Color::Cyan)),
-        Span::raw(": Submit  "),
+        Span::raw(":Save "),
         Span::styled("Esc", Style::default().fg(Color::Cyan)),
-        Span::raw(": Cancel"),
+        Span::raw(":Cancel"),
     ]);
     let instructions_para = Paragraph::new(instructions)
         .alignment(Alignment::Center)
         .style(Style::default().fg(Color::DarkGray));
-    frame.render_widget(instructions_para, chunks[4]);
+    frame.render_widget(instructions_para, chunks[3]);
 }
 
-/// Render confirmation dialog
-fn render_confirm_delete(frame: &mut Frame, message: &str, _entity_type: EntityType) {
-    let area = centered_rect_fixed(50, 8, frame.area());
+/// Name of the account with `id`, or a stand-in once it's deleted
+fn account_label(data: &DataState, id: i32) -> String {
+    data.accounts
+        .iter()
+        .find(|a| a.id == id)
+        .map(|a| a.name.clone())
+        .unwrap_or_else(|| format!("Account #{}", id))
+}
+
+/// Render the selected month's transfers between accounts
+fn render_transfers(
+    frame: &mut Frame,
+    data: &DataState,
+    selected: usize,
+    confirm_remove: bool,
+    money: &MoneyFormat,
+) {
+    let transfers = &data.transfers;
+    let height = (transfers.len().max(1) as u16 + 6).min(24);
+    let area = centered_rect_fixed(76, height, frame.area());
 
     let block = Block::default()
-        .title(" Confirm Delete ")
+        .title(format!(" Transfers ({}) ", transfers.len()))
         .title_alignment(Alignment::Center)
         .borders(Borders::ALL)
-        .border_style(Style::default().fg(Color::Red))
+        .border_style(Style::default().fg(Color::Cyan))
         .style(Style::default().bg(Color::Rgb(30, 30, 35)));
 
     frame.render_widget(Clear, area);
@@ -774,38 +1088,87 @@ fn render_confirm_delete(frame: &mut Frame, message: &str, _entity_type: EntityT
 
     let inner = block.inner(area);
     let chunks = Layout::vertical([
-        Constraint::Length(2), // Message
-        Constraint::Min(1),    // Spacer
-        Constraint::Length(1), // Buttons
+        Constraint::Min(1),    // Transfers
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Instructions
     ])
     .split(inner);
 
-    let message_para = Paragraph::new(message)
-        .style(Style::default().fg(Color::White))
-        .alignment(Alignment::Center);
-    frame.render_widget(message_para, chunks[0]);
+    // Keep the selected row in view
+    let visible = chunks[0].height as usize;
+    let offset = (selected + 1).saturating_sub(visible);
+    let lines: Vec<Line> = if transfers.is_empty() {
+        vec![Line::from(Span::styled(
+            "   No transfers this month",
+            Style::default().fg(Color::DarkGray),
+        ))]
+    } else {
+        transfers
+            .iter()
+            .enumerate()
+            .skip(offset)
+            .take(visible)
+            .map(|(i, transfer)| {
+                let is_selected = i == selected;
+                let row_style = if is_selected {
+                    Style::default()
+                        .fg(Color::White)
+                        .add_modifier(Modifier::BOLD)
+                        .bg(Color::DarkGray)
+                } else {
+                    Style::default().fg(Color::White)
+                };
+                let route = format!(
+                    "{} → {}",
+                    account_label(data, transfer.from_account_id),
+                    account_label(data, transfer.to_account_id)
+                );
+                Line::from(vec![
+                    Span::raw(if is_selected { " > " } else { "   " }),
+                    Span::styled(format!("{:<32.32}", route), row_style),
+                    Span::styled(format!("{:>14}", money.format(transfer.amount)), row_style),
+                    Span::styled(
+                        format!("  {:<20.20}", transfer.notes.as_deref().unwrap_or_default()),
+                        Style::default().fg(Color::Gray),
+                    ),
+                ])
+            })
+            .collect()
+    };
+    frame.render_widget(Paragraph::new(lines), chunks[0]);
 
-    let buttons = Line::from(vec![
-        Span::styled("[y]", Style::default().fg(Color::Red)),
-        Span::raw(" Yes, Delete  "),
-        Span::styled("[n]", Style::default().fg(Color::Green)),
-        Span::raw(" No, Cancel"),
-    ]);
-    let buttons_para = Paragraph::new(buttons)
+    let instructions = if confirm_remove && !transfers.is_empty() {
+        Line::from(vec![
+            Span::styled("d", Style::default().fg(Color::Red)),
+            Span::raw(": Delete this transfer for good  "),
+            Span::styled("any key", Style::default().fg(Color::Yellow)),
+            Span::raw(": Keep it"),
+        ])
+    } else {
+        Line::from(vec![
+            Span::styled("n", Style::default().fg(Color::Green)),
+            Span::raw(": New  "),
+            Span::styled("d", Style::default().fg(Color::Red)),
+            Span::raw(": Delete  "),
+            Span::styled("Esc", Style::default().fg(Color::Yellow)),
+            Span::raw(": Close"),
+        ])
+    };
+    let instructions_para = Paragraph::new(instructions)
         .alignment(Alignment::Center)
         .style(Style::default().fg(Color::White));
-    frame.render_widget(buttons_para, chunks[2]);
+    frame.render_widget(instructions_para, chunks[2]);
 }
 
-/// Render pay confirmation dialog with editable amount
-fn render_confirm_pay(frame: &mut Frame, expense_name: &str, projected: f64, amount_input: &str) {
-    let area = centered_rect_fixed(50, 11, frame.area());
+/// Render the form for a new transfer
+fn render_transfer_form(frame: &mut Frame, form: &TransferFormState, data: &DataState) {
+    let area = centered_rect_fixed(60, 14, frame.area());
 
     let block = Block::default()
-        .title(" Pay Expense ")
+        .title(" New Transfer ")
         .title_alignment(Alignment::Center)
         .borders(Borders::ALL)
-        .border_style(Style::default().fg(Color::Green))
+        .border_style(Style::default().fg(Color::Cyan))
         .style(Style::default().bg(Color::Rgb(30, 30, 35)));
 
     frame.render_widget(Clear, area);
@@ -813,84 +1176,1931 @@ fn render_confirm_pay(frame: &mut Frame, expense_name: &str, projected: f64, amo
 
     let inner = block.inner(area);
     let chunks = Layout::vertical([
-        Constraint::Length(2), // Expense name
-        Constraint::Length(2), // Amount input
-        Constraint::Length(1), // Projected hint
+        Constraint::Length(2), // From
+        Constraint::Length(2), // To
+        Constraint::Length(2), // Amount
+        Constraint::Length(2), // Notes
         Constraint::Min(1),    // Spacer
         Constraint::Length(1), // Instructions
     ])
     .split(inner);
 
-    let name_para = Paragraph::new(format!("Add payment to '{}'", expense_name))
-        .style(Style::default().fg(Color::White))
-        .alignment(Alignment::Center);
-    frame.render_widget(name_para, chunks[0]);
-
-    // Editable amount input
+    let amount = if form.amount.is_empty() {
+        "$0.00".to_string()
+    } else {
+        format!("${}", form.amount)
+    };
+    let fields = [
+        (
+            TransferField::From,
+            "From:",
+            account_display(data, form.from_account_id),
+        ),
+        (
+            TransferField::To,
+            "To:",
+            account_display(data, form.to_account_id),
+        ),
+        (TransferField::Amount, "Amount:", amount),
+        (TransferField::Notes, "Notes:", form.notes.clone()),
+    ];
+    for (field, label, value) in fields {
+        let is_focused = form.focused == field;
+        let label_style = if is_focused {
+            Style::default()
+                .fg(Color::Cyan)
+                .add_modifier(Modifier::BOLD)
+        } else {
+            Style::default().fg(Color::DarkGray)
+        };
+        let value_style = if is_focused {
+            Style::default().fg(Color::White)
+        } else {
+            Style::default().fg(Color::Gray)
+        };
+        let typed = matches!(field, TransferField::Amount | TransferField::Notes);
+        let cursor = if is_focused && typed { "_" } else { "" };
+        let line = Line::from(vec![
+            Span::styled(format!("{:12}", label), label_style),
+            Span::styled(value, value_style),
+            Span::styled(cursor, Style::default().fg(Color::Cyan)),
+        ]);
+        frame.render_widget(Paragraph::new(line), chunks[field.index()]);
+    }
+
+    let instructions = Line::from(vec![
+        Span::styled("Tab", Style::default().fg(Color::Cyan)),
+        Span::raw(":Next "),
+        Span::styled("← →", Style::default().fg(Color::Cyan)),
+        Span::raw(":Account "),
+        Span::styled("Enter", Style::default().fg(Color::Cyan)),
+        Span::raw(":Save "),
+        Span::styled("Esc", Style::default().fg(Color::Cyan)),
+        Span::raw(":Cancel"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::DarkGray));
+    frame.render_widget(instructions_para, chunks[5]);
+}
+
+/// Render the form for adding or editing a debt
+fn render_debt_form(frame: &mut Frame, form: &DebtFormState) {
+    let title = if form.editing_id.is_some() {
+        "Edit Debt"
+    } else {
+        "Add Debt"
+    };
+    let area = centered_rect_fixed(60, 18, frame.area());
+
+    let block = Block::default()
+        .title(format!(" {} ", title))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Name
+        Constraint::Length(2), // Owed
+        Constraint::Length(2), // Interest
+        Constraint::Length(2), // Minimum
+        Constraint::Length(2), // Category
+        Constraint::Length(2), // Period
+        Constraint::Min(1),    // Spacer
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let fields = [
+        (DebtField::Name, "Name:", form.name.clone()),
+        (
+            DebtField::Principal,
+            "Owed:",
+            format!("${}", form.principal),
+        ),
+        (
+            DebtField::Rate,
+            "Interest:",
+            format!("{}% a year", form.interest_rate),
+        ),
+        (
+            DebtField::Minimum,
+            "Minimum:",
+            format!("${} a month", form.minimum_payment),
+        ),
+        (DebtField::Category, "Category:", form.category.clone()),
+        (DebtField::Period, "Period:", form.period.clone()),
+    ];
+    for (field, label, value) in fields {
+        let is_focused = form.focused == field;
+        let label_style = if is_focused {
+            Style::default()
+                .fg(Color::Cyan)
+                .add_modifier(Modifier::BOLD)
+        } else {
+            Style::default().fg(Color::DarkGray)
+        };
+        let value_style = if is_focused {
+            Style::default().fg(Color::White)
+        } else {
+            Style::default().fg(Color::Gray)
+        };
+        let select = matches!(field, DebtField::Category | DebtField::Period);
+        let cursor = match (is_focused, select) {
+            (true, true) => "  ← →",
+            (true, false) => "_",
+            _ => "",
+        };
+        let line = Line::from(vec![
+            Span::styled(format!("{:12}", label), label_style),
+            Span::styled(value, value_style),
+            Span::styled(cursor, Style::default().fg(Color::Cyan)),
+        ]);
+        frame.render_widget(Paragraph::new(line), chunks[field.index()]);
+    }
+
+    let instructions = Line::from(vec![
+        Span::styled("Tab", Style::default().fg(Color::Cyan)),
+        Span::raw(":Next "),
+        Span::styled("Enter", Style::default().fg(Color::Cyan)),
+        Span::raw(":Save "),
+        Span::styled("Esc", Style::default().fg(Color::Cyan)),
+        Span::raw(":Cancel"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::DarkGray));
+    frame.render_widget(instructions_para, chunks[7]);
+}
+
+/// Render the prompt for how much was paid on a debt
+fn render_debt_payment(frame: &mut Frame, debt: &Debt, amount: &str, money: &MoneyFormat) {
+    let area = centered_rect_fixed(56, 10, frame.area());
+
+    let block = Block::default()
+        .title(format!(" Pay {} ", debt.name))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Owed
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Amount
+        Constraint::Length(1), // Spacer
+        Constraint::Length(2), // Hint
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let owed = Line::from(vec![
+        Span::styled("Owed: ", Style::default().fg(Color::Gray)),
+        Span::styled(
+            money.format(debt.principal),
+            Style::default().fg(Color::White),
+        ),
+        Span::styled("   Minimum: ", Style::default().fg(Color::Gray)),
+        Span::styled(
+            money.format(debt.minimum_payment),
+            Style::default().fg(Color::White),
+        ),
+    ]);
+    frame.render_widget(Paragraph::new(owed).alignment(Alignment::Center), chunks[0]);
+
+    let field = Line::from(vec![
+        Span::styled("Paid: $", Style::default().fg(Color::Cyan)),
+        Span::styled(amount, Style::default().fg(Color::White)),
+        Span::styled("_", Style::default().fg(Color::Cyan)),
+    ]);
+    frame.render_widget(
+        Paragraph::new(field).alignment(Alignment::Center),
+        chunks[2],
+    );
+
+    let hint = Paragraph::new(format!(
+        "Recorded as an expense in {} / {} this month",
+        debt.category, debt.period
+    ))
+    .alignment(Alignment::Center)
+    .wrap(Wrap { trim: true })
+    .style(Style::default().fg(Color::DarkGray));
+    frame.render_widget(hint, chunks[4]);
+
+    let instructions = Line::from(vec![
+        Span::styled("Enter", Style::default().fg(Color::Cyan)),
+        Span::raw(":Record "),
+        Span::styled("Esc", Style::default().fg(Color::Cyan)),
+        Span::raw(":Cancel"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::DarkGray));
+    frame.render_widget(instructions_para, chunks[5]);
+}
+
+/// Render password form modal with actual state
+fn render_password_form_with_state(frame: &mut Frame, form: &PasswordFormState) {
+    let area = centered_rect_fixed(50, 14, frame.area());
+
+    let block = Block::default()
+        .title(" Change Password ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Current
+        Constraint::Length(2), // New
+        Constraint::Length(2), // Confirm
+        Constraint::Min(2),    // Spacer
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let render_password_field = |frame: &mut Frame,
+                                 area: ratatui::layout::Rect,
+                                 label: &str,
+                                 value: &str,
+                                 is_focused: bool| {
+        let display_owned = if value.is_empty() {
+            "Enter password...".to_string()
+        } else {
+            "*".repeat(value.len().min(20))
+        };
+
+        let (label_style, value_style) = if is_focused {
+            (
+                Style::default()
+                    .fg(Color::Cyan)
+                    .add_modifier(Modifier::BOLD),
+                Style::default().fg(Color::White),
+            )
+        } else {
+            (
+                Style::default().fg(Color::DarkGray),
+                Style::default().fg(Color::Gray),
+            )
+        };
+        let cursor = if is_focused { "_" } else { "" };
+
+        let line = Line::from(vec![
+            Span::styled(format!("{:12}", label), label_style),
+            Span::styled(display_owned, value_style),
+            Span::styled(cursor, Style::default().fg(Color::Cyan)),
+        ]);
+        frame.render_widget(Paragraph::new(line), area);
+    };
+
+    render_password_field(
+        frame,
+        chunks[0],
+        "Current:",
+        &form.current_password,
+        form.focused_field == 0,
+    );
+    render_password_field(
+        frame,
+        chunks[1],
+        "New:",
+        &form.new_password,
+        form.focused_field == 1,
+    );
+    render_password_field(
+        frame,
+        chunks[2],
+        "Confirm:",
+        &form.confirm_password,
+        form.focused_field == 2,
+    );
+
+    let instructions = Line::from(vec![
+        Span::styled("Tab", Style::default().fg(Color::Cyan)),
+        Span::raw(": Next  "),
+        Span::styled("Enter", Style::default().fg(Color::Cyan)),
+        Span::raw(": Submit  "),
+        Span::styled("Esc", Style::default().fg(Color::Cyan)),
+        Span::raw(": Cancel"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::DarkGray));
+    frame.render_widget(instructions_para, chunks[4]);
+}
+
+/// Render confirmation dialog
+fn render_confirm_delete(frame: &mut Frame, message: &str, _entity_type: EntityType) {
+    let area = centered_rect_fixed(50, 8, frame.area());
+
+    let block = Block::default()
+        .title(" Confirm Delete ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Red))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Message
+        Constraint::Min(1),    // Spacer
+        Constraint::Length(1), // Buttons
+    ])
+    .split(inner);
+
+    let message_para = Paragraph::new(message)
+        .style(Style::default().fg(Color::White))
+        .alignment(Alignment::Center);
+    frame.render_widget(message_para, chunks[0]);
+
+    let buttons = Line::from(vec![
+        Span::styled("[y]", Style::default().fg(Color::Red)),
+        Span::raw(" Yes, Delete  "),
+        Span::styled("[n]", Style::default().fg(Color::Green)),
+        Span::raw(" No, Cancel"),
+    ]);
+    let buttons_para = Paragraph::new(buttons)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(buttons_para, chunks[2]);
+}
+
+/// Render the prompt shown when a name matches an existing item
+fn render_confirm_duplicate(frame: &mut Frame, entity_type: EntityType, existing_name: &str) {
+    let area = centered_rect_fixed(55, 9, frame.area());
+
+    let block = Block::default()
+        .title(" Already Exists ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Yellow))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Existing name
+        Constraint::Length(2), // Description
+        Constraint::Min(1),    // Spacer
+        Constraint::Length(1), // Buttons
+    ])
+    .split(inner);
+
+    let name_para = Paragraph::new(format!(
+        "{} '{}' already exists.",
+        entity_type.as_str(),
+        existing_name
+    ))
+    .style(Style::default().fg(Color::White))
+    .alignment(Alignment::Center);
+    frame.render_widget(name_para, chunks[0]);
+
+    let desc_para = Paragraph::new("Names are compared ignoring case and spacing.")
+        .style(Style::default().fg(Color::DarkGray))
+        .alignment(Alignment::Center);
+    frame.render_widget(desc_para, chunks[1]);
+
+    let buttons = Line::from(vec![
+        Span::styled("[y]", Style::default().fg(Color::Yellow)),
+        Span::raw(" Jump to existing  "),
+        Span::styled("[n]", Style::default().fg(Color::DarkGray)),
+        Span::raw(" Keep editing"),
+    ]);
+    let buttons_para = Paragraph::new(buttons)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(buttons_para, chunks[3]);
+}
+
+/// Render the list of merge targets for a category, period or income type
+fn render_merge_select(
+    frame: &mut Frame,
+    entity_type: EntityType,
+    source_name: &str,
+    targets: &[(i32, String)],
+    selected: usize,
+) {
+    let height = (targets.len() as u16 + 6).min(20);
+    let area = centered_rect_fixed(50, height, frame.area());
+
+    let block = Block::default()
+        .title(format!(" Merge {} ", entity_type.as_str()))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Source
+        Constraint::Min(1),    // Targets
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let source_para = Paragraph::new(format!("Merge '{}' into:", source_name))
+        .style(Style::default().fg(Color::White))
+        .alignment(Alignment::Center);
+    frame.render_widget(source_para, chunks[0]);
+
+    // Keep the selection visible in long lists
+    let visible = chunks[1].height as usize;
+    let skip = (selected + 1).saturating_sub(visible);
+    let lines: Vec<Line> = targets
+        .iter()
+        .enumerate()
+        .skip(skip)
+        .map(|(i, (_, name))| {
+            if i == selected {
+                Line::from(vec![
+                    Span::styled(" > ", Style::default().fg(Color::Cyan)),
+                    Span::styled(
+                        name.as_str(),
+                        Style::default()
+                            .fg(Color::White)
+                            .add_modifier(Modifier::BOLD),
+                    ),
+                ])
+            } else {
+                Line::from(vec![
+                    Span::raw("   "),
+                    Span::styled(name.as_str(), Style::default().fg(Color::Gray)),
+                ])
+            }
+        })
+        .collect();
+    frame.render_widget(Paragraph::new(lines), chunks[1]);
+
+    let instructions = Line::from(vec![
+        Span::styled("Enter", Style::default().fg(Color::Green)),
+        Span::raw(": Preview  "),
+        Span::styled("j/k", Style::default().fg(Color::Cyan)),
+        Span::raw(": Move  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Cancel"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(instructions_para, chunks[2]);
+}
+
+/// Render the merge preview with the rows that will move
+fn render_confirm_merge(
+    frame: &mut Frame,
+    source_name: &str,
+    target_name: &str,
+    preview: &MergePreview,
+    money: &MoneyFormat,
+) {
+    const MAX_ROWS: usize = 10;
+
+    let shown = preview.rows.len().min(MAX_ROWS);
+    let more = preview.rows.len() - shown;
+    let height = shown as u16 + if more > 0 { 1 } else { 0 } + 8;
+    let area = centered_rect_fixed(70, height, frame.area());
+
+    let border_color = if preview.is_blocked() {
+        Color::Red
+    } else {
+        Color::Yellow
+    };
+    let block = Block::default()
+        .title(" Confirm Merge ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(border_color))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Summary
+        Constraint::Min(1),    // Rows
+        Constraint::Length(2), // Warning
+        Constraint::Length(1), // Buttons
+    ])
+    .split(inner);
+
+    let summary = Line::from(vec![
+        Span::styled(
+            format!("'{}'", source_name),
+            Style::default().fg(Color::White),
+        ),
+        Span::raw(" → "),
+        Span::styled(
+            format!("'{}'", target_name),
+            Style::default().fg(Color::White),
+        ),
+        Span::styled(
+            format!(
+                "  {} expense(s), {} income(s) in {} month(s)",
+                preview.expense_count(),
+                preview.income_count(),
+                preview.month_count()
+            ),
+            Style::default().fg(Color::DarkGray),
+        ),
+    ]);
+    frame.render_widget(
+        Paragraph::new(summary).alignment(Alignment::Center),
+        chunks[0],
+    );
+
+    let mut lines: Vec<Line> = preview
+        .rows
+        .iter()
+        .take(MAX_ROWS)
+        .map(|row| {
+            let row_style = if row.month_closed {
+                Style::default().fg(Color::Red)
+            } else {
+                Style::default().fg(Color::Gray)
+            };
+            Line::from(vec![
+                Span::styled(format!(" {:8}", row.kind.as_str()), row_style),
+                Span::styled(format!("{:24}", row.label), row_style),
+                Span::styled(format!("{:16}", row.month_name), row_style),
+                Span::styled(format!("{:>12}", money.format(row.amount)), row_style),
+            ])
+        })
+        .collect();
+    if more > 0 {
+        lines.push(Line::from(Span::styled(
+            format!(" ... and {} more", more),
+            Style::default().fg(Color::DarkGray),
+        )));
+    }
+    if preview.rows.is_empty() {
+        lines.push(Line::from(Span::styled(
+            " Nothing references it; it will just be deleted.",
+            Style::default().fg(Color::DarkGray),
+        )));
+    }
+    frame.render_widget(Paragraph::new(lines), chunks[1]);
+
+    let warning = if preview.is_blocked() {
+        Paragraph::new(format!(
+            "{} row(s) are in closed months. Reopen them before merging.",
+            preview.closed_count()
+        ))
+        .style(Style::default().fg(Color::Red))
+    } else {
+        Paragraph::new(format!(
+            "'{}' will be deleted after its rows move.",
+            source_name
+        ))
+        .style(Style::default().fg(Color::DarkGray))
+    };
+    frame.render_widget(warning.alignment(Alignment::Center), chunks[2]);
+
+    let buttons = Line::from(vec![
+        Span::styled("[y]", Style::default().fg(border_color)),
+        Span::raw(" Yes, Merge  "),
+        Span::styled("[n]", Style::default().fg(Color::DarkGray)),
+        Span::raw(" No, Cancel"),
+    ]);
+    let buttons_para = Paragraph::new(buttons)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(buttons_para, chunks[3]);
+}
+
+/// Render pay confirmation dialog with editable amount
+fn render_confirm_pay(frame: &mut Frame, expense_name: &str, projected: f64, amount_input: &str) {
+    let area = centered_rect_fixed(50, 11, frame.area());
+
+    let block = Block::default()
+        .title(" Pay Expense ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Green))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Expense name
+        Constraint::Length(2), // Amount input
+        Constraint::Length(1), // Projected hint
+        Constraint::Min(1),    // Spacer
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let name_para = Paragraph::new(format!("Add payment to '{}'", expense_name))
+        .style(Style::default().fg(Color::White))
+        .alignment(Alignment::Center);
+    frame.render_widget(name_para, chunks[0]);
+
+    // Editable amount input
     let amount_display = if amount_input.is_empty() {
         "0.00".to_string()
     } else {
-        amount_input.to_string()
+        amount_input.to_string()
+    };
+    let amount_line = Line::from(vec![
+        Span::styled("Amount: $", Style::default().fg(Color::DarkGray)),
+        Span::styled(
+            &amount_display,
+            Style::default()
+                .fg(Color::Green)
+                .add_modifier(Modifier::BOLD),
+        ),
+        Span::styled("_", Style::default().fg(Color::Green)), // Cursor
+    ]);
+    let amount_para = Paragraph::new(amount_line).alignment(Alignment::Center);
+    frame.render_widget(amount_para, chunks[1]);
+
+    // Projected hint
+    let hint_para = Paragraph::new(format!("Projected: ${:.2}", projected))
+        .style(Style::default().fg(Color::DarkGray))
+        .alignment(Alignment::Center);
+    frame.render_widget(hint_para, chunks[2]);
+
+    // Instructions
+    let instructions = Line::from(vec![
+        Span::styled("Enter", Style::default().fg(Color::Green)),
+        Span::raw(": Pay  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Cancel  "),
+        Span::styled("Type", Style::default().fg(Color::Cyan)),
+        Span::raw(": Edit amount"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(instructions_para, chunks[4]);
+}
+
+/// Render close/open month confirmation dialog
+fn render_confirm_close_month(frame: &mut Frame, month_name: &str, is_closing: bool) {
+    let area = centered_rect_fixed(55, 9, frame.area());
+
+    let (title, border_color, action_text, description) = if is_closing {
+        (
+            " Close Month ",
+            Color::Yellow,
+            "Close",
+            "This will prevent adding or editing expenses and incomes.",
+        )
+    } else {
+        (
+            " Reopen Month ",
+            Color::Green,
+            "Reopen",
+            "This will allow adding and editing expenses and incomes.",
+        )
+    };
+
+    let block = Block::default()
+        .title(title)
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(border_color))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Month name
+        Constraint::Length(2), // Description
+        Constraint::Min(1),    // Spacer
+        Constraint::Length(1), // Buttons
+    ])
+    .split(inner);
+
+    let name_para = Paragraph::new(format!("{} '{}'?", action_text, month_name))
+        .style(Style::default().fg(Color::White))
+        .alignment(Alignment::Center);
+    frame.render_widget(name_para, chunks[0]);
+
+    let desc_para = Paragraph::new(description)
+        .style(Style::default().fg(Color::DarkGray))
+        .alignment(Alignment::Center);
+    frame.render_widget(desc_para, chunks[1]);
+
+    let buttons = Line::from(vec![
+        Span::styled("[y]", Style::default().fg(border_color)),
+        Span::raw(format!(" Yes, {}  ", action_text)),
+        Span::styled("[n]", Style::default().fg(Color::DarkGray)),
+        Span::raw(" No, Cancel"),
+    ]);
+    let buttons_para = Paragraph::new(buttons)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(buttons_para, chunks[3]);
+}
+
+/// Render the prompt to create a month that doesn't exist yet
+fn render_rollover(
+    frame: &mut Frame,
+    month_name: &str,
+    previous: Option<&(i32, String)>,
+    template: Option<&(i32, String)>,
+) {
+    let area = centered_rect_fixed(55, 10, frame.area());
+
+    let block = Block::default()
+        .title(" New Month ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Green))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Month name
+        Constraint::Min(3),    // Options
+        Constraint::Length(1), // Buttons
+    ])
+    .split(inner);
+
+    let name_para = Paragraph::new(format!("{} hasn't been created yet.", month_name))
+        .style(Style::default().fg(Color::White))
+        .alignment(Alignment::Center);
+    frame.render_widget(name_para, chunks[0]);
+
+    let option = |key: &'static str, text: String| {
+        Line::from(vec![
+            Span::styled(key, Style::default().fg(Color::Green)),
+            Span::raw(format!(" {}", text)),
+        ])
+    };
+    let mut options = Vec::new();
+    if let Some((_, name)) = previous {
+        options.push(option("[c]", format!("Clone {}", name)));
+        options.push(option(
+            "[a]",
+            format!("Clone {}, budgets from recent spending", name),
+        ));
+    }
+    if let Some((_, name)) = template {
+        options.push(option("[t]", format!("Apply template {}", name)));
+    }
+    options.push(option("[e]", "Start empty".to_string()));
+    let options_para = Paragraph::new(options)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(options_para, chunks[1]);
+
+    let hint = Paragraph::new("Amounts start at zero. Esc: not now")
+        .style(Style::default().fg(Color::DarkGray))
+        .alignment(Alignment::Center);
+    frame.render_widget(hint, chunks[2]);
+}
+
+/// Render the budgets autofill would set, next to the current ones
+fn render_autofill(frame: &mut Frame, preview: &AutofillPreview, money: &MoneyFormat) {
+    const MAX_ROWS: usize = 12;
+
+    let shown = preview.rows.len().min(MAX_ROWS);
+    let more = preview.rows.len() - shown;
+    let height = shown as u16 + if more > 0 { 1 } else { 0 } + 7;
+    let area = centered_rect_fixed(60, height, frame.area());
+
+    let block = Block::default()
+        .title(" Autofill Budgets ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Summary
+        Constraint::Min(1),    // Rows
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Buttons
+    ])
+    .split(inner);
+
+    let summary = Paragraph::new(format!(
+        "{} from the average of {} month(s) before",
+        preview.month_name, preview.months_used
+    ))
+    .style(Style::default().fg(Color::White))
+    .alignment(Alignment::Center);
+    frame.render_widget(summary, chunks[0]);
+
+    let mut lines: Vec<Line> = preview
+        .rows
+        .iter()
+        .take(MAX_ROWS)
+        .map(|row| {
+            let style = if row.changes() {
+                Style::default().fg(Color::White)
+            } else {
+                Style::default().fg(Color::DarkGray)
+            };
+            Line::from(vec![
+                Span::styled(format!(" {:24}", row.category), style),
+                Span::styled(format!("{:>12}", money.format(row.current)), style),
+                Span::raw(" → "),
+                Span::styled(format!("{:>12}", money.format(row.budget)), style),
+            ])
+        })
+        .collect();
+    if more > 0 {
+        lines.push(Line::from(Span::styled(
+            format!(" ... and {} more", more),
+            Style::default().fg(Color::DarkGray),
+        )));
+    }
+    frame.render_widget(Paragraph::new(lines), chunks[1]);
+
+    let buttons = Line::from(vec![
+        Span::styled("[y]", Style::default().fg(Color::Cyan)),
+        Span::raw(" Apply  "),
+        Span::styled("[n]", Style::default().fg(Color::DarkGray)),
+        Span::raw(" Cancel"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(buttons).alignment(Alignment::Center),
+        chunks[3],
+    );
+}
+
+/// Render the reallocation board: each category's remaining budget as a
+/// bar, and the money held between moves
+fn render_reallocate(frame: &mut Frame, board: &ReallocateBoard, money: &MoneyFormat) {
+    const MAX_ROWS: usize = 14;
+    const BAR_WIDTH: usize = 20;
+
+    let shown = board.rows.len().min(MAX_ROWS);
+    let area = centered_rect_fixed(76, shown as u16 + 8, frame.area());
+
+    let block = Block::default()
+        .title(format!(" Move Budget - {} ", board.month_name))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Held and step
+        Constraint::Min(1),    // Rows
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Buttons
+    ])
+    .split(inner);
+
+    let held_style = if board.is_balanced() {
+        Style::default().fg(Color::Green)
+    } else {
+        Style::default()
+            .fg(Color::Yellow)
+            .add_modifier(Modifier::BOLD)
+    };
+    let status = Line::from(vec![
+        Span::raw("Held: "),
+        Span::styled(money.format(board.held), held_style),
+        Span::raw("   Step: "),
+        Span::styled(
+            money.format(board.step_amount()),
+            Style::default().fg(Color::Cyan),
+        ),
+    ]);
+    frame.render_widget(
+        Paragraph::new(status).alignment(Alignment::Center),
+        chunks[0],
+    );
+
+    // Bars are to scale with the largest budget on the board
+    let scale = board
+        .rows
+        .iter()
+        .map(|row| row.budget)
+        .fold(0.0_f64, f64::max);
+    let first = board.selected.saturating_sub(MAX_ROWS - 1);
+    let lines: Vec<Line> = board
+        .rows
+        .iter()
+        .enumerate()
+        .skip(first)
+        .take(MAX_ROWS)
+        .map(|(i, row)| {
+            let selected = i == board.selected;
+            let style = if selected {
+                Style::default()
+                    .fg(Color::White)
+                    .add_modifier(Modifier::BOLD)
+            } else if row.changes() {
+                Style::default().fg(Color::White)
+            } else {
+                Style::default().fg(Color::Gray)
+            };
+            let color = if row.remaining() < 0.0 {
+                Color::Red
+            } else if row.changes() {
+                Color::Cyan
+            } else {
+                Color::Green
+            };
+            let fraction = if scale > 0.0 {
+                row.remaining() / scale
+            } else {
+                0.0
+            };
+            let mut spans = vec![Span::styled(
+                format!("{} {:18} ", if selected { "▸" } else { " " }, row.category),
+                style,
+            )];
+            spans.extend(progress_bar(BAR_WIDTH, fraction, color, None).spans);
+            spans.push(Span::styled(
+                format!(
+                    " {:>11} of {:>11}",
+                    money.format(row.remaining()),
+                    money.format(row.budget)
+                ),
+                style,
+            ));
+            Line::from(spans)
+        })
+        .collect();
+    frame.render_widget(Paragraph::new(lines), chunks[1]);
+
+    let buttons = Line::from(vec![
+        Span::styled("[-/+]", Style::default().fg(Color::Cyan)),
+        Span::raw(" Take/give  "),
+        Span::styled("[s]", Style::default().fg(Color::Cyan)),
+        Span::raw(" Step  "),
+        Span::styled("[Enter]", Style::default().fg(Color::Cyan)),
+        Span::raw(" Apply  "),
+        Span::styled("[Esc]", Style::default().fg(Color::DarkGray)),
+        Span::raw(" Cancel"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(buttons).alignment(Alignment::Center),
+        chunks[3],
+    );
+}
+
+/// Render the issue a problem report would open, before opening it
+fn render_report_problem(frame: &mut Frame, body: &str) {
+    let area = centered_rect_fixed(76, 24, frame.area());
+
+    let block = Block::default()
+        .title(" Report a Problem ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Explanation
+        Constraint::Min(1),    // Body
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Buttons
+    ])
+    .split(inner);
+
+    let explanation = Paragraph::new(
+        "Opens a new issue with this filled in. Add what happened, check it, then submit.",
+    )
+    .style(Style::default().fg(Color::White))
+    .wrap(Wrap { trim: true });
+    frame.render_widget(explanation, chunks[0]);
+
+    let preview = Paragraph::new(body)
+        .style(Style::default().fg(Color::Gray))
+        .wrap(Wrap { trim: false });
+    frame.render_widget(preview, chunks[1]);
+
+    let buttons = Line::from(vec![
+        Span::styled("[Enter]", Style::default().fg(Color::Cyan)),
+        Span::raw(" Open in browser  "),
+        Span::styled("[c]", Style::default().fg(Color::Cyan)),
+        Span::raw(" Copy  "),
+        Span::styled("[Esc]", Style::default().fg(Color::DarkGray)),
+        Span::raw(" Cancel"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(buttons).alignment(Alignment::Center),
+        chunks[3],
+    );
+}
+
+/// Render the notes editor for a month, for the local scratchpad or the
+/// notes shared on the server
+fn render_notes(frame: &mut Frame, month_name: &str, text: &str, shared: bool) {
+    let area = centered_rect_fixed(60, 16, frame.area());
+
+    let (title, hint) = if shared {
+        ("Shared notes", "Shared with everyone using the budget")
+    } else {
+        ("Notes", "Stored locally, never sent to the server")
+    };
+    let block = Block::default()
+        .title(format!(" {} - {} ", title, month_name))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Min(1),    // Text
+        Constraint::Length(1), // Hint
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    // Editable text with cursor on the last line
+    let mut lines: Vec<Line> = text
+        .split('\n')
+        .map(|line| Line::from(Span::styled(line, Style::default().fg(Color::White))))
+        .collect();
+    if let Some(last) = lines.last_mut() {
+        last.spans
+            .push(Span::styled("_", Style::default().fg(Color::Cyan)));
+    }
+
+    // Keep the cursor visible once the text outgrows the box
+    let scroll = (lines.len() as u16).saturating_sub(chunks[0].height);
+    let text_para = Paragraph::new(lines)
+        .wrap(Wrap { trim: false })
+        .scroll((scroll, 0));
+    frame.render_widget(text_para, chunks[0]);
+
+    let hint_para = Paragraph::new(hint)
+        .style(Style::default().fg(Color::DarkGray))
+        .alignment(Alignment::Center);
+    frame.render_widget(hint_para, chunks[1]);
+
+    let instructions = Line::from(vec![
+        Span::styled("Enter", Style::default().fg(Color::Green)),
+        Span::raw(": New line  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Save & close"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(instructions_para, chunks[2]);
+}
+
+/// Render the prompt for a CSV to fill an empty month from
+fn render_import_csv(frame: &mut Frame, month_name: &str, path: &str) {
+    let area = centered_rect_fixed(64, 8, frame.area());
+
+    let block = Block::default()
+        .title(format!(" Import into {} ", month_name))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Path
+        Constraint::Length(1), // Spacer
+        Constraint::Length(2), // Hint
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let field = Line::from(vec![
+        Span::styled("CSV file: ", Style::default().fg(Color::Gray)),
+        Span::styled(path, Style::default().fg(Color::White)),
+        Span::styled("_", Style::default().fg(Color::Cyan)),
+    ]);
+    frame.render_widget(Paragraph::new(field), chunks[0]);
+
+    let hint = Paragraph::new(
+        "Columns as for --import; only the rows of this month are added, \
+         the others are skipped",
+    )
+    .style(Style::default().fg(Color::DarkGray))
+    .alignment(Alignment::Center)
+    .wrap(Wrap { trim: true });
+    frame.render_widget(hint, chunks[2]);
+
+    let instructions = Line::from(vec![
+        Span::styled("Enter", Style::default().fg(Color::Green)),
+        Span::raw(": Import  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Cancel"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(instructions_para, chunks[3]);
+}
+
+/// Render the shell export lines for the server settings
+fn render_env_export(frame: &mut Frame, url: &str, api_key: &str, reveal_key: bool) {
+    let exports = config::env_exports(url, api_key, !reveal_key);
+    let width = exports
+        .iter()
+        .map(|line| line.chars().count() as u16 + 4)
+        .max()
+        .unwrap_or(0)
+        .clamp(50, 100);
+    let area = centered_rect_fixed(width, 9, frame.area());
+
+    let block = Block::default()
+        .title(" Environment Setup ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(exports.len() as u16), // Export lines
+        Constraint::Length(1),                    // Spacer
+        Constraint::Length(2),                    // Hint
+        Constraint::Length(1),                    // Instructions
+    ])
+    .horizontal_margin(1)
+    .split(inner);
+
+    let lines: Vec<Line> = exports
+        .into_iter()
+        .map(|line| Line::from(Span::styled(line, Style::default().fg(Color::Green))))
+        .collect();
+    frame.render_widget(Paragraph::new(lines), chunks[0]);
+
+    let hint = if reveal_key {
+        "Paste into a shell or CI script to use this server"
+    } else {
+        "API key is masked; press s to show it before copying"
     };
-    let amount_line = Line::from(vec![
-        Span::styled("Amount: $", Style::default().fg(Color::DarkGray)),
+    let hint_para = Paragraph::new(hint)
+        .style(Style::default().fg(Color::DarkGray))
+        .alignment(Alignment::Center)
+        .wrap(Wrap { trim: true });
+    frame.render_widget(hint_para, chunks[2]);
+
+    let instructions = Line::from(vec![
+        Span::styled("c", Style::default().fg(Color::Green)),
+        Span::raw(": Copy  "),
+        Span::styled("s", Style::default().fg(Color::Cyan)),
+        Span::raw(if reveal_key {
+            ": Mask key  "
+        } else {
+            ": Show key  "
+        }),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Close"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(instructions_para, chunks[3]);
+}
+
+/// Render a read-only share link for a month
+fn render_share_link(frame: &mut Frame, month_name: &str, url: &str, expires_at: &str) {
+    let width = (url.chars().count() as u16 + 4).clamp(50, 100);
+    // Long links wrap over several lines
+    let url_lines = (url.chars().count() as u16).div_ceil(width - 4).max(1);
+    let area = centered_rect_fixed(width, url_lines + 8, frame.area());
+
+    let block = Block::default()
+        .title(format!(" Share {} ", month_name))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(url_lines), // Link
+        Constraint::Length(1),         // Spacer
+        Constraint::Length(3),         // Hint
+        Constraint::Length(1),         // Instructions
+    ])
+    .horizontal_margin(1)
+    .split(inner);
+
+    let link = Paragraph::new(url)
+        .style(Style::default().fg(Color::Green))
+        .wrap(Wrap { trim: false });
+    frame.render_widget(link, chunks[0]);
+
+    let hint = format!(
+        "Anyone with the link can view this month's summary, without logging in, until {}",
+        expires_at
+    );
+    let hint_para = Paragraph::new(hint)
+        .style(Style::default().fg(Color::DarkGray))
+        .alignment(Alignment::Center)
+        .wrap(Wrap { trim: true });
+    frame.render_widget(hint_para, chunks[2]);
+
+    let instructions = Line::from(vec![
+        Span::styled("c", Style::default().fg(Color::Green)),
+        Span::raw(": Copy  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Close"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(instructions_para, chunks[3]);
+}
+
+/// Render the monthly routine checklist
+fn render_checklist(
+    frame: &mut Frame,
+    month_name: &str,
+    items: &[(String, bool)],
+    selected: usize,
+) {
+    let done_count = items.iter().filter(|(_, done)| *done).count();
+    let all_done = done_count == items.len();
+    let height = (items.len() as u16 + 6).min(20);
+    let area = centered_rect_fixed(50, height, frame.area());
+
+    let block = Block::default()
+        .title(format!(
+            " Checklist - {} ({}/{}) ",
+            month_name,
+            done_count,
+            items.len()
+        ))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(if all_done { Color::Green } else { Color::Cyan }))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Min(1),    // Items
+        Constraint::Length(1), // Status
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let lines: Vec<Line> = items
+        .iter()
+        .enumerate()
+        .map(|(i, (label, done))| {
+            let is_selected = i == selected;
+            let (mark, mark_color) = if *done {
+                ("[x] ", Color::Green)
+            } else {
+                ("[ ] ", Color::DarkGray)
+            };
+            let label_style = if *done {
+                Style::default()
+                    .fg(Color::DarkGray)
+                    .add_modifier(Modifier::CROSSED_OUT)
+            } else {
+                Style::default().fg(Color::White)
+            };
+            let label_style = if is_selected {
+                label_style.add_modifier(Modifier::BOLD).bg(Color::DarkGray)
+            } else {
+                label_style
+            };
+            Line::from(vec![
+                Span::raw(if is_selected { " > " } else { "   " }),
+                Span::styled(mark, Style::default().fg(mark_color)),
+                Span::styled(label.as_str(), label_style),
+            ])
+        })
+        .collect();
+    frame.render_widget(Paragraph::new(lines), chunks[0]);
+
+    let status = if all_done {
+        Paragraph::new("All done for this month!").style(Style::default().fg(Color::Green))
+    } else {
+        Paragraph::new(format!("{} to go", items.len() - done_count))
+            .style(Style::default().fg(Color::DarkGray))
+    };
+    frame.render_widget(status.alignment(Alignment::Center), chunks[1]);
+
+    let instructions = Line::from(vec![
+        Span::styled("Space", Style::default().fg(Color::Green)),
+        Span::raw(": Toggle  "),
+        Span::styled("j/k", Style::default().fg(Color::Cyan)),
+        Span::raw(": Move  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Close"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(instructions_para, chunks[2]);
+}
+
+/// Render the session's actions, newest first
+fn render_history(frame: &mut Frame, entries: &[(String, String)], selected: usize) {
+    let height = (entries.len().max(1) as u16 + 4).min(24);
+    let area = centered_rect_fixed(64, height, frame.area());
+
+    let block = Block::default()
+        .title(" History ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Min(1),    // Entries
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let lines: Vec<Line> = if entries.is_empty() {
+        vec![Line::from(Span::styled(
+            "   Nothing done yet this session",
+            Style::default().fg(Color::DarkGray),
+        ))]
+    } else {
+        // Keep the selection in view when the list is taller than the panel
+        let visible = chunks[0].height as usize;
+        let skip = (selected + 1).saturating_sub(visible);
+        entries
+            .iter()
+            .enumerate()
+            .skip(skip)
+            .map(|(i, (at, label))| {
+                let is_selected = i == selected;
+                let label_style = if is_selected {
+                    Style::default()
+                        .fg(Color::White)
+                        .add_modifier(Modifier::BOLD)
+                        .bg(Color::DarkGray)
+                } else {
+                    Style::default().fg(Color::White)
+                };
+                Line::from(vec![
+                    Span::raw(if is_selected { " > " } else { "   " }),
+                    Span::styled(format!("{} ", at), Style::default().fg(Color::DarkGray)),
+                    Span::styled(label.as_str(), label_style),
+                ])
+            })
+            .collect()
+    };
+    frame.render_widget(Paragraph::new(lines), chunks[0]);
+
+    let instructions = Line::from(vec![
+        Span::styled("Enter", Style::default().fg(Color::Green)),
+        Span::raw(": Repeat  "),
+        Span::styled("j/k", Style::default().fg(Color::Cyan)),
+        Span::raw(": Move  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Close"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(instructions).alignment(Alignment::Center),
+        chunks[1],
+    );
+}
+
+/// Render the reimbursement tracker
+fn render_reimbursements(
+    frame: &mut Frame,
+    report: &ReimbursementReport,
+    selected: usize,
+    money: &MoneyFormat,
+    dates: DateFormat,
+) {
+    let height = (report.rows.len() as u16 + 7).min(24);
+    let area = centered_rect_fixed(76, height, frame.area());
+    let outstanding = report.outstanding();
+
+    let block = Block::default()
+        .title(" Reimbursements ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(if outstanding > 0.0 {
+            Color::Yellow
+        } else {
+            Color::Green
+        }))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Totals
+        Constraint::Length(1), // Spacer
+        Constraint::Min(1),    // Rows
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let totals = Line::from(vec![
+        Span::raw("Outstanding: "),
+        Span::styled(
+            money.format(outstanding),
+            Style::default()
+                .fg(Color::Yellow)
+                .add_modifier(Modifier::BOLD),
+        ),
+        Span::raw("   Reimbursed: "),
+        Span::styled(
+            money.format(report.reimbursed()),
+            Style::default().fg(Color::Green),
+        ),
+    ]);
+    frame.render_widget(
+        Paragraph::new(totals).alignment(Alignment::Center),
+        chunks[0],
+    );
+
+    // Keep the selected row in view
+    let visible = chunks[2].height as usize;
+    let offset = (selected + 1).saturating_sub(visible);
+    let lines: Vec<Line> = report
+        .rows
+        .iter()
+        .enumerate()
+        .skip(offset)
+        .take(visible)
+        .map(|(i, row)| {
+            let is_selected = i == selected;
+            let (status, status_color) = match (&row.ledger, &row.reimbursed_on) {
+                (Ledger::Reimbursable, Some(date)) => {
+                    (format!("Paid {}", dates.format_text(date)), Color::Green)
+                }
+                (Ledger::Reimbursable, None) => ("Owed".to_string(), Color::Yellow),
+                _ => ("Business".to_string(), Color::Blue),
+            };
+            let row_style = if is_selected {
+                Style::default()
+                    .fg(Color::White)
+                    .add_modifier(Modifier::BOLD)
+                    .bg(Color::DarkGray)
+            } else {
+                Style::default().fg(Color::White)
+            };
+            Line::from(vec![
+                Span::raw(if is_selected { " > " } else { "   " }),
+                Span::styled(format!("{:<14}", row.month_name), row_style),
+                Span::styled(format!("{:<24.24}", row.expense_name), row_style),
+                Span::styled(format!("{:>12}", money.format(row.amount)), row_style),
+                Span::raw("  "),
+                Span::styled(status, Style::default().fg(status_color)),
+            ])
+        })
+        .collect();
+    frame.render_widget(Paragraph::new(lines), chunks[2]);
+
+    let instructions = Line::from(vec![
+        Span::styled("Space", Style::default().fg(Color::Green)),
+        Span::raw(": Mark reimbursed  "),
+        Span::styled("j/k", Style::default().fg(Color::Cyan)),
+        Span::raw(": Move  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Close"),
+    ]);
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(instructions_para, chunks[4]);
+}
+
+/// Render an expense with its purchases and attachments, with the purchase
+/// and the attachment picked highlighted
+fn render_expense_detail(
+    frame: &mut Frame,
+    expense: &Expense,
+    (selected, file): (usize, usize),
+    confirm_remove: bool,
+    money: &MoneyFormat,
+    dates: DateFormat,
+) {
+    let purchases = expense.purchases.as_deref().unwrap_or_default();
+    let attachments = expense.attachments.as_deref().unwrap_or_default();
+    let height = (purchases.len().max(1) as u16 + 10).min(24);
+    let area = centered_rect_fixed(76, height, frame.area());
+    let total = purchases.iter().map(|p| p.amount).sum::<Money>().to_f64();
+    let sums = forms::sums_purchases(expense.cost, Some(purchases));
+
+    let block = Block::default()
+        .title(format!(" {} ", expense.expense_name))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Period and category
+        Constraint::Length(1), // Amounts
+        Constraint::Length(1), // Attachments
+        Constraint::Length(1), // Spacer
+        Constraint::Min(1),    // Purchases
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let place = Line::from(Span::styled(
+        format!("{} · {}", expense.period, expense.category),
+        Style::default().fg(Color::DarkGray),
+    ));
+    frame.render_widget(
+        Paragraph::new(place).alignment(Alignment::Center),
+        chunks[0],
+    );
+
+    let mut amounts = vec![
+        Span::raw("Projected: "),
+        Span::styled(
+            money.format(expense.projected),
+            Style::default().fg(Color::Cyan),
+        ),
+        Span::raw("   Cost: "),
         Span::styled(
-            &amount_display,
-            Style::default()
-                .fg(Color::Green)
-                .add_modifier(Modifier::BOLD),
+            money.format(expense.cost),
+            Style::default().fg(Color::White),
         ),
-        Span::styled("_", Style::default().fg(Color::Green)), // Cursor
+        Span::raw("   Purchases: "),
+        Span::styled(money.format(total), Style::default().fg(Color::Green)),
+    ];
+    if !sums {
+        amounts.push(Span::styled(
+            "  (cost set apart)",
+            Style::default().fg(Color::Yellow),
+        ));
+    }
+    frame.render_widget(
+        Paragraph::new(Line::from(amounts)).alignment(Alignment::Center),
+        chunks[1],
+    );
+
+    let mut files = vec![Span::styled("Files: ", Style::default().fg(Color::Gray))];
+    if attachments.is_empty() {
+        files.push(Span::styled("none", Style::default().fg(Color::DarkGray)));
+    }
+    for (i, attachment) in attachments.iter().enumerate() {
+        let style = if i == file {
+            Style::default()
+                .fg(Color::White)
+                .add_modifier(Modifier::BOLD)
+                .bg(Color::DarkGray)
+        } else {
+            Style::default().fg(Color::Gray)
+        };
+        let size = attachment
+            .size
+            .map(|size| format!(" ({})", ui::format_size(size)))
+            .unwrap_or_default();
+        files.push(Span::styled(
+            format!("{}{}", attachment.file_name, size),
+            style,
+        ));
+        files.push(Span::raw("  "));
+    }
+    frame.render_widget(
+        Paragraph::new(Line::from(files)).alignment(Alignment::Center),
+        chunks[2],
+    );
+
+    // Keep the selected row in view
+    let visible = chunks[4].height as usize;
+    let offset = (selected + 1).saturating_sub(visible);
+    let lines: Vec<Line> = if purchases.is_empty() {
+        vec![Line::from(Span::styled(
+            "   No purchases yet",
+            Style::default().fg(Color::DarkGray),
+        ))]
+    } else {
+        purchases
+            .iter()
+            .enumerate()
+            .skip(offset)
+            .take(visible)
+            .map(|(i, purchase)| {
+                let is_selected = i == selected;
+                let row_style = if is_selected {
+                    Style::default()
+                        .fg(Color::White)
+                        .add_modifier(Modifier::BOLD)
+                        .bg(Color::DarkGray)
+                } else {
+                    Style::default().fg(Color::White)
+                };
+                let date = purchase
+                    .date
+                    .as_deref()
+                    .map(|date| dates.format_text(date))
+                    .unwrap_or_default();
+                Line::from(vec![
+                    Span::raw(if is_selected { " > " } else { "   " }),
+                    Span::styled(format!("{:<36.36}", purchase.name), row_style),
+                    Span::styled(format!("{:<12}", date), row_style),
+                    Span::styled(format!("{:>14}", money.format(purchase.amount)), row_style),
+                ])
+            })
+            .collect()
+    };
+    frame.render_widget(Paragraph::new(lines), chunks[4]);
+
+    let instructions = match purchases.get(selected) {
+        Some(purchase) if confirm_remove => Line::from(vec![
+            Span::styled("d", Style::default().fg(Color::Red)),
+            Span::raw(format!(": Remove '{}' for good  ", purchase.name)),
+            Span::styled("any key", Style::default().fg(Color::Yellow)),
+            Span::raw(": Keep it"),
+        ]),
+        _ => Line::from(vec![
+            Span::styled("a", Style::default().fg(Color::Green)),
+            Span::raw(": Add  "),
+            Span::styled("e", Style::default().fg(Color::Cyan)),
+            Span::raw(": Edit  "),
+            Span::styled("d", Style::default().fg(Color::Red)),
+            Span::raw(": Remove  "),
+            Span::styled("u", Style::default().fg(Color::Green)),
+            Span::raw(": Attach  "),
+            Span::styled("o", Style::default().fg(Color::Cyan)),
+            Span::raw(": Open file  "),
+            Span::styled("Esc", Style::default().fg(Color::Yellow)),
+            Span::raw(": Close"),
+        ]),
+    };
+    let instructions_para = Paragraph::new(instructions)
+        .alignment(Alignment::Center)
+        .style(Style::default().fg(Color::White));
+    frame.render_widget(instructions_para, chunks[6]);
+}
+
+/// Render the prompt for a file to attach to an expense
+fn render_attach_file(frame: &mut Frame, expense: &Expense, path: &str) {
+    let area = centered_rect_fixed(64, 8, frame.area());
+
+    let block = Block::default()
+        .title(format!(" Attach to {} ", expense.expense_name))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Path
+        Constraint::Length(1), // Spacer
+        Constraint::Length(2), // Hint
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let field = Line::from(vec![
+        Span::styled("File: ", Style::default().fg(Color::Gray)),
+        Span::styled(path, Style::default().fg(Color::White)),
+        Span::styled("_", Style::default().fg(Color::Cyan)),
     ]);
-    let amount_para = Paragraph::new(amount_line).alignment(Alignment::Center);
-    frame.render_widget(amount_para, chunks[1]);
+    frame.render_widget(Paragraph::new(field), chunks[0]);
 
-    // Projected hint
-    let hint_para = Paragraph::new(format!("Projected: ${:.2}", projected))
+    let hint = Paragraph::new("A receipt or other file, up to 10 MB, e.g. a photo or a PDF")
         .style(Style::default().fg(Color::DarkGray))
-        .alignment(Alignment::Center);
-    frame.render_widget(hint_para, chunks[2]);
+        .alignment(Alignment::Center)
+        .wrap(Wrap { trim: true });
+    frame.render_widget(hint, chunks[2]);
 
-    // Instructions
     let instructions = Line::from(vec![
         Span::styled("Enter", Style::default().fg(Color::Green)),
-        Span::raw(": Pay  "),
+        Span::raw(": Attach  "),
         Span::styled("Esc", Style::default().fg(Color::Yellow)),
-        Span::raw(": Cancel  "),
-        Span::styled("Type", Style::default().fg(Color::Cyan)),
-        Span::raw(": Edit amount"),
+        Span::raw(": Back"),
     ]);
     let instructions_para = Paragraph::new(instructions)
         .alignment(Alignment::Center)
         .style(Style::default().fg(Color::White));
-    frame.render_widget(instructions_para, chunks[4]);
+    frame.render_widget(instructions_para, chunks[3]);
 }
 
-/// Render close/open month confirmation dialog
-fn render_confirm_close_month(frame: &mut Frame, month_name: &str, is_closing: bool) {
-    let area = centered_rect_fixed(55, 9, frame.area());
+/// Render who created and last changed a record
+fn render_inspect(
+    frame: &mut Frame,
+    entity_type: EntityType,
+    name: &str,
+    audit: &AuditTrail,
+    dates: DateFormat,
+) {
+    let area = centered_rect_fixed(64, 8, frame.area());
 
-    let (title, border_color, action_text, description) = if is_closing {
-        (
-            " Close Month ",
-            Color::Yellow,
-            "Close",
-            "This will prevent adding or editing expenses and incomes.",
-        )
+    let block = Block::default()
+        .title(format!(" {}: {} ", entity_type.as_str(), name))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(2), // Created and updated
+        Constraint::Min(1),    // Spacer
+        Constraint::Length(1), // Instructions
+    ])
+    .horizontal_margin(1)
+    .split(inner);
+
+    let label = Style::default().fg(Color::Gray);
+    let lines: Vec<Line> = audit
+        .lines(dates)
+        .into_iter()
+        .map(|(name, value)| {
+            Line::from(vec![
+                Span::styled(format!("{:<10}", name), label),
+                Span::styled(value, Style::default().fg(Color::White)),
+            ])
+        })
+        .collect();
+    frame.render_widget(Paragraph::new(lines), chunks[0]);
+
+    let instructions = Line::from(vec![
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Close"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(instructions).alignment(Alignment::Center),
+        chunks[2],
+    );
+}
+
+/// Render the request latency panel
+fn render_performance(frame: &mut Frame, endpoints: &[EndpointMetrics]) {
+    let height = (endpoints.len().max(1) as u16 + 8).min(24);
+    let area = centered_rect_fixed(76, height, frame.area());
+
+    let block = Block::default()
+        .title(" Request Performance ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Header
+        Constraint::Min(1),    // Rows
+        Constraint::Length(1), // Spacer
+        Constraint::Length(2), // Hint
+        Constraint::Length(1), // Instructions
+    ])
+    .split(inner);
+
+    let heading = Style::default()
+        .fg(Color::Cyan)
+        .add_modifier(Modifier::BOLD);
+    frame.render_widget(
+        Paragraph::new(Line::from(Span::styled(
+            format!(
+                " {:<32} {:>6} {:>8} {:>8} {:>5} {:>5}",
+                "Endpoint", "Count", "Avg", "p95", "5xx", "Net"
+            ),
+            heading,
+        ))),
+        chunks[0],
+    );
+
+    let millis = |d: std::time::Duration| format!("{}ms", d.as_millis());
+    let count_style = |count: u64| {
+        if count > 0 {
+            Style::default().fg(Color::Red)
+        } else {
+            Style::default().fg(Color::DarkGray)
+        }
+    };
+    let lines: Vec<Line> = if endpoints.is_empty() {
+        vec![Line::from(Span::styled(
+            " No requests yet",
+            Style::default().fg(Color::DarkGray),
+        ))]
     } else {
-        (
-            " Reopen Month ",
-            Color::Green,
-            "Reopen",
-            "This will allow adding and editing expenses and incomes.",
-        )
+        endpoints
+            .iter()
+            .take(chunks[1].height as usize)
+            .map(|e| {
+                Line::from(vec![
+                    Span::raw(format!(
+                        " {:<32.32} {:>6} {:>8} {:>8}",
+                        e.endpoint,
+                        e.requests,
+                        millis(e.average),
+                        millis(e.p95)
+                    )),
+                    Span::styled(
+                        format!(" {:>5}", e.server_errors),
+                        count_style(e.server_errors),
+                    ),
+                    Span::styled(
+                        format!(" {:>5}", e.network_errors),
+                        count_style(e.network_errors),
+                    ),
+                ])
+            })
+            .collect()
     };
+    frame.render_widget(Paragraph::new(lines), chunks[1]);
+
+    let hint = Paragraph::new(
+        "Network errors point at the connection; slow answers and 5xx errors at the server.",
+    )
+    .style(Style::default().fg(Color::DarkGray))
+    .wrap(Wrap { trim: true });
+    frame.render_widget(hint, chunks[3]);
+
+    let instructions = Line::from(vec![
+        Span::styled("r", Style::default().fg(Color::Green)),
+        Span::raw(": Refresh  "),
+        Span::styled("c", Style::default().fg(Color::Cyan)),
+        Span::raw(": Reset  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Close"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(instructions).alignment(Alignment::Center),
+        chunks[4],
+    );
+}
+
+/// Render the release notes shown after an upgrade
+fn render_whats_new(frame: &mut Frame, releases: &[Release], scroll: usize) {
+    let area = centered_rect_fixed(64, 20, frame.area());
 
+    let title = match releases.first() {
+        Some(release) => format!(" What's new in v{} ", release.version),
+        None => " What's new ".to_string(),
+    };
     let block = Block::default()
         .title(title)
         .title_alignment(Alignment::Center)
         .borders(Borders::ALL)
-        .border_style(Style::default().fg(border_color))
+        .border_style(Style::default().fg(Color::Green))
         .style(Style::default().bg(Color::Rgb(30, 30, 35)));
 
     frame.render_widget(Clear, area);
@@ -898,38 +3108,167 @@ fn render_confirm_close_month(frame: &mut Frame, month_name: &str, is_closing: b
 
     let inner = block.inner(area);
     let chunks = Layout::vertical([
-        Constraint::Length(2), // Month name
-        Constraint::Length(2), // Description
-        Constraint::Min(1),    // Spacer
-        Constraint::Length(1), // Buttons
+        Constraint::Min(1),    // Notes
+        Constraint::Length(1), // Instructions
     ])
+    .horizontal_margin(1)
     .split(inner);
 
-    let name_para = Paragraph::new(format!("{} '{}'?", action_text, month_name))
-        .style(Style::default().fg(Color::White))
-        .alignment(Alignment::Center);
-    frame.render_widget(name_para, chunks[0]);
+    let mut lines = Vec::new();
+    for release in releases {
+        lines.push(Line::from(Span::styled(
+            format!("v{}", release.version),
+            Style::default()
+                .fg(Color::Green)
+                .add_modifier(Modifier::BOLD),
+        )));
+        for change in &release.changes {
+            lines.push(Line::from(vec![
+                Span::styled("• ", Style::default().fg(Color::Green)),
+                Span::raw(change.as_str()),
+            ]));
+        }
+        lines.push(Line::from(""));
+    }
+    if releases.is_empty() {
+        lines.push(Line::from(Span::styled(
+            "No release notes for this version",
+            Style::default().fg(Color::DarkGray),
+        )));
+    }
+    frame.render_widget(
+        Paragraph::new(lines)
+            .wrap(Wrap { trim: false })
+            .scroll((scroll as u16, 0)),
+        chunks[0],
+    );
 
-    let desc_para = Paragraph::new(description)
-        .style(Style::default().fg(Color::DarkGray))
-        .alignment(Alignment::Center);
-    frame.render_widget(desc_para, chunks[1]);
+    let instructions = Line::from(vec![
+        Span::styled("j/k", Style::default().fg(Color::Cyan)),
+        Span::raw(": Scroll  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Close"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(instructions).alignment(Alignment::Center),
+        chunks[1],
+    );
+}
 
-    let buttons = Line::from(vec![
-        Span::styled("[y]", Style::default().fg(border_color)),
-        Span::raw(format!(" Yes, {}  ", action_text)),
-        Span::styled("[n]", Style::default().fg(Color::DarkGray)),
-        Span::raw(" No, Cancel"),
+/// Render the debug panel
+fn render_debug(
+    frame: &mut Frame,
+    totals: &EndpointMetrics,
+    slowest: &[EndpointMetrics],
+    details: &[(String, String)],
+) {
+    let height = (details.len() + slowest.len().max(1)) as u16 + 11;
+    let area = centered_rect_fixed(68, height.min(28), frame.area());
+
+    let block = Block::default()
+        .title(" Debug ")
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Magenta))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, area);
+    frame.render_widget(block.clone(), area);
+
+    let inner = block.inner(area);
+    let chunks = Layout::vertical([
+        Constraint::Length(details.len() as u16), // Client state
+        Constraint::Length(1),                    // Spacer
+        Constraint::Length(2),                    // Totals
+        Constraint::Length(1),                    // Spacer
+        Constraint::Min(2),                       // Slowest endpoints
+        Constraint::Length(1),                    // Instructions
+    ])
+    .horizontal_margin(1)
+    .split(inner);
+
+    let label = Style::default().fg(Color::Gray);
+    let lines: Vec<Line> = details
+        .iter()
+        .map(|(name, value)| {
+            Line::from(vec![
+                Span::styled(format!("{:<16}", name), label),
+                Span::styled(value.as_str(), Style::default().fg(Color::White)),
+            ])
+        })
+        .collect();
+    frame.render_widget(Paragraph::new(lines), chunks[0]);
+
+    let millis = |d: std::time::Duration| format!("{}ms", d.as_millis());
+    let error_rate = totals.error_rate() * 100.0;
+    let rate_color = if totals.failures() == 0 {
+        Color::Green
+    } else if error_rate < 5.0 {
+        Color::Yellow
+    } else {
+        Color::Red
+    };
+    let totals_lines = vec![
+        Line::from(vec![
+            Span::styled(format!("{:<16}", "Requests"), label),
+            Span::raw(format!(
+                "{} ({} 5xx, {} network)",
+                totals.requests, totals.server_errors, totals.network_errors
+            )),
+        ]),
+        Line::from(vec![
+            Span::styled(format!("{:<16}", "Error rate"), label),
+            Span::styled(
+                format!("{:.1}%", error_rate),
+                Style::default().fg(rate_color),
+            ),
+            Span::styled("   avg ", label),
+            Span::raw(millis(totals.average)),
+            Span::styled("   p95 ", label),
+            Span::raw(millis(totals.p95)),
+        ]),
+    ];
+    frame.render_widget(Paragraph::new(totals_lines), chunks[2]);
+
+    let mut lines = vec![Line::from(Span::styled(
+        "Slowest endpoints (p95)",
+        Style::default()
+            .fg(Color::Magenta)
+            .add_modifier(Modifier::BOLD),
+    ))];
+    if slowest.is_empty() {
+        lines.push(Line::from(Span::styled(
+            "No requests yet",
+            Style::default().fg(Color::DarkGray),
+        )));
+    }
+    for e in slowest {
+        lines.push(Line::from(format!(
+            "{:<40.40} {:>8} {:>6}",
+            e.endpoint,
+            millis(e.p95),
+            e.requests
+        )));
+    }
+    frame.render_widget(Paragraph::new(lines), chunks[4]);
+
+    let instructions = Line::from(vec![
+        Span::styled("r", Style::default().fg(Color::Green)),
+        Span::raw(": Refresh  "),
+        Span::styled("c", Style::default().fg(Color::Cyan)),
+        Span::raw(": Reset  "),
+        Span::styled("Esc", Style::default().fg(Color::Yellow)),
+        Span::raw(": Close"),
     ]);
-    let buttons_para = Paragraph::new(buttons)
-        .alignment(Alignment::Center)
-        .style(Style::default().fg(Color::White));
-    frame.render_widget(buttons_para, chunks[3]);
+    frame.render_widget(
+        Paragraph::new(instructions).alignment(Alignment::Center),
+        chunks[5],
+    );
 }
 
 /// Render help overlay
 fn render_help(frame: &mut Frame) {
-    let area = centered_rect_fixed(60, 20, frame.area());
+    let area = centered_rect_fixed(60, 37, frame.area());
 
     let block = Block::default()
         .title(" Keyboard Shortcuts ")
@@ -954,6 +3293,14 @@ fn render_help(frame: &mut Frame) {
             Span::styled("  q / Ctrl+C", Style::default().fg(Color::Yellow)),
             Span::raw("  Quit application"),
         ]),
+        Line::from(vec![
+            Span::styled("  L", Style::default().fg(Color::Yellow)),
+            Span::raw("           Log out of this profile"),
+        ]),
+        Line::from(vec![
+            Span::styled("  K", Style::default().fg(Color::Yellow)),
+            Span::raw("           Lock the app"),
+        ]),
         Line::from(vec![
             Span::styled("  ?", Style::default().fg(Color::Yellow)),
             Span::raw("           Show this help"),
@@ -963,7 +3310,7 @@ fn render_help(frame: &mut Frame) {
             Span::raw("         Next tab"),
         ]),
         Line::from(vec![
-            Span::styled("  1-5", Style::default().fg(Color::Yellow)),
+            Span::styled("  1-6", Style::default().fg(Color::Yellow)),
             Span::raw("         Jump to tab"),
         ]),
         Line::from(""),
@@ -989,21 +3336,89 @@ fn render_help(frame: &mut Frame) {
             Span::styled("  n", Style::default().fg(Color::Yellow)),
             Span::raw("           Create new item"),
         ]),
+        Line::from(vec![
+            Span::styled("  f F a / g s", Style::default().fg(Color::Yellow)),
+            Span::raw(" Filter, search, group, sort (Alt+N clears)"),
+        ]),
         Line::from(vec![
             Span::styled("  d", Style::default().fg(Color::Yellow)),
             Span::raw("           Delete item"),
         ]),
+        Line::from(vec![
+            Span::styled("  i", Style::default().fg(Color::Yellow)),
+            Span::raw("           Who changed it and when"),
+        ]),
         Line::from(vec![
             Span::styled("  p", Style::default().fg(Color::Yellow)),
             Span::raw("           Pay expense"),
         ]),
         Line::from(vec![
-            Span::styled("  c", Style::default().fg(Color::Yellow)),
-            Span::raw("           Close/Open month"),
+            Span::styled("  c / U", Style::default().fg(Color::Yellow)),
+            Span::raw("       Close/Open month / Edit closed anyway"),
+        ]),
+        Line::from(vec![
+            Span::styled("  o / N", Style::default().fg(Color::Yellow)),
+            Span::raw("       Month notes: local / shared"),
+        ]),
+        Line::from(vec![
+            Span::styled("  x", Style::default().fg(Color::Yellow)),
+            Span::raw("           Monthly checklist"),
+        ]),
+        Line::from(vec![
+            Span::styled("  t / T", Style::default().fg(Color::Yellow)),
+            Span::raw("       Tax flag / Export tax report"),
+        ]),
+        Line::from(vec![
+            Span::styled("  X / E", Style::default().fg(Color::Yellow)),
+            Span::raw("       Export year to XLSX / month to CSV"),
+        ]),
+        Line::from(vec![
+            Span::styled("  G", Style::default().fg(Color::Yellow)),
+            Span::raw("           Push month to Google Sheets"),
+        ]),
+        Line::from(vec![
+            Span::styled("  S", Style::default().fg(Color::Yellow)),
+            Span::raw("           Share month (read-only link)"),
+        ]),
+        Line::from(vec![
+            Span::styled("  b / R", Style::default().fg(Color::Yellow)),
+            Span::raw("       Ledger / Reimbursements"),
+        ]),
+        Line::from(vec![
+            Span::styled("  | / Ctrl+W", Style::default().fg(Color::Yellow)),
+            Span::raw("  Split two months / Switch pane (M/Y move/copy)"),
+        ]),
+        Line::from(vec![
+            Span::styled("  m", Style::default().fg(Color::Yellow)),
+            Span::raw("           Merge (settings)"),
+        ]),
+        Line::from(vec![
+            Span::styled("  v / O", Style::default().fg(Color::Yellow)),
+            Span::raw("       Purchases, env setup (settings) / Open file"),
+        ]),
+        Line::from(vec![
+            Span::styled("  P", Style::default().fg(Color::Yellow)),
+            Span::raw("           Request performance"),
+        ]),
+        Line::from(vec![
+            Span::styled("  . / H", Style::default().fg(Color::Yellow)),
+            Span::raw("       Repeat last action / History"),
+        ]),
+        Line::from(vec![
+            Span::styled("  C / i", Style::default().fg(Color::Yellow)),
+            Span::raw("       Fill an empty month: copy last / CSV"),
+        ]),
+        Line::from(vec![
+            Span::styled("  A / B", Style::default().fg(Color::Yellow)),
+            Span::raw("       Budgets from recent spending / Move budget"),
+        ]),
+        Line::from(vec![
+            Span::styled("  z", Style::default().fg(Color::Yellow)),
+            Span::raw("           Large text"),
         ]),
         Line::from(""),
         Line::from(vec![Span::styled(
-            "Press any key to close",
+            "w: what's new  r: report a problem  any other key: close",
             Style::default().fg(Color::DarkGray),
         )]),
     ];
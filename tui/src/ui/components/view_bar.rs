@@ -0,0 +1,88 @@
+use ratatui::{
+    layout::{Constraint, Layout, Rect},
+    style::{Color, Modifier, Style},
+    text::{Line, Span},
+    widgets::{Block, Borders, Paragraph},
+    Frame,
+};
+
+use crate::state::{AppState, DashboardTab};
+
+/// Render the bar above the Expenses and Income tables
+///
+/// Each active filter, search, grouping and sort is a numbered chip that
+/// Alt+number removes; with none active, the keys that add them are listed.
+pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
+    let block = Block::default()
+        .borders(Borders::BOTTOM)
+        .border_style(Style::default().fg(Color::DarkGray));
+
+    let inner = block.inner(area);
+    frame.render_widget(block, area);
+
+    let chunks = Layout::horizontal([
+        Constraint::Min(10),    // Chips
+        Constraint::Length(15), // Add button hint
+    ])
+    .split(inner);
+
+    let chips = app.view_chips();
+    let line = if chips.is_empty() {
+        let keys: &[(&str, &str)] = if app.ui.selected_tab == DashboardTab::Expenses {
+            &[
+                ("f", "period"),
+                ("F", "category"),
+                ("/", "search"),
+                ("g", "group"),
+                ("s", "sort"),
+            ]
+        } else {
+            &[
+                ("f", "period"),
+                ("/", "search"),
+                ("g", "group"),
+                ("s", "sort"),
+            ]
+        };
+        let mut spans = vec![Span::raw(" ")];
+        for (key, action) in keys {
+            spans.push(Span::styled(*key, Style::default().fg(Color::Cyan)));
+            spans.push(Span::styled(
+                format!(" {}  ", action),
+                Style::default().fg(Color::DarkGray),
+            ));
+        }
+        Line::from(spans)
+    } else {
+        let mut spans = vec![Span::raw(" ")];
+        for (i, chip) in chips.iter().enumerate() {
+            spans.push(Span::styled(
+                format!("{}", i + 1),
+                Style::default()
+                    .fg(Color::Yellow)
+                    .add_modifier(Modifier::BOLD),
+            ));
+            spans.push(Span::styled(
+                format!(" {}", chip.label()),
+                Style::default().fg(Color::White),
+            ));
+            spans.push(Span::raw("  "));
+        }
+        if app.ui.searching {
+            spans.push(Span::styled(
+                "Enter keep  Esc clear",
+                Style::default().fg(Color::DarkGray),
+            ));
+        } else {
+            spans.push(Span::styled(
+                "Alt+N removes",
+                Style::default().fg(Color::DarkGray),
+            ));
+        }
+        Line::from(spans)
+    };
+    frame.render_widget(Paragraph::new(line), chunks[0]);
+
+    let add_hint = Paragraph::new("[n] Add New").style(Style::default().fg(Color::Cyan));
+    frame.render_widget(add_hint, chunks[1]);
+}
@@ -7,7 +7,9 @@ use ratatui::{
 };
 
 use super::components;
+use super::hex_to_color;
 use super::tabs;
+use crate::models::{MonthStatus, Scope};
 use crate::state::forms::{
     CategoryFormState, ExpenseFormState, IncomeFormState, IncomeTypeFormState, PasswordFormState,
     PeriodFormState,
@@ -63,6 +65,7 @@ pub fn render_with_forms(
         DashboardTab::Expenses => tabs::expenses::render(app, frame, chunks[2]),
         DashboardTab::Income => tabs::income::render(app, frame, chunks[2]),
         DashboardTab::Charts => tabs::charts::render(app, frame, chunks[2]),
+        DashboardTab::Debts => tabs::debts::render(app, frame, chunks[2]),
         DashboardTab::Settings => tabs::settings::render(app, frame, chunks[2]),
     }
 
@@ -81,15 +84,21 @@ pub fn render_with_forms(
             income_type_form,
             password_form,
             &app.data,
+            &app.period_display,
+            &app.money,
+            &app.rates,
+            app.dates,
         );
     }
 }
 
 /// Render the header with app title and month selector
 fn render_header(app: &AppState, frame: &mut Frame, area: Rect) {
+    // The profile's accent sets servers apart, e.g. a red line for production
+    let accent = app.profile_accent.as_deref().map(hex_to_color);
     let block = Block::default()
         .borders(Borders::BOTTOM)
-        .border_style(Style::default().fg(Color::DarkGray));
+        .border_style(Style::default().fg(accent.unwrap_or(Color::DarkGray)));
 
     let inner = block.inner(area);
     frame.render_widget(block, area);
@@ -98,7 +107,7 @@ fn render_header(app: &AppState, frame: &mut Frame, area: Rect) {
     let header_chunks = Layout::horizontal([
         Constraint::Length(20), // App title
         Constraint::Min(20),    // Spacer
-        Constraint::Length(30), // Month selector
+        Constraint::Length(32), // Month selector
         Constraint::Length(5),  // Help hint
     ])
     .split(inner);
@@ -111,28 +120,59 @@ fn render_header(app: &AppState, frame: &mut Frame, area: Rect) {
     );
     frame.render_widget(title, header_chunks[0]);
 
-    // Month selector with closed indicator
-    if let Some(month) = app.selected_month() {
-        let month_spans = if month.is_closed {
-            vec![
-                Span::raw("◀ "),
-                Span::styled(month.display_name(), Style::default().fg(Color::White)),
-                Span::raw(" "),
-                Span::styled(
-                    "[CLOSED]",
-                    Style::default()
-                        .fg(Color::Yellow)
-                        .add_modifier(Modifier::BOLD),
-                ),
-                Span::raw(" ▶"),
-            ]
+    let mut tags = Vec::new();
+    if let Some(profile) = &app.profile {
+        tags.push(Span::styled(
+            format!("[{}] ", profile),
+            Style::default()
+                .fg(accent.unwrap_or(Color::Gray))
+                .add_modifier(Modifier::BOLD),
+        ));
+    }
+    // A scoped API key hides what it can't change; say so
+    if let Some(scopes) = &app.data.key_scopes {
+        let tag = if app.key_is_read_only() {
+            Some("[READ-ONLY]")
+        } else if Scope::ALL.iter().any(|scope| !scopes.allows(*scope)) {
+            Some("[LIMITED KEY]")
         } else {
-            vec![
-                Span::raw("◀ "),
-                Span::styled(month.display_name(), Style::default().fg(Color::White)),
-                Span::raw(" ▶"),
-            ]
+            None
         };
+        if let Some(tag) = tag {
+            tags.push(Span::styled(tag, Style::default().fg(Color::Yellow)));
+        }
+    }
+    if !tags.is_empty() {
+        frame.render_widget(Paragraph::new(Line::from(tags)), header_chunks[1]);
+    }
+
+    // Month selector with closed indicator
+    if let Some(month) = app.selected_month() {
+        let mut month_spans = vec![
+            Span::raw("◀ "),
+            Span::styled(month.display_name(), Style::default().fg(Color::White)),
+        ];
+        if month.status() == MonthStatus::Closed {
+            // Unlocked while the user edits the closed month anyway
+            let (badge, color) = if app.selected_month_locked() {
+                ("[CLOSED]", Color::Yellow)
+            } else {
+                ("[UNLOCKED]", Color::Red)
+            };
+            month_spans.push(Span::raw(" "));
+            month_spans.push(Span::styled(
+                badge,
+                Style::default().fg(color).add_modifier(Modifier::BOLD),
+            ));
+        }
+        month_spans.push(Span::raw(" ▶"));
+        if app.notes.has_note(month.id) || month.shared_notes().is_some() {
+            // Badge before the trailing arrow when the month has notes
+            month_spans.insert(
+                month_spans.len() - 1,
+                Span::styled(" ✎", Style::default().fg(Color::Cyan)),
+            );
+        }
         let month_selector = Paragraph::new(Line::from(month_spans)).alignment(Alignment::Center);
         frame.render_widget(month_selector, header_chunks[2]);
     } else {
@@ -179,6 +219,9 @@ fn render_footer(app: &AppState, frame: &mut Frame, area: Rect) {
         DashboardTab::Summary => vec![
             ("h/l", "Month"),
             ("c", "Close/Open"),
+            ("o", "Notes"),
+            ("x", "Checklist"),
+            ("X", "XLSX"),
             ("Tab", "Tab"),
             ("q", "Quit"),
             ("?", "Help"),
@@ -187,8 +230,12 @@ fn render_footer(app: &AppState, frame: &mut Frame, area: Rect) {
             ("j/k", "Nav"),
             ("n", "New"),
             ("e", "Edit"),
+            ("v", "View"),
             ("d", "Del"),
             ("p", "Pay"),
+            ("t/T", "Tax"),
+            ("b/R", "Ledger"),
+            ("|", "Split"),
             ("c", "Close"),
             ("q", "Quit"),
         ],
@@ -197,6 +244,7 @@ fn render_footer(app: &AppState, frame: &mut Frame, area: Rect) {
             ("n", "New"),
             ("e", "Edit"),
             ("d", "Del"),
+            ("t/T", "Tax"),
             ("c", "Close"),
             ("Tab", "Tab"),
             ("q", "Quit"),
@@ -204,6 +252,17 @@ fn render_footer(app: &AppState, frame: &mut Frame, area: Rect) {
         DashboardTab::Charts => vec![
             ("h/l", "Month"),
             ("c", "Close/Open"),
+            ("o", "Notes"),
+            ("x", "Checklist"),
+            ("Tab", "Tab"),
+            ("q", "Quit"),
+        ],
+        DashboardTab::Debts => vec![
+            ("j/k", "Nav"),
+            ("n", "New"),
+            ("e", "Edit"),
+            ("d", "Del"),
+            ("p", "Pay"),
             ("Tab", "Tab"),
             ("q", "Quit"),
         ],
@@ -212,6 +271,8 @@ fn render_footer(app: &AppState, frame: &mut Frame, area: Rect) {
             ("n", "New"),
             ("e", "Edit"),
             ("d", "Delete"),
+            ("m", "Merge"),
+            ("v", "Env"),
             ("Tab", "Tab"),
             ("q", "Quit"),
         ],
@@ -219,6 +280,7 @@ fn render_footer(app: &AppState, frame: &mut Frame, area: Rect) {
 
     let spans: Vec<Span> = shortcuts
         .iter()
+        .filter(|(key, _)| shortcut_scope(app, key).map_or(true, |scope| app.key_allows(scope)))
         .flat_map(|(key, action)| {
             vec![
                 Span::styled(*key, Style::default().fg(Color::Cyan)),
@@ -231,3 +293,13 @@ fn render_footer(app: &AppState, frame: &mut Frame, area: Rect) {
     let footer = Paragraph::new(line).style(Style::default().fg(Color::DarkGray));
     frame.render_widget(footer, area);
 }
+
+/// Area a footer shortcut changes, to leave out the ones the API key can't use
+fn shortcut_scope(app: &AppState, key: &str) -> Option<Scope> {
+    match key {
+        "n" | "e" | "d" | "m" => app.edit_scope(),
+        "p" => Some(Scope::Expenses),
+        "c" => Some(Scope::Months),
+        _ => None,
+    }
+}
@@ -0,0 +1,55 @@
+//! How dates are shown
+//!
+//! The server sends dates as `YYYY-MM-DD` text. Everything on screen goes
+//! through `DateFormat`, so `[display] dates` picks day-first, month-first
+//! or ISO order everywhere; files written for other tools keep ISO dates.
+
+use chrono::{NaiveDate, NaiveDateTime};
+use serde::{Deserialize, Serialize};
+
+use crate::models::Month;
+
+/// Order of a date's parts
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum DateFormat {
+    /// `2024-03-31`
+    #[default]
+    Iso,
+    /// `31/03/2024`
+    Dmy,
+    /// `03/31/2024`
+    Mdy,
+}
+
+impl DateFormat {
+    /// A full date, e.g. `31/03/2024`
+    pub fn format(self, date: NaiveDate) -> String {
+        date.format(match self {
+            DateFormat::Iso => "%Y-%m-%d",
+            DateFormat::Dmy => "%d/%m/%Y",
+            DateFormat::Mdy => "%m/%d/%Y",
+        })
+        .to_string()
+    }
+
+    /// A date and time to the minute, e.g. `31/03/2024 14:05`
+    pub fn format_time(self, time: NaiveDateTime) -> String {
+        format!("{} {}", self.format(time.date()), time.format("%H:%M"))
+    }
+
+    /// A date the server or a local file gave as text; the text as is when
+    /// it doesn't start with `YYYY-MM-DD`
+    pub fn format_text(self, text: &str) -> String {
+        text.get(..10)
+            .and_then(|date| NaiveDate::parse_from_str(date, "%Y-%m-%d").ok())
+            .map(|date| self.format(date))
+            .unwrap_or_else(|| text.to_string())
+    }
+
+    /// First to last day of `month`, e.g. `01/03/2024 - 31/03/2024`
+    pub fn month_range(self, month: &Month) -> Option<String> {
+        let (start, end) = month.date_range()?;
+        Some(format!("{} - {}", self.format(start), self.format(end)))
+    }
+}
@@ -0,0 +1,171 @@
+use std::fmt::Write;
+
+use anyhow::Result;
+use ratatui::{
+    backend::TestBackend,
+    buffer::{Buffer, Cell},
+    style::{Color, Modifier},
+    Terminal,
+};
+
+use crate::state::AppState;
+use crate::ui::tabs;
+
+/// Width used when the terminal size can't be read (e.g. output is piped)
+pub const DEFAULT_WIDTH: u16 = 100;
+
+/// A view that can be printed into the scrollback with `--inline`
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum InlineView {
+    Summary,
+    Expenses,
+    Income,
+}
+
+impl InlineView {
+    pub const ALL: [InlineView; 3] = [
+        InlineView::Summary,
+        InlineView::Expenses,
+        InlineView::Income,
+    ];
+
+    /// Parse a view name as given on the command line
+    pub fn parse(name: &str) -> Option<Self> {
+        match name.to_lowercase().as_str() {
+            "summary" => Some(InlineView::Summary),
+            "expenses" => Some(InlineView::Expenses),
+            "income" | "incomes" => Some(InlineView::Income),
+            _ => None,
+        }
+    }
+
+    pub fn name(&self) -> &'static str {
+        match self {
+            InlineView::Summary => "summary",
+            InlineView::Expenses => "expenses",
+            InlineView::Income => "income",
+        }
+    }
+
+    /// Rows needed to show the whole view without scrolling
+    ///
+    /// Mirrors the layouts in `ui::tabs`: fixed panels plus one row per item
+    /// in each table (header, borders and the total row included).
+    pub fn height(&self, app: &AppState) -> u16 {
+        let table = |rows: usize| rows as u16 + 4;
+        match self {
+            InlineView::Summary => {
+                let insights = match app.data.insights {
+                    Some(ref insights) => insights.insights.len().max(1) as u16 + 4,
+                    None => 0,
+                };
+                let suggestions = app.end_of_month_suggestions(app.clock.today());
+                let suggestions = match tabs::summary::suggestions_height(suggestions.len()) {
+                    0 => 0,
+                    height => height + 1,
+                };
+                let tables = table(app.data.category_summary.len())
+                    .max(table(app.data.income_type_summary.len()))
+                    .max(8);
+                insights + suggestions + 7 + 1 + 10 + 1 + tables
+            }
+            // Filter bar plus the table
+            InlineView::Expenses => 3 + table(app.filtered_expenses().len()).max(5),
+            InlineView::Income => 3 + table(app.filtered_incomes().len()).max(5),
+        }
+    }
+}
+
+/// Render a view once into an off-screen buffer sized to fit its content
+pub fn render(app: &AppState, view: InlineView, width: u16) -> Result<Buffer> {
+    let mut terminal = Terminal::new(TestBackend::new(width, view.height(app)))?;
+    terminal.draw(|frame| {
+        let area = frame.area();
+        match view {
+            InlineView::Summary => tabs::summary::render(app, frame, area),
+            InlineView::Expenses => tabs::expenses::render(app, frame, area),
+            InlineView::Income => tabs::income::render(app, frame, area),
+        }
+    })?;
+    Ok(terminal.backend().buffer().clone())
+}
+
+/// Convert a rendered buffer into text for the scrollback
+///
+/// With `color`, styles are written as ANSI escape sequences; without it the
+/// output is plain text, suitable for pipes and files. Trailing blank cells
+/// are dropped from every line.
+pub fn to_ansi(buffer: &Buffer, color: bool) -> String {
+    let width = buffer.area.width as usize;
+    let mut output = String::new();
+    if width == 0 {
+        return output;
+    }
+
+    for row in buffer.content.chunks(width) {
+        let end = row
+            .iter()
+            .rposition(|cell| cell.symbol() != " " || cell.bg != Color::Reset)
+            .map_or(0, |last| last + 1);
+
+        let mut current: Option<String> = None;
+        for cell in &row[..end] {
+            if color {
+                let sgr = sgr(cell);
+                if current.as_ref() != Some(&sgr) {
+                    let _ = write!(output, "\x1b[0;{sgr}m");
+                    current = Some(sgr);
+                }
+            }
+            output.push_str(cell.symbol());
+        }
+        if color && current.is_some() {
+            output.push_str("\x1b[0m");
+        }
+        output.push('\n');
+    }
+    output
+}
+
+/// SGR parameters for a cell's colors and modifiers
+fn sgr(cell: &Cell) -> String {
+    let mut params = vec![color_code(cell.fg, false), color_code(cell.bg, true)];
+    for (modifier, code) in [
+        (Modifier::BOLD, "1"),
+        (Modifier::DIM, "2"),
+        (Modifier::ITALIC, "3"),
+        (Modifier::UNDERLINED, "4"),
+        (Modifier::REVERSED, "7"),
+        (Modifier::CROSSED_OUT, "9"),
+    ] {
+        if cell.modifier.contains(modifier) {
+            params.push(code.to_string());
+        }
+    }
+    params.join(";")
+}
+
+fn color_code(color: Color, background: bool) -> String {
+    let base = |code: u8| (if background { code + 10 } else { code }).to_string();
+    match color {
+        Color::Reset => base(39),
+        Color::Black => base(30),
+        Color::Red => base(31),
+        Color::Green => base(32),
+        Color::Yellow => base(33),
+        Color::Blue => base(34),
+        Color::Magenta => base(35),
+        Color::Cyan => base(36),
+        Color::Gray => base(37),
+        Color::DarkGray => base(90),
+        Color::LightRed => base(91),
+        Color::LightGreen => base(92),
+        Color::LightYellow => base(93),
+        Color::LightBlue => base(94),
+        Color::LightMagenta => base(95),
+        Color::LightCyan => base(96),
+        Color::White => base(97),
+        Color::Rgb(r, g, b) => format!("{};2;{r};{g};{b}", base(38)),
+        Color::Indexed(index) => format!("{};5;{index}", base(38)),
+    }
+}
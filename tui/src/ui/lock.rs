@@ -0,0 +1,95 @@
+//! Lock screen
+//!
+//! Covers every screen after the idle time in `[lock]` or on `K`, until the
+//! lock passphrase is entered again. Nothing from the budget is drawn
+//! underneath.
+
+use ratatui::{
+    layout::{Alignment, Constraint, Layout},
+    style::{Color, Modifier, Style},
+    text::{Line, Span},
+    widgets::{Block, Borders, Clear, Paragraph},
+    Frame,
+};
+
+use super::centered_rect_fixed;
+
+/// Render the lock screen with the passphrase typed so far
+pub fn render(frame: &mut Frame, passphrase: &str, error: Option<&str>, version: &str) {
+    let area = frame.area();
+
+    // A frosted backdrop where the screen was
+    let shade = "░".repeat(area.width as usize);
+    let backdrop: Vec<Line> = (0..area.height)
+        .map(|_| Line::from(shade.as_str()))
+        .collect();
+    frame.render_widget(
+        Paragraph::new(backdrop)
+            .style(Style::default().fg(Color::Rgb(40, 40, 48)).bg(Color::Black)),
+        area,
+    );
+
+    let card_area = centered_rect_fixed(48, 10, area);
+    let card_block = Block::default()
+        .title(format!(" Budget v{} ", version))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Yellow))
+        .style(Style::default().bg(Color::Rgb(30, 30, 35)));
+
+    frame.render_widget(Clear, card_area);
+    frame.render_widget(card_block.clone(), card_area);
+
+    let inner = card_block.inner(card_area);
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Header
+        Constraint::Length(1), // Spacer
+        Constraint::Length(3), // Passphrase
+        Constraint::Length(1), // Error
+        Constraint::Min(1),    // Instructions
+    ])
+    .horizontal_margin(1)
+    .split(inner);
+
+    let header = Paragraph::new("Locked - enter the passphrase to continue")
+        .style(Style::default().fg(Color::White))
+        .alignment(Alignment::Center);
+    frame.render_widget(header, chunks[0]);
+
+    let input_block = Block::default()
+        .title(" Passphrase ")
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::Cyan));
+    let masked = "*".repeat(passphrase.chars().count());
+    let input =
+        Paragraph::new(Span::styled(masked, Style::default().fg(Color::White))).block(input_block);
+    frame.render_widget(input, chunks[2]);
+    frame.set_cursor_position((
+        chunks[2].x + 1 + passphrase.chars().count() as u16,
+        chunks[2].y + 1,
+    ));
+
+    if let Some(err) = error {
+        let error_line = Line::from(vec![
+            Span::styled(
+                "Error: ",
+                Style::default().fg(Color::Red).add_modifier(Modifier::BOLD),
+            ),
+            Span::styled(err, Style::default().fg(Color::Red)),
+        ]);
+        frame.render_widget(Paragraph::new(error_line), chunks[3]);
+    }
+
+    let instructions = Line::from(vec![
+        Span::styled("Enter", Style::default().fg(Color::Cyan)),
+        Span::raw(" unlock  "),
+        Span::styled("Ctrl+C", Style::default().fg(Color::Cyan)),
+        Span::raw(" quit"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(instructions)
+            .alignment(Alignment::Center)
+            .style(Style::default().fg(Color::Gray)),
+        chunks[4],
+    );
+}
@@ -6,7 +6,10 @@ use ratatui::{
     Frame,
 };
 
+use std::time::{Duration, Instant};
+
 use super::centered_rect_fixed;
+use crate::models::{DeviceCode, TotpChallenge};
 use crate::state::{AppState, InputMode};
 
 /// Login form state stored in the app
@@ -45,6 +48,99 @@ impl LoginField {
     }
 }
 
+/// Longest code the two-factor prompt takes; authenticator apps show 6 or 8
+/// digits
+pub const TOTP_MAX_DIGITS: usize = 8;
+
+/// Second login step, shown instead of the form when the server asks for a
+/// code from the account's authenticator app
+#[derive(Debug, Clone)]
+pub struct TotpPrompt {
+    pub challenge: String,
+    pub code: String,
+    /// When the server stops accepting the challenge, if it said
+    pub expires_at: Option<Instant>,
+}
+
+impl TotpPrompt {
+    pub fn new(challenge: TotpChallenge) -> Self {
+        Self {
+            expires_at: challenge
+                .expires_in
+                .map(|secs| Instant::now() + Duration::from_secs(secs)),
+            challenge: challenge.challenge,
+            code: String::new(),
+        }
+    }
+
+    pub fn is_expired(&self) -> bool {
+        self.expires_at.is_some_and(|at| Instant::now() >= at)
+    }
+
+    /// Add a typed digit; anything else, or digits past the longest code,
+    /// is ignored
+    pub fn push(&mut self, c: char) {
+        if c.is_ascii_digit() && self.code.len() < TOTP_MAX_DIGITS {
+            self.code.push(c);
+        }
+    }
+}
+
+/// A device login waiting to be approved in a browser
+#[derive(Debug, Clone)]
+pub struct DeviceLogin {
+    pub code: DeviceCode,
+    pub expires_at: Instant,
+    pub next_poll: Instant,
+    pub interval: Duration,
+}
+
+impl DeviceLogin {
+    pub fn new(code: DeviceCode) -> Self {
+        let now = Instant::now();
+        let interval = Duration::from_secs(code.interval.max(1));
+        Self {
+            expires_at: now + Duration::from_secs(code.expires_in),
+            next_poll: now + interval,
+            interval,
+            code,
+        }
+    }
+
+    /// Page to open, with the code filled in if the server supports it
+    pub fn link(&self) -> &str {
+        self.code
+            .verification_uri_complete
+            .as_deref()
+            .unwrap_or(&self.code.verification_uri)
+    }
+
+    pub fn is_expired(&self) -> bool {
+        Instant::now() >= self.expires_at
+    }
+
+    pub fn is_due(&self) -> bool {
+        Instant::now() >= self.next_poll
+    }
+
+    /// Wait another interval, `slow_down` adding 5 seconds to it for good
+    pub fn schedule_poll(&mut self, slow_down: bool) {
+        if slow_down {
+            self.interval += Duration::from_secs(5);
+        }
+        self.next_poll = Instant::now() + self.interval;
+    }
+
+    /// Time left to approve, as `m:ss`
+    pub fn remaining(&self) -> String {
+        let secs = self
+            .expires_at
+            .saturating_duration_since(Instant::now())
+            .as_secs();
+        format!("{}:{:02}", secs / 60, secs % 60)
+    }
+}
+
 // Colors
 const CYAN: Color = Color::Cyan;
 const GREEN: Color = Color::Green;
@@ -121,6 +217,7 @@ pub fn render_with_state(
     is_loading: bool,
     version: &str,
     server_url: &str,
+    profile: Option<&str>,
 ) {
     let area = frame.area();
 
@@ -129,7 +226,7 @@ pub fn render_with_state(
     frame.render_widget(bg, area);
 
     // Calculate card size
-    let card_width = 54u16;
+    let card_width = 60u16;
     let card_height = 16u16;
     let card_area = centered_rect_fixed(card_width, card_height, area);
 
@@ -158,20 +255,32 @@ pub fn render_with_state(
     .horizontal_margin(1)
     .split(inner);
 
-    // Server info line
-    let server_display = if server_url.len() > 35 {
-        format!("{}...", &server_url[..32])
+    // Server info line, led by the profile's name when there are profiles
+    let (label, max_url) = match profile {
+        Some(name) => (
+            format!("{}: ", name.chars().take(12).collect::<String>()),
+            24,
+        ),
+        None => ("Server: ".to_string(), 35),
+    };
+    let server_display = if server_url.len() > max_url {
+        format!("{}...", &server_url[..max_url - 3])
     } else {
         server_url.to_string()
     };
-    let server_line = Line::from(vec![
-        Span::styled("Server: ", Style::default().fg(GRAY)),
+    let mut server_spans = vec![
+        Span::styled(label, Style::default().fg(GRAY)),
         Span::styled(&server_display, Style::default().fg(GREEN)),
-        Span::styled("  ", Style::default()),
-        Span::styled("[s]", Style::default().fg(YELLOW)),
-        Span::styled(" config", Style::default().fg(GRAY)),
-    ]);
-    frame.render_widget(Paragraph::new(server_line), chunks[0]);
+    ];
+    if profile.is_some() {
+        server_spans.push(Span::styled("  ", Style::default()));
+        server_spans.push(Span::styled("[^P]", Style::default().fg(YELLOW)));
+        server_spans.push(Span::styled(" next", Style::default().fg(GRAY)));
+    }
+    server_spans.push(Span::styled("  ", Style::default()));
+    server_spans.push(Span::styled("[s]", Style::default().fg(YELLOW)));
+    server_spans.push(Span::styled(" config", Style::default().fg(GRAY)));
+    frame.render_widget(Paragraph::new(Line::from(server_spans)), chunks[0]);
 
     // Email field
     let email_focused = focused_field == LoginField::Email.index();
@@ -238,6 +347,8 @@ pub fn render_with_state(
             Span::raw(" login  "),
             Span::styled("s", Style::default().fg(CYAN)),
             Span::raw(" server  "),
+            Span::styled("^D", Style::default().fg(CYAN)),
+            Span::raw(" device  "),
             Span::styled("Esc", Style::default().fg(CYAN)),
             Span::raw(" quit"),
         ])
@@ -249,3 +360,191 @@ pub fn render_with_state(
         chunks[6],
     );
 }
+
+/// Render the two-factor code prompt of the login screen
+pub fn render_totp_prompt(
+    frame: &mut Frame,
+    prompt: &TotpPrompt,
+    email: &str,
+    error: Option<&str>,
+    is_loading: bool,
+    version: &str,
+) {
+    let area = frame.area();
+
+    // Black background
+    let bg = Block::default().style(Style::default().bg(Color::Black));
+    frame.render_widget(bg, area);
+
+    let card_area = centered_rect_fixed(54, 12, area);
+
+    let card_block = Block::default()
+        .title(format!(" Appz Budget v{} ", version))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(CYAN));
+
+    frame.render_widget(Clear, card_area);
+    frame.render_widget(card_block.clone(), card_area);
+
+    let inner = card_block.inner(card_area);
+
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Header
+        Constraint::Length(1), // Account
+        Constraint::Length(1), // Spacer
+        Constraint::Length(3), // Code
+        Constraint::Length(1), // Error
+        Constraint::Min(1),    // Instructions
+    ])
+    .horizontal_margin(1)
+    .split(inner);
+
+    let header = Paragraph::new("Enter the code from your authenticator app")
+        .style(Style::default().fg(WHITE))
+        .alignment(Alignment::Center);
+    frame.render_widget(header, chunks[0]);
+    let account = Paragraph::new(email)
+        .style(Style::default().fg(GRAY))
+        .alignment(Alignment::Center);
+    frame.render_widget(account, chunks[1]);
+
+    let input_block = Block::default()
+        .title(" Code ")
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(CYAN));
+    let code_text = if prompt.code.is_empty() {
+        Span::styled("123456", Style::default().fg(DARK_GRAY))
+    } else {
+        Span::styled(&prompt.code, Style::default().fg(WHITE))
+    };
+    frame.render_widget(Paragraph::new(code_text).block(input_block), chunks[3]);
+    frame.set_cursor_position((chunks[3].x + 1 + prompt.code.len() as u16, chunks[3].y + 1));
+
+    if let Some(err) = error {
+        let error_line = Line::from(vec![
+            Span::styled(
+                "Error: ",
+                Style::default().fg(RED).add_modifier(Modifier::BOLD),
+            ),
+            Span::styled(err, Style::default().fg(RED)),
+        ]);
+        frame.render_widget(Paragraph::new(error_line), chunks[4]);
+    }
+
+    let instructions = if is_loading {
+        Line::from(vec![Span::styled(
+            "Verifying...",
+            Style::default().fg(YELLOW),
+        )])
+    } else {
+        Line::from(vec![
+            Span::styled("Enter", Style::default().fg(CYAN)),
+            Span::raw(" verify  "),
+            Span::styled("Esc", Style::default().fg(CYAN)),
+            Span::raw(" back"),
+        ])
+    };
+    frame.render_widget(
+        Paragraph::new(instructions)
+            .alignment(Alignment::Center)
+            .style(Style::default().fg(GRAY)),
+        chunks[5],
+    );
+}
+
+/// Render the device login of the login screen: the page to open and the
+/// code to enter there
+pub fn render_device_login(
+    frame: &mut Frame,
+    login: &DeviceLogin,
+    error: Option<&str>,
+    version: &str,
+) {
+    let area = frame.area();
+
+    // Black background
+    let bg = Block::default().style(Style::default().bg(Color::Black));
+    frame.render_widget(bg, area);
+
+    let card_area = centered_rect_fixed(60, 14, area);
+
+    let card_block = Block::default()
+        .title(format!(" Appz Budget v{} ", version))
+        .title_alignment(Alignment::Center)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(CYAN));
+
+    frame.render_widget(Clear, card_area);
+    frame.render_widget(card_block.clone(), card_area);
+
+    let inner = card_block.inner(card_area);
+
+    let chunks = Layout::vertical([
+        Constraint::Length(1), // Header
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Link
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Code
+        Constraint::Length(1), // Spacer
+        Constraint::Length(1), // Status
+        Constraint::Length(1), // Error
+        Constraint::Min(1),    // Instructions
+    ])
+    .horizontal_margin(1)
+    .split(inner);
+
+    let header = Paragraph::new("Open this page on any device and sign in:")
+        .style(Style::default().fg(WHITE))
+        .alignment(Alignment::Center);
+    frame.render_widget(header, chunks[0]);
+
+    let link = Paragraph::new(login.code.verification_uri.as_str())
+        .style(Style::default().fg(GREEN))
+        .alignment(Alignment::Center);
+    frame.render_widget(link, chunks[2]);
+
+    let code_line = Line::from(vec![
+        Span::styled("Code: ", Style::default().fg(GRAY)),
+        Span::styled(
+            login.code.user_code.as_str(),
+            Style::default().fg(YELLOW).add_modifier(Modifier::BOLD),
+        ),
+    ]);
+    frame.render_widget(
+        Paragraph::new(code_line).alignment(Alignment::Center),
+        chunks[4],
+    );
+
+    let status = Paragraph::new(format!(
+        "Waiting for approval - expires in {}",
+        login.remaining()
+    ))
+    .style(Style::default().fg(DARK_GRAY))
+    .alignment(Alignment::Center);
+    frame.render_widget(status, chunks[6]);
+
+    if let Some(err) = error {
+        let error_line = Line::from(vec![
+            Span::styled(
+                "Error: ",
+                Style::default().fg(RED).add_modifier(Modifier::BOLD),
+            ),
+            Span::styled(err, Style::default().fg(RED)),
+        ]);
+        frame.render_widget(Paragraph::new(error_line), chunks[7]);
+    }
+
+    let instructions = Line::from(vec![
+        Span::styled("c", Style::default().fg(CYAN)),
+        Span::raw(" copy link  "),
+        Span::styled("Esc", Style::default().fg(CYAN)),
+        Span::raw(" back to password"),
+    ]);
+    frame.render_widget(
+        Paragraph::new(instructions)
+            .alignment(Alignment::Center)
+            .style(Style::default().fg(GRAY)),
+        chunks[8],
+    );
+}
@@ -0,0 +1,100 @@
+use ratatui::{buffer::Buffer, style::Color};
+
+/// The 16 ANSI colors with their usual (xterm) RGB values
+const ANSI_16: [(Color, (u8, u8, u8)); 16] = [
+    (Color::Black, (0, 0, 0)),
+    (Color::Red, (205, 0, 0)),
+    (Color::Green, (0, 205, 0)),
+    (Color::Yellow, (205, 205, 0)),
+    (Color::Blue, (0, 0, 238)),
+    (Color::Magenta, (205, 0, 205)),
+    (Color::Cyan, (0, 205, 205)),
+    (Color::Gray, (229, 229, 229)),
+    (Color::DarkGray, (127, 127, 127)),
+    (Color::LightRed, (255, 0, 0)),
+    (Color::LightGreen, (0, 255, 0)),
+    (Color::LightYellow, (255, 255, 0)),
+    (Color::LightBlue, (92, 92, 255)),
+    (Color::LightMagenta, (255, 0, 255)),
+    (Color::LightCyan, (0, 255, 255)),
+    (Color::White, (255, 255, 255)),
+];
+
+/// Check if a terminal only supports the basic 16 colors
+///
+/// Based on `TERM` and `COLORTERM`, which is what SSH sessions, `screen` and
+/// older terminals pass along. Without `TERM` (e.g. on Windows) full color is
+/// assumed.
+pub fn is_limited_terminal(term: Option<&str>, colorterm: Option<&str>) -> bool {
+    if colorterm.is_some_and(|c| c == "truecolor" || c == "24bit") {
+        return false;
+    }
+    match term {
+        Some(term) => !(term.contains("256color") || term.contains("truecolor")),
+        None => false,
+    }
+}
+
+/// Nearest ANSI-16 color for any color
+pub fn to_ansi16(color: Color) -> Color {
+    let rgb = match color {
+        Color::Rgb(r, g, b) => (r, g, b),
+        Color::Indexed(index) if index < 16 => return ANSI_16[index as usize].0,
+        Color::Indexed(index) => indexed_to_rgb(index),
+        // Named colors and Reset are already fine
+        color => return color,
+    };
+
+    ANSI_16
+        .iter()
+        .min_by_key(|(_, candidate)| distance(rgb, *candidate))
+        .map(|(color, _)| *color)
+        .unwrap_or(Color::Reset)
+}
+
+/// Rewrite a rendered frame for a 16-color terminal
+///
+/// Colors are mapped to their nearest ANSI-16 match and box-drawing borders
+/// are replaced with plain ASCII, which survives any font or encoding.
+pub fn simplify(buffer: &mut Buffer) {
+    for cell in buffer.content.iter_mut() {
+        cell.fg = to_ansi16(cell.fg);
+        cell.bg = to_ansi16(cell.bg);
+        if let Some(symbol) = ascii_border(cell.symbol()) {
+            cell.set_symbol(symbol);
+        }
+    }
+}
+
+/// ASCII replacement for a box-drawing character
+fn ascii_border(symbol: &str) -> Option<&'static str> {
+    match symbol {
+        "─" | "━" | "═" => Some("-"),
+        "│" | "┃" | "║" => Some("|"),
+        "┌" | "┐" | "└" | "┘" | "╭" | "╮" | "╰" | "╯" | "├" | "┤" | "┬" | "┴" | "┼" | "╔" | "╗"
+        | "╚" | "╝" | "┏" | "┓" | "┗" | "┛" => Some("+"),
+        "▶" => Some(">"),
+        _ => None,
+    }
+}
+
+/// RGB value of a 256-color palette index (16-255)
+fn indexed_to_rgb(index: u8) -> (u8, u8, u8) {
+    if index >= 232 {
+        let level = 8 + (index - 232) * 10;
+        return (level, level, level);
+    }
+    // 6x6x6 color cube
+    const LEVELS: [u8; 6] = [0, 95, 135, 175, 215, 255];
+    let index = index - 16;
+    (
+        LEVELS[(index / 36) as usize],
+        LEVELS[(index / 6 % 6) as usize],
+        LEVELS[(index % 6) as usize],
+    )
+}
+
+fn distance(a: (u8, u8, u8), b: (u8, u8, u8)) -> u32 {
+    let d = |x: u8, y: u8| (x as i32 - y as i32).pow(2) as u32;
+    d(a.0, b.0) + d(a.1, b.1) + d(a.2, b.2)
+}
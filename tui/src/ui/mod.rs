@@ -1,12 +1,22 @@
 pub mod api_config;
+pub mod clipboard;
 pub mod components;
 pub mod dashboard;
+pub mod dates;
+pub mod inline;
+pub mod lock;
 pub mod login;
+pub mod low_color;
+pub mod money;
+pub mod notify;
+pub mod palette;
 pub mod tabs;
+pub mod theme;
 
 use ratatui::{
     layout::{Alignment, Constraint, Layout, Rect},
     style::{Color, Style},
+    text::{Line, Span},
     widgets::{Block, Borders, Clear, Paragraph},
     Frame,
 };
@@ -170,6 +180,33 @@ pub fn centered_rect_fixed(width: u16, height: u16, r: Rect) -> Rect {
     }
 }
 
+/// A `width`-cell progress bar filled to `fraction` (0 to 1)
+///
+/// With a `pace` (share of the month passed), a marker shows where spending
+/// would be if it kept up with the calendar.
+pub fn progress_bar(width: usize, fraction: f64, color: Color, pace: Option<f64>) -> Line<'static> {
+    let filled = ((fraction.clamp(0.0, 1.0) * width as f64) as usize).min(width);
+    let marker = pace
+        .filter(|_| width > 0)
+        .map(|pace| ((pace.clamp(0.0, 1.0) * width as f64) as usize).min(width - 1));
+
+    let mut spans: Vec<Span<'static>> = Vec::new();
+    let mut push = |symbol: &str, style: Style| match spans.last_mut() {
+        Some(last) if last.style == style => last.content.to_mut().push_str(symbol),
+        _ => spans.push(Span::styled(symbol.to_string(), style)),
+    };
+    for i in 0..width {
+        if Some(i) == marker {
+            push("┃", Style::default().fg(Color::White));
+        } else if i < filled {
+            push("█", Style::default().fg(color));
+        } else {
+            push("░", Style::default().fg(Color::DarkGray));
+        }
+    }
+    Line::from(spans)
+}
+
 /// Convert a hex color string to ratatui Color
 pub fn hex_to_color(hex: &str) -> Color {
     let hex = hex.trim_start_matches('#');
@@ -182,11 +219,11 @@ pub fn hex_to_color(hex: &str) -> Color {
     Color::Rgb(r, g, b)
 }
 
-/// Format a number as currency
-pub fn format_currency(amount: f64) -> String {
-    if amount >= 0.0 {
-        format!("${:.2}", amount)
-    } else {
-        format!("-${:.2}", amount.abs())
+/// Format a byte count, e.g. "512 B" or "12.3 KB"
+pub fn format_size(bytes: u64) -> String {
+    match bytes {
+        0..1024 => format!("{} B", bytes),
+        1024..1_048_576 => format!("{:.1} KB", bytes as f64 / 1024.0),
+        _ => format!("{:.1} MB", bytes as f64 / 1_048_576.0),
     }
 }
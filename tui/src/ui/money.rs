@@ -0,0 +1,163 @@
+//! How amounts are shown
+//!
+//! Every amount on screen goes through `MoneyFormat::format`, so the options
+//! under `[display.money]` apply to cards, tables and reports alike. Inputs
+//! and exported files keep full precision, with a `.` for the decimal mark.
+
+use std::fmt::Write;
+
+use serde::{Deserialize, Serialize};
+
+use crate::models::Money;
+
+/// How negative amounts are written
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum NegativeStyle {
+    /// `-$123.45`
+    #[default]
+    Minus,
+    /// `($123.45)`, as in accounting
+    Parentheses,
+}
+
+/// Digit grouping and decimal mark
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum NumberStyle {
+    /// `1234.56`
+    #[default]
+    Plain,
+    /// `1,234.56`
+    Comma,
+    /// `1.234,56`
+    Period,
+    /// `1 234,56`
+    Space,
+}
+
+impl NumberStyle {
+    /// Thousands separator, if any, and decimal mark
+    fn marks(self) -> (Option<char>, char) {
+        match self {
+            NumberStyle::Plain => (None, '.'),
+            NumberStyle::Comma => (Some(','), '.'),
+            NumberStyle::Period => (Some('.'), ','),
+            NumberStyle::Space => (Some(' '), ','),
+        }
+    }
+}
+
+/// Display options for amounts
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct MoneyFormat {
+    /// Round to whole units, e.g. `$1235`
+    #[serde(default)]
+    pub hide_cents: bool,
+    /// Shorten amounts from a thousand up, e.g. `$1.2k` or `$3.4M`
+    #[serde(default)]
+    pub compact: bool,
+    #[serde(default)]
+    pub negatives: NegativeStyle,
+    #[serde(default)]
+    pub numbers: NumberStyle,
+}
+
+/// Suffixes for compact amounts, largest first
+const COMPACT_UNITS: &[(f64, &str)] = &[(1e9, "B"), (1e6, "M"), (1e3, "k")];
+
+impl MoneyFormat {
+    /// Format an amount, e.g. `$1234.50`, `-$12.00` or `($12)`
+    ///
+    /// Called for every amount cell on every frame, so it builds the text in
+    /// a single allocation.
+    pub fn format(&self, amount: f64) -> String {
+        let mut out = String::with_capacity(16);
+        out.push('$');
+        self.write_digits(&mut out, amount.abs());
+        // No "-$0" for amounts that round to zero
+        if amount < 0.0 && !out[1..].trim_start_matches(['0', '.', ',']).is_empty() {
+            match self.negatives {
+                NegativeStyle::Minus => out.insert(0, '-'),
+                NegativeStyle::Parentheses => {
+                    out.insert(0, '(');
+                    out.push(')');
+                }
+            }
+        }
+        out
+    }
+
+    /// Format an amount in `currency`, e.g. `12.50 EUR`; `None` is the
+    /// budget's own, shown like [`format`](Self::format)
+    pub fn format_in(&self, amount: f64, currency: Option<&str>) -> String {
+        match currency {
+            Some(code) => {
+                let mut out = self.format(amount).replacen('$', "", 1);
+                out.push(' ');
+                out.push_str(code);
+                out
+            }
+            None => self.format(amount),
+        }
+    }
+
+    /// Append the number without sign or currency symbol
+    fn write_digits(&self, out: &mut String, amount: f64) {
+        let start = out.len();
+        self.write_plain(out, amount);
+        self.localize(out, start);
+    }
+
+    /// Group the digits written from `start` and set the decimal mark, for
+    /// `numbers`
+    fn localize(&self, out: &mut String, start: usize) {
+        let (separator, mark) = self.numbers.marks();
+        if mark != '.' {
+            if let Some(dot) = out[start..].find('.') {
+                out.replace_range(start + dot..start + dot + 1, mark.encode_utf8(&mut [0; 4]));
+            }
+        }
+        if let Some(separator) = separator {
+            let whole = out[start..]
+                .find(|c: char| !c.is_ascii_digit())
+                .unwrap_or(out.len() - start);
+            let mut at = whole;
+            while at > 3 {
+                at -= 3;
+                out.insert(start + at, separator);
+            }
+        }
+    }
+
+    /// Append the number with a `.` for the decimal mark and no grouping
+    fn write_plain(&self, out: &mut String, amount: f64) {
+        if self.compact {
+            for (i, (size, suffix)) in COMPACT_UNITS.iter().enumerate() {
+                if amount < *size {
+                    continue;
+                }
+                let scaled = (amount / size * 10.0).round() / 10.0;
+                // 999,950 rounds up to 1000k; that's 1M
+                if scaled >= 1000.0 && i > 0 {
+                    out.push('1');
+                    out.push_str(COMPACT_UNITS[i - 1].1);
+                    return;
+                }
+                let start = out.len();
+                let _ = write!(out, "{:.1}", scaled);
+                if out[start..].ends_with(".0") {
+                    out.truncate(out.len() - 2);
+                }
+                out.push_str(suffix);
+                return;
+            }
+        }
+        if self.hide_cents {
+            let _ = write!(out, "{:.0}", amount.round());
+        } else {
+            // Rounded to the cent the way totals and inputs are
+            let _ = write!(out, "{}", Money::from_f64(amount));
+        }
+    }
+}
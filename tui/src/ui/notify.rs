@@ -0,0 +1,50 @@
+//! Signals that a long task finished
+//!
+//! The bell and notifications are escape sequences written straight to the
+//! terminal, like the clipboard's. Inside tmux a notification is wrapped so
+//! tmux passes it on to the outer terminal; the bell needs no wrapping, as
+//! tmux flags the window it rang in.
+
+use std::io::{self, Write};
+
+use ratatui::buffer::Buffer;
+use ratatui::style::Modifier;
+
+use crate::config::Signal;
+
+/// Escape sequence for `signal` carrying `message`; empty for signals drawn
+/// by the app itself
+pub fn sequence(signal: Signal, message: &str, in_tmux: bool) -> String {
+    match signal {
+        Signal::Bell => "\x07".to_string(),
+        Signal::Notify => {
+            // Control characters would end the sequence early
+            let message: String = message.chars().filter(|c| !c.is_control()).collect();
+            let osc = format!("\x1b]9;{}\x07", message);
+            if in_tmux {
+                format!("\x1bPtmux;{}\x1b\\", osc.replace('\x1b', "\x1b\x1b"))
+            } else {
+                osc
+            }
+        }
+        Signal::None | Signal::Flash => String::new(),
+    }
+}
+
+/// Send `signal` with `message` to the terminal
+pub fn send(signal: Signal, message: &str) -> io::Result<()> {
+    let sequence = sequence(signal, message, std::env::var_os("TMUX").is_some());
+    if sequence.is_empty() {
+        return Ok(());
+    }
+    let mut stdout = io::stdout();
+    write!(stdout, "{}", sequence)?;
+    stdout.flush()
+}
+
+/// Invert every cell, for the visual flash
+pub fn flash(buffer: &mut Buffer) {
+    for cell in buffer.content.iter_mut() {
+        cell.modifier.toggle(Modifier::REVERSED);
+    }
+}
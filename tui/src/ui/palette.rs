@@ -0,0 +1,126 @@
+//! Color generation for categories, periods and income types
+
+use rand::Rng;
+
+use crate::config::ColorMode;
+
+/// Saturation and lightness ranges that read well on a dark background
+const SATURATION: (f64, f64) = (0.55, 0.80);
+const LIGHTNESS: (f64, f64) = (0.45, 0.62);
+
+/// Number of evenly spaced hues considered in palette mode
+const PALETTE_HUES: usize = 24;
+
+/// Random picks closer than this (in degrees) to a used hue are retried
+const MIN_HUE_DISTANCE: f64 = 20.0;
+const RANDOM_ATTEMPTS: usize = 32;
+
+/// Generate a color for a new entity, avoiding the colors already in use
+pub fn generate_color<R: Rng + ?Sized>(rng: &mut R, mode: ColorMode, used: &[String]) -> String {
+    match mode {
+        ColorMode::Random => random_color(rng, used),
+        ColorMode::Palette => palette_color(rng, used),
+    }
+}
+
+/// Pick a random color, retrying a few times if its hue clashes with a used one
+pub fn random_color<R: Rng + ?Sized>(rng: &mut R, used: &[String]) -> String {
+    let used_hues = used_hues(used);
+    let mut hue = rng.random_range(0.0..360.0);
+    for _ in 0..RANDOM_ATTEMPTS {
+        if nearest_hue_distance(hue, &used_hues) >= MIN_HUE_DISTANCE {
+            break;
+        }
+        hue = rng.random_range(0.0..360.0);
+    }
+
+    let saturation = rng.random_range(SATURATION.0..SATURATION.1);
+    let lightness = rng.random_range(LIGHTNESS.0..LIGHTNESS.1);
+    hsl_to_hex(hue, saturation, lightness)
+}
+
+/// Pick the palette hue farthest from every used color
+///
+/// The palette is rotated by a random offset so repeated picks still vary.
+pub fn palette_color<R: Rng + ?Sized>(rng: &mut R, used: &[String]) -> String {
+    let used_hues = used_hues(used);
+    let step = 360.0 / PALETTE_HUES as f64;
+    let offset = rng.random_range(0.0..step);
+
+    let hue = (0..PALETTE_HUES)
+        .map(|i| offset + i as f64 * step)
+        .max_by(|a, b| {
+            nearest_hue_distance(*a, &used_hues).total_cmp(&nearest_hue_distance(*b, &used_hues))
+        })
+        .unwrap_or(offset);
+
+    let saturation = (SATURATION.0 + SATURATION.1) / 2.0;
+    let lightness = (LIGHTNESS.0 + LIGHTNESS.1) / 2.0;
+    hsl_to_hex(hue, saturation, lightness)
+}
+
+/// Convert HSL (hue in degrees, saturation and lightness in 0..=1) to "#rrggbb"
+pub fn hsl_to_hex(hue: f64, saturation: f64, lightness: f64) -> String {
+    let hue = hue.rem_euclid(360.0);
+    let chroma = (1.0 - (2.0 * lightness - 1.0).abs()) * saturation;
+    let x = chroma * (1.0 - ((hue / 60.0) % 2.0 - 1.0).abs());
+    let m = lightness - chroma / 2.0;
+
+    let (r, g, b) = match hue {
+        h if h < 60.0 => (chroma, x, 0.0),
+        h if h < 120.0 => (x, chroma, 0.0),
+        h if h < 180.0 => (0.0, chroma, x),
+        h if h < 240.0 => (0.0, x, chroma),
+        h if h < 300.0 => (x, 0.0, chroma),
+        _ => (chroma, 0.0, x),
+    };
+
+    let to_byte = |v: f64| ((v + m) * 255.0).round().clamp(0.0, 255.0) as u8;
+    format!("#{:02x}{:02x}{:02x}", to_byte(r), to_byte(g), to_byte(b))
+}
+
+/// Get the hue (in degrees) of a "#rrggbb" color, or None for grays and invalid input
+pub fn hex_to_hue(hex: &str) -> Option<f64> {
+    let hex = hex.trim_start_matches('#');
+    if hex.len() != 6 {
+        return None;
+    }
+    let channel = |i: usize| {
+        u8::from_str_radix(hex.get(i..i + 2)?, 16)
+            .ok()
+            .map(|v| v as f64 / 255.0)
+    };
+    let (r, g, b) = (channel(0)?, channel(2)?, channel(4)?);
+
+    let max = r.max(g).max(b);
+    let min = r.min(g).min(b);
+    let delta = max - min;
+    if delta == 0.0 {
+        return None;
+    }
+
+    let hue = if max == r {
+        60.0 * ((g - b) / delta)
+    } else if max == g {
+        60.0 * ((b - r) / delta + 2.0)
+    } else {
+        60.0 * ((r - g) / delta + 4.0)
+    };
+    Some(hue.rem_euclid(360.0))
+}
+
+/// Hues of the used colors, skipping grays and unparseable values
+fn used_hues(used: &[String]) -> Vec<f64> {
+    used.iter().filter_map(|c| hex_to_hue(c)).collect()
+}
+
+/// Circular distance from a hue to the closest used hue (180 if none are used)
+fn nearest_hue_distance(hue: f64, used_hues: &[f64]) -> f64 {
+    used_hues
+        .iter()
+        .map(|used| {
+            let d = (hue - used).rem_euclid(360.0);
+            d.min(360.0 - d)
+        })
+        .fold(180.0, f64::min)
+}
@@ -6,8 +6,11 @@ use ratatui::{
     Frame,
 };
 
+use crate::models::BudgetStatus;
 use crate::state::AppState;
-use crate::ui::{format_currency, hex_to_color};
+use crate::ui::tabs::expenses::status_color;
+use crate::ui::tabs::summary::expense_status;
+use crate::ui::{hex_to_color, progress_bar};
 
 /// Render the charts tab
 pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
@@ -88,40 +91,40 @@ fn render_projected_vs_actual(app: &AppState, frame: &mut Frame, area: Rect) {
         // Build the bar
         let mut bar_spans = vec![label_span, Span::raw(" ")];
 
-        // Actual bar (filled)
-        let actual_bar = "█".repeat(actual_len.min(bar_width as usize));
-        let actual_color = if cs.over_projected {
-            Color::Red
-        } else {
-            Color::Green
-        };
-        bar_spans.push(Span::styled(actual_bar, Style::default().fg(actual_color)));
-
-        // Remaining projected (unfilled)
-        if projected_len > actual_len {
-            let remaining = "░".repeat(projected_len - actual_len);
-            bar_spans.push(Span::styled(
-                remaining,
-                Style::default().fg(Color::DarkGray),
-            ));
+        // Actual (filled) over the projected length, with the pace marked
+        // at the share of the projection the month has reached
+        let status = expense_status(app, cs.total, cs.projected, &cs.category);
+        let actual_len = actual_len.min(bar_width as usize);
+        let bar_len = actual_len.max(projected_len);
+        if bar_len > 0 {
+            let pace = app
+                .month_pace()
+                .filter(|_| projected_len > 0)
+                .map(|pace| pace * projected_len as f64 / bar_len as f64);
+            let bar = progress_bar(
+                bar_len,
+                actual_len as f64 / bar_len as f64,
+                status_color(status),
+                pace,
+            );
+            bar_spans.extend(bar.spans);
         }
 
         // Values
         let values = format!(
             " {} / {}",
-            format_currency(cs.total),
-            format_currency(cs.projected)
+            app.money.format(cs.total),
+            app.money.format(cs.projected)
         );
-        let value_color = if cs.over_projected {
-            Color::Red
-        } else {
-            Color::White
+        let value_color = match status {
+            BudgetStatus::OnTrack => Color::White,
+            status => status_color(status),
         };
         bar_spans.push(Span::styled(values, Style::default().fg(value_color)));
 
-        // Over projected indicator
-        if cs.over_projected {
-            bar_spans.push(Span::styled(" ⚠", Style::default().fg(Color::Red)));
+        // Ahead of pace/near/over projected indicator
+        if status != BudgetStatus::OnTrack {
+            bar_spans.push(Span::styled(" ⚠", Style::default().fg(value_color)));
         }
 
         let line = Line::from(bar_spans);
@@ -204,7 +207,7 @@ fn render_expense_distribution(app: &AppState, frame: &mut Frame, area: Rect) {
 
         let label = format!("{:12}", truncate_str(&cs.category, 12));
         let bar = "█".repeat(filled_len);
-        let pct_str = format!(" {:>3}% ({})", pct, format_currency(cs.total));
+        let pct_str = format!(" {:>3}% ({})", pct, app.money.format(cs.total));
 
         lines.push(Line::from(vec![
             Span::styled(label, Style::default().fg(cat_color)),
@@ -217,7 +220,7 @@ fn render_expense_distribution(app: &AppState, frame: &mut Frame, area: Rect) {
     // Add total line
     lines.push(Line::from(""));
     lines.push(Line::from(vec![Span::styled(
-        format!("Total: {}", format_currency(total)),
+        format!("Total: {}", app.money.format(total)),
         Style::default()
             .fg(Color::White)
             .add_modifier(Modifier::BOLD),
@@ -0,0 +1,213 @@
+use chrono::{Months, NaiveDate};
+use ratatui::{
+    layout::{Constraint, Layout, Rect},
+    style::{Color, Modifier, Style},
+    text::{Line, Span},
+    widgets::{Block, Borders, Cell, Paragraph, Row, Table},
+    Frame,
+};
+
+use crate::models::{Debt, Money};
+use crate::state::debts::{self, Payoff};
+use crate::state::{AppState, ServerFeature};
+use crate::ui::components::empty_state;
+use crate::ui::tabs::expenses::{header_row, rows_area, table_block};
+
+/// Years of balances the projection lists
+const PROJECTION_YEARS: usize = 5;
+
+/// Render the debts tab
+pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
+    let chunks = Layout::vertical([
+        Constraint::Min(5),    // Debts table
+        Constraint::Length(7), // Payoff projection
+    ])
+    .split(area);
+
+    render_debts_table(app, frame, chunks[0]);
+    render_projection(app, frame, chunks[1]);
+}
+
+/// Month a payoff ends, counting from this month
+fn paid_off_on(today: NaiveDate, payoff: &Payoff) -> String {
+    today
+        .checked_add_months(Months::new(payoff.months() as u32))
+        .map(|date| date.format("%b %Y").to_string())
+        .unwrap_or_default()
+}
+
+/// Render the debts table
+fn render_debts_table(app: &AppState, frame: &mut Frame, area: Rect) {
+    let owed = app
+        .data
+        .debts
+        .iter()
+        .map(|d| d.principal)
+        .sum::<Money>()
+        .to_f64();
+    let block = table_block(
+        app,
+        format!(
+            " Debts ({}) · {} owed ",
+            app.data.debts.len(),
+            app.money.format(owed)
+        ),
+    );
+    let header = header_row(&[
+        "Name",
+        "Owed",
+        "Interest",
+        "Minimum",
+        "Paid off",
+        "Interest left",
+    ]);
+    let today = app.clock.today();
+
+    let rows: Vec<Row> = app
+        .data
+        .debts
+        .iter()
+        .map(|debt| {
+            let (paid_off, interest_left) = match debts::payoff(debt, debt.minimum_payment) {
+                Some(payoff) => (
+                    Cell::from(paid_off_on(today, &payoff)),
+                    Cell::from(app.money.format(payoff.interest)),
+                ),
+                None => (
+                    Cell::from("Never").style(Style::default().fg(Color::Red)),
+                    Cell::from("-").style(Style::default().fg(Color::DarkGray)),
+                ),
+            };
+            Row::new(vec![
+                Cell::from(debt.name.clone()),
+                Cell::from(app.money.format(debt.principal)),
+                Cell::from(format!("{}%", debt.interest_rate)),
+                Cell::from(app.money.format(debt.minimum_payment)),
+                paid_off,
+                interest_left,
+            ])
+        })
+        .collect();
+
+    let widths = [
+        Constraint::Percentage(26),
+        Constraint::Percentage(15),
+        Constraint::Percentage(11),
+        Constraint::Percentage(15),
+        Constraint::Percentage(15),
+        Constraint::Percentage(18),
+    ];
+
+    let table = Table::new(rows, widths)
+        .header(header)
+        .block(block)
+        .row_highlight_style(
+            Style::default()
+                .bg(Color::Rgb(50, 50, 60))
+                .add_modifier(Modifier::BOLD),
+        )
+        .highlight_symbol("▶ ");
+
+    let mut table_state = app.ui.debt_table.clone();
+    frame.render_stateful_widget(table, area, &mut table_state);
+
+    let empty_area = rows_area(area, app.ui.large_text);
+    if app.data.unsupported.contains(&ServerFeature::Debts) {
+        empty_state::render(frame, empty_area, "This server doesn't keep debts", &[]);
+    } else if app.data.debts.is_empty() {
+        empty_state::render(
+            frame,
+            empty_area,
+            "No debts yet",
+            &[("n", "Add a loan or card balance".to_string())],
+        );
+    }
+}
+
+/// One line on paying `payment` a month on `debt`
+fn scenario_line(app: &AppState, debt: &Debt, payment: f64) -> Line<'static> {
+    let label = Span::styled(
+        format!("At {}/month: ", app.money.format(payment)),
+        Style::default().fg(Color::Gray),
+    );
+    match debts::payoff(debt, payment) {
+        Some(payoff) => Line::from(vec![
+            label,
+            Span::styled(
+                format!(
+                    "paid off {} ({} months)",
+                    paid_off_on(app.clock.today(), &payoff),
+                    payoff.months()
+                ),
+                Style::default().fg(Color::Green),
+            ),
+            Span::raw(format!(
+                ", {} in interest",
+                app.money.format(payoff.interest)
+            )),
+        ]),
+        None => Line::from(vec![
+            label,
+            Span::styled(
+                format!(
+                    "never paid off; interest adds {} a month",
+                    app.money
+                        .format(debts::monthly_interest(debt.principal, debt.interest_rate))
+                ),
+                Style::default().fg(Color::Red),
+            ),
+        ]),
+    }
+}
+
+/// Render how the selected debt gets paid off
+fn render_projection(app: &AppState, frame: &mut Frame, area: Rect) {
+    let block = Block::default()
+        .title(" Payoff ")
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::DarkGray));
+
+    let Some(debt) = app.selected_debt() else {
+        let hint = Line::from(Span::styled(
+            "Select a debt to see when it's paid off",
+            Style::default().fg(Color::DarkGray),
+        ));
+        frame.render_widget(Paragraph::new(hint).block(block), area);
+        return;
+    };
+
+    let mut lines = vec![
+        scenario_line(app, debt, debt.minimum_payment),
+        scenario_line(app, debt, debt.minimum_payment * 2.0),
+    ];
+    if let Some(payoff) = debts::payoff(debt, debt.minimum_payment) {
+        let mut owed = vec![Span::styled(
+            "Owed at minimum: ",
+            Style::default().fg(Color::Gray),
+        )];
+        for year in 1..=PROJECTION_YEARS {
+            let Some(balance) = payoff.balances.get(year * 12 - 1) else {
+                break;
+            };
+            owed.push(Span::raw(format!(
+                "in {} yr {}  ",
+                year,
+                app.money.format(*balance)
+            )));
+        }
+        if owed.len() > 1 {
+            lines.push(Line::from(owed));
+        }
+    }
+    lines.push(Line::from(vec![
+        Span::styled("p", Style::default().fg(Color::Cyan)),
+        Span::styled(
+            format!(
+                " records a payment as an expense in {} / {}",
+                debt.category, debt.period
+            ),
+            Style::default().fg(Color::DarkGray),
+        ),
+    ]));
+    frame.render_widget(Paragraph::new(lines).block(block), area);
+}
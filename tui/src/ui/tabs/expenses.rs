@@ -1,137 +1,178 @@
+use std::collections::HashMap;
+
 use ratatui::{
     layout::{Constraint, Layout, Rect},
     style::{Color, Modifier, Style},
-    widgets::{Block, Borders, Cell, Paragraph, Row, Table},
+    text::{Line, Span, Text},
+    widgets::{Block, Borders, Cell, Padding, Row, Table},
     Frame,
 };
 
-use crate::state::AppState;
-use crate::ui::{format_currency, hex_to_color};
+use crate::models::BudgetStatus;
+use crate::state::{ledger_split, AppState, EntityType, Pane, SplitPane};
+use crate::storage::Ledger;
+use crate::ui::components::{empty_state, view_bar};
+use crate::ui::hex_to_color;
 
 /// Render the expenses tab
 pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
     let chunks = Layout::vertical([
-        Constraint::Length(3), // Filter bar
+        Constraint::Length(3), // View bar
         Constraint::Min(5),    // Expense table
     ])
     .split(area);
 
-    // Render filter bar
-    render_filter_bar(app, frame, chunks[0]);
-
-    // Render expense table
-    render_expense_table(app, frame, chunks[1]);
-}
-
-/// Render the filter bar
-fn render_filter_bar(app: &AppState, frame: &mut Frame, area: Rect) {
-    let block = Block::default()
-        .borders(Borders::BOTTOM)
-        .border_style(Style::default().fg(Color::DarkGray));
+    // Render filters, search, grouping and sort
+    view_bar::render(app, frame, chunks[0]);
 
-    let inner = block.inner(area);
-    frame.render_widget(block, area);
-
-    let filter_chunks = Layout::horizontal([
-        Constraint::Length(20), // Period filter
-        Constraint::Length(20), // Category filter
-        Constraint::Min(10),    // Spacer
-        Constraint::Length(15), // Add button hint
-    ])
-    .split(inner);
-
-    // Period filter
-    let period_text = app
-        .ui
-        .period_filter
-        .as_ref()
-        .map_or("All Periods", |p| p.as_str());
-    let period =
-        Paragraph::new(format!(" [{}] ", period_text)).style(Style::default().fg(Color::White));
-    frame.render_widget(period, filter_chunks[0]);
-
-    // Category filter
-    let category_text = app
-        .ui
-        .category_filter
-        .as_ref()
-        .map_or("All Categories", |c| c.as_str());
-    let category =
-        Paragraph::new(format!(" [{}] ", category_text)).style(Style::default().fg(Color::White));
-    frame.render_widget(category, filter_chunks[1]);
-
-    // Add hint
-    let add_hint = Paragraph::new("[n] Add New").style(Style::default().fg(Color::Cyan));
-    frame.render_widget(add_hint, filter_chunks[3]);
+    // Render expense table, beside the other month's in split mode
+    match &app.ui.split {
+        Some(split) => {
+            let halves =
+                Layout::horizontal([Constraint::Percentage(50), Constraint::Percentage(50)])
+                    .split(chunks[1]);
+            let (focused, other) = match split.focus {
+                Pane::Left => (halves[0], halves[1]),
+                Pane::Right => (halves[1], halves[0]),
+            };
+            render_expense_table(app, frame, focused);
+            render_split_pane(app, &split.other, frame, other);
+        }
+        None => render_expense_table(app, frame, chunks[1]),
+    }
 }
 
 /// Render the expense table
 fn render_expense_table(app: &AppState, frame: &mut Frame, area: Rect) {
-    let block = Block::default()
-        .title(format!(" Expenses ({}) ", app.filtered_expenses().len()))
-        .borders(Borders::ALL)
-        .border_style(Style::default().fg(Color::DarkGray));
+    let filtered_expenses = app.filtered_expenses();
+
+    // Personal spending on its own once anything is split off
+    let mut title = format!(
+        " Expenses ({}) ",
+        list_count(
+            filtered_expenses.len(),
+            app.data.more_expenses.is_some(),
+            app.data.expenses_total
+        )
+    );
+    if app.ui.split.is_some() {
+        if let Some(month) = app.selected_month() {
+            title = format!(" {} -{}", month.display_name(), title);
+        }
+    }
+    let split = ledger_split(&filtered_expenses, &app.ledgers);
+    if split[1..].iter().any(|(_, total)| *total > 0.0) {
+        let totals: Vec<String> = split
+            .iter()
+            .map(|(ledger, total)| format!("{} {}", ledger.as_str(), app.money.format(*total)))
+            .collect();
+        title = format!("{}- {} ", title, totals.join(" · "));
+    }
+
+    let mut block = table_block(app, title);
+    if app.ui.split.is_some() {
+        block = block.border_style(Style::default().fg(Color::Cyan));
+    }
+    let header = if app.ui.large_text {
+        header_row(&["Expense", "Cost / Projected"])
+    } else {
+        header_row(&["Name", "Period", "Category", "Projected", "Cost", "Status"])
+    };
 
-    let header_cells = ["Name", "Period", "Category", "Projected", "Cost", "Status"]
+    // Looked up once, not per row: a month can have thousands of expenses
+    let category_colors: HashMap<&str, Color> = app
+        .data
+        .categories
         .iter()
-        .map(|h| {
-            Cell::from(*h).style(
-                Style::default()
-                    .fg(Color::Cyan)
-                    .add_modifier(Modifier::BOLD),
-            )
-        });
-    let header = Row::new(header_cells).height(1);
+        .map(|c| (c.name.as_str(), hex_to_color(&c.color)))
+        .collect();
+    let period_colors: HashMap<&str, Color> = app
+        .data
+        .periods
+        .iter()
+        .map(|p| (p.name.as_str(), hex_to_color(&p.color)))
+        .collect();
 
-    let filtered_expenses = app.filtered_expenses();
     let rows: Vec<Row> = filtered_expenses
         .iter()
         .map(|expense| {
-            // Find category color
-            let category_color = app
-                .data
-                .categories
-                .iter()
-                .find(|c| c.name == expense.category)
-                .map(|c| hex_to_color(&c.color))
+            let category_color = category_colors
+                .get(expense.category.as_str())
+                .copied()
                 .unwrap_or(Color::White);
-
-            // Find period color
-            let period_color = app
-                .data
-                .periods
-                .iter()
-                .find(|p| p.name == expense.period)
-                .map(|p| hex_to_color(&p.color))
+            let period_color = period_colors
+                .get(expense.period.as_str())
+                .copied()
                 .unwrap_or(Color::White);
 
             // Status
-            let over_projected = expense.cost > expense.projected;
-            let status_cell = if over_projected {
-                Cell::from("Over").style(Style::default().fg(Color::Red))
+            let status = app
+                .thresholds
+                .for_category(&expense.category)
+                .status(expense.cost, expense.projected);
+            let (status, color) = if app.is_pending_sync(EntityType::Expense, expense.id) {
+                ("Pending", Color::Yellow)
             } else {
-                Cell::from("OK").style(Style::default().fg(Color::Green))
+                (status.as_str(), status_color(status))
             };
 
+            let mut name = vec![Span::raw(expense.expense_name.clone())];
+            if let Some(flag) = app.tax_flags.expense_flag(&expense.expense_name) {
+                name.push(tax_flag_span(flag));
+            }
+            let ledger = app.ledgers.ledger(expense.id);
+            if ledger != Ledger::Personal {
+                name.push(ledger_span(ledger));
+            }
+            if expense.attachment_count() > 0 {
+                name.push(attachment_span(expense.attachment_count()));
+            }
+
+            let period = app.period_label(&expense.period).to_string();
+            let currency = app.rates.foreign(expense.currency.as_deref());
+            if app.ui.large_text {
+                return large_row(
+                    Line::from(name),
+                    Line::from(vec![
+                        Span::styled(period, Style::default().fg(period_color)),
+                        Span::raw(" · "),
+                        Span::styled(
+                            expense.category.clone(),
+                            Style::default().fg(category_color),
+                        ),
+                    ]),
+                    format!(
+                        "{} / {}",
+                        app.money.format_in(expense.cost, currency),
+                        app.money.format_in(expense.projected, currency)
+                    ),
+                    status,
+                    color,
+                );
+            }
             Row::new(vec![
-                Cell::from(expense.expense_name.clone()),
-                Cell::from(expense.period.clone()).style(Style::default().fg(period_color)),
+                Cell::from(Line::from(name)),
+                Cell::from(period).style(Style::default().fg(period_color)),
                 Cell::from(expense.category.clone()).style(Style::default().fg(category_color)),
-                Cell::from(format_currency(expense.projected)),
-                Cell::from(format_currency(expense.cost)),
-                status_cell,
+                Cell::from(app.money.format_in(expense.projected, currency)),
+                Cell::from(app.money.format_in(expense.cost, currency)),
+                Cell::from(status).style(Style::default().fg(color)),
             ])
         })
         .collect();
 
-    let widths = [
-        Constraint::Percentage(25),
-        Constraint::Percentage(15),
-        Constraint::Percentage(15),
-        Constraint::Percentage(15),
-        Constraint::Percentage(15),
-        Constraint::Percentage(15),
-    ];
+    let widths = if app.ui.large_text {
+        LARGE_WIDTHS.to_vec()
+    } else {
+        vec![
+            Constraint::Percentage(25),
+            Constraint::Percentage(15),
+            Constraint::Percentage(15),
+            Constraint::Percentage(15),
+            Constraint::Percentage(15),
+            Constraint::Percentage(15),
+        ]
+    };
 
     let table = Table::new(rows, widths)
         .header(header)
@@ -146,4 +187,179 @@ fn render_expense_table(app: &AppState, frame: &mut Frame, area: Rect) {
     // Create a mutable copy of table state for rendering
     let mut table_state = app.ui.expense_table.clone();
     frame.render_stateful_widget(table, area, &mut table_state);
+
+    if let Some(reason) = app.empty_list() {
+        empty_state::render_list(
+            app,
+            frame,
+            rows_area(area, app.ui.large_text),
+            reason,
+            "expenses",
+            "Add an expense",
+        );
+    }
+}
+
+/// Render the split view's pane out of focus
+///
+/// Only names and amounts fit in half the width; its filters are in the
+/// title, since the view bar shows the focused pane's.
+fn render_split_pane(app: &AppState, pane: &SplitPane, frame: &mut Frame, area: Rect) {
+    let month = app
+        .data
+        .months
+        .get(pane.month_index)
+        .map(|m| m.display_name())
+        .unwrap_or_default();
+    let mut title = format!(" {} - Expenses ({}) ", month, pane.expenses.len());
+    let filters: Vec<&str> = [&pane.period_filter, &pane.category_filter]
+        .into_iter()
+        .flatten()
+        .map(String::as_str)
+        .collect();
+    if !filters.is_empty() {
+        title = format!("{}[{}] ", title, filters.join(", "));
+    }
+
+    let rows: Vec<Row> = pane
+        .expenses
+        .iter()
+        .map(|expense| {
+            let currency = app.rates.foreign(expense.currency.as_deref());
+            Row::new(vec![
+                Cell::from(expense.expense_name.clone()),
+                Cell::from(app.money.format_in(expense.projected, currency)),
+                Cell::from(app.money.format_in(expense.cost, currency)),
+            ])
+        })
+        .collect();
+    let table = Table::new(
+        rows,
+        [
+            Constraint::Percentage(50),
+            Constraint::Percentage(25),
+            Constraint::Percentage(25),
+        ],
+    )
+    .header(header_row(&["Name", "Projected", "Cost"]))
+    .block(
+        Block::default()
+            .title(title)
+            .title_bottom(" Ctrl+W focus · M move · Y copy ")
+            .borders(Borders::ALL)
+            .border_style(Style::default().fg(Color::DarkGray)),
+    )
+    .style(Style::default().fg(Color::Gray));
+
+    let mut table_state = pane.table.clone();
+    frame.render_stateful_widget(table, area, &mut table_state);
+}
+
+/// Columns of the large-text layout: name and details, then amounts and status
+pub(crate) const LARGE_WIDTHS: [Constraint; 2] =
+    [Constraint::Percentage(55), Constraint::Percentage(45)];
+
+/// Border of the Expenses and Income tables, padded in the large-text layout
+pub(crate) fn table_block(app: &AppState, title: String) -> Block<'static> {
+    let block = Block::default()
+        .title(title)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::DarkGray));
+    if app.ui.large_text {
+        block.padding(Padding::horizontal(2))
+    } else {
+        block
+    }
+}
+
+/// Bold cyan column titles
+pub(crate) fn header_row(titles: &[&'static str]) -> Row<'static> {
+    let cells = titles.iter().map(|h| {
+        Cell::from(*h).style(
+            Style::default()
+                .fg(Color::Cyan)
+                .add_modifier(Modifier::BOLD),
+        )
+    });
+    Row::new(cells).height(1)
+}
+
+/// Row of the large-text layout
+///
+/// The name over its details on the left, the amounts over the status on the
+/// right, with a blank line below to keep rows apart.
+pub(crate) fn large_row<'a>(
+    name: Line<'a>,
+    details: Line<'a>,
+    amounts: String,
+    status: &str,
+    status_color: Color,
+) -> Row<'a> {
+    let bold = Style::default().add_modifier(Modifier::BOLD);
+    Row::new(vec![
+        Cell::from(Text::from(vec![name.patch_style(bold), details])),
+        Cell::from(Text::from(vec![
+            Line::styled(amounts, bold),
+            Line::styled(status.to_uppercase(), bold.fg(status_color)),
+        ])),
+    ])
+    .height(2)
+    .bottom_margin(1)
+}
+
+/// Items listed, with "+" or the server's total while more pages can be
+/// fetched by scrolling down
+pub(crate) fn list_count(listed: usize, more: bool, total: Option<usize>) -> String {
+    match (more, total) {
+        (true, Some(total)) => format!("{} of {}", listed, total),
+        (true, None) => format!("{}+", listed),
+        (false, _) => listed.to_string(),
+    }
+}
+
+/// Where a bordered table's rows go, below its header
+pub(crate) fn rows_area(area: Rect, large_text: bool) -> Rect {
+    let block = Block::default().borders(Borders::ALL);
+    let inner = if large_text {
+        block.padding(Padding::horizontal(2)).inner(area)
+    } else {
+        block.inner(area)
+    };
+    Rect {
+        y: inner.y + 1,
+        height: inner.height.saturating_sub(1),
+        ..inner
+    }
+}
+
+/// Marker for an expense or income with a tax flag
+pub(crate) fn tax_flag_span(flag: &str) -> Span<'static> {
+    Span::styled(format!(" [{}]", flag), Style::default().fg(Color::Magenta))
+}
+
+/// Marker for an expense with files attached, e.g. receipts
+fn attachment_span(count: usize) -> Span<'static> {
+    let label = match count {
+        1 => " [1 file]".to_string(),
+        n => format!(" [{} files]", n),
+    };
+    Span::styled(label, Style::default().fg(Color::Cyan))
+}
+
+/// Marker for an expense kept out of the personal ledger
+fn ledger_span(ledger: Ledger) -> Span<'static> {
+    let label = match ledger {
+        Ledger::Business => " [Biz]",
+        _ => " [Reimb]",
+    };
+    Span::styled(label, Style::default().fg(Color::Blue))
+}
+
+/// Color of a near/over budget status
+pub(crate) fn status_color(status: BudgetStatus) -> Color {
+    match status {
+        BudgetStatus::OnTrack => Color::Green,
+        BudgetStatus::Ahead | BudgetStatus::Near => Color::Yellow,
+        BudgetStatus::Over => Color::Red,
+    }
 }
@@ -1,76 +1,52 @@
 use ratatui::{
     layout::{Constraint, Layout, Rect},
     style::{Color, Modifier, Style},
-    widgets::{Block, Borders, Cell, Paragraph, Row, Table},
+    text::{Line, Span},
+    widgets::{Cell, Row, Table},
     Frame,
 };
 
-use crate::state::AppState;
-use crate::ui::{format_currency, hex_to_color};
+use crate::state::{AppState, EntityType};
+use crate::ui::components::{empty_state, view_bar};
+use crate::ui::hex_to_color;
+use crate::ui::tabs::expenses::{
+    header_row, large_row, list_count, rows_area, table_block, tax_flag_span, LARGE_WIDTHS,
+};
 
 /// Render the income tab
 pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
     let chunks = Layout::vertical([
-        Constraint::Length(3), // Filter bar
+        Constraint::Length(3), // View bar
         Constraint::Min(5),    // Income table
     ])
     .split(area);
 
-    // Render filter bar
-    render_filter_bar(app, frame, chunks[0]);
+    // Render filters, search, grouping and sort
+    view_bar::render(app, frame, chunks[0]);
 
     // Render income table
     render_income_table(app, frame, chunks[1]);
 }
 
-/// Render the filter bar
-fn render_filter_bar(app: &AppState, frame: &mut Frame, area: Rect) {
-    let block = Block::default()
-        .borders(Borders::BOTTOM)
-        .border_style(Style::default().fg(Color::DarkGray));
-
-    let inner = block.inner(area);
-    frame.render_widget(block, area);
-
-    let filter_chunks = Layout::horizontal([
-        Constraint::Length(20), // Period filter
-        Constraint::Min(10),    // Spacer
-        Constraint::Length(15), // Add button hint
-    ])
-    .split(inner);
-
-    // Period filter
-    let period_text = app
-        .ui
-        .period_filter
-        .as_ref()
-        .map_or("All Periods", |p| p.as_str());
-    let period =
-        Paragraph::new(format!(" [{}] ", period_text)).style(Style::default().fg(Color::White));
-    frame.render_widget(period, filter_chunks[0]);
-
-    // Add hint
-    let add_hint = Paragraph::new("[n] Add New").style(Style::default().fg(Color::Cyan));
-    frame.render_widget(add_hint, filter_chunks[2]);
-}
-
 /// Render the income table
 fn render_income_table(app: &AppState, frame: &mut Frame, area: Rect) {
-    let block = Block::default()
-        .title(format!(" Income ({}) ", app.filtered_incomes().len()))
-        .borders(Borders::ALL)
-        .border_style(Style::default().fg(Color::DarkGray));
-
-    let header_cells = ["Income Type", "Period", "Projected", "Amount", "Status"]
-        .iter()
-        .map(|h| {
-            Cell::from(*h).style(
-                Style::default()
-                    .fg(Color::Cyan)
-                    .add_modifier(Modifier::BOLD),
+    let block = table_block(
+        app,
+        format!(
+            " Income ({}) ",
+            list_count(
+                app.filtered_incomes().len(),
+                app.data.more_incomes.is_some(),
+                app.data.incomes_total
             )
-        });
-    let header = Row::new(header_cells).height(1);
+        ),
+    );
+
+    let header = if app.ui.large_text {
+        header_row(&["Income", "Amount / Projected"])
+    } else {
+        header_row(&["Income Type", "Period", "Projected", "Amount", "Status"])
+    };
 
     let filtered_incomes = app.filtered_incomes();
     let rows: Vec<Row> = filtered_incomes
@@ -100,31 +76,60 @@ fn render_income_table(app: &AppState, frame: &mut Frame, area: Rect) {
             } else {
                 0
             };
-            let status_cell = if pct >= 100 {
-                Cell::from(format!("{}%", pct)).style(Style::default().fg(Color::Green))
+            let (status, status_color) = if app.is_pending_sync(EntityType::Income, income.id) {
+                ("Pending".to_string(), Color::Yellow)
+            } else if pct >= 100 {
+                (format!("{}%", pct), Color::Green)
             } else if pct >= 75 {
-                Cell::from(format!("{}%", pct)).style(Style::default().fg(Color::Yellow))
+                (format!("{}%", pct), Color::Yellow)
             } else {
-                Cell::from(format!("{}%", pct)).style(Style::default().fg(Color::Red))
+                (format!("{}%", pct), Color::Red)
             };
 
+            let mut type_cell = vec![Span::styled(
+                type_name.clone(),
+                Style::default().fg(type_color),
+            )];
+            if let Some(flag) = app.tax_flags.income_flag(&type_name) {
+                type_cell.push(tax_flag_span(flag));
+            }
+
+            let period = app.period_label(&income.period).to_string();
+            let currency = app.rates.foreign(income.currency.as_deref());
+            if app.ui.large_text {
+                return large_row(
+                    Line::from(type_cell),
+                    Line::styled(period, Style::default().fg(period_color)),
+                    format!(
+                        "{} / {}",
+                        app.money.format_in(income.amount, currency),
+                        app.money.format_in(income.projected, currency)
+                    ),
+                    &status,
+                    status_color,
+                );
+            }
             Row::new(vec![
-                Cell::from(type_name).style(Style::default().fg(type_color)),
-                Cell::from(income.period.clone()).style(Style::default().fg(period_color)),
-                Cell::from(format_currency(income.projected)),
-                Cell::from(format_currency(income.amount)),
-                status_cell,
+                Cell::from(Line::from(type_cell)),
+                Cell::from(period).style(Style::default().fg(period_color)),
+                Cell::from(app.money.format_in(income.projected, currency)),
+                Cell::from(app.money.format_in(income.amount, currency)),
+                Cell::from(status).style(Style::default().fg(status_color)),
             ])
         })
         .collect();
 
-    let widths = [
-        Constraint::Percentage(25),
-        Constraint::Percentage(20),
-        Constraint::Percentage(20),
-        Constraint::Percentage(20),
-        Constraint::Percentage(15),
-    ];
+    let widths = if app.ui.large_text {
+        LARGE_WIDTHS.to_vec()
+    } else {
+        vec![
+            Constraint::Percentage(25),
+            Constraint::Percentage(20),
+            Constraint::Percentage(20),
+            Constraint::Percentage(20),
+            Constraint::Percentage(15),
+        ]
+    };
 
     let table = Table::new(rows, widths)
         .header(header)
@@ -138,4 +143,15 @@ fn render_income_table(app: &AppState, frame: &mut Frame, area: Rect) {
 
     let mut table_state = app.ui.income_table.clone();
     frame.render_stateful_widget(table, area, &mut table_state);
+
+    if let Some(reason) = app.empty_list() {
+        empty_state::render_list(
+            app,
+            frame,
+            rows_area(area, app.ui.large_text),
+            reason,
+            "incomes",
+            "Add an income",
+        );
+    }
 }
@@ -1,4 +1,5 @@
 pub mod charts;
+pub mod debts;
 pub mod expenses;
 pub mod income;
 pub mod settings;
@@ -6,8 +6,11 @@ use ratatui::{
     Frame,
 };
 
-use crate::state::{AppState, SettingsTab};
+use crate::models::Money;
+use crate::state::{AppState, ServerFeature, SettingsTab};
+use crate::ui::components::empty_state;
 use crate::ui::hex_to_color;
+use crate::ui::tabs::expenses::rows_area;
 
 /// Render the settings tab
 pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
@@ -31,6 +34,7 @@ pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
         SettingsTab::Categories => render_categories(app, frame, main_chunks[1]),
         SettingsTab::Periods => render_periods(app, frame, main_chunks[1]),
         SettingsTab::IncomeTypes => render_income_types(app, frame, main_chunks[1]),
+        SettingsTab::Accounts => render_accounts(app, frame, main_chunks[1]),
         SettingsTab::Password => render_password(app, frame, main_chunks[1]),
     }
 
@@ -77,7 +81,7 @@ fn render_sidebar(app: &AppState, frame: &mut Frame, area: Rect) {
 /// Render help bar at the bottom
 fn render_help_bar(frame: &mut Frame, area: Rect) {
     let help = Line::from(vec![
-        Span::styled(" 1-4 ", Style::default().fg(Color::Black).bg(Color::Cyan)),
+        Span::styled(" 1-5 ", Style::default().fg(Color::Black).bg(Color::Cyan)),
         Span::raw(" Section  "),
         Span::styled(" ↑↓ ", Style::default().fg(Color::Black).bg(Color::Cyan)),
         Span::raw(" Select item  "),
@@ -141,6 +145,15 @@ fn render_categories(app: &AppState, frame: &mut Frame, area: Rect) {
 
     let mut table_state = app.ui.category_table.clone();
     frame.render_stateful_widget(table, area, &mut table_state);
+
+    if app.data.categories.is_empty() {
+        empty_state::render(
+            frame,
+            rows_area(area, false),
+            "No categories yet",
+            &[("n", "Add a category".to_string())],
+        );
+    }
 }
 
 /// Render periods management
@@ -191,6 +204,15 @@ fn render_periods(app: &AppState, frame: &mut Frame, area: Rect) {
 
     let mut table_state = app.ui.period_table.clone();
     frame.render_stateful_widget(table, area, &mut table_state);
+
+    if app.data.periods.is_empty() {
+        empty_state::render(
+            frame,
+            rows_area(area, false),
+            "No periods yet",
+            &[("n", "Add a period".to_string())],
+        );
+    }
 }
 
 /// Render income types management
@@ -241,6 +263,116 @@ fn render_income_types(app: &AppState, frame: &mut Frame, area: Rect) {
 
     let mut table_state = app.ui.income_type_table.clone();
     frame.render_stateful_widget(table, area, &mut table_state);
+
+    if app.data.income_types.is_empty() {
+        empty_state::render(
+            frame,
+            rows_area(area, false),
+            "No income types yet",
+            &[("n", "Add an income type".to_string())],
+        );
+    }
+}
+
+/// Render accounts management, with how many of the month's entries use each
+fn render_accounts(app: &AppState, frame: &mut Frame, area: Rect) {
+    let transfers = !app.data.unsupported.contains(&ServerFeature::Transfers);
+    let title = if transfers {
+        format!(" Accounts ({}) · t Transfers ", app.data.accounts.len())
+    } else {
+        format!(" Accounts ({}) ", app.data.accounts.len())
+    };
+    let block = Block::default()
+        .title(title)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::DarkGray));
+
+    let header_cells = ["Name", "Kind", "This month", "Transfers"].iter().map(|h| {
+        Cell::from(*h).style(
+            Style::default()
+                .fg(Color::Cyan)
+                .add_modifier(Modifier::BOLD),
+        )
+    });
+    let header = Row::new(header_cells).height(1);
+
+    let rows: Vec<Row> = app
+        .data
+        .accounts
+        .iter()
+        .map(|account| {
+            let entries = app
+                .data
+                .expenses
+                .iter()
+                .filter(|e| e.account_id == Some(account.id))
+                .count()
+                + app
+                    .data
+                    .incomes
+                    .iter()
+                    .filter(|i| i.account_id == Some(account.id))
+                    .count();
+            // Net of the month's transfers in and out
+            let moved: f64 = app
+                .data
+                .transfers
+                .iter()
+                .map(|t| t.net_for(account.id))
+                .sum::<Money>()
+                .to_f64();
+            let moved_cell = if moved == 0.0 {
+                Cell::from("-").style(Style::default().fg(Color::DarkGray))
+            } else if moved > 0.0 {
+                Cell::from(format!("+{}", app.money.format(moved)))
+                    .style(Style::default().fg(Color::Green))
+            } else {
+                Cell::from(app.money.format(moved)).style(Style::default().fg(Color::Red))
+            };
+            Row::new(vec![
+                Cell::from(account.name.clone()),
+                Cell::from(account.kind.as_str()),
+                Cell::from(entries.to_string()),
+                moved_cell,
+            ])
+        })
+        .collect();
+
+    let widths = [
+        Constraint::Percentage(40),
+        Constraint::Percentage(20),
+        Constraint::Percentage(20),
+        Constraint::Percentage(20),
+    ];
+
+    let table = Table::new(rows, widths)
+        .header(header)
+        .block(block)
+        .row_highlight_style(
+            Style::default()
+                .bg(Color::Rgb(50, 50, 60))
+                .add_modifier(Modifier::BOLD),
+        )
+        .highlight_symbol("▶ ");
+
+    let mut table_state = app.ui.account_table.clone();
+    frame.render_stateful_widget(table, area, &mut table_state);
+
+    if app.data.unsupported.contains(&ServerFeature::Accounts) {
+        empty_state::render(
+            frame,
+            rows_area(area, false),
+            "This server doesn't keep accounts",
+            &[],
+        );
+    } else if app.data.accounts.is_empty() {
+        empty_state::render(
+            frame,
+            rows_area(area, false),
+            "No accounts yet",
+            &[("n", "Add an account".to_string())],
+        );
+    }
 }
 
 /// Render password change form
@@ -1,13 +1,18 @@
+use chrono::NaiveDate;
 use ratatui::{
     layout::{Constraint, Layout, Rect},
     style::{Color, Modifier, Style},
     text::{Line, Span},
-    widgets::{Block, Borders, Cell, Paragraph, Row, Table},
+    widgets::{Block, Borders, Cell, Padding, Paragraph, Row, Table},
     Frame,
 };
 
-use crate::state::AppState;
-use crate::ui::format_currency;
+use crate::models::{BudgetStatus, Money};
+use crate::state::currency::ConvertedTotals;
+use crate::state::envelopes::CategoryEnvelopes;
+use crate::state::{AppState, Suggestion, SuggestionKind};
+use crate::ui::progress_bar;
+use crate::ui::tabs::expenses::status_color;
 
 /// Render the summary tab
 pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
@@ -20,13 +25,33 @@ pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
         0
     };
 
+    // End-of-month suggestions, in the month's last days
+    let suggestions = app.end_of_month_suggestions(app.clock.today());
+    let suggestions_height = suggestions_height(suggestions.len());
+
+    // Weekly envelopes: one row per category, plus header and borders
+    let envelopes = app.weekly_envelopes();
+    let envelopes_height = if envelopes.is_empty() {
+        0
+    } else {
+        envelopes.len() as u16 + 3
+    };
+
+    // A line under the cards when they add up other currencies
+    let conversion_height = app.data.converted_totals.is_some() as u16;
+
     let chunks = Layout::vertical([
         Constraint::Length(insights_height), // Insights panel
         Constraint::Length(if insights_height > 0 { 1 } else { 0 }), // Spacer (only if insights shown)
+        Constraint::Length(suggestions_height),                      // End-of-month suggestions
+        Constraint::Length(if suggestions_height > 0 { 1 } else { 0 }), // Spacer
         Constraint::Length(7),                                       // Summary cards
+        Constraint::Length(conversion_height),                       // Currency conversion
         Constraint::Length(1),                                       // Spacer
         Constraint::Length(10),                                      // Period summary table
         Constraint::Length(1),                                       // Spacer
+        Constraint::Length(envelopes_height),                        // Weekly envelopes
+        Constraint::Length(if envelopes_height > 0 { 1 } else { 0 }), // Spacer
         Constraint::Min(8),                                          // Category and Income tables
     ])
     .split(area);
@@ -36,15 +61,27 @@ pub fn render(app: &AppState, frame: &mut Frame, area: Rect) {
         render_insights(app, frame, chunks[0]);
     }
 
+    if !suggestions.is_empty() {
+        render_suggestions(app, &suggestions, today, frame, chunks[2]);
+    }
+
     // Render summary cards
-    render_summary_cards(app, frame, chunks[2]);
+    render_summary_cards(app, frame, chunks[4]);
+
+    if let Some(converted) = &app.data.converted_totals {
+        render_conversion(app, converted, frame, chunks[5]);
+    }
 
     // Render period summary table
-    render_period_summary(app, frame, chunks[4]);
+    render_period_summary(app, frame, chunks[7]);
+
+    if !envelopes.is_empty() {
+        render_envelopes(app, &envelopes, frame, chunks[9]);
+    }
 
     // Split tables area horizontally
     let table_chunks = Layout::horizontal([Constraint::Percentage(50), Constraint::Percentage(50)])
-        .split(chunks[6]);
+        .split(chunks[11]);
 
     // Render category summary table
     render_category_summary(app, frame, table_chunks[0]);
@@ -123,6 +160,61 @@ fn render_insights(app: &AppState, frame: &mut Frame, area: Rect) {
     }
 }
 
+/// Most suggestions listed before the rest are counted
+const MAX_SUGGESTIONS: usize = 5;
+
+/// Rows of the suggestions panel for `count` suggestions, borders included
+pub(crate) fn suggestions_height(count: usize) -> u16 {
+    match count {
+        0 => 0,
+        n if n > MAX_SUGGESTIONS => MAX_SUGGESTIONS as u16 + 3,
+        n => n as u16 + 2,
+    }
+}
+
+/// Render the end-of-month suggestions
+fn render_suggestions(
+    app: &AppState,
+    suggestions: &[Suggestion],
+    today: NaiveDate,
+    frame: &mut Frame,
+    area: Rect,
+) {
+    let days_left = app.advisor_days_left(today).unwrap_or(1);
+    let title = match days_left {
+        1 => " Before the month ends (last day) ".to_string(),
+        n => format!(" Before the month ends ({} days left) ", n),
+    };
+    let block = Block::default()
+        .title(title)
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::DarkGray));
+
+    let mut lines: Vec<Line> = suggestions
+        .iter()
+        .take(MAX_SUGGESTIONS)
+        .map(|suggestion| {
+            let (icon, color) = match suggestion.kind {
+                SuggestionKind::NotReceived => ("↓", Color::Yellow),
+                SuggestionKind::NoCost => ("○", Color::Yellow),
+                SuggestionKind::UnderBudget => ("✂", Color::Cyan),
+            };
+            Line::from(vec![
+                Span::styled(format!("{} ", icon), Style::default().fg(color)),
+                Span::raw(suggestion.message.clone()),
+            ])
+        })
+        .collect();
+    if suggestions.len() > MAX_SUGGESTIONS {
+        lines.push(Line::styled(
+            format!("  and {} more", suggestions.len() - MAX_SUGGESTIONS),
+            Style::default().fg(Color::DarkGray),
+        ));
+    }
+
+    frame.render_widget(Paragraph::new(lines).block(block), area);
+}
+
 /// Render the summary cards (income, expenses, balance)
 fn render_summary_cards(app: &AppState, frame: &mut Frame, area: Rect) {
     let card_chunks = Layout::horizontal([
@@ -132,7 +224,14 @@ fn render_summary_cards(app: &AppState, frame: &mut Frame, area: Rect) {
     ])
     .split(area);
 
-    if let Some(ref totals) = app.data.summary_totals {
+    // The server adds other currencies up as they are
+    let totals = match &app.data.converted_totals {
+        Some(converted) => Some(&converted.totals),
+        None => app.data.summary_totals.as_ref(),
+    };
+    if let Some(totals) = totals {
+        let pace = app.month_pace();
+
         // Income card
         let income_pct = if totals.total_projected_income > 0.0 {
             (totals.total_current_income / totals.total_projected_income * 100.0).min(100.0)
@@ -143,10 +242,12 @@ fn render_summary_cards(app: &AppState, frame: &mut Frame, area: Rect) {
             frame,
             card_chunks[0],
             "Income",
-            &format_currency(totals.total_current_income),
-            &format!("of {}", format_currency(totals.total_projected_income)),
+            &app.money.format(totals.total_current_income),
+            &format!("of {}", app.money.format(totals.total_projected_income)),
             income_pct,
             Color::Green,
+            pace,
+            app.ui.large_text,
         );
 
         // Expenses card
@@ -155,19 +256,31 @@ fn render_summary_cards(app: &AppState, frame: &mut Frame, area: Rect) {
         } else {
             0.0
         };
-        let expense_color = if totals.expenses_over_projected() {
-            Color::Red
-        } else {
-            Color::Yellow
+        let expense_status = expense_status(
+            app,
+            totals.total_current_expenses,
+            totals.total_projected_expenses,
+            "",
+        );
+        let expense_color = match expense_status {
+            BudgetStatus::Over => Color::Red,
+            _ => Color::Yellow,
         };
+        let mut expense_subtitle =
+            format!("of {}", app.money.format(totals.total_projected_expenses));
+        if expense_status == BudgetStatus::Ahead {
+            expense_subtitle.push_str(" - ahead of pace");
+        }
         render_card(
             frame,
             card_chunks[1],
             "Expenses",
-            &format_currency(totals.total_current_expenses),
-            &format!("of {}", format_currency(totals.total_projected_expenses)),
+            &app.money.format(totals.total_current_expenses),
+            &expense_subtitle,
             expense_pct,
             expense_color,
+            pace,
+            app.ui.large_text,
         );
 
         // Balance card
@@ -187,10 +300,12 @@ fn render_summary_cards(app: &AppState, frame: &mut Frame, area: Rect) {
             frame,
             card_chunks[2],
             "Balance",
-            &format_currency(balance),
-            &format!("of {}", format_currency(projected_balance)),
+            &app.money.format(balance),
+            &format!("of {}", app.money.format(projected_balance)),
             balance_pct,
             balance_color,
+            None,
+            app.ui.large_text,
         );
     } else {
         // No data
@@ -201,7 +316,47 @@ fn render_summary_cards(app: &AppState, frame: &mut Frame, area: Rect) {
     }
 }
 
+/// Which currencies the cards converted, and any they couldn't
+fn render_conversion(app: &AppState, converted: &ConvertedTotals, frame: &mut Frame, area: Rect) {
+    let mut spans = vec![Span::styled(
+        format!(
+            " Totals in {}, converted from {}",
+            app.rates.base(),
+            converted.currencies.join(", ")
+        ),
+        Style::default().fg(Color::DarkGray),
+    )];
+    if !converted.missing.is_empty() {
+        spans.push(Span::styled(
+            format!(" - no rate for {}, left out", converted.missing.join(", ")),
+            Style::default().fg(Color::Yellow),
+        ));
+    }
+    frame.render_widget(Paragraph::new(Line::from(spans)), area);
+}
+
+/// Near/over status of spending, also flagging it when ahead of the month's pace
+///
+/// An empty `category` uses the global thresholds.
+pub(crate) fn expense_status(
+    app: &AppState,
+    actual: f64,
+    projected: f64,
+    category: &str,
+) -> BudgetStatus {
+    let thresholds = if category.is_empty() {
+        app.thresholds.global()
+    } else {
+        app.thresholds.for_category(category)
+    };
+    match app.month_pace() {
+        Some(pace) => thresholds.status_with_pace(actual, projected, pace),
+        None => thresholds.status(actual, projected),
+    }
+}
+
 /// Render a single summary card
+#[allow(clippy::too_many_arguments)]
 fn render_card(
     frame: &mut Frame,
     area: Rect,
@@ -210,11 +365,16 @@ fn render_card(
     subtitle: &str,
     percentage: f64,
     color: Color,
+    pace: Option<f64>,
+    large: bool,
 ) {
-    let block = Block::default()
+    let mut block = Block::default()
         .title(format!(" {} ", title))
         .borders(Borders::ALL)
         .border_style(Style::default().fg(Color::DarkGray));
+    if large {
+        block = block.padding(Padding::horizontal(1));
+    }
 
     let inner = block.inner(area);
     frame.render_widget(block, area);
@@ -226,7 +386,11 @@ fn render_card(
     ])
     .split(inner);
 
-    // Value
+    // Value, letter-spaced in the large-text layout when it fits
+    let mut value = value.to_string();
+    if large && value.chars().count() * 2 <= chunks[0].width as usize {
+        value = spaced(&value);
+    }
     let value_para =
         Paragraph::new(value).style(Style::default().fg(color).add_modifier(Modifier::BOLD));
     frame.render_widget(value_para, chunks[0]);
@@ -236,25 +400,26 @@ fn render_card(
     frame.render_widget(subtitle_para, chunks[1]);
 
     // Progress bar
-    let bar_width = chunks[2].width.saturating_sub(2);
-    let filled_width = ((percentage / 100.0) * bar_width as f64) as usize;
-    let empty_width = bar_width as usize - filled_width;
-
-    let bar_spans = vec![
-        Span::styled("█".repeat(filled_width), Style::default().fg(color)),
-        Span::styled(
-            "░".repeat(empty_width),
-            Style::default().fg(Color::DarkGray),
-        ),
-    ];
-    let bar = Paragraph::new(Line::from(bar_spans));
+    let bar_width = chunks[2].width.saturating_sub(2) as usize;
+    let bar = Paragraph::new(progress_bar(bar_width, percentage / 100.0, color, pace));
     frame.render_widget(bar, chunks[2]);
 }
 
+/// `text` with a space between its characters, so it reads larger
+fn spaced(text: &str) -> String {
+    let chars: Vec<String> = text.chars().map(String::from).collect();
+    chars.join(" ")
+}
+
 /// Render the period summary table
 fn render_period_summary(app: &AppState, frame: &mut Frame, area: Rect) {
+    // The month's own dates, which needn't be the calendar month's
+    let title = match app.selected_month().and_then(|m| app.dates.month_range(m)) {
+        Some(range) => format!(" Summary by Period - {} ", range),
+        None => " Summary by Period ".to_string(),
+    };
     let block = Block::default()
-        .title(" Summary by Period ")
+        .title(title)
         .borders(Borders::ALL)
         .border_style(Style::default().fg(Color::DarkGray));
 
@@ -270,9 +435,10 @@ fn render_period_summary(app: &AppState, frame: &mut Frame, area: Rect) {
     let header = Row::new(header_cells).height(1);
 
     let mut rows: Vec<Row> = if let Some(ref period_summary) = app.data.period_summary {
-        period_summary
-            .periods
-            .iter()
+        let mut periods: Vec<_> = period_summary.periods.iter().collect();
+        periods.sort_by_key(|ps| app.period_display.rank(&ps.period));
+        periods
+            .into_iter()
             .map(|ps| {
                 let diff_color = if ps.difference >= 0.0 {
                     Color::Green
@@ -280,12 +446,12 @@ fn render_period_summary(app: &AppState, frame: &mut Frame, area: Rect) {
                     Color::Red
                 };
                 Row::new(vec![
-                    Cell::from(ps.period.clone()),
-                    Cell::from(format_currency(ps.total_income))
+                    Cell::from(app.period_label(&ps.period).to_string()),
+                    Cell::from(app.money.format(ps.total_income))
                         .style(Style::default().fg(Color::Green)),
-                    Cell::from(format_currency(ps.total_expenses))
+                    Cell::from(app.money.format(ps.total_expenses))
                         .style(Style::default().fg(Color::Red)),
-                    Cell::from(format_currency(ps.difference))
+                    Cell::from(app.money.format(ps.difference))
                         .style(Style::default().fg(diff_color)),
                 ])
             })
@@ -303,14 +469,14 @@ fn render_period_summary(app: &AppState, frame: &mut Frame, area: Rect) {
         };
         let total_row = Row::new(vec![
             Cell::from("Total").style(Style::default().add_modifier(Modifier::BOLD)),
-            Cell::from(format_currency(period_summary.grand_total_income)).style(
+            Cell::from(app.money.format(period_summary.grand_total_income)).style(
                 Style::default()
                     .fg(Color::Green)
                     .add_modifier(Modifier::BOLD),
             ),
-            Cell::from(format_currency(period_summary.grand_total_expenses))
+            Cell::from(app.money.format(period_summary.grand_total_expenses))
                 .style(Style::default().fg(Color::Red).add_modifier(Modifier::BOLD)),
-            Cell::from(format_currency(period_summary.grand_total_difference)).style(
+            Cell::from(app.money.format(period_summary.grand_total_difference)).style(
                 Style::default()
                     .fg(total_diff_color)
                     .add_modifier(Modifier::BOLD),
@@ -357,15 +523,16 @@ fn render_category_summary(app: &AppState, frame: &mut Frame, area: Rect) {
         .category_summary
         .iter()
         .map(|cs| {
-            let status = if cs.over_projected {
-                Cell::from("Over").style(Style::default().fg(Color::Red))
-            } else {
-                Cell::from("On Track").style(Style::default().fg(Color::Green))
+            let status = expense_status(app, cs.total, cs.projected, &cs.category);
+            let label = match status {
+                BudgetStatus::OnTrack => "On Track",
+                status => status.as_str(),
             };
+            let status = Cell::from(label).style(Style::default().fg(status_color(status)));
             Row::new(vec![
                 Cell::from(cs.category.clone()),
-                Cell::from(format_currency(cs.projected)),
-                Cell::from(format_currency(cs.total)),
+                Cell::from(app.money.format(cs.projected)),
+                Cell::from(app.money.format(cs.total)),
                 status,
             ])
         })
@@ -377,14 +544,22 @@ fn render_category_summary(app: &AppState, frame: &mut Frame, area: Rect) {
         .category_summary
         .iter()
         .map(|cs| cs.projected)
-        .sum();
-    let total_actual: f64 = app.data.category_summary.iter().map(|cs| cs.total).sum();
+        .sum::<Money>()
+        .to_f64();
+    let total_actual: f64 = app
+        .data
+        .category_summary
+        .iter()
+        .map(|cs| cs.total)
+        .sum::<Money>()
+        .to_f64();
     let total_paid_capped: f64 = app
         .data
         .category_summary
         .iter()
         .map(|cs| cs.total.min(cs.projected))
-        .sum();
+        .sum::<Money>()
+        .to_f64();
     let diff_without_over = total_projected - total_paid_capped;
     let diff_with_over = total_projected - total_actual;
 
@@ -396,9 +571,9 @@ fn render_category_summary(app: &AppState, frame: &mut Frame, area: Rect) {
     };
     let projected_control_row = Row::new(vec![
         Cell::from("Projected Control").style(Style::default().fg(Color::DarkGray)),
-        Cell::from(format_currency(total_projected)).style(Style::default().fg(Color::White)),
-        Cell::from(format_currency(total_paid_capped)).style(Style::default().fg(Color::White)),
-        Cell::from(format_currency(diff_without_over))
+        Cell::from(app.money.format(total_projected)).style(Style::default().fg(Color::White)),
+        Cell::from(app.money.format(total_paid_capped)).style(Style::default().fg(Color::White)),
+        Cell::from(app.money.format(diff_without_over))
             .style(Style::default().fg(projected_control_diff_color)),
     ]);
     rows.push(projected_control_row);
@@ -411,17 +586,17 @@ fn render_category_summary(app: &AppState, frame: &mut Frame, area: Rect) {
     };
     let total_row = Row::new(vec![
         Cell::from("Total (with over)").style(Style::default().add_modifier(Modifier::BOLD)),
-        Cell::from(format_currency(total_projected)).style(
+        Cell::from(app.money.format(total_projected)).style(
             Style::default()
                 .fg(Color::White)
                 .add_modifier(Modifier::BOLD),
         ),
-        Cell::from(format_currency(total_actual)).style(
+        Cell::from(app.money.format(total_actual)).style(
             Style::default()
                 .fg(Color::White)
                 .add_modifier(Modifier::BOLD),
         ),
-        Cell::from(format_currency(diff_with_over)).style(
+        Cell::from(app.money.format(diff_with_over)).style(
             Style::default()
                 .fg(total_diff_color)
                 .add_modifier(Modifier::BOLD),
@@ -444,6 +619,80 @@ fn render_category_summary(app: &AppState, frame: &mut Frame, area: Rect) {
     frame.render_widget(table, area);
 }
 
+/// Render the weekly envelopes table
+///
+/// Each week shows spent of budget, colored by the category's thresholds;
+/// the current week is judged against how far into it we are.
+fn render_envelopes(
+    app: &AppState,
+    envelopes: &[CategoryEnvelopes],
+    frame: &mut Frame,
+    area: Rect,
+) {
+    let block = Block::default()
+        .title(" Weekly Envelopes ")
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(Color::DarkGray));
+
+    let today = app.clock.today();
+    let weeks = match envelopes.first() {
+        Some(first) => &first.weeks,
+        None => return,
+    };
+    let has_undated = envelopes.iter().any(|e| e.undated > 0.0);
+
+    let header_style = Style::default()
+        .fg(Color::Cyan)
+        .add_modifier(Modifier::BOLD);
+    let mut header_cells = vec![Cell::from("Category").style(header_style)];
+    for week in weeks {
+        let style = if week.contains(today) {
+            header_style.fg(Color::Yellow)
+        } else {
+            header_style
+        };
+        header_cells.push(Cell::from(format!("Days {}", week.label())).style(style));
+    }
+    if has_undated {
+        header_cells.push(Cell::from("Undated").style(header_style));
+    }
+    let header = Row::new(header_cells).height(1);
+
+    let rows: Vec<Row> = envelopes
+        .iter()
+        .map(|envelope| {
+            let thresholds = app.thresholds.for_category(&envelope.category);
+            let mut cells = vec![Cell::from(envelope.category.clone())];
+            for week in &envelope.weeks {
+                let status = if week.contains(today) && app.thresholds.pace {
+                    thresholds.status_with_pace(week.spent, week.budget, week.pace(today))
+                } else {
+                    thresholds.status(week.spent, week.budget)
+                };
+                cells.push(
+                    Cell::from(format!("{:.0} / {:.0}", week.spent, week.budget))
+                        .style(Style::default().fg(status_color(status))),
+                );
+            }
+            if has_undated {
+                cells.push(
+                    Cell::from(format!("{:.0}", envelope.undated))
+                        .style(Style::default().fg(Color::DarkGray)),
+                );
+            }
+            Row::new(cells)
+        })
+        .collect();
+
+    let columns = weeks.len() + usize::from(has_undated);
+    let mut widths = vec![Constraint::Length(16)];
+    widths.extend((0..columns).map(|_| Constraint::Ratio(1, columns as u32)));
+
+    let table = Table::new(rows, widths).header(header).block(block);
+
+    frame.render_widget(table, area);
+}
+
 /// Render the income type summary table
 fn render_income_summary(app: &AppState, frame: &mut Frame, area: Rect) {
     let block = Block::default()
@@ -467,8 +716,8 @@ fn render_income_summary(app: &AppState, frame: &mut Frame, area: Rect) {
         .map(|its| {
             Row::new(vec![
                 Cell::from(its.income_type.clone()),
-                Cell::from(format_currency(its.projected)),
-                Cell::from(format_currency(its.total)),
+                Cell::from(app.money.format(its.projected)),
+                Cell::from(app.money.format(its.total)),
             ])
         })
         .collect();
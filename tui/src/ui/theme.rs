@@ -0,0 +1,150 @@
+//! Color themes
+//!
+//! Screens are drawn with the basic named colors: cyan titles, green and red
+//! amounts, dark gray borders and hints. A theme replaces each of them once a
+//! frame is rendered, so the app can match the terminal's palette without
+//! recompiling. Category, period and income type colors come from the server
+//! and are kept as they are.
+
+use std::str::FromStr;
+
+use anyhow::{anyhow, Result};
+use ratatui::{buffer::Buffer, style::Color};
+use serde::{Deserialize, Serialize};
+
+/// Theme used when none is configured, which keeps the named colors
+pub const DEFAULT_THEME: &str = "default";
+
+/// Themes that need no config
+pub const BUILTIN_THEMES: &[&str] = &[DEFAULT_THEME, "solarized", "gruvbox"];
+
+/// Colors replacing the named ones the screens use
+///
+/// Values are hex (`"#268bd2"`), color names (`"light blue"`) or 256-color
+/// indexes (`"109"`); a color left out stays as it is.
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct Theme {
+    /// Titles, headers and the focused field (cyan)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub accent: Option<String>,
+    /// Plain text (white)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub text: Option<String>,
+    /// Secondary text (gray)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub subtle: Option<String>,
+    /// Borders and key hints (dark gray)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub muted: Option<String>,
+    /// Behind dialogs and the selected row's text (black)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub background: Option<String>,
+    /// Income, money left and success messages (green)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub positive: Option<String>,
+    /// Expenses, overspending and errors (red)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub negative: Option<String>,
+    /// Near budget, pending and selected items (yellow)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub warning: Option<String>,
+    /// Tags and badges (magenta)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub highlight: Option<String>,
+    /// Links and informational badges (blue)
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub info: Option<String>,
+}
+
+impl Theme {
+    /// A theme that comes with the app
+    pub fn builtin(name: &str) -> Option<Theme> {
+        let color = |value: &str| Some(value.to_string());
+        match name {
+            DEFAULT_THEME => Some(Theme::default()),
+            "solarized" => Some(Theme {
+                accent: color("#2aa198"),
+                text: color("#93a1a1"),
+                subtle: color("#839496"),
+                muted: color("#586e75"),
+                background: color("#002b36"),
+                positive: color("#859900"),
+                negative: color("#dc322f"),
+                warning: color("#b58900"),
+                highlight: color("#d33682"),
+                info: color("#268bd2"),
+            }),
+            "gruvbox" => Some(Theme {
+                accent: color("#8ec07c"),
+                text: color("#ebdbb2"),
+                subtle: color("#d5c4a1"),
+                muted: color("#928374"),
+                background: color("#282828"),
+                positive: color("#b8bb26"),
+                negative: color("#fb4934"),
+                warning: color("#fabd2f"),
+                highlight: color("#d3869b"),
+                info: color("#83a598"),
+            }),
+            _ => None,
+        }
+    }
+
+    /// The replacement of each named color, checking every value parses
+    pub fn color_map(&self) -> Result<ColorMap> {
+        let slots = [
+            ("accent", Color::Cyan, &self.accent),
+            ("text", Color::White, &self.text),
+            ("subtle", Color::Gray, &self.subtle),
+            ("muted", Color::DarkGray, &self.muted),
+            ("background", Color::Black, &self.background),
+            ("positive", Color::Green, &self.positive),
+            ("negative", Color::Red, &self.negative),
+            ("warning", Color::Yellow, &self.warning),
+            ("highlight", Color::Magenta, &self.highlight),
+            ("info", Color::Blue, &self.info),
+        ];
+        let mut replacements = Vec::new();
+        for (name, named, value) in slots {
+            if let Some(value) = value {
+                let color = Color::from_str(value.trim())
+                    .map_err(|_| anyhow!("Theme color {} = \"{}\" isn't a color", name, value))?;
+                if color != named {
+                    replacements.push((named, color));
+                }
+            }
+        }
+        Ok(ColorMap { replacements })
+    }
+}
+
+/// A theme ready to apply to rendered frames
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct ColorMap {
+    replacements: Vec<(Color, Color)>,
+}
+
+impl ColorMap {
+    /// Check if it keeps every color, like the default theme
+    pub fn is_empty(&self) -> bool {
+        self.replacements.is_empty()
+    }
+
+    pub fn map(&self, color: Color) -> Color {
+        self.replacements
+            .iter()
+            .find(|(named, _)| *named == color)
+            .map_or(color, |(_, replacement)| *replacement)
+    }
+
+    /// Recolor a rendered frame
+    pub fn apply(&self, buffer: &mut Buffer) {
+        if self.is_empty() {
+            return;
+        }
+        for cell in buffer.content.iter_mut() {
+            cell.fg = self.map(cell.fg);
+            cell.bg = self.map(cell.bg);
+        }
+    }
+}
@@ -0,0 +1,1752 @@
+//! API client tests for the Budget TUI application
+
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+
+use budget_tui::api::{
+    endpoint_key, expense_pages, format_body, in_range, parse_retry_after, ApiClient, ApiError,
+    BudgetApi, ChangeEvent, ClientOptions, FieldError, LiveEvent, MockApi, MockData, Outcome,
+    RequestContext, RequestHook, RequestInfo, RequestMetrics, ResponseCache, ResponseInfo,
+    RetryPolicy, SseMessage, SseParser, StaticHeaders, MAX_BODY_CHARS,
+};
+use budget_tui::models::{
+    Category, CategorySummary, CustomFields, DevicePoll, Expense, ExpenseBulkUpdate, ExpenseCreate,
+    ExpenseFilters, ExpenseUpdate, IncomeCreate, IncomeFilters, KeyScopes, LoginResponse, Month,
+    PageRequest, PayExpenseRequest, Scope, SummaryTotals, UserCreate,
+};
+use budget_tui::state::rollover::{calendar_month, previous_month};
+use budget_tui::state::{AppState, Modal, ServerFeature};
+use chrono::NaiveDate;
+use reqwest::header::{HeaderMap, HeaderValue};
+use tokio::io::{AsyncReadExt, AsyncWriteExt};
+use tokio::net::TcpListener;
+
+fn client() -> ApiClient {
+    // Nothing listens here; tests must not depend on the network
+    ApiClient::new("http://127.0.0.1:9".to_string(), "test-key".to_string()).unwrap()
+}
+
+#[test]
+fn test_request_context_cancel() {
+    let context = RequestContext::new();
+    assert!(!context.is_cancelled());
+
+    let clone = context.clone();
+    clone.cancel();
+    assert!(context.is_cancelled());
+}
+
+#[tokio::test]
+async fn test_request_context_cancelled_wakes() {
+    let context = RequestContext::new();
+    let waiter = context.clone();
+    let handle = tokio::spawn(async move { waiter.cancelled().await });
+
+    context.cancel();
+    tokio::time::timeout(Duration::from_secs(1), handle)
+        .await
+        .expect("cancelled() did not return after cancel")
+        .unwrap();
+}
+
+#[test]
+fn test_new_context_cancels_previous() {
+    let api = client();
+    let first = api.context();
+    let second = api.new_context();
+
+    assert!(first.is_cancelled());
+    assert!(!second.is_cancelled());
+    assert!(!api.context().is_cancelled());
+}
+
+#[tokio::test]
+async fn test_request_under_cancelled_context_fails_fast() {
+    let api = client();
+    api.context().cancel();
+
+    let result: Result<Vec<Month>, _> = api.get("/months").await;
+    assert!(matches!(result, Err(ApiError::Cancelled)));
+}
+
+#[test]
+fn test_retry_backoff_doubles_and_caps() {
+    let policy = RetryPolicy {
+        max_retries: 5,
+        base_delay: Duration::from_millis(100),
+        max_delay: Duration::from_millis(1000),
+    };
+
+    assert_eq!(policy.backoff(0), Duration::from_millis(100));
+    assert_eq!(policy.backoff(1), Duration::from_millis(200));
+    assert_eq!(policy.backoff(3), Duration::from_millis(800));
+    assert_eq!(policy.backoff(4), Duration::from_millis(1000));
+    assert_eq!(policy.backoff(40), Duration::from_millis(1000));
+}
+
+#[test]
+fn test_retry_delay_jitter_range() {
+    let policy = RetryPolicy::default();
+    let backoff = policy.backoff(2);
+
+    assert_eq!(policy.delay(2, 0.0), backoff / 2);
+    assert_eq!(policy.delay(2, 1.0), backoff);
+    assert!(policy.delay(2, 0.5) > backoff / 2);
+    assert!(policy.delay(2, 0.5) < backoff);
+    // Out-of-range jitter is clamped
+    assert_eq!(policy.delay(2, 7.0), backoff);
+}
+
+#[test]
+fn test_retry_policy_setting() {
+    let api = client();
+    assert_eq!(api.retry_policy(), RetryPolicy::default());
+
+    api.set_retry_policy(RetryPolicy::none());
+    assert_eq!(api.retry_policy().max_retries, 0);
+}
+
+#[tokio::test]
+async fn test_connection_refused_retries_then_fails() {
+    let api = client();
+    api.set_retry_policy(RetryPolicy {
+        max_retries: 2,
+        base_delay: Duration::from_millis(1),
+        max_delay: Duration::from_millis(5),
+    });
+
+    let result: Result<Vec<Month>, _> = api.get("/months").await;
+    assert!(matches!(result, Err(ApiError::Network(_))));
+}
+
+#[tokio::test]
+async fn test_not_implemented_is_not_retried() {
+    let (base_url, server) = serve(vec![json_response(
+        "501 Not Implemented",
+        r#"{"detail":"Not implemented"}"#,
+    )])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    api.set_retry_policy(RetryPolicy {
+        max_retries: 2,
+        base_delay: Duration::from_millis(1),
+        max_delay: Duration::from_millis(5),
+    });
+
+    let result: Result<Vec<Month>, _> = api.get("/summary/by-income-type").await;
+
+    let err = result.unwrap_err();
+    assert!(matches!(err, ApiError::NotImplemented));
+    assert!(err.is_unsupported());
+    assert!(!ApiError::Server("down".to_string()).is_unsupported());
+    assert_eq!(server.await.unwrap().len(), 1);
+}
+
+struct RecordingHook(Arc<Mutex<Vec<String>>>);
+
+impl RequestHook for RecordingHook {
+    fn after_response(&self, request: &RequestInfo, response: &ResponseInfo) {
+        self.0.lock().unwrap().push(format!(
+            "{} {} #{} -> {:?}",
+            request.method, request.path, request.attempt, response.status
+        ));
+    }
+}
+
+#[tokio::test]
+async fn test_request_hooks_run_for_each_attempt() {
+    let (base_url, server) = serve(vec![
+        json_response("503 Service Unavailable", r#"{"detail":"busy"}"#),
+        json_response("200 OK", "[]"),
+    ])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    api.set_retry_policy(RetryPolicy {
+        max_retries: 2,
+        base_delay: Duration::from_millis(1),
+        max_delay: Duration::from_millis(5),
+    });
+
+    let seen = Arc::new(Mutex::new(Vec::new()));
+    api.add_hook(RecordingHook(seen.clone()));
+    api.add_hook(|request: &RequestInfo, headers: &mut HeaderMap| {
+        let trace = format!("trace-{}", request.attempt);
+        headers.insert("x-trace-id", HeaderValue::from_str(&trace).unwrap());
+    });
+    api.add_hook(StaticHeaders::new([("X-Tenant", "home")]).unwrap());
+
+    let _: Vec<Month> = api.get("/months").await.unwrap();
+
+    assert_eq!(
+        *seen.lock().unwrap(),
+        vec![
+            "GET /months #0 -> Some(503)".to_string(),
+            "GET /months #1 -> Some(200)".to_string(),
+        ]
+    );
+    let requests = server.await.unwrap();
+    assert!(requests[0].contains("x-trace-id: trace-0"));
+    assert!(requests[1].contains("x-trace-id: trace-1"));
+    assert!(requests[1].contains("x-tenant: home"));
+
+    api.clear_hooks();
+    assert!(StaticHeaders::new([("bad name", "x")]).is_err());
+    assert!(StaticHeaders::new([("X-Ok", "line\nbreak")]).is_err());
+}
+
+#[tokio::test]
+async fn test_validation_errors_name_fields() {
+    let fastapi =
+        r#"{"detail":[{"loc":["body","expense_name"],"msg":"field required","type":"missing"}]}"#;
+    let zod = r#"{"success":false,"error":{"name":"ZodError","issues":[{"path":["budget"],"message":"Expected number"}]}}"#;
+    let (base_url, _server) = serve(vec![
+        json_response("422 Unprocessable Entity", fastapi),
+        json_response("400 Bad Request", zod),
+        json_response("400 Bad Request", r#"{"detail":"Month is closed"}"#),
+    ])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+
+    let err = api.get::<Vec<Month>>("/months").await.unwrap_err();
+    assert_eq!(
+        err.field_errors(),
+        &[FieldError {
+            field: Some("expense_name".to_string()),
+            message: "field required".to_string(),
+        }]
+    );
+    assert_eq!(
+        err.to_string(),
+        "Invalid input - expense_name: field required"
+    );
+
+    let err = api.get::<Vec<Month>>("/months").await.unwrap_err();
+    assert_eq!(err.field_errors()[0].field.as_deref(), Some("budget"));
+
+    // A plain message is still a bad request
+    let err = api.get::<Vec<Month>>("/months").await.unwrap_err();
+    assert!(matches!(err, ApiError::BadRequest(ref m) if m == "Month is closed"));
+    assert!(err.field_errors().is_empty());
+}
+
+#[tokio::test]
+async fn test_rejected_token_is_reported_once() {
+    let unauthorized = json_response("401 Unauthorized", r#"{"detail":"Token expired"}"#);
+    let (base_url, _server) = serve(vec![unauthorized.clone(), unauthorized]).await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+
+    // Wrong credentials at login aren't an expired session
+    let err = api.get::<Vec<Month>>("/months").await.unwrap_err();
+    assert!(matches!(err, ApiError::Unauthorized));
+    assert!(!api.take_token_rejected());
+
+    api.set_token("stale".to_string());
+    let _ = api.get::<Vec<Month>>("/months").await;
+    assert!(api.take_token_rejected());
+    assert!(!api.take_token_rejected());
+}
+
+#[test]
+fn test_response_cache_cacheable_endpoints() {
+    assert!(ResponseCache::is_cacheable("/months"));
+    assert!(ResponseCache::is_cacheable("/months/current"));
+    assert!(ResponseCache::is_cacheable("/categories"));
+    assert!(ResponseCache::is_cacheable("/periods/3"));
+    assert!(ResponseCache::is_cacheable("/income-types?limit=10"));
+    assert!(!ResponseCache::is_cacheable("/expenses"));
+    assert!(!ResponseCache::is_cacheable("/summary/totals"));
+}
+
+#[test]
+fn test_response_cache_insert_and_clear() {
+    let mut cache = ResponseCache::new();
+    cache.insert("/categories", "\"v1\"".to_string(), "[]".to_string());
+    cache.insert("/expenses", "\"v1\"".to_string(), "[]".to_string());
+
+    assert_eq!(cache.len(), 1);
+    assert_eq!(cache.get("/categories").unwrap().etag, "\"v1\"");
+    assert!(cache.get("/expenses").is_none());
+
+    cache.clear();
+    assert!(cache.is_empty());
+}
+
+/// Serve each request with the next canned response and return the requests received
+async fn serve(responses: Vec<String>) -> (String, tokio::task::JoinHandle<Vec<String>>) {
+    let listener = TcpListener::bind("127.0.0.1:0").await.unwrap();
+    let base_url = format!("http://{}", listener.local_addr().unwrap());
+
+    let handle = tokio::spawn(async move {
+        let mut requests = Vec::new();
+        for response in responses {
+            let (mut socket, _) = listener.accept().await.unwrap();
+            let mut buf = vec![0; 4096];
+            let n = socket.read(&mut buf).await.unwrap();
+            requests.push(String::from_utf8_lossy(&buf[..n]).to_lowercase());
+            socket.write_all(response.as_bytes()).await.unwrap();
+        }
+        requests
+    });
+
+    (base_url, handle)
+}
+
+#[tokio::test]
+async fn test_post_reuses_idempotency_key_until_settled() {
+    let (base_url, server) = serve(vec![
+        json_response("500 Internal Server Error", r#"{"detail":"boom"}"#),
+        json_response("200 OK", r##"{"id":1,"name":"Food","color":"#22c55e"}"##),
+        json_response("200 OK", r##"{"id":2,"name":"Food","color":"#22c55e"}"##),
+    ])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    api.set_retry_policy(RetryPolicy::none());
+    let body = serde_json::json!({"name": "Food"});
+
+    assert!(api.post::<_, Category>("/categories", &body).await.is_err());
+    api.post::<_, Category>("/categories", &body).await.unwrap();
+    api.post::<_, Category>("/categories", &body).await.unwrap();
+
+    let requests = server.await.unwrap();
+    let key = |request: &str| {
+        request
+            .lines()
+            .find_map(|line| line.strip_prefix("idempotency-key: "))
+            .map(str::to_string)
+            .unwrap()
+    };
+    // Sent again after a 5xx, the write keeps its key; once it went
+    // through, the same body is a new write
+    assert_eq!(key(&requests[0]), key(&requests[1]));
+    assert_ne!(key(&requests[1]), key(&requests[2]));
+    assert_eq!(key(&requests[0]).len(), 36);
+    assert_eq!(key(&requests[0]).chars().nth(14), Some('4'));
+}
+
+#[tokio::test]
+async fn test_get_revalidates_with_etag() {
+    let body = r##"[{"id":1,"name":"Food","color":"#22c55e"}]"##;
+    let first = format!(
+        "HTTP/1.1 200 OK\r\nETag: \"v1\"\r\nContent-Type: application/json\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        body.len(),
+        body
+    );
+    let second =
+        "HTTP/1.1 304 Not Modified\r\nETag: \"v1\"\r\nConnection: close\r\n\r\n".to_string();
+    let (base_url, server) = serve(vec![first, second]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let fresh: Vec<Category> = api.get("/categories").await.unwrap();
+    let cached: Vec<Category> = api.get("/categories").await.unwrap();
+
+    assert_eq!(fresh.len(), 1);
+    assert_eq!(cached.len(), 1);
+    assert_eq!(cached[0].name, "Food");
+
+    let requests = server.await.unwrap();
+    assert!(!requests[0].contains("if-none-match"));
+    assert!(requests[1].contains("if-none-match: \"v1\""));
+}
+
+#[test]
+fn test_client_options_reject_bad_ca_bundle() {
+    let missing = ClientOptions {
+        ca_bundle: Some("/nonexistent/ca.pem".into()),
+        ..Default::default()
+    };
+    let result = ApiClient::with_options("https://x".to_string(), "key".to_string(), &missing);
+    assert!(format!("{:#}", result.err().unwrap()).contains("Failed to read CA bundle"));
+
+    let path = std::env::temp_dir().join(format!("budget-tui-ca-{}.pem", std::process::id()));
+    std::fs::write(&path, "not a certificate").unwrap();
+    let empty = ClientOptions {
+        ca_bundle: Some(path.clone()),
+        ..Default::default()
+    };
+    assert!(ApiClient::with_options("https://x".to_string(), "key".to_string(), &empty).is_err());
+    let _ = std::fs::remove_file(&path);
+
+    let insecure = ClientOptions {
+        insecure_skip_verify: true,
+        ..Default::default()
+    };
+    assert!(ApiClient::with_options("https://x".to_string(), "key".to_string(), &insecure).is_ok());
+}
+
+#[tokio::test]
+async fn test_requests_go_through_configured_proxy() {
+    let body = "[]";
+    let response = format!(
+        "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        body.len(),
+        body
+    );
+    let (proxy_url, proxy) = serve(vec![response]).await;
+
+    let options = ClientOptions {
+        proxy: Some(proxy_url),
+        no_proxy: Some(String::new()),
+        ..Default::default()
+    };
+    let api = ApiClient::with_options(
+        "http://budget.example".to_string(),
+        "key".to_string(),
+        &options,
+    )
+    .unwrap();
+    let categories: Vec<Category> = api.get("/categories").await.unwrap();
+
+    assert!(categories.is_empty());
+    let requests = proxy.await.unwrap();
+    assert!(requests[0].starts_with("get http://budget.example/api/v1/categories"));
+}
+
+#[test]
+fn test_client_options_reject_bad_proxy() {
+    let invalid = ClientOptions {
+        proxy: Some("not a url".to_string()),
+        ..Default::default()
+    };
+    let result = ApiClient::with_options("https://x".to_string(), "key".to_string(), &invalid);
+    assert!(format!("{:#}", result.err().unwrap()).contains("Invalid proxy URL"));
+
+    let direct = ClientOptions {
+        proxy: Some("none".to_string()),
+        ..Default::default()
+    };
+    assert!(ApiClient::with_options("https://x".to_string(), "key".to_string(), &direct).is_ok());
+}
+
+#[test]
+fn test_debug_log_masks_secrets() {
+    let body = br#"{"username":"ana","password":"hunter2","user":{"access_token":"abc"}}"#;
+    let logged = format_body(body);
+
+    assert!(logged.contains("\"username\":\"ana\""));
+    assert!(!logged.contains("hunter2"));
+    assert!(!logged.contains("abc"));
+
+    // The two-factor step's challenge and code
+    let logged = format_body(br#"{"challenge":"ch-123","code":"492817"}"#);
+    assert!(!logged.contains("ch-123"));
+    assert!(!logged.contains("492817"));
+
+    // A device login's code gets a session once it is approved
+    let logged = format_body(br#"{"device_code":"dev-abc","user_code":"WDJB-MJHT"}"#);
+    assert!(!logged.contains("dev-abc"));
+    assert!(logged.contains("WDJB-MJHT"));
+}
+
+#[test]
+fn test_debug_log_truncates_bodies() {
+    let body = "x".repeat(MAX_BODY_CHARS + 10);
+    let logged = format_body(body.as_bytes());
+
+    assert!(logged.starts_with(&"x".repeat(MAX_BODY_CHARS)));
+    assert!(logged.ends_with("... (10 more chars)"));
+    assert_eq!(format_body(b"not json\nat all"), "not json\\nat all");
+}
+
+#[tokio::test]
+async fn test_debug_log_records_requests() {
+    let body = r#"{"detail":"Month is closed"}"#;
+    let response = format!(
+        "HTTP/1.1 400 Bad Request\r\nContent-Type: application/json\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        body.len(),
+        body
+    );
+    let (base_url, server) = serve(vec![response]).await;
+    let path = std::env::temp_dir().join(format!("budget-tui-debug-{}.log", std::process::id()));
+    let _ = std::fs::remove_file(&path);
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    api.enable_debug_log(&path).unwrap();
+    let update = ExpenseUpdate {
+        cost: Some(12.5),
+        ..Default::default()
+    };
+    let result: Result<Expense, _> = api.put("/expenses/1", &update).await;
+    server.await.unwrap();
+
+    assert!(matches!(result, Err(ApiError::BadRequest(_))));
+    assert_eq!(api.debug_log_path(), Some(path.clone()));
+    let log = std::fs::read_to_string(&path).unwrap();
+    assert!(log.contains("PUT http://"));
+    assert!(log.contains("/api/v1/expenses/1 -> 400 Bad Request"));
+    assert!(log.contains("  > {\"cost\":12.5"));
+    assert!(log.contains("  < {\"detail\":\"Month is closed\"}"));
+    assert!(!log.contains("test-key"));
+    let _ = std::fs::remove_file(&path);
+}
+
+#[tokio::test]
+async fn test_update_sends_patch_and_falls_back_to_put() {
+    let (base_url, server) = serve(vec![
+        json_response("200 OK", &expense_json(1, "Rent")),
+        // An older server without PATCH
+        json_response("404 Not Found", r#"{"detail":"Not Found"}"#),
+        json_response("200 OK", &expense_json(1, "Rent")),
+    ])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    api.set_retry_policy(RetryPolicy::none());
+    let update = ExpenseUpdate {
+        cost: Some(12.5),
+        ..Default::default()
+    };
+    assert!(!update.is_empty());
+    assert!(ExpenseUpdate::default().is_empty());
+
+    api.expenses().update(1, &update).await.unwrap();
+    api.expenses().update(1, &update).await.unwrap();
+    let requests = server.await.unwrap();
+
+    assert!(requests[0].starts_with("patch /api/v1/expenses/1 "));
+    assert!(requests[0].ends_with(r#"{"cost":12.5}"#));
+    assert!(requests[1].starts_with("patch /api/v1/expenses/1 "));
+    assert!(requests[2].starts_with("put /api/v1/expenses/1 "));
+    assert!(requests[2].ends_with(r#"{"cost":12.5}"#));
+}
+
+#[tokio::test]
+async fn test_admin_user_management() {
+    let user =
+        r#"{"id":2,"email":"ann@example.com","full_name":"Ann","is_active":true,"is_admin":false}"#;
+    let (base_url, server) = serve(vec![
+        json_response("201 Created", user),
+        json_response(
+            "200 OK",
+            &user.replace(r#""is_active":true"#, r#""is_active":false"#),
+        ),
+        json_response(
+            "200 OK",
+            &user.replace(r#""is_admin":false"#, r#""is_admin":true"#),
+        ),
+        json_response(
+            "200 OK",
+            r#"{"message":"Password set successfully for ann@example.com"}"#,
+        ),
+        json_response("403 Forbidden", r#"{"detail":"Admin access required"}"#),
+    ])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    api.set_retry_policy(RetryPolicy::none());
+    let auth = api.auth();
+
+    let created = auth
+        .create_user(&UserCreate {
+            email: "ann@example.com".to_string(),
+            password: "secret".to_string(),
+            full_name: Some("Ann".to_string()),
+            is_active: true,
+            is_admin: false,
+        })
+        .await
+        .unwrap();
+    assert_eq!(created.id, 2);
+    assert!(!auth.set_user_active(2, false).await.unwrap().is_active);
+    assert!(auth.set_user_admin(2, true).await.unwrap().is_admin);
+    let reset = auth.reset_user_password(2, "new-secret").await.unwrap();
+    assert!(reset.message.contains("ann@example.com"));
+    assert!(matches!(
+        auth.list_users().await,
+        Err(ApiError::Forbidden(_))
+    ));
+    let requests = server.await.unwrap();
+
+    assert!(requests[0].starts_with("post /api/v1/auth/users "));
+    assert!(requests[1].starts_with("put /api/v1/auth/users/2 "));
+    assert!(requests[1].ends_with(r#"{"is_active":false}"#));
+    assert!(requests[2].ends_with(r#"{"is_admin":true}"#));
+    assert!(requests[3].starts_with("post /api/v1/auth/users/2/set-password "));
+    assert!(requests[3].ends_with(r#"{"new_password":"new-secret"}"#));
+    assert!(requests[4].starts_with("get /api/v1/auth/users "));
+}
+
+fn offline_client(name: &str) -> (ApiClient, std::path::PathBuf) {
+    let dir = std::env::temp_dir().join(format!("budget-tui-test-{}-{}", name, std::process::id()));
+    let _ = std::fs::remove_dir_all(&dir);
+
+    let api = client();
+    api.set_retry_policy(RetryPolicy::none());
+    api.enable_write_queue(dir.clone()).unwrap();
+    (api, dir)
+}
+
+#[tokio::test]
+async fn test_writes_queued_while_unreachable() {
+    let (api, dir) = offline_client("queue-writes");
+
+    let result: Result<Expense, _> = api
+        .put("/expenses/3", &serde_json::json!({"cost": 10.0}))
+        .await;
+    assert!(matches!(result, Err(ApiError::Queued)));
+    assert!(matches!(
+        api.delete("/incomes/4").await,
+        Err(ApiError::Queued)
+    ));
+
+    let queued = api.queued_writes();
+    assert_eq!(queued.len(), 2);
+    assert_eq!(queued[0].method, "PUT");
+    assert_eq!(queued[0].body, Some(serde_json::json!({"cost": 10.0})));
+    assert_eq!(queued[1].method, "DELETE");
+
+    // The queue survives a restart
+    let restarted = client();
+    restarted.enable_write_queue(dir.clone()).unwrap();
+    assert_eq!(restarted.queued_writes(), queued);
+
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[tokio::test]
+async fn test_reads_and_other_writes_are_not_queued() {
+    let (api, dir) = offline_client("queue-skip");
+
+    let read: Result<Vec<Expense>, _> = api.get("/expenses").await;
+    assert!(matches!(read, Err(ApiError::Network(_))));
+    let login: Result<serde_json::Value, _> = api.post("/auth/login", &serde_json::json!({})).await;
+    assert!(matches!(login, Err(ApiError::Network(_))));
+
+    assert!(!api.has_queued_writes());
+
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[tokio::test]
+async fn test_sync_keeps_writes_while_unreachable() {
+    let (api, dir) = offline_client("queue-offline-sync");
+    let _ = api.delete("/expenses/1").await;
+
+    let report = api.sync_queued_writes().await;
+
+    assert_eq!(report.synced, 0);
+    assert!(report.failed.is_empty());
+    assert_eq!(report.remaining, 1);
+
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[tokio::test]
+async fn test_sync_replays_queued_writes() {
+    let dir =
+        std::env::temp_dir().join(format!("budget-tui-test-queue-sync-{}", std::process::id()));
+    let _ = std::fs::remove_dir_all(&dir);
+    let mut journal = budget_tui::storage::WriteJournal::default();
+    journal.push("DELETE", "/expenses/1", None);
+    journal.push("DELETE", "/expenses/2", None);
+    journal.save(&dir).unwrap();
+
+    let ok = "HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n".to_string();
+    let rejected = "HTTP/1.1 400 Bad Request\r\nContent-Type: application/json\r\nContent-Length: 25\r\nConnection: close\r\n\r\n{\"detail\":\"Month closed\"}".to_string();
+    let (base_url, server) = serve(vec![ok, rejected]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    api.enable_write_queue(dir.clone()).unwrap();
+    let report = api.sync_queued_writes().await;
+
+    assert_eq!(report.synced, 1);
+    assert_eq!(report.failed.len(), 1);
+    assert!(report.failed[0].contains("Month closed"));
+    assert_eq!(report.remaining, 0);
+    assert!(!api.has_queued_writes());
+
+    let requests = server.await.unwrap();
+    assert!(requests[0].starts_with("delete /api/v1/expenses/1 "));
+    assert!(requests[1].starts_with("delete /api/v1/expenses/2 "));
+
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+fn mock_month(id: i32, month: i32) -> Month {
+    Month {
+        id,
+        year: 2024,
+        month,
+        name: format!("Month {}", month),
+        start_date: format!("2024-{:02}-01", month),
+        end_date: format!("2024-{:02}-28", month),
+        is_closed: false,
+        closed_at: None,
+        closed_by: None,
+        notes: None,
+    }
+}
+
+fn mock_expense(id: i32, month_id: i32, category: &str) -> Expense {
+    Expense {
+        id,
+        expense_name: format!("Expense {}", id),
+        period: "Monthly".to_string(),
+        category: category.to_string(),
+        projected: 100.0,
+        cost: 0.0,
+        notes: None,
+        month_id,
+        purchases: None,
+        order: id,
+        expense_date: None,
+        created_at: None,
+        updated_at: None,
+        created_by: None,
+        updated_by: None,
+        attachments: None,
+        account_id: None,
+        currency: None,
+        custom_fields: CustomFields::new(),
+    }
+}
+
+fn mock_api() -> MockApi {
+    MockApi::new(MockData {
+        months: vec![mock_month(1, 1), mock_month(2, 2)],
+        current_month_id: Some(2),
+        expenses: vec![
+            mock_expense(1, 1, "Food"),
+            mock_expense(2, 2, "Food"),
+            mock_expense(3, 2, "Housing"),
+        ],
+        ..Default::default()
+    })
+}
+
+#[tokio::test]
+async fn test_mock_api_filters_expenses() {
+    let api = mock_api();
+
+    let filters = ExpenseFilters {
+        month_id: Some(2),
+        category: Some("Food".to_string()),
+        ..Default::default()
+    };
+    let expenses = api.get_expenses(&filters).await.unwrap();
+
+    assert_eq!(expenses.len(), 1);
+    assert_eq!(expenses[0].id, 2);
+    assert_eq!(
+        api.get_expenses(&ExpenseFilters::default())
+            .await
+            .unwrap()
+            .len(),
+        3
+    );
+}
+
+#[tokio::test]
+async fn test_mock_api_expense_writes() {
+    let api = mock_api();
+
+    let update = ExpenseUpdate {
+        expense_name: Some("Renamed".to_string()),
+        ..Default::default()
+    };
+    let updated = api.update_expense(2, &update).await.unwrap();
+    assert_eq!(updated.expense_name, "Renamed");
+    assert_eq!(updated.category, "Food");
+
+    let paid = api
+        .pay_expense(2, Some(&PayExpenseRequest { amount: Some(40.0) }))
+        .await
+        .unwrap();
+    assert_eq!(paid.cost, 40.0);
+    let paid = api.pay_expense(2, None).await.unwrap();
+    assert_eq!(paid.cost, 140.0);
+    assert_eq!(paid.purchases.map(|p| p.len()), Some(2));
+
+    api.delete_expense(2).await.unwrap();
+    assert!(matches!(
+        api.delete_expense(2).await,
+        Err(ApiError::NotFound)
+    ));
+    assert_eq!(api.data().expenses.len(), 2);
+}
+
+#[tokio::test]
+async fn test_mock_api_creates_incomes_with_new_ids() {
+    let api = mock_api();
+    let income = IncomeCreate {
+        income_type_id: 1,
+        period: "Monthly".to_string(),
+        projected: 1000.0,
+        amount: 0.0,
+        month_id: 2,
+        account_id: None,
+        currency: None,
+    };
+
+    let first = api.create_income(&income).await.unwrap();
+    let second = api.create_income(&income).await.unwrap();
+
+    assert_ne!(first.id, second.id);
+    let filters = IncomeFilters {
+        month_id: Some(2),
+        ..Default::default()
+    };
+    assert_eq!(api.get_incomes(&filters).await.unwrap().len(), 2);
+}
+
+#[tokio::test]
+async fn test_mock_api_fail_next() {
+    let api = mock_api();
+    api.fail_next(ApiError::Unauthorized);
+
+    assert!(matches!(
+        api.get_months().await,
+        Err(ApiError::Unauthorized)
+    ));
+    assert_eq!(api.get_months().await.unwrap().len(), 2);
+}
+
+#[tokio::test]
+async fn test_get_month_range_data() {
+    let api = MockApi::new(MockData {
+        months: vec![mock_month(3, 3), mock_month(1, 1), mock_month(2, 2)],
+        expenses: vec![
+            mock_expense(1, 1, "Food"),
+            mock_expense(2, 2, "Food"),
+            mock_expense(3, 2, "Housing"),
+            mock_expense(4, 3, "Food"),
+        ],
+        ..Default::default()
+    });
+
+    let months = api
+        .get_month_range_data((2024, 2), (2024, 12))
+        .await
+        .unwrap();
+
+    let ids: Vec<i32> = months.iter().map(|m| m.month.id).collect();
+    assert_eq!(ids, vec![2, 3]);
+    assert_eq!(months[0].expenses.len(), 2);
+    assert_eq!(months[1].expenses.len(), 1);
+    // The mock has no totals unless set, as on older servers
+    assert!(months[0].totals.is_none());
+    assert_eq!(months[0].category_summary.as_ref().map(Vec::len), Some(0));
+
+    assert!(api
+        .get_month_range_data((2023, 1), (2023, 12))
+        .await
+        .unwrap()
+        .is_empty());
+}
+
+#[tokio::test]
+async fn test_get_month_range_data_fails_as_a_whole() {
+    let api = mock_api();
+    api.fail_next(ApiError::Server("down".to_string()));
+
+    assert!(matches!(
+        api.get_month_range_data((2024, 1), (2024, 12)).await,
+        Err(ApiError::Server(_))
+    ));
+}
+
+#[test]
+fn test_month_in_range() {
+    let march = mock_month(3, 3);
+    assert!(in_range(&march, (2024, 3), (2024, 3)));
+    assert!(in_range(&march, (2023, 6), (2024, 5)));
+    assert!(!in_range(&march, (2024, 4), (2024, 12)));
+    assert!(!in_range(&march, (2023, 1), (2024, 2)));
+}
+
+#[tokio::test]
+async fn test_state_loads_from_mock_api() {
+    let api = mock_api();
+    let mut state = AppState::default();
+
+    state.load_reference_data(&api).await;
+    state.load_month_data(&api).await;
+
+    assert_eq!(state.data.months.len(), 2);
+    assert_eq!(state.selected_month_id(), Some(2));
+    let ids: Vec<i32> = state.data.expenses.iter().map(|e| e.id).collect();
+    assert_eq!(ids, vec![2, 3]);
+    // Summaries the mock doesn't have are computed from the month's expenses
+    let totals = state.data.summary_totals.as_ref().unwrap();
+    assert_eq!(totals.total_projected_expenses, 200.0);
+    assert!(state.data.insights.is_none());
+}
+
+#[tokio::test]
+async fn test_state_computes_summaries_the_server_lacks() {
+    let api = mock_api();
+    let mut state = AppState {
+        page_size: 1,
+        ..Default::default()
+    };
+    state.load_reference_data(&api).await;
+    // The category summary endpoint answers, the rest are missing
+    api.data().category_summary = vec![CategorySummary {
+        category: "From server".to_string(),
+        projected: 1.0,
+        total: 1.0,
+        over_projected: false,
+    }];
+    state.load_month_data(&api).await;
+
+    assert!(state
+        .data
+        .unsupported
+        .contains(&ServerFeature::SummaryTotals));
+    assert!(state.data.unsupported.contains(&ServerFeature::Insights));
+    assert!(!state
+        .data
+        .unsupported
+        .contains(&ServerFeature::CategorySummary));
+    assert_eq!(state.data.category_summary[0].category, "From server");
+    // Covers the whole month, not just the listed page
+    assert_eq!(state.data.expenses.len(), 1);
+    let totals = state.data.summary_totals.as_ref().unwrap();
+    assert_eq!(totals.total_projected_expenses, 200.0);
+    assert_eq!(totals.total_projected, -200.0);
+
+    // Missing endpoints aren't asked again
+    api.data().summary_totals = Some(SummaryTotals {
+        total_projected_expenses: 0.0,
+        total_current_expenses: 0.0,
+        total_projected_income: 0.0,
+        total_current_income: 0.0,
+        total_projected: 0.0,
+        total_current: 0.0,
+    });
+    state.load_month_data(&api).await;
+    let totals = state.data.summary_totals.as_ref().unwrap();
+    assert_eq!(totals.total_projected_expenses, 200.0);
+}
+
+#[tokio::test]
+async fn test_state_loads_key_scopes() {
+    // Servers without scoped keys leave every operation available
+    let api = mock_api();
+    let mut state = AppState::default();
+    state.load_reference_data(&api).await;
+    assert!(state.data.key_scopes.is_none());
+    assert!(state.data.unsupported.contains(&ServerFeature::KeyScopes));
+    assert!(state.key_allows(Scope::Months));
+
+    api.data().key_scopes = Some(KeyScopes {
+        scopes: vec!["read".to_string(), "incomes:write".to_string()],
+    });
+    let mut state = AppState::default();
+    state.load_reference_data(&api).await;
+    assert!(state.key_allows(Scope::Incomes));
+    assert!(!state.key_allows(Scope::Expenses));
+}
+
+#[tokio::test]
+async fn test_state_loads_split_pane() {
+    let api = mock_api();
+    let mut state = AppState::default();
+    state.load_reference_data(&api).await;
+    state.ui.selected_month_index = 0;
+    assert!(state.open_split());
+    state.ui.split.as_mut().unwrap().other.category_filter = Some("Housing".to_string());
+
+    state.load_split_pane(&api).await;
+    let ids: Vec<i32> = state
+        .ui
+        .split
+        .as_ref()
+        .unwrap()
+        .other
+        .expenses
+        .iter()
+        .map(|e| e.id)
+        .collect();
+    assert_eq!(ids, vec![3]);
+}
+
+#[tokio::test]
+async fn test_state_refreshes_one_expense() {
+    let api = mock_api();
+    let mut state = AppState::default();
+    state.load_reference_data(&api).await;
+    state.load_month_data(&api).await;
+
+    api.data().expenses[1].cost = 42.0;
+    assert!(state.refresh_expense(&api, 2).await);
+    assert_eq!(state.data.expenses[0].cost, 42.0);
+
+    // Deleted on another device
+    api.data().expenses.retain(|e| e.id != 3);
+    assert!(!state.refresh_expense(&api, 3).await);
+    let ids: Vec<i32> = state.data.expenses.iter().map(|e| e.id).collect();
+    assert_eq!(ids, vec![2]);
+
+    // Other failures keep what is listed
+    api.fail_next(ApiError::Server("down".to_string()));
+    assert!(state.refresh_expense(&api, 2).await);
+    assert_eq!(state.data.expenses.len(), 1);
+}
+
+#[tokio::test]
+async fn test_state_keeps_data_when_loading_fails() {
+    let api = mock_api();
+    let mut state = AppState::default();
+    state.load_reference_data(&api).await;
+    state.load_month_data(&api).await;
+
+    api.fail_next(ApiError::Server("down".to_string()));
+    state.load_filtered_expenses(&api).await;
+
+    assert_eq!(state.data.expenses.len(), 2);
+}
+
+#[tokio::test]
+async fn test_state_loads_expenses_in_pages() {
+    let api = mock_api();
+    {
+        let mut data = api.data();
+        for id in 4..=8 {
+            data.expenses.push(mock_expense(id, 2, "Food"));
+        }
+    }
+    let mut state = AppState {
+        page_size: 3,
+        ..Default::default()
+    };
+    state.load_reference_data(&api).await;
+    state.load_month_data(&api).await;
+
+    assert_eq!(state.data.expenses.len(), 3);
+    assert!(state.data.more_expenses.is_some());
+    assert_eq!(state.data.expenses_total, Some(7));
+
+    assert!(state.load_more_expenses(&api).await);
+    assert_eq!(state.data.expenses.len(), 6);
+
+    // The last page is short, so there is nothing after it
+    assert!(state.load_more_expenses(&api).await);
+    assert_eq!(state.data.expenses.len(), 7);
+    assert!(state.data.more_expenses.is_none());
+    assert!(!state.load_more_expenses(&api).await);
+
+    // Reloading starts over from the first page
+    state.load_filtered_expenses(&api).await;
+    assert_eq!(state.data.expenses.len(), 3);
+}
+
+#[tokio::test]
+async fn test_state_load_more_keeps_page_on_failure() {
+    let api = mock_api();
+    let mut state = AppState {
+        page_size: 1,
+        ..Default::default()
+    };
+    state.load_reference_data(&api).await;
+    state.load_month_data(&api).await;
+
+    api.fail_next(ApiError::Server("down".to_string()));
+    assert!(!state.load_more_expenses(&api).await);
+    assert!(state.data.more_expenses.is_some());
+
+    assert!(state.load_more_expenses(&api).await);
+    let ids: Vec<i32> = state.data.expenses.iter().map(|e| e.id).collect();
+    assert_eq!(ids, vec![2, 3]);
+}
+
+#[tokio::test]
+async fn test_expenses_page_reads_paging_headers() {
+    let body = format!("[{},{}]", expense_json(1, "Rent"), expense_json(2, "Power"));
+    let response = format!(
+        "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nX-Total-Count: 5\r\nLink: </api/v1/expenses?limit=2&offset=2>; rel=\"next\"\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        body.len(),
+        body
+    );
+    let (base_url, server) = serve(vec![response]).await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+
+    let page = api
+        .get_expenses_page(&ExpenseFilters::default(), PageRequest::first(2))
+        .await
+        .unwrap();
+
+    assert_eq!(page.items.len(), 2);
+    assert_eq!(page.total, Some(5));
+    assert!(page.has_more);
+    assert_eq!(
+        page.next(),
+        Some(PageRequest {
+            offset: 2,
+            limit: 2
+        })
+    );
+    let requests = server.await.unwrap();
+    assert!(requests[0].contains("limit=2"));
+}
+
+#[tokio::test]
+async fn test_expense_pages_stream_all_pages() {
+    let api = mock_api();
+    {
+        let mut data = api.data();
+        for id in 4..=8 {
+            data.expenses.push(mock_expense(id, 2, "Food"));
+        }
+    }
+
+    let mut pages = expense_pages(&api, ExpenseFilters::default(), 3);
+    let mut sizes = Vec::new();
+    while let Some(page) = pages.next_page().await {
+        sizes.push(page.unwrap().len());
+    }
+    assert_eq!(sizes, vec![3, 3, 2]);
+    assert_eq!(pages.total(), Some(8));
+
+    // A page size of 0 fetches everything at once
+    let mut all = expense_pages(&api, ExpenseFilters::default(), 0);
+    assert_eq!(all.next_page().await.unwrap().unwrap().len(), 8);
+    assert!(all.next_page().await.is_none());
+}
+
+#[tokio::test]
+async fn test_rollover_starts_missing_month() {
+    let api = mock_api();
+    let mut state = AppState::default();
+    state.load_reference_data(&api).await;
+
+    // February exists, so there's nothing to ask
+    let february = NaiveDate::from_ymd_opt(2024, 2, 29).unwrap();
+    assert!(state.rollover_prompt(february, None).is_none());
+    assert!(state.select_calendar_month(calendar_month(february)));
+
+    let march = NaiveDate::from_ymd_opt(2024, 3, 1).unwrap();
+    assert!(!state.select_calendar_month(calendar_month(march)));
+    match state.rollover_prompt(march, Some("month 1")) {
+        Some(Modal::Rollover {
+            year,
+            month,
+            name,
+            previous,
+            template,
+        }) => {
+            assert_eq!((year, month), (2024, 3));
+            assert_eq!(name, "March 2024");
+            assert_eq!(previous, Some((2, "February 2024".to_string())));
+            assert_eq!(template, Some((1, "January 2024".to_string())));
+        }
+        other => panic!("unexpected prompt {:?}", other),
+    }
+
+    {
+        let mut data = api.data();
+        data.expenses[2].cost = 80.0;
+    }
+    let start = state.start_month(&api, (2024, 3), Some(2)).await.unwrap();
+    assert_eq!(start.expenses, 2);
+    assert_eq!(start.incomes, 0);
+    let selected = state.selected_month().unwrap();
+    assert_eq!((selected.year, selected.month), (2024, 3));
+
+    let copied = api
+        .get_expenses(&ExpenseFilters {
+            month_id: Some(selected.id),
+            ..Default::default()
+        })
+        .await
+        .unwrap();
+    assert_eq!(copied.len(), 2);
+    assert!(copied.iter().all(|e| e.cost == 0.0 && e.projected == 100.0));
+
+    // An empty start only creates the month
+    let april = state.start_month(&api, (2024, 4), None).await.unwrap();
+    assert_eq!(april.expenses, 0);
+    assert_eq!(state.data.months.len(), 4);
+}
+
+#[test]
+fn test_previous_month() {
+    assert_eq!(previous_month((2024, 3)), (2024, 2));
+    assert_eq!(previous_month((2024, 1)), (2023, 12));
+}
+
+#[test]
+fn test_sse_parser_split_chunks() {
+    let mut parser = SseParser::new();
+
+    assert!(parser.feed(b": keepalive\n\nevent: cha").is_empty());
+    assert!(parser.feed(b"nge\r\ndata: {\"a\":\n").is_empty());
+    let messages = parser.feed(b"data: 1}\n\ndata:plain\n\n");
+
+    assert_eq!(
+        messages,
+        vec![
+            SseMessage {
+                event: "change".to_string(),
+                data: "{\"a\":\n1}".to_string(),
+            },
+            SseMessage {
+                event: "message".to_string(),
+                data: "plain".to_string(),
+            },
+        ]
+    );
+}
+
+#[test]
+fn test_sse_parser_keeps_split_utf8() {
+    let mut parser = SseParser::new();
+    let body = "data: café\n\n".as_bytes();
+
+    // Split inside the two-byte é
+    assert!(parser.feed(&body[..9]).is_empty());
+    let messages = parser.feed(&body[9..]);
+    assert_eq!(messages[0].data, "café");
+}
+
+#[test]
+fn test_change_event_affects_month() {
+    let change = |resource: &str, id: Option<i32>, month_id: Option<i32>| ChangeEvent {
+        resource: resource.to_string(),
+        action: "updated".to_string(),
+        id,
+        month_id,
+        source: None,
+    };
+
+    assert!(change("expenses", Some(4), Some(7)).affects_month(7));
+    assert!(!change("expenses", Some(4), Some(8)).affects_month(7));
+    // Reordering names no month
+    assert!(change("expenses", None, None).affects_month(7));
+    assert!(change("months", Some(7), Some(7)).affects_month(7));
+    assert!(!change("months", Some(8), Some(8)).affects_month(7));
+    assert!(change("categories", Some(1), None).affects_month(7));
+    assert!(change("income-types", Some(1), None).affects_settings());
+    assert!(!change("incomes", Some(1), Some(7)).affects_settings());
+}
+
+#[tokio::test]
+async fn test_subscribe_receives_changes() {
+    let body = concat!(
+        ": keepalive\n\n",
+        "event: change\n",
+        "data: {\"resource\":\"expenses\",\"action\":\"created\",\"id\":5,\"month_id\":2,\"source\":\"web\"}\n\n",
+        "event: ping\ndata: {}\n\n",
+    );
+    let response = format!(
+        "HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\nConnection: close\r\n\r\n{}",
+        body
+    );
+    let (base_url, server) = serve(vec![response]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    api.set_token("token".to_string());
+    let live = api.subscribe();
+
+    let mut events = Vec::new();
+    for _ in 0..100 {
+        events.extend(live.drain());
+        if !events.is_empty() {
+            break;
+        }
+        tokio::time::sleep(Duration::from_millis(20)).await;
+    }
+
+    assert_eq!(
+        events,
+        vec![LiveEvent::Change(ChangeEvent {
+            resource: "expenses".to_string(),
+            action: "created".to_string(),
+            id: Some(5),
+            month_id: Some(2),
+            source: Some("web".to_string()),
+        })]
+    );
+    let requests = server.await.unwrap();
+    assert!(requests[0].starts_with("get /api/v1/events "));
+    assert!(requests[0].contains("accept: text/event-stream"));
+    assert!(requests[0].contains("authorization: bearer token"));
+    assert!(requests[0].contains("x-client-id: "));
+}
+
+#[tokio::test]
+async fn test_subscribe_stops_without_change_feed() {
+    let response =
+        "HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\nConnection: close\r\n\r\n".to_string();
+    let (base_url, server) = serve(vec![response]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let live = api.subscribe();
+    server.await.unwrap();
+
+    for _ in 0..100 {
+        if live.is_finished() {
+            break;
+        }
+        tokio::time::sleep(Duration::from_millis(20)).await;
+    }
+    assert!(live.is_finished());
+    assert!(live.drain().is_empty());
+}
+
+#[test]
+fn test_parse_retry_after() {
+    let now = chrono::DateTime::parse_from_rfc3339("2015-10-21T07:28:00Z")
+        .unwrap()
+        .with_timezone(&chrono::Utc);
+
+    assert_eq!(
+        parse_retry_after("120", now),
+        Some(Duration::from_secs(120))
+    );
+    assert_eq!(
+        parse_retry_after("Wed, 21 Oct 2015 07:28:30 GMT", now),
+        Some(Duration::from_secs(30))
+    );
+    // Already past
+    assert_eq!(
+        parse_retry_after("Wed, 21 Oct 2015 07:00:00 GMT", now),
+        Some(Duration::ZERO)
+    );
+    assert_eq!(parse_retry_after("soon", now), None);
+}
+
+#[tokio::test]
+async fn test_rate_limited_post_is_retried_after_wait() {
+    let limited = "HTTP/1.1 429 Too Many Requests\r\nRetry-After: 0\r\nContent-Length: 0\r\nConnection: close\r\n\r\n".to_string();
+    let body = r##"{"id":3,"name":"Food","color":"#22c55e"}"##;
+    let created = format!(
+        "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        body.len(),
+        body
+    );
+    let (base_url, server) = serve(vec![limited, created]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let category: Category = api
+        .post("/categories", &serde_json::json!({ "name": "Food" }))
+        .await
+        .unwrap();
+
+    assert_eq!(category.id, 3);
+    assert_eq!(api.take_rate_limit_wait(), Some(Duration::ZERO));
+    assert_eq!(api.take_rate_limit_wait(), None);
+    assert_eq!(server.await.unwrap().len(), 2);
+}
+
+#[tokio::test]
+async fn test_rate_limit_too_long_to_wait_fails() {
+    let limited = "HTTP/1.1 429 Too Many Requests\r\nRetry-After: 3600\r\nContent-Length: 0\r\nConnection: close\r\n\r\n".to_string();
+    let (base_url, server) = serve(vec![limited]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let result: Result<Vec<Category>, ApiError> = api.get("/categories").await;
+
+    let err = result.unwrap_err();
+    assert!(matches!(err, ApiError::RateLimited(Some(wait)) if wait.as_secs() == 3600));
+    assert_eq!(
+        err.to_string(),
+        "Rate limited by the server - try again in 3600s"
+    );
+    assert_eq!(api.take_rate_limit_wait(), None);
+    server.await.unwrap();
+}
+
+fn new_expense(name: &str) -> ExpenseCreate {
+    ExpenseCreate {
+        expense_name: name.to_string(),
+        period: "Monthly".to_string(),
+        category: "Food".to_string(),
+        projected: 100.0,
+        cost: 0.0,
+        notes: None,
+        month_id: 1,
+        purchases: None,
+        expense_date: None,
+        account_id: None,
+        currency: None,
+        custom_fields: CustomFields::new(),
+    }
+}
+
+fn json_response(status: &str, body: &str) -> String {
+    format!(
+        "HTTP/1.1 {}\r\nContent-Type: application/json\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        status,
+        body.len(),
+        body
+    )
+}
+
+fn expense_json(id: i32, name: &str) -> String {
+    serde_json::json!({
+        "id": id,
+        "expense_name": name,
+        "period": "Monthly",
+        "category": "Food",
+        "projected": 100.0,
+        "cost": 0.0,
+        "notes": null,
+        "month_id": 1,
+        "purchases": null,
+        "order": id,
+        "expense_date": null,
+    })
+    .to_string()
+}
+
+#[tokio::test]
+async fn test_get_expense_by_id_reads_audit_fields() {
+    let mut body: serde_json::Value = serde_json::from_str(&expense_json(7, "Rent")).unwrap();
+    body["updated_at"] = "2024-03-02T10:00:00Z".into();
+    body["updated_by"] = "sam@example.com".into();
+    let (base_url, server) = serve(vec![json_response("200 OK", &body.to_string())]).await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+
+    let expense = api.get_expense(7).await.unwrap();
+
+    assert_eq!(expense.updated_by.as_deref(), Some("sam@example.com"));
+    assert_eq!(expense.updated_at.as_deref(), Some("2024-03-02T10:00:00Z"));
+    // Older servers leave them out
+    assert!(expense.created_by.is_none());
+    let requests = server.await.unwrap();
+    assert!(requests[0].starts_with("get /api/v1/expenses/7 "));
+}
+
+#[tokio::test]
+async fn test_export_csv_downloads_to_file() {
+    let csv = "month,type,name,category,period,projected,actual\r\n2024-03,expense,Rent,Housing,Monthly,1000,1000\r\n";
+    let response = format!(
+        "HTTP/1.1 200 OK\r\nContent-Type: text/csv\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        csv.len(),
+        csv
+    );
+    let (base_url, server) = serve(vec![response]).await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let path = std::env::temp_dir().join(format!("budget-tui-export-{}.csv", std::process::id()));
+
+    let mut progress = Vec::new();
+    let size = api
+        .months()
+        .export_csv(3, &path, |received, total| progress.push((received, total)))
+        .await
+        .unwrap();
+
+    assert_eq!(size, csv.len() as u64);
+    assert_eq!(std::fs::read_to_string(&path).unwrap(), csv);
+    assert_eq!(progress.last(), Some(&(size, Some(size))));
+    let requests = server.await.unwrap();
+    assert!(requests[0].starts_with("get /api/v1/months/3/export "));
+    std::fs::remove_file(&path).unwrap();
+}
+
+#[tokio::test]
+async fn test_failed_download_leaves_no_file() {
+    let (base_url, _server) = serve(vec![json_response(
+        "404 Not Found",
+        r#"{"detail":"Month with ID 3 not found"}"#,
+    )])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let path = std::env::temp_dir().join(format!("budget-tui-missing-{}.csv", std::process::id()));
+
+    let result = api.months().export_csv(3, &path, |_, _| {}).await;
+
+    assert!(matches!(result, Err(ApiError::NotFound)));
+    assert!(!path.exists());
+    assert!(!path.with_extension("csv.part").exists());
+}
+
+#[tokio::test]
+async fn test_upload_attachment_sends_file_as_is() {
+    let (base_url, server) = serve(vec![json_response(
+        "201 Created",
+        r#"{"id":4,"file_name":"march receipt.pdf","content_type":"application/pdf","size":12,"uploaded_at":null}"#,
+    )])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+
+    let attachment = api
+        .expenses()
+        .upload_attachment(7, "march receipt.pdf", b"%PDF-receipt".to_vec())
+        .await
+        .unwrap();
+
+    assert_eq!(attachment.id, 4);
+    assert_eq!(attachment.size, Some(12));
+    let requests = server.await.unwrap();
+    assert!(
+        requests[0].starts_with("post /api/v1/expenses/7/attachments?file_name=march+receipt.pdf ")
+    );
+    assert!(requests[0].contains("content-type: application/pdf"));
+    assert!(requests[0].ends_with("%pdf-receipt"));
+}
+
+#[test]
+fn test_request_metrics_group_by_endpoint() {
+    assert_eq!(endpoint_key("GET", "/expenses/12"), "GET /expenses/{id}");
+    assert_eq!(
+        endpoint_key("POST", "/months/3/close?x=1"),
+        "POST /months/{id}/close"
+    );
+
+    let mut metrics = RequestMetrics::new();
+    for ms in 1..=20 {
+        metrics.record(
+            "GET",
+            &format!("/expenses/{}", ms),
+            Duration::from_millis(ms * 10),
+            Outcome::Response,
+        );
+    }
+    metrics.record(
+        "GET",
+        "/months",
+        Duration::from_millis(5),
+        Outcome::NetworkError,
+    );
+
+    let snapshot = metrics.snapshot();
+    assert_eq!(snapshot.len(), 2);
+    let expenses = &snapshot[0];
+    assert_eq!(expenses.endpoint, "GET /expenses/{id}");
+    assert_eq!(expenses.requests, 20);
+    assert_eq!(expenses.average, Duration::from_millis(105));
+    assert_eq!(expenses.p95, Duration::from_millis(190));
+    assert_eq!(snapshot[1].failures(), 1);
+
+    let totals = metrics.totals();
+    assert_eq!(totals.requests, 21);
+    assert_eq!(totals.failures(), 1);
+    assert!((totals.error_rate() - 1.0 / 21.0).abs() < 1e-9);
+    assert_eq!(totals.p95, Duration::from_millis(190));
+
+    metrics.clear();
+    assert!(metrics.is_empty());
+    assert_eq!(metrics.totals().requests, 0);
+    assert_eq!(metrics.totals().error_rate(), 0.0);
+}
+
+#[tokio::test]
+async fn test_client_records_request_metrics() {
+    let (base_url, _server) = serve(vec![
+        json_response("200 OK", "[]"),
+        json_response("503 Service Unavailable", r#"{"detail":"down"}"#),
+    ])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    api.set_retry_policy(RetryPolicy::none());
+
+    let _: Vec<Month> = api.get("/months").await.unwrap();
+    let failed: Result<Vec<Month>, _> = api.get("/months").await;
+    assert!(failed.is_err());
+
+    let metrics = api.request_metrics();
+    assert_eq!(metrics[0].endpoint, "GET /months");
+    assert_eq!(metrics[0].requests, 2);
+    assert_eq!(metrics[0].server_errors, 1);
+    assert_eq!(metrics[0].network_errors, 0);
+    assert_eq!(api.request_totals().error_rate(), 0.5);
+
+    api.reset_request_metrics();
+    assert!(api.request_metrics().is_empty());
+}
+
+#[tokio::test]
+async fn test_create_expenses_bulk_sends_one_request() {
+    let body = format!("[{},{}]", expense_json(1, "Rent"), expense_json(2, "Food"));
+    let (base_url, server) = serve(vec![json_response("201 Created", &body)]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let created = api
+        .create_expenses_bulk(&[new_expense("Rent"), new_expense("Food")])
+        .await
+        .unwrap();
+
+    let names: Vec<&str> = created.iter().map(|e| e.expense_name.as_str()).collect();
+    assert_eq!(names, vec!["Rent", "Food"]);
+    let requests = server.await.unwrap();
+    assert_eq!(requests.len(), 1);
+    assert!(requests[0].starts_with("post /api/v1/expenses/bulk "));
+    assert!(requests[0].contains(r#""expenses":[{"#));
+}
+
+#[tokio::test]
+async fn test_create_expenses_bulk_falls_back_without_endpoint() {
+    let not_found = json_response("404 Not Found", r#"{"detail":"Not Found"}"#);
+    let first = json_response("201 Created", &expense_json(1, "Rent"));
+    let second = json_response("201 Created", &expense_json(2, "Food"));
+    let (base_url, server) = serve(vec![not_found, first, second]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let created = api
+        .create_expenses_bulk(&[new_expense("Rent"), new_expense("Food")])
+        .await
+        .unwrap();
+
+    assert_eq!(created.len(), 2);
+    let requests = server.await.unwrap();
+    assert!(requests[0].starts_with("post /api/v1/expenses/bulk "));
+    assert!(requests[1].starts_with("post /api/v1/expenses "));
+    assert!(requests[2].starts_with("post /api/v1/expenses "));
+}
+
+#[tokio::test]
+async fn test_update_expenses_bulk_flattens_changes() {
+    let body = format!("[{}]", expense_json(4, "Groceries"));
+    let (base_url, server) = serve(vec![json_response("200 OK", &body)]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let update = ExpenseBulkUpdate {
+        id: 4,
+        changes: ExpenseUpdate {
+            expense_name: Some("Groceries".to_string()),
+            ..Default::default()
+        },
+    };
+    let updated = api.update_expenses_bulk(&[update]).await.unwrap();
+
+    assert_eq!(updated[0].expense_name, "Groceries");
+    let requests = server.await.unwrap();
+    assert!(requests[0].starts_with("put /api/v1/expenses/bulk "));
+    assert!(requests[0].contains(r#"{"updates":[{"id":4,"expense_name":"groceries"}]}"#));
+}
+
+#[tokio::test]
+async fn test_mock_bulk_expenses_one_at_a_time() {
+    let api = MockApi::new(MockData::default());
+
+    let created = api
+        .create_expenses_bulk(&[new_expense("Rent"), new_expense("Food")])
+        .await
+        .unwrap();
+    assert_eq!(created.len(), 2);
+
+    let updates: Vec<ExpenseBulkUpdate> = created
+        .iter()
+        .map(|expense| ExpenseBulkUpdate {
+            id: expense.id,
+            changes: ExpenseUpdate {
+                cost: Some(25.0),
+                ..Default::default()
+            },
+        })
+        .collect();
+    api.update_expenses_bulk(&updates).await.unwrap();
+    assert!(api.data().expenses.iter().all(|e| e.cost == 25.0));
+
+    // Stops at the first failure
+    api.fail_next(ApiError::Server("boom".to_string()));
+    assert!(api.update_expenses_bulk(&updates).await.is_err());
+}
+
+#[tokio::test]
+async fn test_generated_operation_calls_endpoint() {
+    let body = r#"{"id":7,"name":"March 2024","is_closed":true,"closed_at":"2024-04-01","closed_by":"sam","message":"Month March 2024 has been closed"}"#;
+    let (base_url, server) = serve(vec![json_response("200 OK", body)]).await;
+
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let closed = api.operations().close_month(7).await.unwrap();
+
+    assert!(closed.is_closed);
+    assert_eq!(closed.closed_by.as_deref(), Some("sam"));
+    let requests = server.await.unwrap();
+    assert!(requests[0].starts_with("post /api/v1/months/7/close "));
+}
+
+#[tokio::test]
+async fn test_login_with_totp() {
+    let token = r#"{"access_token":"jwt","token_type":"bearer","user_id":1,"email":"a@b.c"}"#;
+    let (base_url, server) = serve(vec![
+        json_response(
+            "200 OK",
+            r#"{"totp_required":true,"challenge":"ch1","expires_in":300}"#,
+        ),
+        json_response("401 Unauthorized", r#"{"detail":"Invalid code"}"#),
+        json_response("200 OK", token),
+    ])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+
+    let challenge = match api.auth().login("a@b.c", "secret").await.unwrap() {
+        LoginResponse::TotpRequired(challenge) => challenge,
+        LoginResponse::Token(_) => panic!("expected a challenge"),
+    };
+    assert_eq!(challenge.challenge, "ch1");
+    assert_eq!(challenge.expires_in, Some(300));
+
+    assert!(matches!(
+        api.auth().verify_totp("ch1", "000000").await,
+        Err(ApiError::Unauthorized)
+    ));
+    let response = api.auth().verify_totp("ch1", " 123456 ").await.unwrap();
+    assert_eq!(response.access_token, "jwt");
+
+    let requests = server.await.unwrap();
+    assert!(requests[2].starts_with("post /api/v1/auth/login/totp"));
+    assert!(requests[2].contains(r#"{"challenge":"ch1","code":"123456"}"#));
+}
+
+#[tokio::test]
+async fn test_login_without_totp_and_expired_challenge() {
+    let token = r#"{"access_token":"jwt","token_type":"bearer","user_id":1,"email":"a@b.c"}"#;
+    let (base_url, _server) = serve(vec![
+        json_response("200 OK", token),
+        json_response("410 Gone", r#"{"detail":"Challenge expired"}"#),
+    ])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+
+    assert!(matches!(
+        api.auth().login("a@b.c", "secret").await,
+        Ok(LoginResponse::Token(_))
+    ));
+    assert!(matches!(
+        api.auth().verify_totp("ch1", "123456").await,
+        Err(ApiError::Expired)
+    ));
+}
+
+#[tokio::test]
+async fn test_device_login() {
+    let code = r#"{"device_code":"dev1","user_code":"WDJB-MJHT","verification_uri":"https://sso.example.com/device","expires_in":600}"#;
+    let token = r#"{"access_token":"jwt","token_type":"bearer","user_id":1,"email":"a@b.c"}"#;
+    let (base_url, server) = serve(vec![
+        json_response("200 OK", code),
+        json_response("400 Bad Request", r#"{"detail":"authorization_pending"}"#),
+        json_response("400 Bad Request", r#"{"error":"slow_down"}"#),
+        json_response("200 OK", token),
+        json_response("400 Bad Request", r#"{"detail":"access_denied"}"#),
+        json_response("410 Gone", r#"{"detail":"expired_token"}"#),
+        json_response("400 Bad Request", r#"{"detail":"Unknown device code"}"#),
+    ])
+    .await;
+    let api = ApiClient::new(base_url, "test-key".to_string()).unwrap();
+    let auth = api.auth();
+
+    let code = auth.start_device_login().await.unwrap();
+    assert_eq!(code.user_code, "WDJB-MJHT");
+    assert_eq!(code.interval, 5);
+    assert!(code.verification_uri_complete.is_none());
+
+    assert!(matches!(
+        auth.poll_device_login("dev1").await,
+        Ok(DevicePoll::Pending)
+    ));
+    assert!(matches!(
+        auth.poll_device_login("dev1").await,
+        Ok(DevicePoll::SlowDown)
+    ));
+    match auth.poll_device_login("dev1").await {
+        Ok(DevicePoll::Token(token)) => assert_eq!(token.access_token, "jwt"),
+        other => panic!("expected a token, got {:?}", other),
+    }
+    assert!(matches!(
+        auth.poll_device_login("dev1").await,
+        Ok(DevicePoll::Denied)
+    ));
+    assert!(matches!(
+        auth.poll_device_login("dev1").await,
+        Ok(DevicePoll::Expired)
+    ));
+    assert!(matches!(
+        auth.poll_device_login("dev1").await,
+        Err(ApiError::BadRequest(message)) if message == "Unknown device code"
+    ));
+
+    let requests = server.await.unwrap();
+    assert!(requests[0].starts_with("post /api/v1/auth/device/code"));
+    assert!(requests[1].contains(r#"{"device_code":"dev1"}"#));
+}
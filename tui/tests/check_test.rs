@@ -0,0 +1,102 @@
+//! Server check tests for the Budget TUI application
+
+use budget_tui::api::ClientOptions;
+use budget_tui::check::{check_server, CheckStatus, REQUIRED_ENDPOINTS};
+use tokio::io::{AsyncReadExt, AsyncWriteExt};
+use tokio::net::TcpListener;
+
+/// Serve until dropped, answering by request line; `/auth/me` with `me_status`
+async fn serve_routes(me_status: &'static str, missing: &'static str) -> String {
+    let listener = TcpListener::bind("127.0.0.1:0").await.unwrap();
+    let base_url = format!("http://{}", listener.local_addr().unwrap());
+
+    tokio::spawn(async move {
+        loop {
+            let (mut socket, _) = listener.accept().await.unwrap();
+            let mut buf = vec![0; 4096];
+            // The connection check hangs up without sending anything
+            let n = socket.read(&mut buf).await.unwrap_or(0);
+            if n == 0 {
+                continue;
+            }
+            let request = String::from_utf8_lossy(&buf[..n]).to_string();
+            let path = request.split_whitespace().nth(1).unwrap_or_default();
+            let status = if path == "/api/v1/auth/me" {
+                me_status
+            } else if path == "/api/v1/auth/login" {
+                "400 Bad Request"
+            } else if path == format!("/api/v1{}", missing) {
+                "404 Not Found"
+            } else {
+                "200 OK"
+            };
+            let response = format!(
+                "HTTP/1.1 {}\r\nContent-Type: application/json\r\nContent-Length: 2\r\nConnection: close\r\n\r\n{{}}",
+                status
+            );
+            let _ = socket.write_all(response.as_bytes()).await;
+        }
+    });
+
+    base_url
+}
+
+#[tokio::test]
+async fn test_check_server_passes() {
+    let base_url = serve_routes("401 Unauthorized", "").await;
+    let mut reported = Vec::new();
+    let results = check_server(&base_url, "key", &ClientOptions::default(), |r| {
+        reported.push(r.name)
+    })
+    .await;
+
+    let statuses: Vec<(&str, CheckStatus)> = results.iter().map(|r| (r.name, r.status)).collect();
+    assert_eq!(
+        statuses,
+        vec![
+            ("URL", CheckStatus::Pass),
+            ("DNS", CheckStatus::Pass),
+            ("Connection", CheckStatus::Pass),
+            // Plain HTTP
+            ("TLS", CheckStatus::Warn),
+            ("Health", CheckStatus::Pass),
+            ("API key", CheckStatus::Pass),
+            ("Auth endpoint", CheckStatus::Pass),
+            ("Endpoints", CheckStatus::Pass),
+        ]
+    );
+    assert_eq!(reported.len(), results.len());
+}
+
+#[tokio::test]
+async fn test_check_server_stops_at_first_failure() {
+    let base_url = serve_routes("403 Forbidden", "").await;
+    let results = check_server(&base_url, "wrong", &ClientOptions::default(), |_| {}).await;
+    let key = results.iter().find(|r| r.name == "API key").unwrap();
+    assert_eq!(key.status, CheckStatus::Fail);
+    assert!(key.detail.contains("API_KEY"));
+    let skipped: Vec<&str> = results
+        .iter()
+        .filter(|r| r.status == CheckStatus::Skip)
+        .map(|r| r.name)
+        .collect();
+    assert_eq!(skipped, vec!["Auth endpoint", "Endpoints"]);
+
+    let base_url = serve_routes("401 Unauthorized", "/summary/totals").await;
+    let results = check_server(&base_url, "key", &ClientOptions::default(), |_| {}).await;
+    let endpoints = results.last().unwrap();
+    assert_eq!(endpoints.status, CheckStatus::Fail);
+    assert!(endpoints.detail.contains("/summary/totals"));
+    assert!(REQUIRED_ENDPOINTS.contains(&"/summary/totals"));
+
+    let results = check_server(
+        "ftp://example.com",
+        "key",
+        &ClientOptions::default(),
+        |_| {},
+    )
+    .await;
+    assert_eq!(results.len(), 8);
+    assert_eq!(results[0].status, CheckStatus::Fail);
+    assert!(results[1..].iter().all(|r| r.status == CheckStatus::Skip));
+}
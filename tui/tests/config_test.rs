@@ -0,0 +1,1141 @@
+//! Config tests for the Budget TUI application
+
+use base64::engine::general_purpose::URL_SAFE_NO_PAD;
+use base64::Engine;
+use budget_tui::config::backup;
+use budget_tui::config::bundle::{SettingsBundle, BUNDLE_VERSION};
+use budget_tui::config::crypt::{self, Passphrase};
+use budget_tui::config::keyring::Keyring;
+use budget_tui::config::migrate::CONFIG_VERSION;
+use budget_tui::config::validate;
+use budget_tui::config::{
+    env_exports, is_truthy, migrate_config_dir, token_expiry, AuthConfig, Config, CredentialStore,
+    LockConfig, NotifyConfig, Signal, StartupConfig, StartupMonth, TaskKind, ThresholdConfig,
+    DEFAULT_API_URL, DEFAULT_PROFILE, ENV_DEFAULT_MONTH,
+};
+use budget_tui::feedback::{self, ProblemReport};
+use budget_tui::models::{Category, Period};
+use budget_tui::state::DashboardTab;
+use budget_tui::ui::{clipboard, notify};
+use chrono::{Duration, NaiveDate, Utc, Weekday};
+use ratatui::buffer::Buffer;
+use ratatui::layout::Rect;
+use ratatui::style::Color;
+
+#[test]
+fn test_lock_config_default_is_unlocked() {
+    let lock = LockConfig::default();
+
+    assert!(!lock.is_locked());
+    assert!(lock.verify(""));
+    assert!(lock.verify("anything"));
+}
+
+#[test]
+fn test_lock_config_passphrase() {
+    let mut lock = LockConfig::default();
+    lock.set_passphrase("family").unwrap();
+
+    assert!(lock.is_locked());
+    assert!(lock.verify("family"));
+    assert!(!lock.verify("Family"));
+    assert!(!lock.verify(""));
+    // Only the hash is kept
+    let hash = lock.passphrase_hash.as_deref().unwrap();
+    assert!(hash.starts_with("pbkdf2-sha256$600000$"));
+    assert!(!hash.contains("family"));
+    assert_eq!(lock.passphrase_sha256, None);
+}
+
+#[test]
+fn test_lock_config_salts_each_hash() {
+    let mut first = LockConfig::default();
+    let mut second = LockConfig::default();
+    first.set_passphrase("family").unwrap();
+    second.set_passphrase("family").unwrap();
+
+    assert_ne!(first.passphrase_hash, second.passphrase_hash);
+    assert!(second.verify("family"));
+}
+
+#[test]
+fn test_lock_config_accepts_legacy_hash() {
+    // SHA-256 of "family", as older versions stored it
+    let mut lock = LockConfig {
+        passphrase_sha256: Some(
+            "d34a569ab7aaa54dacd715ae64953455d86b768846cd0085ef4e9e7471489b7b".to_string(),
+        ),
+        ..Default::default()
+    };
+    assert!(lock.is_locked());
+    assert!(lock.has_legacy_hash());
+    assert!(lock.verify("family"));
+    assert!(!lock.verify("Family"));
+
+    lock.set_passphrase("family").unwrap();
+    assert!(!lock.has_legacy_hash());
+    assert_eq!(lock.passphrase_sha256, None);
+    assert!(lock.verify("family"));
+}
+
+#[test]
+fn test_lock_config_damaged_hash_matches_nothing() {
+    let lock = LockConfig {
+        passphrase_hash: Some("pbkdf2-sha256$0$AAAA$AAAA".to_string()),
+        ..Default::default()
+    };
+    assert!(lock.is_locked());
+    assert!(!lock.verify(""));
+    assert!(!lock.verify("family"));
+}
+
+#[test]
+fn test_lock_config_idle_timeout() {
+    let mut lock = LockConfig {
+        idle_minutes: 5,
+        ..Default::default()
+    };
+    // Nothing could unlock it without a passphrase
+    assert_eq!(lock.idle_timeout(), None);
+
+    lock.set_passphrase("family").unwrap();
+    assert_eq!(
+        lock.idle_timeout(),
+        Some(std::time::Duration::from_secs(300))
+    );
+    lock.idle_minutes = 0;
+    assert_eq!(lock.idle_timeout(), None);
+}
+
+#[test]
+fn test_lock_config_empty_passphrase_removes_lock() {
+    let mut lock = LockConfig::default();
+    lock.set_passphrase("family").unwrap();
+    lock.set_passphrase("").unwrap();
+
+    assert!(!lock.is_locked());
+}
+
+#[test]
+fn test_lock_config_toml_roundtrip() {
+    let mut config = Config::default();
+    config.lock.set_passphrase("family").unwrap();
+
+    let content = config.to_toml().unwrap();
+    let parsed: Config = toml::from_str(&content).unwrap();
+
+    assert!(parsed.lock.verify("family"));
+}
+
+#[test]
+fn test_override_server() {
+    let mut config = Config::default();
+    let file_url = config.server.url.clone();
+
+    config.override_server(None, None);
+    assert!(!config.has_env_override());
+
+    config.override_server(Some("http://localhost:8000".to_string()), None);
+    assert!(config.has_env_override());
+    assert_eq!(config.server.url, "http://localhost:8000");
+
+    // The env values are never written back to the file
+    let content = config.to_toml().unwrap();
+    assert!(!content.contains("localhost:8000"));
+    assert!(content.contains(&file_url));
+}
+
+#[test]
+fn test_override_server_ignores_empty_url() {
+    let mut config = Config::default();
+    let file_url = config.server.url.clone();
+
+    config.override_server(Some(String::new()), Some("secret".to_string()));
+
+    assert_eq!(config.server.url, file_url);
+    assert_eq!(config.server.api_key, "secret");
+}
+
+#[test]
+fn test_set_server_replaces_override() {
+    let mut config = Config::default();
+    config.override_server(Some("http://localhost:8000".to_string()), None);
+
+    config.set_server("http://budget.lan".to_string(), "key".to_string());
+
+    assert!(!config.has_env_override());
+    assert_eq!(config.server.url, "http://budget.lan");
+    assert_eq!(config.server.api_key, "key");
+}
+
+#[test]
+fn test_server_profiles() {
+    let mut config = Config::default();
+    config.set_server("http://budget.lan".to_string(), "home-key".to_string());
+    config.auth.token = Some("home-token".to_string());
+    assert_eq!(config.next_profile(), None);
+
+    // A new name keeps the first server as "default"
+    config.save_profile(
+        "demo",
+        "https://demo.example".to_string(),
+        "demo-key".to_string(),
+    );
+    assert_eq!(config.profile_names(), vec!["default", "demo"]);
+    assert_eq!(config.profiles.active.as_deref(), Some("demo"));
+    assert_eq!(config.server.url, "https://demo.example");
+    assert_eq!(config.auth.token, None);
+    assert_eq!(
+        config.profiles.servers[DEFAULT_PROFILE].token.as_deref(),
+        Some("home-token")
+    );
+    config.auth.token = Some("demo-token".to_string());
+
+    // Switching swaps the sessions
+    assert_eq!(config.next_profile().as_deref(), Some("default"));
+    config.switch_profile("default").unwrap();
+    assert_eq!(config.server.url, "http://budget.lan");
+    assert_eq!(config.server.api_key, "home-key");
+    assert_eq!(config.auth.token.as_deref(), Some("home-token"));
+    assert_eq!(
+        config.profiles.servers["demo"].token.as_deref(),
+        Some("demo-token")
+    );
+    assert!(config.switch_profile("missing").is_err());
+
+    // Same server, new key: the session stays
+    config.save_profile(
+        "default",
+        "http://budget.lan".to_string(),
+        "new-key".to_string(),
+    );
+    assert_eq!(config.server.api_key, "new-key");
+    assert_eq!(config.auth.token.as_deref(), Some("home-token"));
+
+    let parsed: Config = toml::from_str(&config.to_toml().unwrap()).unwrap();
+    assert_eq!(parsed.profiles.active.as_deref(), Some("default"));
+    assert_eq!(parsed.profiles.servers["demo"].api_key, "demo-key");
+
+    // With a keyring, no profile's secrets are written to the file
+    config.set_keyring(Some(Keyring::SecretService));
+    let content = config.to_toml().unwrap();
+    assert!(!content.contains("demo-key"));
+    assert!(!content.contains("demo-token"));
+    assert!(content.contains("https://demo.example"));
+}
+
+#[test]
+fn test_use_profile_for_one_run() {
+    let content = r##"
+[server]
+url = "https://budget.example"
+api_key = "prod-key"
+
+[auth]
+token = "prod-token"
+
+[profiles]
+active = "prod"
+
+[profiles.servers.test]
+url = "http://localhost:8000"
+api_key = "test-key"
+
+[profiles.themes.test]
+accent = "#ef4444"
+"##;
+    let mut config: Config = toml::from_str(content).unwrap();
+    assert!(config.profiles.active_theme().is_none());
+
+    config.use_profile("test").unwrap();
+    assert_eq!(config.server.url, "http://localhost:8000");
+    assert_eq!(
+        config.profiles.active_theme().unwrap().accent.as_deref(),
+        Some("#ef4444")
+    );
+    config.auth.token = Some("test-token".to_string());
+
+    // The file stays on prod, and the test login is kept for next time
+    let parsed: Config = toml::from_str(&config.to_toml().unwrap()).unwrap();
+    assert_eq!(parsed.profiles.active.as_deref(), Some("prod"));
+    assert_eq!(parsed.server.url, "https://budget.example");
+    assert_eq!(parsed.auth.token.as_deref(), Some("prod-token"));
+    assert_eq!(
+        parsed.profiles.servers["test"].token.as_deref(),
+        Some("test-token")
+    );
+    assert_eq!(
+        parsed.profiles.themes["test"].accent.as_deref(),
+        Some("#ef4444")
+    );
+    assert!(config.use_profile("missing").is_err());
+
+    // Switching in the app sticks
+    config.switch_profile("prod").unwrap();
+    config.switch_profile("test").unwrap();
+    let parsed: Config = toml::from_str(&config.to_toml().unwrap()).unwrap();
+    assert_eq!(parsed.profiles.active.as_deref(), Some("test"));
+}
+
+#[test]
+fn test_env_exports() {
+    let lines = env_exports("https://budget.example.com", "abcd1234secret", false);
+
+    assert_eq!(
+        lines,
+        vec![
+            "export BUDGET_API_URL='https://budget.example.com'",
+            "export BUDGET_API_KEY='abcd1234secret'",
+        ]
+    );
+}
+
+#[test]
+fn test_env_exports_masks_key() {
+    let lines = env_exports("http://localhost:8000", "abcd1234secret", true);
+    assert_eq!(lines[1], "export BUDGET_API_KEY='abcd****'");
+
+    // Short keys are hidden completely
+    let lines = env_exports("http://localhost:8000", "short", true);
+    assert_eq!(lines[1], "export BUDGET_API_KEY='****'");
+}
+
+#[test]
+fn test_env_exports_quotes_for_shell() {
+    let lines = env_exports("http://localhost:8000", "it's $ecret", false);
+
+    assert_eq!(lines[1], "export BUDGET_API_KEY='it'\\''s $ecret'");
+}
+
+#[test]
+fn test_notify_signals() {
+    let notify = NotifyConfig {
+        export: Signal::Notify,
+        bulk: Signal::Flash,
+        ..Default::default()
+    };
+    let long = std::time::Duration::from_secs(6);
+    assert_eq!(notify.signal(TaskKind::Export, long), Signal::Notify);
+    assert_eq!(notify.signal(TaskKind::Bulk, long), Signal::Flash);
+    assert_eq!(notify.signal(TaskKind::Import, long), Signal::None);
+    // Quick ones stay quiet
+    let quick = std::time::Duration::from_secs(1);
+    assert_eq!(notify.signal(TaskKind::Export, quick), Signal::None);
+
+    assert_eq!(notify::sequence(Signal::Bell, "x", false), "\x07");
+    assert_eq!(
+        notify::sequence(Signal::Notify, "Saved\nit", false),
+        "\x1b]9;Savedit\x07"
+    );
+    assert_eq!(
+        notify::sequence(Signal::Notify, "Saved", true),
+        "\x1bPtmux;\x1b\x1b]9;Saved\x07\x1b\\"
+    );
+    assert_eq!(notify::sequence(Signal::Flash, "x", false), "");
+}
+
+#[test]
+fn test_clipboard_osc52_sequence() {
+    assert_eq!(clipboard::osc52_sequence("hi"), "\x1b]52;c;aGk=\x07");
+}
+
+/// Unsigned JWT with the given payload; only the payload matters to the client
+fn jwt(payload: &str) -> String {
+    format!(
+        "{}.{}.signature",
+        URL_SAFE_NO_PAD.encode(r#"{"alg":"HS256","typ":"JWT"}"#),
+        URL_SAFE_NO_PAD.encode(payload)
+    )
+}
+
+#[test]
+fn test_token_expiry() {
+    let token = jwt(r#"{"sub":"me@example.com","user_id":1,"exp":1767225600}"#);
+    let expiry = token_expiry(&token).unwrap();
+
+    assert_eq!(expiry.to_rfc3339(), "2026-01-01T00:00:00+00:00");
+}
+
+#[test]
+fn test_token_expiry_invalid() {
+    assert_eq!(token_expiry("not-a-jwt"), None);
+    assert_eq!(token_expiry("a.!!!.c"), None);
+    assert_eq!(token_expiry(&jwt(r#"{"sub":"me@example.com"}"#)), None);
+}
+
+#[test]
+fn test_auth_valid_token() {
+    let mut auth = AuthConfig {
+        token: Some("token".to_string()),
+        expires_at: Some(Utc::now() + Duration::hours(1)),
+        ..Default::default()
+    };
+    assert_eq!(auth.valid_token(), Some("token"));
+
+    auth.expires_at = Some(Utc::now() - Duration::minutes(1));
+    assert_eq!(auth.valid_token(), None);
+
+    // Tokens saved before expiry was tracked are still tried
+    auth.expires_at = None;
+    assert_eq!(auth.valid_token(), Some("token"));
+
+    assert_eq!(AuthConfig::default().valid_token(), None);
+}
+
+#[test]
+fn test_session_token_stays_with_its_server() {
+    let mut config = Config::default();
+    config.set_server("http://budget.lan".to_string(), "key".to_string());
+    config.auth.token = Some("home-token".to_string());
+    // Saved before the server was recorded: it is the file's server's
+    assert_eq!(config.session_token(), Some("home-token"));
+
+    config.override_server(Some("https://other.example".to_string()), None);
+    assert_eq!(config.session_token(), None);
+    assert!(!config.token_expired());
+    config.override_server(Some("http://budget.lan/".to_string()), None);
+    assert_eq!(config.session_token(), Some("home-token"));
+
+    // A login made while pointed elsewhere isn't kept for the file's server
+    config.override_server(Some("https://other.example".to_string()), None);
+    config.auth.token = Some("other-token".to_string());
+    config.auth.server_url = Some("https://other.example".to_string());
+    config.save_profile(
+        "demo",
+        "https://demo.example".to_string(),
+        "demo-key".to_string(),
+    );
+    assert_eq!(config.profiles.servers[DEFAULT_PROFILE].token, None);
+    assert_eq!(config.session_token(), None);
+
+    // Each profile's session travels with its server
+    config.auth.token = Some("demo-token".to_string());
+    config.auth.server_url = Some("https://demo.example".to_string());
+    config.switch_profile(DEFAULT_PROFILE).unwrap();
+    assert_eq!(config.session_token(), None);
+    config.switch_profile("demo").unwrap();
+    assert_eq!(
+        config.auth.server_url.as_deref(),
+        Some("https://demo.example")
+    );
+    assert_eq!(config.session_token(), Some("demo-token"));
+
+    config.auth.expires_at = Some(Utc::now() - Duration::minutes(1));
+    assert_eq!(config.session_token(), None);
+    assert!(config.token_expired());
+}
+
+#[test]
+fn test_auth_expiry_toml_roundtrip() {
+    let mut config = Config::default();
+    config.auth.token = Some("token".to_string());
+    config.auth.expires_at = token_expiry(&jwt(r#"{"exp":1767225600}"#));
+
+    let parsed: Config = toml::from_str(&config.to_toml().unwrap()).unwrap();
+
+    assert_eq!(parsed.auth.expires_at, config.auth.expires_at);
+    assert!(parsed.auth.valid_token().is_none());
+}
+
+#[test]
+fn test_credentials_default_to_auto() {
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "http://localhost:8000"
+api_key = "key"
+"#,
+    )
+    .unwrap();
+    assert_eq!(config.credentials.store, CredentialStore::Auto);
+    assert!(config.keyring().is_none());
+
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "http://localhost:8000"
+api_key = "key"
+
+[credentials]
+store = "file"
+"#,
+    )
+    .unwrap();
+    assert_eq!(config.credentials.store, CredentialStore::File);
+}
+
+#[test]
+fn test_to_toml_keeps_secrets_without_keyring() {
+    let mut config = Config::default();
+    config.server.api_key = "secret-key".to_string();
+    config.auth.token = Some("secret-token".to_string());
+
+    let content = config.to_toml().unwrap();
+
+    assert!(content.contains("secret-key"));
+    assert!(content.contains("secret-token"));
+}
+
+#[test]
+fn test_to_toml_leaves_secrets_to_keyring() {
+    let mut config = Config::default();
+    config.server.api_key = "secret-key".to_string();
+    config.auth.token = Some("secret-token".to_string());
+    config.set_keyring(Some(Keyring::SecretService));
+
+    let content = config.to_toml().unwrap();
+    let parsed: Config = toml::from_str(&content).unwrap();
+
+    assert!(!content.contains("secret-key"));
+    assert!(!content.contains("secret-token"));
+    assert_eq!(parsed.server.url, config.server.url);
+    // Secrets stay in memory for the session
+    assert_eq!(config.server.api_key, "secret-key");
+}
+
+#[test]
+fn test_debug_env_flag() {
+    assert!(is_truthy(Some("1")));
+    assert!(is_truthy(Some(" TRUE ")));
+    assert!(is_truthy(Some("yes")));
+    assert!(!is_truthy(Some("0")));
+    assert!(!is_truthy(Some("")));
+    assert!(!is_truthy(None));
+}
+
+#[test]
+fn test_debug_defaults_off() {
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "http://localhost:8000"
+api_key = "key"
+
+[network]
+max_retries = 1
+"#,
+    )
+    .unwrap();
+
+    assert!(!config.network.debug);
+    assert!(Config::debug_log_path().unwrap().ends_with("debug.log"));
+}
+
+#[test]
+fn test_network_client_options() {
+    let config = Config::default();
+    let options = config.network.client_options();
+    assert_eq!(options.timeout, std::time::Duration::from_secs(30));
+    assert_eq!(options.ca_bundle, None);
+    assert!(!options.insecure_skip_verify);
+
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "https://budget.internal"
+api_key = "key"
+
+[network]
+timeout_secs = 0
+ca_bundle = "/etc/ssl/internal-ca.pem"
+insecure_skip_verify = true
+"#,
+    )
+    .unwrap();
+    let options = config.network.client_options();
+
+    // Zero would time out every request immediately
+    assert_eq!(options.timeout, std::time::Duration::from_secs(1));
+    assert_eq!(
+        options.ca_bundle,
+        Some(std::path::PathBuf::from("/etc/ssl/internal-ca.pem"))
+    );
+    assert!(options.insecure_skip_verify);
+}
+
+#[test]
+fn test_network_proxy_options() {
+    let mut config = Config::default();
+    assert_eq!(config.network.client_options().proxy, None);
+
+    config.network.proxy = Some(" ".to_string());
+    assert_eq!(config.network.client_options().proxy, None);
+
+    config.network.proxy = Some("socks5h://127.0.0.1:1080".to_string());
+    config.network.no_proxy = Some("localhost".to_string());
+    let options = config.network.client_options();
+    assert_eq!(options.proxy.as_deref(), Some("socks5h://127.0.0.1:1080"));
+    assert_eq!(options.no_proxy.as_deref(), Some("localhost"));
+}
+
+#[test]
+fn test_network_headers() {
+    assert!(Config::default().network.header_hook().unwrap().is_empty());
+
+    let mut config: Config = toml::from_str(
+        r#"
+[server]
+url = "https://budget.internal"
+api_key = "key"
+
+[network.headers]
+X-Tenant = "home"
+"#,
+    )
+    .unwrap();
+    assert!(!config.network.header_hook().unwrap().is_empty());
+
+    config
+        .network
+        .headers
+        .insert("Not a header".to_string(), "x".to_string());
+    let err = config.network.header_hook().unwrap_err();
+    assert!(format!("{:#}", err).contains("Not a header"));
+}
+
+#[test]
+fn test_ca_bundle_expands_home() {
+    let mut config = Config::default();
+    config.network.ca_bundle = Some("~/certs/ca.pem".into());
+
+    let path = config.network.client_options().ca_bundle.unwrap();
+    assert!(!path.starts_with("~"));
+    assert!(path.ends_with("certs/ca.pem"));
+}
+
+#[test]
+fn test_google_sheets_config() {
+    assert!(!Config::default().google_sheets.is_configured());
+
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "http://localhost:8000"
+api_key = "key"
+
+[google_sheets]
+spreadsheet_id = "1AbC"
+service_account = "~/keys/budget.json"
+push_on_close = true
+"#,
+    )
+    .unwrap();
+    let sheets = &config.google_sheets;
+    assert!(sheets.is_configured());
+    assert!(sheets.push_on_close);
+    let path = sheets.service_account_path().unwrap();
+    assert!(!path.starts_with("~"));
+    assert!(path.ends_with("keys/budget.json"));
+
+    // A key file alone isn't enough
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "http://localhost:8000"
+api_key = "key"
+
+[google_sheets]
+service_account = "key.json"
+"#,
+    )
+    .unwrap();
+    assert!(!config.google_sheets.is_configured());
+}
+
+#[test]
+fn test_threshold_category_overrides() {
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "http://localhost:8000"
+api_key = "key"
+
+[thresholds]
+near_percent = 85
+
+[thresholds.categories.Groceries]
+over_percent = 110
+"#,
+    )
+    .unwrap();
+
+    let global = config.thresholds.global();
+    assert_eq!(global.near_percent, 85.0);
+    assert_eq!(global.over_percent, 100.0);
+
+    let groceries = config.thresholds.for_category("groceries");
+    assert_eq!(groceries.near_percent, 85.0);
+    assert_eq!(groceries.over_percent, 110.0);
+    assert_eq!(config.thresholds.for_category("Rent"), global);
+}
+
+#[test]
+fn test_periods_config() {
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "http://localhost:8000"
+api_key = "key"
+
+[periods]
+order = ["Variable/2nd Period"]
+week_start = "Sunday"
+
+[periods.labels]
+"Fixed/1st Period" = "Quincena 1"
+"#,
+    )
+    .unwrap();
+
+    let periods = &config.periods;
+    assert_eq!(periods.week_start, Weekday::Sun);
+    assert_eq!(periods.rank("variable/2nd period"), 0);
+    assert_eq!(periods.rank("Fixed/1st Period"), 1);
+    assert_eq!(periods.label("Fixed/1st Period"), "Quincena 1");
+    assert_eq!(periods.label("Variable/2nd Period"), "Variable/2nd Period");
+
+    let parsed: Config = toml::from_str(&config.to_toml().unwrap()).unwrap();
+    assert_eq!(parsed.periods.week_start, Weekday::Sun);
+    assert_eq!(Config::default().periods.week_start, Weekday::Mon);
+}
+
+#[test]
+fn test_themes() {
+    let mut config: Config = toml::from_str(
+        r##"
+[server]
+url = "http://localhost:8000"
+api_key = "key"
+
+[display]
+theme = "mine"
+
+[themes.mine]
+accent = "#268bd2"
+negative = "light red"
+muted = "240"
+"##,
+    )
+    .unwrap();
+
+    let theme = config.theme().unwrap();
+    assert_eq!(theme.map(Color::Cyan), Color::Rgb(0x26, 0x8b, 0xd2));
+    assert_eq!(theme.map(Color::Red), Color::LightRed);
+    assert_eq!(theme.map(Color::DarkGray), Color::Indexed(240));
+    // Colors from the server and ones the theme leaves out are kept
+    assert_eq!(theme.map(Color::Green), Color::Green);
+    assert_eq!(theme.map(Color::Rgb(1, 2, 3)), Color::Rgb(1, 2, 3));
+
+    let mut buffer = Buffer::empty(Rect::new(0, 0, 2, 1));
+    buffer[(0, 0)].set_fg(Color::Cyan).set_bg(Color::Red);
+    theme.apply(&mut buffer);
+    assert_eq!(buffer[(0, 0)].fg, Color::Rgb(0x26, 0x8b, 0xd2));
+    assert_eq!(buffer[(0, 0)].bg, Color::LightRed);
+
+    config.display.theme = Some("gruvbox".to_string());
+    assert!(!config.theme().unwrap().is_empty());
+    config.display.theme = None;
+    assert!(config.theme().unwrap().is_empty());
+
+    config.display.theme = Some("missing".to_string());
+    let error = config.theme().unwrap_err().to_string();
+    assert!(error.contains("solarized"), "{}", error);
+    assert!(error.contains("mine"), "{}", error);
+
+    config.display.theme = Some("mine".to_string());
+    config.themes.get_mut("mine").unwrap().text = Some("not a color".to_string());
+    assert!(config.theme().is_err());
+}
+
+#[test]
+fn test_thresholds_toml_roundtrip() {
+    let config = Config::default();
+    let parsed: Config = toml::from_str(&config.to_toml().unwrap()).unwrap();
+
+    assert_eq!(parsed.thresholds, ThresholdConfig::default());
+}
+
+#[test]
+fn test_settings_bundle() {
+    let mut config = Config::default();
+    config.server.api_key = "secret-key".to_string();
+    config.tax.flags = vec!["Deductible".to_string()];
+    config.envelopes.weekly = vec!["Groceries".to_string()];
+    let categories = vec![Category {
+        id: 1,
+        name: "Groceries".to_string(),
+        color: "#22c55e".to_string(),
+    }];
+    let periods = vec![Period {
+        id: 1,
+        name: "Fixed/1st Period".to_string(),
+        color: "#8b5cf6".to_string(),
+    }];
+
+    let text = SettingsBundle::new(&config, &categories, &periods, &[])
+        .to_toml()
+        .unwrap();
+    // Credentials never leave the machine
+    assert!(!text.contains("secret-key"));
+    let bundle = SettingsBundle::parse(&text).unwrap();
+    assert_eq!(bundle.version, BUNDLE_VERSION);
+    assert_eq!(bundle.categories[0].name, "Groceries");
+    assert_eq!(bundle.categories[0].color.as_deref(), Some("#22c55e"));
+
+    // A fresh server gets everything; names match ignoring case and spacing
+    let plan = bundle.plan(&[], &[], &[]);
+    assert_eq!((plan.categories.len(), plan.periods.len()), (1, 1));
+    let existing = vec![Category {
+        name: " groceries".to_string(),
+        ..categories[0].clone()
+    }];
+    let plan = bundle.plan(&existing, &periods, &[]);
+    assert!(plan.is_empty());
+    assert_eq!(plan.existing, 2);
+
+    let mut other = Config::default();
+    let applied = bundle.apply_settings(&mut other);
+    assert!(applied.contains(&"tax"));
+    assert_eq!(other.tax.flags, vec!["Deductible".to_string()]);
+    assert_eq!(other.envelopes.weekly, vec!["Groceries".to_string()]);
+    assert_eq!(other.server.api_key, Config::default().server.api_key);
+
+    // Sections left out of a bundle are kept
+    let bundle = SettingsBundle::parse("version = 1\n[[periods]]\nname = \"Weekly\"").unwrap();
+    assert!(bundle.apply_settings(&mut other).is_empty());
+    assert_eq!(bundle.periods[0].color, None);
+
+    assert!(SettingsBundle::parse("version = 99").is_err());
+}
+
+#[test]
+fn test_migrate_config_dir() {
+    let root = std::env::temp_dir().join(format!("budget-tui-migrate-{}", std::process::id()));
+    let old = root.join("home").join(".config").join("budget-tui");
+    let new = root.join("xdg").join("budget-tui");
+    std::fs::create_dir_all(old.join("data").join("server")).unwrap();
+    std::fs::write(old.join("config.toml"), "[server]\n").unwrap();
+    std::fs::write(old.join("data").join("server").join("notes.json"), "{}").unwrap();
+
+    assert!(migrate_config_dir(&old, &new).unwrap());
+    assert!(!old.exists());
+    assert_eq!(
+        std::fs::read_to_string(new.join("config.toml")).unwrap(),
+        "[server]\n"
+    );
+    assert!(new.join("data").join("server").join("notes.json").exists());
+
+    // Nothing left to move, and an existing config is never replaced
+    assert!(!migrate_config_dir(&old, &new).unwrap());
+    std::fs::create_dir_all(&old).unwrap();
+    std::fs::write(old.join("config.toml"), "old").unwrap();
+    assert!(!migrate_config_dir(&old, &new).unwrap());
+    assert_eq!(
+        std::fs::read_to_string(new.join("config.toml")).unwrap(),
+        "[server]\n"
+    );
+
+    std::fs::remove_dir_all(&root).unwrap();
+}
+
+#[test]
+fn test_config_versions() {
+    // The first releases' bare url/key file
+    let (config, version) =
+        Config::parse("url = \"http://localhost:8000\"\nkey = \"secret\"\ntoken = \"abc\"\n")
+            .unwrap();
+    assert_eq!(version, 0);
+    assert_eq!(config.version, CONFIG_VERSION);
+    assert_eq!(config.server.url, "http://localhost:8000");
+    assert_eq!(config.server.api_key, "secret");
+    assert_eq!(config.auth.token.as_deref(), Some("abc"));
+
+    // Unversioned files with sections keep them
+    let (config, version) =
+        Config::parse("[server]\nurl = \"https://a.example\"\napi_key = \"k\"\n").unwrap();
+    assert_eq!(version, 0);
+    assert_eq!(config.server.url, "https://a.example");
+
+    // Nothing to upgrade in a file written now
+    let saved = Config::default().to_toml().unwrap();
+    assert!(saved.starts_with(&format!("version = {}", CONFIG_VERSION)));
+    let (config, version) = Config::parse(&saved).unwrap();
+    assert_eq!(version, CONFIG_VERSION);
+    assert_eq!(config.server.url, Config::default().server.url);
+
+    // Newer files are refused
+    let newer = format!("version = {}\n", CONFIG_VERSION + 1);
+    let err = Config::parse(&newer).unwrap_err();
+    assert!(err.to_string().contains("newer"));
+    assert!(Config::parse("version = \"one\"\n").is_err());
+}
+
+#[test]
+fn test_config_encryption() {
+    let passphrase = Passphrase::new("correct horse");
+    let plain = Config::default().to_toml().unwrap();
+
+    let sealed = crypt::encrypt(&plain, &passphrase).unwrap();
+    assert!(crypt::is_encrypted(&sealed));
+    assert!(!crypt::is_encrypted(&plain));
+    assert!(!sealed.contains(DEFAULT_API_URL));
+    assert_eq!(crypt::decrypt(&sealed, &passphrase).unwrap(), plain);
+
+    // Fresh salt and nonce every time
+    assert_ne!(crypt::encrypt(&plain, &passphrase).unwrap(), sealed);
+
+    let err = crypt::decrypt(&sealed, &Passphrase::new("wrong")).unwrap_err();
+    assert!(err.to_string().contains("Wrong passphrase"));
+    let damaged = &sealed[..sealed.len() - 8];
+    assert!(crypt::decrypt(damaged, &passphrase).is_err());
+    assert!(crypt::decrypt(&plain, &passphrase).is_err());
+
+    assert_eq!(format!("{:?}", passphrase), "Passphrase(..)");
+}
+
+#[test]
+fn test_config_encryption_rejects_excessive_rounds() {
+    let passphrase = Passphrase::new("correct horse");
+    let sealed = crypt::encrypt("x = 1", &passphrase).unwrap();
+
+    // Ten times the default is the most a file may ask for
+    let tampered = sealed.replacen("\n600000 ", "\n6000001 ", 1);
+    assert_ne!(tampered, sealed);
+    let err = crypt::decrypt(&tampered, &passphrase).unwrap_err();
+    assert!(err.to_string().contains("too many PBKDF2 rounds"));
+
+    let mut lock = LockConfig::default();
+    lock.set_passphrase("family").unwrap();
+    lock.passphrase_hash = lock
+        .passphrase_hash
+        .map(|hash| hash.replacen("$600000$", "$4294967295$", 1));
+    assert!(!lock.verify("family"));
+}
+
+#[test]
+fn test_config_reload() {
+    let content = r##"
+[server]
+url = "https://budget.example"
+api_key = "prod-key"
+
+[profiles]
+active = "prod"
+
+[profiles.servers.test]
+url = "http://localhost:8000"
+api_key = "test-key"
+"##;
+    let mut config: Config = toml::from_str(content).unwrap();
+
+    // Settings edited elsewhere come in as they are
+    let edited = content.replace("[profiles]", "[display]\ntheme = \"gruvbox\"\n\n[profiles]");
+    let reloaded = config.reload_from(&edited).unwrap();
+    assert_eq!(reloaded.display.theme.as_deref(), Some("gruvbox"));
+    assert_eq!(reloaded.server.url, "https://budget.example");
+
+    // A run on another profile stays on it
+    config.use_profile("test").unwrap();
+    let reloaded = config.reload_from(&edited).unwrap();
+    assert_eq!(reloaded.profiles.active.as_deref(), Some("test"));
+    assert_eq!(reloaded.server.url, "http://localhost:8000");
+    assert!(config.reload_from("[server").is_err());
+
+    // An encrypted file opens only with this run's passphrase
+    let passphrase = Passphrase::new("correct horse");
+    let sealed = crypt::encrypt(content, &passphrase).unwrap();
+    let err = Config::default().reload_from(&sealed).unwrap_err();
+    assert!(err.to_string().contains("restart"));
+    let mut unlocked = Config::default();
+    unlocked.set_passphrase(Some(passphrase));
+    let reloaded = unlocked.reload_from(&sealed).unwrap();
+    assert!(reloaded.is_encrypted());
+    assert_eq!(reloaded.server.url, "https://budget.example");
+}
+
+#[test]
+fn test_startup_config() {
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "https://budget.example"
+api_key = "key"
+
+[startup]
+tab = "Expenses"
+month = "latest"
+period = "fixed/1st period"
+"#,
+    )
+    .unwrap();
+    assert_eq!(config.startup.tab().unwrap(), Some(DashboardTab::Expenses));
+    assert_eq!(config.startup.month, StartupMonth::Latest);
+    assert_eq!(config.startup.category, None);
+
+    let defaults = StartupConfig::default();
+    assert_eq!(defaults.tab().unwrap(), None);
+    assert_eq!(defaults.month, StartupMonth::Current);
+
+    let unknown = StartupConfig {
+        tab: Some("budget".to_string()),
+        ..Default::default()
+    };
+    let err = unknown.tab().unwrap_err().to_string();
+    assert!(err.contains("expected summary, expenses"), "{}", err);
+}
+
+#[test]
+fn test_startup_default_month() {
+    // Left to BUDGET_DEFAULT_MONTH, which would win over the field
+    if std::env::var_os(ENV_DEFAULT_MONTH).is_some() {
+        return;
+    }
+    let config: Config = toml::from_str(
+        r#"
+[server]
+url = "https://budget.example"
+api_key = "key"
+
+[startup]
+default_month = "2024-03"
+"#,
+    )
+    .unwrap();
+    assert_eq!(config.startup.opening_month().unwrap(), Some((2024, 3)));
+    assert_eq!(StartupConfig::default().opening_month().unwrap(), None);
+
+    let typo = StartupConfig {
+        default_month: Some("March".to_string()),
+        ..Default::default()
+    };
+    let err = typo.opening_month().unwrap_err().to_string();
+    assert!(
+        err.contains("default_month must be a month like 2024-03"),
+        "{}",
+        err
+    );
+}
+
+#[test]
+fn test_config_problems() {
+    // What the app writes has nothing to report
+    let saved = Config::default().to_toml().unwrap();
+    assert!(Config::parse(&saved).unwrap().0.problems().is_empty());
+
+    let text = "[server]
+url = \"localhost:8000\"
+api_key = \"k\"
+
+[months]
+pin_curent = true
+
+[network]
+ca_bundle = \"/nonexistent/ca.pem\"
+
+[startup]
+tab = \"reports\"
+
+[mystery]
+answer = 42
+";
+    let (config, _) = Config::parse(text).unwrap();
+    let problems: Vec<String> = config.problems().iter().map(|p| p.to_string()).collect();
+    let find = |key: &str| {
+        problems
+            .iter()
+            .find(|p| p.starts_with(key))
+            .unwrap_or_else(|| panic!("no problem for {} in {:?}", key, problems))
+    };
+    assert!(find("server.url:").contains("http:// or https://"));
+    assert!(find("months.pin_curent:").contains("did you mean 'pin_current'?"));
+    assert!(find("mystery:").contains("Unknown setting"));
+    assert!(find("network.ca_bundle:").contains("/nonexistent/ca.pem"));
+    assert!(find("startup.tab:").contains("No tab named 'reports'"));
+    assert_eq!(problems.len(), 5);
+    // The settings that were right still apply
+    assert!(!config.months.pin_current);
+    assert_eq!(config.server.api_key, "k");
+
+    assert_eq!(validate::summary(&[]), None);
+    let summary = validate::summary(config.problems()).unwrap();
+    assert!(summary.contains("(and 4 more; run --check-config)"));
+}
+
+#[test]
+fn test_config_backup_round_trip() {
+    let mut config = Config::default();
+    config.server.api_key = "secret-key".to_string();
+    config.auth.token = Some("session".to_string());
+    config.months.autofill_months = 6;
+    let passphrase = Passphrase::new("correct horse");
+
+    let sealed = backup::export(&config, &passphrase).unwrap();
+    assert!(sealed.starts_with(b"age-encryption.org/v1"));
+    assert!(!String::from_utf8_lossy(&sealed).contains("secret-key"));
+
+    let imported = backup::import(&sealed, &passphrase).unwrap();
+    assert_eq!(imported.server.api_key, "secret-key");
+    assert_eq!(imported.auth.token.as_deref(), Some("session"));
+    assert_eq!(imported.months.autofill_months, 6);
+
+    let err = backup::import(&sealed, &Passphrase::new("wrong")).unwrap_err();
+    assert!(err.to_string().contains("Wrong passphrase"));
+    assert!(backup::import(b"not a backup", &passphrase).is_err());
+}
+
+#[test]
+fn test_config_shared_export() {
+    let mut config = Config::default();
+    config.server.api_key = "secret-key".to_string();
+    config.auth.token = Some("tok-123".to_string());
+    config.months.autofill_round = 5.0;
+
+    let text = backup::export_shared(&config).unwrap();
+    assert!(!backup::is_encrypted(text.as_bytes()));
+    assert!(!text.contains("secret-key"));
+    assert!(!text.contains("tok-123"));
+
+    let imported = backup::import_shared(&text).unwrap();
+    assert!(imported.problems().is_empty());
+    assert_eq!(imported.server.url, config.server.url);
+    assert_eq!(imported.server.api_key, "");
+    assert_eq!(imported.auth.token, None);
+    assert_eq!(imported.months.autofill_round, 5.0);
+}
+
+#[test]
+fn test_problem_report() {
+    let mut config = Config::default();
+    config.server.url = "https://budget.home.example".to_string();
+    config.server.api_key = "secret-key".to_string();
+    config.network.proxy = Some("http://proxy.home.example:3128".to_string());
+    config.months.autofill_round = 5.0;
+
+    let errors = vec![(
+        NaiveDate::from_ymd_opt(2024, 3, 5)
+            .unwrap()
+            .and_hms_opt(14, 5, 0)
+            .unwrap(),
+        "Failed to load expenses".to_string(),
+    )];
+    let report = ProblemReport::new("1.2.3\n", &config, &errors).unwrap();
+    assert_eq!(report.version, "1.2.3");
+
+    let body = report.body();
+    assert!(body.contains("- Version: 1.2.3"), "{}", body);
+    assert!(body.contains("- 2024-03-05 14:05 Failed to load expenses"));
+    assert!(body.contains("autofill_round = 5.0"));
+    assert!(body.contains("<redacted>"));
+    for private in ["secret-key", "budget.home.example", "proxy.home.example"] {
+        assert!(!body.contains(private), "{} in {}", private, body);
+    }
+
+    let url = report.issue_url();
+    assert!(url.starts_with(feedback::ISSUES_URL), "{}", url);
+    assert!(
+        url.contains("title=Problem%3A+Failed+to+load+expenses"),
+        "{}",
+        url
+    );
+    assert!(!url.contains("secret-key"));
+}
@@ -0,0 +1,271 @@
+//! Report export tests for the Budget TUI application
+
+use budget_tui::export::xlsx::{column_name, Cell, Sheet, Workbook};
+use budget_tui::export::{csv_field, month_csv_file_name, TaxReport, YearReport};
+use budget_tui::models::{CustomFields, Expense, Income, IncomeType, Month};
+use budget_tui::storage::TaxFlags;
+
+fn expense(name: &str, category: &str, projected: f64, cost: f64, month_id: i32) -> Expense {
+    Expense {
+        id: month_id * 100 + name.len() as i32,
+        expense_name: name.to_string(),
+        period: "Monthly".to_string(),
+        category: category.to_string(),
+        projected,
+        cost,
+        notes: None,
+        month_id,
+        purchases: None,
+        order: 0,
+        expense_date: None,
+        created_at: None,
+        updated_at: None,
+        created_by: None,
+        updated_by: None,
+        attachments: None,
+        account_id: None,
+        currency: None,
+        custom_fields: CustomFields::new(),
+    }
+}
+
+fn income(income_type_id: i32, amount: f64, month_id: i32) -> Income {
+    Income {
+        id: month_id,
+        income_type_id,
+        period: "Monthly".to_string(),
+        projected: amount,
+        amount,
+        month_id,
+        created_at: "2024-01-01T00:00:00Z".to_string(),
+        updated_at: "2024-01-01T00:00:00Z".to_string(),
+        created_by: None,
+        updated_by: None,
+        account_id: None,
+        currency: None,
+    }
+}
+
+fn month(id: i32, year: i32, month: i32) -> Month {
+    Month {
+        id,
+        year,
+        month,
+        name: format!("{}-{:02}", year, month),
+        start_date: format!("{}-{:02}-01", year, month),
+        end_date: format!("{}-{:02}-28", year, month),
+        is_closed: false,
+        closed_at: None,
+        closed_by: None,
+        notes: None,
+    }
+}
+
+fn income_types() -> Vec<IncomeType> {
+    vec![
+        IncomeType {
+            id: 1,
+            name: "Salary".to_string(),
+            color: "#10b981".to_string(),
+        },
+        IncomeType {
+            id: 2,
+            name: "Gifts".to_string(),
+            color: "#f59e0b".to_string(),
+        },
+    ]
+}
+
+fn sample_report() -> TaxReport {
+    let mut flags = TaxFlags::default();
+    flags.set_expense_flag("Doctor", Some("Medical".to_string()));
+    flags.set_expense_flag("Pharmacy", Some("Medical".to_string()));
+    flags.set_expense_flag("Donation", Some("Charity".to_string()));
+    flags.set_income_flag("Salary", Some("Taxable".to_string()));
+
+    let expenses = vec![
+        expense("Doctor", "Health", 100.0, 80.0, 1),
+        expense("Doctor", "Health", 100.0, 120.0, 2),
+        expense("Pharmacy", "Drugstore", 50.0, 45.5, 1),
+        expense("Donation", "Gifts, misc", 20.0, 20.0, 1),
+        expense("Groceries", "Food", 500.0, 480.0, 1),
+    ];
+    let incomes = vec![
+        income(1, 3000.0, 1),
+        income(1, 3000.0, 2),
+        income(2, 50.0, 1),
+    ];
+    TaxReport::build(2024, &expenses, &incomes, &income_types(), &flags)
+}
+
+#[test]
+fn test_tax_report_totals_flagged_items_per_category() {
+    let report = sample_report();
+
+    let rows: Vec<(&str, &str, &str, usize, f64)> = report
+        .rows
+        .iter()
+        .map(|r| (r.kind, r.flag.as_str(), r.group.as_str(), r.items, r.actual))
+        .collect();
+    assert_eq!(
+        rows,
+        vec![
+            ("Expense", "Charity", "Gifts, misc", 1, 20.0),
+            ("Expense", "Medical", "Drugstore", 1, 45.5),
+            ("Expense", "Medical", "Health", 2, 200.0),
+            ("Income", "Taxable", "Salary", 2, 6000.0),
+        ]
+    );
+    assert_eq!(report.file_name(), "tax-report-2024.csv");
+}
+
+#[test]
+fn test_tax_report_csv() {
+    let csv = sample_report().to_csv();
+
+    assert_eq!(
+        csv,
+        "Type,Flag,Category,Items,Projected,Actual\n\
+         Expense,Charity,\"Gifts, misc\",1,20.00,20.00\n\
+         Expense,Charity,Total,1,20.00,20.00\n\
+         Expense,Medical,Drugstore,1,50.00,45.50\n\
+         Expense,Medical,Health,2,200.00,200.00\n\
+         Expense,Medical,Total,3,250.00,245.50\n\
+         Income,Taxable,Salary,2,6000.00,6000.00\n\
+         Income,Taxable,Total,2,6000.00,6000.00\n"
+    );
+}
+
+#[test]
+fn test_csv_field_quoting() {
+    assert_eq!(csv_field("plain"), "plain");
+    assert_eq!(csv_field("a,b"), "\"a,b\"");
+    assert_eq!(csv_field("say \"hi\""), "\"say \"\"hi\"\"\"");
+}
+
+fn sample_year() -> YearReport {
+    // Listed out of order, with a month from another year
+    let months = vec![month(2, 2024, 2), month(1, 2024, 1), month(9, 2023, 12)];
+    let expenses = vec![
+        expense("Rent", "Housing", 1000.0, 1000.0, 1),
+        expense("Groceries", "Food", 400.0, 450.0, 1),
+        expense("Rent", "Housing", 1000.0, 1000.0, 2),
+        expense("Old", "Food", 10.0, 10.0, 9),
+    ];
+    let incomes = vec![income(1, 3000.0, 1), income(1, 3100.0, 2)];
+
+    YearReport::build(2024, &months, &expenses, &incomes, &income_types())
+}
+
+#[test]
+fn test_year_report_totals() {
+    let report = sample_year();
+
+    let totals: Vec<(String, f64, f64, f64)> = report
+        .month_totals()
+        .into_iter()
+        .map(|t| {
+            let balance = t.balance();
+            (t.name, t.income_actual, t.expenses_actual, balance)
+        })
+        .collect();
+    assert_eq!(
+        totals,
+        vec![
+            ("January 2024".to_string(), 3000.0, 1450.0, 1550.0),
+            ("February 2024".to_string(), 3100.0, 1000.0, 2100.0),
+        ]
+    );
+    assert_eq!(
+        report.category_totals(),
+        vec![
+            ("Food".to_string(), 400.0, 450.0),
+            ("Housing".to_string(), 2000.0, 2000.0),
+        ]
+    );
+    assert_eq!(report.file_name(), "budget-2024.xlsx");
+}
+
+#[test]
+fn test_year_report_sheets() {
+    let workbook = sample_year().to_workbook();
+
+    assert_eq!(
+        workbook.sheet_names(),
+        vec!["2024 Overview", "January 2024", "February 2024"]
+    );
+    let overview = &workbook.sheets[0];
+    // Header, two months, then the year's total
+    assert_eq!(overview.rows[3][0], Cell::heading("Total"));
+    assert_eq!(overview.rows[3][2], Cell::Number(6100.0));
+    assert_eq!(overview.rows[3][5], Cell::Number(3650.0));
+
+    let january = &workbook.sheets[1];
+    assert_eq!(january.rows[1][0], Cell::text("Rent"));
+    assert_eq!(january.rows[3][4], Cell::Number(1450.0));
+    assert!(january.rows.contains(&vec![
+        Cell::text("Salary"),
+        Cell::text("Monthly"),
+        Cell::Number(3000.0),
+        Cell::Number(3000.0),
+    ]));
+}
+
+#[test]
+fn test_workbook_sheet_names_are_valid_and_unique() {
+    let mut workbook = Workbook::default();
+    workbook.push(Sheet::new("Q1/Q2 [draft]"));
+    workbook.push(Sheet::new("A very long sheet name that Excel would reject"));
+    workbook.push(Sheet::new(
+        "a very long sheet name that excel would reject too",
+    ));
+    workbook.push(Sheet::new(""));
+
+    let names = workbook.sheet_names();
+    assert_eq!(names[0], "Q1-Q2 -draft-");
+    assert_eq!(names[1], "A very long sheet name that Exc");
+    assert_eq!(names[2], "a very long sheet name that (2)");
+    assert_eq!(names[3], "Sheet4");
+    assert!(names.iter().all(|n| n.chars().count() <= 31));
+}
+
+#[test]
+fn test_workbook_xlsx_archive() {
+    let mut sheet = Sheet::new("Data");
+    sheet.push_headings(&["Name", "Amount"]);
+    sheet.push(vec![Cell::text("Fish & <Chips>"), Cell::Number(12.5)]);
+    let mut workbook = Workbook::default();
+    workbook.push(sheet);
+
+    let bytes = workbook.to_bytes();
+    let text = String::from_utf8_lossy(&bytes);
+
+    assert!(bytes.starts_with(b"PK\x03\x04"));
+    assert!(text.contains("xl/worksheets/sheet1.xml"));
+    assert!(text.contains("Fish &amp; &lt;Chips&gt;"));
+    assert!(text.contains(r#"<c r="B2" s="1"><v>12.5</v></c>"#));
+
+    // End of central directory: 5 package parts plus the sheet
+    let end = &bytes[bytes.len() - 22..];
+    assert_eq!(&end[..4], b"PK\x05\x06");
+    assert_eq!(u16::from_le_bytes([end[10], end[11]]), 6);
+    // Same data, same bytes
+    assert_eq!(workbook.to_bytes(), bytes);
+}
+
+#[test]
+fn test_xlsx_column_names() {
+    assert_eq!(column_name(0), "A");
+    assert_eq!(column_name(25), "Z");
+    assert_eq!(column_name(26), "AA");
+    assert_eq!(column_name(701), "ZZ");
+    assert_eq!(column_name(702), "AAA");
+}
+
+#[test]
+fn test_month_csv_file_name() {
+    assert_eq!(
+        month_csv_file_name(&month(1, 2024, 3)),
+        "budget-2024-03.csv"
+    );
+}
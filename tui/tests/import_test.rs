@@ -0,0 +1,392 @@
+//! Import tests for the Budget TUI application
+
+use budget_tui::api::{ApiError, MockApi, MockData};
+use budget_tui::import::{
+    parse_csv, parse_csv_lines, parse_month, synthetic_history, HistoryImport, SeedOptions,
+};
+use budget_tui::models::{Category, IncomeType, Month, Period};
+use budget_tui::state::EntityType;
+use rand::{rngs::StdRng, SeedableRng};
+
+const HISTORY: &str = "\
+Month,Type,Name,Category,Period,Projected,Actual
+2019-02,expense,Rent,Housing,Monthly,1000,1000
+2019-01,expense,Groceries,Food,Monthly,400,\"$1,210.50\"
+2019-01,income,Salary,,Monthly,3000,3000
+2019-02,expense,Groceries,food,monthly,,380
+";
+
+fn named<T>(make: impl Fn(i32, String, String) -> T, names: &[&str]) -> Vec<T> {
+    names
+        .iter()
+        .enumerate()
+        .map(|(i, name)| make(i as i32 + 1, name.to_string(), "#ffffff".to_string()))
+        .collect()
+}
+
+fn categories() -> Vec<Category> {
+    named(
+        |id, name, color| Category { id, name, color },
+        &["Housing", "Food"],
+    )
+}
+
+fn periods() -> Vec<Period> {
+    named(|id, name, color| Period { id, name, color }, &["Monthly"])
+}
+
+fn income_types() -> Vec<IncomeType> {
+    named(
+        |id, name, color| IncomeType { id, name, color },
+        &["Salary"],
+    )
+}
+
+fn month(id: i32, year: i32, month: i32) -> Month {
+    Month {
+        id,
+        year,
+        month,
+        name: format!("{}-{:02}", year, month),
+        start_date: format!("{}-{:02}-01", year, month),
+        end_date: format!("{}-{:02}-28", year, month),
+        is_closed: false,
+        closed_at: None,
+        closed_by: None,
+        notes: None,
+    }
+}
+
+#[test]
+fn test_parse_csv_quotes_and_blank_lines() {
+    let records = parse_csv("a,\"b,c\",\"say \"\"hi\"\"\"\r\n\r\n1,2,3");
+
+    assert_eq!(
+        records,
+        vec![
+            vec!["a".to_string(), "b,c".to_string(), "say \"hi\"".to_string()],
+            vec!["1".to_string(), "2".to_string(), "3".to_string()],
+        ]
+    );
+}
+
+#[test]
+fn test_parse_csv_lines() {
+    // Records keep the line they start on past blank lines and quoted breaks
+    let records = parse_csv_lines("\u{feff}a,b\n\n\"one\ntwo\",c\r\nd,e");
+
+    assert_eq!(records[0], (1, vec!["a".to_string(), "b".to_string()]));
+    assert_eq!(records[1].0, 3);
+    assert_eq!(records[1].1[0], "one\ntwo");
+    assert_eq!(records[2], (5, vec!["d".to_string(), "e".to_string()]));
+}
+
+#[test]
+fn test_parse_month_formats() {
+    assert_eq!(parse_month("2019-03"), Some((2019, 3)));
+    assert_eq!(parse_month("2019/3"), Some((2019, 3)));
+    assert_eq!(parse_month("2019-03-15"), Some((2019, 3)));
+    assert_eq!(parse_month("03/2019"), Some((2019, 3)));
+    assert_eq!(parse_month("2019-13"), None);
+    assert_eq!(parse_month("March"), None);
+}
+
+#[test]
+fn test_history_import_parse() {
+    let import = HistoryImport::parse(HISTORY).unwrap();
+
+    assert_eq!(import.months(), vec![(2019, 1), (2019, 2)]);
+    // Oldest month first, file order kept within a month
+    let names: Vec<&str> = import.rows.iter().map(|r| r.name.as_str()).collect();
+    assert_eq!(names, vec!["Groceries", "Salary", "Rent", "Groceries"]);
+
+    let groceries = &import.rows[0];
+    assert_eq!(groceries.kind, EntityType::Expense);
+    assert_eq!(groceries.actual, 1210.5);
+    assert_eq!(groceries.line, 3);
+    assert_eq!(import.rows[1].kind, EntityType::Income);
+    // A missing projection is taken from the actual amount
+    assert_eq!(import.rows[3].projected, 380.0);
+}
+
+#[test]
+fn test_history_import_parse_errors() {
+    let err = HistoryImport::parse("Name,Period,Actual\nRent,Monthly,10").unwrap_err();
+    assert!(err.to_string().contains("'month'"));
+
+    let err = HistoryImport::parse("Month,Name,Period\n2019-01,Rent,Monthly").unwrap_err();
+    assert!(err.to_string().contains("'projected' or 'actual'"));
+
+    let err = HistoryImport::parse(
+        "Month,Name,Category,Period,Actual\n2019-01,Rent,Housing,Monthly,10\nJan,Rent,Housing,Monthly,10",
+    )
+    .unwrap_err();
+    assert!(err.to_string().starts_with("line 3:"));
+
+    let err =
+        HistoryImport::parse("Month,Name,Period,Actual\n2019-01,Rent,Monthly,10").unwrap_err();
+    assert!(err.to_string().contains("category"));
+
+    let err =
+        HistoryImport::parse("Month,Name,Category,Period,Actual\n2019-01,Rent,Housing,Monthly,ten")
+            .unwrap_err();
+    assert!(err.to_string().contains("'ten' is not an amount"));
+
+    // Lines of the file, not records
+    let err = HistoryImport::parse(
+        "Month,Name,Category,Period,Actual\n\n2019-01,\"Rent\nflat\",Housing,Monthly,10\nJan,Rent,Housing,Monthly,10",
+    )
+    .unwrap_err();
+    assert!(err.to_string().starts_with("line 5:"));
+}
+
+#[test]
+fn test_history_import_parse_bom() {
+    // As spreadsheets save "CSV UTF-8"
+    let text = format!("\u{feff}{}", HISTORY.replace('\n', "\r\n"));
+    let import = HistoryImport::parse(&text).unwrap();
+
+    assert_eq!(import.months(), vec![(2019, 1), (2019, 2)]);
+    assert_eq!(import.rows[0].line, 3);
+}
+
+#[test]
+fn test_history_import_plan() {
+    let import = HistoryImport::parse(HISTORY).unwrap();
+
+    let plan = import.plan(
+        &[month(1, 2019, 1)],
+        &categories(),
+        &periods(),
+        &income_types(),
+    );
+    assert_eq!(plan.existing_months, vec![(2019, 1)]);
+    assert_eq!(plan.new_months, vec![(2019, 2)]);
+    // Rows in existing months are not counted
+    assert_eq!((plan.expenses, plan.incomes), (2, 0));
+    assert!(plan.is_ready());
+
+    let plan = import.plan(&[], &categories()[..1], &periods(), &[]);
+    assert_eq!(
+        plan.missing,
+        vec!["category 'Food'", "income type 'Salary'"]
+    );
+    assert!(!plan.is_ready());
+}
+
+#[tokio::test]
+async fn test_history_import_run() {
+    let api = MockApi::new(MockData::default());
+    let import = HistoryImport::parse(HISTORY).unwrap();
+    let plan = import.plan(&[], &categories(), &periods(), &income_types());
+
+    let mut progress = Vec::new();
+    let summary = import
+        .run(
+            &api,
+            &plan,
+            &categories(),
+            &periods(),
+            &income_types(),
+            |p| progress.push((p.index, p.total, p.expenses, p.incomes)),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(
+        (summary.months, summary.expenses, summary.incomes),
+        (2, 3, 1)
+    );
+    assert_eq!(progress, vec![(1, 2, 1, 1), (2, 2, 2, 0)]);
+
+    let data = api.data();
+    let months: Vec<(i32, i32)> = data.months.iter().map(|m| (m.year, m.month)).collect();
+    assert_eq!(months, vec![(2019, 1), (2019, 2)]);
+    let february = data.months[1].id;
+    let groceries = data
+        .expenses
+        .iter()
+        .find(|e| e.month_id == february && e.expense_name == "Groceries")
+        .unwrap();
+    // Names are stored with the server's spelling
+    assert_eq!(groceries.category, "Food");
+    assert_eq!(groceries.period, "Monthly");
+    assert_eq!(data.incomes[0].income_type_id, 1);
+}
+
+#[tokio::test]
+async fn test_history_import_fill_month() {
+    let january = month(7, 2019, 1);
+    let api = MockApi::new(MockData {
+        months: vec![january.clone()],
+        ..Default::default()
+    });
+    let import = HistoryImport::parse(HISTORY).unwrap();
+
+    let summary = import
+        .fill_month(&api, &january, &categories(), &periods(), &income_types())
+        .await
+        .unwrap();
+    assert_eq!((summary.expenses, summary.incomes), (1, 1));
+    let data = api.data();
+    // Only January's rows, added to the existing month
+    assert_eq!(data.months.len(), 1);
+    assert!(data.expenses.iter().all(|e| e.month_id == 7));
+    assert_eq!(data.incomes[0].month_id, 7);
+    drop(data);
+
+    let march = month(8, 2019, 3);
+    let error = import
+        .fill_month(&api, &march, &categories(), &periods(), &income_types())
+        .await
+        .unwrap_err();
+    assert!(error.to_string().contains("no rows"), "{}", error);
+
+    let error = import
+        .fill_month(
+            &api,
+            &january,
+            &categories()[..1],
+            &periods(),
+            &income_types(),
+        )
+        .await
+        .unwrap_err();
+    assert!(error.to_string().contains("category 'Food'"), "{}", error);
+}
+
+#[tokio::test]
+async fn test_batch_import_jsonl() {
+    let lines = r#"{"name": "Groceries", "category": "food", "period": "Monthly", "cost": 84.2}
+
+{"type": "income", "income_type": "Salary", "period": "Monthly", "amount": 3000, "month": "2019-01"}
+{"name": "Rent", "category": "Housing", "period": "Monthly", "projected": 1000, "month": "2019-02"}
+"#;
+    let import = HistoryImport::parse_jsonl(lines, (2019, 2)).unwrap();
+    assert_eq!(import.months(), vec![(2019, 1), (2019, 2)]);
+    let groceries = &import.rows[1];
+    assert_eq!((groceries.line, groceries.projected), (1, 84.2));
+    assert_eq!(import.rows[0].kind, EntityType::Income);
+
+    let error = HistoryImport::parse_jsonl("{\"name\": \"Rent\"}\nnot json", (2019, 2))
+        .unwrap_err()
+        .to_string();
+    assert!(
+        error.contains("line 1: expenses need a category"),
+        "{}",
+        error
+    );
+    let error = HistoryImport::parse_jsonl("\n{oops", (2019, 2)).unwrap_err();
+    assert!(error.to_string().starts_with("line 2:"), "{}", error);
+
+    // Rows go into months that exist, next to what they have
+    let mut january = month(7, 2019, 1);
+    let api = MockApi::new(MockData {
+        months: vec![january.clone()],
+        ..Default::default()
+    });
+    let error = import
+        .existing_months(
+            &[january.clone()],
+            &categories(),
+            &periods(),
+            &income_types(),
+        )
+        .unwrap_err();
+    assert!(error.to_string().contains("no 2019-02"), "{}", error);
+
+    let february = month(8, 2019, 2);
+    let months = [january.clone(), february];
+    let targets = import
+        .existing_months(&months, &categories(), &periods(), &income_types())
+        .unwrap();
+    let summary = import
+        .add_to_months(
+            &api,
+            &targets,
+            &categories(),
+            &periods(),
+            &income_types(),
+            |_| {},
+        )
+        .await
+        .unwrap();
+    assert_eq!(
+        (summary.months, summary.expenses, summary.incomes),
+        (2, 2, 1)
+    );
+    assert_eq!(api.data().months.len(), 1);
+
+    january.is_closed = true;
+    let error = import
+        .existing_months(&[january], &categories(), &periods(), &income_types())
+        .unwrap_err();
+    assert!(error.to_string().contains("closed"), "{}", error);
+}
+
+#[tokio::test]
+async fn test_history_import_run_stops_at_failure() {
+    let api = MockApi::new(MockData::default());
+    let import = HistoryImport::parse(HISTORY).unwrap();
+    let plan = import.plan(&[], &categories(), &periods(), &income_types());
+
+    // Nothing is written past the failed month
+    api.fail_next(ApiError::Server("boom".to_string()));
+    let result = import
+        .run(
+            &api,
+            &plan,
+            &categories(),
+            &periods(),
+            &income_types(),
+            |_| {},
+        )
+        .await;
+
+    let err = result.unwrap_err();
+    assert!(err.to_string().contains("Failed to create 2019-01"));
+    assert!(api.data().months.is_empty());
+}
+
+#[test]
+fn test_synthetic_history() {
+    let mut rng = StdRng::seed_from_u64(7);
+    let options = SeedOptions {
+        months: 14,
+        expenses: 25,
+    };
+    let import = synthetic_history(
+        &mut rng,
+        options,
+        (2020, 1),
+        &categories(),
+        &periods(),
+        &income_types(),
+    )
+    .unwrap();
+
+    let months = import.months();
+    assert_eq!(months.len(), 14);
+    assert_eq!(months[0], (2018, 12));
+    assert_eq!(months[13], (2020, 1));
+
+    let plan = import.plan(&[], &categories(), &periods(), &income_types());
+    assert!(plan.is_ready());
+    assert_eq!((plan.expenses, plan.incomes), (14 * 25, 14));
+
+    // Names repeat with a number once the list runs out, and keep their projection
+    let first: Vec<&str> = import.rows[..25].iter().map(|r| r.name.as_str()).collect();
+    assert_eq!(first[0], "Groceries");
+    assert_eq!(first[20], "Groceries 2");
+    let projected = |month: (i32, i32)| {
+        import
+            .rows
+            .iter()
+            .find(|r| (r.year, r.month) == month && r.name == "Rent")
+            .unwrap()
+            .projected
+    };
+    assert_eq!(projected((2018, 12)), projected((2020, 1)));
+
+    assert!(synthetic_history(&mut rng, options, (2020, 1), &[], &periods(), &[]).is_err());
+}
@@ -0,0 +1,50 @@
+//! Low-color rendering tests for the Budget TUI application
+
+use budget_tui::ui::low_color::{is_limited_terminal, simplify, to_ansi16};
+use ratatui::buffer::Buffer;
+use ratatui::style::Color;
+
+#[test]
+fn test_is_limited_terminal() {
+    assert!(is_limited_terminal(Some("xterm"), None));
+    assert!(is_limited_terminal(Some("screen"), None));
+    assert!(is_limited_terminal(Some("linux"), None));
+    assert!(!is_limited_terminal(Some("xterm-256color"), None));
+    assert!(!is_limited_terminal(Some("tmux-256color"), None));
+    assert!(!is_limited_terminal(Some("xterm"), Some("truecolor")));
+    assert!(!is_limited_terminal(None, None));
+}
+
+#[test]
+fn test_to_ansi16_rgb() {
+    // Dark modal backgrounds become plain black
+    assert_eq!(to_ansi16(Color::Rgb(30, 30, 35)), Color::Black);
+    // Typical category colors keep their hue
+    assert_eq!(to_ansi16(Color::Rgb(0x22, 0xc5, 0x5e)), Color::Green);
+    assert_eq!(to_ansi16(Color::Rgb(0xef, 0x44, 0x44)), Color::LightRed);
+    assert_eq!(to_ansi16(Color::Rgb(255, 255, 255)), Color::White);
+}
+
+#[test]
+fn test_to_ansi16_keeps_named_colors() {
+    assert_eq!(to_ansi16(Color::Cyan), Color::Cyan);
+    assert_eq!(to_ansi16(Color::DarkGray), Color::DarkGray);
+    assert_eq!(to_ansi16(Color::Reset), Color::Reset);
+}
+
+#[test]
+fn test_to_ansi16_indexed() {
+    assert_eq!(to_ansi16(Color::Indexed(1)), Color::Red);
+    assert_eq!(to_ansi16(Color::Indexed(16)), Color::Black);
+    assert_eq!(to_ansi16(Color::Indexed(231)), Color::White);
+    assert_eq!(to_ansi16(Color::Indexed(244)), Color::DarkGray);
+}
+
+#[test]
+fn test_simplify_borders() {
+    let mut buffer = Buffer::with_lines(["┌──┐", "│▶x│", "╰──╯"]);
+    simplify(&mut buffer);
+
+    let text: String = buffer.content.iter().map(|cell| cell.symbol()).collect();
+    assert_eq!(text, "+--+|>x|+--+");
+}
@@ -1,10 +1,14 @@
 //! Model tests for the Budget TUI application
 
 use budget_tui::models::{
-    Category, CategoryCreate, CategoryUpdate, Expense, ExpenseCreate, ExpenseFilters,
+    Account, AccountCreate, AccountKind, Attachment, BudgetStatus, BudgetThresholds, Category,
+    CategoryCreate, CategoryUpdate, CustomFields, Expense, ExpenseCreate, ExpenseFilters,
     ExpenseUpdate, Income, IncomeCreate, IncomeFilters, IncomeType, IncomeTypeCreate,
-    IncomeTypeUpdate, IncomeUpdate, Month, Period, PeriodCreate, PeriodUpdate, Purchase,
+    IncomeTypeUpdate, IncomeUpdate, KeyScopes, Month, MonthShareRequest, MonthStatus, MonthUpdate,
+    Page, PageInfo, PageRequest, Period, PeriodCreate, PeriodUpdate, Purchase, Scope, ShareLink,
+    Transfer, TransferCreate,
 };
+use budget_tui::validate::{self, Validate, MAX_MONTH_NOTES_LEN, MAX_NAME_LEN};
 
 #[test]
 fn test_expense_serialization() {
@@ -24,6 +28,14 @@ fn test_expense_serialization() {
         }]),
         order: 0,
         expense_date: None,
+        created_at: None,
+        updated_at: None,
+        created_by: None,
+        updated_by: None,
+        attachments: None,
+        account_id: None,
+        currency: None,
+        custom_fields: CustomFields::new(),
     };
 
     let json = serde_json::to_string(&expense).unwrap();
@@ -48,6 +60,9 @@ fn test_expense_create_serialization() {
         month_id: 1,
         purchases: None,
         expense_date: None,
+        account_id: None,
+        currency: None,
+        custom_fields: CustomFields::new(),
     };
 
     let json = serde_json::to_string(&create).unwrap();
@@ -77,6 +92,7 @@ fn test_expense_filters_to_query_params() {
         period: Some("Monthly".to_string()),
         category: Some("Food".to_string()),
         month_id: Some(1),
+        ..Default::default()
     };
 
     let params = filters.to_query_params();
@@ -93,6 +109,119 @@ fn test_expense_filters_empty() {
     assert!(params.is_empty());
 }
 
+#[test]
+fn test_filters_page_query_params() {
+    let filters = ExpenseFilters {
+        month_id: Some(1),
+        page: Some(PageRequest {
+            offset: 200,
+            limit: 100,
+        }),
+        ..Default::default()
+    };
+
+    let params = filters.to_query_params();
+    assert!(params.contains(&("limit", "100".to_string())));
+    assert!(params.contains(&("offset", "200".to_string())));
+}
+
+#[test]
+fn test_page_next() {
+    let request = PageRequest::first(2);
+
+    let full = Page::from_items(vec![1, 2], request);
+    assert!(full.has_more);
+    assert_eq!(
+        full.next(),
+        Some(PageRequest {
+            offset: 2,
+            limit: 2
+        })
+    );
+
+    let last = Page::from_items(
+        vec![3],
+        PageRequest {
+            offset: 2,
+            limit: 2,
+        },
+    );
+    assert_eq!(last.next(), None);
+
+    // A server without paging returns everything
+    let all = Page::from_items(vec![1, 2, 3], request);
+    assert!(!all.has_more);
+}
+
+#[test]
+fn test_page_info() {
+    let info = PageInfo::parse(
+        Some("7"),
+        Some(
+            r#"<https://budget.example/api/v1/expenses?limit=2&offset=4>; rel="next", <https://budget.example/api/v1/expenses?limit=2&offset=6>; rel="last""#,
+        ),
+    );
+    assert_eq!(info.total, Some(7));
+    assert_eq!(info.has_next, Some(true));
+    assert_eq!(
+        info.next,
+        Some(PageRequest {
+            offset: 4,
+            limit: 2
+        })
+    );
+
+    // The last page links back but not forward
+    let last = PageInfo::parse(
+        None,
+        Some(r#"<https://x/expenses?limit=2&offset=0>; rel="first""#),
+    );
+    assert_eq!(last.has_next, Some(false));
+    assert_eq!(PageInfo::parse(Some("lots"), None), PageInfo::default());
+
+    // The total decides when there's no Link, even for a full last page
+    let request = PageRequest {
+        offset: 4,
+        limit: 3,
+    };
+    let info = PageInfo {
+        total: Some(7),
+        ..Default::default()
+    };
+    let page = Page::with_info(vec![5, 6, 7], request, info.clone());
+    assert!(!page.has_more);
+    assert_eq!(page.total, Some(7));
+    let first = Page::with_info(vec![1, 2, 3], PageRequest::first(3), info);
+    assert_eq!(
+        first.next(),
+        Some(PageRequest {
+            offset: 3,
+            limit: 3
+        })
+    );
+
+    // A Link's next page is followed as given
+    let linked = Page::with_info(
+        vec![1, 2],
+        PageRequest::first(2),
+        PageInfo {
+            has_next: Some(true),
+            next: Some(PageRequest {
+                offset: 10,
+                limit: 5,
+            }),
+            ..Default::default()
+        },
+    );
+    assert_eq!(
+        linked.next(),
+        Some(PageRequest {
+            offset: 10,
+            limit: 5
+        })
+    );
+}
+
 #[test]
 fn test_income_serialization() {
     let income = Income {
@@ -106,6 +235,8 @@ fn test_income_serialization() {
         updated_at: "2024-01-01".to_string(),
         created_by: Some("user".to_string()),
         updated_by: None,
+        account_id: None,
+        currency: None,
     };
 
     let json = serde_json::to_string(&income).unwrap();
@@ -122,6 +253,7 @@ fn test_income_filters_to_query_params() {
         period: Some("Monthly".to_string()),
         income_type_id: Some(2),
         month_id: Some(3),
+        ..Default::default()
     };
 
     let params = filters.to_query_params();
@@ -208,6 +340,7 @@ fn test_month_serialization() {
         is_closed: false,
         closed_at: None,
         closed_by: None,
+        notes: None,
     };
 
     let json = serde_json::to_string(&month).unwrap();
@@ -219,6 +352,110 @@ fn test_month_serialization() {
     assert_eq!(month.month, deserialized.month);
 }
 
+fn month_of(year: i32, month: i32, start_date: &str, end_date: &str) -> Month {
+    Month {
+        id: 1,
+        year,
+        month,
+        name: String::new(),
+        start_date: start_date.to_string(),
+        end_date: end_date.to_string(),
+        is_closed: false,
+        closed_at: None,
+        closed_by: None,
+        notes: None,
+    }
+}
+
+#[test]
+fn test_month_pace() {
+    let month = month_of(2024, 4, "2024-04-01", "2024-04-30");
+    let day = |d| chrono::NaiveDate::from_ymd_opt(2024, 4, d).unwrap();
+
+    assert_eq!(month.pace(day(15)), 0.5);
+    assert_eq!(month.pace(day(30)), 1.0);
+    assert_eq!(
+        month.pace(chrono::NaiveDate::from_ymd_opt(2024, 3, 31).unwrap()),
+        0.0
+    );
+    assert_eq!(
+        month.pace(chrono::NaiveDate::from_ymd_opt(2024, 5, 2).unwrap()),
+        1.0
+    );
+}
+
+#[test]
+fn test_month_pace_falls_back_to_calendar() {
+    // Datetimes are cut to the date; unparseable dates use the calendar month
+    let month = month_of(2024, 2, "2024-02-01T00:00:00", "");
+    let today = chrono::NaiveDate::from_ymd_opt(2024, 2, 29).unwrap();
+
+    assert_eq!(month.pace(today), 1.0);
+    let pace = month.pace(chrono::NaiveDate::from_ymd_opt(2024, 2, 1).unwrap());
+    assert!((pace - 1.0 / 29.0).abs() < 1e-9);
+}
+
+#[test]
+fn test_month_status_and_notes() {
+    let mut month = month_of(2024, 4, "2024-04-01", "2024-04-30");
+    assert_eq!(month.status(), MonthStatus::Open);
+    assert_eq!(month.shared_notes(), None);
+    month.is_closed = true;
+    month.notes = Some(" ".to_string());
+    assert_eq!(month.status().as_str(), "Closed");
+    assert_eq!(month.shared_notes(), None);
+
+    // Older servers send no notes
+    let json = r#"{"id": 1, "year": 2024, "month": 4, "name": "April 2024",
+        "start_date": "2024-04-01", "end_date": "2024-04-30",
+        "is_closed": true, "closed_at": null, "closed_by": "ana"}"#;
+    let month: Month = serde_json::from_str(json).unwrap();
+    assert_eq!(month.notes, None);
+    let json = json.replace("\"ana\"", "\"ana\", \"notes\": \"Car repair\"");
+    let month: Month = serde_json::from_str(&json).unwrap();
+    assert_eq!(month.shared_notes(), Some("Car repair"));
+
+    // Only the notes are sent to change them
+    let update = MonthUpdate {
+        notes: Some("Car repair".to_string()),
+        ..Default::default()
+    };
+    assert_eq!(
+        serde_json::to_value(&update).unwrap(),
+        serde_json::json!({"notes": "Car repair"})
+    );
+    assert!(update.validate().is_empty());
+    let update = MonthUpdate {
+        notes: Some("x".repeat(MAX_MONTH_NOTES_LEN + 1)),
+        ..Default::default()
+    };
+    assert_eq!(update.validate().len(), 1);
+}
+
+#[test]
+fn test_budget_status_with_pace() {
+    let thresholds = BudgetThresholds::default();
+
+    // 60% spent is fine on day 25 but ahead of pace on day 5
+    assert_eq!(
+        thresholds.status_with_pace(60.0, 100.0, 25.0 / 30.0),
+        BudgetStatus::OnTrack
+    );
+    assert_eq!(
+        thresholds.status_with_pace(60.0, 100.0, 5.0 / 30.0),
+        BudgetStatus::Ahead
+    );
+    // Near and over are not downgraded
+    assert_eq!(
+        thresholds.status_with_pace(95.0, 100.0, 0.1),
+        BudgetStatus::Near
+    );
+    assert_eq!(
+        thresholds.status_with_pace(0.0, 0.0, 0.0),
+        BudgetStatus::OnTrack
+    );
+}
+
 #[test]
 fn test_purchase_serialization() {
     let purchase = Purchase {
@@ -233,6 +470,58 @@ fn test_purchase_serialization() {
     assert_eq!(purchase, deserialized);
 }
 
+#[test]
+fn test_expense_attachments() {
+    let json = r#"{"id":1,"expense_name":"Groceries","period":"Monthly","category":"Food",
+        "projected":100.0,"cost":80.0,"notes":null,"month_id":1,"purchases":null,"order":0,
+        "expense_date":null,"attachments":[{"id":3,"file_name":"receipt.jpg",
+        "content_type":"image/jpeg","size":2048,"uploaded_at":"2024-03-02T10:00:00Z"}]}"#;
+    let expense: Expense = serde_json::from_str(json).unwrap();
+    assert_eq!(expense.attachment_count(), 1);
+    assert_eq!(expense.attachments.unwrap()[0].file_name, "receipt.jpg");
+
+    assert_eq!(
+        Attachment::content_type_for("Receipt.PDF"),
+        "application/pdf"
+    );
+    assert_eq!(Attachment::content_type_for("scan.jpeg"), "image/jpeg");
+    assert_eq!(
+        Attachment::content_type_for("notes"),
+        "application/octet-stream"
+    );
+
+    // Only known documents and images are opened, never a program
+    assert!(Attachment::is_viewable("receipt.pdf"));
+    assert!(Attachment::is_viewable("Scan.PNG"));
+    assert!(!Attachment::is_viewable("receipt.exe"));
+    assert!(!Attachment::is_viewable("receipt.pdf.bat"));
+    assert!(!Attachment::is_viewable("receipt"));
+}
+
+#[test]
+fn test_attachment_local_file_name() {
+    let attachment = |id, file_name: &str| Attachment {
+        id,
+        file_name: file_name.to_string(),
+        content_type: None,
+        size: None,
+        uploaded_at: None,
+    };
+
+    assert_eq!(
+        attachment(7, "Receipt March.pdf").local_file_name(),
+        "7-Receipt March.pdf"
+    );
+    // Only the last part of a path
+    assert_eq!(attachment(7, "../../.bashrc").local_file_name(), "7-bashrc");
+    // Nothing cmd or a shell would act on
+    assert_eq!(
+        attachment(8, "a&calc%PATH%^|\"x\".pdf").local_file_name(),
+        "8-a_calc_PATH____x_.pdf"
+    );
+    assert_eq!(attachment(9, "").local_file_name(), "9-attachment");
+}
+
 #[test]
 fn test_income_create_serialization() {
     let create = IncomeCreate {
@@ -241,6 +530,8 @@ fn test_income_create_serialization() {
         projected: 5000.0,
         amount: 4800.0,
         month_id: 1,
+        account_id: None,
+        currency: None,
     };
 
     let json = serde_json::to_string(&create).unwrap();
@@ -314,3 +605,262 @@ fn test_category_update() {
     assert!(json.contains("\"name\":\"Groceries\""));
     assert!(json.contains("\"color\":\"#ABCDEF\""));
 }
+
+#[test]
+fn test_budget_thresholds_status() {
+    let thresholds = BudgetThresholds::default();
+
+    assert_eq!(thresholds.status(50.0, 100.0), BudgetStatus::OnTrack);
+    assert_eq!(thresholds.status(90.0, 100.0), BudgetStatus::Near);
+    assert_eq!(thresholds.status(100.0, 100.0), BudgetStatus::Near);
+    assert_eq!(thresholds.status(100.01, 100.0), BudgetStatus::Over);
+
+    let loose = BudgetThresholds {
+        near_percent: 100.0,
+        over_percent: 120.0,
+    };
+    assert_eq!(loose.status(95.0, 100.0), BudgetStatus::OnTrack);
+    assert_eq!(loose.status(110.0, 100.0), BudgetStatus::Near);
+    assert_eq!(loose.status(121.0, 100.0), BudgetStatus::Over);
+}
+
+#[test]
+fn test_budget_thresholds_without_projection() {
+    let thresholds = BudgetThresholds::default();
+
+    assert_eq!(thresholds.status(0.0, 0.0), BudgetStatus::OnTrack);
+    assert_eq!(thresholds.status(5.0, 0.0), BudgetStatus::Over);
+}
+
+#[test]
+fn test_share_link() {
+    let json = r#"{
+        "token": "abc.def.ghi",
+        "path": "/share/abc.def.ghi",
+        "url": "http://10.0.0.5:8000/share/abc.def.ghi",
+        "month_id": 7,
+        "expires_at": "2024-03-21T10:00:00.000Z"
+    }"#;
+    let link: ShareLink = serde_json::from_str(json).unwrap();
+
+    assert_eq!(link.month_id, 7);
+    // The address the client uses wins over what the server saw
+    assert_eq!(
+        link.url_for("https://budget.example.com/"),
+        "https://budget.example.com/share/abc.def.ghi"
+    );
+
+    // The server picks the expiry unless asked otherwise
+    let request = serde_json::to_value(MonthShareRequest::default()).unwrap();
+    assert_eq!(request, serde_json::json!({}));
+    let request = MonthShareRequest {
+        expires_in_hours: Some(24),
+    };
+    assert_eq!(
+        serde_json::to_value(request).unwrap(),
+        serde_json::json!({ "expires_in_hours": 24 })
+    );
+}
+
+#[test]
+fn test_key_scopes() {
+    let scopes: KeyScopes =
+        serde_json::from_str(r#"{"scopes": ["read", "expenses:write"]}"#).unwrap();
+    assert!(scopes.allows(Scope::Expenses));
+    assert!(!scopes.allows(Scope::Incomes));
+    assert!(!scopes.allows(Scope::Months));
+    assert!(!scopes.is_read_only());
+
+    let read_only = KeyScopes {
+        scopes: vec!["read".to_string()],
+    };
+    assert!(read_only.is_read_only());
+
+    for all in ["write", "*"] {
+        let scopes = KeyScopes {
+            scopes: vec![all.to_string()],
+        };
+        assert!(Scope::ALL.iter().all(|scope| scopes.allows(*scope)));
+    }
+}
+
+#[test]
+fn test_accounts() {
+    let account: Account =
+        serde_json::from_str(r#"{"id": 2, "name": "Visa", "kind": "credit_card"}"#).unwrap();
+    assert_eq!(account.kind, AccountKind::CreditCard);
+    assert_eq!(account.kind.as_str(), "Credit card");
+    assert_eq!(AccountKind::Cash.next(), AccountKind::Checking);
+
+    let create = AccountCreate {
+        name: "Wallet".to_string(),
+        kind: AccountKind::Cash,
+    };
+    let json = serde_json::to_value(&create).unwrap();
+    assert_eq!(json["kind"], "cash");
+
+    // Older servers send entries without an account or currency
+    let income: Income = serde_json::from_str(
+        r#"{"id": 1, "income_type_id": 1, "period": "Monthly", "projected": 10.0,
+            "amount": 10.0, "month_id": 1, "created_at": "", "updated_at": "",
+            "created_by": null, "updated_by": null}"#,
+    )
+    .unwrap();
+    assert_eq!(income.account_id, None);
+    assert_eq!(income.currency, None);
+
+    let moved = IncomeUpdate {
+        account_id: Some(None),
+        ..Default::default()
+    };
+    assert!(!moved.is_empty());
+    assert_eq!(
+        serde_json::to_string(&moved).unwrap(),
+        r#"{"account_id":null}"#
+    );
+    assert_eq!(
+        serde_json::to_string(&IncomeUpdate::default()).unwrap(),
+        "{}"
+    );
+}
+
+#[test]
+fn test_transfers() {
+    let transfer: Transfer = serde_json::from_str(
+        r#"{"id": 3, "from_account_id": 1, "to_account_id": 2, "amount": 250.0,
+            "month_id": 1, "transfer_date": null, "notes": "Savings"}"#,
+    )
+    .unwrap();
+    assert_eq!(transfer.net_for(1), -250.0);
+    assert_eq!(transfer.net_for(2), 250.0);
+    assert_eq!(transfer.net_for(9), 0.0);
+
+    let create = TransferCreate {
+        from_account_id: 1,
+        to_account_id: 2,
+        amount: 250.0,
+        month_id: 1,
+        transfer_date: None,
+        notes: None,
+    };
+    let json = serde_json::to_value(&create).unwrap();
+    assert!(json.get("notes").is_none());
+    assert!(json.get("transfer_date").is_none());
+}
+
+#[test]
+fn test_validate_payloads() {
+    let fields = |errors: Vec<budget_tui::api::FieldError>| {
+        errors
+            .into_iter()
+            .map(|e| e.field.unwrap_or_default())
+            .collect::<Vec<_>>()
+    };
+
+    let mut expense = ExpenseCreate {
+        expense_name: "Groceries".to_string(),
+        period: "Monthly".to_string(),
+        category: "Food".to_string(),
+        projected: 500.0,
+        cost: 0.0,
+        notes: None,
+        month_id: 1,
+        purchases: None,
+        expense_date: None,
+        account_id: None,
+        currency: Some("EUR".to_string()),
+        custom_fields: CustomFields::new(),
+    };
+    assert!(expense.check().is_ok());
+
+    expense.expense_name = " ".to_string();
+    expense.projected = -1.0;
+    expense.purchases = Some(vec![Purchase {
+        name: String::new(),
+        amount: 5.0,
+        date: None,
+    }]);
+    expense.currency = Some("euro".to_string());
+    assert_eq!(
+        fields(expense.validate()),
+        vec!["expense_name", "projected", "purchases", "currency"]
+    );
+    let error = expense.check().unwrap_err();
+    assert_eq!(error.field_errors()[0].message, "Name is required");
+
+    // Updates are checked only on what they change
+    assert!(ExpenseUpdate::default().validate().is_empty());
+    let update = ExpenseUpdate {
+        expense_name: Some("x".repeat(MAX_NAME_LEN + 1)),
+        ..Default::default()
+    };
+    assert_eq!(fields(update.validate()), vec!["expense_name"]);
+
+    let income = IncomeCreate {
+        income_type_id: 1,
+        period: String::new(),
+        projected: 100.0,
+        amount: -5.0,
+        month_id: 1,
+        account_id: None,
+        currency: None,
+    };
+    assert_eq!(fields(income.validate()), vec!["period", "amount"]);
+
+    assert!(validate::is_hex_color("#8b5cf6"));
+    assert!(validate::is_hex_color("#FFF"));
+    assert!(!validate::is_hex_color("8b5cf6"));
+    assert!(!validate::is_hex_color("#8b5cf"));
+    let category = CategoryUpdate {
+        name: "Food".to_string(),
+        color: Some("green".to_string()),
+    };
+    assert_eq!(fields(category.validate()), vec!["color"]);
+    let period = PeriodCreate {
+        name: "Weekly".to_string(),
+        color: None,
+    };
+    assert!(period.validate().is_empty());
+
+    let transfer = TransferCreate {
+        from_account_id: 1,
+        to_account_id: 1,
+        amount: 0.0,
+        month_id: 1,
+        transfer_date: None,
+        notes: None,
+    };
+    assert_eq!(fields(transfer.validate()), vec!["to_account_id", "amount"]);
+}
+
+#[test]
+fn test_custom_fields() {
+    // Fields the client doesn't know are kept, and sent back as they came
+    let expense: Expense = serde_json::from_str(
+        r#"{"id": 1, "expense_name": "Fuel", "period": "Monthly", "category": "Car",
+            "projected": 80.0, "cost": 0.0, "notes": null, "month_id": 1,
+            "purchases": null, "order": 0, "expense_date": null,
+            "cost_center": "ops", "mileage": 120}"#,
+    )
+    .unwrap();
+    let names: Vec<&str> = expense.custom_fields.iter().map(|(name, _)| name).collect();
+    assert_eq!(names, vec!["cost_center", "mileage"]);
+    let json = serde_json::to_value(&expense).unwrap();
+    assert_eq!(json["mileage"], 120);
+    assert!(json.get("custom_fields").is_none());
+    assert_eq!(
+        CustomFields::text(expense.custom_fields.get("cost_center").unwrap()),
+        "ops"
+    );
+
+    let mut update = ExpenseUpdate::default();
+    assert_eq!(serde_json::to_string(&update).unwrap(), "{}");
+    update
+        .custom_fields
+        .insert("mileage", serde_json::Value::Null);
+    assert!(!update.is_empty());
+    assert_eq!(
+        serde_json::to_string(&update).unwrap(),
+        r#"{"mileage":null}"#
+    );
+}
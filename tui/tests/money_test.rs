@@ -0,0 +1,143 @@
+//! Money display tests for the Budget TUI application
+
+use budget_tui::config::Config;
+use budget_tui::models::Money;
+use budget_tui::ui::dates::DateFormat;
+use budget_tui::ui::money::{MoneyFormat, NegativeStyle, NumberStyle};
+use chrono::NaiveDate;
+
+#[test]
+fn test_money_default() {
+    let money = MoneyFormat::default();
+    assert_eq!(money.format(1234.5), "$1234.50");
+    assert_eq!(money.format(0.0), "$0.00");
+    assert_eq!(money.format(-12.0), "-$12.00");
+    // Rounds to zero without a sign
+    assert_eq!(money.format(-0.001), "$0.00");
+}
+
+#[test]
+fn test_money_in_other_currency() {
+    let money = MoneyFormat::default();
+    assert_eq!(money.format_in(12.5, Some("EUR")), "12.50 EUR");
+    assert_eq!(money.format_in(-12.5, Some("EUR")), "-12.50 EUR");
+    assert_eq!(money.format_in(12.5, None), "$12.50");
+}
+
+#[test]
+fn test_money_hide_cents() {
+    let money = MoneyFormat {
+        hide_cents: true,
+        ..Default::default()
+    };
+    assert_eq!(money.format(1234.5), "$1235");
+    assert_eq!(money.format(-0.4), "$0");
+}
+
+#[test]
+fn test_money_compact() {
+    let money = MoneyFormat {
+        compact: true,
+        ..Default::default()
+    };
+    assert_eq!(money.format(999.99), "$999.99");
+    assert_eq!(money.format(1000.0), "$1k");
+    assert_eq!(money.format(1234.0), "$1.2k");
+    assert_eq!(money.format(3_400_000.0), "$3.4M");
+    assert_eq!(money.format(999_950.0), "$1M");
+    assert_eq!(money.format(2_500_000_000.0), "$2.5B");
+    assert_eq!(money.format(-1500.0), "-$1.5k");
+}
+
+#[test]
+fn test_money_parentheses() {
+    let money = MoneyFormat {
+        negatives: NegativeStyle::Parentheses,
+        ..Default::default()
+    };
+    assert_eq!(money.format(-123.45), "($123.45)");
+    assert_eq!(money.format(123.45), "$123.45");
+}
+
+#[test]
+fn test_money_numbers() {
+    let comma = MoneyFormat {
+        numbers: NumberStyle::Comma,
+        ..Default::default()
+    };
+    assert_eq!(comma.format(1234567.5), "$1,234,567.50");
+    assert_eq!(comma.format(123.0), "$123.00");
+    assert_eq!(comma.format(-1234.0), "-$1,234.00");
+
+    let period = MoneyFormat {
+        numbers: NumberStyle::Period,
+        compact: true,
+        ..Default::default()
+    };
+    assert_eq!(period.format(999.99), "$999,99");
+    assert_eq!(period.format(1234.0), "$1,2k");
+
+    let space = MoneyFormat {
+        numbers: NumberStyle::Space,
+        hide_cents: true,
+        ..Default::default()
+    };
+    assert_eq!(space.format(1234567.0), "$1 234 567");
+    assert_eq!(space.format(-0.001), "$0");
+}
+
+#[test]
+fn test_money_fixed_point() {
+    let dimes: f64 = std::iter::repeat(0.1).take(10).sum();
+    assert_ne!(dimes, 1.0);
+    let dimes: Money = std::iter::repeat(0.1).take(10).sum();
+    assert_eq!(dimes, Money::from_cents(100));
+    assert_eq!(dimes.to_f64(), 1.0);
+
+    assert_eq!(Money::parse("12"), Some(Money::from_cents(1200)));
+    assert_eq!(Money::parse(".5"), Some(Money::from_cents(50)));
+    assert_eq!(Money::parse("10.005"), Some(Money::from_cents(1001)));
+    assert_eq!(Money::parse("-3.25"), Some(Money::from_cents(-325)));
+    assert_eq!(Money::parse("."), None);
+    assert_eq!(Money::parse("1,000"), None);
+    assert_eq!(Money::parse("1e3"), None);
+
+    assert_eq!(Money::from_cents(123450).to_string(), "1234.50");
+    assert_eq!(Money::from_cents(-5).to_string(), "-0.05");
+    assert_eq!(Money::from_f64(9.999), Money::from_cents(1000));
+    assert_eq!(
+        serde_json::from_str::<Money>("19.99").unwrap(),
+        Money::from_cents(1999)
+    );
+}
+
+#[test]
+fn test_date_formats() {
+    let date = NaiveDate::from_ymd_opt(2024, 3, 5).unwrap();
+    assert_eq!(DateFormat::Iso.format(date), "2024-03-05");
+    assert_eq!(DateFormat::Dmy.format(date), "05/03/2024");
+    assert_eq!(DateFormat::Mdy.format(date), "03/05/2024");
+    assert_eq!(
+        DateFormat::Dmy.format_time(date.and_hms_opt(14, 5, 0).unwrap()),
+        "05/03/2024 14:05"
+    );
+    assert_eq!(
+        DateFormat::Mdy.format_text("2024-12-01T10:00:00"),
+        "12/01/2024"
+    );
+    assert_eq!(DateFormat::Dmy.format_text("soon"), "soon");
+}
+
+#[test]
+fn test_money_config_toml_roundtrip() {
+    let mut config = Config::default();
+    config.display.money.hide_cents = true;
+    config.display.money.negatives = NegativeStyle::Parentheses;
+    config.display.money.numbers = NumberStyle::Period;
+    config.display.dates = DateFormat::Dmy;
+
+    let parsed: Config = toml::from_str(&config.to_toml().unwrap()).unwrap();
+    assert_eq!(parsed.display.money, config.display.money);
+    assert_eq!(parsed.display.dates, DateFormat::Dmy);
+    assert!(!parsed.display.money.compact);
+}
@@ -0,0 +1,96 @@
+//! Color generation tests for the Budget TUI application
+
+use rand::{rngs::StdRng, SeedableRng};
+
+use budget_tui::config::ColorMode;
+use budget_tui::ui::palette::{
+    generate_color, hex_to_hue, hsl_to_hex, palette_color, random_color,
+};
+
+/// Circular distance between two hues in degrees
+fn hue_distance(a: f64, b: f64) -> f64 {
+    let d = (a - b).rem_euclid(360.0);
+    d.min(360.0 - d)
+}
+
+#[test]
+fn test_hsl_to_hex_primaries() {
+    assert_eq!(hsl_to_hex(0.0, 1.0, 0.5), "#ff0000");
+    assert_eq!(hsl_to_hex(120.0, 1.0, 0.5), "#00ff00");
+    assert_eq!(hsl_to_hex(240.0, 1.0, 0.5), "#0000ff");
+    assert_eq!(hsl_to_hex(360.0, 1.0, 0.5), "#ff0000");
+    assert_eq!(hsl_to_hex(0.0, 0.0, 1.0), "#ffffff");
+}
+
+#[test]
+fn test_hex_to_hue() {
+    assert_eq!(hex_to_hue("#ff0000"), Some(0.0));
+    assert_eq!(hex_to_hue("#00ff00"), Some(120.0));
+    assert_eq!(hex_to_hue("0000ff"), Some(240.0));
+    assert_eq!(hex_to_hue("#808080"), None);
+    assert_eq!(hex_to_hue("#xyz"), None);
+    assert_eq!(hex_to_hue(""), None);
+}
+
+#[test]
+fn test_hue_round_trip() {
+    for hue in [15.0, 75.0, 150.0, 210.0, 285.0, 345.0] {
+        let back = hex_to_hue(&hsl_to_hex(hue, 0.7, 0.5)).unwrap();
+        assert!(hue_distance(hue, back) < 1.5, "{} -> {}", hue, back);
+    }
+}
+
+#[test]
+fn test_random_color_varies() {
+    let mut rng = StdRng::seed_from_u64(7);
+    let colors: Vec<String> = (0..10).map(|_| random_color(&mut rng, &[])).collect();
+
+    let mut unique = colors.clone();
+    unique.sort();
+    unique.dedup();
+    assert!(unique.len() > 5, "too many repeats: {:?}", colors);
+}
+
+#[test]
+fn test_random_color_avoids_used_hues() {
+    let used: Vec<String> = (0..6)
+        .map(|i| hsl_to_hex(i as f64 * 60.0, 0.7, 0.5))
+        .collect();
+    let mut rng = StdRng::seed_from_u64(42);
+
+    for _ in 0..20 {
+        let hue = hex_to_hue(&random_color(&mut rng, &used)).unwrap();
+        let nearest = (0..6)
+            .map(|i| hue_distance(hue, i as f64 * 60.0))
+            .fold(180.0, f64::min);
+        assert!(nearest >= 19.0, "hue {} too close to a used color", hue);
+    }
+}
+
+#[test]
+fn test_palette_color_picks_largest_gap() {
+    // Reds and greens are taken, so the pick should land in the blue/purple gap
+    let used = vec![
+        "#ff0000".to_string(),
+        "#ff8000".to_string(),
+        "#00ff00".to_string(),
+        "#80ff00".to_string(),
+    ];
+    let mut rng = StdRng::seed_from_u64(1);
+
+    for _ in 0..10 {
+        let hue = hex_to_hue(&palette_color(&mut rng, &used)).unwrap();
+        assert!((180.0..=300.0).contains(&hue), "unexpected hue {}", hue);
+    }
+}
+
+#[test]
+fn test_generate_color_modes() {
+    let mut rng = StdRng::seed_from_u64(3);
+    for mode in [ColorMode::Random, ColorMode::Palette] {
+        let color = generate_color(&mut rng, mode, &[]);
+        assert_eq!(color.len(), 7);
+        assert!(color.starts_with('#'));
+        assert!(hex_to_hue(&color).is_some());
+    }
+}
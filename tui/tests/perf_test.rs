@@ -0,0 +1,298 @@
+//! Performance tests for large months
+//!
+//! The `bench_*` tests time table rendering, summary composition and amount
+//! formatting on thousands of entries and print the time per iteration.
+//! Run them in release mode:
+//!
+//! ```sh
+//! cargo test --release --test perf_test bench_ -- --ignored --nocapture
+//! ```
+//!
+//! The `*_within_budget` tests are the performance budget: the same work has
+//! to finish within a generous limit, so a change that makes it an order of
+//! magnitude slower (a lookup per row, a sort that allocates per comparison)
+//! fails CI. Wall-clock limits flake on a loaded machine, so they're ignored
+//! by a plain `cargo test` too; CI runs them on their own in release mode:
+//!
+//! ```sh
+//! cargo test --release --test perf_test within_budget -- --ignored
+//! ```
+//!
+//! `PERF_BUDGET_SCALE=2` doubles the limits on a slow machine.
+
+use std::hint::black_box;
+use std::time::{Duration, Instant};
+
+use ratatui::{backend::TestBackend, Terminal};
+use serde_json::json;
+
+use budget_tui::models::{Category, Expense, Income, IncomeType, Month, Period};
+use budget_tui::state::fallback::{
+    category_summary, income_type_summary, period_summary, summary_totals,
+};
+use budget_tui::state::{AppState, DashboardTab, GroupKey, Screen, SortKey};
+use budget_tui::ui;
+use budget_tui::ui::money::{MoneyFormat, NegativeStyle};
+
+/// Entries in the month the budget tests use, well above a real month's
+const EXPENSES: usize = 10_000;
+const INCOMES: usize = 200;
+
+const CATEGORIES: &[&str] = &[
+    "Housing",
+    "Food",
+    "Transport",
+    "Utilities",
+    "Health",
+    "Leisure",
+    "Education",
+    "Gifts",
+    "Insurance",
+    "Savings",
+];
+
+/// Run `f` a few times after a warm-up and return the average time per run
+fn measure(iterations: u32, mut f: impl FnMut()) -> Duration {
+    f();
+    let started = Instant::now();
+    for _ in 0..iterations {
+        f();
+    }
+    started.elapsed() / iterations
+}
+
+/// A limit, scaled by `PERF_BUDGET_SCALE`
+fn budget(millis: u64) -> Duration {
+    let scale: f64 = std::env::var("PERF_BUDGET_SCALE")
+        .ok()
+        .and_then(|scale| scale.parse().ok())
+        .unwrap_or(1.0);
+    Duration::from_millis(millis).mul_f64(scale)
+}
+
+fn assert_within(name: &str, took: Duration, limit: Duration) {
+    assert!(
+        took <= limit,
+        "{} took {:?}, over its budget of {:?}",
+        name,
+        took,
+        limit
+    );
+}
+
+fn expenses(count: usize) -> Vec<Expense> {
+    (0..count)
+        .map(|i| {
+            let projected = (i % 500 + 5) as f64;
+            serde_json::from_value(json!({
+                "id": i + 1,
+                "expense_name": format!("Expense {}", count - i),
+                "period": if i % 2 == 0 { "Fixed/1st Period" } else { "Variable/2nd Period" },
+                "category": CATEGORIES[i % CATEGORIES.len()],
+                "projected": projected,
+                "cost": if i % 10 == 0 { 0.0 } else { projected * 1.1 },
+                "notes": null,
+                "month_id": 1,
+                "purchases": null,
+                "order": i,
+                "expense_date": null
+            }))
+            .unwrap()
+        })
+        .collect()
+}
+
+fn incomes(count: usize) -> Vec<Income> {
+    (0..count)
+        .map(|i| {
+            serde_json::from_value(json!({
+                "id": i + 1,
+                "income_type_id": i % 3 + 1,
+                "period": if i % 2 == 0 { "Fixed/1st Period" } else { "Variable/2nd Period" },
+                "projected": 1000.0 + i as f64,
+                "amount": 1000.0,
+                "month_id": 1,
+                "created_at": "2024-12-01T09:00:00",
+                "updated_at": "2024-12-01T09:00:00",
+                "created_by": null,
+                "updated_by": null
+            }))
+            .unwrap()
+        })
+        .collect()
+}
+
+/// A logged-in state on one month with `expense_count` expenses
+fn large_state(expense_count: usize) -> AppState {
+    let mut state = AppState {
+        screen: Screen::Dashboard,
+        ..Default::default()
+    };
+
+    let month: Month = serde_json::from_value(json!({
+        "id": 1, "year": 2024, "month": 12, "name": "December 2024",
+        "start_date": "2024-12-01", "end_date": "2024-12-31",
+        "is_closed": false, "closed_at": null, "closed_by": null
+    }))
+    .unwrap();
+    state.data.current_month = Some(month.clone());
+    state.data.months = vec![month];
+
+    state.data.categories = CATEGORIES
+        .iter()
+        .enumerate()
+        .map(|(i, name)| Category {
+            id: i as i32 + 1,
+            name: name.to_string(),
+            color: format!("#{:02x}80c0", i * 20),
+        })
+        .collect();
+    state.data.periods = serde_json::from_value::<Vec<Period>>(json!([
+        {"id": 1, "name": "Fixed/1st Period", "color": "#3b82f6"},
+        {"id": 2, "name": "Variable/2nd Period", "color": "#ec4899"}
+    ]))
+    .unwrap();
+    state.data.income_types = serde_json::from_value::<Vec<IncomeType>>(json!([
+        {"id": 1, "name": "Salary", "color": "#10b981"},
+        {"id": 2, "name": "Freelance", "color": "#06b6d4"},
+        {"id": 3, "name": "Dividends", "color": "#f59e0b"}
+    ]))
+    .unwrap();
+
+    state.data.expenses = expenses(expense_count);
+    state.data.incomes = incomes(INCOMES);
+    state.data.summary_totals = Some(summary_totals(&state.data.expenses, &state.data.incomes));
+    state.data.category_summary = category_summary(&state.data.expenses);
+    state.data.income_type_summary =
+        income_type_summary(&state.data.incomes, &state.data.income_types);
+    state.data.period_summary = Some(period_summary(
+        &state.data.expenses,
+        &state.data.incomes,
+        &state.data.periods,
+    ));
+    state
+}
+
+/// Render one frame of the dashboard
+fn render(terminal: &mut Terminal<TestBackend>, state: &AppState) {
+    terminal.draw(|frame| ui::render(state, frame)).unwrap();
+    black_box(terminal.backend().buffer());
+}
+
+/// Compose every summary the Summary tab shows, as done for older servers
+fn compose_summaries(state: &AppState) {
+    let data = &state.data;
+    black_box(summary_totals(&data.expenses, &data.incomes));
+    black_box(category_summary(&data.expenses));
+    black_box(income_type_summary(&data.incomes, &data.income_types));
+    black_box(period_summary(&data.expenses, &data.incomes, &data.periods));
+}
+
+fn format_amounts(money: &MoneyFormat, count: usize) {
+    for i in 0..count {
+        black_box(money.format(black_box(i as f64 * 37.21 - 50_000.0)));
+    }
+}
+
+#[test]
+#[ignore = "performance budget; run with --ignored in release mode"]
+fn test_expense_table_within_budget() {
+    let mut state = large_state(EXPENSES);
+    state.ui.selected_tab = DashboardTab::Expenses;
+    // Sorting by name and grouping is the slowest arrangement
+    state.ui.sort = Some(SortKey::Name);
+    state.ui.group = Some(GroupKey::Category);
+    let mut terminal = Terminal::new(TestBackend::new(120, 40)).unwrap();
+
+    let took = measure(3, || render(&mut terminal, &state));
+    assert_within("Expenses tab", took, budget(1500));
+}
+
+#[test]
+#[ignore = "performance budget; run with --ignored in release mode"]
+fn test_summary_within_budget() {
+    let mut state = large_state(EXPENSES);
+    state.ui.selected_tab = DashboardTab::Summary;
+    let mut terminal = Terminal::new(TestBackend::new(120, 40)).unwrap();
+
+    let took = measure(3, || compose_summaries(&state));
+    assert_within("Summary composition", took, budget(250));
+    let took = measure(3, || render(&mut terminal, &state));
+    assert_within("Summary tab", took, budget(500));
+}
+
+#[test]
+#[ignore = "performance budget; run with --ignored in release mode"]
+fn test_money_format_within_budget() {
+    let money = MoneyFormat::default();
+
+    let took = measure(3, || format_amounts(&money, 100_000));
+    assert_within("Formatting 100k amounts", took, budget(500));
+}
+
+#[test]
+#[ignore = "benchmark; run with --ignored --nocapture in release mode"]
+fn bench_tabs() {
+    let mut terminal = Terminal::new(TestBackend::new(160, 50)).unwrap();
+    for count in [1_000, 10_000, 50_000] {
+        let mut state = large_state(count);
+        for tab in [
+            DashboardTab::Summary,
+            DashboardTab::Expenses,
+            DashboardTab::Income,
+            DashboardTab::Charts,
+        ] {
+            state.ui.selected_tab = tab;
+            let took = measure(20, || render(&mut terminal, &state));
+            println!("{:>8} tab, {:>6} expenses: {:?}", tab.as_str(), count, took);
+        }
+        state.ui.selected_tab = DashboardTab::Expenses;
+        state.ui.sort = Some(SortKey::Name);
+        state.ui.group = Some(GroupKey::Category);
+        let took = measure(20, || render(&mut terminal, &state));
+        println!("Expenses sorted+grouped, {:>6} expenses: {:?}", count, took);
+    }
+}
+
+#[test]
+#[ignore = "benchmark; run with --ignored --nocapture in release mode"]
+fn bench_summary_composition() {
+    for count in [1_000, 10_000, 100_000] {
+        let state = large_state(count);
+        let took = measure(20, || compose_summaries(&state));
+        println!("Summaries, {:>6} expenses: {:?}", count, took);
+    }
+}
+
+#[test]
+#[ignore = "benchmark; run with --ignored --nocapture in release mode"]
+fn bench_money_format() {
+    let modes = [
+        ("default", MoneyFormat::default()),
+        (
+            "hide_cents",
+            MoneyFormat {
+                hide_cents: true,
+                ..Default::default()
+            },
+        ),
+        (
+            "compact",
+            MoneyFormat {
+                compact: true,
+                ..Default::default()
+            },
+        ),
+        (
+            "parentheses",
+            MoneyFormat {
+                negatives: NegativeStyle::Parentheses,
+                ..Default::default()
+            },
+        ),
+    ];
+    for (name, money) in modes {
+        let took = measure(20, || format_amounts(&money, 100_000));
+        println!("{:>11}: {:?} per 100k amounts", name, took);
+    }
+}
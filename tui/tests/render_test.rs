@@ -0,0 +1,494 @@
+//! Golden-file rendering tests for the Budget TUI views
+//!
+//! Each view is rendered into a `TestBackend` at several terminal sizes and
+//! compared against `tests/golden/<name>.txt`. Views are pure functions of
+//! the state passed in, so the fixture below fully determines the output.
+//!
+//! A missing golden file fails the test like a changed one. Record new ones,
+//! or regenerate them after an intentional layout change, with:
+//!
+//! ```sh
+//! UPDATE_GOLDEN=1 cargo test --test render_test
+//! ```
+
+use std::path::PathBuf;
+
+use chrono::NaiveDate;
+use ratatui::{backend::TestBackend, buffer::Buffer, Frame, Terminal};
+use serde_json::json;
+
+use budget_tui::clock::Clock;
+use budget_tui::models::{
+    Category, DeviceCode, Expense, Income, IncomeType, KeyScopes, Month, Period, TotpChallenge,
+    User,
+};
+use budget_tui::state::{AppState, DashboardTab, Modal, Screen};
+use budget_tui::ui;
+
+/// Terminal sizes every view is rendered at (small, default, large)
+const SIZES: [(u16, u16); 3] = [(80, 24), (120, 40), (160, 50)];
+
+/// Fixed version string so the login footer doesn't change between releases
+const VERSION: &str = "0.0.0-test";
+
+/// Render a view into a plain-text snapshot of the terminal buffer
+fn render_to_string(width: u16, height: u16, view: impl FnOnce(&mut Frame)) -> String {
+    let mut terminal = Terminal::new(TestBackend::new(width, height)).unwrap();
+    terminal.draw(view).unwrap();
+    buffer_to_string(terminal.backend().buffer())
+}
+
+/// Convert a buffer into lines of text, one per terminal row
+fn buffer_to_string(buffer: &Buffer) -> String {
+    let area = buffer.area;
+    let mut out = String::new();
+    for y in area.top()..area.bottom() {
+        let line: String = (area.left()..area.right())
+            .map(|x| buffer[(x, y)].symbol())
+            .collect();
+        out.push_str(line.trim_end());
+        out.push('\n');
+    }
+    out
+}
+
+/// Compare a snapshot against its golden file, or record it with
+/// `UPDATE_GOLDEN` set
+fn assert_golden(name: &str, actual: &str) {
+    let path = PathBuf::from(env!("CARGO_MANIFEST_DIR"))
+        .join("tests")
+        .join("golden")
+        .join(format!("{}.txt", name));
+
+    if std::env::var_os("UPDATE_GOLDEN").is_some() {
+        std::fs::create_dir_all(path.parent().unwrap()).unwrap();
+        std::fs::write(&path, actual).unwrap();
+        return;
+    }
+
+    let expected = std::fs::read_to_string(&path).unwrap_or_else(|_| {
+        panic!(
+            "no golden file for '{}' at {}; run with UPDATE_GOLDEN=1 to record it",
+            name,
+            path.display()
+        )
+    });
+    assert_eq!(
+        expected, actual,
+        "rendering of '{}' changed; rerun with UPDATE_GOLDEN=1 if intended",
+        name
+    );
+}
+
+/// Render the dashboard for a state at every size and check the golden files
+fn assert_dashboard_golden(name: &str, state: &AppState) {
+    for (width, height) in SIZES {
+        let actual = render_to_string(width, height, |frame| ui::render(state, frame));
+        assert_golden(&format!("{}_{}x{}", name, width, height), &actual);
+    }
+}
+
+/// A logged-in state with one month of representative data
+fn fixture_state() -> AppState {
+    // After the fixture's months, so nothing depends on the day tests run
+    let mut state = AppState {
+        screen: Screen::Dashboard,
+        clock: Clock::frozen_on(NaiveDate::from_ymd_opt(2025, 1, 15).unwrap()),
+        ..Default::default()
+    };
+
+    state.user = Some(
+        serde_json::from_value::<User>(json!({
+            "id": 1,
+            "email": "test@example.com",
+            "full_name": "Test User",
+            "is_active": true,
+            "is_admin": true
+        }))
+        .unwrap(),
+    );
+
+    let months: Vec<Month> = serde_json::from_value(json!([
+        {
+            "id": 1, "year": 2024, "month": 11, "name": "November 2024",
+            "start_date": "2024-11-01", "end_date": "2024-11-30",
+            "is_closed": true, "closed_at": "2024-12-01T10:00:00", "closed_by": "test@example.com"
+        },
+        {
+            "id": 2, "year": 2024, "month": 12, "name": "December 2024",
+            "start_date": "2024-12-01", "end_date": "2024-12-31",
+            "is_closed": false, "closed_at": null, "closed_by": null
+        }
+    ]))
+    .unwrap();
+    state.data.current_month = months.last().cloned();
+    state.data.months = months;
+    state.ui.selected_month_index = 1;
+
+    state.data.categories = serde_json::from_value::<Vec<Category>>(json!([
+        {"id": 1, "name": "Housing", "color": "#8b5cf6"},
+        {"id": 2, "name": "Food", "color": "#22c55e"},
+        {"id": 3, "name": "Transport", "color": "#f59e0b"}
+    ]))
+    .unwrap();
+    state.data.periods = serde_json::from_value::<Vec<Period>>(json!([
+        {"id": 1, "name": "Fixed/1st Period", "color": "#3b82f6"},
+        {"id": 2, "name": "Variable/2nd Period", "color": "#ec4899"}
+    ]))
+    .unwrap();
+    state.data.income_types = serde_json::from_value::<Vec<IncomeType>>(json!([
+        {"id": 1, "name": "Salary", "color": "#10b981"},
+        {"id": 2, "name": "Freelance", "color": "#06b6d4"}
+    ]))
+    .unwrap();
+
+    state.data.expenses = serde_json::from_value::<Vec<Expense>>(json!([
+        {
+            "id": 1, "expense_name": "Rent", "period": "Fixed/1st Period", "category": "Housing",
+            "projected": 1500.0, "cost": 1500.0, "notes": null, "month_id": 2,
+            "purchases": null, "order": 0, "expense_date": "2024-12-01"
+        },
+        {
+            "id": 2, "expense_name": "Groceries", "period": "Variable/2nd Period", "category": "Food",
+            "projected": 600.0, "cost": 642.5, "notes": "Holiday dinner", "month_id": 2,
+            "purchases": [
+                {"name": "Market", "amount": 400.0, "date": "2024-12-05"},
+                {"name": "Bakery", "amount": 242.5, "date": "2024-12-20"}
+            ],
+            "order": 1, "expense_date": null
+        },
+        {
+            "id": 3, "expense_name": "Fuel", "period": "Variable/2nd Period", "category": "Transport",
+            "projected": 200.0, "cost": 0.0, "notes": null, "month_id": 2,
+            "purchases": null, "order": 2, "expense_date": null
+        }
+    ]))
+    .unwrap();
+    state.data.incomes = serde_json::from_value::<Vec<Income>>(json!([
+        {
+            "id": 1, "income_type_id": 1, "period": "Fixed/1st Period",
+            "projected": 4000.0, "amount": 4000.0, "month_id": 2,
+            "created_at": "2024-12-01T09:00:00", "updated_at": "2024-12-01T09:00:00",
+            "created_by": "test@example.com", "updated_by": null
+        },
+        {
+            "id": 2, "income_type_id": 2, "period": "Variable/2nd Period",
+            "projected": 800.0, "amount": 350.0, "month_id": 2,
+            "created_at": "2024-12-15T09:00:00", "updated_at": "2024-12-15T09:00:00",
+            "created_by": "test@example.com", "updated_by": null
+        }
+    ]))
+    .unwrap();
+
+    state.data.summary_totals = Some(
+        serde_json::from_value(json!({
+            "total_projected_expenses": 2300.0,
+            "total_current_expenses": 2142.5,
+            "total_projected_income": 4800.0,
+            "total_current_income": 4350.0,
+            "total_projected": 2500.0,
+            "total_current": 2207.5
+        }))
+        .unwrap(),
+    );
+    state.data.category_summary = serde_json::from_value(json!([
+        {"category": "Housing", "projected": 1500.0, "total": 1500.0, "over_projected": false},
+        {"category": "Food", "projected": 600.0, "total": 642.5, "over_projected": true},
+        {"category": "Transport", "projected": 200.0, "total": 0.0, "over_projected": false}
+    ]))
+    .unwrap();
+    state.data.income_type_summary = serde_json::from_value(json!([
+        {"income_type": "Salary", "projected": 4000.0, "total": 4000.0},
+        {"income_type": "Freelance", "projected": 800.0, "total": 350.0}
+    ]))
+    .unwrap();
+    state.data.period_summary = Some(
+        serde_json::from_value(json!({
+            "periods": [
+                {
+                    "period": "Fixed/1st Period", "color": "#3b82f6",
+                    "total_income": 4000.0, "total_expenses": 1500.0, "difference": 2500.0
+                },
+                {
+                    "period": "Variable/2nd Period", "color": "#ec4899",
+                    "total_income": 350.0, "total_expenses": 642.5, "difference": -292.5
+                }
+            ],
+            "grand_total_income": 4350.0,
+            "grand_total_expenses": 2142.5,
+            "grand_total_difference": 2207.5
+        }))
+        .unwrap(),
+    );
+    state.data.insights = Some(
+        serde_json::from_value(json!({
+            "insights": [
+                {"type": "warning", "icon": "!", "message": "Food is 7% over projected", "category": "Food"},
+                {"type": "positive", "icon": "+", "message": "Income is on track", "category": null}
+            ],
+            "savingsProjection": 2500.0,
+            "budgetHealth": "warning",
+            "overProjectedCount": 1,
+            "totalCategories": 3
+        }))
+        .unwrap(),
+    );
+
+    state
+}
+
+/// Fixture state with a tab selected
+fn fixture_state_on(tab: DashboardTab) -> AppState {
+    let mut state = fixture_state();
+    state.ui.selected_tab = tab;
+    state
+}
+
+#[test]
+fn test_render_login() {
+    for (width, height) in SIZES {
+        let actual = render_to_string(width, height, |frame| {
+            ui::login::render_with_state(
+                frame,
+                "test@example.com",
+                "secret",
+                1,
+                Some("Invalid credentials"),
+                false,
+                VERSION,
+                "http://localhost:8000",
+                None,
+            )
+        });
+        assert_golden(&format!("login_{}x{}", width, height), &actual);
+    }
+}
+
+#[test]
+fn test_render_totp_prompt() {
+    let prompt = ui::login::TotpPrompt::new(TotpChallenge {
+        challenge: "abc".to_string(),
+        expires_in: Some(300),
+    });
+    for (width, height) in SIZES {
+        let actual = render_to_string(width, height, |frame| {
+            ui::login::render_totp_prompt(
+                frame,
+                &prompt,
+                "test@example.com",
+                Some("Invalid code - try again"),
+                false,
+                VERSION,
+            )
+        });
+        assert_golden(&format!("login_totp_{}x{}", width, height), &actual);
+    }
+}
+
+#[test]
+fn test_render_device_login() {
+    let login = ui::login::DeviceLogin::new(DeviceCode {
+        device_code: "dev1".to_string(),
+        user_code: "WDJB-MJHT".to_string(),
+        verification_uri: "https://sso.example.com/device".to_string(),
+        verification_uri_complete: None,
+        expires_in: 600,
+        interval: 5,
+    });
+    for (width, height) in SIZES {
+        let actual = render_to_string(width, height, |frame| {
+            ui::login::render_device_login(frame, &login, None, VERSION)
+        });
+        assert_golden(&format!("login_device_{}x{}", width, height), &actual);
+    }
+}
+
+#[test]
+fn test_render_api_config() {
+    for (width, height) in SIZES {
+        let actual = render_to_string(width, height, |frame| {
+            ui::api_config::render(
+                frame,
+                "home",
+                &["demo".to_string(), "home".to_string()],
+                "http://localhost:8000",
+                "test-api-key",
+                1,
+                None,
+                VERSION,
+            )
+        });
+        assert_golden(&format!("api_config_{}x{}", width, height), &actual);
+    }
+}
+
+#[test]
+fn test_render_summary_tab() {
+    assert_dashboard_golden("summary", &fixture_state_on(DashboardTab::Summary));
+}
+
+#[test]
+fn test_render_summary_month_end() {
+    // Pace marker and end-of-month suggestions for the open December
+    let mut state = fixture_state_on(DashboardTab::Summary);
+    state.clock = Clock::frozen_on(NaiveDate::from_ymd_opt(2024, 12, 29).unwrap());
+    assert_dashboard_golden("summary_month_end", &state);
+}
+
+#[test]
+fn test_render_expenses_tab() {
+    assert_dashboard_golden("expenses", &fixture_state_on(DashboardTab::Expenses));
+}
+
+#[test]
+fn test_render_income_tab() {
+    assert_dashboard_golden("income", &fixture_state_on(DashboardTab::Income));
+}
+
+#[test]
+fn test_render_large_text() {
+    for (name, tab) in [
+        ("large_summary", DashboardTab::Summary),
+        ("large_expenses", DashboardTab::Expenses),
+        ("large_income", DashboardTab::Income),
+    ] {
+        let mut state = fixture_state_on(tab);
+        state.toggle_large_text();
+        assert!(state.ui.large_text);
+        assert_dashboard_golden(name, &state);
+    }
+}
+
+#[test]
+fn test_render_charts_tab() {
+    assert_dashboard_golden("charts", &fixture_state_on(DashboardTab::Charts));
+}
+
+#[test]
+fn test_render_settings_tab() {
+    assert_dashboard_golden("settings", &fixture_state_on(DashboardTab::Settings));
+}
+
+#[test]
+fn test_render_empty_dashboard() {
+    let state = AppState {
+        screen: Screen::Dashboard,
+        ..Default::default()
+    };
+    assert_dashboard_golden("empty", &state);
+}
+
+#[test]
+fn test_render_help_modal() {
+    let mut state = fixture_state();
+    state.ui.modal = Some(Modal::Help);
+    assert_dashboard_golden("help", &state);
+}
+
+#[test]
+fn test_render_error_message() {
+    let mut state = fixture_state();
+    state.set_error("Failed to load data: connection refused");
+    assert_dashboard_golden("error", &state);
+}
+
+#[test]
+fn test_render_read_only_key() {
+    let mut state = fixture_state_on(DashboardTab::Expenses);
+    let actual = render_to_string(120, 40, |frame| ui::render(&state, frame));
+    assert!(actual.contains("n:New"));
+    assert!(!actual.contains("[READ-ONLY]"));
+
+    state.data.key_scopes = Some(KeyScopes {
+        scopes: vec!["read".to_string()],
+    });
+    let actual = render_to_string(120, 40, |frame| ui::render(&state, frame));
+    assert!(actual.contains("[READ-ONLY]"));
+    assert!(!actual.contains("n:New"));
+    assert!(!actual.contains("p:Pay"));
+    assert!(actual.contains("t/T:Tax"));
+
+    state.data.key_scopes = Some(KeyScopes {
+        scopes: vec!["read".to_string(), "expenses:write".to_string()],
+    });
+    let actual = render_to_string(120, 40, |frame| ui::render(&state, frame));
+    assert!(actual.contains("[LIMITED KEY]"));
+    assert!(actual.contains("n:New"));
+    assert!(!actual.contains("c:Close"));
+}
+
+#[test]
+fn test_buffer_to_string_trims_rows() {
+    let buffer = Buffer::with_lines(["ab  ", "    "]);
+    assert_eq!(buffer_to_string(&buffer), "ab\n\n");
+}
+
+#[test]
+fn test_progress_bar_marks_pace() {
+    let line = ui::progress_bar(10, 0.3, ratatui::style::Color::Green, Some(0.5));
+    let text: String = line.spans.iter().map(|s| s.content.as_ref()).collect();
+    assert_eq!(text, "███░░┃░░░░");
+
+    let line = ui::progress_bar(4, 1.5, ratatui::style::Color::Red, Some(1.0));
+    let text: String = line.spans.iter().map(|s| s.content.as_ref()).collect();
+    assert_eq!(text, "███┃");
+
+    assert!(
+        ui::progress_bar(0, 0.5, ratatui::style::Color::Red, Some(0.5))
+            .spans
+            .is_empty()
+    );
+}
+
+#[test]
+fn test_render_inline_views() {
+    let state = fixture_state();
+    for view in ui::inline::InlineView::ALL {
+        let buffer = ui::inline::render(&state, view, 100).unwrap();
+        let actual = ui::inline::to_ansi(&buffer, false);
+        assert_golden(&format!("inline_{}", view.name()), &actual);
+    }
+}
+
+#[test]
+fn test_inline_view_height_fits_rows() {
+    let mut state = fixture_state();
+    let view = ui::inline::InlineView::Expenses;
+    let height = view.height(&state);
+
+    let extra = state.data.expenses[0].clone();
+    state.data.expenses.push(extra);
+    assert_eq!(view.height(&state), height + 1);
+}
+
+#[test]
+fn test_inline_view_parse() {
+    use ui::inline::InlineView;
+    assert_eq!(InlineView::parse("summary"), Some(InlineView::Summary));
+    assert_eq!(InlineView::parse("Expenses"), Some(InlineView::Expenses));
+    assert_eq!(InlineView::parse("incomes"), Some(InlineView::Income));
+    assert_eq!(InlineView::parse("charts"), None);
+}
+
+#[test]
+fn test_inline_to_ansi_plain_trims_rows() {
+    let buffer = Buffer::with_lines(["ab  ", "    "]);
+    assert_eq!(ui::inline::to_ansi(&buffer, false), "ab\n\n");
+}
+
+#[test]
+fn test_inline_to_ansi_writes_styles() {
+    let mut buffer = Buffer::with_lines(["ab"]);
+    buffer.content[1].set_fg(ratatui::style::Color::Red);
+    assert_eq!(
+        ui::inline::to_ansi(&buffer, true),
+        "\x1b[0;39;49ma\x1b[0;31;49mb\x1b[0m\n"
+    );
+}
+
+#[test]
+fn test_format_size() {
+    assert_eq!(ui::format_size(512), "512 B");
+    assert_eq!(ui::format_size(12_595), "12.3 KB");
+    assert_eq!(ui::format_size(3 * 1_048_576), "3.0 MB");
+}
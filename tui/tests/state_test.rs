@@ -1,9 +1,27 @@
 //! State management tests for the Budget TUI application
 
-use budget_tui::models::{Expense, Income, Month};
+use budget_tui::api::{FieldError, MonthData};
+use budget_tui::clock::Clock;
+use budget_tui::config::{CurrencyConfig, StartupConfig, StartupMonth};
+use budget_tui::models::{
+    Account, AccountKind, Category, CategorySummary, CustomFields, Debt, Expense, ExpenseCreate,
+    Income, IncomeType, KeyScopes, Month, Period, Purchase, Scope,
+};
+use budget_tui::state::audit::AuditTrail;
+use budget_tui::state::autofill::{round_to, AutofillPreview};
+use budget_tui::state::currency::{self, Rates};
+use budget_tui::state::debts;
+use budget_tui::state::history::HISTORY_LEN;
+use budget_tui::state::reallocate::ReallocateBoard;
 use budget_tui::state::{
-    AppState, DashboardTab, EntityType, InputMode, Modal, Screen, SettingsTab,
+    envelopes, fallback, forms, ledger_split, money_input, next_filter, normalize_name, Action,
+    ActionHistory, AppState, DashboardTab, DebtField, DebtFormState, EmptyList, EntityType,
+    ExpenseField, ExpenseFormState, GroupKey, IncomeField, IncomeFormState, InputMode,
+    MergePreview, Modal, Pane, ReimbursementReport, Screen, SettingsTab, SortKey, SuggestionKind,
+    TransferField, TransferFormState, ViewChip,
 };
+use budget_tui::storage::{ExpenseLedgers, LastView, Ledger, WriteJournal};
+use budget_tui::ui::dates::DateFormat;
 
 #[test]
 fn test_screen_enum() {
@@ -18,12 +36,13 @@ fn test_screen_enum() {
 #[test]
 fn test_dashboard_tab_all() {
     let tabs = DashboardTab::all();
-    assert_eq!(tabs.len(), 5);
+    assert_eq!(tabs.len(), 6);
     assert_eq!(tabs[0], DashboardTab::Summary);
     assert_eq!(tabs[1], DashboardTab::Expenses);
     assert_eq!(tabs[2], DashboardTab::Income);
     assert_eq!(tabs[3], DashboardTab::Charts);
-    assert_eq!(tabs[4], DashboardTab::Settings);
+    assert_eq!(tabs[4], DashboardTab::Debts);
+    assert_eq!(tabs[5], DashboardTab::Settings);
 }
 
 #[test]
@@ -32,6 +51,7 @@ fn test_dashboard_tab_as_str() {
     assert_eq!(DashboardTab::Expenses.as_str(), "Expenses");
     assert_eq!(DashboardTab::Income.as_str(), "Income");
     assert_eq!(DashboardTab::Charts.as_str(), "Charts");
+    assert_eq!(DashboardTab::Debts.as_str(), "Debts");
     assert_eq!(DashboardTab::Settings.as_str(), "Settings");
 }
 
@@ -41,7 +61,21 @@ fn test_dashboard_tab_index() {
     assert_eq!(DashboardTab::Expenses.index(), 1);
     assert_eq!(DashboardTab::Income.index(), 2);
     assert_eq!(DashboardTab::Charts.index(), 3);
-    assert_eq!(DashboardTab::Settings.index(), 4);
+    assert_eq!(DashboardTab::Debts.index(), 4);
+    assert_eq!(DashboardTab::Settings.index(), 5);
+}
+
+#[test]
+fn test_dashboard_tab_from_name() {
+    assert_eq!(
+        DashboardTab::from_name("expenses"),
+        Some(DashboardTab::Expenses)
+    );
+    assert_eq!(
+        DashboardTab::from_name(" Settings "),
+        Some(DashboardTab::Settings)
+    );
+    assert_eq!(DashboardTab::from_name("budget"), None);
 }
 
 #[test]
@@ -50,7 +84,8 @@ fn test_dashboard_tab_from_index() {
     assert_eq!(DashboardTab::from_index(1), DashboardTab::Expenses);
     assert_eq!(DashboardTab::from_index(2), DashboardTab::Income);
     assert_eq!(DashboardTab::from_index(3), DashboardTab::Charts);
-    assert_eq!(DashboardTab::from_index(4), DashboardTab::Settings);
+    assert_eq!(DashboardTab::from_index(4), DashboardTab::Debts);
+    assert_eq!(DashboardTab::from_index(5), DashboardTab::Settings);
     // Out of bounds defaults to Summary
     assert_eq!(DashboardTab::from_index(99), DashboardTab::Summary);
 }
@@ -60,7 +95,8 @@ fn test_dashboard_tab_next() {
     assert_eq!(DashboardTab::Summary.next(), DashboardTab::Expenses);
     assert_eq!(DashboardTab::Expenses.next(), DashboardTab::Income);
     assert_eq!(DashboardTab::Income.next(), DashboardTab::Charts);
-    assert_eq!(DashboardTab::Charts.next(), DashboardTab::Settings);
+    assert_eq!(DashboardTab::Charts.next(), DashboardTab::Debts);
+    assert_eq!(DashboardTab::Debts.next(), DashboardTab::Settings);
     // Wraps around
     assert_eq!(DashboardTab::Settings.next(), DashboardTab::Summary);
 }
@@ -72,17 +108,19 @@ fn test_dashboard_tab_previous() {
     assert_eq!(DashboardTab::Expenses.previous(), DashboardTab::Summary);
     assert_eq!(DashboardTab::Income.previous(), DashboardTab::Expenses);
     assert_eq!(DashboardTab::Charts.previous(), DashboardTab::Income);
-    assert_eq!(DashboardTab::Settings.previous(), DashboardTab::Charts);
+    assert_eq!(DashboardTab::Debts.previous(), DashboardTab::Charts);
+    assert_eq!(DashboardTab::Settings.previous(), DashboardTab::Debts);
 }
 
 #[test]
 fn test_settings_tab_all() {
     let tabs = SettingsTab::all();
-    assert_eq!(tabs.len(), 4);
+    assert_eq!(tabs.len(), 5);
     assert_eq!(tabs[0], SettingsTab::Categories);
     assert_eq!(tabs[1], SettingsTab::Periods);
     assert_eq!(tabs[2], SettingsTab::IncomeTypes);
-    assert_eq!(tabs[3], SettingsTab::Password);
+    assert_eq!(tabs[3], SettingsTab::Accounts);
+    assert_eq!(tabs[4], SettingsTab::Password);
 }
 
 #[test]
@@ -90,6 +128,7 @@ fn test_settings_tab_as_str() {
     assert_eq!(SettingsTab::Categories.as_str(), "Categories");
     assert_eq!(SettingsTab::Periods.as_str(), "Periods");
     assert_eq!(SettingsTab::IncomeTypes.as_str(), "Income Types");
+    assert_eq!(SettingsTab::Accounts.as_str(), "Accounts");
     assert_eq!(SettingsTab::Password.as_str(), "Password");
 }
 
@@ -98,7 +137,8 @@ fn test_settings_tab_index() {
     assert_eq!(SettingsTab::Categories.index(), 0);
     assert_eq!(SettingsTab::Periods.index(), 1);
     assert_eq!(SettingsTab::IncomeTypes.index(), 2);
-    assert_eq!(SettingsTab::Password.index(), 3);
+    assert_eq!(SettingsTab::Accounts.index(), 3);
+    assert_eq!(SettingsTab::Password.index(), 4);
 }
 
 #[test]
@@ -106,7 +146,8 @@ fn test_settings_tab_from_index() {
     assert_eq!(SettingsTab::from_index(0), SettingsTab::Categories);
     assert_eq!(SettingsTab::from_index(1), SettingsTab::Periods);
     assert_eq!(SettingsTab::from_index(2), SettingsTab::IncomeTypes);
-    assert_eq!(SettingsTab::from_index(3), SettingsTab::Password);
+    assert_eq!(SettingsTab::from_index(3), SettingsTab::Accounts);
+    assert_eq!(SettingsTab::from_index(4), SettingsTab::Password);
     // Out of bounds defaults to Categories
     assert_eq!(SettingsTab::from_index(99), SettingsTab::Categories);
 }
@@ -115,7 +156,8 @@ fn test_settings_tab_from_index() {
 fn test_settings_tab_next() {
     assert_eq!(SettingsTab::Categories.next(), SettingsTab::Periods);
     assert_eq!(SettingsTab::Periods.next(), SettingsTab::IncomeTypes);
-    assert_eq!(SettingsTab::IncomeTypes.next(), SettingsTab::Password);
+    assert_eq!(SettingsTab::IncomeTypes.next(), SettingsTab::Accounts);
+    assert_eq!(SettingsTab::Accounts.next(), SettingsTab::Password);
     // Wraps around
     assert_eq!(SettingsTab::Password.next(), SettingsTab::Categories);
 }
@@ -126,7 +168,8 @@ fn test_settings_tab_previous() {
     assert_eq!(SettingsTab::Categories.previous(), SettingsTab::Password);
     assert_eq!(SettingsTab::Periods.previous(), SettingsTab::Categories);
     assert_eq!(SettingsTab::IncomeTypes.previous(), SettingsTab::Periods);
-    assert_eq!(SettingsTab::Password.previous(), SettingsTab::IncomeTypes);
+    assert_eq!(SettingsTab::Accounts.previous(), SettingsTab::IncomeTypes);
+    assert_eq!(SettingsTab::Password.previous(), SettingsTab::Accounts);
 }
 
 #[test]
@@ -213,6 +256,7 @@ fn test_app_state_month_navigation() {
             is_closed: false,
             closed_at: None,
             closed_by: None,
+            notes: None,
         },
         Month {
             id: 2,
@@ -224,6 +268,7 @@ fn test_app_state_month_navigation() {
             is_closed: false,
             closed_at: None,
             closed_by: None,
+            notes: None,
         },
         Month {
             id: 3,
@@ -235,6 +280,7 @@ fn test_app_state_month_navigation() {
             is_closed: false,
             closed_at: None,
             closed_by: None,
+            notes: None,
         },
     ];
 
@@ -282,6 +328,7 @@ fn test_app_state_select_current_month() {
             is_closed: false,
             closed_at: None,
             closed_by: None,
+            notes: None,
         },
         Month {
             id: 2,
@@ -293,6 +340,7 @@ fn test_app_state_select_current_month() {
             is_closed: false,
             closed_at: None,
             closed_by: None,
+            notes: None,
         },
         Month {
             id: 3,
@@ -304,6 +352,7 @@ fn test_app_state_select_current_month() {
             is_closed: false,
             closed_at: None,
             closed_by: None,
+            notes: None,
         },
     ];
 
@@ -317,6 +366,7 @@ fn test_app_state_select_current_month() {
         is_closed: false,
         closed_at: None,
         closed_by: None,
+        notes: None,
     });
 
     state.select_current_month();
@@ -349,6 +399,14 @@ fn test_app_state_filtered_expenses() {
             purchases: None,
             order: 0,
             expense_date: None,
+            created_at: None,
+            updated_at: None,
+            created_by: None,
+            updated_by: None,
+            attachments: None,
+            account_id: None,
+            currency: None,
+            custom_fields: CustomFields::new(),
         },
         Expense {
             id: 2,
@@ -362,6 +420,14 @@ fn test_app_state_filtered_expenses() {
             purchases: None,
             order: 1,
             expense_date: None,
+            created_at: None,
+            updated_at: None,
+            created_by: None,
+            updated_by: None,
+            attachments: None,
+            account_id: None,
+            currency: None,
+            custom_fields: CustomFields::new(),
         },
         Expense {
             id: 3,
@@ -375,6 +441,14 @@ fn test_app_state_filtered_expenses() {
             purchases: None,
             order: 2,
             expense_date: None,
+            created_at: None,
+            updated_at: None,
+            created_by: None,
+            updated_by: None,
+            attachments: None,
+            account_id: None,
+            currency: None,
+            custom_fields: CustomFields::new(),
         },
     ];
 
@@ -418,6 +492,8 @@ fn test_app_state_filtered_incomes() {
             updated_at: "2024-01-01".to_string(),
             created_by: None,
             updated_by: None,
+            account_id: None,
+            currency: None,
         },
         Income {
             id: 2,
@@ -430,6 +506,8 @@ fn test_app_state_filtered_incomes() {
             updated_at: "2024-01-01".to_string(),
             created_by: None,
             updated_by: None,
+            account_id: None,
+            currency: None,
         },
     ];
 
@@ -468,3 +546,1626 @@ fn test_app_state_messages() {
     assert!(state.ui.error_message.is_none());
     assert!(state.ui.success_message.is_none());
 }
+
+fn settings_state() -> AppState {
+    let mut state = AppState::default();
+    state.data.categories = vec![
+        Category {
+            id: 1,
+            name: "Food".to_string(),
+            color: "#22c55e".to_string(),
+        },
+        Category {
+            id: 2,
+            name: "Eating  Out".to_string(),
+            color: "#f59e0b".to_string(),
+        },
+    ];
+    state.data.periods = vec![Period {
+        id: 7,
+        name: "Fixed/1st Period".to_string(),
+        color: "#3b82f6".to_string(),
+    }];
+    state
+}
+
+#[test]
+fn test_normalize_name() {
+    assert_eq!(normalize_name("  Food "), "food");
+    assert_eq!(normalize_name("Eating   Out"), "eating out");
+    assert_eq!(normalize_name(""), "");
+}
+
+#[test]
+fn test_find_duplicate_name() {
+    let state = settings_state();
+
+    assert_eq!(
+        state.find_duplicate_name(EntityType::Category, "food", None),
+        Some((1, "Food".to_string()))
+    );
+    assert_eq!(
+        state.find_duplicate_name(EntityType::Category, " eating out ", None),
+        Some((2, "Eating  Out".to_string()))
+    );
+    assert_eq!(
+        state.find_duplicate_name(EntityType::Category, "Groceries", None),
+        None
+    );
+    // Names are only compared within the same entity type
+    assert_eq!(
+        state.find_duplicate_name(EntityType::Period, "Food", None),
+        None
+    );
+}
+
+#[test]
+fn test_find_duplicate_name_excludes_edited_item() {
+    let state = settings_state();
+
+    // Renaming "Food" to "FOOD" is not a duplicate of itself
+    assert_eq!(
+        state.find_duplicate_name(EntityType::Category, "FOOD", Some(1)),
+        None
+    );
+    assert_eq!(
+        state.find_duplicate_name(EntityType::Category, "FOOD", Some(2)),
+        Some((1, "Food".to_string()))
+    );
+}
+
+#[test]
+fn test_jump_to_entity() {
+    let mut state = settings_state();
+    state.jump_to_entity(EntityType::Category, 2);
+
+    assert_eq!(state.ui.selected_tab, DashboardTab::Settings);
+    assert_eq!(state.ui.settings_tab, SettingsTab::Categories);
+    assert_eq!(state.ui.category_table.selected(), Some(1));
+
+    state.jump_to_entity(EntityType::Period, 7);
+    assert_eq!(state.ui.settings_tab, SettingsTab::Periods);
+    assert_eq!(state.ui.period_table.selected(), Some(0));
+}
+
+#[test]
+fn test_entity_names() {
+    let state = settings_state();
+
+    assert_eq!(
+        state.entity_names(EntityType::Category),
+        vec![(1, "Food".to_string()), (2, "Eating  Out".to_string())]
+    );
+    assert_eq!(
+        state.entity_names(EntityType::Period),
+        vec![(7, "Fixed/1st Period".to_string())]
+    );
+    assert!(state.entity_names(EntityType::Expense).is_empty());
+}
+
+#[test]
+fn test_selected_settings_entity() {
+    let mut state = settings_state();
+    assert_eq!(state.selected_settings_entity(), None);
+
+    state.ui.settings_tab = SettingsTab::Categories;
+    state.ui.category_table.select(Some(1));
+    assert_eq!(
+        state.selected_settings_entity(),
+        Some((EntityType::Category, 2, "Eating  Out".to_string()))
+    );
+
+    state.ui.settings_tab = SettingsTab::Password;
+    assert_eq!(state.selected_settings_entity(), None);
+}
+
+#[test]
+fn test_closed_month_override() {
+    let mut state = AppState::default();
+    state.data.months = vec![merge_month(1, true), merge_month(2, false)];
+
+    assert!(state.selected_month_locked());
+    assert_eq!(state.toggle_closed_month_override(), Some(true));
+    assert!(!state.selected_month_locked());
+    assert_eq!(state.ui.closed_month_override, Some(1));
+
+    // Only the unlocked month; an open one has nothing to unlock
+    state.next_month();
+    assert!(!state.selected_month_locked());
+    assert_eq!(state.toggle_closed_month_override(), None);
+    assert_eq!(state.ui.closed_month_override, Some(1));
+
+    state.previous_month();
+    assert_eq!(state.toggle_closed_month_override(), Some(false));
+    assert!(state.selected_month_locked());
+    assert_eq!(state.ui.closed_month_override, None);
+}
+
+fn merge_month(id: i32, is_closed: bool) -> Month {
+    Month {
+        id,
+        year: 2024,
+        month: id,
+        name: format!("Month {}", id),
+        start_date: "2024-01-01".to_string(),
+        end_date: "2024-01-31".to_string(),
+        is_closed,
+        closed_at: None,
+        closed_by: None,
+        notes: None,
+    }
+}
+
+fn merge_expense(id: i32, month_id: i32) -> Expense {
+    Expense {
+        id,
+        expense_name: format!("Expense {}", id),
+        period: "Fixed/1st Period".to_string(),
+        category: "Food".to_string(),
+        projected: 100.0,
+        cost: 90.0,
+        notes: None,
+        month_id,
+        purchases: None,
+        order: 0,
+        expense_date: None,
+        created_at: None,
+        updated_at: None,
+        created_by: None,
+        updated_by: None,
+        attachments: None,
+        account_id: None,
+        currency: None,
+        custom_fields: CustomFields::new(),
+    }
+}
+
+#[test]
+fn test_merge_preview_build() {
+    let months = vec![merge_month(1, false), merge_month(2, false)];
+    let income_types = vec![IncomeType {
+        id: 3,
+        name: "Salary".to_string(),
+        color: "#22c55e".to_string(),
+    }];
+    let expenses = vec![merge_expense(1, 1), merge_expense(2, 2)];
+    let incomes = vec![Income {
+        id: 5,
+        income_type_id: 3,
+        period: "Fixed/1st Period".to_string(),
+        projected: 5000.0,
+        amount: 4800.0,
+        month_id: 2,
+        created_at: "2024-01-01".to_string(),
+        updated_at: "2024-01-01".to_string(),
+        created_by: None,
+        updated_by: None,
+        account_id: None,
+        currency: None,
+    }];
+
+    let preview = MergePreview::build(&expenses, &incomes, &months, &income_types);
+
+    assert_eq!(preview.rows.len(), 3);
+    assert_eq!(preview.expense_count(), 2);
+    assert_eq!(preview.income_count(), 1);
+    assert_eq!(preview.month_count(), 2);
+    assert_eq!(preview.rows[2].kind, EntityType::Income);
+    assert_eq!(preview.rows[2].label, "Salary (Fixed/1st Period)");
+    assert_eq!(preview.rows[2].amount, 4800.0);
+    assert!(!preview.is_blocked());
+}
+
+#[test]
+fn test_merge_preview_blocked_by_closed_month() {
+    let months = vec![merge_month(1, true), merge_month(2, false)];
+    let expenses = vec![merge_expense(1, 1), merge_expense(2, 2)];
+
+    let preview = MergePreview::build(&expenses, &[], &months, &[]);
+
+    assert_eq!(preview.closed_count(), 1);
+    assert!(preview.is_blocked());
+    assert!(preview.rows[0].month_closed);
+}
+
+#[test]
+fn test_merge_preview_empty() {
+    let preview = MergePreview::build(&[], &[], &[], &[]);
+
+    assert!(preview.rows.is_empty());
+    assert_eq!(preview.month_count(), 0);
+    assert!(!preview.is_blocked());
+}
+
+#[test]
+fn test_reimbursement_report_outstanding_first() {
+    let months = vec![merge_month(1, false), merge_month(2, false)];
+    let expenses = vec![
+        merge_expense(1, 1),
+        merge_expense(2, 1),
+        merge_expense(3, 2),
+        merge_expense(4, 2),
+    ];
+    let mut ledgers = ExpenseLedgers::default();
+    ledgers.set_ledger(1, 1, Ledger::Reimbursable);
+    ledgers.toggle_reimbursed(1, chrono::NaiveDate::from_ymd_opt(2024, 3, 1).unwrap());
+    ledgers.set_ledger(2, 1, Ledger::Business);
+    ledgers.set_ledger(3, 2, Ledger::Reimbursable);
+    // Deleted on the server since
+    ledgers.set_ledger(9, 2, Ledger::Reimbursable);
+
+    let report = ReimbursementReport::build(&expenses, &months, &ledgers);
+
+    assert_eq!(report.rows.len(), 3);
+    assert_eq!(report.rows[0].expense_id, 3);
+    assert!(report.rows[0].is_outstanding());
+    assert_eq!(report.outstanding(), 90.0);
+    assert_eq!(report.reimbursed(), 90.0);
+    assert!(report.rows.iter().all(|r| r.expense_id != 4));
+}
+
+#[test]
+fn test_ledger_split() {
+    let expenses = vec![
+        merge_expense(1, 1),
+        merge_expense(2, 1),
+        merge_expense(3, 1),
+    ];
+    let refs: Vec<&Expense> = expenses.iter().collect();
+    let mut ledgers = ExpenseLedgers::default();
+    ledgers.set_ledger(2, 1, Ledger::Business);
+
+    let split = ledger_split(&refs, &ledgers);
+
+    assert_eq!(split[0], (Ledger::Personal, 180.0));
+    assert_eq!(split[1], (Ledger::Business, 90.0));
+    assert_eq!(split[2], (Ledger::Reimbursable, 0.0));
+}
+
+fn pending_state() -> AppState {
+    let mut state = AppState::default();
+    state.data.months = vec![merge_month(1, false)];
+    state.data.expenses = vec![merge_expense(1, 1), merge_expense(2, 1)];
+    state
+}
+
+#[test]
+fn test_apply_queued_writes() {
+    let mut state = pending_state();
+    let mut journal = WriteJournal::default();
+    journal.push(
+        "POST",
+        "/expenses",
+        Some(serde_json::json!({
+            "expense_name": "Gym",
+            "period": "Fixed/1st Period",
+            "category": "Health",
+            "projected": 40.0,
+            "cost": 0.0,
+            "notes": null,
+            "month_id": 1,
+            "purchases": null,
+            "expense_date": null,
+        })),
+    );
+    journal.push(
+        "PUT",
+        "/expenses/1",
+        Some(serde_json::json!({"cost": 120.0})),
+    );
+    journal.push("DELETE", "/expenses/2", None);
+
+    state.apply_queued_writes(journal.entries());
+    // Applying again after a redraw must not duplicate queued creates
+    state.apply_queued_writes(journal.entries());
+
+    assert_eq!(state.data.expenses.len(), 3);
+    let created = &state.data.expenses[2];
+    assert_eq!(created.id, -1);
+    assert_eq!(created.expense_name, "Gym");
+    assert_eq!(state.data.expenses[0].cost, 120.0);
+
+    assert!(state.is_pending_sync(EntityType::Expense, -1));
+    assert!(state.is_pending_sync(EntityType::Expense, 1));
+    assert!(state.is_pending_sync(EntityType::Expense, 2));
+    assert!(!state.is_pending_sync(EntityType::Income, 1));
+}
+
+#[test]
+fn test_apply_queued_writes_skips_other_months() {
+    let mut state = pending_state();
+    let mut journal = WriteJournal::default();
+    journal.push(
+        "POST",
+        "/incomes",
+        Some(serde_json::json!({
+            "income_type_id": 1,
+            "period": "Fixed/1st Period",
+            "projected": 100.0,
+            "amount": 0.0,
+            "month_id": 2,
+        })),
+    );
+
+    state.apply_queued_writes(journal.entries());
+
+    assert!(state.data.incomes.is_empty());
+    assert!(state.data.pending_sync.is_empty());
+}
+
+#[test]
+fn test_apply_queued_writes_clears_synced_markers() {
+    let mut state = pending_state();
+    let mut journal = WriteJournal::default();
+    journal.push("DELETE", "/expenses/2", None);
+    state.apply_queued_writes(journal.entries());
+    assert!(state.is_pending_sync(EntityType::Expense, 2));
+
+    state.apply_queued_writes(&[]);
+    assert!(!state.is_pending_sync(EntityType::Expense, 2));
+}
+
+#[test]
+fn test_fallback_summaries() {
+    let mut rent = merge_expense(1, 1);
+    rent.category = "Housing".to_string();
+    rent.cost = 120.0;
+    let expenses = vec![merge_expense(2, 1), rent, merge_expense(3, 1)];
+    let incomes = vec![Income {
+        id: 1,
+        income_type_id: 7,
+        period: "Fixed/1st Period".to_string(),
+        projected: 500.0,
+        amount: 450.0,
+        month_id: 1,
+        created_at: "2024-01-01".to_string(),
+        updated_at: "2024-01-01".to_string(),
+        created_by: None,
+        updated_by: None,
+        account_id: None,
+        currency: None,
+    }];
+    let periods = vec![
+        Period {
+            id: 1,
+            name: "Fixed/1st Period".to_string(),
+            color: "#ffffff".to_string(),
+        },
+        Period {
+            id: 2,
+            name: "Variable".to_string(),
+            color: "#000000".to_string(),
+        },
+    ];
+
+    let categories = fallback::category_summary(&expenses);
+    let names: Vec<&str> = categories.iter().map(|c| c.category.as_str()).collect();
+    assert_eq!(names, vec!["Food", "Housing"]);
+    assert_eq!(
+        (categories[0].projected, categories[0].total),
+        (200.0, 180.0)
+    );
+    assert!(categories[1].over_projected);
+
+    // The income type isn't loaded
+    let income_types = fallback::income_type_summary(&incomes, &[]);
+    assert_eq!(income_types[0].income_type, "Unknown");
+    assert_eq!(income_types[0].total, 450.0);
+
+    let totals = fallback::summary_totals(&expenses, &incomes);
+    assert_eq!(totals.total_current_expenses, 300.0);
+    assert_eq!(totals.total_current, 150.0);
+
+    let by_period = fallback::period_summary(&expenses, &incomes, &periods);
+    assert_eq!(by_period.periods.len(), 2);
+    assert_eq!(by_period.periods[0].difference, 150.0);
+    assert_eq!(by_period.periods[1].total_expenses, 0.0);
+    assert_eq!(by_period.grand_total_expenses, 300.0);
+}
+
+#[test]
+fn test_money_shorthand() {
+    assert_eq!(money_input::parse_money("45", 0.0), Some(45.0));
+    assert_eq!(money_input::parse_money("1.2k", 0.0), Some(1200.0));
+    assert_eq!(money_input::parse_money("2K", 0.0), Some(2000.0));
+    assert_eq!(money_input::parse_money(".5", 0.0), Some(0.5));
+    assert_eq!(money_input::parse_money("12.345", 0.0), Some(12.35));
+    assert_eq!(money_input::parse_money("1.2345k", 0.0), Some(1234.5));
+    assert_eq!(money_input::parse_money("+0.1", 0.2), Some(0.3));
+    assert_eq!(money_input::parse_money("+10", 90.0), Some(100.0));
+    assert_eq!(money_input::parse_money("-0.5k", 800.0), Some(300.0));
+    assert_eq!(money_input::parse_money("+10", 0.0), Some(10.0));
+
+    assert_eq!(money_input::parse_money("-10", 5.0), None);
+    assert_eq!(money_input::parse_money("", 0.0), None);
+    assert_eq!(money_input::parse_money(".", 0.0), None);
+    assert_eq!(money_input::parse_money("k", 0.0), None);
+    assert_eq!(money_input::parse_money("inf", 0.0), None);
+    assert_eq!(money_input::parse_money("1e3", 0.0), None);
+}
+
+#[test]
+fn test_money_input_accepts_char() {
+    assert!(money_input::accepts_char("", '+'));
+    assert!(!money_input::accepts_char("1", '+'));
+    assert!(money_input::accepts_char("1.2", 'k'));
+    assert!(!money_input::accepts_char("+", 'k'));
+    assert!(!money_input::accepts_char("1k", '0'));
+    assert!(!money_input::accepts_char("1.2", '.'));
+    assert!(!money_input::accepts_char("1", 'x'));
+}
+
+#[test]
+fn test_forms_read_money_shorthand() {
+    let mut expense = merge_expense(1, 1);
+    expense.projected = 90.0;
+    let mut form = ExpenseFormState::from_expense(&expense);
+    form.projected = "+10".to_string();
+    assert_eq!(form.to_update().unwrap().projected, Some(100.0));
+
+    form.tidy_amounts();
+    assert_eq!(form.projected, "100.00");
+    form.add_purchase();
+    form.purchase_amount_inputs[0] = "1.2k".to_string();
+    assert_eq!(form.calculated_cost(), 1200.0);
+
+    let mut form = IncomeFormState {
+        income_type_id: Some(1),
+        period: "Monthly".to_string(),
+        projected: "3k".to_string(),
+        amount: ".5".to_string(),
+        ..Default::default()
+    };
+    assert!(form.validate().is_empty());
+    let create = form.to_create(1).unwrap();
+    assert_eq!((create.projected, create.amount), (3000.0, 0.5));
+
+    form.amount = "-1".to_string();
+    assert_eq!(form.validate(), vec!["Amount must be a valid number"]);
+}
+
+#[test]
+fn test_forms_update_only_changed_fields() {
+    let mut expense = merge_expense(1, 1);
+    expense.notes = Some("Paid by card".to_string());
+    expense.purchases = Some(vec![Purchase {
+        name: "Rent".to_string(),
+        amount: 90.0,
+        date: None,
+    }]);
+    let mut form = ExpenseFormState::from_expense(&expense);
+    assert!(form.to_update().unwrap().is_empty());
+
+    form.projected = "120".to_string();
+    let update = form.to_update().unwrap();
+    assert_eq!(update.projected, Some(120.0));
+    // Notes and purchases stay as they are on the server
+    assert_eq!(update.notes, None);
+    assert_eq!(update.purchases, None);
+    assert_eq!(update.cost, None);
+
+    form.purchase_amount_inputs[0] = "95".to_string();
+    let update = form.to_update().unwrap();
+    assert_eq!(update.cost, Some(95.0));
+    assert_eq!(update.purchases.map(|p| p[0].amount), Some(95.0));
+
+    let income = Income {
+        id: 1,
+        income_type_id: 2,
+        period: "Monthly".to_string(),
+        projected: 3000.0,
+        amount: 0.0,
+        month_id: 1,
+        created_at: String::new(),
+        updated_at: String::new(),
+        created_by: None,
+        updated_by: None,
+        account_id: None,
+        currency: None,
+    };
+    let mut form = IncomeFormState::from_income(&income);
+    assert!(form.to_update().unwrap().is_empty());
+    form.amount = "3000".to_string();
+    let update = form.to_update().unwrap();
+    assert_eq!(update.amount, Some(3000.0));
+    assert_eq!((update.income_type_id, update.projected), (None, None));
+}
+
+#[test]
+fn test_forms_cost_follows_purchases() {
+    let mut expense = merge_expense(1, 1);
+    expense.purchases = Some(vec![Purchase {
+        name: "Groceries".to_string(),
+        amount: 90.0,
+        date: Some("2024-03-02".to_string()),
+    }]);
+    let mut form = ExpenseFormState::from_expense(&expense);
+    assert!(form.sum_purchases);
+    form.add_purchase();
+    form.purchase_amount_inputs[1] = "10".to_string();
+    assert_eq!(form.to_update().unwrap().cost, Some(100.0));
+
+    // Paid in one go: purchases added later don't change the cost
+    expense.purchases = None;
+    let mut form = ExpenseFormState::from_expense(&expense);
+    assert!(!form.sum_purchases);
+    form.add_purchase();
+    form.purchase_amount_inputs[0] = "25".to_string();
+    let update = form.to_update().unwrap();
+    assert_eq!(update.cost, None);
+    assert_eq!(update.purchases.map(|p| p[0].amount), Some(25.0));
+
+    form.sum_purchases = true;
+    assert_eq!(form.to_update().unwrap().cost, Some(25.0));
+    assert!(forms::sums_purchases(0.0, None));
+}
+
+#[test]
+fn test_forms_show_field_errors() {
+    let errors = vec![
+        FieldError {
+            field: None,
+            message: "Check the form".to_string(),
+        },
+        FieldError {
+            field: Some("budget".to_string()),
+            message: "Expected number".to_string(),
+        },
+    ];
+
+    let mut form = ExpenseFormState::default();
+    assert!(form.show_field_errors(&errors));
+    assert_eq!(form.focused_field, ExpenseField::Projected);
+    assert_eq!(
+        form.invalid,
+        Some((ExpenseField::Projected, "Expected number".to_string()))
+    );
+
+    let mut form = IncomeFormState::default();
+    assert!(form.show_field_errors(&errors));
+    assert_eq!(form.focused_field, IncomeField::Projected);
+    assert!(!form.show_field_errors(&errors[..1]));
+    assert_eq!(
+        IncomeField::from_api_field("amount"),
+        Some(IncomeField::Amount)
+    );
+    assert_eq!(ExpenseField::from_api_field("month_id"), None);
+}
+
+#[test]
+fn test_remove_missing() {
+    let mut state = AppState::default();
+    state.data.expenses = vec![merge_expense(1, 1), merge_expense(2, 1)];
+    state.remove_missing(EntityType::Expense, 1);
+    state.remove_missing(EntityType::Income, 2);
+
+    let ids: Vec<i32> = state.data.expenses.iter().map(|e| e.id).collect();
+    assert_eq!(ids, vec![2]);
+}
+
+fn view_state() -> AppState {
+    let mut state = AppState::default();
+    state.ui.selected_tab = DashboardTab::Expenses;
+    let mut rent = merge_expense(1, 1);
+    rent.expense_name = "Rent".to_string();
+    rent.category = "Housing".to_string();
+    rent.projected = 1000.0;
+    let mut apples = merge_expense(2, 1);
+    apples.expense_name = "apples".to_string();
+    apples.projected = 20.0;
+    let mut bread = merge_expense(3, 1);
+    bread.expense_name = "Bread".to_string();
+    bread.projected = 50.0;
+    state.data.expenses = vec![rent, apples, bread];
+    state
+}
+
+fn listed_ids(state: &AppState) -> Vec<i32> {
+    state.filtered_expenses().iter().map(|e| e.id).collect()
+}
+
+#[test]
+fn test_expenses_sorted_grouped_and_searched() {
+    let mut state = view_state();
+    assert_eq!(listed_ids(&state), vec![1, 2, 3]);
+
+    state.ui.sort = Some(SortKey::Name);
+    assert_eq!(listed_ids(&state), vec![2, 3, 1]);
+    state.ui.sort = Some(SortKey::Projected);
+    assert_eq!(listed_ids(&state), vec![1, 3, 2]);
+
+    state.ui.group = Some(GroupKey::Category);
+    assert_eq!(listed_ids(&state), vec![3, 2, 1]);
+
+    state.ui.search = "READ".to_string();
+    assert_eq!(listed_ids(&state), vec![3]);
+    state.ui.search = "housing".to_string();
+    assert_eq!(listed_ids(&state), vec![1]);
+}
+
+#[test]
+fn test_view_chips() {
+    let mut state = view_state();
+    assert!(state.view_chips().is_empty());
+
+    state.ui.category_filter = Some("Food".to_string());
+    state.ui.search = "br".to_string();
+    state.ui.sort = Some(SortKey::Actual);
+    state.ui.group = Some(GroupKey::Category);
+    assert_eq!(
+        state.view_chips(),
+        vec![
+            ViewChip::Category("Food".to_string()),
+            ViewChip::Search("br".to_string()),
+            ViewChip::Group(GroupKey::Category),
+            ViewChip::Sort(SortKey::Actual),
+        ]
+    );
+    assert_eq!(state.view_chips()[1].label(), "Search: \"br\"");
+
+    // Incomes have no categories
+    state.ui.selected_tab = DashboardTab::Income;
+    assert_eq!(state.view_chips().len(), 2);
+    state.ui.selected_tab = DashboardTab::Expenses;
+
+    let removed = state.remove_view_chip(1).unwrap();
+    assert!(!removed.is_server_filter());
+    assert!(state.ui.search.is_empty());
+    assert!(state.remove_view_chip(0).unwrap().is_server_filter());
+    assert_eq!(state.ui.category_filter, None);
+    assert_eq!(state.view_chips().len(), 2);
+    assert_eq!(state.remove_view_chip(5), None);
+
+    state.ui.selected_tab = DashboardTab::Summary;
+    assert!(state.view_chips().is_empty());
+}
+
+#[test]
+fn test_accounts_filter_and_forms() {
+    let mut state = view_state();
+    state.data.accounts = vec![
+        Account {
+            id: 7,
+            name: "Checking".to_string(),
+            kind: AccountKind::Checking,
+        },
+        Account {
+            id: 8,
+            name: "Visa".to_string(),
+            kind: AccountKind::CreditCard,
+        },
+    ];
+    state.data.expenses[1].account_id = Some(8);
+    state.data.expenses[2].account_id = Some(8);
+
+    // No account between the last and the first
+    let accounts = &state.data.accounts;
+    assert_eq!(forms::step_account(accounts, None, true), Some(7));
+    assert_eq!(forms::step_account(accounts, Some(8), true), None);
+    assert_eq!(forms::step_account(accounts, None, false), Some(8));
+    assert_eq!(forms::step_account(&[], None, true), None);
+
+    state.ui.account_filter = Some(8);
+    assert_eq!(listed_ids(&state), vec![2, 3]);
+    assert_eq!(
+        state.view_chips(),
+        vec![ViewChip::Account("Visa".to_string())]
+    );
+    assert!(!state.view_chips()[0].is_server_filter());
+    state.remove_view_chip(0);
+    assert_eq!(listed_ids(&state), vec![1, 2, 3]);
+
+    // Only a changed account is sent; clearing it sends null
+    let mut form = ExpenseFormState::from_expense(&state.data.expenses[1]);
+    assert_eq!(form.to_update().unwrap().account_id, None);
+    form.account_id = None;
+    assert_eq!(form.to_update().unwrap().account_id, Some(None));
+    form.account_id = Some(7);
+    assert_eq!(form.to_create(1).unwrap().account_id, Some(7));
+    assert_eq!(
+        ExpenseField::from_api_field("account_id"),
+        Some(ExpenseField::Account)
+    );
+
+    state.jump_to_entity(EntityType::Account, 8);
+    assert_eq!(state.ui.settings_tab, SettingsTab::Accounts);
+    assert_eq!(
+        state.selected_settings_entity(),
+        Some((EntityType::Account, 8, "Visa".to_string()))
+    );
+}
+
+#[test]
+fn test_transfer_form() {
+    let accounts = vec![
+        Account {
+            id: 7,
+            name: "Checking".to_string(),
+            kind: AccountKind::Checking,
+        },
+        Account {
+            id: 8,
+            name: "Savings".to_string(),
+            kind: AccountKind::Checking,
+        },
+    ];
+
+    // Out of the selected account, into the first other one
+    let mut form = TransferFormState::new(&accounts, Some(8));
+    assert_eq!(form.to_account_id, Some(7));
+    assert_eq!(form.focused, TransferField::From);
+    assert_eq!(form.focused.previous(), TransferField::Notes);
+    assert_eq!(form.validate(), vec!["Amount must be a number"]);
+    assert_eq!(form.to_create(1), None);
+
+    form.amount = "1.2k".to_string();
+    form.to_account_id = Some(8);
+    assert_eq!(
+        form.validate(),
+        vec!["From and to must be different accounts"]
+    );
+
+    form.to_account_id = Some(7);
+    form.notes = "  ".to_string();
+    let create = form.to_create(3).unwrap();
+    assert_eq!(
+        (
+            create.from_account_id,
+            create.to_account_id,
+            create.month_id
+        ),
+        (8, 7, 3)
+    );
+    assert_eq!(create.amount, 1200.0);
+    assert_eq!(create.notes, None);
+
+    form.amount = "0".to_string();
+    assert_eq!(form.validate(), vec!["Amount must be more than zero"]);
+}
+
+#[test]
+fn test_debt_payoff() {
+    let debt = Debt {
+        id: 1,
+        name: "Car loan".to_string(),
+        principal: 1000.0,
+        interest_rate: 12.0,
+        minimum_payment: 100.0,
+        category: "Loans".to_string(),
+        period: "Monthly".to_string(),
+    };
+
+    // 1% a month on what is owed, the payment after
+    assert_eq!(debts::monthly_interest(1000.0, 12.0), 10.0);
+    assert_eq!(debts::after_payment(&debt, 100.0), 910.0);
+    assert_eq!(debts::after_payment(&debt, 5000.0), 0.0);
+
+    let payoff = debts::payoff(&debt, 100.0).unwrap();
+    assert_eq!(payoff.months(), 11);
+    assert_eq!(payoff.balances[0], 910.0);
+    assert_eq!(payoff.balances.last(), Some(&0.0));
+    assert_eq!(payoff.interest, 58.98);
+
+    // Paying no more than the interest never ends
+    assert_eq!(debts::payoff(&debt, 10.0), None);
+
+    let expense = debts::payment_expense(&debt, 150.0, 4);
+    assert_eq!(expense.expense_name, "Car loan");
+    assert_eq!(
+        (expense.category.as_str(), expense.period.as_str()),
+        ("Loans", "Monthly")
+    );
+    assert_eq!(
+        (expense.projected, expense.cost, expense.month_id),
+        (100.0, 150.0, 4)
+    );
+
+    let mut form = DebtFormState::from_debt(&debt);
+    assert_eq!(form.to_update().unwrap().principal, 1000.0);
+    form.interest_rate = "120".to_string();
+    assert_eq!(
+        form.validate(),
+        vec!["Interest must be a percentage from 0 to 100"]
+    );
+
+    let categories = vec![
+        Category {
+            id: 1,
+            name: "Loans".to_string(),
+            color: "#000000".to_string(),
+        },
+        Category {
+            id: 2,
+            name: "Cards".to_string(),
+            color: "#000000".to_string(),
+        },
+    ];
+    form.focused = DebtField::Category;
+    form.step_choice(&categories, &[], true);
+    assert_eq!(form.category, "Cards");
+    form.step_choice(&categories, &[], true);
+    assert_eq!(form.category, "Loans");
+}
+
+#[test]
+fn test_currency_conversion() {
+    let config = CurrencyConfig {
+        base: "usd".to_string(),
+        rates: [("eur".to_string(), 1.1), ("GBP".to_string(), -1.0)]
+            .into_iter()
+            .collect(),
+        ..Default::default()
+    };
+    let mut rates = Rates::from_config(&config);
+    // Codes are read in capitals; a rate that can't convert is dropped
+    assert_eq!(rates.base(), "USD");
+    assert_eq!(rates.codes(), vec!["EUR"]);
+    assert_eq!(rates.convert(10.0, Some("eur")), Some(11.0));
+    assert_eq!(rates.convert(10.0, Some("USD")), Some(10.0));
+    assert_eq!(rates.convert(10.0, Some("JPY")), None);
+    rates.extend([("jpy", 0.0067)]);
+    assert_eq!(rates.convert(1000.0, Some("JPY")), Some(6.7));
+
+    let mut hotel = merge_expense(1, 1);
+    hotel.currency = Some("EUR".to_string());
+    let mut train = merge_expense(2, 1);
+    train.currency = Some("CHF".to_string());
+    let groceries = merge_expense(3, 1);
+    assert!(currency::converted_totals(&[groceries.clone()], &[], &rates).is_none());
+
+    // 90 EUR is 99 USD; CHF has no rate, so it is left out
+    let converted =
+        currency::converted_totals(&[hotel.clone(), train, groceries], &[], &rates).unwrap();
+    assert_eq!(converted.currencies, vec!["CHF", "EUR"]);
+    assert_eq!(converted.missing, vec!["CHF"]);
+    assert_eq!(converted.totals.total_current_expenses, 189.0);
+    assert_eq!(converted.totals.total_projected_expenses, 210.0);
+    assert_eq!(converted.totals.total_current, -189.0);
+
+    let mut state = AppState::default();
+    assert!(!state.may_mix_currencies());
+    state.data.expenses = vec![hotel];
+    assert!(state.may_mix_currencies());
+
+    // The budget's own currency between the last and the first
+    let codes = rates.codes();
+    assert_eq!(
+        forms::step_currency(&codes, None, true),
+        Some("EUR".to_string())
+    );
+    assert_eq!(forms::step_currency(&codes, Some("JPY"), true), None);
+    assert_eq!(
+        forms::step_currency(&codes, None, false),
+        Some("JPY".to_string())
+    );
+
+    let mut form = ExpenseFormState::from_expense(&merge_expense(4, 1));
+    assert_eq!(form.to_update().unwrap().currency, None);
+    form.currency = Some("EUR".to_string());
+    assert_eq!(
+        form.to_update().unwrap().currency,
+        Some(Some("EUR".to_string()))
+    );
+    assert_eq!(form.to_create(1).unwrap().currency.as_deref(), Some("EUR"));
+    assert_eq!(
+        ExpenseField::from_api_field("currency"),
+        Some(ExpenseField::Currency)
+    );
+}
+
+#[test]
+fn test_audit_trail() {
+    let mut expense = merge_expense(1, 1);
+    expense.created_at = Some("2024-03-01T14:05:12.345678".to_string());
+    expense.created_by = Some("ana".to_string());
+    expense.updated_at = Some("2024-03-04T09:12:00".to_string());
+    expense.updated_by = Some("ben".to_string());
+    let audit = AuditTrail::from(&expense);
+    assert!(audit.was_updated());
+    assert_eq!(
+        audit.lines(DateFormat::Dmy),
+        vec![
+            ("Created".to_string(), "01/03/2024 14:05 by ana".to_string()),
+            ("Updated".to_string(), "04/03/2024 09:12 by ben".to_string()),
+        ]
+    );
+
+    // Stamped once at creation means never changed
+    expense.updated_at = expense.created_at.clone();
+    expense.updated_by = None;
+    assert!(!AuditTrail::from(&expense).was_updated());
+    assert_eq!(
+        AuditTrail::from(&expense).lines(DateFormat::Iso)[1].1,
+        "Never"
+    );
+
+    // Incomes queued offline have blank stamps
+    let income = Income {
+        id: -1,
+        income_type_id: 7,
+        period: "Fixed/1st Period".to_string(),
+        projected: 500.0,
+        amount: 0.0,
+        month_id: 1,
+        created_at: String::new(),
+        updated_at: String::new(),
+        created_by: None,
+        updated_by: None,
+        account_id: None,
+        currency: None,
+    };
+    let audit = AuditTrail::from(&income);
+    assert_eq!(audit, AuditTrail::default());
+    assert_eq!(audit.lines(DateFormat::Iso)[0].1, "Unknown");
+}
+
+#[test]
+fn test_selected_entry_row() {
+    let mut state = AppState::default();
+    state.ui.selected_tab = DashboardTab::Expenses;
+    state.data.expenses = vec![merge_expense(1, 1), merge_expense(2, 1)];
+    state.ui.expense_table.select(Some(1));
+    assert_eq!(state.selected_entry_row(), Some(1));
+
+    // A selection kept from a fuller month points at no row, so `i` imports
+    state.data.expenses.clear();
+    assert_eq!(state.selected_entry_row(), None);
+    state.ui.expense_table.select(Some(0));
+    assert_eq!(state.selected_entry_row(), None);
+
+    state.ui.selected_tab = DashboardTab::Summary;
+    state.data.expenses = vec![merge_expense(1, 1)];
+    assert_eq!(state.selected_entry_row(), None);
+}
+
+#[test]
+fn test_view_cycles() {
+    assert_eq!(SortKey::cycle(None), Some(SortKey::Name));
+    assert_eq!(SortKey::cycle(Some(SortKey::Actual)), None);
+    assert_eq!(
+        GroupKey::cycle(None, DashboardTab::Expenses),
+        Some(GroupKey::Category)
+    );
+    assert_eq!(
+        GroupKey::cycle(None, DashboardTab::Income),
+        Some(GroupKey::Period)
+    );
+
+    let options = vec!["A".to_string(), "B".to_string()];
+    assert_eq!(next_filter(&options, None), Some("A".to_string()));
+    assert_eq!(next_filter(&options, Some("A")), Some("B".to_string()));
+    assert_eq!(next_filter(&options, Some("B")), None);
+    assert_eq!(next_filter(&options, Some("Gone")), None);
+}
+
+#[test]
+fn test_weekly_envelopes() {
+    let date = |d: u32| chrono::NaiveDate::from_ymd_opt(2024, 2, d).unwrap();
+    // February 2024 starts on a Thursday
+    let weeks = envelopes::month_weeks(date(1), date(29), chrono::Weekday::Mon);
+    assert_eq!(
+        weeks,
+        vec![
+            (date(1), date(4)),
+            (date(5), date(11)),
+            (date(12), date(18)),
+            (date(19), date(25)),
+            (date(26), date(29)),
+        ]
+    );
+
+    let mut groceries = merge_expense(1, 2);
+    groceries.category = "Groceries".to_string();
+    groceries.projected = 290.0;
+    groceries.expense_date = Some("2024-02-20".to_string());
+    groceries.purchases = Some(vec![
+        Purchase {
+            name: "Market".to_string(),
+            amount: 30.0,
+            date: Some("2024-02-03T10:00:00".to_string()),
+        },
+        Purchase {
+            name: "Bakery".to_string(),
+            amount: 12.5,
+            date: None,
+        },
+    ]);
+    let mut snacks = groceries.clone();
+    snacks.id = 2;
+    snacks.projected = 0.0;
+    snacks.purchases = None;
+    snacks.expense_date = None;
+    snacks.cost = 8.0;
+
+    let mut state = AppState::default();
+    state.data.months = vec![Month {
+        start_date: "2024-02-01".to_string(),
+        end_date: "2024-02-29".to_string(),
+        ..merge_month(2, false)
+    }];
+    state.data.expenses = vec![groceries, snacks, merge_expense(3, 2)];
+    assert!(state.weekly_envelopes().is_empty());
+
+    state.envelope_categories = vec!["groceries".to_string(), "Missing".to_string()];
+    let envelopes = state.weekly_envelopes();
+    assert_eq!(envelopes.len(), 1);
+    let groceries = &envelopes[0];
+    assert_eq!(groceries.category, "Groceries");
+    // 290 over 29 days, by days per week
+    let budgets: Vec<f64> = groceries.weeks.iter().map(|w| w.budget).collect();
+    assert_eq!(budgets, vec![40.0, 70.0, 70.0, 70.0, 40.0]);
+    // Undated purchases fall back to the expense's date
+    let spent: Vec<f64> = groceries.weeks.iter().map(|w| w.spent).collect();
+    assert_eq!(spent, vec![30.0, 0.0, 0.0, 12.5, 0.0]);
+    assert_eq!(groceries.undated, 8.0);
+    assert_eq!(groceries.weeks[1].label(), "5-11");
+}
+
+#[test]
+fn test_action_history() {
+    let at = chrono::NaiveTime::from_hms_opt(9, 30, 0).unwrap();
+    let mut history = ActionHistory::default();
+    assert!(history.last().is_none());
+
+    for i in 0..HISTORY_LEN + 5 {
+        history.record(
+            Action::PeriodFilter(format!("Period {}", i)),
+            format!("{}", i),
+            at,
+        );
+    }
+    assert_eq!(history.len(), HISTORY_LEN);
+    // Newest first, the oldest ones dropped
+    assert_eq!(
+        history.last().unwrap().label,
+        format!("{}", HISTORY_LEN + 4)
+    );
+    assert_eq!(history.get(HISTORY_LEN - 1).unwrap().label, "5");
+    assert!(history.get(HISTORY_LEN).is_none());
+
+    let create = ExpenseCreate {
+        expense_name: "Coffee".to_string(),
+        period: "Fixed/1st Period".to_string(),
+        category: "Food".to_string(),
+        projected: 4.5,
+        cost: 4.5,
+        notes: Some("Corner shop".to_string()),
+        month_id: 1,
+        purchases: None,
+        expense_date: None,
+        account_id: None,
+        currency: None,
+        custom_fields: CustomFields::new(),
+    };
+    history.record(Action::CreateExpense(create), "Created expense Coffee", at);
+    let Action::CreateExpense(create) = &history.last().unwrap().action else {
+        panic!("expected a created expense");
+    };
+    // Repeating fills the form for whichever month is selected then
+    let form = ExpenseFormState::from_create(create);
+    assert_eq!(form.name, "Coffee");
+    assert_eq!(form.notes, "Corner shop");
+    assert_eq!(form.editing_id, None);
+    assert_eq!(form.to_create(7).unwrap().month_id, 7);
+}
+
+#[test]
+fn test_period_display() {
+    let mut state = AppState::default();
+    state.period_display.order = vec!["Variable/2nd Period".to_string()];
+    state
+        .period_display
+        .labels
+        .insert("Fixed/1st Period".to_string(), "Quincena 1".to_string());
+    state.set_periods(
+        serde_json::from_value(serde_json::json!([
+            {"id": 1, "name": "Fixed/1st Period", "color": "#3b82f6"},
+            {"id": 2, "name": "Variable/2nd Period", "color": "#ec4899"}
+        ]))
+        .unwrap(),
+    );
+
+    // Selectors and filters cycle through this list
+    let names: Vec<&str> = state.data.periods.iter().map(|p| p.name.as_str()).collect();
+    assert_eq!(names, vec!["Variable/2nd Period", "Fixed/1st Period"]);
+
+    state.ui.selected_tab = DashboardTab::Expenses;
+    state.ui.period_filter = Some("Fixed/1st Period".to_string());
+    assert_eq!(state.view_chips()[0].label(), "Period: Quincena 1");
+
+    // Weeks start on the configured day
+    let date = |d: u32| chrono::NaiveDate::from_ymd_opt(2024, 2, d).unwrap();
+    let weeks = envelopes::month_weeks(date(1), date(10), chrono::Weekday::Sun);
+    assert_eq!(weeks, vec![(date(1), date(3)), (date(4), date(10))]);
+}
+
+#[test]
+fn test_empty_list() {
+    let mut state = AppState::default();
+    state.ui.selected_tab = DashboardTab::Expenses;
+    assert_eq!(state.empty_list(), Some(EmptyList::NoMonth));
+
+    state.data.months = vec![merge_month(1, false), merge_month(2, false)];
+    state.ui.selected_month_index = 1;
+    assert_eq!(state.empty_list(), Some(EmptyList::EmptyMonth));
+    assert!(state.month_is_empty());
+    assert_eq!(state.previous_of_selected().map(|m| m.id), Some(1));
+
+    // A filter hides what's loaded, so the month may not be empty
+    state.ui.period_filter = Some("Fixed/1st Period".to_string());
+    assert_eq!(state.empty_list(), Some(EmptyList::Filtered));
+    assert!(!state.month_is_empty());
+    state.ui.period_filter = None;
+
+    state.data.incomes = vec![serde_json::from_value(serde_json::json!({
+        "id": 1, "income_type_id": 1, "period": "Fixed/1st Period",
+        "projected": 100.0, "amount": 0.0, "month_id": 2,
+        "created_at": "2024-02-01T09:00:00", "updated_at": "2024-02-01T09:00:00",
+        "created_by": null, "updated_by": null
+    }))
+    .unwrap()];
+    assert_eq!(state.empty_list(), Some(EmptyList::NoneYet));
+    state.ui.selected_tab = DashboardTab::Income;
+    assert_eq!(state.empty_list(), None);
+    state.ui.selected_tab = DashboardTab::Summary;
+    assert_eq!(state.empty_list(), None);
+
+    state.ui.selected_month_index = 0;
+    assert!(state.previous_of_selected().is_none());
+}
+
+#[test]
+fn test_clock_drives_pace() {
+    let date = |d: u32| chrono::NaiveDate::from_ymd_opt(2024, 1, d).unwrap();
+    let clock = Clock::parse("2024-01-16T17:30").unwrap();
+    assert!(clock.is_frozen());
+    assert_eq!(clock.today(), date(16));
+    assert_eq!(
+        clock.now().time(),
+        chrono::NaiveTime::from_hms_opt(17, 30, 0).unwrap()
+    );
+    assert_eq!(
+        Clock::parse(" 2024-01-16 "),
+        Some(Clock::frozen_on(date(16)))
+    );
+    assert_eq!(Clock::parse("next tuesday"), None);
+    assert!(!Clock::system().is_frozen());
+
+    let mut state = AppState {
+        clock,
+        ..Default::default()
+    };
+    state.data.months = vec![merge_month(1, false)];
+    assert_eq!(state.month_pace(), Some(16.0 / 31.0));
+
+    // Once the month is over there's no pace to keep
+    state.clock = Clock::frozen_on(chrono::NaiveDate::from_ymd_opt(2024, 2, 2).unwrap());
+    assert_eq!(state.month_pace(), None);
+}
+
+#[test]
+fn test_apply_startup() {
+    let mut state = AppState {
+        clock: Clock::frozen_on(chrono::NaiveDate::from_ymd_opt(2024, 2, 10).unwrap()),
+        ..Default::default()
+    };
+    state.data.months = vec![
+        merge_month(1, false),
+        merge_month(2, false),
+        merge_month(3, false),
+    ];
+    state.data.periods = serde_json::from_value(serde_json::json!([
+        {"id": 1, "name": "Fixed/1st Period", "color": "#3b82f6"}
+    ]))
+    .unwrap();
+    state.data.categories = serde_json::from_value(serde_json::json!([
+        {"id": 1, "name": "Food", "color": "#22c55e"}
+    ]))
+    .unwrap();
+
+    let problems = state.apply_startup(&StartupConfig {
+        month: StartupMonth::Calendar,
+        period: Some(" fixed/1st period".to_string()),
+        category: Some("Travel".to_string()),
+        ..Default::default()
+    });
+    assert_eq!(state.ui.selected_month_index, 1);
+    assert_eq!(state.ui.period_filter.as_deref(), Some("Fixed/1st Period"));
+    assert_eq!(state.ui.category_filter, None);
+    assert_eq!(problems, vec!["No category named 'Travel'".to_string()]);
+
+    let problems = state.apply_startup(&StartupConfig {
+        month: StartupMonth::Latest,
+        ..Default::default()
+    });
+    assert!(problems.is_empty());
+    assert_eq!(state.ui.selected_month_index, 2);
+
+    // The calendar month may not exist yet
+    state.clock = Clock::frozen_on(chrono::NaiveDate::from_ymd_opt(2024, 6, 1).unwrap());
+    let problems = state.apply_startup(&StartupConfig {
+        month: StartupMonth::Calendar,
+        ..Default::default()
+    });
+    assert_eq!(problems, vec!["No month 2024-06 yet".to_string()]);
+    assert_eq!(state.ui.selected_month_index, 2);
+}
+
+#[test]
+fn test_restore_view() {
+    let with_data = || {
+        let mut state = AppState::default();
+        state.data.months = vec![
+            merge_month(1, false),
+            merge_month(2, false),
+            merge_month(3, false),
+        ];
+        state.data.periods = serde_json::from_value(serde_json::json!([
+            {"id": 1, "name": "Fixed/1st Period", "color": "#3b82f6"}
+        ]))
+        .unwrap();
+        state
+    };
+
+    let mut state = with_data();
+    state.ui.selected_month_index = 1;
+    state.ui.selected_tab = DashboardTab::Expenses;
+    state.ui.period_filter = Some("Fixed/1st Period".to_string());
+    let view = state.last_view();
+    assert_eq!(view.tab.as_deref(), Some("Expenses"));
+    assert_eq!(view.month.as_deref(), Some("2024-02"));
+    assert_eq!(view.category, None);
+
+    let mut restored = with_data();
+    restored.restore_view(&view);
+    assert_eq!(restored.ui.selected_tab, DashboardTab::Expenses);
+    assert_eq!(restored.ui.selected_month_index, 1);
+    assert_eq!(
+        restored.ui.period_filter.as_deref(),
+        Some("Fixed/1st Period")
+    );
+
+    // What the server no longer has is left out
+    let mut gone = with_data();
+    gone.restore_view(&LastView {
+        month: Some("2023-07".to_string()),
+        category: Some("Travel".to_string()),
+        ..view
+    });
+    assert_eq!(gone.ui.selected_month_index, 0);
+    assert_eq!(gone.ui.category_filter, None);
+}
+
+#[test]
+fn test_end_of_month_suggestions() {
+    let date = |d: u32| chrono::NaiveDate::from_ymd_opt(2024, 1, d).unwrap();
+    let mut unpaid = merge_expense(1, 1);
+    unpaid.expense_name = "Rent".to_string();
+    unpaid.cost = 0.0;
+
+    let mut state = AppState::default();
+    state.data.months = vec![merge_month(1, false)];
+    state.data.expenses = vec![unpaid, merge_expense(2, 1)];
+    state.data.income_types = vec![serde_json::from_value(serde_json::json!({
+        "id": 1, "name": "Salary", "color": "#22c55e"
+    }))
+    .unwrap()];
+    state.data.incomes = vec![serde_json::from_value(serde_json::json!({
+        "id": 1, "income_type_id": 1, "period": "Fixed/1st Period",
+        "projected": 1000.0, "amount": 0.0, "month_id": 1,
+        "created_at": "2024-01-01T09:00:00", "updated_at": "2024-01-01T09:00:00",
+        "created_by": null, "updated_by": null
+    }))
+    .unwrap()];
+    state.data.category_summary = vec![
+        CategorySummary {
+            category: "Dining".to_string(),
+            projected: 400.0,
+            total: 120.0,
+            over_projected: false,
+        },
+        CategorySummary {
+            category: "Food".to_string(),
+            projected: 200.0,
+            total: 190.0,
+            over_projected: false,
+        },
+    ];
+
+    // Only in the last 5 days (the default)
+    assert!(state.end_of_month_suggestions(date(26)).is_empty());
+    assert_eq!(state.advisor_days_left(date(27)), Some(5));
+    assert_eq!(state.advisor_days_left(date(31)), Some(1));
+
+    let suggestions = state.end_of_month_suggestions(date(31));
+    let kinds: Vec<SuggestionKind> = suggestions.iter().map(|s| s.kind).collect();
+    assert_eq!(
+        kinds,
+        vec![
+            SuggestionKind::NotReceived,
+            SuggestionKind::NoCost,
+            SuggestionKind::UnderBudget,
+        ]
+    );
+    assert!(suggestions[0]
+        .message
+        .starts_with("Salary (Fixed/1st Period)"));
+    assert!(suggestions[1].message.starts_with("Rent"));
+    assert!(suggestions[2].message.starts_with("Dining used 30%"));
+
+    state.advisor.under_percent = 25.0;
+    assert_eq!(state.end_of_month_suggestions(date(31)).len(), 2);
+
+    // Nothing once the month is closed or the advisor is off
+    state.advisor.days = 0;
+    assert!(state.end_of_month_suggestions(date(31)).is_empty());
+    state.advisor.days = 5;
+    state.data.months[0].is_closed = true;
+    assert!(state.end_of_month_suggestions(date(31)).is_empty());
+}
+
+#[test]
+fn test_scope_denied() {
+    let mut state = AppState::default();
+    assert_eq!(state.scope_denied(Scope::Expenses), None);
+    assert!(!state.key_is_read_only());
+
+    state.data.key_scopes = Some(KeyScopes {
+        scopes: vec!["read".to_string(), "settings:write".to_string()],
+    });
+    assert_eq!(
+        state.scope_denied(Scope::Expenses).as_deref(),
+        Some("This API key can't change expenses - it needs the expenses:write scope")
+    );
+    assert_eq!(state.scope_denied(Scope::Settings), None);
+
+    state.ui.selected_tab = DashboardTab::Income;
+    assert_eq!(state.edit_scope(), Some(Scope::Incomes));
+    state.ui.selected_tab = DashboardTab::Settings;
+    assert_eq!(state.edit_scope(), Some(Scope::Settings));
+    // Changing the password isn't up to the API key
+    state.ui.settings_tab = SettingsTab::Password;
+    assert_eq!(state.edit_scope(), None);
+    state.ui.selected_tab = DashboardTab::Summary;
+    assert_eq!(state.edit_scope(), None);
+}
+
+#[test]
+fn test_split_view() {
+    let mut state = AppState::default();
+    state.data.months = vec![merge_month(1, true), merge_month(2, false)];
+    state.ui.selected_month_index = 0;
+    state.ui.period_filter = Some("Fixed/1st Period".to_string());
+    state.data.expenses = vec![merge_expense(1, 1)];
+
+    assert!(state.open_split());
+    let split = state.ui.split.as_ref().unwrap();
+    assert_eq!(split.focus, Pane::Left);
+    assert_eq!(state.split_month().map(|m| m.id), Some(2));
+
+    // A closed month can't give or take expenses
+    assert!(state.move_to_split().unwrap_err().contains("is closed"));
+
+    // Focus swaps the month, list and filters; the other pane keeps its own
+    state.switch_split_focus();
+    assert_eq!(state.ui.selected_month_index, 1);
+    assert_eq!(state.ui.period_filter, None);
+    assert!(state.data.expenses.is_empty());
+    let split = state.ui.split.as_ref().unwrap();
+    assert_eq!(split.focus, Pane::Right);
+    assert_eq!(split.other.month_index, 0);
+    assert_eq!(
+        split.other.period_filter.as_deref(),
+        Some("Fixed/1st Period")
+    );
+    assert_eq!(split.other.expenses.len(), 1);
+
+    state.data.months[0].is_closed = false;
+    let update = state.move_to_split().unwrap();
+    assert_eq!(update.month_id, Some(1));
+    let copy = state.copy_to_split(&merge_expense(5, 2)).unwrap();
+    assert_eq!(copy.month_id, 1);
+    assert_eq!(copy.projected, 100.0);
+    assert_eq!(copy.cost, 0.0);
+
+    // Both panes on one month
+    state.ui.split.as_mut().unwrap().other.month_index = 1;
+    assert_eq!(
+        state.move_to_split().unwrap_err(),
+        "Both panes show February 2024"
+    );
+
+    state.close_split();
+    assert!(state.move_to_split().is_err());
+
+    // A single month has nothing to show beside it
+    state.data.months.truncate(1);
+    state.ui.selected_month_index = 0;
+    assert!(!state.open_split());
+}
+
+fn spent(month_id: i32, category: &str, cost: f64) -> Expense {
+    Expense {
+        category: category.to_string(),
+        cost,
+        ..merge_expense(month_id * 10, month_id)
+    }
+}
+
+#[test]
+fn test_autofill_preview() {
+    assert_eq!(round_to(103.33, 10.0), 100.0);
+    assert_eq!(round_to(103.336, 0.0), 103.34);
+
+    let history: Vec<MonthData> = [
+        vec![spent(1, "Food", 90.0), spent(1, "Rent", 1000.0)],
+        vec![spent(2, "Food", 120.0)],
+        vec![spent(3, "Food", 100.0), spent(3, "Travel", 0.0)],
+    ]
+    .into_iter()
+    .enumerate()
+    .map(|(i, expenses)| MonthData {
+        month: merge_month(i as i32 + 1, true),
+        expenses,
+        incomes: Vec::new(),
+        totals: None,
+        category_summary: None,
+    })
+    .collect();
+    let month = merge_month(4, false);
+    let expenses = vec![
+        Expense {
+            projected: 30.0,
+            ..merge_expense(41, 4)
+        },
+        Expense {
+            projected: 10.0,
+            ..merge_expense(42, 4)
+        },
+    ];
+
+    let preview = AutofillPreview::build(&month, &expenses, &history, 10.0);
+    assert_eq!(preview.months_used, 3);
+    // Nothing was spent on Travel, so it's left alone
+    let rows: Vec<(&str, f64, f64)> = preview
+        .rows
+        .iter()
+        .map(|row| (row.category.as_str(), row.current, row.budget))
+        .collect();
+    assert_eq!(rows, vec![("Food", 40.0, 100.0), ("Rent", 0.0, 330.0)]);
+    assert!(preview.has_changes());
+
+    // Food's expenses keep their shares; Rent gets an expense of its own
+    let changes = preview.changes(&expenses, "Fixed/1st Period");
+    let updates: Vec<(i32, Option<f64>)> = changes
+        .updates
+        .iter()
+        .map(|(id, update)| (*id, update.projected))
+        .collect();
+    assert_eq!(updates, vec![(41, Some(75.0)), (42, Some(25.0))]);
+    assert_eq!(changes.creates.len(), 1);
+    assert_eq!(changes.creates[0].expense_name, "Rent");
+    assert_eq!(changes.creates[0].projected, 330.0);
+    assert_eq!(changes.creates[0].month_id, 4);
+    assert_eq!(changes.creates[0].cost, 0.0);
+}
+
+#[test]
+fn test_reallocate_board() {
+    let month = merge_month(4, false);
+    let expenses = vec![
+        merge_expense(41, 4),
+        Expense {
+            cost: 20.0,
+            ..merge_expense(42, 4)
+        },
+        Expense {
+            category: "Rent".to_string(),
+            projected: 500.0,
+            cost: 500.0,
+            ..merge_expense(43, 4)
+        },
+    ];
+
+    let mut board = ReallocateBoard::build(&month, &expenses);
+    let rows: Vec<(&str, f64, f64)> = board
+        .rows
+        .iter()
+        .map(|row| (row.category.as_str(), row.budget, row.remaining()))
+        .collect();
+    assert_eq!(rows, vec![("Food", 200.0, 90.0), ("Rent", 500.0, 0.0)]);
+
+    // Rent has nothing left to give
+    board.select_next();
+    board.take();
+    assert_eq!(board.held, 0.0);
+
+    // Food gives all it has left, short of the 100 step
+    board.select_previous();
+    board.next_step();
+    board.next_step();
+    assert_eq!(board.step_amount(), 100.0);
+    board.take();
+    assert_eq!(board.held, 90.0);
+    assert!(!board.is_balanced());
+
+    board.select_next();
+    board.give();
+    assert!(board.is_balanced());
+    assert_eq!(board.rows[0].budget, 110.0);
+    assert_eq!(board.rows[1].budget, 590.0);
+    let total: f64 = board.rows.iter().map(|row| row.budget).sum();
+    assert_eq!(total, 700.0);
+
+    let updates: Vec<(i32, Option<f64>)> = board
+        .changes(&expenses)
+        .iter()
+        .map(|(id, update)| (*id, update.projected))
+        .collect();
+    assert_eq!(
+        updates,
+        vec![(41, Some(55.0)), (42, Some(55.0)), (43, Some(590.0))]
+    );
+    assert!(board.conflicts(&expenses).is_empty());
+
+    // Someone else changed Food's budget while the board was open
+    let mut changed = expenses.clone();
+    changed[0].projected = 150.0;
+    assert_eq!(board.conflicts(&changed), vec!["Food".to_string()]);
+}
+
+#[test]
+fn test_custom_fields_on_expense_form() {
+    let mut expense = merge_expense(1, 1);
+    expense
+        .custom_fields
+        .insert("cost_center", json_value("\"ops\""));
+    expense.custom_fields.insert("mileage", json_value("12"));
+    let mut form = ExpenseFormState::from_expense(&expense);
+    assert_eq!(form.custom_fields[1].text, "12");
+
+    // Fields other expenses have are offered blank, and stay unsent
+    let mut other = merge_expense(2, 1);
+    other.custom_fields.insert("billable", json_value("true"));
+    form.offer_custom_fields(&forms::custom_fields_in(&[expense.clone(), other]));
+    let names: Vec<&str> = form.custom_fields.iter().map(|f| f.name.as_str()).collect();
+    assert_eq!(names, vec!["billable", "cost_center", "mileage"]);
+    assert!(form.to_update().unwrap().is_empty());
+
+    // Text is read as the kind of value the server keeps
+    form.custom_fields[0].text = "yes".to_string();
+    form.custom_fields[2].text = "15.5".to_string();
+    let update = form.to_update().unwrap();
+    assert_eq!(
+        update.custom_fields.get("billable"),
+        Some(&json_value("true"))
+    );
+    assert_eq!(
+        update.custom_fields.get("mileage"),
+        Some(&json_value("15.5"))
+    );
+    assert_eq!(update.custom_fields.get("cost_center"), None);
+
+    form.custom_fields[2].text = "far".to_string();
+    assert_eq!(form.validate(), vec!["mileage must be a number"]);
+    assert!(form.to_update().is_none());
+    assert!(form.show_field_errors(&form.custom_field_errors()));
+    assert_eq!(
+        (form.focused_field, form.selected_custom),
+        (ExpenseField::Custom, 2)
+    );
+
+    // Without any, Tab passes over them
+    let mut form = ExpenseFormState {
+        focused_field: ExpenseField::Notes,
+        ..Default::default()
+    };
+    form.focus_next();
+    assert_eq!(form.focused_field, ExpenseField::Name);
+    form.focus_previous();
+    assert_eq!(form.focused_field, ExpenseField::Notes);
+}
+
+fn json_value(text: &str) -> serde_json::Value {
+    serde_json::from_str(text).unwrap()
+}
@@ -0,0 +1,314 @@
+//! Local storage tests for the Budget TUI application
+
+use std::path::PathBuf;
+
+use budget_tui::changelog::{self, CHANGELOG};
+use budget_tui::storage::{
+    next_flag, ExpenseLedgers, LastView, Ledger, LocalState, MonthChecklist, MonthNotes, TaxFlags,
+    WriteJournal,
+};
+use chrono::NaiveDate;
+use serde_json::json;
+
+/// Unique scratch directory for a test
+fn temp_dir(name: &str) -> PathBuf {
+    let dir = std::env::temp_dir().join(format!("budget-tui-test-{}-{}", name, std::process::id()));
+    let _ = std::fs::remove_dir_all(&dir);
+    dir
+}
+
+#[test]
+fn test_month_notes_set_and_get() {
+    let mut notes = MonthNotes::default();
+    assert!(!notes.has_note(1));
+    assert_eq!(notes.get(1), None);
+
+    notes.set(1, "Call the landlord".to_string());
+    assert!(notes.has_note(1));
+    assert_eq!(notes.get(1), Some("Call the landlord"));
+    assert!(!notes.has_note(2));
+}
+
+#[test]
+fn test_month_notes_blank_removes() {
+    let mut notes = MonthNotes::default();
+    notes.set(1, "Something".to_string());
+    notes.set(1, "  \n ".to_string());
+
+    assert!(!notes.has_note(1));
+    assert_eq!(notes, MonthNotes::default());
+}
+
+#[test]
+fn test_month_notes_load_missing() {
+    let dir = temp_dir("notes-missing");
+    let notes = MonthNotes::load(&dir).unwrap();
+    assert_eq!(notes, MonthNotes::default());
+}
+
+#[test]
+fn test_month_notes_round_trip() {
+    let dir = temp_dir("notes-round-trip");
+    let mut notes = MonthNotes::default();
+    notes.set(3, "Line one\nLine two".to_string());
+    notes.set(7, "Bonus expected".to_string());
+    notes.save(&dir).unwrap();
+
+    let loaded = MonthNotes::load(&dir).unwrap();
+    assert_eq!(loaded, notes);
+    assert_eq!(loaded.get(3), Some("Line one\nLine two"));
+
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[test]
+fn test_month_checklist_toggle() {
+    let mut checklist = MonthChecklist::default();
+    assert!(!checklist.is_done(1, "Enter paychecks"));
+
+    assert!(checklist.toggle(1, "Enter paychecks"));
+    assert!(checklist.is_done(1, "Enter paychecks"));
+    assert!(!checklist.is_done(2, "Enter paychecks"));
+
+    assert!(!checklist.toggle(1, "Enter paychecks"));
+    assert!(!checklist.is_done(1, "Enter paychecks"));
+    assert_eq!(checklist, MonthChecklist::default());
+}
+
+#[test]
+fn test_month_checklist_completed() {
+    let items = vec![
+        "Enter paychecks".to_string(),
+        "Reconcile credit card".to_string(),
+        "Clone to next month".to_string(),
+    ];
+    let mut checklist = MonthChecklist::default();
+    checklist.toggle(1, "Enter paychecks");
+    checklist.toggle(1, "Clone to next month");
+    checklist.toggle(1, "Removed from config");
+
+    assert_eq!(checklist.completed(1, &items), 2);
+    assert_eq!(checklist.completed(2, &items), 0);
+}
+
+#[test]
+fn test_month_checklist_round_trip() {
+    let dir = temp_dir("checklist-round-trip");
+    let mut checklist = MonthChecklist::default();
+    checklist.toggle(4, "Reconcile credit card");
+    checklist.save(&dir).unwrap();
+
+    let loaded = MonthChecklist::load(&dir).unwrap();
+    assert_eq!(loaded, checklist);
+    assert!(loaded.is_done(4, "Reconcile credit card"));
+
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[test]
+fn test_write_journal_push_and_remove() {
+    let mut journal = WriteJournal::default();
+    let first = journal.push("POST", "/expenses", Some(json!({"expense_name": "Rent"})));
+    let second = journal.push("DELETE", "/incomes/4", None);
+
+    assert_eq!(journal.len(), 2);
+    assert_ne!(first, second);
+    assert_eq!(journal.entries()[0].endpoint, "/expenses");
+    // Only POSTs need a key; replays keep it
+    assert!(journal.entries()[0].idempotency_key.is_some());
+    assert_eq!(journal.entries()[1].idempotency_key, None);
+
+    journal.remove(first);
+    assert_eq!(journal.len(), 1);
+    assert_eq!(journal.entries()[0].id, second);
+
+    // IDs are never reused, even after removing entries
+    journal.remove(second);
+    assert!(journal.is_empty());
+    assert!(journal.push("POST", "/expenses", None) > second);
+}
+
+#[test]
+fn test_journal_entry_target() {
+    let mut journal = WriteJournal::default();
+    journal.push("POST", "/expenses", None);
+    journal.push("PUT", "/expenses/12", None);
+    journal.push("POST", "/expenses/12/pay", None);
+    journal.push("DELETE", "/incomes/7?force=true", None);
+
+    let entries = journal.entries();
+    assert_eq!(entries[0].target(), ("/expenses", None));
+    assert!(entries[0].is_create());
+    assert_eq!(entries[1].target(), ("/expenses", Some(12)));
+    assert!(!entries[1].is_create());
+    assert_eq!(entries[2].target(), ("/expenses", Some(12)));
+    assert!(!entries[2].is_create());
+    assert_eq!(entries[3].target(), ("/incomes", Some(7)));
+    assert!(entries[3].is_delete());
+}
+
+#[test]
+fn test_write_journal_save_and_load() {
+    let dir = temp_dir("journal-roundtrip");
+    let mut journal = WriteJournal::default();
+    journal.push("PUT", "/incomes/3", Some(json!({"amount": 1200.0})));
+    journal.save(&dir).unwrap();
+
+    let loaded = WriteJournal::load(&dir).unwrap();
+    assert_eq!(loaded, journal);
+
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[test]
+fn test_tax_flags_match_names_loosely() {
+    let mut flags = TaxFlags::default();
+    flags.set_expense_flag("Doctor ", Some("Medical".to_string()));
+    flags.set_income_flag("Salary", Some("Taxable".to_string()));
+
+    assert_eq!(flags.expense_flag("doctor"), Some("Medical"));
+    assert_eq!(flags.income_flag("SALARY"), Some("Taxable"));
+    assert_eq!(flags.expense_flag("Salary"), None);
+
+    flags.set_expense_flag("DOCTOR", None);
+    assert_eq!(flags.expense_flag("Doctor"), None);
+    assert!(!flags.is_empty());
+}
+
+#[test]
+fn test_tax_flags_roundtrip() {
+    let dir = temp_dir("tax-flags");
+    let mut flags = TaxFlags::default();
+    flags.set_expense_flag("Donation", Some("Charity".to_string()));
+    flags.save(&dir).unwrap();
+
+    let loaded = TaxFlags::load(&dir).unwrap();
+    assert_eq!(loaded, flags);
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[test]
+fn test_next_tax_flag_cycles_back_to_none() {
+    let flags = vec!["Deductible".to_string(), "Medical".to_string()];
+
+    assert_eq!(next_flag(&flags, None), Some("Deductible".to_string()));
+    assert_eq!(
+        next_flag(&flags, Some("Deductible")),
+        Some("Medical".to_string())
+    );
+    assert_eq!(next_flag(&flags, Some("Medical")), None);
+    // A flag removed from the config starts over
+    assert_eq!(
+        next_flag(&flags, Some("Old")),
+        Some("Deductible".to_string())
+    );
+    assert_eq!(next_flag(&[], None), None);
+}
+
+#[test]
+fn test_expense_ledgers_cycle() {
+    let mut ledgers = ExpenseLedgers::default();
+    assert_eq!(ledgers.ledger(7), Ledger::Personal);
+
+    ledgers.set_ledger(7, 3, Ledger::Personal.next());
+    assert_eq!(ledgers.ledger(7), Ledger::Business);
+    ledgers.set_ledger(7, 3, Ledger::Business.next());
+    assert_eq!(ledgers.ledger(7), Ledger::Reimbursable);
+    assert_eq!(ledgers.month_ids().into_iter().collect::<Vec<_>>(), vec![3]);
+
+    // Back to personal drops the entry
+    ledgers.set_ledger(7, 3, Ledger::Reimbursable.next());
+    assert_eq!(ledgers.ledger(7), Ledger::Personal);
+    assert!(ledgers.is_empty());
+}
+
+#[test]
+fn test_expense_ledgers_toggle_reimbursed() {
+    let mut ledgers = ExpenseLedgers::default();
+    ledgers.set_ledger(1, 2, Ledger::Reimbursable);
+    let today = NaiveDate::from_ymd_opt(2024, 3, 28).unwrap();
+
+    assert!(ledgers.toggle_reimbursed(1, today));
+    assert_eq!(
+        ledgers.get(1).unwrap().reimbursed_on.as_deref(),
+        Some("2024-03-28")
+    );
+    assert!(!ledgers.toggle_reimbursed(1, today));
+    assert_eq!(ledgers.get(1).unwrap().reimbursed_on, None);
+    // Personal expenses have nothing to reimburse
+    assert!(!ledgers.toggle_reimbursed(99, today));
+}
+
+#[test]
+fn test_expense_ledgers_roundtrip() {
+    let dir = temp_dir("ledgers");
+    let mut ledgers = ExpenseLedgers::default();
+    ledgers.set_ledger(4, 1, Ledger::Business);
+    ledgers.set_ledger(5, 2, Ledger::Reimbursable);
+    ledgers.toggle_reimbursed(5, NaiveDate::from_ymd_opt(2024, 3, 28).unwrap());
+    ledgers.save(&dir).unwrap();
+
+    let loaded = ExpenseLedgers::load(&dir).unwrap();
+    assert_eq!(loaded, ledgers);
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[test]
+fn test_local_state_roundtrip() {
+    let dir = temp_dir("local-state");
+    assert_eq!(LocalState::load(&dir).unwrap(), LocalState::default());
+
+    let state = LocalState {
+        last_version: Some("1.0.11".to_string()),
+    };
+    state.save(&dir).unwrap();
+    assert_eq!(LocalState::load(&dir).unwrap(), state);
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[test]
+fn test_last_view_roundtrip() {
+    let dir = temp_dir("last-view");
+    assert_eq!(LastView::load(&dir).unwrap(), LastView::default());
+
+    let view = LastView {
+        tab: Some("Expenses".to_string()),
+        month: Some("2024-03".to_string()),
+        period: None,
+        category: Some("Groceries".to_string()),
+    };
+    view.save(&dir).unwrap();
+    assert_eq!(LastView::load(&dir).unwrap(), view);
+    let _ = std::fs::remove_dir_all(&dir);
+}
+
+#[test]
+fn test_changelog_releases_since() {
+    let text = "# Changelog\n\nIntro\n\n## v1.2.0\n\n- New thing\n  on two lines\n- Fix\n\n## 1.1.0\n- Older\n\n## 1.0.9\n- Oldest\n";
+    let releases = changelog::parse(text);
+    assert_eq!(releases.len(), 3);
+    assert_eq!(releases[0].version, "1.2.0");
+    assert_eq!(releases[0].changes, vec!["New thing on two lines", "Fix"]);
+
+    let since = changelog::releases_since(text, Some("1.0.9"), "1.2.0");
+    let versions: Vec<&str> = since.iter().map(|r| r.version.as_str()).collect();
+    assert_eq!(versions, vec!["1.2.0", "1.1.0"]);
+    // Compared by number, not text
+    assert_eq!(
+        changelog::releases_since(text, Some("1.0.10"), "1.1.0").len(),
+        1
+    );
+
+    // First run, same version and downgrades show nothing
+    assert!(changelog::releases_since(text, None, "1.2.0").is_empty());
+    assert!(changelog::releases_since(text, Some("1.2.0"), "1.2.0").is_empty());
+    assert!(changelog::releases_since(text, Some("1.2.0"), "1.1.0").is_empty());
+
+    assert_eq!(
+        changelog::release(text, "v1.1.0").unwrap().changes,
+        vec!["Older"]
+    );
+    // The shipped changelog has notes for the version being built
+    let version = budget_tui::app::VERSION;
+    assert!(changelog::release(CHANGELOG, version).is_some());
+}